@@ -308,6 +308,7 @@ func run(ctx context.Context) error {
 			if err != nil {
 				logrus.Fatal(err)
 			}
+			cluster.DropPreflightResults(ctx)
 			return nil
 		}
 
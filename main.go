@@ -141,6 +141,18 @@ func main() {
 			Usage:       "Defines the maximum size in megabytes of the audit log file before it gets rotated, default size is 100M",
 			Destination: &config.AuditLogMaxsize,
 		},
+		cli.StringFlag{
+			Name:        "audit-log-rotate",
+			EnvVar:      "AUDIT_LOG_ROTATE",
+			Usage:       "Rotates the audit log on a fixed schedule in addition to its size: daily (at midnight) or hourly. Empty rotates the audit log on its size only",
+			Destination: &config.AuditLogRotate,
+		},
+		cli.StringSliceFlag{
+			Name:   "audit-log-redact-fields",
+			EnvVar: "AUDIT_LOG_REDACT_FIELDS",
+			Usage:  "Fields redacted from the request and response bodies of the audit log, in addition to the credential fields of the node drivers and the password and token fields. A field is either a key or a dot separated path such as spec.secretKey",
+			Value:  &config.AuditLogRedact,
+		},
 		cli.IntFlag{
 			Name:        "audit-level",
 			Value:       0,
@@ -148,6 +160,13 @@ func main() {
 			Usage:       "Audit log level: 0 - disable audit log, 1 - log event metadata, 2 - log event metadata and request body, 3 - log event metadata, request body and response body",
 			Destination: &config.AuditLevel,
 		},
+		cli.IntFlag{
+			Name:        "audit-cluster-proxy-level",
+			Value:       0,
+			EnvVar:      "AUDIT_CLUSTER_PROXY_LEVEL",
+			Usage:       "Audit log level of the requests proxied to downstream clusters through /k8s/clusters, logged with the Kubernetes verb and resource they target: 0 - log them at audit-level without the Kubernetes attributes, 1 - log event metadata, 2 - log event metadata and request body, 3 - log event metadata, request body and response body",
+			Destination: &config.AuditClusterProxyLevel,
+		},
 		cli.StringFlag{
 			Name:        "profile-listen-address",
 			Value:       "127.0.0.1:6060",
@@ -46,6 +46,14 @@ type bindingsCleanup struct {
 	prtbs               v3.ProjectRoleTemplateBindingClient
 	clusterRoleBindings v1.ClusterRoleBindingClient
 	roleBindings        v1.RoleBindingClient
+	// dryRun logs the bindings that would be deleted instead of deleting them
+	dryRun bool
+	// cluster limits the cleanup to the bindings of a single cluster, all the bindings are cleaned up when it is empty
+	cluster string
+	// logf reports the duplicates found and the summary of the cleanup
+	logf func(format string, args ...interface{})
+	// preserve holds the names of the bindings that are never deleted, role bindings can also be named namespace/name
+	preserve map[string]bool
 }
 
 func Bindings(clientConfig *restclient.Config) error {
@@ -90,11 +98,25 @@ func Bindings(clientConfig *restclient.Config) error {
 		prtbs:               rancherManagement.Management().V3().ProjectRoleTemplateBinding(),
 		clusterRoleBindings: k8srbac.Rbac().V1().ClusterRoleBinding(),
 		roleBindings:        k8srbac.Rbac().V1().RoleBinding(),
+		dryRun:              dryRun,
+		logf:                logrus.Infof,
+		preserve:            preservedNames(),
 	}
 
 	return bc.clean()
 }
 
+// preservedNames returns the names of the bindings listed in CLEANUP_PRESERVE_NAMES, separated by commas
+func preservedNames() map[string]bool {
+	names := map[string]bool{}
+	for _, name := range strings.Split(os.Getenv("CLEANUP_PRESERVE_NAMES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
 func (bc *bindingsCleanup) clean() error {
 	crtbs, err := bc.crtbs.List("", metav1.ListOptions{})
 	if err != nil {
@@ -119,10 +141,15 @@ func (bc *bindingsCleanup) clean() error {
 			rancher25 = true
 		}
 	} else {
-		logrus.Info("No clusterRoleTemplateBindings or projectRoleTemplateBindings found, exiting.")
+		bc.logf("No clusterRoleTemplateBindings or projectRoleTemplateBindings found, exiting.")
 		return nil
 	}
 
+	if bc.cluster != "" {
+		crtbs.Items = bc.clusterCRTBs(crtbs.Items)
+		prtbs.Items = bc.clusterPRTBs(prtbs.Items)
+	}
+
 	var waitGroup sync.WaitGroup
 
 	waitGroup.Add(2)
@@ -143,6 +170,26 @@ func (bc *bindingsCleanup) clean() error {
 	return nil
 }
 
+func (bc *bindingsCleanup) clusterCRTBs(crtbs []apiv3.ClusterRoleTemplateBinding) []apiv3.ClusterRoleTemplateBinding {
+	var result []apiv3.ClusterRoleTemplateBinding
+	for i := range crtbs {
+		if crtbs[i].ObjClusterName() == bc.cluster {
+			result = append(result, crtbs[i])
+		}
+	}
+	return result
+}
+
+func (bc *bindingsCleanup) clusterPRTBs(prtbs []apiv3.ProjectRoleTemplateBinding) []apiv3.ProjectRoleTemplateBinding {
+	var result []apiv3.ProjectRoleTemplateBinding
+	for i := range prtbs {
+		if prtbs[i].ObjClusterName() == bc.cluster {
+			result = append(result, prtbs[i])
+		}
+	}
+	return result
+}
+
 func (bc *bindingsCleanup) cleanCRTB(newLabel bool, crtbs []apiv3.ClusterRoleTemplateBinding) error {
 	var objectMetas []metav1.ObjectMeta
 	for _, crtb := range crtbs {
@@ -200,11 +247,11 @@ func (bc *bindingsCleanup) cleanObjectDuplicates(bindingType string, newLabel bo
 			if CRBduplicates > 0 || RBDupes > 0 {
 				totalCRBDupes += CRBduplicates
 				totalRoleDupes += RBDupes
-				logrus.Infof("%v %v label:%v Duplicates: CRB:%v RB:%v", bindingUpper, meta.Name, label, CRBduplicates, RBDupes)
+				bc.logf("%v %v label:%v Duplicates: CRB:%v RB:%v", bindingUpper, meta.Name, label, CRBduplicates, RBDupes)
 			}
 		}
 	}
-	logrus.Infof("Total %v duplicate clusterRoleBindings %v, roleBindings %v", bindingUpper, totalCRBDupes, totalRoleDupes)
+	bc.logf("Total %v duplicate clusterRoleBindings %v, roleBindings %v", bindingUpper, totalCRBDupes, totalRoleDupes)
 	return returnErr
 }
 
@@ -231,12 +278,16 @@ func (bc *bindingsCleanup) dedupeCRB(bindings []k8srbacv1.ClusterRoleBinding) er
 			logrus.Infof("found the CRB with the deterministic name %v, will not delete this", binding.Name)
 			continue
 		}
-		if !dryRun {
+		if bc.preserve[binding.Name] {
+			bc.logf("clusterRoleBinding %v is in CLEANUP_PRESERVE_NAMES, will not delete this", binding.Name)
+			continue
+		}
+		if !bc.dryRun {
 			if err := bc.clusterRoleBindings.Delete(binding.Name, &metav1.DeleteOptions{}); err != nil {
 				logrus.Errorf("error attempting to delete CRB %v %v", binding.Name, err)
 			}
 		} else {
-			logrus.Infof("DryRun enabled, clusterRoleBinding %v would be deleted", binding.Name)
+			bc.logf("DryRun enabled, clusterRoleBinding %v would be deleted", binding.Name)
 		}
 	}
 	return nil
@@ -275,13 +326,17 @@ func (bc *bindingsCleanup) dedupeRB(roleBindings []k8srbacv1.RoleBinding) (int,
 				logrus.Infof("found the RB with the deterministic name %v in namespace %v, will not delete this", binding.Name, binding.Namespace)
 				continue
 			}
+			if bc.preserve[binding.Name] || bc.preserve[binding.Namespace+"/"+binding.Name] {
+				bc.logf("roleBinding %v in namespace %v is in CLEANUP_PRESERVE_NAMES, will not delete this", binding.Name, binding.Namespace)
+				continue
+			}
 			duplicatesFound++
-			if !dryRun {
+			if !bc.dryRun {
 				if err := bc.roleBindings.Delete(binding.Namespace, binding.Name, &metav1.DeleteOptions{}); err != nil {
 					logrus.Errorf("error attempting to delete RB %v %v", binding.Name, err)
 				}
 			} else {
-				logrus.Infof("DryRun enabled, roleBinding %v in namespace %v would be deleted", binding.Name, binding.Namespace)
+				bc.logf("DryRun enabled, roleBinding %v in namespace %v would be deleted", binding.Name, binding.Namespace)
 			}
 		}
 	}
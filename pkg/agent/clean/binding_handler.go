@@ -0,0 +1,88 @@
+// +build !windows
+
+package clean
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/auth/util"
+	v3norman "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	typedauthzv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+type bindingsHandler struct {
+	clusterLister v3norman.ClusterLister
+	sarClient     typedauthzv1.SubjectAccessReviewInterface
+	cleanup       bindingsCleanup
+}
+
+// NewBindingsHandler runs the bindings cleanup of a cluster on demand for the admins, whether they are admins through
+// their own global role bindings or the ones of their groups. The cleanup is a dry run unless the dryRun query
+// parameter is false, its output is streamed as plain text.
+func NewBindingsHandler(scaledContext *config.ScaledContext) http.Handler {
+	return &bindingsHandler{
+		clusterLister: scaledContext.Management.Clusters("").Controller().Lister(),
+		sarClient:     scaledContext.K8sClient.AuthorizationV1().SubjectAccessReviews(),
+		cleanup: bindingsCleanup{
+			crtbs:               scaledContext.Wrangler.Mgmt.ClusterRoleTemplateBinding(),
+			prtbs:               scaledContext.Wrangler.Mgmt.ProjectRoleTemplateBinding(),
+			clusterRoleBindings: scaledContext.Wrangler.RBAC.ClusterRoleBinding(),
+			roleBindings:        scaledContext.Wrangler.RBAC.RoleBinding(),
+			preserve:            preservedNames(),
+		},
+	}
+}
+
+func (h *bindingsHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	isAdmin, err := sar.IsAdmin(req, h.sarClient)
+	if err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !isAdmin {
+		util.ReturnHTTPError(rw, req, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	clusterID := mux.Vars(req)["clusterID"]
+	if _, err := h.clusterLister.Get("", clusterID); kerror.IsNotFound(err) {
+		util.ReturnHTTPError(rw, req, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	bc := h.cleanup
+	bc.cluster = clusterID
+	bc.dryRun = req.URL.Query().Get("dryRun") != "false"
+
+	// the bindings of the clusters and of the projects are cleaned up concurrently
+	var lock sync.Mutex
+	flusher, _ := rw.(http.Flusher)
+	bc.logf = func(format string, args ...interface{}) {
+		logrus.Infof("[bindingscleanup] cluster %s: "+format, append([]interface{}{clusterID}, args...)...)
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintf(rw, format+"\n", args...)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.WriteHeader(http.StatusOK)
+	bc.logf("Starting bindings cleanup of cluster %s, dry run: %v", clusterID, bc.dryRun)
+	if err := bc.clean(); err != nil {
+		bc.logf("Bindings cleanup failed: %v", err)
+		return
+	}
+	bc.logf("Bindings cleanup finished")
+}
@@ -0,0 +1,201 @@
+// +build !windows
+
+package clean
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	apiv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	"github.com/rancher/rancher/pkg/controllers/management/auth"
+	v3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	v3norman "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	v1 "github.com/rancher/wrangler/pkg/generated/controllers/rbac/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8srbacv1 "k8s.io/api/rbac/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeCRTBClient struct {
+	v3.ClusterRoleTemplateBindingClient
+}
+
+func (f *fakeCRTBClient) List(namespace string, opts metav1.ListOptions) (*apiv3.ClusterRoleTemplateBindingList, error) {
+	return &apiv3.ClusterRoleTemplateBindingList{
+		Items: []apiv3.ClusterRoleTemplateBinding{
+			newCRTB("c-abcde", "crtb-abcde"),
+			newCRTB("c-fghij", "crtb-fghij"),
+		},
+	}, nil
+}
+
+type fakePRTBClient struct {
+	v3.ProjectRoleTemplateBindingClient
+}
+
+func (f *fakePRTBClient) List(namespace string, opts metav1.ListOptions) (*apiv3.ProjectRoleTemplateBindingList, error) {
+	return &apiv3.ProjectRoleTemplateBindingList{}, nil
+}
+
+// fakeCRBClient holds two duplicate cluster role bindings for the membership of each cluster role template binding
+type fakeCRBClient struct {
+	v1.ClusterRoleBindingClient
+	selectors []string
+	deleted   []string
+}
+
+func (f *fakeCRBClient) List(opts metav1.ListOptions) (*k8srbacv1.ClusterRoleBindingList, error) {
+	f.selectors = append(f.selectors, opts.LabelSelector)
+	list := &k8srbacv1.ClusterRoleBindingList{}
+	if !strings.HasSuffix(opts.LabelSelector, "="+auth.MembershipBindingOwner) {
+		return list, nil
+	}
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"crb-older", "crb-newer"} {
+		list.Items = append(list.Items, k8srbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+			RoleRef:    k8srbacv1.RoleRef{Kind: "ClusterRole", Name: "c-abcde-clustermember"},
+			Subjects:   []k8srbacv1.Subject{{Kind: "User", Name: "u-abcde"}},
+		})
+		created = created.Add(time.Hour)
+	}
+	return list, nil
+}
+
+func (f *fakeCRBClient) Get(name string, options metav1.GetOptions) (*k8srbacv1.ClusterRoleBinding, error) {
+	return nil, kerror.NewNotFound(schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"}, name)
+}
+
+func (f *fakeCRBClient) Delete(name string, options *metav1.DeleteOptions) error {
+	f.deleted = append(f.deleted, name)
+	return nil
+}
+
+type fakeRBClient struct {
+	v1.RoleBindingClient
+	deleted []string
+}
+
+func (f *fakeRBClient) List(namespace string, opts metav1.ListOptions) (*k8srbacv1.RoleBindingList, error) {
+	return &k8srbacv1.RoleBindingList{}, nil
+}
+
+func (f *fakeRBClient) Get(namespace, name string, options metav1.GetOptions) (*k8srbacv1.RoleBinding, error) {
+	return nil, kerror.NewNotFound(schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "rolebindings"}, name)
+}
+
+func (f *fakeRBClient) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	f.deleted = append(f.deleted, namespace+"/"+name)
+	return nil
+}
+
+func newCRTB(clusterName, name string) apiv3.ClusterRoleTemplateBinding {
+	return apiv3.ClusterRoleTemplateBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      name,
+			Labels:    map[string]string{auth.RtbCrbRbLabelsUpdated: "true"},
+		},
+		ClusterName: clusterName,
+	}
+}
+
+func newBindingsRouter(crbs *fakeCRBClient) http.Handler {
+	h := &bindingsHandler{
+		clusterLister: &fakes.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v3norman.Cluster, error) {
+				if name != "c-abcde" && name != "c-fghij" {
+					return nil, kerror.NewNotFound(schema.GroupResource{Group: "management.cattle.io", Resource: "clusters"}, name)
+				}
+				return &v3norman.Cluster{}, nil
+			},
+		},
+		sarClient: &fake.SubjectAccessReviews{Admins: map[string]bool{
+			"u-admin":               true,
+			"okta_group://platform": true,
+		}},
+		cleanup: bindingsCleanup{
+			crtbs:               &fakeCRTBClient{},
+			prtbs:               &fakePRTBClient{},
+			clusterRoleBindings: crbs,
+			roleBindings:        &fakeRBClient{},
+		},
+	}
+
+	router := mux.NewRouter()
+	router.Path("/v3/bindingscleanup/{clusterID}").Handler(h)
+	return router
+}
+
+func serveBindingsCleanup(router http.Handler, user, path string, groups ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	if user != "" {
+		req.Header.Set("Impersonate-User", user)
+	}
+	for _, group := range groups {
+		req.Header.Add("Impersonate-Group", group)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBindingsHandlerAuthorization(t *testing.T) {
+	crbs := &fakeCRBClient{}
+	router := newBindingsRouter(crbs)
+
+	rec := serveBindingsCleanup(router, "", "/v3/bindingscleanup/c-abcde")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = serveBindingsCleanup(router, "u-user", "/v3/bindingscleanup/c-abcde?dryRun=false")
+	assert.Equal(t, http.StatusForbidden, rec.Code, "expected a user without the admin global role to be forbidden")
+
+	rec = serveBindingsCleanup(router, "u-admin", "/v3/bindingscleanup/c-unknown")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serveBindingsCleanup(router, "u-member", "/v3/bindingscleanup/c-unknown", "okta_group://platform")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "expected the members of an admin group to be admins")
+
+	assert.Empty(t, crbs.selectors, "expected no cleanup to run")
+	assert.Empty(t, crbs.deleted)
+}
+
+func TestBindingsHandlerDryRun(t *testing.T) {
+	crbs := &fakeCRBClient{}
+	router := newBindingsRouter(crbs)
+
+	rec := serveBindingsCleanup(router, "u-admin", "/v3/bindingscleanup/c-abcde")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "dry run: true")
+	assert.Contains(t, body, "clusterRoleBinding crb-newer would be deleted")
+	assert.Contains(t, body, "Total CRTB duplicate clusterRoleBindings 1, roleBindings 0")
+	assert.Contains(t, body, "Bindings cleanup finished")
+	assert.Empty(t, crbs.deleted, "expected no binding to be deleted in a dry run")
+
+	require.NotEmpty(t, crbs.selectors)
+	for _, selector := range crbs.selectors {
+		assert.True(t, strings.HasPrefix(selector, "c-abcde_crtb-abcde="), "expected only the bindings of the cluster to be cleaned up, got %s", selector)
+	}
+}
+
+func TestBindingsHandler(t *testing.T) {
+	crbs := &fakeCRBClient{}
+	router := newBindingsRouter(crbs)
+
+	rec := serveBindingsCleanup(router, "u-admin", "/v3/bindingscleanup/c-abcde?dryRun=false")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "dry run: false")
+	assert.Equal(t, []string{"crb-newer"}, crbs.deleted, "expected the duplicates but the oldest binding to be deleted")
+}
@@ -0,0 +1,77 @@
+// +build !windows
+
+package clean
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8srbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPreservedNames(t *testing.T) {
+	os.Setenv("CLEANUP_PRESERVE_NAMES", " crb-keep, p-abcde/rb-keep,,")
+	defer os.Unsetenv("CLEANUP_PRESERVE_NAMES")
+	assert.Equal(t, map[string]bool{"crb-keep": true, "p-abcde/rb-keep": true}, preservedNames())
+}
+
+func TestDedupeCRBPreserved(t *testing.T) {
+	crbs := &fakeCRBClient{}
+	bc := bindingsCleanup{
+		clusterRoleBindings: crbs,
+		logf:                logrus.Infof,
+		preserve:            map[string]bool{"crb-keep": true},
+	}
+
+	var bindings []k8srbacv1.ClusterRoleBinding
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"crb-oldest", "crb-keep", "crb-newest"} {
+		bindings = append(bindings, k8srbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+			RoleRef:    k8srbacv1.RoleRef{Kind: "ClusterRole", Name: "c-abcde-clustermember"},
+			Subjects:   []k8srbacv1.Subject{{Kind: "User", Name: "u-abcde"}},
+		})
+		created = created.Add(time.Hour)
+	}
+
+	require.NoError(t, bc.dedupeCRB(bindings))
+	assert.Equal(t, []string{"crb-newest"}, crbs.deleted, "expected the preserved duplicate to survive")
+}
+
+func TestDedupeRBPreserved(t *testing.T) {
+	rbs := &fakeRBClient{}
+	bc := bindingsCleanup{
+		roleBindings: rbs,
+		logf:         logrus.Infof,
+		preserve:     map[string]bool{"rb-keep": true, "p-fghij/rb-keep-namespaced": true},
+	}
+
+	var bindings []k8srbacv1.RoleBinding
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, binding := range []struct{ namespace, name string }{
+		{"p-abcde", "rb-oldest"},
+		{"p-abcde", "rb-keep"},
+		{"p-abcde", "rb-newest"},
+		{"p-fghij", "rb-oldest"},
+		{"p-fghij", "rb-keep-namespaced"},
+		{"p-fghij", "rb-newest"},
+	} {
+		bindings = append(bindings, k8srbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: binding.namespace, Name: binding.name, CreationTimestamp: metav1.NewTime(created)},
+			RoleRef:    k8srbacv1.RoleRef{Kind: "Role", Name: "project-member"},
+			Subjects:   []k8srbacv1.Subject{{Kind: "User", Name: "u-abcde"}},
+		})
+		created = created.Add(time.Hour)
+	}
+
+	duplicates, err := bc.dedupeRB(bindings)
+	require.NoError(t, err)
+	assert.Equal(t, 2, duplicates)
+	assert.ElementsMatch(t, []string{"p-abcde/rb-newest", "p-fghij/rb-newest"}, rbs.deleted,
+		"expected the preserved duplicates to survive")
+}
@@ -1,9 +1,16 @@
 package clean
 
 import (
+	"net/http"
+
+	"github.com/rancher/rancher/pkg/types/config"
 	restclient "k8s.io/client-go/rest"
 )
 
 func Bindings(clientConfig *restclient.Config) error {
 	return nil
 }
+
+func NewBindingsHandler(scaledContext *config.ScaledContext) http.Handler {
+	return http.NotFoundHandler()
+}
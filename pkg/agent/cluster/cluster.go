@@ -11,8 +11,12 @@
 
 	"github.com/pkg/errors"
 	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/systemtemplate"
+	"github.com/rancher/rancher/pkg/version"
 	"github.com/rancher/wrangler/pkg/kubeconfig"
+	"github.com/sirupsen/logrus"
 	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -48,12 +52,16 @@ func CAChecksum() string {
 	return os.Getenv("CATTLE_CA_CHECKSUM")
 }
 
-func getTokenFromAPI() ([]byte, []byte, error) {
+func newClient() (kubernetes.Interface, error) {
 	cfg, err := kubeconfig.GetNonInteractiveClientConfig("").ClientConfig()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	k8s, err := kubernetes.NewForConfig(cfg)
+	return kubernetes.NewForConfig(cfg)
+}
+
+func getTokenFromAPI() ([]byte, []byte, error) {
+	k8s, err := newClient()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -86,15 +94,58 @@ func Params() (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	clusterParams := map[string]interface{}{
+		"address":      fmt.Sprintf("%s:%s", kubernetesServiceHost, kubernetesServicePort),
+		"token":        strings.TrimSpace(string(token)),
+		"caCert":       base64.StdEncoding.EncodeToString(caData),
+		"agentVersion": version.Version,
+	}
+	if reason, checkedAt := preflightFailure(); reason != "" {
+		logrus.Errorf("Import %s, the agent may be unable to connect to Rancher", reason)
+		clusterParams["preflightFailure"] = reason
+		clusterParams["preflightCheckedAt"] = checkedAt
+	}
+
 	return map[string]interface{}{
-		"cluster": map[string]interface{}{
-			"address": fmt.Sprintf("%s:%s", kubernetesServiceHost, kubernetesServicePort),
-			"token":   strings.TrimSpace(string(token)),
-			"caCert":  base64.StdEncoding.EncodeToString(caData),
-		},
+		"cluster": clusterParams,
 	}, nil
 }
 
+// preflightFailure returns the reason the pre-flight Job of the import manifest failed and the time the checks were
+// run, or an empty string if the Job passed or did not run.
+func preflightFailure() (string, string) {
+	k8s, err := newClient()
+	if err != nil {
+		return "", ""
+	}
+	cm, err := k8s.CoreV1().ConfigMaps(namespace.System).Get(context.Background(), systemtemplate.PreflightConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logrus.Debugf("failed to read pre-flight results from %s/%s: %v", namespace.System, systemtemplate.PreflightConfigMapName, err)
+		}
+		return "", ""
+	}
+	results, err := systemtemplate.ParsePreflightResults(cm.Data)
+	if err != nil {
+		logrus.Warnf("failed to parse pre-flight results from %s/%s: %v", namespace.System, systemtemplate.PreflightConfigMapName, err)
+		return "", ""
+	}
+	return systemtemplate.PreflightFailureReason(results), cm.Annotations[systemtemplate.PreflightCheckedAtAnnotation]
+}
+
+// DropPreflightResults deletes the results of the pre-flight Job of the import manifest once the agent runs, so that
+// the failures it reported are not reported again after they were fixed.
+func DropPreflightResults(ctx context.Context) {
+	k8s, err := newClient()
+	if err != nil {
+		return
+	}
+	err = k8s.CoreV1().ConfigMaps(namespace.System).Delete(ctx, systemtemplate.PreflightConfigMapName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logrus.Warnf("failed to delete pre-flight results %s/%s: %v", namespace.System, systemtemplate.PreflightConfigMapName, err)
+	}
+}
+
 func getenv(env string) (string, error) {
 	value := os.Getenv(env)
 	if value == "" {
@@ -1,12 +1,14 @@
 package rancher
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"os"
 	"sync"
 
 	"github.com/rancher/rancher/pkg/agent/cluster"
+	"github.com/rancher/rancher/pkg/api/steve/aggregation"
 	"github.com/rancher/rancher/pkg/features"
 	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/rancher"
@@ -18,6 +20,7 @@
 	corev1 "k8s.io/api/core/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
@@ -122,7 +125,21 @@ func setupSteveAggregation() error {
 		"CATTLE_TOKEN":       []byte(token),
 		"CATTLE_CA_CHECKSUM": []byte(cluster.CAChecksum()),
 		"url":                []byte(url + "/v3/connect"),
-		"token":              []byte("steve-cluster-" + token),
+		aggregation.TokenKey: []byte("steve-cluster-" + token),
+	}
+
+	// a rotated token is handed to the aggregation client as its next token, so that it drains the connections made
+	// with the replaced token rather than interrupting them
+	k8s, err := kubernetes.NewForConfig(c)
+	if err != nil {
+		return err
+	}
+	existing, err := k8s.CoreV1().Secrets(namespace.System).Get(context.TODO(), "steve-aggregation", metav1.GetOptions{})
+	if err != nil && !apierror.IsNotFound(err) {
+		return err
+	} else if err == nil && len(existing.Data[aggregation.TokenKey]) > 0 && !bytes.Equal(existing.Data[aggregation.TokenKey], data[aggregation.TokenKey]) {
+		data[aggregation.NextTokenKey] = data[aggregation.TokenKey]
+		data[aggregation.TokenKey] = existing.Data[aggregation.TokenKey]
 	}
 
 	ca, err := ioutil.ReadFile("/etc/kubernetes/ssl/certs/serverca")
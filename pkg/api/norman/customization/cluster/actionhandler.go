@@ -6,9 +6,11 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
 	gaccess "github.com/rancher/rancher/pkg/api/norman/customization/globalnamespaceaccess"
+	"github.com/rancher/rancher/pkg/api/norman/customization/roletemplatebinding"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/catalog/manager"
 	mgmtclient "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/clusterimages"
 	"github.com/rancher/rancher/pkg/clustermanager"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/user"
@@ -17,24 +19,27 @@
 )
 
 type ActionHandler struct {
-	NodepoolGetter                v3.NodePoolsGetter
-	NodeLister                    v3.NodeLister
-	ClusterClient                 v3.ClusterInterface
-	CatalogManager                manager.CatalogManager
-	NodeTemplateGetter            v3.NodeTemplatesGetter
-	UserMgr                       user.Manager
-	ClusterManager                *clustermanager.Manager
-	CatalogTemplateVersionLister  v3.CatalogTemplateVersionLister
-	BackupClient                  v3.EtcdBackupInterface
-	ClusterScanClient             v3.ClusterScanInterface
-	ClusterTemplateClient         v3.ClusterTemplateInterface
-	ClusterTemplateRevisionClient v3.ClusterTemplateRevisionInterface
-	SubjectAccessReviewClient     v1.SubjectAccessReviewInterface
-	CisBenchmarkVersionClient     v3.CisBenchmarkVersionInterface
-	CisBenchmarkVersionLister     v3.CisBenchmarkVersionLister
-	CisConfigClient               v3.CisConfigInterface
-	CisConfigLister               v3.CisConfigLister
-	TokenClient                   v3.TokenInterface
+	NodepoolGetter                 v3.NodePoolsGetter
+	NodeLister                     v3.NodeLister
+	ClusterClient                  v3.ClusterInterface
+	CatalogManager                 manager.CatalogManager
+	NodeTemplateGetter             v3.NodeTemplatesGetter
+	UserMgr                        user.Manager
+	ClusterManager                 *clustermanager.Manager
+	CatalogTemplateVersionLister   v3.CatalogTemplateVersionLister
+	BackupClient                   v3.EtcdBackupInterface
+	ClusterScanClient              v3.ClusterScanInterface
+	ClusterTemplateClient          v3.ClusterTemplateInterface
+	ClusterTemplateRevisionClient  v3.ClusterTemplateRevisionInterface
+	SubjectAccessReviewClient      v1.SubjectAccessReviewInterface
+	CisBenchmarkVersionClient      v3.CisBenchmarkVersionInterface
+	CisBenchmarkVersionLister      v3.CisBenchmarkVersionLister
+	CisConfigClient                v3.CisConfigInterface
+	CisConfigLister                v3.CisConfigLister
+	TokenClient                    v3.TokenInterface
+	MemberSetter                   *roletemplatebinding.MemberSetter
+	ImageResolver                  *clusterimages.Resolver
+	ClusterRegistrationTokenLister v3.ClusterRegistrationTokenLister
 }
 
 func (a ActionHandler) ClusterActionHandler(actionName string, action *types.Action, apiContext *types.APIContext) error {
@@ -54,6 +59,15 @@ func (a ActionHandler) ClusterActionHandler(actionName string, action *types.Act
 		return apiContext.AccessControl.CanDo(v3.EtcdBackupGroupVersionKind.Group, v3.EtcdBackupResource.Name, "create", apiContext, backupMap, &etcdBackupSchema) == nil
 	}
 
+	canGetRegistrationTokens := func() bool {
+		crtSchema := types.Schema{ID: mgmtclient.ClusterRegistrationTokenType}
+		// the registration tokens of a cluster are in the namespace of the cluster ID
+		crtMap := map[string]interface{}{
+			"namespaceId": apiContext.ID,
+		}
+		return apiContext.AccessControl.CanDo(v3.ClusterRegistrationTokenGroupVersionKind.Group, v3.ClusterRegistrationTokenResource.Name, "get", apiContext, crtMap, &crtSchema) == nil
+	}
+
 	canCreateClusterTemplate := func() bool {
 		callerID := apiContext.Request.Header.Get(gaccess.ImpersonateUserHeader)
 		canCreateTemplates, _ := CanCreateRKETemplate(callerID, a.SubjectAccessReviewClient)
@@ -89,6 +103,13 @@ func (a ActionHandler) ClusterActionHandler(actionName string, action *types.Act
 			return httperror.NewAPIError(httperror.PermissionDenied, "can not backup etcd")
 		}
 		return a.BackupEtcdHandler(actionName, action, apiContext)
+	case v32.ClusterActionNodeCommand:
+		if !canGetRegistrationTokens() {
+			return httperror.NewAPIError(httperror.PermissionDenied, "can not get registration tokens")
+		}
+		return a.NodeCommand(actionName, action, apiContext)
+	case v32.ClusterActionResolvedImages:
+		return a.ResolvedImages(actionName, action, apiContext)
 	case v32.ClusterActionRestoreFromEtcdBackup:
 		if !canUpdateCluster() {
 			return httperror.NewAPIError(httperror.PermissionDenied, "can not restore etcd backup")
@@ -104,6 +125,11 @@ func (a ActionHandler) ClusterActionHandler(actionName string, action *types.Act
 			return httperror.NewAPIError(httperror.PermissionDenied, "can not rotate encryption key")
 		}
 		return a.RotateEncryptionKey(actionName, action, apiContext)
+	case v32.ClusterActionRotateServiceAccountToken:
+		if !canUpdateCluster() {
+			return httperror.NewAPIError(httperror.PermissionDenied, "can not rotate service account token")
+		}
+		return a.RotateServiceAccountToken(actionName, action, apiContext)
 	case v32.ClusterActionRunSecurityScan:
 		return a.runCisScan(actionName, action, apiContext)
 	case v32.ClusterActionSaveAsTemplate:
@@ -114,6 +140,11 @@ func (a ActionHandler) ClusterActionHandler(actionName string, action *types.Act
 			return httperror.NewAPIError(httperror.PermissionDenied, "can not save the cluster as an RKETemplate")
 		}
 		return a.saveAsTemplate(actionName, action, apiContext)
+	case v32.ClusterActionSetMembers:
+		if !canUpdateCluster() {
+			return httperror.NewAPIError(httperror.PermissionDenied, "can not access")
+		}
+		return a.MemberSetter.SetMembers(apiContext, apiContext.ID)
 	}
 	return httperror.NewAPIError(httperror.NotFound, "not found")
 }
@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResolvedImages returns the images Rancher deploys to the cluster, so that the completeness of the mirror of an
+// air-gapped setup can be checked before provisioning
+func (a ActionHandler) ResolvedImages(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	var mgmtCluster mgmtv3.Cluster
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, &mgmtCluster); err != nil {
+		return httperror.NewAPIError(httperror.NotFound, "cluster does not exist")
+	}
+
+	cluster, err := a.ClusterClient.Get(apiContext.ID, v1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get cluster by ID %s", apiContext.ID)
+	}
+
+	images, err := a.ImageResolver.Resolve(cluster)
+	if err != nil {
+		return httperror.WrapAPIError(err, httperror.ServerError, "failed to resolve the images of the cluster")
+	}
+
+	res, err := json.Marshal(map[string]interface{}{
+		"type":                                   v3client.ResolvedImagesOutputType,
+		v3client.ResolvedImagesOutputFieldImages: images,
+	})
+	if err != nil {
+		return err
+	}
+
+	apiContext.Response.Header().Set("Content-Type", "application/json")
+	http.ServeContent(apiContext.Response, apiContext.Request, v3.ClusterActionResolvedImages, time.Now(), bytes.NewReader(res))
+	return nil
+}
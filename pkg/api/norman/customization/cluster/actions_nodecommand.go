@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/clusterregistrationtoken"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const defaultTokenName = "default-token"
+
+// NodeCommand returns the command registering a node in the cluster for the OS and runtime of the input, as shown
+// in the status of the registration tokens of the cluster
+func (a ActionHandler) NodeCommand(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	var mgmtCluster mgmtv3.Cluster
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, &mgmtCluster); err != nil {
+		return httperror.NewAPIError(httperror.NotFound, "cluster does not exist")
+	}
+
+	data, err := ioutil.ReadAll(apiContext.Request.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read request body")
+	}
+	input := v3client.NodeCommandInput{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &input); err != nil {
+			return httperror.NewAPIError(httperror.InvalidBodyContent, "failed to parse request content")
+		}
+	}
+
+	cluster, err := a.ClusterClient.Get(apiContext.ID, v1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get cluster by ID %s", apiContext.ID)
+	}
+
+	token, err := a.registrationToken(cluster.Name)
+	if err != nil {
+		return err
+	}
+
+	command, err := clusterregistrationtoken.NodeCommandFor(cluster, token, input.OS, input.Runtime, input.Insecure)
+	if err != nil {
+		return httperror.NewAPIError(httperror.InvalidOption, err.Error())
+	}
+
+	res, err := json.Marshal(map[string]interface{}{
+		"type":                                 v3client.NodeCommandOutputType,
+		v3client.NodeCommandOutputFieldCommand: command,
+	})
+	if err != nil {
+		return err
+	}
+
+	apiContext.Response.Header().Set("Content-Type", "application/json")
+	http.ServeContent(apiContext.Response, apiContext.Request, v3.ClusterActionNodeCommand, time.Now(), bytes.NewReader(res))
+	return nil
+}
+
+// registrationToken returns the token agents should use of the default registration token of the cluster, falling
+// back to the first registration token with a token by name
+func (a ActionHandler) registrationToken(clusterID string) (string, error) {
+	crts, err := a.ClusterRegistrationTokenLister.List(clusterID, labels.Everything())
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list registration tokens of cluster %s", clusterID)
+	}
+
+	sort.Slice(crts, func(i, j int) bool {
+		return crts[i].Name < crts[j].Name
+	})
+	var token string
+	for _, crt := range crts {
+		if crt.AgentToken() == "" {
+			continue
+		}
+		if crt.Name == defaultTokenName {
+			return crt.AgentToken(), nil
+		}
+		if token == "" {
+			token = crt.AgentToken()
+		}
+	}
+	if token == "" {
+		return "", httperror.NewAPIError(httperror.NotFound, "cluster has no registration token")
+	}
+	return token, nil
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/clustermanager"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (a ActionHandler) RotateServiceAccountToken(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	response := map[string]interface{}{
+		"type": v3client.RotateServiceAccountTokenOutputType,
+		v3client.RotateServiceAccountTokenOutputFieldMessage: "service account token rotated",
+	}
+
+	var mgmtCluster mgmtv3.Cluster
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, &mgmtCluster); err != nil {
+		response[v3client.RotateServiceAccountTokenOutputFieldMessage] = "cluster does not exist"
+		apiContext.WriteResponse(http.StatusBadRequest, response)
+		return errors.Wrapf(err, "failed to get cluster by ID %s", apiContext.ID)
+	}
+
+	cluster, err := a.ClusterClient.Get(apiContext.ID, v1.GetOptions{})
+	if err != nil {
+		response[v3client.RotateServiceAccountTokenOutputFieldMessage] = "cluster does not exist"
+		apiContext.WriteResponse(http.StatusBadRequest, response)
+		return errors.Wrapf(err, "failed to get cluster by ID %s", apiContext.ID)
+	}
+
+	if !clustermanager.CanRotateServiceAccountToken(cluster) {
+		return httperror.NewAPIError(httperror.InvalidAction, "service account token can only be rotated for imported and hosted clusters")
+	}
+
+	if !v3.ClusterConditionReady.IsTrue(cluster) {
+		return httperror.NewAPIError(httperror.InvalidAction, "cluster is not ready")
+	}
+
+	revoked, err := a.ClusterManager.RotateServiceAccountToken(apiContext.Request.Context(), cluster.Name)
+	if err != nil {
+		response[v3client.RotateServiceAccountTokenOutputFieldMessage] = err.Error()
+		apiContext.WriteResponse(http.StatusInternalServerError, response)
+		return errors.Wrapf(err, "unable to rotate service account token of cluster %s", cluster.Name)
+	}
+	if !revoked {
+		response[v3client.RotateServiceAccountTokenOutputFieldMessage] = "service account token rotated, but the old " +
+			"token was not revoked: it doesn't belong to a service account managed by Rancher and remains valid until " +
+			"its secret is deleted in the cluster"
+	}
+
+	res, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	apiContext.Response.Header().Set("Content-Type", "application/json")
+	http.ServeContent(apiContext.Response, apiContext.Request, v3.ClusterActionRotateServiceAccountToken, time.Now(), bytes.NewReader(res))
+	return nil
+}
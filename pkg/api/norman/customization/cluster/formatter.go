@@ -8,6 +8,7 @@
 	"github.com/rancher/norman/types/values"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/clustermanager"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	managementschema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -61,6 +62,8 @@ func (f *Formatter) Formatter(request *types.APIContext, resource *types.RawReso
 	resource.Links["shell"] = shellLink
 	resource.AddAction(request, v32.ClusterActionGenerateKubeconfig)
 	resource.AddAction(request, v32.ClusterActionImportYaml)
+	resource.AddAction(request, v32.ClusterActionNodeCommand)
+	resource.AddAction(request, v32.ClusterActionResolvedImages)
 	if _, ok := resource.Values["rancherKubernetesEngineConfig"]; ok {
 		resource.AddAction(request, v32.ClusterActionExportYaml)
 		resource.AddAction(request, v32.ClusterActionRotateCertificates)
@@ -89,6 +92,7 @@ func (f *Formatter) Formatter(request *types.APIContext, resource *types.RawReso
 	}
 
 	if err := request.AccessControl.CanDo(v3.ClusterGroupVersionKind.Group, v3.ClusterResource.Name, "update", request, resource.Values, request.Schema); err == nil {
+		resource.AddAction(request, v32.ClusterActionSetMembers)
 		if convert.ToBool(resource.Values["enableClusterMonitoring"]) {
 			resource.AddAction(request, v32.ClusterActionDisableMonitoring)
 			resource.AddAction(request, v32.ClusterActionEditMonitoring)
@@ -108,6 +112,10 @@ func (f *Formatter) Formatter(request *types.APIContext, resource *types.RawReso
 		resource.AddAction(request, v32.ClusterActionViewMonitoring)
 	}
 
+	if rotateServiceAccountTokenEnabled(f.clusterLister, resource.ID) && canUserUpdateCluster(request, resource) {
+		resource.AddAction(request, v32.ClusterActionRotateServiceAccountToken)
+	}
+
 	if gkeConfig, ok := resource.Values["googleKubernetesEngineConfig"]; ok && gkeConfig != nil {
 		configMap, ok := gkeConfig.(map[string]interface{})
 		if !ok {
@@ -178,6 +186,16 @@ func rotateEncryptionKeyEnabled(clusterLister v3.ClusterLister, clusterName stri
 	return v32.ClusterConditionUpdated.IsTrue(cluster)
 }
 
+// rotateServiceAccountTokenEnabled returns true if the rotateServiceAccountToken action should be enabled in the API view,
+// otherwise, it returns false.
+func rotateServiceAccountTokenEnabled(clusterLister v3.ClusterLister, clusterName string) bool {
+	cluster, err := clusterLister.Get("", clusterName)
+	if err != nil {
+		return false
+	}
+	return clustermanager.CanRotateServiceAccountToken(cluster) && v32.ClusterConditionReady.IsTrue(cluster)
+}
+
 func setTrueIfNil(configMap map[string]interface{}, fieldName string) {
 	if configMap[fieldName] == nil {
 		configMap[fieldName] = true
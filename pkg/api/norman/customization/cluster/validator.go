@@ -13,9 +13,12 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
 	gaccess "github.com/rancher/rancher/pkg/api/norman/customization/globalnamespaceaccess"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	mgmtclient "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/clusterregistrationtoken"
 	"github.com/rancher/rancher/pkg/controllers/management/k3sbasedupgrade"
 	"github.com/rancher/rancher/pkg/controllers/managementuserlegacy/cis"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
@@ -68,6 +71,22 @@ func (v *Validator) Validator(request *types.APIContext, schema *types.Schema, d
 		return err
 	}
 
+	if err := validateAgentNodeCommandCustomization(&clusterSpec); err != nil {
+		return err
+	}
+
+	if err := validateAgentEnvVars(&clusterSpec); err != nil {
+		return err
+	}
+
+	if err := v.validateExecAuth(request, schema, data, &clusterSpec); err != nil {
+		return err
+	}
+
+	if err := clusterregistrationtoken.ValidateAgentImageOverride(clusterSpec.AgentImageOverride); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, "agentImageOverride", err.Error())
+	}
+
 	if err := v.validateGenericEngineConfig(request, &clusterSpec); err != nil {
 		return err
 	}
@@ -76,7 +95,7 @@ func (v *Validator) Validator(request *types.APIContext, schema *types.Schema, d
 		return err
 	}
 
-	if err := v.validateEKSConfig(request, data, &clusterSpec); err != nil {
+	if err := v.validateEKSConfig(request, schema, data, &clusterSpec); err != nil {
 		return err
 	}
 
@@ -176,6 +195,52 @@ func (v *Validator) validateLocalClusterAuthEndpoint(request *types.APIContext,
 	return nil
 }
 
+func validateAgentNodeCommandCustomization(spec *v32.ClusterSpec) error {
+	if err := clusterregistrationtoken.ValidateAgentNodeCommandCustomization(spec.AgentNodeCommandCustomization); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, "agentNodeCommandCustomization", err.Error())
+	}
+	return nil
+}
+
+// validateAgentEnvVars refuses the agent env vars taking their value from a secret for RKE clusters, whose nodes are
+// registered with the docker node command
+func validateAgentEnvVars(spec *v32.ClusterSpec) error {
+	if spec.RancherKubernetesEngineConfig == nil {
+		return nil
+	}
+	if err := clusterregistrationtoken.ValidateDockerAgentEnvVars(spec.AgentEnvVars); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, "agentEnvVars", err.Error())
+	}
+	return nil
+}
+
+// validateExecAuth only lets the admins configure the exec credential plugin of the cluster, as the plugin runs on the
+// Rancher server with the arguments and environment set by the cluster
+// validateExecAuth only lets admins configure the exec auth of a cluster and records the admin on the cluster, the exec
+// credential plugin is only run while that admin approved the current exec auth
+func (v *Validator) validateExecAuth(request *types.APIContext, schema *types.Schema, data map[string]interface{}, spec *v32.ClusterSpec) error {
+	if spec.ExecAuth == nil {
+		return nil
+	}
+	if request.ID != "" {
+		cluster, err := v.ClusterLister.Get("", request.ID)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(cluster.Spec.ExecAuth, spec.ExecAuth) {
+			return nil
+		}
+	}
+	if err := request.AccessControl.CanDo("*", "*", "*", request, nil, schema); err != nil {
+		return httperror.NewFieldAPIError(httperror.PermissionDenied, "execAuth", "only admins may configure the exec auth of a cluster")
+	}
+	if err := execauth.Validate(spec.ExecAuth); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, "execAuth", err.Error())
+	}
+	values.PutValue(data, execauth.Approval(request.Request.Header.Get("Impersonate-User"), spec.ExecAuth), "annotations", execauth.ApprovalAnnotation)
+	return nil
+}
+
 func (v *Validator) validateEnforcement(request *types.APIContext, data map[string]interface{}) error {
 
 	if !strings.EqualFold(settings.ClusterTemplateEnforcement.Get(), "true") {
@@ -485,7 +550,7 @@ func validateAKSClusterName(client v3.ClusterInterface, spec *v32.ClusterSpec) e
 	return nil
 }
 
-func (v *Validator) validateEKSConfig(request *types.APIContext, cluster map[string]interface{}, clusterSpec *v32.ClusterSpec) error {
+func (v *Validator) validateEKSConfig(request *types.APIContext, schema *types.Schema, cluster map[string]interface{}, clusterSpec *v32.ClusterSpec) error {
 	eksConfig, ok := cluster["eksConfig"].(map[string]interface{})
 	if !ok {
 		return nil
@@ -502,10 +567,13 @@ func (v *Validator) validateEKSConfig(request *types.APIContext, cluster map[str
 	}
 
 	// check user's access to cloud credential
-	if amazonCredential, ok := eksConfig["amazonCredentialSecret"].(string); ok {
+	// an empty cloud credential means the default AWS credential chain of Rancher is used
+	if amazonCredential, _ := eksConfig["amazonCredentialSecret"].(string); amazonCredential != "" {
 		if err := validateEKSCredentialAuth(request, amazonCredential, prevCluster); err != nil {
 			return err
 		}
+	} else if err := validateEKSDefaultCredentialChain(request, schema, prevCluster); err != nil {
+		return err
 	}
 
 	createFromImport := request.Method == http.MethodPost && eksConfig["imported"] == true
@@ -602,6 +670,21 @@ func validateEKSKubernetesVersion(spec *v32.ClusterSpec, prevCluster *v3.Cluster
 // validateEKSCredentialAuth validates that a user has access to the credential they are setting and the credential
 // they are overwriting. If there is no previous credential such as during a create or the old credential cannot
 // be found, the auth check will succeed as long as the user can access the new credential.
+// validateEKSDefaultCredentialChain only lets admins configure an EKS cluster without a cloud credential, and only once
+// the default AWS credential chain of Rancher is enabled for EKS clusters.
+func validateEKSDefaultCredentialChain(request *types.APIContext, schema *types.Schema, prevCluster *v3.Cluster) error {
+	if prevCluster != nil && prevCluster.Spec.EKSConfig != nil && prevCluster.Spec.EKSConfig.AmazonCredentialSecret == "" {
+		return nil
+	}
+	if !strings.EqualFold(settings.EKSDefaultCredentialChain.Get(), "true") {
+		return httperror.NewFieldAPIError(httperror.MissingRequired, "amazonCredentialSecret", "an amazon cloud credential is required")
+	}
+	if err := request.AccessControl.CanDo("*", "*", "*", request, nil, schema); err != nil {
+		return httperror.NewFieldAPIError(httperror.PermissionDenied, "amazonCredentialSecret", "only admins may use the default AWS credential chain of Rancher")
+	}
+	return nil
+}
+
 func validateEKSCredentialAuth(request *types.APIContext, credential string, prevCluster *v3.Cluster) error {
 	var accessCred mgmtclient.CloudCredential
 	credentialErr := "error accessing cloud credential"
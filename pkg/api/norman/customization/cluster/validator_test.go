@@ -2,10 +2,22 @@
 
 import (
 	"encoding/json"
+	"net/http"
 	"testing"
 
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/values"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	mgmtclient "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const clusterSpecJSON = `
@@ -191,3 +203,110 @@ func TestClusterTemplateValidateScheduledClusterScan(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+type adminAccessControl struct {
+	types.AccessControl
+	admin bool
+}
+
+func (a adminAccessControl) CanDo(apiGroup, resource, verb string, apiContext *types.APIContext, obj map[string]interface{}, schema *types.Schema) error {
+	if a.admin && apiGroup == "*" && resource == "*" && verb == "*" {
+		return nil
+	}
+	return httperror.NewAPIError(httperror.PermissionDenied, "can not do it")
+}
+
+func TestValidateExecAuth(t *testing.T) {
+	execAuth := &v32.ClusterExecAuth{Command: "/usr/local/bin/kubelogin", Args: []string{"get-token"}}
+	v := &Validator{
+		ClusterLister: &fakes.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Cluster, error) {
+				return &v3.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec:       v32.ClusterSpec{ClusterSpecBase: v32.ClusterSpecBase{ExecAuth: execAuth}},
+				}, nil
+			},
+		},
+	}
+	orig := settings.ClusterExecAuthCommands.Get()
+	assert.NoError(t, settings.ClusterExecAuthCommands.Set(`[{"command": "/usr/local/bin/kubelogin", "args": ["get-token", "--oidc-issuer-url=*"]}]`))
+	defer settings.ClusterExecAuthCommands.Set(orig)
+
+	tests := []struct {
+		name         string
+		id           string
+		admin        bool
+		execAuth     *v32.ClusterExecAuth
+		wantErr      bool
+		wantApproval bool
+	}{
+		{name: "no exec auth"},
+		{name: "admin", admin: true, execAuth: execAuth, wantApproval: true},
+		{name: "admin with args not allowed", admin: true, execAuth: &v32.ClusterExecAuth{Command: "/usr/local/bin/kubelogin", Args: []string{"--exec=/bin/sh"}}, wantErr: true},
+		{name: "admin with command not allowed", admin: true, execAuth: &v32.ClusterExecAuth{Command: "/bin/sh"}, wantErr: true},
+		{name: "member", execAuth: execAuth, wantErr: true},
+		{name: "unchanged by member", id: "c-abcde", execAuth: execAuth},
+		{name: "changed by member", id: "c-abcde", execAuth: &v32.ClusterExecAuth{Command: "/usr/local/bin/kubelogin", Args: []string{"--exec=/bin/sh"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &types.APIContext{
+				ID:            tt.id,
+				AccessControl: adminAccessControl{admin: tt.admin},
+				Request:       &http.Request{Header: http.Header{"Impersonate-User": []string{"u-admin"}}},
+			}
+			data := map[string]interface{}{}
+			spec := &v32.ClusterSpec{ClusterSpecBase: v32.ClusterSpecBase{ExecAuth: tt.execAuth}}
+			err := v.validateExecAuth(request, &types.Schema{ID: "cluster"}, data, spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			approval := values.GetValueN(data, "annotations", execauth.ApprovalAnnotation)
+			if tt.wantApproval {
+				assert.Equal(t, execauth.Approval("u-admin", tt.execAuth), approval)
+			} else {
+				assert.Nil(t, approval)
+			}
+		})
+	}
+}
+
+func TestValidateEKSDefaultCredentialChain(t *testing.T) {
+	defaultChainCluster := &v3.Cluster{Spec: v32.ClusterSpec{EKSConfig: &eksv1.EKSClusterConfigSpec{}}}
+	credentialCluster := &v3.Cluster{Spec: v32.ClusterSpec{EKSConfig: &eksv1.EKSClusterConfigSpec{AmazonCredentialSecret: "cattle-global-data:cc-abcde"}}}
+
+	tests := []struct {
+		name        string
+		enabled     bool
+		admin       bool
+		prevCluster *v3.Cluster
+		wantErr     bool
+	}{
+		{name: "disabled", admin: true, wantErr: true},
+		{name: "admin", enabled: true, admin: true},
+		{name: "member", enabled: true, wantErr: true},
+		{name: "member removing the cloud credential", enabled: true, prevCluster: credentialCluster, wantErr: true},
+		{name: "member keeping the default credential chain", prevCluster: defaultChainCluster},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := "false"
+			if tt.enabled {
+				value = "true"
+			}
+			orig := settings.EKSDefaultCredentialChain.Get()
+			assert.NoError(t, settings.EKSDefaultCredentialChain.Set(value))
+			defer settings.EKSDefaultCredentialChain.Set(orig)
+
+			request := &types.APIContext{AccessControl: adminAccessControl{admin: tt.admin}}
+			err := validateEKSDefaultCredentialChain(request, &types.Schema{ID: "cluster"}, tt.prevCluster)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
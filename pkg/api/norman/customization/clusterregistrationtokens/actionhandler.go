@@ -0,0 +1,85 @@
+package clusterregistrationtokens
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/randomtoken"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ActionHandler struct {
+	ClusterRegistrationTokens v3.ClusterRegistrationTokenInterface
+}
+
+func Formatter(request *types.APIContext, resource *types.RawResource) {
+	if err := request.AccessControl.CanDo(v3.ClusterRegistrationTokenGroupVersionKind.Group, v3.ClusterRegistrationTokenResource.Name, "update", request, resource.Values, request.Schema); err == nil {
+		resource.AddAction(request, v32.ClusterRegistrationTokenActionRegenerateManifest)
+	}
+}
+
+func (a ActionHandler) ClusterRegistrationTokenActionHandler(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	if actionName != v32.ClusterRegistrationTokenActionRegenerateManifest {
+		return httperror.NewAPIError(httperror.NotFound, "not found")
+	}
+
+	var crtData map[string]interface{}
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, &crtData); err != nil {
+		return err
+	}
+	if err := apiContext.AccessControl.CanDo(v3.ClusterRegistrationTokenGroupVersionKind.Group, v3.ClusterRegistrationTokenResource.Name, "update", apiContext, crtData, apiContext.Schema); err != nil {
+		return httperror.NewAPIError(httperror.PermissionDenied, "can not regenerate the import manifest")
+	}
+
+	namespace, name := ref.Parse(apiContext.ID)
+	crt, err := a.ClusterRegistrationTokens.GetNamespaced(namespace, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	crt = crt.DeepCopy()
+	if err := regenerate(crt, time.Now(), gracePeriod()); err != nil {
+		return err
+	}
+	if _, err := a.ClusterRegistrationTokens.Update(crt); err != nil {
+		return err
+	}
+
+	apiContext.WriteResponse(http.StatusOK, nil)
+	return nil
+}
+
+// regenerate replaces the token serving the import manifest, so that its previous URL stops working and the manifest
+// can be retrieved again. A rotation in progress is completed first, as the agents may already use its next token, and
+// the token they use is still accepted during the grace period of the rotations.
+func regenerate(crt *v3.ClusterRegistrationToken, now time.Time, grace time.Duration) error {
+	token, err := randomtoken.Generate()
+	if err != nil {
+		return err
+	}
+
+	if crt.Status.NextToken != "" {
+		crt.PromoteToken(crt.Status.NextToken, now, grace)
+	}
+	crt.PromoteToken(token, now, grace)
+	crt.Status.ManifestFetchCount = 0
+	crt.Status.ManifestFirstFetchedAt = ""
+	crt.Status.ManifestLastFetchedAt = ""
+	return nil
+}
+
+func gracePeriod() time.Duration {
+	seconds, err := strconv.Atoi(settings.ClusterRegistrationTokenGrace.Get())
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
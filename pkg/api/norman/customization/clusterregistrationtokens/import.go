@@ -1,21 +1,35 @@
 package clusterregistrationtokens
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/urlbuilder"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/image"
 	schema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/systemtemplate"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+var (
+	errManifestUsed = errors.New("the import manifest was already retrieved, it has to be regenerated")
+
+	systemTemplate = systemtemplate.SystemTemplate
 )
 
 type ClusterImport struct {
-	Clusters v3.ClusterInterface
+	Clusters                       v3.ClusterInterface
+	ClusterRegistrationTokens      v3.ClusterRegistrationTokenInterface
+	ClusterRegistrationTokenLister v3.ClusterRegistrationTokenLister
 }
 
 func (ch *ClusterImport) ClusterImportHandler(resp http.ResponseWriter, req *http.Request) {
@@ -40,14 +54,95 @@ func (ch *ClusterImport) ClusterImportHandler(resp http.ResponseWriter, req *htt
 		authImage = authImages[0]
 	}
 
+	// the pre-flight Job is added unless opted out with ?preflight=false
+	preflight := req.URL.Query().Get("preflight") != "false"
+
 	var cluster *v3.Cluster
 	if clusterID != "" {
 		cluster, _ = ch.Clusters.Get(clusterID, metav1.GetOptions{})
 	}
 
-	if err := systemtemplate.SystemTemplate(resp, image.Resolve(settings.AgentImage.Get()), authImage, "", token, url,
-		false, cluster, nil, nil); err != nil {
+	crt, err := ch.manifestToken(clusterID, token)
+	if err != nil {
 		resp.WriteHeader(500)
 		resp.Write([]byte(err.Error()))
+		return
+	}
+	if crt == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte("import manifest not found"))
+		return
 	}
+
+	if settings.ImportManifestActiveGate.Get() == "true" && cluster != nil && v32.ClusterConditionReady.IsTrue(cluster) {
+		resp.WriteHeader(http.StatusGone)
+		resp.Write([]byte("the cluster is active, its import manifest is no longer served"))
+		return
+	}
+
+	// the manifest is rendered before the retrieval is recorded so that a failed rendering doesn't use up a single use
+	// manifest
+	var manifest bytes.Buffer
+	if err := systemTemplate(&manifest, image.Resolve(settings.AgentImage.Get()), authImage, "", token, url,
+		false, preflight, cluster, nil, nil); err != nil {
+		resp.WriteHeader(500)
+		resp.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := ch.recordFetch(crt, time.Now()); errors.Is(err, errManifestUsed) {
+		resp.WriteHeader(http.StatusGone)
+		resp.Write([]byte(err.Error()))
+		return
+	} else if err != nil {
+		resp.WriteHeader(500)
+		resp.Write([]byte(err.Error()))
+		return
+	}
+
+	resp.Write(manifest.Bytes())
+}
+
+// manifestToken returns the cluster registration token of the cluster whose manifest is served with the token, or
+// nil if there is none. The manifest is served with the token and the next token of a rotation, the tokens replaced by
+// a rotation or a regeneration no longer serve it.
+func (ch *ClusterImport) manifestToken(clusterID, token string) (*v3.ClusterRegistrationToken, error) {
+	if clusterID == "" || token == "" {
+		return nil, nil
+	}
+	crts, err := ch.ClusterRegistrationTokenLister.List(clusterID, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, crt := range crts {
+		if crt.Status.Token == token || crt.Status.NextToken == token {
+			return crt, nil
+		}
+	}
+	return nil, nil
+}
+
+// recordFetch records the retrieval of the manifest on the status of the token, it fails with errManifestUsed if the
+// manifest of a single use token was already retrieved. The update is rejected on conflict so that concurrent
+// retrievals of a single use manifest can't both succeed.
+func (ch *ClusterImport) recordFetch(crt *v3.ClusterRegistrationToken, now time.Time) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		crt, err := ch.ClusterRegistrationTokens.GetNamespaced(crt.Namespace, crt.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if crt.Spec.SingleUseManifest && crt.Status.ManifestFetchCount > 0 {
+			return errManifestUsed
+		}
+
+		crt = crt.DeepCopy()
+		fetchedAt := now.UTC().Format(time.RFC3339)
+		if crt.Status.ManifestFirstFetchedAt == "" {
+			crt.Status.ManifestFirstFetchedAt = fetchedAt
+		}
+		crt.Status.ManifestLastFetchedAt = fetchedAt
+		crt.Status.ManifestFetchCount++
+		_, err = ch.ClusterRegistrationTokens.Update(crt)
+		return err
+	})
 }
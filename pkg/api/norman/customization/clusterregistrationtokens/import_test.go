@@ -0,0 +1,177 @@
+package clusterregistrationtokens
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/systemtemplate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// newClusterImport serves the manifest of the token of the cluster c-abcde, the token is updated in place
+func newClusterImport(crt *v3.ClusterRegistrationToken, cluster *v3.Cluster) *ClusterImport {
+	return &ClusterImport{
+		Clusters: &fakes.ClusterInterfaceMock{
+			GetFunc: func(name string, opts metav1.GetOptions) (*v3.Cluster, error) {
+				return cluster, nil
+			},
+		},
+		ClusterRegistrationTokens: &fakes.ClusterRegistrationTokenInterfaceMock{
+			GetNamespacedFunc: func(namespace, name string, opts metav1.GetOptions) (*v3.ClusterRegistrationToken, error) {
+				if namespace != crt.Namespace || name != crt.Name {
+					return nil, apierrors.NewNotFound(v3.ClusterRegistrationTokenGroupVersionResource.GroupResource(), name)
+				}
+				return crt.DeepCopy(), nil
+			},
+			UpdateFunc: func(in1 *v3.ClusterRegistrationToken) (*v3.ClusterRegistrationToken, error) {
+				*crt = *in1.DeepCopy()
+				return in1, nil
+			},
+		},
+		ClusterRegistrationTokenLister: &fakes.ClusterRegistrationTokenListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.ClusterRegistrationToken, error) {
+				if namespace != crt.Namespace {
+					return nil, nil
+				}
+				return []*v3.ClusterRegistrationToken{crt.DeepCopy()}, nil
+			},
+		},
+	}
+}
+
+func newToken(singleUse bool) *v3.ClusterRegistrationToken {
+	return &v3.ClusterRegistrationToken{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "default-token"},
+		Spec: v32.ClusterRegistrationTokenSpec{
+			ClusterName:       "c-abcde",
+			SingleUseManifest: singleUse,
+		},
+		Status: v32.ClusterRegistrationTokenStatus{Token: "abcdefghij"},
+	}
+}
+
+func getManifest(ch *ClusterImport, token string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.Handle("/v3/import/{token}_{clusterId}.yaml", http.HandlerFunc(ch.ClusterImportHandler))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v3/import/"+token+"_c-abcde.yaml", nil))
+	return rec
+}
+
+func TestImportManifestSingleUse(t *testing.T) {
+	crt := newToken(true)
+	ch := newClusterImport(crt, &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}})
+
+	rec := getManifest(ch, "abcdefghij")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "kind: Deployment")
+
+	rec = getManifest(ch, "abcdefghij")
+	assert.Equal(t, http.StatusGone, rec.Code, "expected a single use manifest to be served once")
+	assert.Equal(t, 1, crt.Status.ManifestFetchCount)
+
+	require.NoError(t, regenerate(crt, time.Now(), time.Hour))
+	assert.Equal(t, "abcdefghij", crt.Status.PreviousToken, "expected the replaced token to be accepted during the grace period")
+
+	rec = getManifest(ch, "abcdefghij")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "expected the URL of the replaced token to stop working")
+
+	rec = getManifest(ch, crt.Status.Token)
+	assert.Equal(t, http.StatusOK, rec.Code, "expected the regenerated manifest to be served")
+}
+
+func TestImportManifestRegenerateDuringRotation(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	crt := newToken(true)
+	crt.Status.NextToken = "klmnopqrst"
+	crt.Status.NextTokenIssuedAt = now.Add(-time.Minute).Format(time.RFC3339)
+	ch := newClusterImport(crt, &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}})
+
+	require.NoError(t, regenerate(crt, now, time.Hour))
+	assert.Empty(t, crt.Status.NextToken, "expected the rotation in progress not to promote its next token over the regenerated one")
+	assert.Empty(t, crt.Status.NextTokenIssuedAt)
+	assert.NotContains(t, []string{"", "abcdefghij", "klmnopqrst"}, crt.Status.Token)
+	assert.Equal(t, "klmnopqrst", crt.Status.PreviousToken, "expected the token the agents switched to to be kept during the grace period")
+	assert.True(t, crt.AcceptsToken("klmnopqrst", now.Add(59*time.Minute)))
+	assert.False(t, crt.AcceptsToken("abcdefghij", now))
+
+	for _, token := range []string{"abcdefghij", "klmnopqrst"} {
+		rec := getManifest(ch, token)
+		assert.Equal(t, http.StatusNotFound, rec.Code, "expected the URL of the replaced token %s to stop working", token)
+	}
+	rec := getManifest(ch, crt.Status.Token)
+	assert.Equal(t, http.StatusOK, rec.Code, "expected the regenerated manifest to be served")
+}
+
+func TestImportManifestRenderFailure(t *testing.T) {
+	crt := newToken(true)
+	ch := newClusterImport(crt, &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}})
+
+	systemTemplate = func(io.Writer, string, string, string, string, string, bool, bool, *v3.Cluster, map[string]bool,
+		[]corev1.Taint) error {
+		return errors.New("render failed")
+	}
+	rec := getManifest(ch, "abcdefghij")
+	systemTemplate = systemtemplate.SystemTemplate
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Zero(t, crt.Status.ManifestFetchCount, "expected a failed rendering not to be recorded")
+
+	rec = getManifest(ch, "abcdefghij")
+	assert.Equal(t, http.StatusOK, rec.Code, "expected a single use manifest to be served after a failed rendering")
+	assert.Equal(t, 1, crt.Status.ManifestFetchCount)
+}
+
+func TestImportManifestActiveGate(t *testing.T) {
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	v32.ClusterConditionReady.True(cluster)
+	ch := newClusterImport(newToken(false), cluster)
+
+	rec := getManifest(ch, "abcdefghij")
+	assert.Equal(t, http.StatusOK, rec.Code, "expected the manifest of active clusters to be served unless gated")
+
+	require.NoError(t, settings.ImportManifestActiveGate.Set("true"))
+	defer settings.ImportManifestActiveGate.Set("false")
+
+	rec = getManifest(ch, "abcdefghij")
+	assert.Equal(t, http.StatusGone, rec.Code, "expected the manifest of an active cluster not to be served")
+
+	v32.ClusterConditionReady.False(cluster)
+	rec = getManifest(ch, "abcdefghij")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestImportManifestFetchStatus(t *testing.T) {
+	crt := newToken(false)
+	ch := newClusterImport(crt, nil)
+
+	first := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, ch.recordFetch(crt, first))
+	require.NoError(t, ch.recordFetch(crt, first.Add(time.Hour)))
+
+	assert.Equal(t, 2, crt.Status.ManifestFetchCount)
+	assert.Equal(t, "2021-06-01T12:00:00Z", crt.Status.ManifestFirstFetchedAt)
+	assert.Equal(t, "2021-06-01T13:00:00Z", crt.Status.ManifestLastFetchedAt)
+
+	require.NoError(t, regenerate(crt, first, 0))
+	assert.Zero(t, crt.Status.ManifestFetchCount, "expected the regeneration to reset the fetch count")
+	assert.Empty(t, crt.Status.ManifestFirstFetchedAt)
+	assert.Empty(t, crt.Status.PreviousToken, "expected the replaced token to be dropped without a grace period")
+
+	rec := getManifest(ch, "unknown")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -7,8 +7,10 @@
 	"github.com/rancher/norman/api/access"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/api/norman/customization/nodetemplate"
 	mgmtclient "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	nodehelper "github.com/rancher/rancher/pkg/node"
 	mgmtSchema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
 )
 
@@ -17,13 +19,19 @@ type Validator struct {
 }
 
 func (v *Validator) Validator(request *types.APIContext, schema *types.Schema, data map[string]interface{}) error {
+	if request.ID == "" {
+		if err := nodetemplate.ValidateSkipCredentialAccessCheck(request, schema, data, ""); err != nil {
+			return err
+		}
+	}
+
 	// validate access to nodetemplate
 	nodetemplateID, ok := data["nodeTemplateId"].(string)
-	if !ok {
-		// nodetemplate not passed, nothing to check
-		return nil
-	}
 	if request.ID == "" {
+		if !ok {
+			// nodetemplate not passed, nothing to check
+			return nil
+		}
 		// creating new pool, confirm access to template
 		return checkNodetemplateAccess(request, nodetemplateID)
 	}
@@ -39,6 +47,13 @@ func (v *Validator) Validator(request *types.APIContext, schema *types.Schema, d
 		return httperror.NewAPIError(httperror.NotFound, fmt.Sprintf("unable to find nodepool [%s]", request.ID))
 	}
 
+	if err := nodetemplate.ValidateSkipCredentialAccessCheck(request, schema, data, np.Annotations[nodehelper.SkipCredentialAccessCheckAnnotation]); err != nil {
+		return err
+	}
+	if !ok {
+		// nodetemplate not passed, nothing to check
+		return nil
+	}
 	if np.Spec.NodeTemplateName != nodetemplateID {
 		// pulling from lister failed, or update attempt to the nodetemplate
 		return checkNodetemplateAccess(request, nodetemplateID)
@@ -1,11 +1,16 @@
 package nodetemplate
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/rancher/norman/api/access"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	nodehelper "github.com/rancher/rancher/pkg/node"
 )
 
 func Validator(request *types.APIContext, schema *types.Schema, data map[string]interface{}) error {
@@ -16,6 +21,48 @@ func Validator(request *types.APIContext, schema *types.Schema, data map[string]
 	if data != nil {
 		data["driver"] = driver
 	}
+
+	if convert.ToBool(data[client.NodeTemplateFieldRequireImdsV2]) && driver != "amazonec2" {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, client.NodeTemplateFieldRequireImdsV2, "IMDSv2 may only be required for the amazonec2 driver")
+	}
+
+	if checksum := convert.ToString(data[client.NodeTemplateFieldEngineInstallURLChecksum]); checksum != "" {
+		if convert.ToString(data[client.NodeTemplateFieldEngineInstallURL]) == "" {
+			return httperror.NewAPIError(httperror.MissingRequired, "engineInstallURL must be set to be verified with engineInstallURLChecksum")
+		}
+		if !nodehelper.ValidEngineInstallURLChecksum(checksum) {
+			return httperror.NewAPIError(httperror.InvalidFormat, "engineInstallURLChecksum must be a sha256 checksum")
+		}
+	}
+
+	var current map[string]interface{}
+	if request.ID != "" {
+		if err := access.ByID(request, request.Version, request.Type, request.ID, &current); err != nil {
+			return err
+		}
+	}
+	return ValidateSkipCredentialAccessCheck(request, schema, data, convert.ToString(values.GetValueN(current, "annotations", nodehelper.SkipCredentialAccessCheckAnnotation)))
+}
+
+// ValidateSkipCredentialAccessCheck only lets the admins set or clear the annotation provisioning the nodes although
+// the creator of the node pool or node template can't access its cloud credential anymore, current is the value of the
+// annotation before the update. The annotation records the admin who set it, so that the node controller can check it.
+func ValidateSkipCredentialAccessCheck(request *types.APIContext, schema *types.Schema, data map[string]interface{}, current string) error {
+	annotations, ok := values.GetValueN(data, "annotations").(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	value := convert.ToString(annotations[nodehelper.SkipCredentialAccessCheckAnnotation])
+	if value == current {
+		return nil
+	}
+	if err := request.AccessControl.CanDo("*", "*", "*", request, nil, schema); err != nil {
+		return httperror.NewAPIError(httperror.PermissionDenied,
+			fmt.Sprintf("only admins may set or clear the annotation [%s]", nodehelper.SkipCredentialAccessCheckAnnotation))
+	}
+	if value != "" {
+		annotations[nodehelper.SkipCredentialAccessCheckAnnotation] = request.Request.Header.Get("Impersonate-User")
+	}
 	return nil
 }
 
@@ -17,6 +17,7 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/rancher/pkg/api/norman/customization/roletemplatebinding"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	"github.com/rancher/rancher/pkg/clustermanager"
 	"github.com/rancher/rancher/pkg/generated/compose"
@@ -33,6 +34,7 @@ func Formatter(apiContext *types.APIContext, resource *types.RawResource) {
 	resource.AddAction(apiContext, "exportYaml")
 
 	if err := apiContext.AccessControl.CanDo(v3.ProjectGroupVersionKind.Group, v3.ProjectResource.Name, "update", apiContext, resource.Values, apiContext.Schema); err == nil {
+		resource.AddAction(apiContext, "setMembers")
 		if convert.ToBool(resource.Values["enableProjectMonitoring"]) {
 			resource.AddAction(apiContext, "disableMonitoring")
 			resource.AddAction(apiContext, "editMonitoring")
@@ -53,6 +55,7 @@ type Handler struct {
 	ClusterLister     v3.ClusterLister
 	UserMgr           user.Manager
 	PSPTemplateLister v3.PodSecurityPolicyTemplateLister
+	MemberSetter      *roletemplatebinding.MemberSetter
 }
 
 func (h *Handler) Actions(actionName string, action *types.Action, apiContext *types.APIContext) error {
@@ -86,6 +89,11 @@ func (h *Handler) Actions(actionName string, action *types.Action, apiContext *t
 			return httperror.NewAPIError(httperror.Unauthorized, "can not access")
 		}
 		return h.disableMonitoring(actionName, action, apiContext)
+	case "setMembers":
+		if !canUpdateProject() {
+			return httperror.NewAPIError(httperror.Unauthorized, "can not access")
+		}
+		return h.MemberSetter.SetMembers(apiContext, apiContext.ID)
 	}
 
 	return errors.Errorf("unrecognized action %v", actionName)
@@ -0,0 +1,354 @@
+package roletemplatebinding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/providers"
+	"github.com/rancher/rancher/pkg/auth/requests"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/clusterrouter"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	managementschema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// setMembersLabel marks the bindings created by the setMembers action, they are the only bindings it removes
+	// unless the members are replaced
+	setMembersLabel = "authz.management.cattle.io/set-members"
+
+	MemberResultCreated = "created"
+	MemberResultExists  = "exists"
+	MemberResultRemoved = "removed"
+	MemberResultInvalid = "invalid"
+	MemberResultFailed  = "failed"
+
+	// maxConcurrentWrites is how many bindings are created or removed at once
+	maxConcurrentWrites = 10
+)
+
+// PrincipalResolver returns an error if the principal can't be resolved with the token of the request
+type PrincipalResolver func(apiContext *types.APIContext, principalID string) error
+
+// MemberSetter converges the role template bindings of a project or a cluster to a list of members
+type MemberSetter struct {
+	userLister       v3.UserLister
+	resolvePrincipal PrincipalResolver
+	bindingType      string
+	apiGroup         string
+	resource         string
+	targetField      string
+}
+
+func NewPRTBMemberSetter(ctx context.Context, management *config.ScaledContext) *MemberSetter {
+	return &MemberSetter{
+		userLister:       management.Management.Users("").Controller().Lister(),
+		resolvePrincipal: NewPrincipalResolver(ctx, management),
+		bindingType:      client.ProjectRoleTemplateBindingType,
+		apiGroup:         v3.ProjectRoleTemplateBindingGroupVersionKind.Group,
+		resource:         v3.ProjectRoleTemplateBindingResource.Name,
+		targetField:      client.ProjectRoleTemplateBindingFieldProjectID,
+	}
+}
+
+func NewCRTBMemberSetter(ctx context.Context, management *config.ScaledContext) *MemberSetter {
+	return &MemberSetter{
+		userLister:       management.Management.Users("").Controller().Lister(),
+		resolvePrincipal: NewPrincipalResolver(ctx, management),
+		bindingType:      client.ClusterRoleTemplateBindingType,
+		apiGroup:         v3.ClusterRoleTemplateBindingGroupVersionKind.Group,
+		resource:         v3.ClusterRoleTemplateBindingResource.Name,
+		targetField:      client.ClusterRoleTemplateBindingFieldClusterID,
+	}
+}
+
+// NewPrincipalResolver resolves the principals through the auth provider of the token of the request
+func NewPrincipalResolver(ctx context.Context, management *config.ScaledContext) PrincipalResolver {
+	auth := requests.NewAuthenticator(ctx, clusterrouter.GetClusterID, management)
+	return func(apiContext *types.APIContext, principalID string) error {
+		token, err := auth.TokenFromRequest(apiContext.Request)
+		if err != nil {
+			return err
+		}
+		_, err = providers.GetPrincipal(principalID, *token)
+		return err
+	}
+}
+
+// SetMembers handles the setMembers action of the project or cluster with the given ID. The members are validated as a
+// whole first, nothing is changed if any of them is invalid.
+func (m *MemberSetter) SetMembers(apiContext *types.APIContext, target string) error {
+	data, err := ioutil.ReadAll(apiContext.Request.Body)
+	if err != nil {
+		return httperror.WrapAPIError(err, httperror.InvalidBodyContent, "unable to read request content")
+	}
+	var input v32.SetMembersInput
+	if err = json.Unmarshal(data, &input); err != nil {
+		return httperror.WrapAPIError(err, httperror.InvalidBodyContent, "failed to parse request content")
+	}
+
+	schema := apiContext.Schemas.Schema(&managementschema.Version, m.bindingType)
+	if schema == nil {
+		return fmt.Errorf("no %v store available", m.bindingType)
+	}
+
+	output, valid, err := m.setMembers(apiContext, schema, target, input)
+	if err != nil {
+		return err
+	}
+
+	resp, err := convert.EncodeToMap(output)
+	if err != nil {
+		return httperror.WrapAPIError(err, httperror.ServerError, "failed to parse response")
+	}
+	resp["type"] = client.SetMembersOutputType
+
+	status := http.StatusOK
+	if !valid {
+		status = httperror.InvalidBodyContent.Status
+	}
+	apiContext.WriteResponse(status, resp)
+	return nil
+}
+
+// setMembers creates the bindings of the members that don't exist yet and removes the bindings that are no longer
+// listed. It returns false with the reason of each invalid member if any of them is invalid.
+func (m *MemberSetter) setMembers(apiContext *types.APIContext, schema *types.Schema, target string, input v32.SetMembersInput) (*v32.SetMembersOutput, bool, error) {
+	parts := strings.Split(target, ":")
+	scope := map[string]interface{}{
+		"namespaceId": parts[len(parts)-1],
+	}
+	if err := apiContext.AccessControl.CanDo(m.apiGroup, m.resource, "create", apiContext, scope, schema); err != nil {
+		return nil, false, httperror.NewAPIError(httperror.PermissionDenied, fmt.Sprintf("can not create %s", m.resource))
+	}
+
+	output := &v32.SetMembersOutput{}
+	valid := true
+	for _, member := range input.Members {
+		result := v32.RoleTemplateMemberResult{
+			UserPrincipalName:  member.UserPrincipalName,
+			GroupPrincipalName: member.GroupPrincipalName,
+			RoleTemplateName:   member.RoleTemplateName,
+		}
+		msg, err := m.validateMember(apiContext, schema, m.newBinding(target, member), member)
+		if err != nil {
+			return nil, false, err
+		}
+		if msg != "" {
+			result.Result = MemberResultInvalid
+			result.Message = msg
+			valid = false
+		}
+		output.Results = append(output.Results, result)
+	}
+	if !valid {
+		return output, false, nil
+	}
+
+	bindings, err := schema.Store.List(apiContext, schema, &types.QueryOptions{
+		Conditions: []*types.QueryCondition{
+			types.NewConditionFromString(m.targetField, types.ModifierEQ, target),
+		},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("error retrieving bindings: %v", err)
+	}
+
+	// the bindings are matched by principal, the principals of the users they bind by name included
+	existing := map[string]string{}
+	bindingKeys := make([][]string, len(bindings))
+	for i, binding := range bindings {
+		keys, err := m.bindingKeys(binding)
+		if err != nil {
+			return nil, false, err
+		}
+		bindingKeys[i] = keys
+		for _, key := range keys {
+			existing[key] = convert.ToString(binding["id"])
+		}
+	}
+
+	var writes writer
+	wanted := map[string]bool{}
+	for i, member := range input.Members {
+		key := memberKey(member.UserPrincipalName, member.GroupPrincipalName, member.RoleTemplateName)
+		if wanted[key] {
+			output.Results[i].Result = MemberResultExists
+			continue
+		}
+		wanted[key] = true
+		if id, ok := existing[key]; ok {
+			output.Results[i].BindingName = id
+			output.Results[i].Result = MemberResultExists
+			continue
+		}
+
+		result := &output.Results[i]
+		binding := m.newBinding(target, member)
+		writes.run(func() {
+			created, err := schema.Store.Create(apiContext, schema, binding)
+			if err != nil {
+				result.Result = MemberResultFailed
+				result.Message = err.Error()
+				return
+			}
+			result.BindingName = convert.ToString(created["id"])
+			result.Result = MemberResultCreated
+		})
+	}
+
+	var removed []v32.RoleTemplateMemberResult
+	var toRemove []map[string]interface{}
+	for i, binding := range bindings {
+		if anyWanted(wanted, bindingKeys[i]) {
+			continue
+		}
+		// bindings created by other means are left alone unless the members are replaced
+		if !input.Replace && convert.ToString(convert.ToMapInterface(binding["labels"])[setMembersLabel]) != "true" {
+			continue
+		}
+		removed = append(removed, v32.RoleTemplateMemberResult{
+			UserPrincipalName:  convert.ToString(binding["userPrincipalId"]),
+			GroupPrincipalName: convert.ToString(binding["groupPrincipalId"]),
+			RoleTemplateName:   convert.ToString(binding["roleTemplateId"]),
+			BindingName:        convert.ToString(binding["id"]),
+			Result:             MemberResultRemoved,
+		})
+		toRemove = append(toRemove, binding)
+	}
+	for i, binding := range toRemove {
+		result, binding := &removed[i], binding
+		writes.run(func() {
+			if err := apiContext.AccessControl.CanDo(m.apiGroup, m.resource, "delete", apiContext, binding, schema); err != nil {
+				result.Result = MemberResultFailed
+				result.Message = fmt.Sprintf("can not delete %s", result.BindingName)
+			} else if _, err := schema.Store.Delete(apiContext, schema, result.BindingName); err != nil && !apierrors.IsNotFound(err) {
+				result.Result = MemberResultFailed
+				result.Message = err.Error()
+			}
+		})
+	}
+	writes.wait()
+
+	output.Results = append(output.Results, removed...)
+	return output, true, nil
+}
+
+// newBinding returns the binding of the member created by the setMembers action
+func (m *MemberSetter) newBinding(target string, member v32.RoleTemplateMember) map[string]interface{} {
+	binding := map[string]interface{}{
+		m.targetField:    target,
+		"roleTemplateId": member.RoleTemplateName,
+		"labels": map[string]interface{}{
+			setMembersLabel: "true",
+		},
+	}
+	if member.UserPrincipalName != "" {
+		binding["userPrincipalId"] = member.UserPrincipalName
+	}
+	if member.GroupPrincipalName != "" {
+		binding["groupPrincipalId"] = member.GroupPrincipalName
+	}
+	return binding
+}
+
+// validateMember returns why the binding of the member is invalid, or an empty message if it is valid. The binding is
+// validated by the validator of its schema, like the bindings created one by one.
+func (m *MemberSetter) validateMember(apiContext *types.APIContext, schema *types.Schema, binding map[string]interface{}, member v32.RoleTemplateMember) (string, error) {
+	if schema.Validator != nil {
+		if err := schema.Validator(apiContext, schema, binding); err != nil {
+			apiErr, ok := err.(*httperror.APIError)
+			if !ok {
+				return "", err
+			}
+			return apiErr.Message, nil
+		}
+	}
+
+	principalID := member.UserPrincipalName
+	if principalID == "" {
+		principalID = member.GroupPrincipalName
+	}
+	if err := m.resolvePrincipal(apiContext, principalID); err != nil {
+		return fmt.Sprintf("principal [%s] cannot be resolved: %v", principalID, err), nil
+	}
+
+	return "", nil
+}
+
+// bindingKeys returns the member keys of the binding: the one of its principal, and the ones of the principals of the
+// user it binds by name
+func (m *MemberSetter) bindingKeys(binding map[string]interface{}) ([]string, error) {
+	roleTemplate := convert.ToString(binding["roleTemplateId"])
+	var keys []string
+	if group := convert.ToString(binding["groupPrincipalId"]); group != "" {
+		keys = append(keys, memberKey("", group, roleTemplate))
+	}
+	if group := convert.ToString(binding["groupId"]); group != "" {
+		keys = append(keys, memberKey("", group, roleTemplate))
+	}
+	if principal := convert.ToString(binding["userPrincipalId"]); principal != "" {
+		keys = append(keys, memberKey(principal, "", roleTemplate))
+	}
+	if userName := convert.ToString(binding["userId"]); userName != "" {
+		user, err := m.userLister.Get("", userName)
+		if apierrors.IsNotFound(err) {
+			return keys, nil
+		} else if err != nil {
+			return nil, httperror.NewAPIError(httperror.ServerError, fmt.Sprintf("Error getting user: %v", err))
+		}
+		for _, principal := range user.PrincipalIDs {
+			keys = append(keys, memberKey(principal, "", roleTemplate))
+		}
+	}
+	return keys, nil
+}
+
+func anyWanted(wanted map[string]bool, keys []string) bool {
+	for _, key := range keys {
+		if wanted[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func memberKey(userPrincipal, groupPrincipal, roleTemplate string) string {
+	return userPrincipal + "/" + groupPrincipal + "/" + roleTemplate
+}
+
+// writer runs the writes of the bindings concurrently, maxConcurrentWrites at a time
+type writer struct {
+	wg    sync.WaitGroup
+	slots chan struct{}
+}
+
+func (w *writer) run(f func()) {
+	if w.slots == nil {
+		w.slots = make(chan struct{}, maxConcurrentWrites)
+	}
+	w.wg.Add(1)
+	w.slots <- struct{}{}
+	go func() {
+		defer func() {
+			<-w.slots
+			w.wg.Done()
+		}()
+		f()
+	}()
+}
+
+func (w *writer) wait() {
+	w.wg.Wait()
+}
@@ -0,0 +1,241 @@
+package roletemplatebinding
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeBindingStore struct {
+	types.Store
+	sync.Mutex
+	bindings []map[string]interface{}
+	created  []map[string]interface{}
+	deleted  []string
+}
+
+func (f *fakeBindingStore) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
+	return f.bindings, nil
+}
+
+func (f *fakeBindingStore) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.created = append(f.created, data)
+	data["id"] = fmt.Sprintf("p-abcde:prtb-%d", len(f.created))
+	return data, nil
+}
+
+func (f *fakeBindingStore) Delete(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.deleted = append(f.deleted, id)
+	return nil, nil
+}
+
+type fakeAccessControl struct {
+	types.AccessControl
+}
+
+func (f fakeAccessControl) CanDo(apiGroup, resource, verb string, apiContext *types.APIContext, obj map[string]interface{}, schema *types.Schema) error {
+	return nil
+}
+
+// newTestSchema returns the schema of the project role template bindings stored in the store, validated by the
+// validator of the bindings created one by one
+func newTestSchema(store types.Store) *types.Schema {
+	roleTemplates := map[string]*v3.RoleTemplate{
+		"project-member": {ObjectMeta: metav1.ObjectMeta{Name: "project-member"}, Context: "project"},
+		"project-owner":  {ObjectMeta: metav1.ObjectMeta{Name: "project-owner"}, Context: "project"},
+		"locked":         {ObjectMeta: metav1.ObjectMeta{Name: "locked"}, Context: "project", Locked: true},
+		"cluster-member": {ObjectMeta: metav1.ObjectMeta{Name: "cluster-member"}, Context: "cluster"},
+	}
+	v := &validator{
+		roleTemplateLister: &fakes.RoleTemplateListerMock{
+			GetFunc: func(namespace string, name string) (*v3.RoleTemplate, error) {
+				if roleTemplate, ok := roleTemplates[name]; ok {
+					return roleTemplate, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+			},
+		},
+		field:   client.ProjectRoleTemplateBindingFieldRoleTemplateID,
+		context: "project",
+	}
+	return &types.Schema{Store: store, Validator: v.validator}
+}
+
+func newTestMemberSetter() *MemberSetter {
+	return &MemberSetter{
+		userLister: &fakes.UserListerMock{
+			GetFunc: func(namespace string, name string) (*v3.User, error) {
+				if name == "u-abcde" {
+					return &v3.User{
+						ObjectMeta:   metav1.ObjectMeta{Name: name},
+						PrincipalIDs: []string{"local://u-abcde", "openldap_user://owner"},
+					}, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+			},
+		},
+		resolvePrincipal: func(apiContext *types.APIContext, principalID string) error {
+			if principalID == "openldap_user://unknown" {
+				return httperror.NewAPIError(httperror.NotFound, "principal not found")
+			}
+			return nil
+		},
+		bindingType: client.ProjectRoleTemplateBindingType,
+		targetField: client.ProjectRoleTemplateBindingFieldProjectID,
+	}
+}
+
+func newBinding(id, userPrincipal, roleTemplate string, managed bool) map[string]interface{} {
+	binding := map[string]interface{}{
+		"id":              id,
+		"projectId":       "c-abcde:p-abcde",
+		"userPrincipalId": userPrincipal,
+		"roleTemplateId":  roleTemplate,
+	}
+	if managed {
+		binding["labels"] = map[string]interface{}{setMembersLabel: "true"}
+	}
+	return binding
+}
+
+func TestSetMembersPartiallyInvalid(t *testing.T) {
+	store := &fakeBindingStore{}
+	apiContext := &types.APIContext{AccessControl: fakeAccessControl{}}
+
+	output, valid, err := newTestMemberSetter().setMembers(apiContext, newTestSchema(store), "c-abcde:p-abcde", v32.SetMembersInput{
+		Members: []v32.RoleTemplateMember{
+			{UserPrincipalName: "openldap_user://alice", RoleTemplateName: "project-member"},
+			{UserPrincipalName: "openldap_user://bob", RoleTemplateName: "locked"},
+			{UserPrincipalName: "openldap_user://carol", RoleTemplateName: "missing"},
+			{UserPrincipalName: "openldap_user://dave", RoleTemplateName: "cluster-member"},
+			{UserPrincipalName: "openldap_user://unknown", RoleTemplateName: "project-member"},
+			{UserPrincipalName: "openldap_user://erin", GroupPrincipalName: "openldap_group://devs", RoleTemplateName: "project-member"},
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	var results []string
+	for _, result := range output.Results {
+		results = append(results, result.Result)
+	}
+	assert.Equal(t, []string{"", MemberResultInvalid, MemberResultInvalid, MemberResultInvalid, MemberResultInvalid, MemberResultInvalid}, results)
+	assert.Contains(t, output.Results[1].Message, "locked")
+	assert.Contains(t, output.Results[2].Message, "not found")
+	assert.Contains(t, output.Results[3].Message, "context")
+	assert.Contains(t, output.Results[4].Message, "cannot be resolved")
+	assert.Contains(t, output.Results[5].Message, "must target a user")
+	assert.Empty(t, store.created, "expected no binding to be created when any member is invalid")
+	assert.Empty(t, store.deleted)
+}
+
+func TestSetMembersConverges(t *testing.T) {
+	store := &fakeBindingStore{
+		bindings: []map[string]interface{}{
+			newBinding("p-abcde:prtb-alice", "openldap_user://alice", "project-member", true),
+			newBinding("p-abcde:prtb-bob", "openldap_user://bob", "project-member", true),
+			newBinding("p-abcde:creator-project-owner", "local://u-abcde", "project-owner", false),
+		},
+	}
+	apiContext := &types.APIContext{AccessControl: fakeAccessControl{}}
+
+	output, valid, err := newTestMemberSetter().setMembers(apiContext, newTestSchema(store), "c-abcde:p-abcde", v32.SetMembersInput{
+		Members: []v32.RoleTemplateMember{
+			{UserPrincipalName: "openldap_user://alice", RoleTemplateName: "project-member"},
+			{GroupPrincipalName: "openldap_group://devs", RoleTemplateName: "project-member"},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	require.Len(t, output.Results, 3)
+	assert.Equal(t, MemberResultExists, output.Results[0].Result)
+	assert.Equal(t, "p-abcde:prtb-alice", output.Results[0].BindingName)
+	assert.Equal(t, MemberResultCreated, output.Results[1].Result)
+	assert.Equal(t, MemberResultRemoved, output.Results[2].Result)
+	assert.Equal(t, "openldap_user://bob", output.Results[2].UserPrincipalName)
+
+	require.Len(t, store.created, 1)
+	assert.Equal(t, map[string]interface{}{
+		"id":               "p-abcde:prtb-1",
+		"projectId":        "c-abcde:p-abcde",
+		"groupPrincipalId": "openldap_group://devs",
+		"roleTemplateId":   "project-member",
+		"labels":           map[string]interface{}{setMembersLabel: "true"},
+	}, store.created[0])
+	assert.Equal(t, []string{"p-abcde:prtb-bob"}, store.deleted, "expected only the bindings created by setMembers to be removed")
+}
+
+func TestSetMembersMatchesUserName(t *testing.T) {
+	// the creator of the project is bound by user name, without principal
+	store := &fakeBindingStore{
+		bindings: []map[string]interface{}{
+			{
+				"id":             "p-abcde:creator-project-owner",
+				"projectId":      "c-abcde:p-abcde",
+				"userId":         "u-abcde",
+				"roleTemplateId": "project-owner",
+			},
+		},
+	}
+	apiContext := &types.APIContext{AccessControl: fakeAccessControl{}}
+
+	output, valid, err := newTestMemberSetter().setMembers(apiContext, newTestSchema(store), "c-abcde:p-abcde", v32.SetMembersInput{
+		Members: []v32.RoleTemplateMember{
+			{UserPrincipalName: "openldap_user://owner", RoleTemplateName: "project-owner"},
+		},
+		Replace: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	require.Len(t, output.Results, 1)
+	assert.Equal(t, MemberResultExists, output.Results[0].Result)
+	assert.Equal(t, "p-abcde:creator-project-owner", output.Results[0].BindingName)
+	assert.Empty(t, store.created, "expected the binding of the user by name not to be duplicated")
+	assert.Empty(t, store.deleted, "expected the binding of the user by name to be kept")
+}
+
+func TestSetMembersReplace(t *testing.T) {
+	store := &fakeBindingStore{
+		bindings: []map[string]interface{}{
+			newBinding("p-abcde:prtb-alice", "openldap_user://alice", "project-member", true),
+			newBinding("p-abcde:prtb-bob", "openldap_user://bob", "project-member", true),
+			newBinding("p-abcde:creator-project-owner", "local://u-abcde", "project-owner", false),
+		},
+	}
+	apiContext := &types.APIContext{AccessControl: fakeAccessControl{}}
+
+	output, valid, err := newTestMemberSetter().setMembers(apiContext, newTestSchema(store), "c-abcde:p-abcde", v32.SetMembersInput{
+		Members: []v32.RoleTemplateMember{
+			{UserPrincipalName: "openldap_user://alice", RoleTemplateName: "project-member"},
+		},
+		Replace: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	require.Len(t, output.Results, 3)
+	assert.Equal(t, MemberResultExists, output.Results[0].Result)
+	assert.Equal(t, MemberResultRemoved, output.Results[1].Result)
+	assert.Equal(t, MemberResultRemoved, output.Results[2].Result)
+	assert.Empty(t, store.created)
+	assert.ElementsMatch(t, []string{"p-abcde:prtb-bob", "p-abcde:creator-project-owner"}, store.deleted,
+		"expected all the bindings that aren't listed to be removed when replacing the members")
+}
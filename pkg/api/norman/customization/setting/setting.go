@@ -75,6 +75,10 @@ func Validator(request *types.APIContext, schema *types.Schema, data map[string]
 		return fmt.Errorf("value not string")
 	}
 
+	if err := settings.Validate(id, newValueString); err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, err.Error())
+	}
+
 	var err error
 	switch id {
 	case "auth-user-info-max-age-seconds":
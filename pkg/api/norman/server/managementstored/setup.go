@@ -14,6 +14,7 @@
 	"github.com/rancher/rancher/pkg/api/norman/customization/authn"
 	"github.com/rancher/rancher/pkg/api/norman/customization/catalog"
 	ccluster "github.com/rancher/rancher/pkg/api/norman/customization/cluster"
+	"github.com/rancher/rancher/pkg/api/norman/customization/clusterregistrationtokens"
 	"github.com/rancher/rancher/pkg/api/norman/customization/clusterscan"
 	"github.com/rancher/rancher/pkg/api/norman/customization/clustertemplate"
 	"github.com/rancher/rancher/pkg/api/norman/customization/cred"
@@ -47,6 +48,7 @@
 	globaldnsAPIStore "github.com/rancher/rancher/pkg/api/norman/store/globaldns"
 	globalRoleStore "github.com/rancher/rancher/pkg/api/norman/store/globalrole"
 	grbstore "github.com/rancher/rancher/pkg/api/norman/store/globalrolebindings"
+	"github.com/rancher/rancher/pkg/api/norman/store/listoptions"
 	nodeStore "github.com/rancher/rancher/pkg/api/norman/store/node"
 	nodeTemplateStore "github.com/rancher/rancher/pkg/api/norman/store/nodetemplate"
 	"github.com/rancher/rancher/pkg/api/norman/store/noopwatching"
@@ -61,6 +63,7 @@
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	projectclient "github.com/rancher/rancher/pkg/client/generated/project/v3"
+	"github.com/rancher/rancher/pkg/clusterimages"
 	"github.com/rancher/rancher/pkg/clustermanager"
 	"github.com/rancher/rancher/pkg/clusterrouter"
 	md "github.com/rancher/rancher/pkg/controllers/management/kontainerdrivermetadata"
@@ -163,7 +166,7 @@ func Setup(ctx context.Context, apiContext *config.ScaledContext, clusterManager
 		return err
 	}
 
-	Clusters(schemas, apiContext, clusterManager, k8sProxy)
+	Clusters(ctx, schemas, apiContext, clusterManager, k8sProxy)
 	ClusterRoleTemplateBinding(schemas, apiContext)
 	api.User(ctx, schemas, apiContext)
 	SecretTypes(ctx, schemas, apiContext)
@@ -173,7 +176,7 @@ func Setup(ctx context.Context, apiContext *config.ScaledContext, clusterManager
 	ClusterRegistrationTokens(schemas, apiContext)
 	Tokens(ctx, schemas, apiContext)
 	NodeTemplates(schemas, apiContext)
-	Project(schemas, apiContext)
+	Project(ctx, schemas, apiContext)
 	ProjectRoleTemplateBinding(schemas, apiContext)
 	PodSecurityPolicyTemplate(schemas, apiContext)
 	PodSecurityPolicyTemplateProjectBinding(schemas, apiContext)
@@ -209,6 +212,7 @@ func Setup(ctx context.Context, apiContext *config.ScaledContext, clusterManager
 	authn.SetRTBStore(ctx, schemas.Schema(&managementschema.Version, client.ClusterRoleTemplateBindingType), apiContext)
 	authn.SetRTBStore(ctx, schemas.Schema(&managementschema.Version, client.ProjectRoleTemplateBindingType), apiContext)
 	nodeStore.SetupStore(schemas.Schema(&managementschema.Version, client.NodeType))
+	listoptions.Setup(schemas.Schema(&managementschema.Version, client.NodeType), listoptions.ObjectClientPager(apiContext.Management.Nodes("").ObjectClient()))
 	projectaction.SetProjectStore(schemas.Schema(&managementschema.Version, client.ProjectType), apiContext)
 	setupScopedTypes(schemas)
 	setupPasswordTypes(ctx, schemas, apiContext)
@@ -249,27 +253,40 @@ func setupScopedTypes(schemas *types.Schemas) {
 	}
 }
 
-func Clusters(schemas *types.Schemas, managementContext *config.ScaledContext, clusterManager *clustermanager.Manager, k8sProxy http.Handler) {
+func Clusters(ctx context.Context, schemas *types.Schemas, managementContext *config.ScaledContext, clusterManager *clustermanager.Manager, k8sProxy http.Handler) {
 	schema := schemas.Schema(&managementschema.Version, client.ClusterType)
 	clusterFormatter := ccluster.NewFormatter(schemas, managementContext)
 	schema.Formatter = clusterFormatter.Formatter
 	schema.CollectionFormatter = clusterFormatter.CollectionFormatter
 	clusterStore := cluster.GetClusterStore(schema, managementContext, clusterManager, k8sProxy)
 	schema.Store = clusterStore
+	listoptions.Setup(schema, listoptions.ObjectClientPager(managementContext.Management.Clusters("").ObjectClient()))
 
 	handler := ccluster.ActionHandler{
-		NodepoolGetter:                managementContext.Management,
-		NodeLister:                    managementContext.Management.Nodes("").Controller().Lister(),
-		ClusterClient:                 managementContext.Management.Clusters(""),
-		CatalogManager:                managementContext.CatalogManager,
-		UserMgr:                       managementContext.UserManager,
-		ClusterManager:                clusterManager,
-		NodeTemplateGetter:            managementContext.Management,
-		BackupClient:                  managementContext.Management.EtcdBackups(""),
-		ClusterTemplateClient:         managementContext.Management.ClusterTemplates(""),
-		ClusterTemplateRevisionClient: managementContext.Management.ClusterTemplateRevisions(""),
-		SubjectAccessReviewClient:     managementContext.K8sClient.AuthorizationV1().SubjectAccessReviews(),
-		TokenClient:                   managementContext.Management.Tokens(""),
+		NodepoolGetter:                 managementContext.Management,
+		NodeLister:                     managementContext.Management.Nodes("").Controller().Lister(),
+		ClusterClient:                  managementContext.Management.Clusters(""),
+		CatalogManager:                 managementContext.CatalogManager,
+		UserMgr:                        managementContext.UserManager,
+		ClusterManager:                 clusterManager,
+		NodeTemplateGetter:             managementContext.Management,
+		BackupClient:                   managementContext.Management.EtcdBackups(""),
+		ClusterTemplateClient:          managementContext.Management.ClusterTemplates(""),
+		ClusterTemplateRevisionClient:  managementContext.Management.ClusterTemplateRevisions(""),
+		SubjectAccessReviewClient:      managementContext.K8sClient.AuthorizationV1().SubjectAccessReviews(),
+		TokenClient:                    managementContext.Management.Tokens(""),
+		MemberSetter:                   roletemplatebinding.NewCRTBMemberSetter(ctx, managementContext),
+		ClusterRegistrationTokenLister: managementContext.Management.ClusterRegistrationTokens("").Controller().Lister(),
+		ImageResolver: &clusterimages.Resolver{
+			ProvisioningClusters: managementContext.Wrangler.Provisioning.Cluster().Cache(),
+			ChartValues: func(chartName, version string) (map[string]interface{}, error) {
+				info, err := managementContext.Wrangler.CatalogContentManager.Info("", "rancher-charts", chartName, version)
+				if err != nil {
+					return nil, err
+				}
+				return info.Values, nil
+			},
+		},
 	}
 
 	clusterValidator := ccluster.Validator{
@@ -398,6 +415,11 @@ func ClusterRegistrationTokens(schemas *types.Schemas, management *config.Scaled
 	schema.Store = &cluster.RegistrationTokenStore{
 		Store: schema.Store,
 	}
+	schema.Formatter = clusterregistrationtokens.Formatter
+	handler := clusterregistrationtokens.ActionHandler{
+		ClusterRegistrationTokens: management.Management.ClusterRegistrationTokens(""),
+	}
+	schema.ActionHandler = handler.ClusterRegistrationTokenActionHandler
 }
 
 func Tokens(ctx context.Context, schemas *types.Schemas, mgmt *config.ScaledContext) {
@@ -664,7 +686,7 @@ func Pipeline(schemas *types.Schemas, management *config.ScaledContext, clusterM
 
 }
 
-func Project(schemas *types.Schemas, management *config.ScaledContext) {
+func Project(ctx context.Context, schemas *types.Schemas, management *config.ScaledContext) {
 	schema := schemas.Schema(&managementschema.Version, client.ProjectType)
 	schema.Formatter = projectaction.Formatter
 	handler := &projectaction.Handler{
@@ -674,6 +696,7 @@ func Project(schemas *types.Schemas, management *config.ScaledContext) {
 		ClusterManager:    management.ClientGetter.(*clustermanager.Manager),
 		ClusterLister:     management.Management.Clusters("").Controller().Lister(),
 		PSPTemplateLister: management.Management.PodSecurityPolicyTemplates("").Controller().Lister(),
+		MemberSetter:      roletemplatebinding.NewPRTBMemberSetter(ctx, management),
 	}
 	schema.ActionHandler = handler.Actions
 }
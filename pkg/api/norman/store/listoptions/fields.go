@@ -0,0 +1,58 @@
+package listoptions
+
+// alwaysSelected are kept on every object so the object can still be identified, authorized and linked
+var alwaysSelected = []string{"id", "type", "namespaceId"}
+
+// SelectFields returns a copy of obj with only the given paths. Paths going through a list select the rest of the path
+// from each element of the list, paths that don't exist in obj are ignored.
+func SelectFields(obj map[string]interface{}, paths [][]string) map[string]interface{} {
+	result := map[string]interface{}{}
+	for _, field := range alwaysSelected {
+		if v, ok := obj[field]; ok {
+			result[field] = v
+		}
+	}
+	for _, path := range paths {
+		selectPath(obj, result, path)
+	}
+	return result
+}
+
+func selectPath(obj, result map[string]interface{}, path []string) {
+	v, ok := obj[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		result[path[0]] = v
+		return
+	}
+
+	switch child := v.(type) {
+	case map[string]interface{}:
+		childResult, _ := result[path[0]].(map[string]interface{})
+		if childResult == nil {
+			childResult = map[string]interface{}{}
+			result[path[0]] = childResult
+		}
+		selectPath(child, childResult, path[1:])
+	case []interface{}:
+		childResult, _ := result[path[0]].([]interface{})
+		if len(childResult) != len(child) {
+			childResult = make([]interface{}, len(child))
+			result[path[0]] = childResult
+		}
+		for i, item := range child {
+			itemObj, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			itemResult, _ := childResult[i].(map[string]interface{})
+			if itemResult == nil {
+				itemResult = map[string]interface{}{}
+				childResult[i] = itemResult
+			}
+			selectPath(itemObj, itemResult, path[1:])
+		}
+	}
+}
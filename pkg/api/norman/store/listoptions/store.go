@@ -0,0 +1,249 @@
+package listoptions
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/norman/api/handler"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/objectclient"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	FieldsParam   = "fields"
+	LimitParam    = "limit"
+	ContinueParam = "continue"
+)
+
+// Pager lists a page of up to limit objects of the collection from Kubernetes, starting from the continue token of the
+// previous page. It returns the ids of the objects of the page in the order of the list and the continue token of the
+// next page, empty once the list is complete.
+type Pager func(limit int64, continueToken string) ([]string, string, error)
+
+// ObjectClientPager pages the objects of the client, their ids are the ids norman gives them, namespace:name for the
+// namespaced objects
+func ObjectClientPager(client *objectclient.ObjectClient) Pager {
+	return func(limit int64, continueToken string) ([]string, string, error) {
+		list, err := client.List(metav1.ListOptions{Limit: limit, Continue: continueToken})
+		if err != nil {
+			return nil, "", err
+		}
+		listMeta, err := meta.ListAccessor(list)
+		if err != nil {
+			return nil, "", err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, "", err
+		}
+
+		ids := make([]string, 0, len(items))
+		for _, item := range items {
+			obj, err := meta.Accessor(item)
+			if err != nil {
+				return nil, "", err
+			}
+			id := obj.GetName()
+			if obj.GetNamespace() != "" {
+				id = obj.GetNamespace() + ":" + id
+			}
+			ids = append(ids, id)
+		}
+		return ids, listMeta.GetContinue(), nil
+	}
+}
+
+// Setup lets the collection of the schema select fields with the fields query parameter, a comma separated list of
+// dotted paths such as status.conditions.type, and paginate with the limit and continue query parameters. The pages are
+// the pages of the Kubernetes list of the collection, listed by the pager, and the continue tokens are the ones of the
+// Kubernetes list, so that a paginated list is served from a consistent snapshot of the collection and the tokens stay
+// valid across restarts, until they expire like the tokens of the Kubernetes lists. The objects of the collection the
+// user isn't allowed or the query conditions filter out are dropped from the pages, which are then completed from the
+// next pages. The fields are selected by the formatter, once the stores wrapping this one are done with the whole
+// objects. It wraps the store and formatters of the schema, so it has to be called once they are set.
+func Setup(schema *types.Schema, pager Pager) {
+	schema.Store = &Store{
+		Store: schema.Store,
+		pager: pager,
+	}
+	schema.Formatter = fieldsFormatter(schema.Formatter)
+	schema.CollectionFormatter = paginationFormatter(schema.CollectionFormatter)
+}
+
+type Store struct {
+	types.Store
+	pager Pager
+}
+
+func (s *Store) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
+	query := apiContext.Query
+	if query.Get(ContinueParam) == "" && query.Get(LimitParam) == "" {
+		return s.Store.List(apiContext, schema, opt)
+	}
+
+	limit, err := parseLimit(query.Get(LimitParam))
+	if err != nil {
+		return nil, err
+	}
+
+	// the page is cut here so the wrapped store must return the whole collection, and the stores wrapping this one
+	// must not paginate it again
+	opt.Pagination = nil
+	data, err := s.Store.List(apiContext, schema, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiContext.AccessControl != nil {
+		data = apiContext.AccessControl.FilterList(apiContext, schema, data, map[string]string{
+			"apiGroup": schema.Version.Group,
+			"resource": schema.PluralName,
+		})
+	}
+	// the conditions are applied again by the store wrapper, but they have to be applied before cutting the page so
+	// the objects they filter out don't shorten it
+	data = handler.ApplyQueryConditions(opt.Conditions, schema, data)
+
+	data, next, err := s.page(data, limit, query.Get(ContinueParam))
+	if err != nil {
+		return nil, err
+	}
+	setPagination(apiContext, limit, next)
+	return data, nil
+}
+
+// page returns up to limit objects of the data in the order of the pages listed by the pager from the continue token,
+// and the continue token of the next page. The pages are listed until the page is full, as the objects which aren't
+// in the data are skipped.
+func (s *Store) page(data []map[string]interface{}, limit int, continueToken string) ([]map[string]interface{}, string, error) {
+	byID := make(map[string]map[string]interface{}, len(data))
+	for _, obj := range data {
+		byID[convert.ToString(obj["id"])] = obj
+	}
+
+	var page []map[string]interface{}
+	for {
+		var remaining int64
+		if limit > 0 {
+			remaining = int64(limit - len(page))
+		}
+		ids, next, err := s.pager(remaining, continueToken)
+		if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+			return nil, "", httperror.NewAPIError(httperror.ErrorCode{Code: "ResourceExpired", Status: http.StatusGone},
+				"the continue token expired, the list has to be restarted")
+		} else if apierrors.IsBadRequest(err) {
+			return nil, "", httperror.NewAPIError(httperror.InvalidFormat, "invalid continue token")
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		for _, id := range ids {
+			if obj, ok := byID[id]; ok {
+				page = append(page, obj)
+			}
+		}
+		continueToken = next
+		if continueToken == "" || (limit > 0 && len(page) >= limit) {
+			return page, continueToken, nil
+		}
+	}
+}
+
+// fieldsFormatter selects the fields of the objects of collections once they are formatted
+func fieldsFormatter(next types.Formatter) types.Formatter {
+	return func(apiContext *types.APIContext, resource *types.RawResource) {
+		if next != nil {
+			next(apiContext, resource)
+		}
+		if apiContext.Method != http.MethodGet || apiContext.ID != "" {
+			return
+		}
+		if fields := apiContext.Query.Get(FieldsParam); fields != "" {
+			resource.Values = SelectFields(resource.Values, ParseFields(fields))
+		}
+	}
+}
+
+// paginationFormatter restores the link to the next page of collections, which the response writer turns into a norman
+// marker link the store doesn't handle. The marker is the link set by the store.
+func paginationFormatter(next types.CollectionFormatter) types.CollectionFormatter {
+	return func(apiContext *types.APIContext, collection *types.GenericCollection) {
+		if next != nil {
+			next(apiContext, collection)
+		}
+		pagination := collection.Pagination
+		if pagination == nil || !pagination.Partial || pagination.Next == "" ||
+			apiContext.Query.Get(ContinueParam) == "" && apiContext.Query.Get(LimitParam) == "" {
+			return
+		}
+		if markerLink, err := url.Parse(pagination.Next); err == nil {
+			if marker := markerLink.Query().Get("marker"); marker != "" {
+				pagination.Next = marker
+			}
+		}
+	}
+}
+
+func parseLimit(limit string) (int, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 0 {
+		return 0, httperror.NewAPIError(httperror.InvalidFormat, "limit must be a positive integer")
+	}
+	return n, nil
+}
+
+func setPagination(apiContext *types.APIContext, limit int, next string) {
+	pagination := &types.Pagination{
+		Partial: next != "",
+	}
+	if limit > 0 {
+		limit64 := int64(limit)
+		pagination.Limit = &limit64
+	}
+	if next != "" {
+		pagination.Next = nextLink(apiContext, next)
+	}
+	apiContext.Pagination = pagination
+}
+
+func nextLink(apiContext *types.APIContext, token string) string {
+	u := &url.URL{}
+	if apiContext.URLBuilder != nil {
+		current, err := url.Parse(apiContext.URLBuilder.Current())
+		if err != nil {
+			return ""
+		}
+		u = current
+	}
+	query := url.Values{}
+	for k, v := range apiContext.Query {
+		query[k] = v
+	}
+	query.Del("marker")
+	query.Set(ContinueParam, token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ParseFields splits a fields query parameter into paths, an optional leading $. of JSON paths is accepted
+func ParseFields(fields string) [][]string {
+	var paths [][]string
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimPrefix(strings.TrimSpace(field), "$.")
+		if field == "" {
+			continue
+		}
+		paths = append(paths, strings.Split(field, "."))
+	}
+	return paths
+}
@@ -0,0 +1,337 @@
+package listoptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/rancher/norman/api/handler"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/store/wrapper"
+	"github.com/rancher/norman/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type dummyStore struct {
+	types.Store
+	data []map[string]interface{}
+}
+
+func (d *dummyStore) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
+	result := make([]map[string]interface{}, len(d.data))
+	copy(result, d.data)
+	return result, nil
+}
+
+// fakePager pages a snapshot of the ids of the collection, the continue token is the offset of the next page
+type fakePager struct {
+	ids []string
+}
+
+func (f *fakePager) page(limit int64, continueToken string) ([]string, string, error) {
+	if continueToken == "expired" {
+		return nil, "", apierrors.NewResourceExpired("too old resource version")
+	}
+	start := 0
+	if continueToken != "" {
+		var err error
+		if start, err = strconv.Atoi(continueToken); err != nil {
+			return nil, "", apierrors.NewBadRequest("continue key is not valid")
+		}
+	}
+
+	end := len(f.ids)
+	if limit > 0 && start+int(limit) < end {
+		end = start + int(limit)
+	}
+	var next string
+	if end < len(f.ids) {
+		next = strconv.Itoa(end)
+	}
+	return f.ids[start:end], next, nil
+}
+
+type denyAccessControl struct {
+	types.AccessControl
+	denied string
+}
+
+func (d denyAccessControl) FilterList(apiContext *types.APIContext, schema *types.Schema, objs []map[string]interface{}, context map[string]string) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, obj := range objs {
+		if obj["id"] != d.denied {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+func newNode(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          id,
+		"type":        "node",
+		"namespaceId": "c-1",
+		"nodeName":    id,
+		"info": map[string]interface{}{
+			"cpu":    map[string]interface{}{"count": 4},
+			"memory": map[string]interface{}{"memTotalKiB": 16384},
+		},
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True", "message": "kubelet is posting ready status"},
+			map[string]interface{}{"type": "Registered", "status": "True"},
+		},
+		"taints": []interface{}{"not an object"},
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "top level field",
+			fields: "nodeName",
+			want:   map[string]interface{}{"id": "m-1", "type": "node", "namespaceId": "c-1", "nodeName": "m-1"},
+		},
+		{
+			name:   "nested map",
+			fields: "$.info.cpu.count,nodeName",
+			want: map[string]interface{}{"id": "m-1", "type": "node", "namespaceId": "c-1", "nodeName": "m-1",
+				"info": map[string]interface{}{"cpu": map[string]interface{}{"count": 4}}},
+		},
+		{
+			name:   "nested list",
+			fields: "conditions.type, conditions.status",
+			want: map[string]interface{}{"id": "m-1", "type": "node", "namespaceId": "c-1",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True"},
+					map[string]interface{}{"type": "Registered", "status": "True"},
+				}},
+		},
+		{
+			name:   "missing and non object paths",
+			fields: "missing.field,taints.key,info.disk",
+			want: map[string]interface{}{"id": "m-1", "type": "node", "namespaceId": "c-1",
+				"taints": []interface{}{nil}, "info": map[string]interface{}{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newNode("m-1")
+			assert.Equal(t, tt.want, SelectFields(node, ParseFields(tt.fields)))
+			assert.Equal(t, newNode("m-1"), node, "expected the object to be left unchanged")
+		})
+	}
+}
+
+type noSubContext struct{}
+
+func (noSubContext) Query(apiContext *types.APIContext, schema *types.Schema) []*types.QueryCondition {
+	return nil
+}
+
+func (noSubContext) Create(apiContext *types.APIContext, schema *types.Schema) map[string]interface{} {
+	return nil
+}
+
+// newSchema returns the schema of a collection of the objects, the pager lists a snapshot of their ids
+func newSchema(data ...map[string]interface{}) *types.Schema {
+	pager := &fakePager{ids: ids(data)}
+	sort.Strings(pager.ids)
+	schema := &types.Schema{PluralName: "nodes", Store: &dummyStore{data: data}}
+	Setup(schema, pager.page)
+	return schema
+}
+
+// list lists the collection of the schema as the list handler does, through the store wrapper of norman
+func list(t *testing.T, schema *types.Schema, query url.Values, conditions ...*types.QueryCondition) ([]map[string]interface{}, *types.APIContext) {
+	limit := int64(1000)
+	opts := &types.QueryOptions{
+		Conditions: conditions,
+		Pagination: &types.Pagination{Limit: &limit},
+	}
+	apiContext := &types.APIContext{
+		Method:                      http.MethodGet,
+		Query:                       query,
+		AccessControl:               denyAccessControl{denied: "m-3"},
+		SubContextAttributeProvider: noSubContext{},
+		QueryFilter:                 handler.QueryFilter,
+		Pagination:                  opts.Pagination,
+	}
+	data, err := wrapper.Wrap(schema.Store).List(apiContext, schema, opts)
+	require.NoError(t, err)
+	return data, apiContext
+}
+
+// format formats the objects as the response writer does, which turns the next link of partial collections into a
+// marker link
+func format(apiContext *types.APIContext, schema *types.Schema, data []map[string]interface{}) *types.GenericCollection {
+	collection := &types.GenericCollection{Collection: types.Collection{Pagination: apiContext.Pagination}}
+	for _, obj := range data {
+		resource := &types.RawResource{ID: obj["id"].(string), Schema: schema, Values: obj}
+		schema.Formatter(apiContext, resource)
+		collection.Data = append(collection.Data, resource)
+	}
+	if pagination := collection.Pagination; pagination != nil && pagination.Partial && pagination.Next != "" {
+		pagination.Next = "/v3/nodes?" + url.Values{"marker": {pagination.Next}}.Encode()
+	}
+	schema.CollectionFormatter(apiContext, collection)
+	return collection
+}
+
+func ids(data []map[string]interface{}) []string {
+	var result []string
+	for _, obj := range data {
+		result = append(result, obj["id"].(string))
+	}
+	return result
+}
+
+func TestListPagination(t *testing.T) {
+	schema := newSchema(newNode("m-5"), newNode("m-2"), newNode("m-4"), newNode("m-1"), newNode("m-3"))
+
+	data, apiContext := list(t, schema, url.Values{LimitParam: {"2"}})
+	assert.Equal(t, []string{"m-1", "m-2"}, ids(data))
+	collection := format(apiContext, schema, data)
+	require.NotNil(t, collection.Pagination)
+	assert.True(t, collection.Pagination.Partial)
+	next, err := url.Parse(collection.Pagination.Next)
+	require.NoError(t, err)
+	assert.Equal(t, "2", next.Query().Get(ContinueParam), "expected the continue token of the list")
+	assert.Empty(t, next.Query().Get("marker"))
+
+	// the denied m-3 is skipped rather than counted in the page, which is completed from the next page of the list
+	data, apiContext = list(t, schema, next.Query())
+	assert.Equal(t, []string{"m-4", "m-5"}, ids(data))
+	collection = format(apiContext, schema, data)
+	assert.False(t, collection.Pagination.Partial)
+	assert.Empty(t, collection.Pagination.Next)
+}
+
+func TestListPaginationConditions(t *testing.T) {
+	schema := newSchema(newNode("m-1"), newNode("m-2"), newNode("m-4"), newNode("m-5"))
+
+	data, apiContext := list(t, schema, url.Values{LimitParam: {"2"}},
+		types.NewConditionFromString("nodeName", types.ModifierNE, "m-2"))
+	assert.Equal(t, []string{"m-1", "m-4"}, ids(data), "expected the objects filtered out by the conditions not to shorten the page")
+	assert.True(t, apiContext.Pagination.Partial)
+}
+
+func TestListContinueFromSnapshot(t *testing.T) {
+	schema := newSchema(newNode("m-1"), newNode("m-2"), newNode("m-4"), newNode("m-5"))
+
+	// m-2 is deleted and m-0 is created once the list started
+	schema.Store.(*Store).Store = &dummyStore{data: []map[string]interface{}{newNode("m-0"), newNode("m-1"), newNode("m-4"), newNode("m-5")}}
+	data, apiContext := list(t, schema, url.Values{LimitParam: {"2"}, ContinueParam: {"1"}})
+	assert.Equal(t, []string{"m-4", "m-5"}, ids(data), "expected the page to follow the snapshot of the list")
+	assert.False(t, apiContext.Pagination.Partial)
+}
+
+func TestListContinueExpired(t *testing.T) {
+	schema := newSchema(newNode("m-1"))
+	_, err := schema.Store.List(&types.APIContext{Query: url.Values{ContinueParam: {"expired"}}}, schema, &types.QueryOptions{})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusGone, err.(*httperror.APIError).Code.Status)
+}
+
+func TestListInvalidParams(t *testing.T) {
+	schema := newSchema()
+	for _, query := range []url.Values{
+		{LimitParam: {"-1"}},
+		{LimitParam: {"ten"}},
+		{ContinueParam: {"!!"}},
+	} {
+		_, err := schema.Store.List(&types.APIContext{Query: query}, schema, &types.QueryOptions{})
+		assert.Error(t, err, "expected %v to be rejected", query)
+	}
+}
+
+func TestListFields(t *testing.T) {
+	schema := newSchema(newNode("m-2"), newNode("m-1"))
+
+	data, apiContext := list(t, schema, url.Values{FieldsParam: {"nodeName"}},
+		types.NewConditionFromString("info", types.ModifierNotNull))
+	assert.Equal(t, []string{"m-1", "m-2"}, ids(data), "expected the conditions to apply to the whole objects")
+	for _, obj := range data {
+		assert.Contains(t, obj, "info", "expected the store wrapper to get the whole objects")
+	}
+
+	for _, item := range format(apiContext, schema, data).Data {
+		values := item.(*types.RawResource).Values
+		assert.NotContains(t, values, "info")
+		assert.Contains(t, values, "nodeName")
+	}
+}
+
+func TestFieldsOfSingleObject(t *testing.T) {
+	schema := newSchema()
+	resource := &types.RawResource{ID: "m-1", Schema: schema, Values: newNode("m-1")}
+	schema.Formatter(&types.APIContext{Method: http.MethodGet, ID: "m-1", Query: url.Values{FieldsParam: {"nodeName"}}}, resource)
+	assert.Equal(t, newNode("m-1"), resource.Values, "expected the fields to only be selected on collections")
+}
+
+// heavyNode approximates a node of a large cluster, carrying its full internal node status
+func heavyNode(i int) map[string]interface{} {
+	node := newNode(fmt.Sprintf("m-%05d", i))
+	var images []interface{}
+	for j := 0; j < 50; j++ {
+		images = append(images, map[string]interface{}{
+			"names":     []interface{}{fmt.Sprintf("docker.io/rancher/image-%d@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", j)},
+			"sizeBytes": 123456789,
+		})
+	}
+	node["internalNodeStatus"] = map[string]interface{}{
+		"images":          images,
+		"volumesAttached": []interface{}{},
+		"addresses": []interface{}{
+			map[string]interface{}{"type": "InternalIP", "address": "10.0.0.1"},
+			map[string]interface{}{"type": "Hostname", "address": node["nodeName"]},
+		},
+	}
+	return node
+}
+
+func benchmarkList(b *testing.B, query url.Values) {
+	var data []map[string]interface{}
+	for i := 0; i < 2000; i++ {
+		data = append(data, heavyNode(i))
+	}
+	schema := newSchema(data...)
+	apiContext := &types.APIContext{Method: http.MethodGet, Query: query}
+
+	var size int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := schema.Store.List(apiContext, schema, &types.QueryOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		bytes, err := json.Marshal(format(apiContext, schema, result))
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(bytes)
+	}
+	b.ReportMetric(float64(size), "payload-bytes")
+}
+
+func BenchmarkListAllFields(b *testing.B) {
+	benchmarkList(b, url.Values{})
+}
+
+func BenchmarkListSelectedFields(b *testing.B) {
+	benchmarkList(b, url.Values{FieldsParam: {"nodeName,conditions.type,conditions.status,internalNodeStatus.addresses"}})
+}
+
+func BenchmarkListSelectedFieldsPage(b *testing.B) {
+	benchmarkList(b, url.Values{FieldsParam: {"nodeName,conditions.type,conditions.status"}, LimitParam: {"100"}})
+}
@@ -82,6 +82,15 @@ func (s *Store) Update(apiContext *types.APIContext, schema *types.Schema, data
 		}
 		data["labels"] = labels
 	}
+	// the settings controller attributes the change to this user in the audit trail
+	if user := apiContext.Request.Header.Get("Impersonate-User"); user != "" {
+		annotations := convert.ToMapInterface(data["annotations"])
+		if annotations == nil {
+			annotations = map[string]interface{}{}
+		}
+		annotations[settings.UpdatedByAnnotation] = settings.UpdatedBy(user, convert.ToString(data["value"]))
+		data["annotations"] = annotations
+	}
 	return s.Store.Update(apiContext, schema, data, id)
 }
 
@@ -23,7 +23,8 @@ func AdditionalAPIsPreMCM(config *wrangler.Context) func(http.Handler) http.Hand
 		mux := gmux.NewRouter()
 		mux.UseEncodedPath()
 		mux.Handle("/v3/connect/agent", configserver.New(config))
-		mux.Handle("/system-agent-install.sh", server.InstallHandler())
+		mux.Handle("/system-agent-install.sh", server.InstallHandler(config.Provisioning.Cluster().Cache(),
+			config.Mgmt.ClusterRegistrationToken().Cache(), config.Core.ConfigMap().Cache(), config.Core.Secret().Cache()))
 		return func(next http.Handler) http.Handler {
 			mux.NotFoundHandler = next
 			return mux
@@ -53,7 +54,7 @@ func AdditionalAPIs(ctx context.Context, config *wrangler.Context, steve *steve.
 	mux.UseEncodedPath()
 	mux.Handle("/v1/github{path:.*}", githubHandler)
 	mux.Handle("/v3/connect", Tunnel(config))
-	health.Register(mux)
+	health.Register(mux, config.Readiness)
 
 	return func(next http.Handler) http.Handler {
 		mux.NotFoundHandler = clusterAPI(next)
@@ -64,5 +65,5 @@ func AdditionalAPIs(ctx context.Context, config *wrangler.Context, steve *steve.
 func Tunnel(config *wrangler.Context) http.Handler {
 	config.TunnelAuthorizer.Add(proxy.NewAuthorizer(config))
 	config.TunnelAuthorizer.Add(aggregation.New(config))
-	return config.TunnelServer
+	return config.TunnelSessions.Handler(config.TunnelServer)
 }
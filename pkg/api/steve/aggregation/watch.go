@@ -0,0 +1,126 @@
+package aggregation
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	steveaggregation "github.com/rancher/steve/pkg/aggregation"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// NextTokenKey holds the token the aggregation client switches to while its token is being rotated. Once the
+	// client is connected with it and the connections of the replaced token are drained, it replaces the token.
+	NextTokenKey = "nextToken"
+	TokenKey     = "token"
+
+	// drainPeriod is how long the client connected with the replaced token keeps serving the connections in flight
+	drainPeriod = 30 * time.Second
+)
+
+// Watch connects to the steve aggregation server set by the secret, like the watcher of steve does. Unlike it, a
+// rotated token doesn't interrupt the connections in flight: the client connected with the next token is started
+// before the one connected with the replaced token is stopped, after a drain period.
+func Watch(ctx context.Context, secrets corecontrollers.SecretController, secretNamespace, secretName string, httpHandler http.Handler) {
+	if secretNamespace == "" || secretName == "" {
+		return
+	}
+	h := &watcher{
+		ctx:       ctx,
+		secrets:   secrets,
+		handler:   httpHandler,
+		namespace: secretNamespace,
+		name:      secretName,
+		listen:    steveaggregation.ListenAndServe,
+		now:       time.Now,
+	}
+	secrets.OnChange(ctx, "aggregation-controller", h.OnSecret)
+}
+
+type watcher struct {
+	ctx             context.Context
+	secrets         corecontrollers.SecretController
+	handler         http.Handler
+	namespace, name string
+	listen          func(ctx context.Context, url string, caCert []byte, token string, handler http.Handler)
+	now             func() time.Time
+
+	url    string
+	caCert []byte
+	token  string
+	cancel func()
+
+	// retiring stops the client connected with the replaced token once retireAt is reached
+	retiring func()
+	retireAt time.Time
+}
+
+func (h *watcher) OnSecret(key string, secret *corev1.Secret) (*corev1.Secret, error) {
+	if secret == nil || secret.Namespace != h.namespace || secret.Name != h.name {
+		return secret, nil
+	}
+
+	url := string(secret.Data["url"])
+	token := string(secret.Data[NextTokenKey])
+	if token == "" {
+		token = string(secret.Data[TokenKey])
+	}
+	if url == "" || token == "" {
+		return secret, nil
+	}
+	caCert := secret.Data["ca.crt"]
+
+	switch {
+	case h.cancel == nil:
+		logrus.Info("Starting steve aggregation client")
+		h.start(url, caCert, token)
+	case h.url != url || !bytes.Equal(h.caCert, caCert):
+		logrus.Info("Restarting steve aggregation client")
+		h.cancel()
+		h.stopRetiring()
+		h.start(url, caCert, token)
+	case h.token != token:
+		logrus.Info("Switching steve aggregation client to the rotated token")
+		h.stopRetiring()
+		h.retiring = h.cancel
+		h.retireAt = h.now().Add(drainPeriod)
+		h.start(url, caCert, token)
+	}
+
+	if h.retiring != nil {
+		if wait := h.retireAt.Sub(h.now()); wait > 0 {
+			h.secrets.EnqueueAfter(secret.Namespace, secret.Name, wait)
+			return secret, nil
+		}
+		h.stopRetiring()
+	}
+
+	if len(secret.Data[NextTokenKey]) > 0 {
+		secret = secret.DeepCopy()
+		secret.Data[TokenKey] = secret.Data[NextTokenKey]
+		delete(secret.Data, NextTokenKey)
+		return h.secrets.Update(secret)
+	}
+	return secret, nil
+}
+
+func (h *watcher) start(url string, caCert []byte, token string) {
+	ctx, cancel := context.WithCancel(h.ctx)
+	go h.listen(ctx, url, caCert, token, h.handler)
+
+	h.url = url
+	h.caCert = caCert
+	h.token = token
+	h.cancel = cancel
+}
+
+func (h *watcher) stopRetiring() {
+	if h.retiring != nil {
+		h.retiring()
+		h.retiring = nil
+	}
+}
@@ -0,0 +1,136 @@
+package aggregation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeSecrets struct {
+	corecontrollers.SecretController
+	updated  []*corev1.Secret
+	enqueued []time.Duration
+}
+
+func (f *fakeSecrets) Update(secret *corev1.Secret) (*corev1.Secret, error) {
+	f.updated = append(f.updated, secret)
+	return secret, nil
+}
+
+func (f *fakeSecrets) EnqueueAfter(namespace, name string, duration time.Duration) {
+	f.enqueued = append(f.enqueued, duration)
+}
+
+type client struct {
+	ctx   context.Context
+	token string
+}
+
+func newSecret(data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-system", Name: "steve-aggregation"},
+		Data:       map[string][]byte{"url": []byte("https://rancher.example.com/v3/connect")},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func TestWatchTokenRotation(t *testing.T) {
+	now := time.Now()
+	secrets := &fakeSecrets{}
+	var clients []client
+	h := &watcher{
+		ctx:       context.Background(),
+		secrets:   secrets,
+		namespace: "cattle-system",
+		name:      "steve-aggregation",
+		now:       func() time.Time { return now },
+	}
+	started := make(chan client, 2)
+	h.listen = func(ctx context.Context, url string, caCert []byte, token string, handler http.Handler) {
+		started <- client{ctx: ctx, token: token}
+	}
+	waitStarted := func() client {
+		select {
+		case c := <-started:
+			clients = append(clients, c)
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected an aggregation client to be started")
+			return client{}
+		}
+	}
+
+	_, err := h.OnSecret("", newSecret(map[string]string{TokenKey: "steve-cluster-old"}))
+	require.NoError(t, err)
+	assert.Equal(t, "steve-cluster-old", waitStarted().token)
+
+	// the next token is connected while the client of the replaced token keeps serving the connections in flight
+	rotating := newSecret(map[string]string{TokenKey: "steve-cluster-old", NextTokenKey: "steve-cluster-new"})
+	_, err = h.OnSecret("", rotating)
+	require.NoError(t, err)
+	assert.Equal(t, "steve-cluster-new", waitStarted().token)
+	assert.NoError(t, clients[0].ctx.Err(), "expected the client of the replaced token to keep serving while draining")
+	assert.Equal(t, []time.Duration{drainPeriod}, secrets.enqueued)
+	assert.Empty(t, secrets.updated)
+
+	// a resync while draining doesn't restart the client of the next token
+	now = now.Add(drainPeriod / 2)
+	_, err = h.OnSecret("", rotating)
+	require.NoError(t, err)
+	assert.Len(t, started, 0)
+	assert.NoError(t, clients[0].ctx.Err())
+
+	// once drained the client of the replaced token is stopped and the next token replaces it in the secret
+	now = now.Add(drainPeriod)
+	_, err = h.OnSecret("", rotating)
+	require.NoError(t, err)
+	assert.Error(t, clients[0].ctx.Err(), "expected the client of the replaced token to be stopped")
+	assert.NoError(t, clients[1].ctx.Err())
+	require.Len(t, secrets.updated, 1)
+	assert.Equal(t, "steve-cluster-new", string(secrets.updated[0].Data[TokenKey]))
+	assert.NotContains(t, secrets.updated[0].Data, NextTokenKey)
+
+	_, err = h.OnSecret("", secrets.updated[0])
+	require.NoError(t, err)
+	assert.Len(t, started, 0, "expected the promoted token not to restart the client")
+	assert.Len(t, secrets.updated, 1)
+}
+
+func TestWatchRestartsOnURLChange(t *testing.T) {
+	started := make(chan context.Context, 2)
+	h := &watcher{
+		ctx:       context.Background(),
+		secrets:   &fakeSecrets{},
+		namespace: "cattle-system",
+		name:      "steve-aggregation",
+		now:       time.Now,
+		listen: func(ctx context.Context, url string, caCert []byte, token string, handler http.Handler) {
+			started <- ctx
+		},
+	}
+
+	_, err := h.OnSecret("", newSecret(map[string]string{TokenKey: "steve-cluster-token"}))
+	require.NoError(t, err)
+	first := <-started
+
+	_, err = h.OnSecret("", newSecret(map[string]string{TokenKey: "steve-cluster-token"}))
+	require.NoError(t, err)
+	assert.Len(t, started, 0, "expected an unchanged secret not to restart the client")
+
+	moved := newSecret(map[string]string{TokenKey: "steve-cluster-token"})
+	moved.Data["url"] = []byte("https://rancher2.example.com/v3/connect")
+	_, err = h.OnSecret("", moved)
+	require.NoError(t, err)
+	<-started
+	assert.Error(t, first.Err(), "expected the client of the previous server to be stopped")
+}
@@ -7,9 +7,10 @@
 	"k8s.io/apiserver/pkg/server/healthz"
 )
 
-func Register(router *mux.Router) {
+func Register(router *mux.Router, readiness *Readiness) {
 	healthz.InstallHandler((*muxWrapper)(router))
 	router.Handle("/ping", Pong())
+	router.Handle("/readyz", readiness)
 }
 
 func Pong() http.Handler {
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReadyzTest(readiness *Readiness) func(path string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	Register(router, readiness)
+
+	return func(path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+		return rw
+	}
+}
+
+func TestReadyzLeader(t *testing.T) {
+	readiness := NewReadiness()
+	request := newReadyzTest(readiness)
+
+	assert.Equal(t, http.StatusServiceUnavailable, request("/readyz").Code)
+	assert.Equal(t, http.StatusOK, request("/ping").Code, "expected liveness not to depend on readiness")
+
+	readiness.Started()
+	failing := true
+	bootstrap := readiness.Track(func(ctx context.Context) error {
+		assert.Equal(t, http.StatusServiceUnavailable, request("/readyz").Code, "expected not to be ready while bootstrapping")
+		if failing {
+			return errors.New("system charts not installed yet")
+		}
+		return nil
+	})
+
+	require.Error(t, bootstrap(context.Background()))
+	assert.Equal(t, http.StatusServiceUnavailable, request("/readyz").Code, "expected not to be ready until the bootstrap succeeds")
+
+	failing = false
+	require.NoError(t, bootstrap(context.Background()))
+	rw := request("/readyz")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "ok", rw.Body.String())
+}
+
+func TestReadyzFollower(t *testing.T) {
+	readiness := NewReadiness()
+	request := newReadyzTest(readiness)
+
+	// the leader callbacks never run on the followers
+	readiness.Track(func(ctx context.Context) error { return nil })
+	assert.Equal(t, http.StatusServiceUnavailable, request("/readyz").Code)
+
+	readiness.Started()
+	assert.Equal(t, http.StatusOK, request("/readyz").Code)
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Readiness reports Rancher as ready once its startup completed and, on the leader, while none of the tracked
+// bootstrap steps run by the leader is running or failing. Liveness is left to /healthz, which doesn't depend on the
+// startup.
+type Readiness struct {
+	started int32
+	pending int32
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// Started marks the startup of Rancher as completed, the replicas which aren't the leader are ready from then on.
+func (r *Readiness) Started() {
+	atomic.StoreInt32(&r.started, 1)
+}
+
+// Track wraps a leader callback so that Rancher isn't ready from the time the callback first runs until it succeeds.
+// The leader callbacks run concurrently, each bootstrap step must be tracked.
+func (r *Readiness) Track(f func(ctx context.Context) error) func(ctx context.Context) error {
+	var once sync.Once
+	return func(ctx context.Context) error {
+		once.Do(func() {
+			atomic.AddInt32(&r.pending, 1)
+		})
+		if err := f(ctx); err != nil {
+			return err
+		}
+		atomic.AddInt32(&r.pending, -1)
+		return nil
+	}
+}
+
+func (r *Readiness) Ready() bool {
+	return atomic.LoadInt32(&r.started) == 1 && atomic.LoadInt32(&r.pending) == 0
+}
+
+func (r *Readiness) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !r.Ready() {
+		http.Error(rw, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	rw.Write([]byte("ok"))
+}
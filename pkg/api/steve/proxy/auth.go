@@ -3,6 +3,7 @@
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
@@ -25,7 +26,7 @@ func NewAuthorizer(wrangler *wrangler.Context) remotedialer.Authorizer {
 		tokenCache: wrangler.Mgmt.ClusterRegistrationToken().Cache(),
 	}
 	a.tokenCache.AddIndexer(tokenIndex, func(obj *apimgmtv3.ClusterRegistrationToken) ([]string, error) {
-		return []string{obj.Status.Token}, nil
+		return obj.Tokens(), nil
 	})
 
 	return a.Authorize
@@ -36,12 +37,18 @@ func (a *clusterProxyAuthorizer) Authorize(req *http.Request) (string, bool, err
 	if !strings.HasPrefix(auth, Prefix) {
 		return "", false, nil
 	}
-	crts, err := a.tokenCache.GetByIndex(tokenIndex, strings.TrimPrefix(auth, Prefix))
-	if apierror.IsNotFound(err) || len(crts) == 0 {
+	token := strings.TrimPrefix(auth, Prefix)
+	crts, err := a.tokenCache.GetByIndex(tokenIndex, token)
+	if apierror.IsNotFound(err) {
 		return "", false, nil
 	} else if err != nil {
 		return "", false, err
 	}
 
-	return Prefix + crts[0].Namespace, true, nil
+	for _, crt := range crts {
+		if crt.AcceptsToken(token, time.Now()) {
+			return Prefix + crt.Namespace, true, nil
+		}
+	}
+	return "", false, nil
 }
@@ -0,0 +1,48 @@
+package settings
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// store validates the values the settings are set to and attributes them to the user, like the norman API does.
+// Patches aren't decoded, so the settings they change are attributed to the system in the audit trail.
+type store struct {
+	types.Store
+}
+
+func (s *store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	if err := setUpdatedBy(apiOp, data); err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Create(apiOp, schema, data)
+}
+
+func (s *store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	if apiOp.Method != http.MethodPatch {
+		if err := setUpdatedBy(apiOp, data); err != nil {
+			return types.APIObject{}, err
+		}
+	}
+	return s.Store.Update(apiOp, schema, data, id)
+}
+
+func setUpdatedBy(apiOp *types.APIRequest, data types.APIObject) error {
+	obj := data.Data()
+	if obj == nil {
+		return nil
+	}
+	name, value := types.Name(obj), obj.String("value")
+	if err := settings.Validate(name, value); err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	if user, ok := request.UserFrom(apiOp.Context()); ok {
+		obj.SetNested(settings.UpdatedBy(user.GetName(), value), "metadata", "annotations", settings.UpdatedByAnnotation)
+	}
+	return nil
+}
@@ -91,6 +91,8 @@ type UserAttribute struct {
 	UserName        string
 	GroupPrincipals map[string]Principals // the value is a []Principal, but code generator cannot handle slice as a value
 	LastRefresh     string
+	LastLogin       string // RFC3339 time of the last login of the user
+	LastActivity    string // RFC3339 time the user last authenticated with one of its tokens, recorded at most hourly
 	NeedsRefresh    bool
 }
 
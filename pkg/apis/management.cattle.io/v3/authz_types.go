@@ -12,12 +12,13 @@
 )
 
 var (
-	NamespaceBackedResource                  condition.Cond = "BackingNamespaceCreated"
-	CreatorMadeOwner                         condition.Cond = "CreatorMadeOwner"
-	DefaultNetworkPolicyCreated              condition.Cond = "DefaultNetworkPolicyCreated"
-	ProjectConditionInitialRolesPopulated    condition.Cond = "InitialRolesPopulated"
-	ProjectConditionMonitoringEnabled        condition.Cond = "MonitoringEnabled"
-	ProjectConditionMetricExpressionDeployed condition.Cond = "MetricExpressionDeployed"
+	NamespaceBackedResource                    condition.Cond = "BackingNamespaceCreated"
+	CreatorMadeOwner                           condition.Cond = "CreatorMadeOwner"
+	DefaultNetworkPolicyCreated                condition.Cond = "DefaultNetworkPolicyCreated"
+	ProjectConditionInitialRolesPopulated      condition.Cond = "InitialRolesPopulated"
+	ProjectConditionMonitoringEnabled          condition.Cond = "MonitoringEnabled"
+	ProjectConditionMetricExpressionDeployed   condition.Cond = "MetricExpressionDeployed"
+	ProjectConditionResourceQuotaOverCommitted condition.Cond = "ResourceQuotaOverCommitted"
 )
 
 // +genclient
@@ -38,9 +39,10 @@ func (p *Project) ObjClusterName() string {
 }
 
 type ProjectStatus struct {
-	Conditions                    []ProjectCondition `json:"conditions"`
-	PodSecurityPolicyTemplateName string             `json:"podSecurityPolicyTemplateId"`
-	MonitoringStatus              *MonitoringStatus  `json:"monitoringStatus,omitempty" norman:"nocreate,noupdate"`
+	Conditions                    []ProjectCondition   `json:"conditions"`
+	PodSecurityPolicyTemplateName string               `json:"podSecurityPolicyTemplateId"`
+	MonitoringStatus              *MonitoringStatus    `json:"monitoringStatus,omitempty" norman:"nocreate,noupdate"`
+	ResourceQuotaUsage            []ResourceQuotaUsage `json:"resourceQuotaUsage,omitempty" norman:"nocreate,noupdate"`
 }
 
 type ProjectCondition struct {
@@ -193,3 +195,27 @@ func (c *ClusterRoleTemplateBinding) ObjClusterName() string {
 type SetPodSecurityPolicyTemplateInput struct {
 	PodSecurityPolicyTemplateName string `json:"podSecurityPolicyTemplateId" norman:"required,type=reference[podSecurityPolicyTemplate]"`
 }
+
+type SetMembersInput struct {
+	Members []RoleTemplateMember `json:"members,omitempty"`
+	Replace bool                 `json:"replace,omitempty"`
+}
+
+type RoleTemplateMember struct {
+	UserPrincipalName  string `json:"userPrincipalId,omitempty" norman:"type=reference[principal]"`
+	GroupPrincipalName string `json:"groupPrincipalId,omitempty" norman:"type=reference[principal]"`
+	RoleTemplateName   string `json:"roleTemplateId,omitempty" norman:"required,type=reference[roleTemplate]"`
+}
+
+type SetMembersOutput struct {
+	Results []RoleTemplateMemberResult `json:"results,omitempty"`
+}
+
+type RoleTemplateMemberResult struct {
+	UserPrincipalName  string `json:"userPrincipalId,omitempty"`
+	GroupPrincipalName string `json:"groupPrincipalId,omitempty"`
+	RoleTemplateName   string `json:"roleTemplateId,omitempty"`
+	BindingName        string `json:"bindingId,omitempty"`
+	Result             string `json:"result,omitempty" norman:"type=enum,options=created|exists|removed|invalid|failed"`
+	Message            string `json:"message,omitempty"`
+}
@@ -4,6 +4,7 @@
 	"bytes"
 	"encoding/gob"
 	"strings"
+	"time"
 
 	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
 	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
@@ -25,19 +26,25 @@ func init() {
 type ClusterConditionType string
 
 const (
-	ClusterActionGenerateKubeconfig    = "generateKubeconfig"
-	ClusterActionImportYaml            = "importYaml"
-	ClusterActionExportYaml            = "exportYaml"
-	ClusterActionViewMonitoring        = "viewMonitoring"
-	ClusterActionEditMonitoring        = "editMonitoring"
-	ClusterActionEnableMonitoring      = "enableMonitoring"
-	ClusterActionDisableMonitoring     = "disableMonitoring"
-	ClusterActionBackupEtcd            = "backupEtcd"
-	ClusterActionRestoreFromEtcdBackup = "restoreFromEtcdBackup"
-	ClusterActionRotateCertificates    = "rotateCertificates"
-	ClusterActionRotateEncryptionKey   = "rotateEncryptionKey"
-	ClusterActionRunSecurityScan       = "runSecurityScan"
-	ClusterActionSaveAsTemplate        = "saveAsTemplate"
+	ClusterActionGenerateKubeconfig        = "generateKubeconfig"
+	ClusterActionImportYaml                = "importYaml"
+	ClusterActionExportYaml                = "exportYaml"
+	ClusterActionViewMonitoring            = "viewMonitoring"
+	ClusterActionEditMonitoring            = "editMonitoring"
+	ClusterActionEnableMonitoring          = "enableMonitoring"
+	ClusterActionDisableMonitoring         = "disableMonitoring"
+	ClusterActionBackupEtcd                = "backupEtcd"
+	ClusterActionNodeCommand               = "nodeCommand"
+	ClusterActionResolvedImages            = "resolvedImages"
+	ClusterActionRestoreFromEtcdBackup     = "restoreFromEtcdBackup"
+	ClusterActionRotateCertificates        = "rotateCertificates"
+	ClusterActionRotateEncryptionKey       = "rotateEncryptionKey"
+	ClusterActionRotateServiceAccountToken = "rotateServiceAccountToken"
+	ClusterActionRunSecurityScan           = "runSecurityScan"
+	ClusterActionSaveAsTemplate            = "saveAsTemplate"
+	ClusterActionSetMembers                = "setMembers"
+
+	ClusterRegistrationTokenActionRegenerateManifest = "regenerateManifest"
 
 	// ClusterConditionReady Cluster ready to serve API (healthy when true, unhealthy when false)
 	ClusterConditionReady          condition.Cond = "Ready"
@@ -71,6 +78,12 @@ func init() {
 	ClusterConditionPrometheusOperatorDeployed condition.Cond = "PrometheusOperatorDeployed"
 	ClusterConditionMonitoringEnabled          condition.Cond = "MonitoringEnabled"
 	ClusterConditionAlertingEnabled            condition.Cond = "AlertingEnabled"
+	// ClusterConditionCACertMismatch is true when the cluster presents a certificate that isn't signed by the CA cert
+	// in the status of the cluster, usually because the CA of the cluster was rotated or has expired
+	ClusterConditionCACertMismatch condition.Cond = "CACertMismatch"
+	// ClusterConditionAgentHeartbeatStale is true when the cluster agent hasn't checked in for longer than the
+	// agent-heartbeat-stale-threshold setting
+	ClusterConditionAgentHeartbeatStale condition.Cond = "AgentHeartbeatStale"
 
 	ClusterDriverImported = "imported"
 	ClusterDriverLocal    = "local"
@@ -82,6 +95,19 @@ func init() {
 	ClusterDriverEKS      = "EKS"
 	ClusterDriverGKE      = "GKE"
 	ClusterDriverRancherD = "rancherd"
+
+	// ClusterAgentCACertAnnotation holds the CA cert reported by the cluster agent when it differs from the CA cert in
+	// the status of a cluster that isn't imported
+	ClusterAgentCACertAnnotation = "management.cattle.io/agent-ca-cert"
+
+	// ClusterRotatedServiceAccountTokenAnnotation is set on clusters whose service account token was rotated by
+	// Rancher, the token reported by the agent of an imported cluster then no longer replaces it
+	ClusterRotatedServiceAccountTokenAnnotation = "management.cattle.io/rotated-service-account-token"
+
+	// ClusterClientQPSAnnotation and ClusterClientBurstAnnotation override the cluster-client-qps and
+	// cluster-client-burst settings limiting the requests of Rancher to the API server of the cluster
+	ClusterClientQPSAnnotation   = "management.cattle.io/client-qps"
+	ClusterClientBurstAnnotation = "management.cattle.io/client-burst"
 )
 
 // +genclient
@@ -106,6 +132,8 @@ type ClusterSpecBase struct {
 	DesiredAuthImage                     string                                  `json:"desiredAuthImage"`
 	AgentImageOverride                   string                                  `json:"agentImageOverride"`
 	AgentEnvVars                         []v1.EnvVar                             `json:"agentEnvVars,omitempty"`
+	AgentInstallScript                   *AgentInstallScript                     `json:"agentInstallScript,omitempty"`
+	AgentNodeCommandCustomization        *AgentNodeCommandCustomization          `json:"agentNodeCommandCustomization,omitempty"`
 	RancherKubernetesEngineConfig        *rketypes.RancherKubernetesEngineConfig `json:"rancherKubernetesEngineConfig,omitempty"`
 	DefaultPodSecurityPolicyTemplateName string                                  `json:"defaultPodSecurityPolicyTemplateName,omitempty" norman:"type=reference[podSecurityPolicyTemplate]"`
 	DefaultClusterRoleForProjectMembers  string                                  `json:"defaultClusterRoleForProjectMembers,omitempty" norman:"type=reference[roleTemplate]"`
@@ -116,6 +144,65 @@ type ClusterSpecBase struct {
 	WindowsPreferedCluster               bool                                    `json:"windowsPreferedCluster" norman:"noupdate"`
 	LocalClusterAuthEndpoint             LocalClusterAuthEndpoint                `json:"localClusterAuthEndpoint,omitempty"`
 	ScheduledClusterScan                 *ScheduledClusterScan                   `json:"scheduledClusterScan,omitempty"`
+	NodeMetadataPolicy                   *NodeMetadataPolicy                     `json:"nodeMetadataPolicy,omitempty"`
+	ExecAuth                             *ClusterExecAuth                        `json:"execAuth,omitempty"`
+}
+
+// ClusterExecAuth configures Rancher to authenticate to the API server of the cluster with the short lived tokens, such
+// as OIDC ID tokens, returned by a client-go exec credential plugin instead of the service account token. The command
+// and its args must be allowed by the cluster-exec-auth-commands setting and only admins may configure it.
+type ClusterExecAuth struct {
+	// Command is the path of the plugin on the Rancher server
+	Command string `json:"command,omitempty"`
+	// Args must match the patterns of the args of the command in the cluster-exec-auth-commands setting
+	Args []string `json:"args,omitempty"`
+	// Env are the environment variables added to the environment of the plugin
+	Env []ClusterExecAuthEnvVar `json:"env,omitempty"`
+	// APIVersion is the version of the ExecCredential exchanged with the plugin, defaults to
+	// client.authentication.k8s.io/v1beta1
+	APIVersion string `json:"apiVersion,omitempty"`
+}
+
+// ClusterExecAuthEnvVar is an environment variable of the exec credential plugin, its value is read from a secret of
+// the cattle-global-data namespace as the values are usually credentials
+type ClusterExecAuthEnvVar struct {
+	Name string `json:"name"`
+	// SecretName is the name of the secret holding the value
+	SecretName string `json:"secretName"`
+	// Key is the key of the value in the secret
+	Key string `json:"key"`
+}
+
+// AgentInstallScript is the source of the system agent install script of an RKE2/K3s cluster, copied from the
+// agentInstallScript of the rkeConfig of the provisioning cluster.
+type AgentInstallScript struct {
+	URL          string                   `json:"url,omitempty"`
+	ConfigMapRef *v1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+	SHA256       string                   `json:"sha256,omitempty"`
+}
+
+// AgentNodeCommandCustomization holds the overrides applied to the docker run command that registers
+// RKE nodes with the cluster.
+type AgentNodeCommandCustomization struct {
+	// Env is passed to the agent container in addition to AgentEnvVars
+	Env []v1.EnvVar `json:"env,omitempty"`
+	// VolumeMounts are additional host:container[:options] bind mounts
+	VolumeMounts []string `json:"volumeMounts,omitempty"`
+	// CapAdd replaces --privileged with the listed capabilities when set
+	CapAdd []string `json:"capAdd,omitempty"`
+	// ExtraFlags are additional docker run flags, limited to an allowlist
+	ExtraFlags []string `json:"extraFlags,omitempty"`
+}
+
+// NodeMetadataPolicy limits the labels and taints Rancher enforces on the Kubernetes nodes of the cluster.
+// When unset, all the labels and taints of the nodes are reconciled as before.
+type NodeMetadataPolicy struct {
+	// ManagedLabelPrefixes are the prefixes of the label keys Rancher manages exclusively, labels with
+	// other keys are left to the tools managing the nodes
+	ManagedLabelPrefixes []string `json:"managedLabelPrefixes,omitempty"`
+	// ManagedTaintPrefixes are the prefixes of the taint keys Rancher manages exclusively, taints with
+	// other keys are never removed by Rancher
+	ManagedTaintPrefixes []string `json:"managedTaintPrefixes,omitempty"`
 }
 
 type ClusterSpec struct {
@@ -155,6 +242,8 @@ type ClusterStatus struct {
 	AgentImage                           string                      `json:"agentImage"`
 	AppliedAgentEnvVars                  []v1.EnvVar                 `json:"appliedAgentEnvVars,omitempty"`
 	AgentFeatures                        map[string]bool             `json:"agentFeatures,omitempty"`
+	AgentLastSeen                        string                      `json:"agentLastSeen,omitempty" norman:"nocreate,noupdate"`
+	AgentVersion                         string                      `json:"agentVersion,omitempty" norman:"nocreate,noupdate"`
 	AuthImage                            string                      `json:"authImage"`
 	ComponentStatuses                    []ClusterComponentStatus    `json:"componentStatuses,omitempty"`
 	APIEndpoint                          string                      `json:"apiEndpoint,omitempty"`
@@ -248,6 +337,8 @@ func (c *ClusterRegistrationToken) ObjClusterName() string {
 
 type ClusterRegistrationTokenSpec struct {
 	ClusterName string `json:"clusterName" norman:"required,type=reference[cluster]"`
+	// SingleUseManifest serves the import manifest of the token once, until it is regenerated
+	SingleUseManifest bool `json:"singleUseManifest,omitempty"`
 }
 
 func (c *ClusterRegistrationTokenSpec) ObjClusterName() string {
@@ -262,6 +353,71 @@ type ClusterRegistrationTokenStatus struct {
 	InsecureNodeCommand string `json:"insecureNodeCommand"`
 	ManifestURL         string `json:"manifestUrl"`
 	Token               string `json:"token"`
+	// TokenIssuedAt is when the token was generated or last rotated, in RFC3339 format
+	TokenIssuedAt string `json:"tokenIssuedAt,omitempty"`
+	// NextToken is the token agents switch to while the token is being rotated, it is accepted along with Token
+	NextToken         string `json:"nextToken,omitempty"`
+	NextTokenIssuedAt string `json:"nextTokenIssuedAt,omitempty"`
+	// PreviousToken is the token replaced by the last rotation, it is still accepted until PreviousTokenExpiresAt so
+	// agents connected with it can switch over
+	PreviousToken          string `json:"previousToken,omitempty"`
+	PreviousTokenExpiresAt string `json:"previousTokenExpiresAt,omitempty"`
+	// ManifestFetchCount is the number of times the import manifest was served since it was last regenerated, the
+	// first and last times it was served are in RFC3339 format
+	ManifestFetchCount     int    `json:"manifestFetchCount,omitempty"`
+	ManifestFirstFetchedAt string `json:"manifestFirstFetchedAt,omitempty"`
+	ManifestLastFetchedAt  string `json:"manifestLastFetchedAt,omitempty"`
+}
+
+// Tokens returns every token agents may authenticate with, regardless of the expiry of the previous token
+func (c *ClusterRegistrationToken) Tokens() []string {
+	var tokens []string
+	for _, token := range []string{c.Status.Token, c.Status.NextToken, c.Status.PreviousToken} {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// AgentToken returns the token agents should be configured with, the next token while the token is being rotated
+func (c *ClusterRegistrationToken) AgentToken() string {
+	if c.Status.NextToken != "" {
+		return c.Status.NextToken
+	}
+	return c.Status.Token
+}
+
+// PromoteToken makes the token the current token and ends any rotation in progress. The replaced token is kept as the
+// previous token until the grace period passes, so agents connected with it can switch over.
+func (c *ClusterRegistrationToken) PromoteToken(token string, now time.Time, grace time.Duration) {
+	status := &c.Status
+	if status.Token != "" && grace > 0 {
+		status.PreviousToken = status.Token
+		status.PreviousTokenExpiresAt = now.UTC().Add(grace).Format(time.RFC3339)
+	} else {
+		status.PreviousToken = ""
+		status.PreviousTokenExpiresAt = ""
+	}
+	status.Token = token
+	status.TokenIssuedAt = now.UTC().Format(time.RFC3339)
+	status.NextToken = ""
+	status.NextTokenIssuedAt = ""
+}
+
+// AcceptsToken returns true if agents may authenticate with the token at the given time
+func (c *ClusterRegistrationToken) AcceptsToken(token string, now time.Time) bool {
+	if token == "" {
+		return false
+	}
+	if token == c.Status.Token || token == c.Status.NextToken {
+		return true
+	}
+	if token != c.Status.PreviousToken {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, c.Status.PreviousTokenExpiresAt)
+	return err == nil && now.Before(expiresAt)
 }
 
 type GenerateKubeConfigOutput struct {
@@ -341,6 +497,24 @@ type RotateEncryptionKeyOutput struct {
 	Message string `json:"message,omitempty"`
 }
 
+type RotateServiceAccountTokenOutput struct {
+	Message string `json:"message,omitempty"`
+}
+
+type NodeCommandInput struct {
+	OS       string `json:"os,omitempty" norman:"type=enum,options=linux|windows,default=linux"`
+	Runtime  string `json:"runtime,omitempty" norman:"type=enum,options=docker|rke2"`
+	Insecure bool   `json:"insecure,omitempty"`
+}
+
+type NodeCommandOutput struct {
+	Command string `json:"command,omitempty"`
+}
+
+type ResolvedImagesOutput struct {
+	Images map[string]string `json:"images,omitempty"`
+}
+
 type LocalClusterAuthEndpoint struct {
 	Enabled bool   `json:"enabled"`
 	FQDN    string `json:"fqdn,omitempty"`
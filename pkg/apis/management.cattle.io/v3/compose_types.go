@@ -23,14 +23,34 @@ type ComposeConfig struct {
 
 type ComposeSpec struct {
 	RancherCompose string `json:"rancherCompose,omitempty"`
+	// Prune re-applies the compose config when it changes and deletes the resources it created that were removed
+	// from it
+	Prune bool `json:"prune,omitempty"`
+	// TeardownOnDelete deletes the resources created by the compose config when the compose config is deleted.
+	// Resources that already existed and were updated by the compose config are never deleted.
+	TeardownOnDelete bool `json:"teardownOnDelete,omitempty"`
 }
 
 type ComposeStatus struct {
 	Conditions []ComposeCondition `json:"conditions,omitempty"`
+	// AppliedChecksum is the checksum of the last applied compose config, only set when pruning
+	AppliedChecksum string `json:"appliedChecksum,omitempty"`
+	// Resources are the remove links of the resources created by the compose config by schema and resource ID, only
+	// set when pruning. The resources that existed before the compose config applied them are not tracked, so they are
+	// never pruned
+	Resources map[string]map[string]string `json:"resources,omitempty"`
+	// Created are the remove links of the resources created, rather than updated, by the compose config by schema and
+	// resource ID, only set with teardownOnDelete
+	Created map[string]map[string]string `json:"created,omitempty"`
+	// ValidatedChecksum is the checksum of the last validated compose config, only set in validation-only mode
+	ValidatedChecksum string `json:"validatedChecksum,omitempty"`
+	// ValidationErrors are the errors found by the last validation of the compose config
+	ValidationErrors []string `json:"validationErrors,omitempty"`
 }
 
 var (
-	ComposeConditionExecuted condition.Cond = "Executed"
+	ComposeConditionExecuted  condition.Cond = "Executed"
+	ComposeConditionValidated condition.Cond = "Validated"
 )
 
 type ComposeCondition struct {
@@ -52,6 +52,13 @@ type NodeTemplateSpec struct {
 	CloudCredentialName string     `json:"cloudCredentialName" norman:"type=reference[cloudCredential]"`
 	NodeTaints          []v1.Taint `json:"nodeTaints,omitempty"`
 	NodeCommonParams    `json:",inline"`
+
+	// RegistrationTimeoutSecs is the time a provisioned node may wait to register with Kubernetes before it is marked
+	// as failed, 0 disables the timeout
+	RegistrationTimeoutSecs time.Duration `json:"registrationTimeoutSecs,omitempty" norman:"default=0,max=31540000,min=0"`
+
+	// RequireIMDSv2 requires the amazonec2 instances of the template to use IMDSv2 to reach their metadata service
+	RequireIMDSv2 bool `json:"requireImdsV2,omitempty"`
 }
 
 // +genclient
@@ -100,6 +107,9 @@ type NodeStatus struct {
 	DockerInfo         *DockerInfo             `json:"dockerInfo,omitempty"`
 	NodePlan           *NodePlan               `json:"nodePlan,omitempty"`
 	AppliedNodeVersion int                     `json:"appliedNodeVersion,omitempty"`
+	// NormalizedHostname is the requested hostname normalized for the constraints of the node driver, it is the
+	// hostname the node is provisioned with
+	NormalizedHostname string `json:"normalizedHostname,omitempty"`
 }
 
 type DockerInfo struct {
@@ -135,6 +145,8 @@ type DockerInfo struct {
 	NodeConditionReady       condition.Cond = "Ready"
 	NodeConditionDrained     condition.Cond = "Drained"
 	NodeConditionUpgraded    condition.Cond = "Upgraded"
+	// NodeConditionCredentialValid is false while the cloud credential of the node template can't be found
+	NodeConditionCredentialValid condition.Cond = "CredentialValid"
 )
 
 type NodeCondition struct {
@@ -192,6 +204,12 @@ type NodePoolSpec struct {
 	ClusterName string `json:"clusterName,omitempty" norman:"type=reference[cluster],noupdate,required"`
 
 	DeleteNotReadyAfterSecs time.Duration `json:"deleteNotReadyAfterSecs" norman:"default=0,max=31540000,min=0"`
+
+	// RegistrationTimeoutSecs is the time a provisioned node may wait to register with Kubernetes before it is marked
+	// as failed, it overrides the timeout of the node template when set
+	RegistrationTimeoutSecs time.Duration `json:"registrationTimeoutSecs" norman:"default=0,max=31540000,min=0"`
+	// ReplaceUnregisteredNodes deletes nodes that hit the registration timeout so they are recreated
+	ReplaceUnregisteredNodes bool `json:"replaceUnregisteredNodes" norman:"default=false"`
 }
 
 func (n *NodePoolSpec) ObjClusterName() string {
@@ -253,6 +271,7 @@ type NodeCommonParams struct {
 	AuthCertificateAuthority string            `json:"authCertificateAuthority,omitempty"`
 	AuthKey                  string            `json:"authKey,omitempty"`
 	EngineInstallURL         string            `json:"engineInstallURL,omitempty"`
+	EngineInstallURLChecksum string            `json:"engineInstallURLChecksum,omitempty"`
 	DockerVersion            string            `json:"dockerVersion,omitempty"`
 	EngineOpt                map[string]string `json:"engineOpt,omitempty"`
 	EngineInsecureRegistry   []string          `json:"engineInsecureRegistry,omitempty"`
@@ -25,6 +25,14 @@ type ResourceQuotaLimit struct {
 	LimitsMemory           string `json:"limitsMemory,omitempty"`
 }
 
+// ResourceQuotaUsage reports the quota of a resource allocated to the namespaces of a project against the limit of the
+// project
+type ResourceQuotaUsage struct {
+	Resource string `json:"resource,omitempty"`
+	Used     string `json:"used,omitempty"`
+	Limit    string `json:"limit,omitempty"`
+}
+
 type ContainerResourceLimit struct {
 	RequestsCPU    string `json:"requestsCpu,omitempty"`
 	RequestsMemory string `json:"requestsMemory,omitempty"`
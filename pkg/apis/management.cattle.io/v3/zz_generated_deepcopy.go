@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 /*
@@ -362,6 +363,65 @@ func (in *ActiveDirectoryTestAndApplyInput) DeepCopy() *ActiveDirectoryTestAndAp
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentInstallScript) DeepCopyInto(out *AgentInstallScript) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentInstallScript.
+func (in *AgentInstallScript) DeepCopy() *AgentInstallScript {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentInstallScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentNodeCommandCustomization) DeepCopyInto(out *AgentNodeCommandCustomization) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CapAdd != nil {
+		in, out := &in.CapAdd, &out.CapAdd
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraFlags != nil {
+		in, out := &in.ExtraFlags, &out.ExtraFlags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentNodeCommandCustomization.
+func (in *AgentNodeCommandCustomization) DeepCopy() *AgentNodeCommandCustomization {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentNodeCommandCustomization)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertCommonSpec) DeepCopyInto(out *AlertCommonSpec) {
 	*out = *in
@@ -1775,6 +1835,48 @@ func (in *ClusterCondition) DeepCopy() *ClusterCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterExecAuth) DeepCopyInto(out *ClusterExecAuth) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]ClusterExecAuthEnvVar, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterExecAuth.
+func (in *ClusterExecAuth) DeepCopy() *ClusterExecAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterExecAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterExecAuthEnvVar) DeepCopyInto(out *ClusterExecAuthEnvVar) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterExecAuthEnvVar.
+func (in *ClusterExecAuthEnvVar) DeepCopy() *ClusterExecAuthEnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterExecAuthEnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterGroupSpec) DeepCopyInto(out *ClusterGroupSpec) {
 	*out = *in
@@ -2429,6 +2531,16 @@ func (in *ClusterSpecBase) DeepCopyInto(out *ClusterSpecBase) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AgentInstallScript != nil {
+		in, out := &in.AgentInstallScript, &out.AgentInstallScript
+		*out = new(AgentInstallScript)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AgentNodeCommandCustomization != nil {
+		in, out := &in.AgentNodeCommandCustomization, &out.AgentNodeCommandCustomization
+		*out = new(AgentNodeCommandCustomization)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RancherKubernetesEngineConfig != nil {
 		in, out := &in.RancherKubernetesEngineConfig, &out.RancherKubernetesEngineConfig
 		*out = new(types.RancherKubernetesEngineConfig)
@@ -2445,6 +2557,16 @@ func (in *ClusterSpecBase) DeepCopyInto(out *ClusterSpecBase) {
 		*out = new(ScheduledClusterScan)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NodeMetadataPolicy != nil {
+		in, out := &in.NodeMetadataPolicy, &out.NodeMetadataPolicy
+		*out = new(NodeMetadataPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExecAuth != nil {
+		in, out := &in.ExecAuth, &out.ExecAuth
+		*out = new(ClusterExecAuth)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -2986,6 +3108,45 @@ func (in *ComposeStatus) DeepCopyInto(out *ComposeStatus) {
 		*out = make([]ComposeCondition, len(*in))
 		copy(*out, *in)
 	}
+	if in.Created != nil {
+		in, out := &in.Created, &out.Created
+		*out = make(map[string]map[string]string, len(*in))
+		for key, val := range *in {
+			var outVal map[string]string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]map[string]string, len(*in))
+		for key, val := range *in {
+			var outVal map[string]string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.ValidationErrors != nil {
+		in, out := &in.ValidationErrors, &out.ValidationErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -6028,6 +6189,38 @@ func (in *Node) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCommandInput) DeepCopyInto(out *NodeCommandInput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCommandInput.
+func (in *NodeCommandInput) DeepCopy() *NodeCommandInput {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCommandInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCommandOutput) DeepCopyInto(out *NodeCommandOutput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCommandOutput.
+func (in *NodeCommandOutput) DeepCopy() *NodeCommandOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCommandOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeCommonParams) DeepCopyInto(out *NodeCommonParams) {
 	*out = *in
@@ -6232,6 +6425,32 @@ func (in *NodeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMetadataPolicy) DeepCopyInto(out *NodeMetadataPolicy) {
+	*out = *in
+	if in.ManagedLabelPrefixes != nil {
+		in, out := &in.ManagedLabelPrefixes, &out.ManagedLabelPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ManagedTaintPrefixes != nil {
+		in, out := &in.ManagedTaintPrefixes, &out.ManagedTaintPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMetadataPolicy.
+func (in *NodeMetadataPolicy) DeepCopy() *NodeMetadataPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMetadataPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePlan) DeepCopyInto(out *NodePlan) {
 	*out = *in
@@ -8272,6 +8491,11 @@ func (in *ProjectStatus) DeepCopyInto(out *ProjectStatus) {
 		*out = new(MonitoringStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ResourceQuotaUsage != nil {
+		in, out := &in.ResourceQuotaUsage, &out.ResourceQuotaUsage
+		*out = make([]ResourceQuotaUsage, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -8565,6 +8789,29 @@ func (in *Recipient) DeepCopy() *Recipient {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedImagesOutput) DeepCopyInto(out *ResolvedImagesOutput) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedImagesOutput.
+func (in *ResolvedImagesOutput) DeepCopy() *ResolvedImagesOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedImagesOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceQuotaLimit) DeepCopyInto(out *ResourceQuotaLimit) {
 	*out = *in
@@ -8581,6 +8828,22 @@ func (in *ResourceQuotaLimit) DeepCopy() *ResourceQuotaLimit {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceQuotaUsage) DeepCopyInto(out *ResourceQuotaUsage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaUsage.
+func (in *ResourceQuotaUsage) DeepCopy() *ResourceQuotaUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceQuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoreFromEtcdBackupInput) DeepCopyInto(out *RestoreFromEtcdBackupInput) {
 	*out = *in
@@ -8867,6 +9130,38 @@ func (in *RoleTemplateList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleTemplateMember) DeepCopyInto(out *RoleTemplateMember) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleTemplateMember.
+func (in *RoleTemplateMember) DeepCopy() *RoleTemplateMember {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleTemplateMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleTemplateMemberResult) DeepCopyInto(out *RoleTemplateMemberResult) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleTemplateMemberResult.
+func (in *RoleTemplateMemberResult) DeepCopy() *RoleTemplateMemberResult {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleTemplateMemberResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RollingUpdate) DeepCopyInto(out *RollingUpdate) {
 	*out = *in
@@ -8936,6 +9231,22 @@ func (in *RotateEncryptionKeyOutput) DeepCopy() *RotateEncryptionKeyOutput {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotateServiceAccountTokenOutput) DeepCopyInto(out *RotateServiceAccountTokenOutput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotateServiceAccountTokenOutput.
+func (in *RotateServiceAccountTokenOutput) DeepCopy() *RotateServiceAccountTokenOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(RotateServiceAccountTokenOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route53ProviderConfig) DeepCopyInto(out *Route53ProviderConfig) {
 	*out = *in
@@ -9328,6 +9639,48 @@ func (in *SearchPrincipalsInput) DeepCopy() *SearchPrincipalsInput {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SetMembersInput) DeepCopyInto(out *SetMembersInput) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]RoleTemplateMember, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SetMembersInput.
+func (in *SetMembersInput) DeepCopy() *SetMembersInput {
+	if in == nil {
+		return nil
+	}
+	out := new(SetMembersInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SetMembersOutput) DeepCopyInto(out *SetMembersOutput) {
+	*out = *in
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]RoleTemplateMemberResult, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SetMembersOutput.
+func (in *SetMembersOutput) DeepCopy() *SetMembersOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(SetMembersOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SetPasswordInput) DeepCopyInto(out *SetPasswordInput) {
 	*out = *in
@@ -28,6 +28,14 @@ type ClusterSpec struct {
 	DefaultPodSecurityPolicyTemplateName string          `json:"defaultPodSecurityPolicyTemplateName,omitempty" norman:"type=reference[podSecurityPolicyTemplate]"`
 	DefaultClusterRoleForProjectMembers  string          `json:"defaultClusterRoleForProjectMembers,omitempty" norman:"type=reference[roleTemplate]"`
 	EnableNetworkPolicy                  *bool           `json:"enableNetworkPolicy,omitempty" norman:"default=false"`
+
+	// ClusterProfileName is the name of the profile, in the namespace of the cluster, the spec of the cluster is
+	// rendered from
+	ClusterProfileName string `json:"clusterProfileName,omitempty"`
+
+	// Paused stops the objects generated for the cluster and the plans of its machines from being updated, the status
+	// of the cluster is still reported and its machines can still be deleted
+	Paused bool `json:"paused,omitempty"`
 }
 
 type ClusterStatus struct {
@@ -38,6 +46,17 @@ type ClusterStatus struct {
 	ObservedGeneration int64                               `json:"observedGeneration"`
 	Conditions         []genericcondition.GenericCondition `json:"conditions,omitempty"`
 	ETCDSnapshots      []rkev1.ETCDSnapshot                `json:"etcdSnapshots,omitempty"`
+	MachinePools       []MachinePoolStatus                 `json:"machinePools,omitempty"`
+	UpgradeStatus      *rkev1.UpgradeStatus                `json:"upgradeStatus,omitempty"`
+}
+
+// MachinePoolStatus is the readiness of the machines of a machine pool, read from its MachineDeployment
+type MachinePoolStatus struct {
+	Name              string `json:"name,omitempty"`
+	Replicas          int32  `json:"replicas"`
+	ReadyReplicas     int32  `json:"readyReplicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+	UpdatedReplicas   int32  `json:"updatedReplicas"`
 }
 
 type ImportedConfig struct {
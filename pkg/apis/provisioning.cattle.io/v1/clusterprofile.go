@@ -0,0 +1,57 @@
+package v1
+
+import (
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/genericcondition"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ClusterProfileAnnotation is the name of the profile the spec of a cluster was last rendered from
+	ClusterProfileAnnotation = "provisioning.cattle.io/cluster-profile"
+	// ClusterProfileRevisionAnnotation is the revision of the profile the spec of a cluster was last rendered from
+	ClusterProfileRevisionAnnotation = "provisioning.cattle.io/cluster-profile-revision"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterProfile is an approved configuration of RKE2 clusters. The clusters that reference a profile get the fields set
+// in the template of the profile, the rest of their spec is left to each cluster.
+type ClusterProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterProfileSpec   `json:"spec"`
+	Status ClusterProfileStatus `json:"status,omitempty"`
+}
+
+type ClusterProfileSpec struct {
+	// KubernetesVersionRange is a semver constraint, such as ">= 1.21, < 1.22", the kubernetes version of the clusters
+	// must satisfy
+	KubernetesVersionRange string `json:"kubernetesVersionRange,omitempty"`
+
+	Template ClusterProfileTemplate `json:"template,omitempty"`
+
+	// RolloutRevision confirms the rollout of a revision of the profile to the clusters that were rendered from an
+	// older revision. Clusters stay on their revision until it is set to the current revision of the profile.
+	RolloutRevision int64 `json:"rolloutRevision,omitempty"`
+}
+
+// ClusterProfileTemplate holds the fields managed by a profile, fields left empty are not managed
+type ClusterProfileTemplate struct {
+	KubernetesVersion                    string           `json:"kubernetesVersion,omitempty"`
+	AgentEnvVars                         []corev1.EnvVar  `json:"agentEnvVars,omitempty"`
+	DefaultPodSecurityPolicyTemplateName string           `json:"defaultPodSecurityPolicyTemplateName,omitempty"`
+	Registries                           *rkev1.Registry  `json:"registries,omitempty"`
+	ControlPlaneConfig                   rkev1.GenericMap `json:"controlPlaneConfig,omitempty" wrangler:"nullable"`
+}
+
+type ClusterProfileStatus struct {
+	// Revision is incremented every time the kubernetes version range or the template of the profile changes
+	Revision           int64                               `json:"revision,omitempty"`
+	Hash               string                              `json:"hash,omitempty"`
+	ObservedGeneration int64                               `json:"observedGeneration"`
+	Conditions         []genericcondition.GenericCondition `json:"conditions,omitempty"`
+}
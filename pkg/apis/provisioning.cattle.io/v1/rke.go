@@ -20,6 +20,9 @@ type RKEMachinePool struct {
 	RollingUpdate                *RKEMachinePoolRollingUpdate `json:"rollingUpdate,omitempty"`
 	MachineDeploymentLabels      map[string]string            `json:"machineDeploymentLabels,omitempty"`
 	MachineDeploymentAnnotations map[string]string            `json:"machineDeploymentAnnotations,omitempty"`
+	KubeletArgs                  map[string]string            `json:"kubeletArgs,omitempty"`
+	UserDataSecretName           string                       `json:"userDataSecretName,omitempty"`
+	UserDataConfigMapName        string                       `json:"userDataConfigMapName,omitempty"`
 }
 
 type RKEMachinePoolRollingUpdate struct {
@@ -62,4 +65,14 @@ type RKEConfig struct {
 	ETCDSnapshotRestore *rkev1.ETCDSnapshot       `json:"etcdSnapshotRestore,omitempty"`
 	MachinePools        []RKEMachinePool          `json:"machinePools,omitempty"`
 	InfrastructureRef   *corev1.ObjectReference   `json:"infrastructureRef,omitempty"`
+
+	// ControlPlaneEndpoint is the address of a load balancer or VIP in front of the control plane nodes, used by the
+	// cluster-api cluster instead of the address of a control plane node
+	ControlPlaneEndpoint *rkev1.Endpoint `json:"controlPlaneEndpoint,omitempty"`
+
+	// GeneratedObjectLabels and GeneratedObjectAnnotations are added to all the cluster-api objects generated for the
+	// cluster, e.g. to charge their cost back to a cost center. They don't override the labels and annotations set on
+	// the objects by Rancher or by the machine pools.
+	GeneratedObjectLabels      map[string]string `json:"generatedObjectLabels,omitempty"`
+	GeneratedObjectAnnotations map[string]string `json:"generatedObjectAnnotations,omitempty"`
 }
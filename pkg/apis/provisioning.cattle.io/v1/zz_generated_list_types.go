@@ -43,6 +43,23 @@ func NewCluster(namespace, name string, obj Cluster) *Cluster {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// ClusterProfileList is a list of ClusterProfile resources
+type ClusterProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterProfile `json:"items"`
+}
+
+func NewClusterProfile(namespace, name string, obj ClusterProfile) *ClusterProfile {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ClusterProfile").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // ManagedOSList is a list of ManagedOS resources
 type ManagedOSList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -28,8 +28,9 @@
 )
 
 var (
-	ClusterResourceName   = "clusters"
-	ManagedOSResourceName = "managedoses"
+	ClusterResourceName        = "clusters"
+	ClusterProfileResourceName = "clusterprofiles"
+	ManagedOSResourceName      = "managedoses"
 )
 
 // SchemeGroupVersion is group version used to register these objects
@@ -55,6 +56,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Cluster{},
 		&ClusterList{},
+		&ClusterProfile{},
+		&ClusterProfileList{},
 		&ManagedOS{},
 		&ManagedOSList{},
 	)
@@ -2,6 +2,7 @@
 
 import (
 	"github.com/rancher/wrangler/pkg/genericcondition"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,6 +31,19 @@ type RKEClusterSpecCommon struct {
 	AdditionalManifest       string                   `json:"additionalManifest,omitempty"`
 	Registries               *Registry                `json:"registries,omitempty"`
 	ETCD                     *ETCD                    `json:"etcd,omitempty"`
+	AgentInstallScript       *AgentInstallScript      `json:"agentInstallScript,omitempty"`
+}
+
+// AgentInstallScript overrides the source of the system agent install script of the machines of a cluster, set by the
+// system-agent-install-script setting otherwise.
+type AgentInstallScript struct {
+	// URL the install script is downloaded from
+	URL string `json:"url,omitempty"`
+	// ConfigMapRef references the content of the install script in a ConfigMap in the namespace of the cluster, it
+	// takes precedence over the URL
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+	// SHA256 pins the checksum of the install script, a script with another checksum is rejected
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 type LocalClusterAuthEndpoint struct {
@@ -26,6 +26,9 @@ type RKEControlPlaneSpec struct {
 	ClusterName           string              `json:"clusterName,omitempty" wrangler:"required"`
 	ManagementClusterName string              `json:"managementClusterName,omitempty" wrangler:"required"`
 	UnmanagedConfig       bool                `json:"unmanagedConfig,omitempty"`
+	// MachinePoolKubeletArgs are the kubelet args of the machine pools, keyed by the name of the MachineDeployment
+	// of the pool
+	MachinePoolKubeletArgs map[string]map[string]string `json:"machinePoolKubeletArgs,omitempty"`
 }
 
 type ETCDSnapshotPhase string
@@ -46,4 +49,27 @@ type RKEControlPlaneStatus struct {
 	ETCDSnapshotCreate       *ETCDSnapshotCreate                 `json:"etcdSnapshotCreate,omitempty"`
 	ETCDSnapshotCreatePhase  ETCDSnapshotPhase                   `json:"etcdSnapshotCreatePhase,omitempty"`
 	ConfigGeneration         int64                               `json:"configGeneration,omitempty"`
+	UpgradeStatus            *UpgradeStatus                      `json:"upgradeStatus,omitempty"`
+}
+
+type UpgradePhase string
+
+var (
+	UpgradePhasePending   UpgradePhase = "Pending"
+	UpgradePhaseCordoned  UpgradePhase = "Cordoned"
+	UpgradePhaseUpgrading UpgradePhase = "Upgrading"
+	UpgradePhaseDone      UpgradePhase = "Done"
+)
+
+// UpgradeStatus is the progress of the machines of the cluster towards the desired Kubernetes version and plan
+type UpgradeStatus struct {
+	Machines []MachineUpgradeStatus `json:"machines,omitempty"`
+}
+
+type MachineUpgradeStatus struct {
+	MachineName    string       `json:"machineName,omitempty"`
+	CurrentVersion string       `json:"currentVersion,omitempty"`
+	DesiredVersion string       `json:"desiredVersion,omitempty"`
+	Phase          UpgradePhase `json:"phase,omitempty"`
+	LastError      string       `json:"lastError,omitempty"`
 }
@@ -21,6 +21,7 @@ type RKEMachineStatus struct {
 	CloudCredentialSecretName string                `json:"cloudCredentialSecretName,omitempty"`
 	FailureReason             string                `json:"failureReason,omitempty"`
 	FailureMessage            string                `json:"failureMessage,omitempty"`
+	FailureLog                string                `json:"failureLog,omitempty"`
 	Addresses                 []capi.MachineAddress `json:"addresses,omitempty"`
 }
 
@@ -43,6 +43,11 @@ type File struct {
 	Content string `json:"content,omitempty"`
 	Path    string `json:"path,omitempty"`
 	Dynamic bool   `json:"dynamic,omitempty"`
+	// Encoding of the content, empty for base64 or gzip+base64
+	Encoding string `json:"encoding,omitempty"`
+	// Hash is the sha256 of the content of a file that is unchanged since the applied plan, the content is then
+	// omitted
+	Hash string `json:"hash,omitempty"`
 }
 
 type NodePlan struct {
@@ -42,24 +42,27 @@
 type auditLog struct {
 	log                *log
 	writer             *LogWriter
+	level              int
 	reqBody            []byte
 	keysToConcealRegex *regexp.Regexp
+	fieldsToConceal    map[string]bool
 }
 
 type log struct {
-	AuditID           k8stypes.UID `json:"auditID,omitempty"`
-	RequestURI        string       `json:"requestURI,omitempty"`
-	User              *User        `json:"user,omitempty"`
-	Method            string       `json:"method,omitempty"`
-	RemoteAddr        string       `json:"remoteAddr,omitempty"`
-	RequestTimestamp  string       `json:"requestTimestamp,omitempty"`
-	ResponseTimestamp string       `json:"responseTimestamp,omitempty"`
-	ResponseCode      int          `json:"responseCode,omitempty"`
-	RequestHeader     http.Header  `json:"requestHeader,omitempty"`
-	ResponseHeader    http.Header  `json:"responseHeader,omitempty"`
-	RequestBody       []byte       `json:"requestBody,omitempty"`
-	ResponseBody      []byte       `json:"responseBody,omitempty"`
-	UserLoginName     string       `json:"userLoginName,omitempty"`
+	AuditID           k8stypes.UID    `json:"auditID,omitempty"`
+	RequestURI        string          `json:"requestURI,omitempty"`
+	User              *User           `json:"user,omitempty"`
+	Method            string          `json:"method,omitempty"`
+	RemoteAddr        string          `json:"remoteAddr,omitempty"`
+	RequestTimestamp  string          `json:"requestTimestamp,omitempty"`
+	ResponseTimestamp string          `json:"responseTimestamp,omitempty"`
+	ResponseCode      int             `json:"responseCode,omitempty"`
+	RequestHeader     http.Header     `json:"requestHeader,omitempty"`
+	ResponseHeader    http.Header     `json:"responseHeader,omitempty"`
+	RequestBody       []byte          `json:"requestBody,omitempty"`
+	ResponseBody      []byte          `json:"responseBody,omitempty"`
+	UserLoginName     string          `json:"userLoginName,omitempty"`
+	ClusterRequest    *ClusterRequest `json:"clusterRequest,omitempty"`
 }
 
 var userKey struct{}
@@ -98,23 +101,39 @@ func FromContext(ctx context.Context) (*User, bool) {
 	return u, ok
 }
 
-func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regexp.Regexp) (*auditLog, error) {
+// newAuditLog returns the audit log of the request, nil if the request isn't logged at the levels of the writer.
+// The requests proxied to downstream clusters are logged at the cluster proxy level, if set, along with the
+// Kubernetes request they target.
+func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regexp.Regexp, fieldsToConceal map[string]bool) (*auditLog, error) {
+	level := writer.Level
+	var clusterRequest *ClusterRequest
+	if writer.ClusterProxyLevel != levelNull {
+		if clusterRequest = parseClusterRequest(req); clusterRequest != nil {
+			level = writer.ClusterProxyLevel
+		}
+	}
+	if level == levelNull {
+		return nil, nil
+	}
+
 	auditLog := &auditLog{
 		writer: writer,
+		level:  level,
 		log: &log{
 			AuditID:          k8stypes.UID(uuid.NewRandom().String()),
 			RequestURI:       req.RequestURI,
 			Method:           req.Method,
 			RemoteAddr:       req.RemoteAddr,
 			RequestTimestamp: time.Now().Format(time.RFC3339),
+			ClusterRequest:   clusterRequest,
 		},
 		keysToConcealRegex: keysToConcealRegex,
+		fieldsToConceal:    fieldsToConceal,
 	}
 
-	contentType := req.Header.Get("Content-Type")
 	loginReq := isLoginRequest(req.RequestURI)
-	if writer.Level >= levelRequest || loginReq {
-		if bodyMethods[req.Method] && strings.HasPrefix(contentType, contentTypeJSON) {
+	if level >= levelRequest || loginReq {
+		if hasJSONBody(req, clusterRequest) {
 			reqBody, err := readBodyWithoutLosingContent(req)
 			if err != nil {
 				return nil, err
@@ -125,7 +144,7 @@ func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regex
 					auditLog.log.UserLoginName = loginName
 				}
 			}
-			if writer.Level >= levelRequest {
+			if level >= levelRequest {
 				auditLog.reqBody = reqBody
 			}
 		}
@@ -154,11 +173,11 @@ func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, res
 	}
 
 	buffer.Write(bytes.TrimSuffix(alByte, []byte("}")))
-	if a.writer.Level >= levelRequest && len(a.reqBody) > 0 {
+	if a.level >= levelRequest && len(a.reqBody) > 0 {
 		buffer.WriteString(`,"requestBody":`)
 		buffer.Write(bytes.TrimSuffix(a.concealSensitiveData(a.log.RequestURI, a.reqBody), []byte("\n")))
 	}
-	if a.writer.Level >= levelRequestResponse && resHeaders.Get("Content-Type") == contentTypeJSON && len(resBody) > 0 {
+	if a.level >= levelRequestResponse && resHeaders.Get("Content-Type") == contentTypeJSON && len(resBody) > 0 {
 		buffer.WriteString(`,"responseBody":`)
 		buffer.Write(bytes.TrimSuffix(a.concealSensitiveData(a.log.RequestURI, resBody), []byte("\n")))
 	}
@@ -171,19 +190,25 @@ func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, res
 	}
 
 	compactBuffer.WriteString("\n")
-	_, err = a.writer.Output.Write(compactBuffer.Bytes())
+	_, err = a.writer.Write(compactBuffer.Bytes())
 	return err
 }
 
+// hasJSONBody returns whether the body of the request is logged, the requests proxied to downstream clusters also
+// log the JSON patches sent to the Kubernetes API
+func hasJSONBody(req *http.Request, clusterRequest *ClusterRequest) bool {
+	contentType := req.Header.Get("Content-Type")
+	if clusterRequest != nil {
+		return clusterProxyBodyMethods[req.Method] && isKubernetesJSON(contentType)
+	}
+	return bodyMethods[req.Method] && strings.HasPrefix(contentType, contentTypeJSON)
+}
+
 func isLoginRequest(uri string) bool {
 	return strings.Contains(uri, "?action=login")
 }
 
 func readBodyWithoutLosingContent(req *http.Request) ([]byte, error) {
-	if !bodyMethods[req.Method] {
-		return nil, nil
-	}
-
 	bodyBytes, err := ioutil.ReadAll(req.Body)
 	if err != nil {
 		return nil, err
@@ -239,7 +264,7 @@ func (a *auditLog) concealSensitiveData(requestURI string, body []byte) []byte {
 	}
 
 	// Conceal values for data considered sensitive: passwords, tokens, etc.
-	if !a.concealMap(m) && !changed {
+	if !a.concealMap(m, "") && !changed {
 		return body
 	}
 
@@ -250,15 +275,29 @@ func (a *auditLog) concealSensitiveData(requestURI string, body []byte) []byte {
 	return newBody
 }
 
-func (a *auditLog) concealMap(m map[string]interface{}) bool {
+func (a *auditLog) concealMap(m map[string]interface{}, path string) bool {
 	var changed bool
 	for key := range m {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		// Configured fields are concealed whatever their type.
+		if a.fieldsToConceal[key] || a.fieldsToConceal[keyPath] {
+			if m[key] != redacted {
+				changed = true
+				m[key] = redacted
+			}
+			continue
+		}
+
 		if _, ok := m[key].(string); ok {
 			if a.keysToConcealRegex.MatchString(key) {
 				changed = true
 				m[key] = redacted
 			}
-		} else if nested, ok := m[key].(map[string]interface{}); ok && a.concealMap(nested) {
+		} else if nested, ok := m[key].(map[string]interface{}); ok && a.concealMap(nested, keyPath) {
 			changed = true
 			m[key] = nested
 		}
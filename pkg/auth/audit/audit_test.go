@@ -143,3 +143,67 @@ func Test_concealSensitiveData(t *testing.T) {
 		})
 	}
 }
+
+func Test_concealConfiguredFields(t *testing.T) {
+	r, err := constructKeyConcealRegex()
+	if err != nil {
+		t.Fatalf("failed compiling sanitizing regex: %v", err)
+	}
+	a := auditLog{
+		keysToConcealRegex: r,
+		fieldsToConceal:    constructFieldConcealSet([]string{"apiKey", " spec.caBundle ", ""}),
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  []byte
+	}{
+		{
+			name:  "configured key",
+			input: []byte(`{"apiKey": "fake_api_key", "user": "fake_user"}`),
+			want:  []byte(fmt.Sprintf(`{"apiKey": "%s", "user": "fake_user"}`, redacted)),
+		},
+		{
+			name:  "configured key nested",
+			input: []byte(`{"config": {"apiKey": "fake_api_key", "region": "us-west-2"}}`),
+			want:  []byte(fmt.Sprintf(`{"config": {"apiKey": "%s", "region": "us-west-2"}}`, redacted)),
+		},
+		{
+			name:  "configured key not a string",
+			input: []byte(`{"apiKey": {"id": "fake_id", "value": "fake_value"}, "user": "fake_user"}`),
+			want:  []byte(fmt.Sprintf(`{"apiKey": "%s", "user": "fake_user"}`, redacted)),
+		},
+		{
+			name:  "configured path",
+			input: []byte(`{"spec": {"caBundle": "fake_ca_bundle", "url": "https://example.com"}}`),
+			want:  []byte(fmt.Sprintf(`{"spec": {"caBundle": "%s", "url": "https://example.com"}}`, redacted)),
+		},
+		{
+			name:  "configured path at another depth",
+			input: []byte(`{"caBundle": "fake_ca_bundle", "status": {"caBundle": "fake_ca_bundle"}}`),
+			want:  []byte(`{"caBundle": "fake_ca_bundle", "status": {"caBundle": "fake_ca_bundle"}}`),
+		},
+		{
+			name:  "default fields",
+			input: []byte(`{"secretKey": "fake_secret_key", "password": "fake_password", "user": "fake_user"}`),
+			want:  []byte(fmt.Sprintf(`{"secretKey": "%s", "password": "%[1]s", "user": "fake_user"}`, redacted)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want map[string]interface{}
+			if err := json.Unmarshal(tt.want, &want); err != nil {
+				t.Errorf("error unmarshaling: %v", err)
+			}
+			got := a.concealSensitiveData("/v3/nodetemplates", tt.input)
+			var gotMap map[string]interface{}
+			if err := json.Unmarshal(got, &gotMap); err != nil {
+				t.Errorf("error unmarshaling: %v", err)
+			}
+			if !reflect.DeepEqual(want, gotMap) {
+				t.Errorf("concealSensitiveData() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
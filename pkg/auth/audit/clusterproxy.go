@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const clusterProxyPrefix = "/k8s/clusters/"
+
+var (
+	requestInfoResolver = &request.RequestInfoFactory{
+		APIPrefixes:          sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api"),
+	}
+	clusterProxyBodyMethods = map[string]bool{
+		http.MethodPut:   true,
+		http.MethodPost:  true,
+		http.MethodPatch: true,
+	}
+)
+
+// ClusterRequest is the Kubernetes request targeted by a request proxied to a downstream cluster
+type ClusterRequest struct {
+	Cluster     string `json:"cluster,omitempty"`
+	Verb        string `json:"verb,omitempty"`
+	APIGroup    string `json:"apiGroup,omitempty"`
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// parseClusterRequest resolves the Kubernetes request of a request proxied to a downstream cluster through
+// /k8s/clusters/<id>, it returns nil for the other requests.
+func parseClusterRequest(req *http.Request) *ClusterRequest {
+	if !strings.HasPrefix(req.URL.Path, clusterProxyPrefix) {
+		return nil
+	}
+
+	cluster, path := strings.TrimPrefix(req.URL.Path, clusterProxyPrefix), "/"
+	if i := strings.Index(cluster, "/"); i >= 0 {
+		cluster, path = cluster[:i], cluster[i:]
+	}
+	if cluster == "" {
+		return nil
+	}
+
+	proxied := req.WithContext(req.Context())
+	proxiedURL := *req.URL
+	proxiedURL.Path = path
+	proxied.URL = &proxiedURL
+
+	clusterRequest := &ClusterRequest{Cluster: cluster}
+	info, err := requestInfoResolver.NewRequestInfo(proxied)
+	if err != nil {
+		return clusterRequest
+	}
+	clusterRequest.Verb = info.Verb
+	clusterRequest.APIGroup = info.APIGroup
+	clusterRequest.APIVersion = info.APIVersion
+	clusterRequest.Resource = info.Resource
+	clusterRequest.Subresource = info.Subresource
+	clusterRequest.Namespace = info.Namespace
+	clusterRequest.Name = info.Name
+	return clusterRequest
+}
+
+// isKubernetesJSON returns whether the content type is JSON, including the JSON patches sent to the Kubernetes API
+func isKubernetesJSON(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return mediaType == contentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
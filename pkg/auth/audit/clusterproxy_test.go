@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type testLogEntry struct {
+	User           *User                  `json:"user"`
+	ResponseCode   int                    `json:"responseCode"`
+	ClusterRequest *ClusterRequest        `json:"clusterRequest"`
+	RequestBody    map[string]interface{} `json:"requestBody"`
+}
+
+func serveAudited(t *testing.T, writer *LogWriter, req *http.Request, statusCode int) *testLogEntry {
+	middleware, err := NewAuditLogMiddleware(writer, nil)
+	require.NoError(t, err)
+	handler := middleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(statusCode)
+	}))
+
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "u-abcde"}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case entry := <-writer.entries:
+		log := &testLogEntry{}
+		require.NoError(t, json.Unmarshal(entry, log))
+		return log
+	default:
+		return nil
+	}
+}
+
+func newClusterProxyTestWriter(level, clusterProxyLevel int) *LogWriter {
+	return &LogWriter{
+		Level:             level,
+		ClusterProxyLevel: clusterProxyLevel,
+		entries:           make(chan []byte, 10),
+	}
+}
+
+func TestClusterProxyAuditGet(t *testing.T) {
+	writer := newClusterProxyTestWriter(levelMetadata, levelMetadata)
+	req := httptest.NewRequest(http.MethodGet, "/k8s/clusters/c-abcde/api/v1/namespaces/default/pods/nginx", nil)
+
+	log := serveAudited(t, writer, req, http.StatusOK)
+	require.NotNil(t, log)
+	assert.Equal(t, "u-abcde", log.User.Name)
+	assert.Equal(t, http.StatusOK, log.ResponseCode)
+	assert.Equal(t, &ClusterRequest{
+		Cluster:    "c-abcde",
+		Verb:       "get",
+		APIVersion: "v1",
+		Resource:   "pods",
+		Namespace:  "default",
+		Name:       "nginx",
+	}, log.ClusterRequest)
+
+	req = httptest.NewRequest(http.MethodGet, "/k8s/clusters/c-abcde/apis/apps/v1/deployments?watch=true", nil)
+	log = serveAudited(t, writer, req, http.StatusOK)
+	require.NotNil(t, log)
+	assert.Equal(t, &ClusterRequest{
+		Cluster:    "c-abcde",
+		Verb:       "watch",
+		APIGroup:   "apps",
+		APIVersion: "v1",
+		Resource:   "deployments",
+	}, log.ClusterRequest)
+}
+
+func TestClusterProxyAuditPatch(t *testing.T) {
+	writer := newClusterProxyTestWriter(levelMetadata, levelRequest)
+	req := httptest.NewRequest(http.MethodPatch, "/k8s/clusters/c-abcde/api/v1/namespaces/default/secrets/creds",
+		strings.NewReader(`{"data":{"key":"c2VjcmV0"},"metadata":{"labels":{"app":"web"}}}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	log := serveAudited(t, writer, req, http.StatusForbidden)
+	require.NotNil(t, log)
+	assert.Equal(t, http.StatusForbidden, log.ResponseCode)
+	assert.Equal(t, &ClusterRequest{
+		Cluster:    "c-abcde",
+		Verb:       "patch",
+		APIVersion: "v1",
+		Resource:   "secrets",
+		Namespace:  "default",
+		Name:       "creds",
+	}, log.ClusterRequest)
+	assert.Equal(t, map[string]interface{}{
+		"data":     map[string]interface{}{"key": redacted},
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+	}, log.RequestBody, "expected the request body to be logged at the cluster proxy level and redacted")
+
+	req = httptest.NewRequest(http.MethodPatch, "/v3/clusters/c-abcde", strings.NewReader(`{"name":"renamed"}`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	log = serveAudited(t, newClusterProxyTestWriter(levelRequest, levelRequest), req, http.StatusOK)
+	require.NotNil(t, log)
+	assert.Nil(t, log.RequestBody, "expected the body of PATCH requests to be logged only for the proxied requests")
+}
+
+func TestClusterProxyAuditExec(t *testing.T) {
+	writer := newClusterProxyTestWriter(levelMetadata, levelMetadata)
+	req := httptest.NewRequest(http.MethodPost, "/k8s/clusters/c-abcde/api/v1/namespaces/default/pods/nginx/exec?command=sh&stdin=true&tty=true", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "SPDY/3.1")
+
+	log := serveAudited(t, writer, req, http.StatusSwitchingProtocols)
+	require.NotNil(t, log)
+	assert.Equal(t, http.StatusSwitchingProtocols, log.ResponseCode)
+	assert.Equal(t, &ClusterRequest{
+		Cluster:     "c-abcde",
+		Verb:        "create",
+		APIVersion:  "v1",
+		Resource:    "pods",
+		Subresource: "exec",
+		Namespace:   "default",
+		Name:        "nginx",
+	}, log.ClusterRequest)
+}
+
+func TestClusterProxyAuditLevels(t *testing.T) {
+	proxied := func() *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/k8s/clusters/c-abcde/api/v1/namespaces", nil)
+	}
+
+	log := serveAudited(t, newClusterProxyTestWriter(levelMetadata, levelNull), proxied(), http.StatusOK)
+	require.NotNil(t, log, "expected the proxied requests to be logged at the audit level when the cluster proxy level isn't set")
+	assert.Nil(t, log.ClusterRequest)
+
+	writer := newClusterProxyTestWriter(levelNull, levelMetadata)
+	assert.Nil(t, serveAudited(t, writer, httptest.NewRequest(http.MethodGet, "/v3/clusters", nil), http.StatusOK),
+		"expected the requests to Rancher not to be logged when the audit level is null")
+	log = serveAudited(t, writer, proxied(), http.StatusOK)
+	require.NotNil(t, log)
+	assert.Equal(t, "list", log.ClusterRequest.Verb)
+	assert.Equal(t, "namespaces", log.ClusterRequest.Resource)
+}
@@ -16,13 +16,17 @@
 	"github.com/sirupsen/logrus"
 )
 
-func NewAuditLogMiddleware(auditWriter *LogWriter) (func(http.Handler) http.Handler, error) {
+// NewAuditLogMiddleware returns the middleware writing requests to the audit log. On top of the credential fields
+// of management.DriverData and the password and token fields, the redactedFields are concealed from the logged bodies.
+func NewAuditLogMiddleware(auditWriter *LogWriter, redactedFields []string) (func(http.Handler) http.Handler, error) {
 	sensitiveRegex, err := constructKeyConcealRegex()
+	sensitiveFields := constructFieldConcealSet(redactedFields)
 	return func(next http.Handler) http.Handler {
 		return &auditHandler{
 			next:            next,
 			auditWriter:     auditWriter,
 			sanitizingRegex: sensitiveRegex,
+			sanitizingKeys:  sensitiveFields,
 		}
 	}, err
 }
@@ -46,10 +50,23 @@ func constructKeyConcealRegex() (*regexp.Regexp, error) {
 	return regexp.Compile(s.String())
 }
 
+// constructFieldConcealSet builds the set of fields to conceal. A field is either a key, concealed at any depth of a
+// body, or the dot separated path of a key from the root of a body, such as spec.password.
+func constructFieldConcealSet(fields []string) map[string]bool {
+	result := map[string]bool{}
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			result[field] = true
+		}
+	}
+	return result
+}
+
 type auditHandler struct {
 	next            http.Handler
 	auditWriter     *LogWriter
 	sanitizingRegex *regexp.Regexp
+	sanitizingKeys  map[string]bool
 }
 
 func (h auditHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -63,11 +80,15 @@ func (h auditHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	context := context.WithValue(req.Context(), userKey, user)
 	req = req.WithContext(context)
 
-	auditLog, err := newAuditLog(h.auditWriter, req, h.sanitizingRegex)
+	auditLog, err := newAuditLog(h.auditWriter, req, h.sanitizingRegex, h.sanitizingKeys)
 	if err != nil {
 		util.ReturnHTTPError(rw, req, 500, err.Error())
 		return
 	}
+	if auditLog == nil {
+		h.next.ServeHTTP(rw, req)
+		return
+	}
 
 	wr := &wrapWriter{ResponseWriter: rw, auditWriter: h.auditWriter, statusCode: http.StatusOK}
 	h.next.ServeHTTP(wr, req)
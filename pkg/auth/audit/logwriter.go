@@ -2,37 +2,180 @@
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
+const (
+	// RotateDaily rotates the audit log every day at midnight, local time
+	RotateDaily = "daily"
+	// RotateHourly rotates the audit log at the start of every hour
+	RotateHourly = "hourly"
+
+	// logBufferSize is the number of entries buffered for the flusher, entries are dropped once the buffer is full
+	logBufferSize = 10000
+)
+
 type LogWriter struct {
-	Level  int
-	Output *lumberjack.Logger
+	Level int
+	// ClusterProxyLevel is the level of the requests proxied to downstream clusters, they are logged at Level without
+	// their Kubernetes attributes if it is levelNull
+	ClusterProxyLevel int
+	Output            *lumberjack.Logger
+
+	// rotate is the schedule the log is rotated on in addition to its size, empty if the log is only rotated on size
+	rotate       string
+	now          func() time.Time
+	lock         sync.Mutex
+	nextRotation time.Time
+
+	entries chan []byte
+	dropped uint64
+	stopped chan struct{}
 }
 
+// Start flushes the buffered entries to the log in the background until the context is done, the remaining entries
+// are then flushed and the log is closed. Wait blocks until then.
 func (l *LogWriter) Start(ctx context.Context) {
 	if l == nil {
 		return
 	}
-	go func() {
-		<-ctx.Done()
-		l.Output.Close()
-	}()
+	go l.run(ctx)
+}
+
+// Wait blocks until the writer started with a context that is done flushed the remaining entries and closed the log,
+// so that the entries of the last requests aren't lost when the process exits
+func (l *LogWriter) Wait() {
+	if l == nil {
+		return
+	}
+	<-l.stopped
+}
+
+func (l *LogWriter) run(ctx context.Context) {
+	defer close(l.stopped)
+
+	var reported uint64
+	for {
+		select {
+		case entry := <-l.entries:
+			l.flush(entry)
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-l.entries:
+					l.flush(entry)
+				default:
+					l.Output.Close()
+					return
+				}
+			}
+		}
+
+		if dropped := l.Dropped(); dropped != reported {
+			logrus.Warnf("Audit log buffer is full, dropped %d entries", dropped-reported)
+			reported = dropped
+		}
+	}
+}
+
+func (l *LogWriter) flush(entry []byte) {
+	if _, err := l.write(entry); err != nil {
+		logrus.Errorf("Failed to write audit log: %v", err)
+	}
+}
+
+// Write buffers an entry for the flusher without waiting for the disk. The entry is dropped and counted if the buffer
+// is full or if the flusher stopped.
+func (l *LogWriter) Write(p []byte) (int, error) {
+	select {
+	case <-l.stopped:
+		atomic.AddUint64(&l.dropped, 1)
+		return len(p), nil
+	default:
+	}
+
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	select {
+	case l.entries <- entry:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+	return len(p), nil
 }
 
-func NewLogWriter(path string, level, maxAge, maxBackup, maxSize int) *LogWriter {
-	if path == "" || level == levelNull {
+// Dropped returns the number of entries dropped because the buffer was full
+func (l *LogWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// write writes an entry to the log, rotating it first if the schedule of the writer crossed a boundary since the
+// previous entry.
+func (l *LogWriter) write(p []byte) (int, error) {
+	if l.rotate != "" {
+		if err := l.rotateIfDue(); err != nil {
+			return 0, err
+		}
+	}
+	return l.Output.Write(p)
+}
+
+func (l *LogWriter) rotateIfDue() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	if l.nextRotation.IsZero() {
+		l.nextRotation = nextRotation(l.rotate, now)
+		return nil
+	}
+	if now.Before(l.nextRotation) {
 		return nil
 	}
 
+	l.nextRotation = nextRotation(l.rotate, now)
+	return l.Output.Rotate()
+}
+
+// nextRotation returns the first boundary of the schedule after now
+func nextRotation(rotate string, now time.Time) time.Time {
+	year, month, day := now.Date()
+	if rotate == RotateHourly {
+		return time.Date(year, month, day, now.Hour()+1, 0, 0, 0, now.Location())
+	}
+	return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+}
+
+func NewLogWriter(path string, level, clusterProxyLevel, maxAge, maxBackup, maxSize int, rotate string) (*LogWriter, error) {
+	if path == "" || (level == levelNull && clusterProxyLevel == levelNull) {
+		return nil, nil
+	}
+
+	switch rotate {
+	case "", RotateDaily, RotateHourly:
+	default:
+		return nil, fmt.Errorf("invalid audit log rotation schedule [%s], supported schedules are %s and %s", rotate, RotateDaily, RotateHourly)
+	}
+
 	return &LogWriter{
-		Level: level,
+		Level:             level,
+		ClusterProxyLevel: clusterProxyLevel,
 		Output: &lumberjack.Logger{
 			Filename:   path,
 			MaxAge:     maxAge,
 			MaxBackups: maxBackup,
 			MaxSize:    maxSize,
 		},
-	}
+		rotate:  rotate,
+		now:     time.Now,
+		entries: make(chan []byte, logBufferSize),
+		stopped: make(chan struct{}),
+	}, nil
 }
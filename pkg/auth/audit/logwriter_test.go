@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogWriter(t *testing.T, rotate string, now *time.Time) (*LogWriter, string) {
+	dir, err := ioutil.TempDir("", "audit-log")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	writer, err := NewLogWriter(filepath.Join(dir, "audit.log"), levelMetadata, levelNull, 10, 10, 100, rotate)
+	require.NoError(t, err)
+	writer.now = func() time.Time { return *now }
+	t.Cleanup(func() { writer.Output.Close() })
+	return writer, dir
+}
+
+func logFiles(t *testing.T, dir string) int {
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	return len(files)
+}
+
+func TestLogWriterRotateDaily(t *testing.T) {
+	now := time.Date(2021, 6, 1, 23, 58, 0, 0, time.Local)
+	writer, dir := newTestLogWriter(t, RotateDaily, &now)
+
+	_, err := writer.write([]byte("first\n"))
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = writer.write([]byte("second\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, logFiles(t, dir), "expected no rotation before midnight")
+
+	now = time.Date(2021, 6, 2, 0, 0, 0, 0, time.Local)
+	_, err = writer.write([]byte("third\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, logFiles(t, dir), "expected a rotation at midnight")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(content))
+
+	now = now.Add(23 * time.Hour)
+	_, err = writer.write([]byte("fourth\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, logFiles(t, dir), "expected a single rotation a day")
+}
+
+func TestLogWriterRotateHourly(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 30, 0, 0, time.Local)
+	writer, dir := newTestLogWriter(t, RotateHourly, &now)
+
+	_, err := writer.write([]byte("first\n"))
+	require.NoError(t, err)
+	now = time.Date(2021, 6, 1, 11, 0, 0, 0, time.Local)
+	_, err = writer.write([]byte("second\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, logFiles(t, dir))
+}
+
+func TestLogWriterRotateOnSizeOnly(t *testing.T) {
+	now := time.Date(2021, 6, 1, 23, 59, 0, 0, time.Local)
+	writer, dir := newTestLogWriter(t, "", &now)
+
+	_, err := writer.write([]byte("first\n"))
+	require.NoError(t, err)
+	now = now.Add(48 * time.Hour)
+	_, err = writer.write([]byte("second\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, logFiles(t, dir))
+}
+
+func TestNewLogWriterInvalidRotate(t *testing.T) {
+	_, err := NewLogWriter("/var/log/audit.log", levelMetadata, levelNull, 10, 10, 100, "weekly")
+	assert.Error(t, err)
+}
+
+func TestLogWriterFlushOnShutdown(t *testing.T) {
+	now := time.Now()
+	writer, dir := newTestLogWriter(t, "", &now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.Start(ctx)
+	for i := 0; i < 100; i++ {
+		_, err := writer.Write([]byte(fmt.Sprintf("entry %d\n", i)))
+		require.NoError(t, err)
+	}
+	cancel()
+	writer.Wait()
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	require.NoError(t, err)
+	assert.Equal(t, 100, strings.Count(string(content), "\n"), "expected every buffered entry to be flushed")
+	assert.Equal(t, uint64(0), writer.Dropped())
+
+	_, err = writer.Write([]byte("entry 100\n"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), writer.Dropped(), "expected the entries written once stopped to be dropped")
+}
+
+func TestLogWriterOverflow(t *testing.T) {
+	now := time.Now()
+	writer, dir := newTestLogWriter(t, "", &now)
+	writer.entries = make(chan []byte, 2)
+
+	// the flusher isn't started yet so the entries beyond the buffer are dropped
+	for i := 0; i < 5; i++ {
+		n, err := writer.Write([]byte(fmt.Sprintf("entry %d\n", i)))
+		require.NoError(t, err)
+		assert.Equal(t, len("entry 0\n"), n)
+	}
+	assert.Equal(t, uint64(3), writer.Dropped())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer.Start(ctx)
+	writer.Wait()
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "entry 0\nentry 1\n", string(content))
+}
+
+func TestLogWriterCopiesEntries(t *testing.T) {
+	now := time.Now()
+	writer, _ := newTestLogWriter(t, "", &now)
+
+	entry := []byte("entry\n")
+	_, err := writer.Write(entry)
+	require.NoError(t, err)
+	copy(entry, "reused")
+	assert.Equal(t, "entry\n", string(<-writer.entries), "expected the buffered entry to be independent of the caller's slice")
+}
+
+func benchmarkLogWriter(b *testing.B, write func(writer *LogWriter, entry []byte)) {
+	dir, err := ioutil.TempDir("", "audit-log")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := NewLogWriter(filepath.Join(dir, "audit.log"), levelRequestResponse, levelNull, 10, 10, 100, "")
+	require.NoError(b, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.Start(ctx)
+	defer func() {
+		cancel()
+		writer.Wait()
+	}()
+
+	entry := []byte(`{"auditID":"5b8c3f1e","requestURI":"/v3/clusters","method":"GET","responseCode":200}` + "\n")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		write(writer, entry)
+	}
+}
+
+func BenchmarkLogWriterSync(b *testing.B) {
+	benchmarkLogWriter(b, func(writer *LogWriter, entry []byte) {
+		writer.write(entry)
+	})
+}
+
+func BenchmarkLogWriterAsync(b *testing.B) {
+	benchmarkLogWriter(b, func(writer *LogWriter, entry []byte) {
+		writer.Write(entry)
+	})
+}
@@ -4,6 +4,7 @@
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/norman/httperror"
@@ -18,6 +19,7 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 )
 
 var (
@@ -84,8 +86,41 @@ type tokenAuthenticator struct {
 
 const (
 	tokenKeyIndex = "authn.management.cattle.io/token-key-index"
+
+	// activityInterval is how often the use of the tokens of a user is recorded on its attributes, the user retention
+	// considers the users using their tokens active
+	activityInterval = time.Hour
 )
 
+// recentActivity returns true if the use of the tokens of the user was recorded within the activity interval, or if
+// the user has no attributes yet, they are created when it logs in
+func recentActivity(attribs *v3.UserAttribute, now time.Time) bool {
+	if attribs == nil {
+		return true
+	}
+	lastActivity, err := time.Parse(time.RFC3339, attribs.LastActivity)
+	return err == nil && now.Sub(lastActivity) < activityInterval
+}
+
+func (a *tokenAuthenticator) recordActivity(userID string) {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		attribs, err := a.userAttributes.Get(userID, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if recentActivity(attribs, now) {
+			return nil
+		}
+		attribs.LastActivity = now.UTC().Format(time.RFC3339)
+		_, err = a.userAttributes.Update(attribs)
+		return err
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logrus.Errorf("Failed to record the activity of user %s: %v", userID, err)
+	}
+}
+
 func tokenKeyIndexer(obj interface{}) ([]string, error) {
 	token, ok := obj.(*v3.Token)
 	if !ok {
@@ -152,6 +187,9 @@ func (a *tokenAuthenticator) Authenticate(req *http.Request) (*AuthenticatorResp
 
 	if !strings.HasPrefix(token.UserID, "system:") {
 		go a.userAuthRefresher.TriggerUserRefresh(token.UserID, false)
+		if !recentActivity(attribs, time.Now()) {
+			go a.recordActivity(token.UserID)
+		}
 	}
 
 	authResp.IsAuthed = true
@@ -0,0 +1,41 @@
+package fake
+
+import (
+	"context"
+
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedauthzv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// SubjectAccessReviews is a fake SubjectAccessReviewInterface which allows the reviews of the subjects, users or
+// groups, granted the resource of the review, and of the admins. The specs of the reviews are recorded.
+type SubjectAccessReviews struct {
+	typedauthzv1.SubjectAccessReviewInterface
+	// Admins are the subjects allowed every verb on every resource
+	Admins map[string]bool
+	// Allowed are the resources granted to the subjects, as resource/namespace/name
+	Allowed map[string][]string
+	// Reviews are the specs of the reviews created
+	Reviews []authzv1.SubjectAccessReviewSpec
+}
+
+func (f *SubjectAccessReviews) Create(ctx context.Context, review *authzv1.SubjectAccessReview, opts metav1.CreateOptions) (*authzv1.SubjectAccessReview, error) {
+	f.Reviews = append(f.Reviews, review.Spec)
+
+	attrs := review.Spec.ResourceAttributes
+	for _, subject := range append([]string{review.Spec.User}, review.Spec.Groups...) {
+		if f.Admins[subject] {
+			review.Status.Allowed = true
+		}
+		if attrs == nil {
+			continue
+		}
+		for _, resource := range f.Allowed[subject] {
+			if resource == attrs.Resource+"/"+attrs.Namespace+"/"+attrs.Name {
+				review.Status.Allowed = true
+			}
+		}
+	}
+	return review, nil
+}
@@ -3,10 +3,13 @@
 import (
 	"context"
 	"net/http"
+	"strings"
 
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/sirupsen/logrus"
 	authV1 "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
 	v1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
 )
 
@@ -93,3 +96,53 @@ func (sar subjectAccessReview) checkUserCanImpersonateGroup(sarClient v1.Subject
 
 	return true, nil
 }
+
+// UserCanDo checks if the user, as a member of the groups, is allowed the resource attributes
+func UserCanDo(ctx context.Context, sarClient v1.SubjectAccessReviewInterface, user string, groups []string, attributes authV1.ResourceAttributes) (bool, error) {
+	if user == "" {
+		return false, nil
+	}
+	review := authV1.SubjectAccessReview{
+		Spec: authV1.SubjectAccessReviewSpec{
+			User:               user,
+			Groups:             groups,
+			ResourceAttributes: &attributes,
+		},
+	}
+
+	result, err := sarClient.Create(ctx, &review, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return result.Status.Allowed, nil
+}
+
+// IsAdmin checks if the user the request impersonates, as a member of the groups it impersonates, is allowed every
+// verb on every resource like the admins are, whether it is granted by its own global roles or by the ones of its groups
+func IsAdmin(req *http.Request, sarClient v1.SubjectAccessReviewInterface) (bool, error) {
+	return UserIsAdmin(req.Context(), sarClient, req.Header.Get("Impersonate-User"), req.Header["Impersonate-Group"])
+}
+
+// UserIsAdmin checks if the user, as a member of the groups, is allowed every verb on every resource like the admins are
+func UserIsAdmin(ctx context.Context, sarClient v1.SubjectAccessReviewInterface, user string, groups []string) (bool, error) {
+	return UserCanDo(ctx, sarClient, user, groups, authV1.ResourceAttributes{
+		Verb:     "*",
+		Group:    "*",
+		Resource: "*",
+	})
+}
+
+// UserAttributeGroups returns the groups a user is authenticated with according to its user attribute, which is nil
+// for users who never logged in, so that controllers can review a user recorded on an object outside of a request
+func UserAttributeGroups(attribs *v3.UserAttribute) []string {
+	groups := []string{user.AllAuthenticated, "system:cattle:authenticated"}
+	if attribs == nil {
+		return groups
+	}
+	for _, principals := range attribs.GroupPrincipals {
+		for _, principal := range principals.Items {
+			groups = append(groups, strings.TrimPrefix(principal.Name, "local://"))
+		}
+	}
+	return groups
+}
@@ -28,6 +28,7 @@
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 )
 
 // TODO Cleanup error logging. If error is being returned, use errors.wrap to return and dont log here
@@ -570,30 +571,44 @@ func (m *Manager) EnsureAndGetUserAttribute(userID string) (*v3.UserAttribute, b
 }
 
 func (m *Manager) UserAttributeCreateOrUpdate(userID, provider string, groupPrincipals []v3.Principal) error {
-	attribs, needCreate, err := m.EnsureAndGetUserAttribute(userID)
-	if err != nil {
-		return err
-	}
-
-	if needCreate {
-		attribs.GroupPrincipals[provider] = v32.Principals{Items: groupPrincipals}
-		_, err := m.userAttributes.Create(attribs)
+	// the attributes are also updated by the group refresh and the recording of token activity, the update is retried
+	// on the latest attributes so that the login isn't lost
+	retried := false
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		attribs, needCreate, err := m.EnsureAndGetUserAttribute(userID)
 		if err != nil {
 			return err
 		}
-		return nil
-	}
+		if retried {
+			latest, err := m.userAttributes.Get(userID, metav1.GetOptions{})
+			if err == nil {
+				attribs, needCreate = latest, false
+			} else if !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		retried = true
 
-	// Exists, just update if necessary
-	if m.UserAttributeChanged(attribs, provider, groupPrincipals) {
-		attribs.GroupPrincipals[provider] = v32.Principals{Items: groupPrincipals}
-		_, err := m.userAttributes.Update(attribs)
-		if err != nil {
+		// the last login is used by the user retention to find dormant users, so it is recorded on every login
+		attribs.LastLogin = time.Now().UTC().Format(time.RFC3339)
+
+		if needCreate {
+			attribs.GroupPrincipals[provider] = v32.Principals{Items: groupPrincipals}
+			_, err := m.userAttributes.Create(attribs)
 			return err
 		}
-	}
 
-	return nil
+		if m.UserAttributeChanged(attribs, provider, groupPrincipals) {
+			if attribs.GroupPrincipals == nil {
+				attribs.GroupPrincipals = map[string]v32.Principals{}
+			}
+			attribs.GroupPrincipals[provider] = v32.Principals{Items: groupPrincipals}
+		}
+		_, err = m.userAttributes.Update(attribs)
+		return err
+	})
 }
 
 func (m *Manager) UserAttributeChanged(attribs *v32.UserAttribute, provider string, groupPrincipals []v32.Principal) bool {
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	v1 "k8s.io/api/authentication/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	podNameKey = "authentication.kubernetes.io/pod-name"
+	podUIDKey  = "authentication.kubernetes.io/pod-uid"
+
+	// verifierTTL is how long the issuer of a cluster is trusted before it is read again
+	verifierTTL = 10 * time.Minute
+)
+
+// ServiceAccountTokenAuthenticator validates the bound service account tokens issued by a downstream cluster and
+// returns the service account and the audiences of the token.
+type ServiceAccountTokenAuthenticator interface {
+	Authenticate(ctx context.Context, clusterID, token string) (*v1.UserInfo, []string, error)
+}
+
+type serviceAccountClaims struct {
+	Kubernetes struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"serviceaccount"`
+		Pod *struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"pod,omitempty"`
+	} `json:"kubernetes.io"`
+}
+
+// clusterTokenAuthenticator verifies the tokens against the issuer and the JWKS of the cluster, both read from the
+// API server of the cluster through the tunnel
+type clusterTokenAuthenticator struct {
+	ctx           context.Context
+	clusterLister v3.ClusterLister
+	restConfig    func(cluster *v3.Cluster) (rest.Config, error)
+	now           func() time.Time
+
+	lock      sync.Mutex
+	verifiers map[string]*clusterVerifier
+}
+
+// clusterVerifier is a verifier of the tokens of a cluster, which is built again once it expires or once the rest
+// config of the cluster changes, like when the service account token Rancher uses for the cluster is rotated
+type clusterVerifier struct {
+	verifier *oidc.IDTokenVerifier
+	config   string
+	expires  time.Time
+}
+
+func NewServiceAccountTokenAuthenticator(ctx context.Context, clusterLister v3.ClusterLister,
+	restConfig func(cluster *v3.Cluster) (rest.Config, error)) ServiceAccountTokenAuthenticator {
+	return &clusterTokenAuthenticator{
+		ctx:           ctx,
+		clusterLister: clusterLister,
+		restConfig:    restConfig,
+		now:           time.Now,
+		verifiers:     map[string]*clusterVerifier{},
+	}
+}
+
+func (c *clusterTokenAuthenticator) Authenticate(ctx context.Context, clusterID, token string) (*v1.UserInfo, []string, error) {
+	verifier, err := c.verifier(clusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idToken, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid service account token: %v", err)
+	}
+
+	claims := serviceAccountClaims{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, fmt.Errorf("invalid service account token: %v", err)
+	}
+	return serviceAccountUser(claims), idToken.Audience, nil
+}
+
+func serviceAccountUser(claims serviceAccountClaims) *v1.UserInfo {
+	namespace, name := claims.Kubernetes.Namespace, claims.Kubernetes.ServiceAccount.Name
+	result := &v1.UserInfo{
+		UID:      claims.Kubernetes.ServiceAccount.UID,
+		Username: serviceaccount.MakeUsername(namespace, name),
+		Groups:   append(serviceaccount.MakeGroupNames(namespace), user.AllAuthenticated),
+	}
+	if claims.Kubernetes.Pod != nil {
+		result.Extra = map[string]v1.ExtraValue{
+			podNameKey: {claims.Kubernetes.Pod.Name},
+			podUIDKey:  {claims.Kubernetes.Pod.UID},
+		}
+	}
+	return result
+}
+
+// verifier returns the verifier of the tokens of the cluster. The lock is only held to access the cache, so that the
+// requests to a slow or unreachable cluster don't block the reviews of the tokens of the other clusters.
+func (c *clusterTokenAuthenticator) verifier(clusterID string) (*oidc.IDTokenVerifier, error) {
+	cluster, err := c.clusterLister.Get("", clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := c.restConfig(cluster)
+	if err != nil {
+		return nil, err
+	}
+	config := restConfigHash(restConfig)
+
+	c.lock.Lock()
+	cached, ok := c.verifiers[clusterID]
+	c.lock.Unlock()
+	if ok && cached.config == config && c.now().Before(cached.expires) {
+		return cached.verifier, nil
+	}
+
+	transport, err := rest.TransportFor(&restConfig)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+
+	host := strings.TrimSuffix(restConfig.Host, "/")
+	issuer, err := getIssuer(client, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account issuer of cluster [%s]: %v", clusterID, err)
+	}
+
+	keySet := oidc.NewRemoteKeySet(oidc.ClientContext(c.ctx, client), host+"/openid/v1/jwks")
+	verifier := oidc.NewVerifier(issuer, keySet, &oidc.Config{SkipClientIDCheck: true})
+
+	c.lock.Lock()
+	c.verifiers[clusterID] = &clusterVerifier{
+		verifier: verifier,
+		config:   config,
+		expires:  c.now().Add(verifierTTL),
+	}
+	c.lock.Unlock()
+	return verifier, nil
+}
+
+// restConfigHash identifies the host and the credentials of a rest config, without keeping the credentials in memory
+func restConfigHash(restConfig rest.Config) string {
+	hash := sha256.New()
+	for _, value := range []string{restConfig.Host, restConfig.BearerToken, string(restConfig.CAData),
+		string(restConfig.CertData), restConfig.Username, restConfig.Password} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func getIssuer(client *http.Client, host string) (string, error) {
+	resp, err := client.Get(host + "/.well-known/openid-configuration")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	config := struct {
+		Issuer string `json:"issuer"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return "", err
+	}
+	if config.Issuer == "" {
+		return "", fmt.Errorf("no issuer in the discovery document")
+	}
+	return config.Issuer, nil
+}
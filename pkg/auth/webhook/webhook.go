@@ -2,17 +2,25 @@
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/rancher/rancher/pkg/auth/requests"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apiserver/pkg/endpoints/request"
 )
 
+// TokenReviewer implements the TokenReview API for Rancher tokens and, if ServiceAccounts is set, for the bound
+// service account tokens of the downstream cluster in the clusterID path variable.
 type TokenReviewer struct {
-	ExternalIDs bool
+	ExternalIDs     bool
+	Authenticator   requests.Authenticator
+	ServiceAccounts ServiceAccountTokenAuthenticator
 }
 
 func (t *TokenReviewer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -20,30 +28,35 @@ func (t *TokenReviewer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	tr := &v1.TokenReview{}
 	err := dec.Decode(tr)
 	if err != nil {
-		handleErr(rw, err)
+		handleErr(rw, fmt.Errorf("malformed token review: %v", err))
 		return
 	}
-
-	req = &http.Request{
-		Header: map[string][]string{},
+	if tr.Spec.Token == "" {
+		handleErr(rw, fmt.Errorf("token is required"))
+		return
 	}
 
-	userInfo, ok := request.UserFrom(req.Context())
-	if !ok {
-		handleErr(rw, requests.ErrMustAuthenticate)
+	clusterID := mux.Vars(req)["clusterID"]
+
+	var (
+		user      *v1.UserInfo
+		audiences []string
+	)
+	if clusterID != "" && t.ServiceAccounts != nil && isJWT(tr.Spec.Token) {
+		user, audiences, err = t.ServiceAccounts.Authenticate(req.Context(), clusterID, tr.Spec.Token)
+	} else {
+		user, err = t.authenticate(clusterID, tr.Spec.Token)
+		audiences = rancherTokenAudiences()
+	}
+	if err != nil {
+		handleErr(rw, err)
 		return
 	}
 
-	user := v1.UserInfo{
-		UID:      userInfo.GetUID(),
-		Username: userInfo.GetName(),
-		Groups:   userInfo.GetGroups(),
-	}
-	for k, v := range userInfo.GetExtra() {
-		if user.Extra == nil {
-			user.Extra = map[string]v1.ExtraValue{}
-		}
-		user.Extra[k] = v
+	matched, ok := matchAudiences(tr.Spec.Audiences, audiences)
+	if !ok {
+		handleErr(rw, fmt.Errorf("token audiences %v are invalid for the target audiences %v", audiences, tr.Spec.Audiences))
+		return
 	}
 
 	trResp := &v1.TokenReview{
@@ -52,14 +65,49 @@ func (t *TokenReviewer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			Kind:       "TokenReview",
 		},
 		Status: v1.TokenReviewStatus{
-			Authenticated: ok,
-			User:          user,
+			Authenticated: true,
+			User:          *user,
+			Audiences:     matched,
 		},
 	}
 
 	writeResp(rw, trResp)
 }
 
+// authenticate validates a Rancher token. Tokens scoped to a cluster are only valid for the cluster of the review.
+func (t *TokenReviewer) authenticate(clusterID, token string) (*v1.UserInfo, error) {
+	path := "/"
+	if clusterID != "" {
+		path = "/k8s/clusters/" + clusterID
+	}
+	tokenReq, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set(tokens.AuthHeaderName, tokens.AuthValuePrefix+" "+token)
+
+	authResp, err := t.Authenticator.Authenticate(tokenReq)
+	if err != nil {
+		return nil, err
+	}
+	if !authResp.IsAuthed {
+		return nil, requests.ErrMustAuthenticate
+	}
+
+	user := &v1.UserInfo{
+		UID:      authResp.User,
+		Username: authResp.User,
+		Groups:   authResp.Groups,
+	}
+	for k, v := range authResp.Extras {
+		if user.Extra == nil {
+			user.Extra = map[string]v1.ExtraValue{}
+		}
+		user.Extra[k] = v
+	}
+	return user, nil
+}
+
 func writeResp(rw http.ResponseWriter, tr *v1.TokenReview) {
 	rw.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(rw)
@@ -69,6 +117,7 @@ func writeResp(rw http.ResponseWriter, tr *v1.TokenReview) {
 	}
 }
 
+// handleErr responds that the token isn't authenticated with the reason, as the TokenReview API expects
 func handleErr(rw http.ResponseWriter, err error) {
 	writeResp(rw, &v1.TokenReview{
 		TypeMeta: metav1.TypeMeta{
@@ -76,7 +125,44 @@ func handleErr(rw http.ResponseWriter, err error) {
 			Kind:       "TokenReview",
 		},
 		Status: v1.TokenReviewStatus{
-			Error: err.Error(),
+			Authenticated: false,
+			Error:         err.Error(),
 		},
 	})
 }
+
+func rancherTokenAudiences() []string {
+	var audiences []string
+	for _, audience := range strings.Split(settings.TokenReviewAudiences.Get(), ",") {
+		if audience = strings.TrimSpace(audience); audience != "" {
+			audiences = append(audiences, audience)
+		}
+	}
+	return audiences
+}
+
+// matchAudiences returns the audiences of the token that are requested. A token without audiences is valid for any
+// audience and a review without audiences accepts any audience of the token.
+func matchAudiences(requested, audiences []string) ([]string, bool) {
+	if len(audiences) == 0 {
+		return requested, true
+	}
+	if len(requested) == 0 {
+		return audiences, true
+	}
+
+	var matched []string
+	for _, audience := range requested {
+		for _, tokenAudience := range audiences {
+			if audience == tokenAudience {
+				matched = append(matched, audience)
+				break
+			}
+		}
+	}
+	return matched, len(matched) > 0
+}
+
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && !strings.Contains(token, ":")
+}
@@ -20,11 +20,12 @@
 
 func Handler(clusterRegistrationToken v3.ClusterRegistrationTokenCache) http.HandlerFunc {
 	clusterRegistrationToken.AddIndexer(tokenHash, func(obj *apimgmtv3.ClusterRegistrationToken) ([]string, error) {
-		if obj.Status.Token == "" {
-			return nil, nil
+		var hashes []string
+		for _, token := range obj.Tokens() {
+			hash := sha256.Sum256([]byte(token))
+			hashes = append(hashes, base64.StdEncoding.EncodeToString(hash[:]))
 		}
-		hash := sha256.Sum256([]byte(obj.Status.Token))
-		return []string{base64.StdEncoding.EncodeToString(hash[:])}, nil
+		return hashes, nil
 	})
 	return func(rw http.ResponseWriter, req *http.Request) {
 		handler(clusterRegistrationToken, rw, req)
@@ -51,8 +52,8 @@ func handler(clusterRegistrationToken v3.ClusterRegistrationTokenCache, rw http.
 
 	if authorization != "" && nonce != "" {
 		crt, err := clusterRegistrationToken.GetByIndex(tokenHash, authorization)
-		if err == nil && len(crt) >= 0 {
-			digest := hmac.New(sha512.New, []byte(crt[0].Status.Token))
+		if token := tokenForHash(crt, authorization); err == nil && token != "" {
+			digest := hmac.New(sha512.New, []byte(token))
 			digest.Write([]byte(nonce))
 			digest.Write([]byte{0})
 			digest.Write(bytes)
@@ -66,3 +67,17 @@ func handler(clusterRegistrationToken v3.ClusterRegistrationTokenCache, rw http.
 		_, _ = rw.Write([]byte(ca))
 	}
 }
+
+// tokenForHash returns the token of the cluster registration tokens with the given hash, a token may be the current,
+// next or previous token while it is being rotated
+func tokenForHash(crts []*apimgmtv3.ClusterRegistrationToken, hash string) string {
+	for _, crt := range crts {
+		for _, token := range crt.Tokens() {
+			sum := sha256.Sum256([]byte(token))
+			if base64.StdEncoding.EncodeToString(sum[:]) == hash {
+				return token
+			}
+		}
+	}
+	return ""
+}
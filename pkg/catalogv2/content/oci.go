@@ -0,0 +1,113 @@
+package content
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/rancher/rancher/pkg/catalogv2/oci"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// OCISource is a chart pulled from a repository of an OCI registry instead of a repo index
+type OCISource struct {
+	// Reference is the oci:// reference of the repository holding the versions of the chart, it can be pinned to the
+	// digest of a manifest
+	Reference string
+	// SecretNamespace and SecretName reference a basic-auth or dockerconfigjson secret holding the credentials of
+	// the registry
+	SecretNamespace string
+	SecretName      string
+}
+
+// Pinned returns the source pinned to the digest of a manifest
+func (s OCISource) Pinned(digest string) OCISource {
+	if i := strings.Index(s.Reference, "@"); i >= 0 {
+		s.Reference = s.Reference[:i]
+	}
+	s.Reference += "@" + digest
+	return s
+}
+
+func (c *Manager) ociClient(source OCISource) (*oci.Client, oci.Reference, error) {
+	ref, err := oci.ParseReference(source.Reference)
+	if err != nil {
+		return nil, oci.Reference{}, err
+	}
+
+	var secret *corev1.Secret
+	if source.SecretName != "" {
+		secret, err = c.secrets.Get(source.SecretNamespace, source.SecretName)
+		if err != nil {
+			return nil, oci.Reference{}, err
+		}
+	}
+
+	client, err := oci.NewClient(secret, ref.Registry)
+	return client, ref, err
+}
+
+// OCIChartVersion returns the latest version of the chart of the source matching the semver constraint, and the
+// digest of its manifest. If the reference is pinned to a digest, the version of that manifest is returned.
+func (c *Manager) OCIChartVersion(source OCISource, constraint string) (string, string, error) {
+	client, ref, err := c.ociClient(source)
+	if err != nil {
+		return "", "", err
+	}
+
+	if ref.Digest != "" {
+		version, digest, err := client.Version(ref, ref.Digest)
+		if err != nil {
+			return "", "", err
+		}
+		if err := checkVersion(version, constraint); err != nil {
+			return "", "", fmt.Errorf("chart pinned by %s: %w", ref, err)
+		}
+		return version, digest, nil
+	}
+
+	tags, err := client.Tags(ref)
+	if err != nil {
+		return "", "", err
+	}
+	version, err := oci.LatestVersion(tags, constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find chart version in %s: %w", ref, err)
+	}
+
+	_, digest, err := client.Version(ref, oci.VersionToTag(version))
+	if err != nil {
+		return "", "", err
+	}
+	return version, digest, nil
+}
+
+// OCIChart returns the tarball of the version of the chart of the source, or of the manifest the reference is pinned
+// to
+func (c *Manager) OCIChart(source OCISource, version string) (io.ReadCloser, error) {
+	client, ref, err := c.ociClient(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.Digest != "" {
+		return client.Chart(ref, ref.Digest)
+	}
+	return client.Chart(ref, oci.VersionToTag(version))
+}
+
+func checkVersion(version, constraint string) error {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return err
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return err
+	}
+	if !c.Check(v) {
+		return fmt.Errorf("version %s does not match %s", version, constraint)
+	}
+	return nil
+}
@@ -124,6 +124,19 @@ func (s *Operations) Upgrade(ctx context.Context, user user.Info, namespace, nam
 	return s.createOperation(ctx, user, status, cmds)
 }
 
+// UpgradeOCI is like Upgrade, but the charts are pulled from the repository of the OCI source instead of a repo. It
+// is only used to install system charts, so the user is not replaced by the service account of a repo.
+func (s *Operations) UpgradeOCI(ctx context.Context, user user.Info, source content.OCISource, options io.Reader) (*catalog.Operation, error) {
+	status, cmds, err := s.getUpgradeCommandFrom(options, func(chartName, chartVersion string) (io.ReadCloser, error) {
+		return s.contentManager.OCIChart(source, chartVersion)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createOperation(ctx, user, status, cmds)
+}
+
 func (s *Operations) Install(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (*catalog.Operation, error) {
 	status, cmds, err := s.getInstallCommand(namespace, name, options)
 	if err != nil {
@@ -294,7 +307,20 @@ func (s *Operations) getUninstallArgs(appNamespace, appName string, body io.Read
 	return status, Commands{cmd}, nil
 }
 
+// chartLoader returns the tarball of a version of a chart
+type chartLoader func(chartName, chartVersion string) (io.ReadCloser, error)
+
+func (s *Operations) repoChartLoader(repoNamespace, repoName string) chartLoader {
+	return func(chartName, chartVersion string) (io.ReadCloser, error) {
+		return s.contentManager.Chart(repoNamespace, repoName, chartName, chartVersion)
+	}
+}
+
 func (s *Operations) getUpgradeCommand(repoNamespace, repoName string, body io.Reader) (catalog.OperationStatus, Commands, error) {
+	return s.getUpgradeCommandFrom(body, s.repoChartLoader(repoNamespace, repoName))
+}
+
+func (s *Operations) getUpgradeCommandFrom(body io.Reader, loadChart chartLoader) (catalog.OperationStatus, Commands, error) {
 	var (
 		upgradeArgs = &types2.ChartUpgradeAction{}
 		commands    Commands
@@ -315,7 +341,7 @@ func (s *Operations) getUpgradeCommand(repoNamespace, repoName string, body io.R
 	}
 
 	for _, chartUpgrade := range upgradeArgs.Charts {
-		cmd, err := s.getChartCommand(repoNamespace, repoName, chartUpgrade.ChartName, chartUpgrade.Version, chartUpgrade.Annotations, chartUpgrade.Values)
+		cmd, err := s.getChartCommand(loadChart, chartUpgrade.ChartName, chartUpgrade.Version, chartUpgrade.Annotations, chartUpgrade.Values)
 		if err != nil {
 			return status, nil, err
 		}
@@ -534,8 +560,8 @@ func addAnnotations(data []byte, annotations map[string]string) ([]byte, error)
 	return yaml.Marshal(chartData)
 }
 
-func (s *Operations) getChartCommand(namespace, name, chartName, chartVersion string, annotations map[string]string, values map[string]interface{}) (Command, error) {
-	chart, err := s.contentManager.Chart(namespace, name, chartName, chartVersion)
+func (s *Operations) getChartCommand(loadChart chartLoader, chartName, chartVersion string, annotations map[string]string, values map[string]interface{}) (Command, error) {
+	chart, err := loadChart(chartName, chartVersion)
 	if err != nil {
 		return Command{}, err
 	}
@@ -581,7 +607,7 @@ func (s *Operations) getInstallCommand(repoNamespace, repoName string, body io.R
 	)
 
 	for _, chartInstall := range installArgs.Charts {
-		cmd, err := s.getChartCommand(repoNamespace, repoName, chartInstall.ChartName, chartInstall.Version, chartInstall.Annotations, chartInstall.Values)
+		cmd, err := s.getChartCommand(s.repoChartLoader(repoNamespace, repoName), chartInstall.ChartName, chartInstall.Version, chartInstall.Annotations, chartInstall.Values)
 		if err != nil {
 			return status, nil, err
 		}
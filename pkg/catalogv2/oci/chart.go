@@ -0,0 +1,236 @@
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	ManifestMediaType   = "application/vnd.oci.image.manifest.v1+json"
+	ConfigMediaType     = "application/vnd.cncf.helm.config.v1+json"
+	ChartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// legacyChartLayerMediaType is the media type of the charts pushed by helm before 3.7
+	legacyChartLayerMediaType = "application/tar+gzip"
+
+	// maxManifestSize limits the size of the manifests and chart configs read in memory
+	maxManifestSize = 4 << 20
+)
+
+var nextLinkRegexp = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type manifest struct {
+	Config descriptor   `json:"config"`
+	Layers []descriptor `json:"layers"`
+}
+
+func scope(ref Reference) string {
+	return "repository:" + ref.Repository + ":pull"
+}
+
+// Tags lists the tags of the repository of the reference, following the pagination of the registry
+func (c *Client) Tags(ref Reference) ([]string, error) {
+	var tags []string
+
+	next := ref.baseURL() + "/v2/" + ref.Repository + "/tags/list"
+	for next != "" {
+		resp, err := c.get(next, "application/json", scope(ref))
+		if err != nil {
+			return nil, err
+		}
+
+		page := struct {
+			Tags []string `json:"tags"`
+		}{}
+		err = decode(resp, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", ref, err)
+		}
+		tags = append(tags, page.Tags...)
+
+		next, err = nextPage(resp, next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+func nextPage(resp *http.Response, current string) (string, error) {
+	match := nextLinkRegexp.FindStringSubmatch(resp.Header.Get("Link"))
+	if match == nil {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := url.Parse(match[1])
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(next).String(), nil
+}
+
+// Version returns the chart version stored in the config of the manifest of the tag or digest, and the digest of the
+// manifest
+func (c *Client) Version(ref Reference, reference string) (string, string, error) {
+	m, digest, err := c.manifest(ref, reference)
+	if err != nil {
+		return "", "", err
+	}
+	if m.Config.MediaType != ConfigMediaType {
+		return "", "", fmt.Errorf("manifest %s of %s is not a chart, config media type is %s", reference, ref, m.Config.MediaType)
+	}
+
+	data, err := c.blob(ref, m.Config, maxManifestSize)
+	if err != nil {
+		return "", "", err
+	}
+	config := struct {
+		Version string `json:"version"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", fmt.Errorf("failed to decode chart config of %s: %w", ref, err)
+	}
+	return config.Version, digest, nil
+}
+
+// Chart returns the chart tarball of the manifest of the tag or digest
+func (c *Client) Chart(ref Reference, reference string) (io.ReadCloser, error) {
+	m, _, err := c.manifest(ref, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range m.Layers {
+		if layer.MediaType != ChartLayerMediaType && layer.MediaType != legacyChartLayerMediaType {
+			continue
+		}
+		data, err := c.blob(ref, layer, -1)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return nil, fmt.Errorf("manifest %s of %s has no chart layer", reference, ref)
+}
+
+// manifest gets the manifest of the tag or digest and its digest. When getting a manifest by digest, the content is
+// checked against the digest.
+func (c *Client) manifest(ref Reference, reference string) (manifest, string, error) {
+	resp, err := c.get(ref.baseURL()+"/v2/"+ref.Repository+"/manifests/"+reference, ManifestMediaType, scope(ref))
+	if err != nil {
+		return manifest{}, "", err
+	}
+	data, err := read(resp, maxManifestSize)
+	if err != nil {
+		return manifest{}, "", fmt.Errorf("failed to get manifest %s of %s: %w", reference, ref, err)
+	}
+
+	digest := digestOf(data)
+	if digestRegexp.MatchString(reference) && digest != reference {
+		return manifest{}, "", fmt.Errorf("manifest %s of %s does not match its digest, got %s", reference, ref, digest)
+	}
+
+	m := manifest{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, "", fmt.Errorf("failed to decode manifest %s of %s: %w", reference, ref, err)
+	}
+	return m, digest, nil
+}
+
+// blob gets the content of the descriptor and checks it against its digest, limited to max bytes if not negative
+func (c *Client) blob(ref Reference, desc descriptor, max int64) ([]byte, error) {
+	resp, err := c.get(ref.baseURL()+"/v2/"+ref.Repository+"/blobs/"+desc.Digest, "", scope(ref))
+	if err != nil {
+		return nil, err
+	}
+	data, err := read(resp, max)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %s of %s: %w", desc.Digest, ref, err)
+	}
+	if digest := digestOf(data); digest != desc.Digest {
+		return nil, fmt.Errorf("blob %s of %s does not match its digest, got %s", desc.Digest, ref, digest)
+	}
+	return data, nil
+}
+
+func read(resp *http.Response, max int64) ([]byte, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("registry returned %s", resp.Status)
+	}
+
+	body := io.Reader(resp.Body)
+	if max >= 0 {
+		body = io.LimitReader(resp.Body, max+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if max >= 0 && int64(len(data)) > max {
+		return nil, fmt.Errorf("content is larger than %d bytes", max)
+	}
+	return data, nil
+}
+
+func decode(resp *http.Response, obj interface{}) error {
+	data, err := read(resp, maxManifestSize)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// LatestVersion returns the highest chart version of the tags matching the semver constraint, tags that are not
+// versions are ignored
+func LatestVersion(tags []string, constraint string) (string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	var latest *semver.Version
+	for _, tag := range tags {
+		version, err := semver.NewVersion(TagToVersion(tag))
+		if err != nil {
+			continue
+		}
+		if !c.Check(version) {
+			continue
+		}
+		if latest == nil || version.GreaterThan(latest) {
+			latest = version
+		}
+	}
+
+	if latest == nil {
+		return "", fmt.Errorf("no tag matches version %s", constraint)
+	}
+	return latest.Original(), nil
+}
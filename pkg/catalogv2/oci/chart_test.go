@@ -0,0 +1,306 @@
+package oci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	testUsername = "admin"
+	testPassword = "s3cr3t"
+	testToken    = "registry-token"
+)
+
+// testRegistry is a local OCI registry serving charts with the distribution API. Requests to the repositories require
+// a bearer token, which the token endpoint issues for the test credentials.
+type testRegistry struct {
+	server    *httptest.Server
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	tags      map[string]map[string]string
+	pageSize  int
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	r := &testRegistry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+		tags:      map[string]map[string]string{},
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *testRegistry) host() string {
+	return strings.TrimPrefix(r.server.URL, "http://")
+}
+
+func (r *testRegistry) reference(repository string) Reference {
+	return Reference{Registry: r.host(), Repository: repository}
+}
+
+// push stores a chart and its config, tagged with the version, and returns the digest of its manifest
+func (r *testRegistry) push(t *testing.T, repository, version string) string {
+	chart := []byte("chart " + repository + " " + version)
+	config, err := json.Marshal(map[string]string{"name": repository, "version": version})
+	require.NoError(t, err)
+
+	r.blobs[digestOf(chart)] = chart
+	r.blobs[digestOf(config)] = config
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        descriptor{MediaType: ConfigMediaType, Digest: digestOf(config), Size: int64(len(config))},
+		"layers": []descriptor{
+			{MediaType: ChartLayerMediaType, Digest: digestOf(chart), Size: int64(len(chart))},
+		},
+	})
+	require.NoError(t, err)
+
+	digest := digestOf(manifest)
+	r.manifests[digest] = manifest
+	if r.tags[repository] == nil {
+		r.tags[repository] = map[string]string{}
+	}
+	r.tags[repository][VersionToTag(version)] = digest
+	return digest
+}
+
+func (r *testRegistry) serveHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		username, password, ok := req.BasicAuth()
+		if !ok || username != testUsername || password != testPassword {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(rw).Encode(map[string]string{"token": testToken})
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	var repository, kind, reference string
+	for _, k := range []string{"/tags/", "/manifests/", "/blobs/"} {
+		if i := strings.LastIndex(path, k); i >= 0 {
+			repository, kind, reference = path[:i], strings.Trim(k, "/"), path[i+len(k):]
+			break
+		}
+	}
+
+	if req.Header.Get("Authorization") != "Bearer "+testToken {
+		rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry",scope="repository:%s:pull"`, r.server.URL, repository))
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch kind {
+	case "tags":
+		r.serveTags(rw, req, repository)
+	case "manifests":
+		digest := reference
+		if !digestRegexp.MatchString(reference) {
+			digest = r.tags[repository][reference]
+		}
+		manifest, ok := r.manifests[digest]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("Content-Type", ManifestMediaType)
+		rw.Header().Set("Docker-Content-Digest", digest)
+		rw.Write(manifest)
+	case "blobs":
+		blob, ok := r.blobs[reference]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write(blob)
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (r *testRegistry) serveTags(rw http.ResponseWriter, req *http.Request, repository string) {
+	var tags []string
+	for tag := range r.tags[repository] {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	if last := req.URL.Query().Get("last"); last != "" {
+		i := sort.SearchStrings(tags, last)
+		if i < len(tags) && tags[i] == last {
+			i++
+		}
+		tags = tags[i:]
+	}
+	if r.pageSize > 0 && len(tags) > r.pageSize {
+		tags = tags[:r.pageSize]
+		rw.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?n=%s&last=%s>; rel="next"`, repository, strconv.Itoa(r.pageSize), tags[len(tags)-1]))
+	}
+	json.NewEncoder(rw).Encode(map[string]interface{}{"name": repository, "tags": tags})
+}
+
+func basicAuthSecret(username, password string) *corev1.Secret {
+	return &corev1.Secret{
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte(username),
+			corev1.BasicAuthPasswordKey: []byte(password),
+		},
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{
+			ref:  "oci://registry.example.com/rancher/charts/rancher-webhook",
+			want: Reference{Registry: "registry.example.com", Repository: "rancher/charts/rancher-webhook"},
+		},
+		{
+			ref:  "oci://registry.example.com:5000/rancher-webhook@" + digest,
+			want: Reference{Registry: "registry.example.com:5000", Repository: "rancher-webhook", Digest: digest},
+		},
+		{ref: "https://registry.example.com/rancher-webhook", wantErr: true},
+		{ref: "oci://registry.example.com", wantErr: true},
+		{ref: "oci://registry.example.com/rancher-webhook:0.1.0", wantErr: true},
+		{ref: "oci://registry.example.com/rancher-webhook@sha256:abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			ref, err := ParseReference(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ref)
+			assert.Equal(t, tt.ref, ref.String())
+		})
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	assert.Equal(t, "https://registry.example.com", Reference{Registry: "registry.example.com"}.baseURL())
+	assert.Equal(t, "http://127.0.0.1:5000", Reference{Registry: "127.0.0.1:5000"}.baseURL())
+	assert.Equal(t, "http://localhost:5000", Reference{Registry: "localhost:5000"}.baseURL())
+}
+
+func TestLatestVersion(t *testing.T) {
+	tags := []string{"latest", "0.1.0", "0.2.0-rc1", "0.1.1_up1.2.3", "0.1.2"}
+
+	version, err := LatestVersion(tags, ">=0-a")
+	require.NoError(t, err)
+	assert.Equal(t, "0.2.0-rc1", version)
+
+	version, err = LatestVersion(tags, "0.1.1+up1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "0.1.1+up1.2.3", version)
+
+	_, err = LatestVersion(tags, ">=1.0.0")
+	assert.Error(t, err)
+}
+
+func TestTags(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.pageSize = 2
+	for _, version := range []string{"0.1.0", "0.1.1", "0.2.0", "0.2.1+up1.0.0", "0.3.0-rc1"} {
+		registry.push(t, "rancher/charts/rancher-webhook", version)
+	}
+
+	client, err := NewClient(basicAuthSecret(testUsername, testPassword), registry.host())
+	require.NoError(t, err)
+
+	tags, err := client.Tags(registry.reference("rancher/charts/rancher-webhook"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.1.0", "0.1.1", "0.2.0", "0.2.1_up1.0.0", "0.3.0-rc1"}, tags)
+
+	version, err := LatestVersion(tags, ">=0-a")
+	require.NoError(t, err)
+	assert.Equal(t, "0.3.0-rc1", version)
+}
+
+func TestAuthentication(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.push(t, "rancher-webhook", "0.1.0")
+	ref := registry.reference("rancher-webhook")
+
+	client, err := NewClient(nil, registry.host())
+	require.NoError(t, err)
+	_, err = client.Tags(ref)
+	assert.Error(t, err, "expected anonymous pull to be denied")
+
+	client, err = NewClient(basicAuthSecret(testUsername, "wrong"), registry.host())
+	require.NoError(t, err)
+	_, err = client.Tags(ref)
+	assert.Error(t, err, "expected invalid credentials to be denied")
+
+	auths, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			"other.example.com": map[string]string{"username": "other", "password": "other"},
+			"http://" + registry.host(): map[string]string{
+				"auth": base64.StdEncoding.EncodeToString([]byte(testUsername + ":" + testPassword)),
+			},
+		},
+	})
+	require.NoError(t, err)
+	client, err = NewClient(&corev1.Secret{
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: auths},
+	}, registry.host())
+	require.NoError(t, err)
+	tags, err := client.Tags(ref)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.1.0"}, tags)
+}
+
+func TestDigestPinning(t *testing.T) {
+	registry := newTestRegistry(t)
+	pinned := registry.push(t, "rancher-webhook", "0.1.0")
+	latest := registry.push(t, "rancher-webhook", "0.2.0")
+	ref := registry.reference("rancher-webhook")
+
+	client, err := NewClient(basicAuthSecret(testUsername, testPassword), registry.host())
+	require.NoError(t, err)
+
+	version, digest, err := client.Version(ref, VersionToTag("0.2.0"))
+	require.NoError(t, err)
+	assert.Equal(t, "0.2.0", version)
+	assert.Equal(t, latest, digest)
+
+	version, digest, err = client.Version(ref, pinned)
+	require.NoError(t, err)
+	assert.Equal(t, "0.1.0", version)
+	assert.Equal(t, pinned, digest)
+
+	chart, err := client.Chart(ref, pinned)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(chart)
+	require.NoError(t, err)
+	assert.Equal(t, "chart rancher-webhook 0.1.0", string(data))
+
+	// a registry serving different content for the digest is rejected
+	registry.manifests[pinned] = registry.manifests[latest]
+	_, err = client.Chart(ref, pinned)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "does not match its digest")
+	}
+}
@@ -0,0 +1,251 @@
+package oci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Client pulls charts from an OCI registry with the distribution API. It authenticates with basic auth or bearer
+// tokens, depending on the challenge returned by the registry.
+type Client struct {
+	client   *http.Client
+	username string
+	password string
+
+	lock sync.Mutex
+	// authorizations holds the Authorization headers accepted by the registry, by scope
+	authorizations map[string]string
+}
+
+// NewClient returns a client for the registry, authenticating with the credentials of the secret if not nil. The
+// secret can be a basic-auth secret or a dockerconfigjson secret holding credentials for the registry.
+func NewClient(secret *corev1.Secret, registry string) (*Client, error) {
+	username, password, err := credentials(secret, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		client: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+			Timeout:   30 * time.Second,
+		},
+		username:       username,
+		password:       password,
+		authorizations: map[string]string{},
+	}, nil
+}
+
+func credentials(secret *corev1.Secret, registry string) (string, string, error) {
+	if secret == nil {
+		return "", "", nil
+	}
+
+	switch secret.Type {
+	case corev1.SecretTypeBasicAuth:
+		return string(secret.Data[corev1.BasicAuthUsernameKey]), string(secret.Data[corev1.BasicAuthPasswordKey]), nil
+	case corev1.SecretTypeDockerConfigJson:
+		config := struct {
+			Auths map[string]struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+				Auth     string `json:"auth"`
+			} `json:"auths"`
+		}{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return "", "", fmt.Errorf("failed to parse %s of secret %s/%s: %w", corev1.DockerConfigJsonKey, secret.Namespace, secret.Name, err)
+		}
+		for server, auth := range config.Auths {
+			if registryHost(server) != registry {
+				continue
+			}
+			if auth.Auth == "" {
+				return auth.Username, auth.Password, nil
+			}
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to decode auth of %s in secret %s/%s: %w", server, secret.Namespace, secret.Name, err)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return "", "", fmt.Errorf("invalid auth of %s in secret %s/%s", server, secret.Namespace, secret.Name)
+			}
+			return parts[0], parts[1], nil
+		}
+		return "", "", nil
+	}
+
+	return "", "", fmt.Errorf("secret %s/%s must be of type %s or %s", secret.Namespace, secret.Name, corev1.SecretTypeBasicAuth, corev1.SecretTypeDockerConfigJson)
+}
+
+// registryHost returns the host of the server of a docker config, which can be a URL
+func registryHost(server string) string {
+	if strings.Contains(server, "://") {
+		if u, err := url.Parse(server); err == nil {
+			return u.Host
+		}
+	}
+	return strings.SplitN(server, "/", 2)[0]
+}
+
+// get sends a GET request to the registry, authenticating and sending it again if the registry returns a challenge
+func (c *Client) get(u, accept, scope string) (*http.Response, error) {
+	resp, err := c.send(u, accept, scope)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err := c.authenticate(challenge, scope); err != nil {
+		return nil, err
+	}
+	return c.send(u, accept, scope)
+}
+
+func (c *Client) send(u, accept, scope string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	c.lock.Lock()
+	authorization := c.authorizations[scope]
+	c.lock.Unlock()
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return c.client.Do(req)
+}
+
+func (c *Client) authenticate(challenge, scope string) error {
+	scheme, params := parseChallenge(challenge)
+
+	var authorization string
+	switch scheme {
+	case "basic":
+		if c.username == "" && c.password == "" {
+			return fmt.Errorf("registry requires credentials")
+		}
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+	case "bearer":
+		token, err := c.token(params, scope)
+		if err != nil {
+			return err
+		}
+		authorization = "Bearer " + token
+	default:
+		return fmt.Errorf("unsupported registry authentication challenge %q", challenge)
+	}
+
+	c.lock.Lock()
+	c.authorizations[scope] = authorization
+	c.lock.Unlock()
+	return nil
+}
+
+// token gets a bearer token from the realm of the challenge, anonymously if the client has no credentials
+func (c *Client) token(params map[string]string, scope string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("invalid registry authentication realm %q", params["realm"])
+	}
+
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	if params["scope"] != "" {
+		scope = params["scope"]
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to get registry token from %s: %s", realm.Host, resp.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode registry token from %s: %w", realm.Host, err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", fmt.Errorf("registry token response from %s has no token", realm.Host)
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry",scope="repository:charts:pull", the scheme is
+// returned in lower case
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme := strings.ToLower(parts[0])
+	if len(parts) == 1 {
+		return scheme, params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		i := strings.Index(rest, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:i]))
+		rest = rest[i+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if end := strings.Index(rest, ","); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
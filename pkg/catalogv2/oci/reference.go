@@ -0,0 +1,82 @@
+package oci
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const Scheme = "oci://"
+
+var digestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Reference is a repository of an OCI registry holding the versions of a chart, such as
+// oci://registry.example.com/rancher/charts/rancher-webhook. The reference can be pinned to the digest of a manifest
+// with @sha256:<hex>.
+type Reference struct {
+	Registry   string
+	Repository string
+	Digest     string
+}
+
+// ParseReference parses an oci:// reference, tags are not allowed as the version of the chart is resolved from the
+// tags of the repository
+func ParseReference(ref string) (Reference, error) {
+	if !strings.HasPrefix(ref, Scheme) {
+		return Reference{}, fmt.Errorf("invalid OCI reference %s: must start with %s", ref, Scheme)
+	}
+
+	result := Reference{}
+	name := strings.TrimPrefix(ref, Scheme)
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, result.Digest = name[:i], name[i+1:]
+		if !digestRegexp.MatchString(result.Digest) {
+			return Reference{}, fmt.Errorf("invalid OCI reference %s: digest must be sha256:<hex>", ref)
+		}
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Reference{}, fmt.Errorf("invalid OCI reference %s: must be %s<registry>/<repository>", ref, Scheme)
+	}
+	if strings.Contains(parts[1], ":") {
+		return Reference{}, fmt.Errorf("invalid OCI reference %s: tags are not allowed, pin a digest instead", ref)
+	}
+	result.Registry, result.Repository = parts[0], strings.TrimSuffix(parts[1], "/")
+	return result, nil
+}
+
+func (r Reference) String() string {
+	ref := Scheme + r.Registry + "/" + r.Repository
+	if r.Digest != "" {
+		ref += "@" + r.Digest
+	}
+	return ref
+}
+
+// baseURL returns the URL of the registry API, plain HTTP is only used for registries on the loopback interface
+func (r Reference) baseURL() string {
+	host := r.Registry
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return "http://" + r.Registry
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "http://" + r.Registry
+	}
+	return "https://" + r.Registry
+}
+
+// VersionToTag returns the tag of a chart version, "+" is not allowed in tags and is replaced by "_" when charts are
+// pushed
+func VersionToTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+// TagToVersion returns the chart version of a tag
+func TagToVersion(tag string) string {
+	return strings.ReplaceAll(tag, "_", "+")
+}
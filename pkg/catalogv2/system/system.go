@@ -5,6 +5,8 @@
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,7 +17,9 @@
 	"github.com/rancher/rancher/pkg/catalogv2/content"
 	"github.com/rancher/rancher/pkg/catalogv2/helmop"
 	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	"github.com/rancher/rancher/pkg/settings"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/kv"
 	"github.com/sirupsen/logrus"
 	"helm.sh/helm/v3/pkg/action"
 	release2 "helm.sh/helm/v3/pkg/release"
@@ -26,8 +30,13 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/yaml"
 )
 
+// RequestedByAnnotation is set on the chart of the releases installed by the Manager, it records who asked for the
+// install that created the release revision
+const RequestedByAnnotation = "catalog.cattle.io/requested-by"
+
 var (
 	installUser = &user.DefaultInfo{
 		Name: "helm-installer",
@@ -38,34 +47,66 @@
 	}
 )
 
+// desiredKey identifies a release, so that the last request for a chart replaces the earlier ones even if they asked
+// for another version
 type desiredKey struct {
-	namespace  string
-	name       string
-	minVersion string
+	namespace string
+	name      string
+}
+
+// SecretValuesReference references a key of a Secret holding chart values as YAML or JSON, the key defaults to
+// values.yaml
+type SecretValuesReference struct {
+	Namespace string
+	Name      string
+	Key       string
 }
 
 type desired struct {
 	key        desiredKey
+	minVersion string
+	// version pins the chart to exactly this version when set
+	version    string
 	values     map[string]interface{}
+	valuesFrom *SecretValuesReference
 	forceAdopt bool
+	// requestedBy identifies who asked for the chart, such as a controller or a cluster, it is logged and recorded on
+	// the release
+	requestedBy string
+}
+
+// operations are the helm operations of helmop.Operations used by the Manager
+type operations interface {
+	Upgrade(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (*catalog.Operation, error)
+	UpgradeOCI(ctx context.Context, user user.Info, source content.OCISource, options io.Reader) (*catalog.Operation, error)
+	Uninstall(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (*catalog.Operation, error)
 }
 
 type Manager struct {
 	ctx              context.Context
-	operation        *helmop.Operations
+	operation        operations
 	content          *content.Manager
 	restClientGetter genericclioptions.RESTClientGetter
 	pods             corecontrollers.PodClient
-	desiredCharts    map[desiredKey]map[string]interface{}
+	secrets          corecontrollers.SecretCache
+	desiredCharts    map[desiredKey]desired
 	sync             chan desired
-	syncLock         sync.Mutex
+	// valuesChanged receives the charts whose values Secret changed, they are installed again even though the desired
+	// chart is unchanged
+	valuesChanged chan desired
+	syncLock      sync.Mutex
+	// installLock serializes the installs and the removals of the charts, so that a chart removed while it is being
+	// installed is uninstalled once the install is done rather than installed again
+	installLock sync.Mutex
+	hasStatus   func(namespace, name string, stateMask action.ListStates) (bool, error)
 }
 
 func NewManager(ctx context.Context,
 	restClientGetter genericclioptions.RESTClientGetter,
 	contentManager *content.Manager,
 	ops *helmop.Operations,
-	pods corecontrollers.PodClient) (*Manager, error) {
+	pods corecontrollers.PodClient,
+	secrets corecontrollers.SecretController) (*Manager, error) {
 
 	m := &Manager{
 		ctx:              ctx,
@@ -73,9 +114,13 @@ func NewManager(ctx context.Context,
 		content:          contentManager,
 		restClientGetter: restClientGetter,
 		pods:             pods,
+		secrets:          secrets.Cache(),
 		sync:             make(chan desired, 10),
-		desiredCharts:    map[desiredKey]map[string]interface{}{},
+		valuesChanged:    make(chan desired, 10),
+		desiredCharts:    map[desiredKey]desired{},
 	}
+	m.hasStatus = m.releaseHasStatus
+	secrets.OnChange(ctx, "system-charts-values", m.onValuesSecretChange)
 
 	return m, nil
 }
@@ -94,35 +139,125 @@ func (m *Manager) runSync() {
 		case <-m.ctx.Done():
 			return
 		case <-t.C:
-			m.installCharts(m.desiredCharts, true)
-		case desired := <-m.sync:
-			v, exists := m.desiredCharts[desired.key]
-			m.desiredCharts[desired.key] = desired.values
-			// newly requested or changed
-			if !exists || !equality.Semantic.DeepEqual(v, desired.values) {
-				m.installCharts(map[desiredKey]map[string]interface{}{
-					desired.key: desired.values,
-				}, desired.forceAdopt)
+			m.syncLock.Lock()
+			charts := make(map[desiredKey]desired, len(m.desiredCharts))
+			for key, desired := range m.desiredCharts {
+				charts[key] = desired
 			}
+			m.syncLock.Unlock()
+			m.installCharts(charts, true)
+		case desired := <-m.valuesChanged:
+			m.installCharts(map[desiredKey]desired{
+				desired.key: desired,
+			}, desired.forceAdopt)
+		case desired := <-m.sync:
+			m.installCharts(map[desiredKey]desired{
+				desired.key: desired,
+			}, desired.forceAdopt)
+		}
+	}
+}
+
+// changed returns true if the desired chart has to be installed again to apply the request
+func (d desired) changed(previous desired) bool {
+	return previous.minVersion != d.minVersion || previous.version != d.version ||
+		!equality.Semantic.DeepEqual(previous.values, d.values) || !equality.Semantic.DeepEqual(previous.valuesFrom, d.valuesFrom)
+}
+
+// ensure records the desired chart and queues its install if it is newly requested or changed. The chart is recorded
+// right away so that a later Remove drops it.
+func (m *Manager) ensure(desired desired) {
+	m.syncLock.Lock()
+	previous, exists := m.desiredCharts[desired.key]
+	m.desiredCharts[desired.key] = desired
+	m.syncLock.Unlock()
+
+	if !exists || desired.changed(previous) {
+		go func() {
+			m.sync <- desired
+		}()
+	}
+}
+
+// isDesired returns true if the chart is still desired as requested, it is no longer desired once removed and the
+// request is outdated once the chart is requested again
+func (m *Manager) isDesired(desired desired) bool {
+	m.syncLock.Lock()
+	defer m.syncLock.Unlock()
+	current, ok := m.desiredCharts[desired.key]
+	return ok && !desired.changed(current)
+}
+
+// onValuesSecretChange installs the charts whose values are read from the Secret again, so that changes to the Secret
+// don't wait for the periodic sync
+func (m *Manager) onValuesSecretChange(key string, secret *v1.Secret) (*v1.Secret, error) {
+	if secret == nil {
+		return nil, nil
+	}
+
+	m.syncLock.Lock()
+	var charts []desired
+	for _, desired := range m.desiredCharts {
+		if desired.valuesFrom != nil && desired.valuesFrom.Namespace == secret.Namespace && desired.valuesFrom.Name == secret.Name {
+			charts = append(charts, desired)
 		}
 	}
+	m.syncLock.Unlock()
+
+	if len(charts) > 0 {
+		go func() {
+			for _, desired := range charts {
+				m.valuesChanged <- desired
+			}
+		}()
+	}
+	return secret, nil
 }
 
-func (m *Manager) installCharts(charts map[desiredKey]map[string]interface{}, forceAdopt bool) {
-	for key, values := range charts {
+func (m *Manager) installCharts(charts map[desiredKey]desired, forceAdopt bool) {
+	for key, desired := range charts {
+		logger := logrus.WithFields(logrus.Fields{
+			"chart":       key.name,
+			"namespace":   key.namespace,
+			"requestedBy": desired.requestedBy,
+		})
 		for {
-			if err := m.install(key.namespace, key.name, key.minVersion, values, forceAdopt); err == repo.ErrNoChartName || apierrors.IsNotFound(err) {
-				logrus.Errorf("Failed to find system chart %s will try again in 5 seconds: %v", key.name, err)
+			if err := m.installDesired(desired, forceAdopt); err == repo.ErrNoChartName || apierrors.IsNotFound(err) {
+				logger.Errorf("Failed to find system chart %s will try again in 5 seconds: %v", key.name, err)
 				time.Sleep(5 * time.Second)
 				continue
 			} else if err != nil {
-				logrus.Errorf("Failed to install system chart %s: %v", key.name, err)
+				logger.Errorf("Failed to install system chart %s: %v", key.name, err)
 			}
 			break
 		}
 	}
 }
 
+// installDesired installs the chart unless it was removed or requested again since, the install is serialized with the
+// removals
+func (m *Manager) installDesired(desired desired, forceAdopt bool) error {
+	m.installLock.Lock()
+	defer m.installLock.Unlock()
+
+	if !m.isDesired(desired) {
+		return nil
+	}
+	return m.install(desired.key.namespace, desired.key.name, desired.minVersion, desired.version, desired.values, desired.valuesFrom, forceAdopt, desired.requestedBy)
+}
+
+// Remove stops keeping the chart installed and uninstalls its release, waiting for the uninstall to complete. An install
+// of the chart in progress completes before the release is uninstalled.
+func (m *Manager) Remove(namespace, name string) error {
+	m.syncLock.Lock()
+	delete(m.desiredCharts, desiredKey{namespace: namespace, name: name})
+	m.syncLock.Unlock()
+
+	m.installLock.Lock()
+	defer m.installLock.Unlock()
+	return m.Uninstall(namespace, name)
+}
+
 func (m *Manager) Uninstall(namespace, name string) error {
 	if ok, err := m.hasStatus(namespace, name, action.ListDeployed|action.ListFailed); err != nil {
 		return err
@@ -151,34 +286,77 @@ func (m *Manager) Uninstall(namespace, name string) error {
 	return m.waitPodDone(op)
 }
 
-func (m *Manager) Ensure(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool) error {
-	go func() {
-		m.sync <- desired{
-			key: desiredKey{
-				namespace:  namespace,
-				name:       name,
-				minVersion: minVersion,
-			},
-			values:     values,
-			forceAdopt: forceAdopt,
-		}
-	}()
+// Ensure installs the chart at the latest version, or keeps the installed release if it is at least minVersion.
+// requestedBy identifies the caller in the logs of the install and on the release, with the RequestedByAnnotation.
+func (m *Manager) Ensure(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, requestedBy string) error {
+	m.ensure(desired{
+		key: desiredKey{
+			namespace: namespace,
+			name:      name,
+		},
+		minVersion:  minVersion,
+		values:      values,
+		forceAdopt:  forceAdopt,
+		requestedBy: requestedBy,
+	})
 	return nil
 }
 
-func (m *Manager) install(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool) error {
-	index, err := m.content.Index("", "rancher-charts")
+// EnsureWithSecretValues is like Ensure, but the values stored in the referenced Secret are merged over the inline
+// values. The Secret is read each time the chart is installed, so its values are not kept with the desired charts and
+// never logged.
+func (m *Manager) EnsureWithSecretValues(namespace, name, minVersion string, values map[string]interface{}, valuesFrom SecretValuesReference, forceAdopt bool, requestedBy string) error {
+	m.ensure(desired{
+		key: desiredKey{
+			namespace: namespace,
+			name:      name,
+		},
+		minVersion:  minVersion,
+		values:      values,
+		valuesFrom:  &valuesFrom,
+		forceAdopt:  forceAdopt,
+		requestedBy: requestedBy,
+	})
+	return nil
+}
+
+// EnsureVersion is like Ensure, but installs exactly the given version of the chart and keeps the release at that
+// version, downgrading it if needed. An error is returned if the version is not available in the repo.
+func (m *Manager) EnsureVersion(namespace, name, version string, values map[string]interface{}, forceAdopt bool, requestedBy string) error {
+	if _, _, err := m.chartVersion(name, version); err != nil {
+		return fmt.Errorf("version %s of chart %s is not available: %w", version, name, err)
+	}
+
+	m.ensure(desired{
+		key: desiredKey{
+			namespace: namespace,
+			name:      name,
+		},
+		version:     version,
+		values:      values,
+		forceAdopt:  forceAdopt,
+		requestedBy: requestedBy,
+	})
+	return nil
+}
+
+func (m *Manager) install(namespace, name, minVersion, version string, values map[string]interface{}, valuesFrom *SecretValuesReference, forceAdopt bool, requestedBy string) error {
+	values, err := m.resolveValues(values, valuesFrom)
 	if err != nil {
 		return err
 	}
 
 	// get latest, the >=0-a is a weird syntax to match everything including prereleases build
-	chart, err := index.Get(name, ">=0-a")
+	constraint := ">=0-a"
+	if version != "" {
+		constraint = version
+	}
+	chartVersion, source, err := m.chartVersion(name, constraint)
 	if err != nil {
 		return err
 	}
 
-	installed, desiredVersion, desiredValue, err := m.isInstalled(namespace, name, chart.Version, minVersion, values)
+	installed, desiredVersion, desiredValue, err := m.isInstalled(namespace, name, chartVersion, minVersion, version != "", values)
 	if err != nil {
 		return err
 	} else if installed {
@@ -193,7 +371,37 @@ func (m *Manager) install(namespace, name, minVersion string, values map[string]
 		}
 	}
 
-	upgrade, err := json.Marshal(types.ChartUpgradeAction{
+	upgrade, err := upgradeAction(namespace, name, desiredVersion, desiredValue, forceAdopt, requestedBy)
+	if err != nil {
+		return err
+	}
+
+	var op *catalog.Operation
+	if source == nil {
+		op, err = m.operation.Upgrade(m.ctx, installUser, "", "rancher-charts", bytes.NewBuffer(upgrade))
+	} else {
+		if desiredVersion != chartVersion {
+			// the release is upgraded at its current version, pulled by tag instead of the digest resolved for
+			// the latest version
+			source.Digest = ""
+		}
+		op, err = m.operation.UpgradeOCI(m.ctx, installUser, source.resolved(), bytes.NewBuffer(upgrade))
+	}
+	if err != nil {
+		return err
+	}
+
+	return m.waitPodDone(op)
+}
+
+// upgradeAction returns the options of the upgrade installing the version of the chart, the caller who requested it is
+// recorded on the chart of the release
+func upgradeAction(namespace, name, version string, values map[string]interface{}, forceAdopt bool, requestedBy string) ([]byte, error) {
+	var annotations map[string]string
+	if requestedBy != "" {
+		annotations = map[string]string{RequestedByAnnotation: requestedBy}
+	}
+	return json.Marshal(types.ChartUpgradeAction{
 		Timeout:    &metav1.Duration{Duration: 5 * time.Minute},
 		Wait:       true,
 		Install:    true,
@@ -203,23 +411,129 @@ func (m *Manager) install(namespace, name, minVersion string, values map[string]
 		Charts: []types.ChartUpgrade{
 			{
 				ChartName:   name,
-				Version:     desiredVersion,
+				Version:     version,
 				ReleaseName: name,
-				Values:      desiredValue,
+				Values:      values,
 				ResetValues: true,
+				Annotations: annotations,
 			},
 		},
 	})
+}
+
+// ociSource is the OCI source of a system chart, with the digest of the manifest of the version it resolved to
+type ociSource struct {
+	content.OCISource
+	Digest string
+}
+
+// resolved returns the source pinned to the resolved digest, so that the chart pulled is the one that was resolved
+// even if the tag is moved in between
+func (s *ociSource) resolved() content.OCISource {
+	if s.Digest == "" {
+		return s.OCISource
+	}
+	return s.OCISource.Pinned(s.Digest)
+}
+
+// chartVersion returns the latest version of the chart matching the constraint, and the OCI source of the chart if it
+// is not installed from the rancher-charts repo
+func (m *Manager) chartVersion(name, constraint string) (string, *ociSource, error) {
+	source, ok, err := systemChartOCISource(name)
 	if err != nil {
-		return err
+		return "", nil, err
+	}
+	if ok {
+		version, digest, err := m.content.OCIChartVersion(source, constraint)
+		if err != nil {
+			return "", nil, err
+		}
+		return version, &ociSource{OCISource: source, Digest: digest}, nil
 	}
 
-	op, err := m.operation.Upgrade(m.ctx, installUser, "", "rancher-charts", bytes.NewBuffer(upgrade))
+	index, err := m.content.Index("", "rancher-charts")
 	if err != nil {
-		return err
+		return "", nil, err
+	}
+	chart, err := index.Get(name, constraint)
+	if err != nil {
+		return "", nil, err
 	}
+	return chart.Version, nil, nil
+}
 
-	return m.waitPodDone(op)
+// systemChartOCISource returns the OCI source of the chart, false if the chart is installed from the rancher-charts
+// repo. The reference of the chart in the system-charts-oci-references setting takes precedence over the
+// system-charts-oci-repository setting.
+func systemChartOCISource(name string) (content.OCISource, bool, error) {
+	source := content.OCISource{}
+	if secret := settings.SystemChartsOCISecret.Get(); secret != "" {
+		source.SecretNamespace, source.SecretName = kv.Split(secret, ":")
+		if source.SecretNamespace == "" || source.SecretName == "" {
+			return source, false, fmt.Errorf("setting %s must be namespace:name", settings.SystemChartsOCISecret.Name)
+		}
+	}
+
+	if references := settings.SystemChartsOCIReferences.Get(); references != "" {
+		refs := map[string]string{}
+		if err := json.Unmarshal([]byte(references), &refs); err != nil {
+			return source, false, fmt.Errorf("failed to parse setting %s: %w", settings.SystemChartsOCIReferences.Name, err)
+		}
+		if ref, ok := refs[name]; ok {
+			source.Reference = ref
+			return source, true, nil
+		}
+	}
+
+	if repository := settings.SystemChartsOCIRepository.Get(); repository != "" {
+		source.Reference = strings.TrimSuffix(repository, "/") + "/" + name
+		return source, true, nil
+	}
+
+	return source, false, nil
+}
+
+// resolveValues merges the values of the referenced Secret over the inline values. Errors only name the Secret, as
+// the values may hold credentials.
+func (m *Manager) resolveValues(values map[string]interface{}, valuesFrom *SecretValuesReference) (map[string]interface{}, error) {
+	if valuesFrom == nil {
+		return values, nil
+	}
+
+	key := valuesFrom.Key
+	if key == "" {
+		key = "values.yaml"
+	}
+	secret, err := m.secrets.Get(valuesFrom.Namespace, valuesFrom.Name)
+	if err != nil {
+		return nil, err
+	}
+	secretValues, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %s", valuesFrom.Namespace, valuesFrom.Name, key)
+	}
+	secretValuesJSON, err := yaml.YAMLToJSON(secretValues)
+	if err != nil {
+		return nil, fmt.Errorf("key %s of secret %s/%s does not hold valid values", key, valuesFrom.Namespace, valuesFrom.Name)
+	}
+
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	mergedJSON, err := jsonpatch.MergePatch(valuesJSON, secretValuesJSON)
+	if err != nil {
+		return nil, fmt.Errorf("key %s of secret %s/%s does not hold valid values", key, valuesFrom.Namespace, valuesFrom.Name)
+	}
+
+	merged := map[string]interface{}{}
+	if err := json.Unmarshal(mergedJSON, &merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
 }
 
 func (m *Manager) waitPodDone(op *catalog.Operation) error {
@@ -282,8 +596,9 @@ func podDone(chart string, newPod *corev1.Pod) (bool, error) {
 	return false, nil
 }
 
-// isInstalled returns whether the release is installed, if false, it will return the version and values.yaml it should install/upgrade
-func (m *Manager) isInstalled(namespace, name, version, minVersion string, desiredValue map[string]interface{}) (bool, string, map[string]interface{}, error) {
+// isInstalled returns whether the release is installed, if false, it will return the version and values.yaml it should install/upgrade.
+// If exact is true only a release at the given version is considered installed.
+func (m *Manager) isInstalled(namespace, name, version, minVersion string, exact bool, desiredValue map[string]interface{}) (bool, string, map[string]interface{}, error) {
 	helmcfg := &action.Configuration{}
 	if err := helmcfg.Init(m.restClientGetter, namespace, "", logrus.Infof); err != nil {
 		return false, "", nil, err
@@ -354,6 +669,13 @@ func (m *Manager) isInstalled(namespace, name, version, minVersion string, desir
 			}
 		}
 
+		if exact {
+			if desired.Equal(ver) && bytes.Equal(patchedJSON, actualValueJSON) {
+				return true, "", nil, nil
+			}
+			continue
+		}
+
 		if (desired.LessThan(ver) || desired.Equal(ver)) && bytes.Equal(patchedJSON, actualValueJSON) {
 			return true, "", nil, nil
 		}
@@ -362,7 +684,7 @@ func (m *Manager) isInstalled(namespace, name, version, minVersion string, desir
 	return false, version, desiredValue, nil
 }
 
-func (m *Manager) hasStatus(namespace, name string, stateMask action.ListStates) (bool, error) {
+func (m *Manager) releaseHasStatus(namespace, name string, stateMask action.ListStates) (bool, error) {
 	helmcfg := &action.Configuration{}
 	if err := helmcfg.Init(m.restClientGetter, namespace, "", logrus.Infof); err != nil {
 		return false, err
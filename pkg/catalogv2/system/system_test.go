@@ -0,0 +1,388 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/api/steve/catalog/types"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/content"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type fakeClusterRepoCache struct{}
+
+func (fakeClusterRepoCache) Get(name string) (*catalog.ClusterRepo, error) {
+	return nil, errors.New("repo unavailable")
+}
+
+func (fakeClusterRepoCache) List(selector labels.Selector) ([]*catalog.ClusterRepo, error) {
+	return nil, nil
+}
+
+func (fakeClusterRepoCache) AddIndexer(indexName string, indexer catalogcontrollers.ClusterRepoIndexer) {
+}
+
+func (fakeClusterRepoCache) GetByIndex(indexName, key string) ([]*catalog.ClusterRepo, error) {
+	return nil, nil
+}
+
+func TestInstallChartsLogsRequestedBy(t *testing.T) {
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(os.Stderr)
+
+	key := desiredKey{namespace: "cattle-system", name: "rancher-eks-operator"}
+	d := desired{key: key, requestedBy: "cluster/c-abc12"}
+	m := &Manager{
+		content:       content.NewManager(nil, nil, nil, fakeClusterRepoCache{}),
+		desiredCharts: map[desiredKey]desired{key: d},
+	}
+	m.installCharts(map[desiredKey]desired{
+		key: d,
+	}, true)
+
+	assert.Contains(t, buf.String(), "Failed to install system chart rancher-eks-operator")
+	assert.Contains(t, buf.String(), "requestedBy=cluster/c-abc12")
+}
+
+func TestUpgradeActionRecordsRequestedBy(t *testing.T) {
+	data, err := upgradeAction("cattle-system", "rancher-eks-operator", "100.0.0", nil, true, "cluster/c-abc12")
+	require.NoError(t, err)
+
+	var upgrade types.ChartUpgradeAction
+	require.NoError(t, json.Unmarshal(data, &upgrade))
+	require.Len(t, upgrade.Charts, 1)
+	assert.Equal(t, map[string]string{RequestedByAnnotation: "cluster/c-abc12"}, upgrade.Charts[0].Annotations)
+
+	data, err = upgradeAction("cattle-system", "rancher-eks-operator", "100.0.0", nil, true, "")
+	require.NoError(t, err)
+	upgrade = types.ChartUpgradeAction{}
+	require.NoError(t, json.Unmarshal(data, &upgrade))
+	assert.Empty(t, upgrade.Charts[0].Annotations)
+}
+
+type fakeSecretCache struct {
+	corecontrollers.SecretCache
+	secrets map[string]*corev1.Secret
+}
+
+func (f fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	if secret, ok := f.secrets[namespace+"/"+name]; ok {
+		return secret, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+func TestResolveValues(t *testing.T) {
+	m := &Manager{
+		secrets: fakeSecretCache{secrets: map[string]*corev1.Secret{
+			"cattle-system/chart-values": {
+				Data: map[string][]byte{
+					"values.yaml": []byte("credentials:\n  password: s3cr3t\nreplicas: 2\n"),
+					"invalid":     []byte("password: [s3cr3t"),
+				},
+			},
+		}},
+	}
+	inline := map[string]interface{}{
+		"replicas":    1,
+		"credentials": map[string]interface{}{"username": "admin"},
+	}
+
+	tests := []struct {
+		name       string
+		values     map[string]interface{}
+		valuesFrom *SecretValuesReference
+		want       map[string]interface{}
+		wantErr    bool
+	}{
+		{
+			name:   "inline only",
+			values: inline,
+			want:   inline,
+		},
+		{
+			name:       "secret only",
+			valuesFrom: &SecretValuesReference{Namespace: "cattle-system", Name: "chart-values"},
+			want: map[string]interface{}{
+				"replicas":    float64(2),
+				"credentials": map[string]interface{}{"password": "s3cr3t"},
+			},
+		},
+		{
+			name:       "merged",
+			values:     inline,
+			valuesFrom: &SecretValuesReference{Namespace: "cattle-system", Name: "chart-values", Key: "values.yaml"},
+			want: map[string]interface{}{
+				"replicas":    float64(2),
+				"credentials": map[string]interface{}{"username": "admin", "password": "s3cr3t"},
+			},
+		},
+		{
+			name:       "missing key",
+			valuesFrom: &SecretValuesReference{Namespace: "cattle-system", Name: "chart-values", Key: "other"},
+			wantErr:    true,
+		},
+		{
+			name:       "invalid values",
+			valuesFrom: &SecretValuesReference{Namespace: "cattle-system", Name: "chart-values", Key: "invalid"},
+			wantErr:    true,
+		},
+		{
+			name:       "missing secret",
+			valuesFrom: &SecretValuesReference{Namespace: "cattle-system", Name: "missing"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := m.resolveValues(tt.values, tt.valuesFrom)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.NotContains(t, err.Error(), "s3cr3t", "expected the error to not leak the values")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, values)
+		})
+	}
+}
+
+type fakeOperations struct {
+	operations
+	uninstalled []string
+}
+
+func (f *fakeOperations) Uninstall(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (*catalog.Operation, error) {
+	f.uninstalled = append(f.uninstalled, namespace+"/"+name)
+	op := &catalog.Operation{}
+	op.Status.PodNamespace = "cattle-system"
+	op.Status.PodName = "helm-operation-1"
+	return op, nil
+}
+
+type fakePodClient struct {
+	corecontrollers.PodClient
+}
+
+func (fakePodClient) Get(namespace, name string, options metav1.GetOptions) (*corev1.Pod, error) {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "helm", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}},
+			},
+		},
+	}, nil
+}
+
+func TestValuesSecretChange(t *testing.T) {
+	valuesFrom := &SecretValuesReference{Namespace: "cattle-system", Name: "chart-values"}
+	withSecret := desired{key: desiredKey{namespace: "cattle-system", name: "rancher-monitoring"}, valuesFrom: valuesFrom}
+	withoutSecret := desired{key: desiredKey{namespace: "cattle-system", name: "rancher-webhook"}}
+	m := &Manager{
+		valuesChanged: make(chan desired, 10),
+		desiredCharts: map[desiredKey]desired{
+			withSecret.key:    withSecret,
+			withoutSecret.key: withoutSecret,
+		},
+	}
+
+	_, err := m.onValuesSecretChange("cattle-system/other", &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-system", Name: "other"},
+	})
+	require.NoError(t, err)
+	_, err = m.onValuesSecretChange("cattle-system/chart-values", &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-system", Name: "chart-values"},
+	})
+	require.NoError(t, err)
+
+	select {
+	case changed := <-m.valuesChanged:
+		assert.Equal(t, withSecret.key, changed.key, "expected the chart reading its values from the secret to be installed again")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the chart reading its values from the secret to be installed again")
+	}
+	select {
+	case changed := <-m.valuesChanged:
+		t.Fatalf("expected only the chart reading its values from the secret to be installed again, got %s", changed.key.name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRemove(t *testing.T) {
+	ops := &fakeOperations{}
+	m := &Manager{
+		ctx:       context.Background(),
+		operation: ops,
+		pods:      fakePodClient{},
+		hasStatus: func(namespace, name string, stateMask action.ListStates) (bool, error) {
+			return true, nil
+		},
+		desiredCharts: map[desiredKey]desired{
+			{namespace: "cattle-system", name: "rancher-webhook"}:  {minVersion: "0.1.0"},
+			{namespace: "cattle-system", name: "rancher-operator"}: {},
+		},
+	}
+
+	require.NoError(t, m.Remove("cattle-system", "rancher-webhook"))
+	assert.Equal(t, []string{"cattle-system/rancher-webhook"}, ops.uninstalled)
+	assert.Equal(t, map[desiredKey]desired{
+		{namespace: "cattle-system", name: "rancher-operator"}: {},
+	}, m.desiredCharts)
+}
+
+func TestRemoveNotInstalled(t *testing.T) {
+	ops := &fakeOperations{}
+	m := &Manager{
+		operation: ops,
+		hasStatus: func(namespace, name string, stateMask action.ListStates) (bool, error) {
+			return false, nil
+		},
+		desiredCharts: map[desiredKey]desired{
+			{namespace: "cattle-system", name: "rancher-webhook"}: {},
+		},
+	}
+
+	require.NoError(t, m.Remove("cattle-system", "rancher-webhook"))
+	assert.Empty(t, ops.uninstalled)
+	assert.Empty(t, m.desiredCharts)
+}
+
+func TestInstallSkipsRemovedCharts(t *testing.T) {
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(os.Stderr)
+
+	ops := &fakeOperations{}
+	m := &Manager{
+		content:   content.NewManager(nil, nil, nil, fakeClusterRepoCache{}),
+		operation: ops,
+		sync:      make(chan desired, 10),
+		hasStatus: func(namespace, name string, stateMask action.ListStates) (bool, error) {
+			return false, nil
+		},
+		desiredCharts: map[desiredKey]desired{},
+	}
+
+	require.NoError(t, m.Ensure("cattle-system", "rancher-webhook", "0.1.0", nil, false, ""))
+	require.NoError(t, m.Remove("cattle-system", "rancher-webhook"))
+
+	var d desired
+	select {
+	case d = <-m.sync:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the install of the chart to be queued")
+	}
+	m.installCharts(map[desiredKey]desired{d.key: d}, d.forceAdopt)
+
+	assert.NotContains(t, buf.String(), "Failed to install", "expected the removed chart not to be installed")
+	assert.Empty(t, m.desiredCharts)
+}
+
+func TestSystemChartOCISource(t *testing.T) {
+	for _, setting := range []settings.Setting{settings.SystemChartsOCIRepository, settings.SystemChartsOCIReferences, settings.SystemChartsOCISecret} {
+		defer setting.Set(setting.Get())
+	}
+
+	tests := []struct {
+		name       string
+		repository string
+		references string
+		secret     string
+		want       content.OCISource
+		wantOCI    bool
+		wantErr    bool
+	}{
+		{
+			name: "rancher-charts repo by default",
+		},
+		{
+			name:       "global repository",
+			repository: "oci://registry.example.com/rancher/charts/",
+			secret:     "cattle-system:registry-credentials",
+			want: content.OCISource{
+				Reference:       "oci://registry.example.com/rancher/charts/rancher-webhook",
+				SecretNamespace: "cattle-system",
+				SecretName:      "registry-credentials",
+			},
+			wantOCI: true,
+		},
+		{
+			name:       "chart reference takes precedence",
+			repository: "oci://registry.example.com/rancher/charts",
+			references: `{"rancher-webhook": "oci://mirror.example.com/webhook@sha256:` + strings.Repeat("a", 64) + `"}`,
+			want: content.OCISource{
+				Reference: "oci://mirror.example.com/webhook@sha256:" + strings.Repeat("a", 64),
+			},
+			wantOCI: true,
+		},
+		{
+			name:       "references of other charts",
+			references: `{"fleet": "oci://mirror.example.com/fleet"}`,
+		},
+		{
+			name:       "invalid references",
+			references: `["oci://mirror.example.com/fleet"]`,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid secret",
+			repository: "oci://registry.example.com/rancher/charts",
+			secret:     "registry-credentials",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, settings.SystemChartsOCIRepository.Set(tt.repository))
+			require.NoError(t, settings.SystemChartsOCIReferences.Set(tt.references))
+			require.NoError(t, settings.SystemChartsOCISecret.Set(tt.secret))
+
+			source, ok, err := systemChartOCISource("rancher-webhook")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOCI, ok)
+			if tt.wantOCI {
+				assert.Equal(t, tt.want, source)
+			}
+		})
+	}
+}
+
+func TestResolvedOCISource(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("b", 64)
+	source := &ociSource{
+		OCISource: content.OCISource{Reference: "oci://registry.example.com/rancher-webhook@sha256:" + strings.Repeat("a", 64)},
+		Digest:    digest,
+	}
+	assert.Equal(t, "oci://registry.example.com/rancher-webhook@"+digest, source.resolved().Reference)
+
+	source.Digest = ""
+	assert.Equal(t, source.OCISource, source.resolved())
+}
@@ -0,0 +1,14 @@
+package client
+
+const (
+	AgentInstallScriptType              = "agentInstallScript"
+	AgentInstallScriptFieldConfigMapRef = "configMapRef"
+	AgentInstallScriptFieldSHA256       = "sha256"
+	AgentInstallScriptFieldURL          = "url"
+)
+
+type AgentInstallScript struct {
+	ConfigMapRef *ConfigMapKeySelector `json:"configMapRef,omitempty" yaml:"configMapRef,omitempty"`
+	SHA256       string                `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	URL          string                `json:"url,omitempty" yaml:"url,omitempty"`
+}
@@ -0,0 +1,16 @@
+package client
+
+const (
+	AgentNodeCommandCustomizationType              = "agentNodeCommandCustomization"
+	AgentNodeCommandCustomizationFieldCapAdd       = "capAdd"
+	AgentNodeCommandCustomizationFieldEnv          = "env"
+	AgentNodeCommandCustomizationFieldExtraFlags   = "extraFlags"
+	AgentNodeCommandCustomizationFieldVolumeMounts = "volumeMounts"
+)
+
+type AgentNodeCommandCustomization struct {
+	CapAdd       []string `json:"capAdd,omitempty" yaml:"capAdd,omitempty"`
+	Env          []EnvVar `json:"env,omitempty" yaml:"env,omitempty"`
+	ExtraFlags   []string `json:"extraFlags,omitempty" yaml:"extraFlags,omitempty"`
+	VolumeMounts []string `json:"volumeMounts,omitempty" yaml:"volumeMounts,omitempty"`
+}
@@ -13,6 +13,8 @@
 	ClusterFieldAgentFeatures                        = "agentFeatures"
 	ClusterFieldAgentImage                           = "agentImage"
 	ClusterFieldAgentImageOverride                   = "agentImageOverride"
+	ClusterFieldAgentInstallScript                   = "agentInstallScript"
+	ClusterFieldAgentNodeCommandCustomization        = "agentNodeCommandCustomization"
 	ClusterFieldAllocatable                          = "allocatable"
 	ClusterFieldAnnotations                          = "annotations"
 	ClusterFieldAppliedAgentEnvVars                  = "appliedAgentEnvVars"
@@ -45,6 +47,7 @@
 	ClusterFieldEnableClusterAlerting                = "enableClusterAlerting"
 	ClusterFieldEnableClusterMonitoring              = "enableClusterMonitoring"
 	ClusterFieldEnableNetworkPolicy                  = "enableNetworkPolicy"
+	ClusterFieldExecAuth                             = "execAuth"
 	ClusterFieldFailedSpec                           = "failedSpec"
 	ClusterFieldFleetWorkspaceName                   = "fleetWorkspaceName"
 	ClusterFieldGKEConfig                            = "gkeConfig"
@@ -58,6 +61,7 @@
 	ClusterFieldLocalClusterAuthEndpoint             = "localClusterAuthEndpoint"
 	ClusterFieldMonitoringStatus                     = "monitoringStatus"
 	ClusterFieldName                                 = "name"
+	ClusterFieldNodeMetadataPolicy                   = "nodeMetadataPolicy"
 	ClusterFieldNodeCount                            = "nodeCount"
 	ClusterFieldNodeVersion                          = "nodeVersion"
 	ClusterFieldOwnerReferences                      = "ownerReferences"
@@ -85,6 +89,8 @@ type Cluster struct {
 	AgentFeatures                        map[string]bool                `json:"agentFeatures,omitempty" yaml:"agentFeatures,omitempty"`
 	AgentImage                           string                         `json:"agentImage,omitempty" yaml:"agentImage,omitempty"`
 	AgentImageOverride                   string                         `json:"agentImageOverride,omitempty" yaml:"agentImageOverride,omitempty"`
+	AgentInstallScript                   *AgentInstallScript            `json:"agentInstallScript,omitempty" yaml:"agentInstallScript,omitempty"`
+	AgentNodeCommandCustomization        *AgentNodeCommandCustomization `json:"agentNodeCommandCustomization,omitempty" yaml:"agentNodeCommandCustomization,omitempty"`
 	Allocatable                          map[string]string              `json:"allocatable,omitempty" yaml:"allocatable,omitempty"`
 	Annotations                          map[string]string              `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 	AppliedAgentEnvVars                  []EnvVar                       `json:"appliedAgentEnvVars,omitempty" yaml:"appliedAgentEnvVars,omitempty"`
@@ -117,6 +123,7 @@ type Cluster struct {
 	EnableClusterAlerting                bool                           `json:"enableClusterAlerting,omitempty" yaml:"enableClusterAlerting,omitempty"`
 	EnableClusterMonitoring              bool                           `json:"enableClusterMonitoring,omitempty" yaml:"enableClusterMonitoring,omitempty"`
 	EnableNetworkPolicy                  *bool                          `json:"enableNetworkPolicy,omitempty" yaml:"enableNetworkPolicy,omitempty"`
+	ExecAuth                             *ClusterExecAuth               `json:"execAuth,omitempty" yaml:"execAuth,omitempty"`
 	FailedSpec                           *ClusterSpec                   `json:"failedSpec,omitempty" yaml:"failedSpec,omitempty"`
 	FleetWorkspaceName                   string                         `json:"fleetWorkspaceName,omitempty" yaml:"fleetWorkspaceName,omitempty"`
 	GKEConfig                            *GKEClusterConfigSpec          `json:"gkeConfig,omitempty" yaml:"gkeConfig,omitempty"`
@@ -130,6 +137,7 @@ type Cluster struct {
 	LocalClusterAuthEndpoint             *LocalClusterAuthEndpoint      `json:"localClusterAuthEndpoint,omitempty" yaml:"localClusterAuthEndpoint,omitempty"`
 	MonitoringStatus                     *MonitoringStatus              `json:"monitoringStatus,omitempty" yaml:"monitoringStatus,omitempty"`
 	Name                                 string                         `json:"name,omitempty" yaml:"name,omitempty"`
+	NodeMetadataPolicy                   *NodeMetadataPolicy            `json:"nodeMetadataPolicy,omitempty" yaml:"nodeMetadataPolicy,omitempty"`
 	NodeCount                            int64                          `json:"nodeCount,omitempty" yaml:"nodeCount,omitempty"`
 	NodeVersion                          int64                          `json:"nodeVersion,omitempty" yaml:"nodeVersion,omitempty"`
 	OwnerReferences                      []OwnerReference               `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
@@ -181,16 +189,24 @@ type ClusterOperations interface {
 
 	ActionImportYaml(resource *Cluster, input *ImportClusterYamlInput) (*ImportYamlOutput, error)
 
+	ActionNodeCommand(resource *Cluster, input *NodeCommandInput) (*NodeCommandOutput, error)
+
+	ActionResolvedImages(resource *Cluster) (*ResolvedImagesOutput, error)
+
 	ActionRestoreFromEtcdBackup(resource *Cluster, input *RestoreFromEtcdBackupInput) error
 
 	ActionRotateCertificates(resource *Cluster, input *RotateCertificateInput) (*RotateCertificateOutput, error)
 
 	ActionRotateEncryptionKey(resource *Cluster) (*RotateEncryptionKeyOutput, error)
 
+	ActionRotateServiceAccountToken(resource *Cluster) (*RotateServiceAccountTokenOutput, error)
+
 	ActionRunSecurityScan(resource *Cluster, input *CisScanConfig) error
 
 	ActionSaveAsTemplate(resource *Cluster, input *SaveAsTemplateInput) (*SaveAsTemplateOutput, error)
 
+	ActionSetMembers(resource *Cluster, input *SetMembersInput) (*SetMembersOutput, error)
+
 	ActionViewMonitoring(resource *Cluster) (*MonitoringOutput, error)
 }
 
@@ -301,6 +317,18 @@ func (c *ClusterClient) ActionImportYaml(resource *Cluster, input *ImportCluster
 	return resp, err
 }
 
+func (c *ClusterClient) ActionNodeCommand(resource *Cluster, input *NodeCommandInput) (*NodeCommandOutput, error) {
+	resp := &NodeCommandOutput{}
+	err := c.apiClient.Ops.DoAction(ClusterType, "nodeCommand", &resource.Resource, input, resp)
+	return resp, err
+}
+
+func (c *ClusterClient) ActionResolvedImages(resource *Cluster) (*ResolvedImagesOutput, error) {
+	resp := &ResolvedImagesOutput{}
+	err := c.apiClient.Ops.DoAction(ClusterType, "resolvedImages", &resource.Resource, nil, resp)
+	return resp, err
+}
+
 func (c *ClusterClient) ActionRestoreFromEtcdBackup(resource *Cluster, input *RestoreFromEtcdBackupInput) error {
 	err := c.apiClient.Ops.DoAction(ClusterType, "restoreFromEtcdBackup", &resource.Resource, input, nil)
 	return err
@@ -318,6 +346,12 @@ func (c *ClusterClient) ActionRotateEncryptionKey(resource *Cluster) (*RotateEnc
 	return resp, err
 }
 
+func (c *ClusterClient) ActionRotateServiceAccountToken(resource *Cluster) (*RotateServiceAccountTokenOutput, error) {
+	resp := &RotateServiceAccountTokenOutput{}
+	err := c.apiClient.Ops.DoAction(ClusterType, "rotateServiceAccountToken", &resource.Resource, nil, resp)
+	return resp, err
+}
+
 func (c *ClusterClient) ActionRunSecurityScan(resource *Cluster, input *CisScanConfig) error {
 	err := c.apiClient.Ops.DoAction(ClusterType, "runSecurityScan", &resource.Resource, input, nil)
 	return err
@@ -329,6 +363,12 @@ func (c *ClusterClient) ActionSaveAsTemplate(resource *Cluster, input *SaveAsTem
 	return resp, err
 }
 
+func (c *ClusterClient) ActionSetMembers(resource *Cluster, input *SetMembersInput) (*SetMembersOutput, error) {
+	resp := &SetMembersOutput{}
+	err := c.apiClient.Ops.DoAction(ClusterType, "setMembers", &resource.Resource, input, resp)
+	return resp, err
+}
+
 func (c *ClusterClient) ActionViewMonitoring(resource *Cluster) (*MonitoringOutput, error) {
 	resp := &MonitoringOutput{}
 	err := c.apiClient.Ops.DoAction(ClusterType, "viewMonitoring", &resource.Resource, nil, resp)
@@ -0,0 +1,16 @@
+package client
+
+const (
+	ClusterExecAuthType            = "clusterExecAuth"
+	ClusterExecAuthFieldAPIVersion = "apiVersion"
+	ClusterExecAuthFieldArgs       = "args"
+	ClusterExecAuthFieldCommand    = "command"
+	ClusterExecAuthFieldEnv        = "env"
+)
+
+type ClusterExecAuth struct {
+	APIVersion string                  `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Args       []string                `json:"args,omitempty" yaml:"args,omitempty"`
+	Command    string                  `json:"command,omitempty" yaml:"command,omitempty"`
+	Env        []ClusterExecAuthEnvVar `json:"env,omitempty" yaml:"env,omitempty"`
+}
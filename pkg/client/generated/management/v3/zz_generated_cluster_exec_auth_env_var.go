@@ -0,0 +1,14 @@
+package client
+
+const (
+	ClusterExecAuthEnvVarType            = "clusterExecAuthEnvVar"
+	ClusterExecAuthEnvVarFieldKey        = "key"
+	ClusterExecAuthEnvVarFieldName       = "name"
+	ClusterExecAuthEnvVarFieldSecretName = "secretName"
+)
+
+type ClusterExecAuthEnvVar struct {
+	Key        string `json:"key,omitempty" yaml:"key,omitempty"`
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	SecretName string `json:"secretName,omitempty" yaml:"secretName,omitempty"`
+}
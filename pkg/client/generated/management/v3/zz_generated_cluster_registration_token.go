@@ -5,51 +5,69 @@
 )
 
 const (
-	ClusterRegistrationTokenType                      = "clusterRegistrationToken"
-	ClusterRegistrationTokenFieldAnnotations          = "annotations"
-	ClusterRegistrationTokenFieldClusterID            = "clusterId"
-	ClusterRegistrationTokenFieldCommand              = "command"
-	ClusterRegistrationTokenFieldCreated              = "created"
-	ClusterRegistrationTokenFieldCreatorID            = "creatorId"
-	ClusterRegistrationTokenFieldInsecureCommand      = "insecureCommand"
-	ClusterRegistrationTokenFieldInsecureNodeCommand  = "insecureNodeCommand"
-	ClusterRegistrationTokenFieldLabels               = "labels"
-	ClusterRegistrationTokenFieldManifestURL          = "manifestUrl"
-	ClusterRegistrationTokenFieldName                 = "name"
-	ClusterRegistrationTokenFieldNamespaceId          = "namespaceId"
-	ClusterRegistrationTokenFieldNodeCommand          = "nodeCommand"
-	ClusterRegistrationTokenFieldOwnerReferences      = "ownerReferences"
-	ClusterRegistrationTokenFieldRemoved              = "removed"
-	ClusterRegistrationTokenFieldState                = "state"
-	ClusterRegistrationTokenFieldToken                = "token"
-	ClusterRegistrationTokenFieldTransitioning        = "transitioning"
-	ClusterRegistrationTokenFieldTransitioningMessage = "transitioningMessage"
-	ClusterRegistrationTokenFieldUUID                 = "uuid"
-	ClusterRegistrationTokenFieldWindowsNodeCommand   = "windowsNodeCommand"
+	ClusterRegistrationTokenType                        = "clusterRegistrationToken"
+	ClusterRegistrationTokenFieldAnnotations            = "annotations"
+	ClusterRegistrationTokenFieldClusterID              = "clusterId"
+	ClusterRegistrationTokenFieldCommand                = "command"
+	ClusterRegistrationTokenFieldCreated                = "created"
+	ClusterRegistrationTokenFieldCreatorID              = "creatorId"
+	ClusterRegistrationTokenFieldInsecureCommand        = "insecureCommand"
+	ClusterRegistrationTokenFieldInsecureNodeCommand    = "insecureNodeCommand"
+	ClusterRegistrationTokenFieldLabels                 = "labels"
+	ClusterRegistrationTokenFieldManifestFetchCount     = "manifestFetchCount"
+	ClusterRegistrationTokenFieldManifestFirstFetchedAt = "manifestFirstFetchedAt"
+	ClusterRegistrationTokenFieldManifestLastFetchedAt  = "manifestLastFetchedAt"
+	ClusterRegistrationTokenFieldManifestURL            = "manifestUrl"
+	ClusterRegistrationTokenFieldName                   = "name"
+	ClusterRegistrationTokenFieldNamespaceId            = "namespaceId"
+	ClusterRegistrationTokenFieldNextToken              = "nextToken"
+	ClusterRegistrationTokenFieldNextTokenIssuedAt      = "nextTokenIssuedAt"
+	ClusterRegistrationTokenFieldNodeCommand            = "nodeCommand"
+	ClusterRegistrationTokenFieldOwnerReferences        = "ownerReferences"
+	ClusterRegistrationTokenFieldPreviousToken          = "previousToken"
+	ClusterRegistrationTokenFieldPreviousTokenExpiresAt = "previousTokenExpiresAt"
+	ClusterRegistrationTokenFieldRemoved                = "removed"
+	ClusterRegistrationTokenFieldSingleUseManifest      = "singleUseManifest"
+	ClusterRegistrationTokenFieldState                  = "state"
+	ClusterRegistrationTokenFieldToken                  = "token"
+	ClusterRegistrationTokenFieldTokenIssuedAt          = "tokenIssuedAt"
+	ClusterRegistrationTokenFieldTransitioning          = "transitioning"
+	ClusterRegistrationTokenFieldTransitioningMessage   = "transitioningMessage"
+	ClusterRegistrationTokenFieldUUID                   = "uuid"
+	ClusterRegistrationTokenFieldWindowsNodeCommand     = "windowsNodeCommand"
 )
 
 type ClusterRegistrationToken struct {
 	types.Resource
-	Annotations          map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
-	ClusterID            string            `json:"clusterId,omitempty" yaml:"clusterId,omitempty"`
-	Command              string            `json:"command,omitempty" yaml:"command,omitempty"`
-	Created              string            `json:"created,omitempty" yaml:"created,omitempty"`
-	CreatorID            string            `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
-	InsecureCommand      string            `json:"insecureCommand,omitempty" yaml:"insecureCommand,omitempty"`
-	InsecureNodeCommand  string            `json:"insecureNodeCommand,omitempty" yaml:"insecureNodeCommand,omitempty"`
-	Labels               map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
-	ManifestURL          string            `json:"manifestUrl,omitempty" yaml:"manifestUrl,omitempty"`
-	Name                 string            `json:"name,omitempty" yaml:"name,omitempty"`
-	NamespaceId          string            `json:"namespaceId,omitempty" yaml:"namespaceId,omitempty"`
-	NodeCommand          string            `json:"nodeCommand,omitempty" yaml:"nodeCommand,omitempty"`
-	OwnerReferences      []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
-	Removed              string            `json:"removed,omitempty" yaml:"removed,omitempty"`
-	State                string            `json:"state,omitempty" yaml:"state,omitempty"`
-	Token                string            `json:"token,omitempty" yaml:"token,omitempty"`
-	Transitioning        string            `json:"transitioning,omitempty" yaml:"transitioning,omitempty"`
-	TransitioningMessage string            `json:"transitioningMessage,omitempty" yaml:"transitioningMessage,omitempty"`
-	UUID                 string            `json:"uuid,omitempty" yaml:"uuid,omitempty"`
-	WindowsNodeCommand   string            `json:"windowsNodeCommand,omitempty" yaml:"windowsNodeCommand,omitempty"`
+	Annotations            map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	ClusterID              string            `json:"clusterId,omitempty" yaml:"clusterId,omitempty"`
+	Command                string            `json:"command,omitempty" yaml:"command,omitempty"`
+	Created                string            `json:"created,omitempty" yaml:"created,omitempty"`
+	CreatorID              string            `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
+	InsecureCommand        string            `json:"insecureCommand,omitempty" yaml:"insecureCommand,omitempty"`
+	InsecureNodeCommand    string            `json:"insecureNodeCommand,omitempty" yaml:"insecureNodeCommand,omitempty"`
+	Labels                 map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	ManifestFetchCount     int64             `json:"manifestFetchCount,omitempty" yaml:"manifestFetchCount,omitempty"`
+	ManifestFirstFetchedAt string            `json:"manifestFirstFetchedAt,omitempty" yaml:"manifestFirstFetchedAt,omitempty"`
+	ManifestLastFetchedAt  string            `json:"manifestLastFetchedAt,omitempty" yaml:"manifestLastFetchedAt,omitempty"`
+	ManifestURL            string            `json:"manifestUrl,omitempty" yaml:"manifestUrl,omitempty"`
+	Name                   string            `json:"name,omitempty" yaml:"name,omitempty"`
+	NamespaceId            string            `json:"namespaceId,omitempty" yaml:"namespaceId,omitempty"`
+	NextToken              string            `json:"nextToken,omitempty" yaml:"nextToken,omitempty"`
+	NextTokenIssuedAt      string            `json:"nextTokenIssuedAt,omitempty" yaml:"nextTokenIssuedAt,omitempty"`
+	NodeCommand            string            `json:"nodeCommand,omitempty" yaml:"nodeCommand,omitempty"`
+	OwnerReferences        []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+	PreviousToken          string            `json:"previousToken,omitempty" yaml:"previousToken,omitempty"`
+	PreviousTokenExpiresAt string            `json:"previousTokenExpiresAt,omitempty" yaml:"previousTokenExpiresAt,omitempty"`
+	Removed                string            `json:"removed,omitempty" yaml:"removed,omitempty"`
+	SingleUseManifest      bool              `json:"singleUseManifest,omitempty" yaml:"singleUseManifest,omitempty"`
+	State                  string            `json:"state,omitempty" yaml:"state,omitempty"`
+	Token                  string            `json:"token,omitempty" yaml:"token,omitempty"`
+	TokenIssuedAt          string            `json:"tokenIssuedAt,omitempty" yaml:"tokenIssuedAt,omitempty"`
+	Transitioning          string            `json:"transitioning,omitempty" yaml:"transitioning,omitempty"`
+	TransitioningMessage   string            `json:"transitioningMessage,omitempty" yaml:"transitioningMessage,omitempty"`
+	UUID                   string            `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	WindowsNodeCommand     string            `json:"windowsNodeCommand,omitempty" yaml:"windowsNodeCommand,omitempty"`
 }
 
 type ClusterRegistrationTokenCollection struct {
@@ -70,6 +88,8 @@ type ClusterRegistrationTokenOperations interface {
 	Replace(existing *ClusterRegistrationToken) (*ClusterRegistrationToken, error)
 	ByID(id string) (*ClusterRegistrationToken, error)
 	Delete(container *ClusterRegistrationToken) error
+
+	ActionRegenerateManifest(resource *ClusterRegistrationToken) error
 }
 
 func newClusterRegistrationTokenClient(apiClient *Client) *ClusterRegistrationTokenClient {
@@ -140,3 +160,8 @@ func (c *ClusterRegistrationTokenClient) ByID(id string) (*ClusterRegistrationTo
 func (c *ClusterRegistrationTokenClient) Delete(container *ClusterRegistrationToken) error {
 	return c.apiClient.Ops.DoResourceDelete(ClusterRegistrationTokenType, &container.Resource)
 }
+
+func (c *ClusterRegistrationTokenClient) ActionRegenerateManifest(resource *ClusterRegistrationToken) error {
+	err := c.apiClient.Ops.DoAction(ClusterRegistrationTokenType, "regenerateManifest", &resource.Resource, nil, nil)
+	return err
+}
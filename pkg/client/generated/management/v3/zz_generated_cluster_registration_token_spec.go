@@ -1,10 +1,12 @@
 package client
 
 const (
-	ClusterRegistrationTokenSpecType           = "clusterRegistrationTokenSpec"
-	ClusterRegistrationTokenSpecFieldClusterID = "clusterId"
+	ClusterRegistrationTokenSpecType                   = "clusterRegistrationTokenSpec"
+	ClusterRegistrationTokenSpecFieldClusterID         = "clusterId"
+	ClusterRegistrationTokenSpecFieldSingleUseManifest = "singleUseManifest"
 )
 
 type ClusterRegistrationTokenSpec struct {
-	ClusterID string `json:"clusterId,omitempty" yaml:"clusterId,omitempty"`
+	ClusterID         string `json:"clusterId,omitempty" yaml:"clusterId,omitempty"`
+	SingleUseManifest bool   `json:"singleUseManifest,omitempty" yaml:"singleUseManifest,omitempty"`
 }
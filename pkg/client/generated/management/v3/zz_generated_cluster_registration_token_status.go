@@ -1,22 +1,38 @@
 package client
 
 const (
-	ClusterRegistrationTokenStatusType                     = "clusterRegistrationTokenStatus"
-	ClusterRegistrationTokenStatusFieldCommand             = "command"
-	ClusterRegistrationTokenStatusFieldInsecureCommand     = "insecureCommand"
-	ClusterRegistrationTokenStatusFieldInsecureNodeCommand = "insecureNodeCommand"
-	ClusterRegistrationTokenStatusFieldManifestURL         = "manifestUrl"
-	ClusterRegistrationTokenStatusFieldNodeCommand         = "nodeCommand"
-	ClusterRegistrationTokenStatusFieldToken               = "token"
-	ClusterRegistrationTokenStatusFieldWindowsNodeCommand  = "windowsNodeCommand"
+	ClusterRegistrationTokenStatusType                        = "clusterRegistrationTokenStatus"
+	ClusterRegistrationTokenStatusFieldCommand                = "command"
+	ClusterRegistrationTokenStatusFieldInsecureCommand        = "insecureCommand"
+	ClusterRegistrationTokenStatusFieldInsecureNodeCommand    = "insecureNodeCommand"
+	ClusterRegistrationTokenStatusFieldManifestFetchCount     = "manifestFetchCount"
+	ClusterRegistrationTokenStatusFieldManifestFirstFetchedAt = "manifestFirstFetchedAt"
+	ClusterRegistrationTokenStatusFieldManifestLastFetchedAt  = "manifestLastFetchedAt"
+	ClusterRegistrationTokenStatusFieldManifestURL            = "manifestUrl"
+	ClusterRegistrationTokenStatusFieldNextToken              = "nextToken"
+	ClusterRegistrationTokenStatusFieldNextTokenIssuedAt      = "nextTokenIssuedAt"
+	ClusterRegistrationTokenStatusFieldNodeCommand            = "nodeCommand"
+	ClusterRegistrationTokenStatusFieldPreviousToken          = "previousToken"
+	ClusterRegistrationTokenStatusFieldPreviousTokenExpiresAt = "previousTokenExpiresAt"
+	ClusterRegistrationTokenStatusFieldToken                  = "token"
+	ClusterRegistrationTokenStatusFieldTokenIssuedAt          = "tokenIssuedAt"
+	ClusterRegistrationTokenStatusFieldWindowsNodeCommand     = "windowsNodeCommand"
 )
 
 type ClusterRegistrationTokenStatus struct {
-	Command             string `json:"command,omitempty" yaml:"command,omitempty"`
-	InsecureCommand     string `json:"insecureCommand,omitempty" yaml:"insecureCommand,omitempty"`
-	InsecureNodeCommand string `json:"insecureNodeCommand,omitempty" yaml:"insecureNodeCommand,omitempty"`
-	ManifestURL         string `json:"manifestUrl,omitempty" yaml:"manifestUrl,omitempty"`
-	NodeCommand         string `json:"nodeCommand,omitempty" yaml:"nodeCommand,omitempty"`
-	Token               string `json:"token,omitempty" yaml:"token,omitempty"`
-	WindowsNodeCommand  string `json:"windowsNodeCommand,omitempty" yaml:"windowsNodeCommand,omitempty"`
+	Command                string `json:"command,omitempty" yaml:"command,omitempty"`
+	InsecureCommand        string `json:"insecureCommand,omitempty" yaml:"insecureCommand,omitempty"`
+	InsecureNodeCommand    string `json:"insecureNodeCommand,omitempty" yaml:"insecureNodeCommand,omitempty"`
+	ManifestFetchCount     int64  `json:"manifestFetchCount,omitempty" yaml:"manifestFetchCount,omitempty"`
+	ManifestFirstFetchedAt string `json:"manifestFirstFetchedAt,omitempty" yaml:"manifestFirstFetchedAt,omitempty"`
+	ManifestLastFetchedAt  string `json:"manifestLastFetchedAt,omitempty" yaml:"manifestLastFetchedAt,omitempty"`
+	ManifestURL            string `json:"manifestUrl,omitempty" yaml:"manifestUrl,omitempty"`
+	NextToken              string `json:"nextToken,omitempty" yaml:"nextToken,omitempty"`
+	NextTokenIssuedAt      string `json:"nextTokenIssuedAt,omitempty" yaml:"nextTokenIssuedAt,omitempty"`
+	NodeCommand            string `json:"nodeCommand,omitempty" yaml:"nodeCommand,omitempty"`
+	PreviousToken          string `json:"previousToken,omitempty" yaml:"previousToken,omitempty"`
+	PreviousTokenExpiresAt string `json:"previousTokenExpiresAt,omitempty" yaml:"previousTokenExpiresAt,omitempty"`
+	Token                  string `json:"token,omitempty" yaml:"token,omitempty"`
+	TokenIssuedAt          string `json:"tokenIssuedAt,omitempty" yaml:"tokenIssuedAt,omitempty"`
+	WindowsNodeCommand     string `json:"windowsNodeCommand,omitempty" yaml:"windowsNodeCommand,omitempty"`
 }
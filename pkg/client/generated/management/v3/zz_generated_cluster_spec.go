@@ -5,6 +5,8 @@
 	ClusterSpecFieldAKSConfig                           = "aksConfig"
 	ClusterSpecFieldAgentEnvVars                        = "agentEnvVars"
 	ClusterSpecFieldAgentImageOverride                  = "agentImageOverride"
+	ClusterSpecFieldAgentInstallScript                  = "agentInstallScript"
+	ClusterSpecFieldAgentNodeCommandCustomization       = "agentNodeCommandCustomization"
 	ClusterSpecFieldAmazonElasticContainerServiceConfig = "amazonElasticContainerServiceConfig"
 	ClusterSpecFieldAzureKubernetesServiceConfig        = "azureKubernetesServiceConfig"
 	ClusterSpecFieldClusterTemplateAnswers              = "answers"
@@ -22,6 +24,7 @@
 	ClusterSpecFieldEnableClusterAlerting               = "enableClusterAlerting"
 	ClusterSpecFieldEnableClusterMonitoring             = "enableClusterMonitoring"
 	ClusterSpecFieldEnableNetworkPolicy                 = "enableNetworkPolicy"
+	ClusterSpecFieldExecAuth                            = "execAuth"
 	ClusterSpecFieldFleetWorkspaceName                  = "fleetWorkspaceName"
 	ClusterSpecFieldGKEConfig                           = "gkeConfig"
 	ClusterSpecFieldGenericEngineConfig                 = "genericEngineConfig"
@@ -30,6 +33,7 @@
 	ClusterSpecFieldInternal                            = "internal"
 	ClusterSpecFieldK3sConfig                           = "k3sConfig"
 	ClusterSpecFieldLocalClusterAuthEndpoint            = "localClusterAuthEndpoint"
+	ClusterSpecFieldNodeMetadataPolicy                  = "nodeMetadataPolicy"
 	ClusterSpecFieldRancherKubernetesEngineConfig       = "rancherKubernetesEngineConfig"
 	ClusterSpecFieldRke2Config                          = "rke2Config"
 	ClusterSpecFieldScheduledClusterScan                = "scheduledClusterScan"
@@ -40,6 +44,8 @@ type ClusterSpec struct {
 	AKSConfig                           *AKSClusterConfigSpec          `json:"aksConfig,omitempty" yaml:"aksConfig,omitempty"`
 	AgentEnvVars                        []EnvVar                       `json:"agentEnvVars,omitempty" yaml:"agentEnvVars,omitempty"`
 	AgentImageOverride                  string                         `json:"agentImageOverride,omitempty" yaml:"agentImageOverride,omitempty"`
+	AgentInstallScript                  *AgentInstallScript            `json:"agentInstallScript,omitempty" yaml:"agentInstallScript,omitempty"`
+	AgentNodeCommandCustomization       *AgentNodeCommandCustomization `json:"agentNodeCommandCustomization,omitempty" yaml:"agentNodeCommandCustomization,omitempty"`
 	AmazonElasticContainerServiceConfig map[string]interface{}         `json:"amazonElasticContainerServiceConfig,omitempty" yaml:"amazonElasticContainerServiceConfig,omitempty"`
 	AzureKubernetesServiceConfig        map[string]interface{}         `json:"azureKubernetesServiceConfig,omitempty" yaml:"azureKubernetesServiceConfig,omitempty"`
 	ClusterTemplateAnswers              *Answer                        `json:"answers,omitempty" yaml:"answers,omitempty"`
@@ -57,6 +63,7 @@ type ClusterSpec struct {
 	EnableClusterAlerting               bool                           `json:"enableClusterAlerting,omitempty" yaml:"enableClusterAlerting,omitempty"`
 	EnableClusterMonitoring             bool                           `json:"enableClusterMonitoring,omitempty" yaml:"enableClusterMonitoring,omitempty"`
 	EnableNetworkPolicy                 *bool                          `json:"enableNetworkPolicy,omitempty" yaml:"enableNetworkPolicy,omitempty"`
+	ExecAuth                            *ClusterExecAuth               `json:"execAuth,omitempty" yaml:"execAuth,omitempty"`
 	FleetWorkspaceName                  string                         `json:"fleetWorkspaceName,omitempty" yaml:"fleetWorkspaceName,omitempty"`
 	GKEConfig                           *GKEClusterConfigSpec          `json:"gkeConfig,omitempty" yaml:"gkeConfig,omitempty"`
 	GenericEngineConfig                 map[string]interface{}         `json:"genericEngineConfig,omitempty" yaml:"genericEngineConfig,omitempty"`
@@ -65,6 +72,7 @@ type ClusterSpec struct {
 	Internal                            bool                           `json:"internal,omitempty" yaml:"internal,omitempty"`
 	K3sConfig                           *K3sConfig                     `json:"k3sConfig,omitempty" yaml:"k3sConfig,omitempty"`
 	LocalClusterAuthEndpoint            *LocalClusterAuthEndpoint      `json:"localClusterAuthEndpoint,omitempty" yaml:"localClusterAuthEndpoint,omitempty"`
+	NodeMetadataPolicy                  *NodeMetadataPolicy            `json:"nodeMetadataPolicy,omitempty" yaml:"nodeMetadataPolicy,omitempty"`
 	RancherKubernetesEngineConfig       *RancherKubernetesEngineConfig `json:"rancherKubernetesEngineConfig,omitempty" yaml:"rancherKubernetesEngineConfig,omitempty"`
 	Rke2Config                          *Rke2Config                    `json:"rke2Config,omitempty" yaml:"rke2Config,omitempty"`
 	ScheduledClusterScan                *ScheduledClusterScan          `json:"scheduledClusterScan,omitempty" yaml:"scheduledClusterScan,omitempty"`
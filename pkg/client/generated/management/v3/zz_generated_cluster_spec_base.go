@@ -4,6 +4,8 @@
 	ClusterSpecBaseType                                     = "clusterSpecBase"
 	ClusterSpecBaseFieldAgentEnvVars                        = "agentEnvVars"
 	ClusterSpecBaseFieldAgentImageOverride                  = "agentImageOverride"
+	ClusterSpecBaseFieldAgentInstallScript                  = "agentInstallScript"
+	ClusterSpecBaseFieldAgentNodeCommandCustomization       = "agentNodeCommandCustomization"
 	ClusterSpecBaseFieldDefaultClusterRoleForProjectMembers = "defaultClusterRoleForProjectMembers"
 	ClusterSpecBaseFieldDefaultPodSecurityPolicyTemplateID  = "defaultPodSecurityPolicyTemplateId"
 	ClusterSpecBaseFieldDesiredAgentImage                   = "desiredAgentImage"
@@ -12,7 +14,9 @@
 	ClusterSpecBaseFieldEnableClusterAlerting               = "enableClusterAlerting"
 	ClusterSpecBaseFieldEnableClusterMonitoring             = "enableClusterMonitoring"
 	ClusterSpecBaseFieldEnableNetworkPolicy                 = "enableNetworkPolicy"
+	ClusterSpecBaseFieldExecAuth                            = "execAuth"
 	ClusterSpecBaseFieldLocalClusterAuthEndpoint            = "localClusterAuthEndpoint"
+	ClusterSpecBaseFieldNodeMetadataPolicy                  = "nodeMetadataPolicy"
 	ClusterSpecBaseFieldRancherKubernetesEngineConfig       = "rancherKubernetesEngineConfig"
 	ClusterSpecBaseFieldScheduledClusterScan                = "scheduledClusterScan"
 	ClusterSpecBaseFieldWindowsPreferedCluster              = "windowsPreferedCluster"
@@ -21,6 +25,8 @@
 type ClusterSpecBase struct {
 	AgentEnvVars                        []EnvVar                       `json:"agentEnvVars,omitempty" yaml:"agentEnvVars,omitempty"`
 	AgentImageOverride                  string                         `json:"agentImageOverride,omitempty" yaml:"agentImageOverride,omitempty"`
+	AgentInstallScript                  *AgentInstallScript            `json:"agentInstallScript,omitempty" yaml:"agentInstallScript,omitempty"`
+	AgentNodeCommandCustomization       *AgentNodeCommandCustomization `json:"agentNodeCommandCustomization,omitempty" yaml:"agentNodeCommandCustomization,omitempty"`
 	DefaultClusterRoleForProjectMembers string                         `json:"defaultClusterRoleForProjectMembers,omitempty" yaml:"defaultClusterRoleForProjectMembers,omitempty"`
 	DefaultPodSecurityPolicyTemplateID  string                         `json:"defaultPodSecurityPolicyTemplateId,omitempty" yaml:"defaultPodSecurityPolicyTemplateId,omitempty"`
 	DesiredAgentImage                   string                         `json:"desiredAgentImage,omitempty" yaml:"desiredAgentImage,omitempty"`
@@ -29,7 +35,9 @@ type ClusterSpecBase struct {
 	EnableClusterAlerting               bool                           `json:"enableClusterAlerting,omitempty" yaml:"enableClusterAlerting,omitempty"`
 	EnableClusterMonitoring             bool                           `json:"enableClusterMonitoring,omitempty" yaml:"enableClusterMonitoring,omitempty"`
 	EnableNetworkPolicy                 *bool                          `json:"enableNetworkPolicy,omitempty" yaml:"enableNetworkPolicy,omitempty"`
+	ExecAuth                            *ClusterExecAuth               `json:"execAuth,omitempty" yaml:"execAuth,omitempty"`
 	LocalClusterAuthEndpoint            *LocalClusterAuthEndpoint      `json:"localClusterAuthEndpoint,omitempty" yaml:"localClusterAuthEndpoint,omitempty"`
+	NodeMetadataPolicy                  *NodeMetadataPolicy            `json:"nodeMetadataPolicy,omitempty" yaml:"nodeMetadataPolicy,omitempty"`
 	RancherKubernetesEngineConfig       *RancherKubernetesEngineConfig `json:"rancherKubernetesEngineConfig,omitempty" yaml:"rancherKubernetesEngineConfig,omitempty"`
 	ScheduledClusterScan                *ScheduledClusterScan          `json:"scheduledClusterScan,omitempty" yaml:"scheduledClusterScan,omitempty"`
 	WindowsPreferedCluster              bool                           `json:"windowsPreferedCluster,omitempty" yaml:"windowsPreferedCluster,omitempty"`
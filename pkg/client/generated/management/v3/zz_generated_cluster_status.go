@@ -6,6 +6,8 @@
 	ClusterStatusFieldAPIEndpoint                          = "apiEndpoint"
 	ClusterStatusFieldAgentFeatures                        = "agentFeatures"
 	ClusterStatusFieldAgentImage                           = "agentImage"
+	ClusterStatusFieldAgentLastSeen                        = "agentLastSeen"
+	ClusterStatusFieldAgentVersion                         = "agentVersion"
 	ClusterStatusFieldAllocatable                          = "allocatable"
 	ClusterStatusFieldAppliedAgentEnvVars                  = "appliedAgentEnvVars"
 	ClusterStatusFieldAppliedEnableNetworkPolicy           = "appliedEnableNetworkPolicy"
@@ -39,6 +41,8 @@ type ClusterStatus struct {
 	APIEndpoint                          string                      `json:"apiEndpoint,omitempty" yaml:"apiEndpoint,omitempty"`
 	AgentFeatures                        map[string]bool             `json:"agentFeatures,omitempty" yaml:"agentFeatures,omitempty"`
 	AgentImage                           string                      `json:"agentImage,omitempty" yaml:"agentImage,omitempty"`
+	AgentLastSeen                        string                      `json:"agentLastSeen,omitempty" yaml:"agentLastSeen,omitempty"`
+	AgentVersion                         string                      `json:"agentVersion,omitempty" yaml:"agentVersion,omitempty"`
 	Allocatable                          map[string]string           `json:"allocatable,omitempty" yaml:"allocatable,omitempty"`
 	AppliedAgentEnvVars                  []EnvVar                    `json:"appliedAgentEnvVars,omitempty" yaml:"appliedAgentEnvVars,omitempty"`
 	AppliedEnableNetworkPolicy           bool                        `json:"appliedEnableNetworkPolicy,omitempty" yaml:"appliedEnableNetworkPolicy,omitempty"`
@@ -12,10 +12,12 @@
 	ComposeConfigFieldLabels               = "labels"
 	ComposeConfigFieldName                 = "name"
 	ComposeConfigFieldOwnerReferences      = "ownerReferences"
+	ComposeConfigFieldPrune                = "prune"
 	ComposeConfigFieldRancherCompose       = "rancherCompose"
 	ComposeConfigFieldRemoved              = "removed"
 	ComposeConfigFieldState                = "state"
 	ComposeConfigFieldStatus               = "status"
+	ComposeConfigFieldTeardownOnDelete     = "teardownOnDelete"
 	ComposeConfigFieldTransitioning        = "transitioning"
 	ComposeConfigFieldTransitioningMessage = "transitioningMessage"
 	ComposeConfigFieldUUID                 = "uuid"
@@ -29,10 +31,12 @@ type ComposeConfig struct {
 	Labels               map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Name                 string            `json:"name,omitempty" yaml:"name,omitempty"`
 	OwnerReferences      []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+	Prune                bool              `json:"prune,omitempty" yaml:"prune,omitempty"`
 	RancherCompose       string            `json:"rancherCompose,omitempty" yaml:"rancherCompose,omitempty"`
 	Removed              string            `json:"removed,omitempty" yaml:"removed,omitempty"`
 	State                string            `json:"state,omitempty" yaml:"state,omitempty"`
 	Status               *ComposeStatus    `json:"status,omitempty" yaml:"status,omitempty"`
+	TeardownOnDelete     bool              `json:"teardownOnDelete,omitempty" yaml:"teardownOnDelete,omitempty"`
 	Transitioning        string            `json:"transitioning,omitempty" yaml:"transitioning,omitempty"`
 	TransitioningMessage string            `json:"transitioningMessage,omitempty" yaml:"transitioningMessage,omitempty"`
 	UUID                 string            `json:"uuid,omitempty" yaml:"uuid,omitempty"`
@@ -1,10 +1,14 @@
 package client
 
 const (
-	ComposeSpecType                = "composeSpec"
-	ComposeSpecFieldRancherCompose = "rancherCompose"
+	ComposeSpecType                  = "composeSpec"
+	ComposeSpecFieldPrune            = "prune"
+	ComposeSpecFieldRancherCompose   = "rancherCompose"
+	ComposeSpecFieldTeardownOnDelete = "teardownOnDelete"
 )
 
 type ComposeSpec struct {
-	RancherCompose string `json:"rancherCompose,omitempty" yaml:"rancherCompose,omitempty"`
+	Prune            bool   `json:"prune,omitempty" yaml:"prune,omitempty"`
+	RancherCompose   string `json:"rancherCompose,omitempty" yaml:"rancherCompose,omitempty"`
+	TeardownOnDelete bool   `json:"teardownOnDelete,omitempty" yaml:"teardownOnDelete,omitempty"`
 }
@@ -1,10 +1,20 @@
 package client
 
 const (
-	ComposeStatusType            = "composeStatus"
-	ComposeStatusFieldConditions = "conditions"
+	ComposeStatusType                   = "composeStatus"
+	ComposeStatusFieldAppliedChecksum   = "appliedChecksum"
+	ComposeStatusFieldConditions        = "conditions"
+	ComposeStatusFieldCreated           = "created"
+	ComposeStatusFieldResources         = "resources"
+	ComposeStatusFieldValidatedChecksum = "validatedChecksum"
+	ComposeStatusFieldValidationErrors  = "validationErrors"
 )
 
 type ComposeStatus struct {
-	Conditions []ComposeCondition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	AppliedChecksum   string                       `json:"appliedChecksum,omitempty" yaml:"appliedChecksum,omitempty"`
+	Conditions        []ComposeCondition           `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	Created           map[string]map[string]string `json:"created,omitempty" yaml:"created,omitempty"`
+	Resources         map[string]map[string]string `json:"resources,omitempty" yaml:"resources,omitempty"`
+	ValidatedChecksum string                       `json:"validatedChecksum,omitempty" yaml:"validatedChecksum,omitempty"`
+	ValidationErrors  []string                     `json:"validationErrors,omitempty" yaml:"validationErrors,omitempty"`
 }
@@ -33,6 +33,7 @@
 	NodeFieldNodePoolID           = "nodePoolId"
 	NodeFieldNodeTaints           = "nodeTaints"
 	NodeFieldNodeTemplateID       = "nodeTemplateId"
+	NodeFieldNormalizedHostname   = "normalizedHostname"
 	NodeFieldOwnerReferences      = "ownerReferences"
 	NodeFieldPodCidr              = "podCidr"
 	NodeFieldPodCidrs             = "podCidrs"
@@ -83,6 +84,7 @@ type Node struct {
 	NodePoolID           string                    `json:"nodePoolId,omitempty" yaml:"nodePoolId,omitempty"`
 	NodeTaints           []Taint                   `json:"nodeTaints,omitempty" yaml:"nodeTaints,omitempty"`
 	NodeTemplateID       string                    `json:"nodeTemplateId,omitempty" yaml:"nodeTemplateId,omitempty"`
+	NormalizedHostname   string                    `json:"normalizedHostname,omitempty" yaml:"normalizedHostname,omitempty"`
 	OwnerReferences      []OwnerReference          `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
 	PodCidr              string                    `json:"podCidr,omitempty" yaml:"podCidr,omitempty"`
 	PodCidrs             []string                  `json:"podCidrs,omitempty" yaml:"podCidrs,omitempty"`
@@ -0,0 +1,14 @@
+package client
+
+const (
+	NodeCommandInputType          = "nodeCommandInput"
+	NodeCommandInputFieldInsecure = "insecure"
+	NodeCommandInputFieldOS       = "os"
+	NodeCommandInputFieldRuntime  = "runtime"
+)
+
+type NodeCommandInput struct {
+	Insecure bool   `json:"insecure,omitempty" yaml:"insecure,omitempty"`
+	OS       string `json:"os,omitempty" yaml:"os,omitempty"`
+	Runtime  string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+}
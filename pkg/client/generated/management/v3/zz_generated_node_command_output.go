@@ -0,0 +1,10 @@
+package client
+
+const (
+	NodeCommandOutputType         = "nodeCommandOutput"
+	NodeCommandOutputFieldCommand = "command"
+)
+
+type NodeCommandOutput struct {
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+}
@@ -0,0 +1,12 @@
+package client
+
+const (
+	NodeMetadataPolicyType                      = "nodeMetadataPolicy"
+	NodeMetadataPolicyFieldManagedLabelPrefixes = "managedLabelPrefixes"
+	NodeMetadataPolicyFieldManagedTaintPrefixes = "managedTaintPrefixes"
+)
+
+type NodeMetadataPolicy struct {
+	ManagedLabelPrefixes []string `json:"managedLabelPrefixes,omitempty" yaml:"managedLabelPrefixes,omitempty"`
+	ManagedTaintPrefixes []string `json:"managedTaintPrefixes,omitempty" yaml:"managedTaintPrefixes,omitempty"`
+}
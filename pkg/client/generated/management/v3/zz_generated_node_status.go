@@ -18,6 +18,7 @@
 	NodeStatusFieldNodeName           = "nodeName"
 	NodeStatusFieldNodePlan           = "nodePlan"
 	NodeStatusFieldNodeTaints         = "nodeTaints"
+	NodeStatusFieldNormalizedHostname = "normalizedHostname"
 	NodeStatusFieldRequested          = "requested"
 	NodeStatusFieldVolumesAttached    = "volumesAttached"
 	NodeStatusFieldVolumesInUse       = "volumesInUse"
@@ -40,6 +41,7 @@ type NodeStatus struct {
 	NodeName           string                    `json:"nodeName,omitempty" yaml:"nodeName,omitempty"`
 	NodePlan           *NodePlan                 `json:"nodePlan,omitempty" yaml:"nodePlan,omitempty"`
 	NodeTaints         []Taint                   `json:"nodeTaints,omitempty" yaml:"nodeTaints,omitempty"`
+	NormalizedHostname string                    `json:"normalizedHostname,omitempty" yaml:"normalizedHostname,omitempty"`
 	Requested          map[string]string         `json:"requested,omitempty" yaml:"requested,omitempty"`
 	VolumesAttached    map[string]AttachedVolume `json:"volumesAttached,omitempty" yaml:"volumesAttached,omitempty"`
 	VolumesInUse       []string                  `json:"volumesInUse,omitempty" yaml:"volumesInUse,omitempty"`
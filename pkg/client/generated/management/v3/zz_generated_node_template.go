@@ -18,6 +18,7 @@
 	NodeTemplateFieldEngineEnv                = "engineEnv"
 	NodeTemplateFieldEngineInsecureRegistry   = "engineInsecureRegistry"
 	NodeTemplateFieldEngineInstallURL         = "engineInstallURL"
+	NodeTemplateFieldEngineInstallURLChecksum = "engineInstallURLChecksum"
 	NodeTemplateFieldEngineLabel              = "engineLabel"
 	NodeTemplateFieldEngineOpt                = "engineOpt"
 	NodeTemplateFieldEngineRegistryMirror     = "engineRegistryMirror"
@@ -26,7 +27,9 @@
 	NodeTemplateFieldName                     = "name"
 	NodeTemplateFieldNodeTaints               = "nodeTaints"
 	NodeTemplateFieldOwnerReferences          = "ownerReferences"
+	NodeTemplateFieldRegistrationTimeoutSecs  = "registrationTimeoutSecs"
 	NodeTemplateFieldRemoved                  = "removed"
+	NodeTemplateFieldRequireImdsV2            = "requireImdsV2"
 	NodeTemplateFieldState                    = "state"
 	NodeTemplateFieldStatus                   = "status"
 	NodeTemplateFieldTransitioning            = "transitioning"
@@ -49,6 +52,7 @@ type NodeTemplate struct {
 	EngineEnv                map[string]string   `json:"engineEnv,omitempty" yaml:"engineEnv,omitempty"`
 	EngineInsecureRegistry   []string            `json:"engineInsecureRegistry,omitempty" yaml:"engineInsecureRegistry,omitempty"`
 	EngineInstallURL         string              `json:"engineInstallURL,omitempty" yaml:"engineInstallURL,omitempty"`
+	EngineInstallURLChecksum string              `json:"engineInstallURLChecksum,omitempty" yaml:"engineInstallURLChecksum,omitempty"`
 	EngineLabel              map[string]string   `json:"engineLabel,omitempty" yaml:"engineLabel,omitempty"`
 	EngineOpt                map[string]string   `json:"engineOpt,omitempty" yaml:"engineOpt,omitempty"`
 	EngineRegistryMirror     []string            `json:"engineRegistryMirror,omitempty" yaml:"engineRegistryMirror,omitempty"`
@@ -57,7 +61,9 @@ type NodeTemplate struct {
 	Name                     string              `json:"name,omitempty" yaml:"name,omitempty"`
 	NodeTaints               []Taint             `json:"nodeTaints,omitempty" yaml:"nodeTaints,omitempty"`
 	OwnerReferences          []OwnerReference    `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+	RegistrationTimeoutSecs  int64               `json:"registrationTimeoutSecs,omitempty" yaml:"registrationTimeoutSecs,omitempty"`
 	Removed                  string              `json:"removed,omitempty" yaml:"removed,omitempty"`
+	RequireImdsV2            bool                `json:"requireImdsV2,omitempty" yaml:"requireImdsV2,omitempty"`
 	State                    string              `json:"state,omitempty" yaml:"state,omitempty"`
 	Status                   *NodeTemplateStatus `json:"status,omitempty" yaml:"status,omitempty"`
 	Transitioning            string              `json:"transitioning,omitempty" yaml:"transitioning,omitempty"`
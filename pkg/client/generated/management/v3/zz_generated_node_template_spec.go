@@ -12,11 +12,14 @@
 	NodeTemplateSpecFieldEngineEnv                = "engineEnv"
 	NodeTemplateSpecFieldEngineInsecureRegistry   = "engineInsecureRegistry"
 	NodeTemplateSpecFieldEngineInstallURL         = "engineInstallURL"
+	NodeTemplateSpecFieldEngineInstallURLChecksum = "engineInstallURLChecksum"
 	NodeTemplateSpecFieldEngineLabel              = "engineLabel"
 	NodeTemplateSpecFieldEngineOpt                = "engineOpt"
 	NodeTemplateSpecFieldEngineRegistryMirror     = "engineRegistryMirror"
 	NodeTemplateSpecFieldEngineStorageDriver      = "engineStorageDriver"
 	NodeTemplateSpecFieldNodeTaints               = "nodeTaints"
+	NodeTemplateSpecFieldRegistrationTimeoutSecs  = "registrationTimeoutSecs"
+	NodeTemplateSpecFieldRequireImdsV2            = "requireImdsV2"
 	NodeTemplateSpecFieldUseInternalIPAddress     = "useInternalIpAddress"
 )
 
@@ -31,10 +34,13 @@ type NodeTemplateSpec struct {
 	EngineEnv                map[string]string `json:"engineEnv,omitempty" yaml:"engineEnv,omitempty"`
 	EngineInsecureRegistry   []string          `json:"engineInsecureRegistry,omitempty" yaml:"engineInsecureRegistry,omitempty"`
 	EngineInstallURL         string            `json:"engineInstallURL,omitempty" yaml:"engineInstallURL,omitempty"`
+	EngineInstallURLChecksum string            `json:"engineInstallURLChecksum,omitempty" yaml:"engineInstallURLChecksum,omitempty"`
 	EngineLabel              map[string]string `json:"engineLabel,omitempty" yaml:"engineLabel,omitempty"`
 	EngineOpt                map[string]string `json:"engineOpt,omitempty" yaml:"engineOpt,omitempty"`
 	EngineRegistryMirror     []string          `json:"engineRegistryMirror,omitempty" yaml:"engineRegistryMirror,omitempty"`
 	EngineStorageDriver      string            `json:"engineStorageDriver,omitempty" yaml:"engineStorageDriver,omitempty"`
 	NodeTaints               []Taint           `json:"nodeTaints,omitempty" yaml:"nodeTaints,omitempty"`
+	RegistrationTimeoutSecs  int64             `json:"registrationTimeoutSecs,omitempty" yaml:"registrationTimeoutSecs,omitempty"`
+	RequireImdsV2            bool              `json:"requireImdsV2,omitempty" yaml:"requireImdsV2,omitempty"`
 	UseInternalIPAddress     *bool             `json:"useInternalIpAddress,omitempty" yaml:"useInternalIpAddress,omitempty"`
 }
@@ -23,6 +23,7 @@
 	ProjectFieldPodSecurityPolicyTemplateName = "podSecurityPolicyTemplateId"
 	ProjectFieldRemoved                       = "removed"
 	ProjectFieldResourceQuota                 = "resourceQuota"
+	ProjectFieldResourceQuotaUsage            = "resourceQuotaUsage"
 	ProjectFieldState                         = "state"
 	ProjectFieldTransitioning                 = "transitioning"
 	ProjectFieldTransitioningMessage          = "transitioningMessage"
@@ -48,6 +49,7 @@ type Project struct {
 	PodSecurityPolicyTemplateName string                  `json:"podSecurityPolicyTemplateId,omitempty" yaml:"podSecurityPolicyTemplateId,omitempty"`
 	Removed                       string                  `json:"removed,omitempty" yaml:"removed,omitempty"`
 	ResourceQuota                 *ProjectResourceQuota   `json:"resourceQuota,omitempty" yaml:"resourceQuota,omitempty"`
+	ResourceQuotaUsage            []ResourceQuotaUsage    `json:"resourceQuotaUsage,omitempty" yaml:"resourceQuotaUsage,omitempty"`
 	State                         string                  `json:"state,omitempty" yaml:"state,omitempty"`
 	Transitioning                 string                  `json:"transitioning,omitempty" yaml:"transitioning,omitempty"`
 	TransitioningMessage          string                  `json:"transitioningMessage,omitempty" yaml:"transitioningMessage,omitempty"`
@@ -81,6 +83,8 @@ type ProjectOperations interface {
 
 	ActionExportYaml(resource *Project) error
 
+	ActionSetMembers(resource *Project, input *SetMembersInput) (*SetMembersOutput, error)
+
 	ActionSetpodsecuritypolicytemplate(resource *Project, input *SetPodSecurityPolicyTemplateInput) (*Project, error)
 
 	ActionViewMonitoring(resource *Project) (*MonitoringOutput, error)
@@ -175,6 +179,12 @@ func (c *ProjectClient) ActionExportYaml(resource *Project) error {
 	return err
 }
 
+func (c *ProjectClient) ActionSetMembers(resource *Project, input *SetMembersInput) (*SetMembersOutput, error) {
+	resp := &SetMembersOutput{}
+	err := c.apiClient.Ops.DoAction(ProjectType, "setMembers", &resource.Resource, input, resp)
+	return resp, err
+}
+
 func (c *ProjectClient) ActionSetpodsecuritypolicytemplate(resource *Project, input *SetPodSecurityPolicyTemplateInput) (*Project, error) {
 	resp := &Project{}
 	err := c.apiClient.Ops.DoAction(ProjectType, "setpodsecuritypolicytemplate", &resource.Resource, input, resp)
@@ -0,0 +1,10 @@
+package client
+
+const (
+	ResolvedImagesOutputType        = "resolvedImagesOutput"
+	ResolvedImagesOutputFieldImages = "images"
+)
+
+type ResolvedImagesOutput struct {
+	Images map[string]string `json:"images,omitempty" yaml:"images,omitempty"`
+}
@@ -0,0 +1,14 @@
+package client
+
+const (
+	ResourceQuotaUsageType          = "resourceQuotaUsage"
+	ResourceQuotaUsageFieldLimit    = "limit"
+	ResourceQuotaUsageFieldResource = "resource"
+	ResourceQuotaUsageFieldUsed     = "used"
+)
+
+type ResourceQuotaUsage struct {
+	Limit    string `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Resource string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Used     string `json:"used,omitempty" yaml:"used,omitempty"`
+}
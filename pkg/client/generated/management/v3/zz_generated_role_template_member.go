@@ -0,0 +1,14 @@
+package client
+
+const (
+	RoleTemplateMemberType                  = "roleTemplateMember"
+	RoleTemplateMemberFieldGroupPrincipalID = "groupPrincipalId"
+	RoleTemplateMemberFieldRoleTemplateID   = "roleTemplateId"
+	RoleTemplateMemberFieldUserPrincipalID  = "userPrincipalId"
+)
+
+type RoleTemplateMember struct {
+	GroupPrincipalID string `json:"groupPrincipalId,omitempty" yaml:"groupPrincipalId,omitempty"`
+	RoleTemplateID   string `json:"roleTemplateId,omitempty" yaml:"roleTemplateId,omitempty"`
+	UserPrincipalID  string `json:"userPrincipalId,omitempty" yaml:"userPrincipalId,omitempty"`
+}
@@ -0,0 +1,20 @@
+package client
+
+const (
+	RoleTemplateMemberResultType                  = "roleTemplateMemberResult"
+	RoleTemplateMemberResultFieldBindingID        = "bindingId"
+	RoleTemplateMemberResultFieldGroupPrincipalID = "groupPrincipalId"
+	RoleTemplateMemberResultFieldMessage          = "message"
+	RoleTemplateMemberResultFieldResult           = "result"
+	RoleTemplateMemberResultFieldRoleTemplateID   = "roleTemplateId"
+	RoleTemplateMemberResultFieldUserPrincipalID  = "userPrincipalId"
+)
+
+type RoleTemplateMemberResult struct {
+	BindingID        string `json:"bindingId,omitempty" yaml:"bindingId,omitempty"`
+	GroupPrincipalID string `json:"groupPrincipalId,omitempty" yaml:"groupPrincipalId,omitempty"`
+	Message          string `json:"message,omitempty" yaml:"message,omitempty"`
+	Result           string `json:"result,omitempty" yaml:"result,omitempty"`
+	RoleTemplateID   string `json:"roleTemplateId,omitempty" yaml:"roleTemplateId,omitempty"`
+	UserPrincipalID  string `json:"userPrincipalId,omitempty" yaml:"userPrincipalId,omitempty"`
+}
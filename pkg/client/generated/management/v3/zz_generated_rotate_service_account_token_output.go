@@ -0,0 +1,10 @@
+package client
+
+const (
+	RotateServiceAccountTokenOutputType         = "rotateServiceAccountTokenOutput"
+	RotateServiceAccountTokenOutputFieldMessage = "message"
+)
+
+type RotateServiceAccountTokenOutput struct {
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
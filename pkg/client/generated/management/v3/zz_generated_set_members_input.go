@@ -0,0 +1,12 @@
+package client
+
+const (
+	SetMembersInputType         = "setMembersInput"
+	SetMembersInputFieldMembers = "members"
+	SetMembersInputFieldReplace = "replace"
+)
+
+type SetMembersInput struct {
+	Members []RoleTemplateMember `json:"members,omitempty" yaml:"members,omitempty"`
+	Replace bool                 `json:"replace,omitempty" yaml:"replace,omitempty"`
+}
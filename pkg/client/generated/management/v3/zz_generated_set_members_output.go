@@ -0,0 +1,10 @@
+package client
+
+const (
+	SetMembersOutputType         = "setMembersOutput"
+	SetMembersOutputFieldResults = "results"
+)
+
+type SetMembersOutput struct {
+	Results []RoleTemplateMemberResult `json:"results,omitempty" yaml:"results,omitempty"`
+}
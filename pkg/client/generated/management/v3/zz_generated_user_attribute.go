@@ -7,6 +7,8 @@
 	UserAttributeFieldCreatorID       = "creatorId"
 	UserAttributeFieldGroupPrincipals = "groupPrincipals"
 	UserAttributeFieldLabels          = "labels"
+	UserAttributeFieldLastActivity    = "lastActivity"
+	UserAttributeFieldLastLogin       = "lastLogin"
 	UserAttributeFieldLastRefresh     = "lastRefresh"
 	UserAttributeFieldName            = "name"
 	UserAttributeFieldNeedsRefresh    = "needsRefresh"
@@ -22,6 +24,8 @@ type UserAttribute struct {
 	CreatorID       string               `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
 	GroupPrincipals map[string]Principal `json:"groupPrincipals,omitempty" yaml:"groupPrincipals,omitempty"`
 	Labels          map[string]string    `json:"labels,omitempty" yaml:"labels,omitempty"`
+	LastActivity    string               `json:"lastActivity,omitempty" yaml:"lastActivity,omitempty"`
+	LastLogin       string               `json:"lastLogin,omitempty" yaml:"lastLogin,omitempty"`
 	LastRefresh     string               `json:"lastRefresh,omitempty" yaml:"lastRefresh,omitempty"`
 	Name            string               `json:"name,omitempty" yaml:"name,omitempty"`
 	NeedsRefresh    bool                 `json:"needsRefresh,omitempty" yaml:"needsRefresh,omitempty"`
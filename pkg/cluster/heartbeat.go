@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+)
+
+// AgentHeartbeatInterval is the minimum interval between two updates of the agent heartbeat of a cluster
+const AgentHeartbeatInterval = time.Minute
+
+// AgentHeartbeats rate limits the updates of the agent heartbeat in the status of the clusters, so a cluster isn't
+// written to etcd every time its agent checks in.
+type AgentHeartbeats struct {
+	lock     sync.Mutex
+	recorded map[string]time.Time
+	now      func() time.Time
+}
+
+func NewAgentHeartbeats() *AgentHeartbeats {
+	return &AgentHeartbeats{
+		recorded: map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// Due returns the timestamp to record as the agent heartbeat of the cluster and true if the heartbeat is due. It is due
+// if the agent reported a new version, or if the last heartbeat, recorded by this process or in the status of the
+// cluster, is older than AgentHeartbeatInterval. The heartbeat is considered recorded once Due returns true.
+func (h *AgentHeartbeats) Due(cluster *v3.Cluster, agentVersion string) (string, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	if agentVersion == "" || agentVersion == cluster.Status.AgentVersion {
+		if last, ok := h.recorded[cluster.Name]; ok && now.Sub(last) < AgentHeartbeatInterval {
+			return "", false
+		}
+		if last, err := time.Parse(time.RFC3339, cluster.Status.AgentLastSeen); err == nil && now.Sub(last) < AgentHeartbeatInterval {
+			return "", false
+		}
+	}
+
+	h.recorded[cluster.Name] = now
+	return now.UTC().Format(time.RFC3339), true
+}
+
+// AgentHeartbeatStale returns true if the cluster agent last checked in longer than threshold ago, false if it
+// checked in since or never did.
+func AgentHeartbeatStale(cluster *v3.Cluster, threshold time.Duration, now time.Time) bool {
+	last, err := time.Parse(time.RFC3339, cluster.Status.AgentLastSeen)
+	if err != nil {
+		return false
+	}
+	return now.Sub(last) > threshold
+}
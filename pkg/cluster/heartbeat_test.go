@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAgentHeartbeatsDue(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	heartbeats := NewAgentHeartbeats()
+	heartbeats.now = func() time.Time { return now }
+
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	lastSeen, due := heartbeats.Due(cluster, "v2.6.0")
+	assert.True(t, due, "expected the first heartbeat to be due")
+	assert.Equal(t, "2021-06-01T10:00:00Z", lastSeen)
+
+	now = now.Add(30 * time.Second)
+	_, due = heartbeats.Due(cluster, "")
+	assert.False(t, due, "expected the heartbeat to be rate limited by the process")
+
+	cluster.Status.AgentLastSeen = lastSeen
+	cluster.Status.AgentVersion = "v2.6.0"
+	_, due = NewAgentHeartbeats().Due(cluster, "v2.6.0")
+	assert.False(t, due, "expected the heartbeat to be rate limited by the status of the cluster")
+
+	_, due = heartbeats.Due(cluster, "v2.6.1")
+	assert.True(t, due, "expected a new agent version to be recorded right away")
+
+	now = now.Add(AgentHeartbeatInterval)
+	lastSeen, due = heartbeats.Due(cluster, "")
+	assert.True(t, due, "expected the heartbeat to be due once the interval passed")
+	assert.Equal(t, "2021-06-01T10:01:30Z", lastSeen)
+
+	_, due = heartbeats.Due(&v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-fghij"}}, "")
+	assert.True(t, due, "expected the heartbeats to be rate limited per cluster")
+}
+
+func TestAgentHeartbeatStale(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	cluster := &v3.Cluster{Status: v32.ClusterStatus{AgentLastSeen: "2021-06-01T09:56:00Z"}}
+
+	assert.False(t, AgentHeartbeatStale(cluster, 5*time.Minute, now))
+	assert.True(t, AgentHeartbeatStale(cluster, 3*time.Minute, now))
+	assert.False(t, AgentHeartbeatStale(&v3.Cluster{}, 3*time.Minute, now), "expected a cluster never seen not to be stale")
+}
@@ -0,0 +1,108 @@
+package clusterimages
+
+import (
+	"fmt"
+
+	"github.com/rancher/rancher/pkg/controllers/dashboard/clusterregistrationtoken"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/hostedcluster"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/managesystemagent"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/image"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/systemtemplate"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Components of the images which aren't deployed by a chart, the images of a chart are keyed by the name of the chart
+// and the path of the image in its values, e.g. rancher-eks-operator/eksOperator.image
+const (
+	Agent              = "agent"
+	WindowsAgent       = "windowsAgent"
+	KubeAPIAuth        = "kubeAPIAuth"
+	SystemAgentUpgrade = "systemAgentUpgrade"
+)
+
+// ChartValues returns the default values of the chart of the rancher-charts repo, at its latest version if the
+// version is empty
+type ChartValues func(chartName, version string) (map[string]interface{}, error)
+
+// Resolver resolves the images Rancher deploys to a cluster, so that the mirror of an air-gapped setup can be checked
+// before the cluster is provisioned
+type Resolver struct {
+	ProvisioningClusters rocontrollers.ClusterCache
+	ChartValues          ChartValues
+}
+
+// Resolve returns the images of the components Rancher deploys to the cluster, with the registries they are pulled
+// from when they are deployed: the agents use the private registry of the cluster, falling back to the
+// system-default-registry setting, while the system agent upgrade plan and the charts always use the setting.
+func (r *Resolver) Resolve(cluster *v3.Cluster) (map[string]string, error) {
+	windowsAgentImage, err := clusterregistrationtoken.NodeAgentImage(cluster)
+	if err != nil {
+		return nil, err
+	}
+	images := map[string]string{
+		Agent:        systemtemplate.GetDesiredAgentImage(cluster),
+		WindowsAgent: windowsAgentImage,
+	}
+	if authImage := systemtemplate.GetDesiredAuthImage(cluster); authImage != "" {
+		images[KubeAPIAuth] = authImage
+	}
+
+	provisioned, err := r.rkeProvisioned(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if provisioned {
+		if upgradeImage, version := image.SystemAgentUpgradeImage(); upgradeImage != "" {
+			images[SystemAgentUpgrade] = upgradeImage + ":" + version
+		}
+		if err := r.addChartImages(images, managesystemagent.SUCChartName, managesystemagent.SUCChartVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, operatorChart, version := hostedcluster.OperatorCharts(cluster); operatorChart != nil {
+		if err := r.addChartImages(images, operatorChart.ChartName, version); err != nil {
+			return nil, err
+		}
+	}
+
+	return images, nil
+}
+
+func (r *Resolver) addChartImages(images map[string]string, chartName, version string) error {
+	if r.ChartValues == nil {
+		return nil
+	}
+	values, err := r.ChartValues(chartName, version)
+	if err != nil {
+		return fmt.Errorf("failed to get the values of chart %s: %w", chartName, err)
+	}
+	for valuesPath, chartImage := range image.ChartImages(values, settings.SystemDefaultRegistry.Get()) {
+		if valuesPath == "" {
+			images[chartName] = chartImage
+		} else {
+			images[chartName+"/"+valuesPath] = chartImage
+		}
+	}
+	return nil
+}
+
+// rkeProvisioned returns whether the cluster is provisioned by Rancher with RKE2 or k3s, rather than imported
+func (r *Resolver) rkeProvisioned(cluster *v3.Cluster) (bool, error) {
+	if r.ProvisioningClusters == nil ||
+		cluster.Annotations["objectset.rio.cattle.io/owner-gvk"] != "provisioning.cattle.io/v1, Kind=Cluster" {
+		return false, nil
+	}
+
+	provCluster, err := r.ProvisioningClusters.Get(cluster.Annotations["objectset.rio.cattle.io/owner-namespace"],
+		cluster.Annotations["objectset.rio.cattle.io/owner-name"])
+	if apierror.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return provCluster.Spec.RKEConfig != nil, nil
+}
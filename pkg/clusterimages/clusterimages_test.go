@@ -0,0 +1,154 @@
+package clusterimages
+
+import (
+	"testing"
+
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeProvisioningClusters struct {
+	rocontrollers.ClusterCache
+	clusters map[string]*rancherv1.Cluster
+}
+
+func (f fakeProvisioningClusters) Get(namespace, name string) (*rancherv1.Cluster, error) {
+	return f.clusters[namespace+"/"+name], nil
+}
+
+func chartValues(t *testing.T, chartName, version string, values map[string]interface{}) ChartValues {
+	return func(gotChartName, gotVersion string) (map[string]interface{}, error) {
+		assert.Equal(t, chartName, gotChartName)
+		assert.Equal(t, version, gotVersion)
+		return values, nil
+	}
+}
+
+func setSetting(t *testing.T, setting settings.Setting, value string) func() {
+	old := setting.Get()
+	require.NoError(t, setting.Set(value))
+	return func() {
+		setting.Set(old)
+	}
+}
+
+func TestResolveRegistry(t *testing.T) {
+	defer setSetting(t, settings.AgentImage, "rancher/rancher-agent:v2.6.0")()
+
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	r := &Resolver{}
+
+	images, err := r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		Agent:        "rancher/rancher-agent:v2.6.0",
+		WindowsAgent: "rancher/rancher-agent:v2.6.0",
+	}, images, "expected the images to be pulled from their registry without a default registry")
+
+	defer setSetting(t, settings.SystemDefaultRegistry, "registry.example.com")()
+	cluster.Spec.LocalClusterAuthEndpoint.Enabled = true
+	images, err = r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/rancher/rancher-agent:v2.6.0", images[Agent], "expected the global default registry to be used")
+	assert.Equal(t, "registry.example.com/rancher/rancher-agent:v2.6.0", images[WindowsAgent])
+	assert.Equal(t, "registry.example.com/"+settings.AuthImage.Get(), images[KubeAPIAuth])
+
+	cluster.Spec.RancherKubernetesEngineConfig = &rketypes.RancherKubernetesEngineConfig{
+		PrivateRegistries: []rketypes.PrivateRegistry{{URL: "mirror.cluster.local"}},
+	}
+	images, err = r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, "mirror.cluster.local/rancher/rancher-agent:v2.6.0", images[Agent], "expected the registry of the cluster to override the default")
+	assert.Equal(t, "mirror.cluster.local/rancher/rancher-agent:v2.6.0", images[WindowsAgent])
+
+	cluster.Spec.AgentImageOverride = "custom.example.com/rancher-agent:patched"
+	images, err = r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, "custom.example.com/rancher-agent:patched", images[Agent], "expected the agent image override of the cluster to be deployed as is")
+	assert.Equal(t, "custom.example.com/rancher-agent:patched", images[WindowsAgent], "expected the windows node command to run the agent image override")
+}
+
+func TestResolveProvisionedCluster(t *testing.T) {
+	defer setSetting(t, settings.AgentImage, "rancher/rancher-agent:v2.6.0")()
+	defer setSetting(t, settings.SystemDefaultRegistry, "registry.example.com")()
+	defer setSetting(t, settings.SystemAgentUpgradeImage, "rancher/system-agent:v0.1.1-suc")()
+
+	cluster := &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "c-m-abcde",
+			Annotations: map[string]string{
+				"objectset.rio.cattle.io/owner-gvk":       "provisioning.cattle.io/v1, Kind=Cluster",
+				"objectset.rio.cattle.io/owner-namespace": "fleet-default",
+				"objectset.rio.cattle.io/owner-name":      "test",
+			},
+		},
+	}
+	provCluster := &rancherv1.Cluster{Spec: rancherv1.ClusterSpec{RKEConfig: &rancherv1.RKEConfig{}}}
+	r := &Resolver{
+		ProvisioningClusters: fakeProvisioningClusters{clusters: map[string]*rancherv1.Cluster{"fleet-default/test": provCluster}},
+		ChartValues: chartValues(t, "system-upgrade-controller", "100.0.0", map[string]interface{}{
+			"systemUpgradeController": map[string]interface{}{
+				"image": map[string]interface{}{"repository": "rancher/system-upgrade-controller", "tag": "v0.8.0"},
+			},
+			"kubectl": map[string]interface{}{
+				"image": map[string]interface{}{"repository": "rancher/kubectl", "tag": "v1.20.2"},
+			},
+		}),
+	}
+
+	images, err := r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		Agent:              "registry.example.com/rancher/rancher-agent:v2.6.0",
+		WindowsAgent:       "registry.example.com/rancher/rancher-agent:v2.6.0",
+		SystemAgentUpgrade: "registry.example.com/rancher/system-agent:v0.1.1-suc",
+		"system-upgrade-controller/systemUpgradeController.image": "registry.example.com/rancher/system-upgrade-controller:v0.8.0",
+		"system-upgrade-controller/kubectl.image":                 "registry.example.com/rancher/kubectl:v1.20.2",
+	}, images)
+
+	provCluster.Spec.RKEConfig = nil
+	images, err = r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.NotContains(t, images, SystemAgentUpgrade, "expected no system agent upgrade for imported clusters")
+}
+
+func TestResolveHostedCluster(t *testing.T) {
+	defer setSetting(t, settings.EKSOperatorVersion, "100.1.0+up1.1.1")()
+
+	cluster := &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"},
+		Spec: v32.ClusterSpec{
+			EKSConfig: &eksv1.EKSClusterConfigSpec{},
+			ClusterSpecBase: v32.ClusterSpecBase{
+				RancherKubernetesEngineConfig: &rketypes.RancherKubernetesEngineConfig{
+					PrivateRegistries: []rketypes.PrivateRegistry{{URL: "mirror.cluster.local"}},
+				},
+			},
+		},
+	}
+	r := &Resolver{
+		ChartValues: chartValues(t, "rancher-eks-operator", "100.1.0+up1.1.1", map[string]interface{}{
+			"eksOperator": map[string]interface{}{
+				"image": map[string]interface{}{"repository": "rancher/eks-operator", "tag": "v1.1.1"},
+			},
+		}),
+	}
+
+	images, err := r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, "rancher/eks-operator:v1.1.1", images["rancher-eks-operator/eksOperator.image"],
+		"expected the operator chart not to use the registry of the cluster")
+
+	defer setSetting(t, settings.SystemDefaultRegistry, "registry.example.com")()
+	images, err = r.Resolve(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/rancher/eks-operator:v1.1.1", images["rancher-eks-operator/eksOperator.image"])
+}
@@ -0,0 +1,20 @@
+package clustermanager
+
+import (
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"k8s.io/client-go/rest"
+)
+
+// setExecAuth replaces the service account token of the rest config with the exec credential plugin configured on the
+// cluster, the plugin is then run by Rancher to get short lived tokens
+func setExecAuth(rc *rest.Config, cluster *v3.Cluster, resolver *execauth.Resolver) error {
+	execConfig, err := resolver.Config(cluster)
+	if err != nil || execConfig == nil {
+		return err
+	}
+
+	rc.BearerToken = ""
+	rc.ExecProvider = execConfig
+	return nil
+}
@@ -0,0 +1,222 @@
+package execauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"k8s.io/apimachinery/pkg/api/errors"
+	authzv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/plugin/pkg/client/auth/exec"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/transport"
+)
+
+const (
+	defaultAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+	// ApprovalAnnotation records the admin who configured the exec auth of a cluster and the digest of that exec auth,
+	// as <user ID>:<digest>. The plugin is only run while the user is an admin and the exec auth is unchanged, as the
+	// clusters may be updated outside of the API which checks who configures it.
+	ApprovalAnnotation = "management.cattle.io/exec-auth-approved-by"
+)
+
+// AllowedCommand is an exec credential plugin allowed by the cluster-exec-auth-commands setting
+type AllowedCommand struct {
+	Command string `json:"command"`
+	// Args are the patterns of the args of the command, an arg matches a pattern equal to it or, for a pattern ending
+	// with *, starting with the pattern
+	Args []string `json:"args,omitempty"`
+}
+
+// Resolver resolves the exec credential plugins configured on the clusters, reading the values of their environment
+// variables from their secrets and checking that an admin configured them
+type Resolver struct {
+	ctx            context.Context
+	secrets        v1.SecretLister
+	userAttributes v3.UserAttributeLister
+	sarClient      authzv1client.SubjectAccessReviewInterface
+}
+
+func NewResolver(ctx context.Context, secrets v1.SecretLister, userAttributes v3.UserAttributeLister, sarClient authzv1client.SubjectAccessReviewInterface) *Resolver {
+	return &Resolver{
+		ctx:            ctx,
+		secrets:        secrets,
+		userAttributes: userAttributes,
+		sarClient:      sarClient,
+	}
+}
+
+// ResolverFor returns the resolver of the exec credential plugins using the clients of the scaled context
+func ResolverFor(scaledContext *config.ScaledContext) *Resolver {
+	return NewResolver(scaledContext.RunContext,
+		scaledContext.Core.Secrets("").Controller().Lister(),
+		scaledContext.Management.UserAttributes("").Controller().Lister(),
+		scaledContext.K8sClient.AuthorizationV1().SubjectAccessReviews())
+}
+
+// Config returns the exec credential plugin configured on the cluster, nil if the cluster authenticates with its
+// service account token. Only the plugins allowed by the cluster-exec-auth-commands setting are run, as the plugins
+// run on the Rancher server.
+func (r *Resolver) Config(cluster *v3.Cluster) (*clientcmdapi.ExecConfig, error) {
+	execAuth := cluster.Spec.ExecAuth
+	if execAuth == nil {
+		return nil, nil
+	}
+
+	if err := Validate(execAuth); err != nil {
+		return nil, fmt.Errorf("invalid exec auth of cluster [%s]: %w", cluster.Name, err)
+	}
+	if err := r.checkApproval(cluster); err != nil {
+		return nil, err
+	}
+
+	apiVersion := execAuth.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultAPIVersion
+	}
+
+	var env []clientcmdapi.ExecEnvVar
+	for _, envVar := range execAuth.Env {
+		secret, err := r.secrets.Get(namespace.GlobalNamespace, envVar.SecretName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the secret of the exec auth env var [%s] of cluster [%s]: %w", envVar.Name, cluster.Name, err)
+		}
+		value, ok := secret.Data[envVar.Key]
+		if !ok {
+			return nil, fmt.Errorf("secret [%s] of the exec auth env var [%s] of cluster [%s] has no key [%s]", envVar.SecretName, envVar.Name, cluster.Name, envVar.Key)
+		}
+		env = append(env, clientcmdapi.ExecEnvVar{Name: envVar.Name, Value: string(value)})
+	}
+
+	return &clientcmdapi.ExecConfig{
+		Command:    execAuth.Command,
+		Args:       append([]string(nil), execAuth.Args...),
+		Env:        env,
+		APIVersion: apiVersion,
+	}, nil
+}
+
+// WrapTransport wraps the round tripper so that the requests are authenticated with the token returned by the exec
+// credential plugin of the cluster, the round tripper is returned as is if the cluster has none
+func (r *Resolver) WrapTransport(cluster *v3.Cluster, rt http.RoundTripper) (http.RoundTripper, error) {
+	config, err := r.Config(cluster)
+	if err != nil || config == nil {
+		return rt, err
+	}
+
+	authenticator, err := exec.GetAuthenticator(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	transportConfig := &transport.Config{}
+	if err := authenticator.UpdateTransportConfig(transportConfig); err != nil {
+		return nil, err
+	}
+	return transportConfig.WrapTransport(rt), nil
+}
+
+// checkApproval returns an error unless the exec auth of the cluster is the one approved by a user who is still an
+// admin
+func (r *Resolver) checkApproval(cluster *v3.Cluster) error {
+	approval := cluster.Annotations[ApprovalAnnotation]
+	userID, digest := approval, ""
+	if i := strings.LastIndex(approval, ":"); i >= 0 {
+		userID, digest = approval[:i], approval[i+1:]
+	}
+	if userID == "" || digest != Digest(cluster.Spec.ExecAuth) {
+		return fmt.Errorf("exec auth of cluster [%s] was not configured by an admin", cluster.Name)
+	}
+
+	attribs, err := r.userAttributes.Get("", userID)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	isAdmin, err := sar.UserIsAdmin(r.ctx, r.sarClient, userID, sar.UserAttributeGroups(attribs))
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return fmt.Errorf("exec auth of cluster [%s] was configured by [%s] who is not an admin", cluster.Name, userID)
+	}
+	return nil
+}
+
+// Validate returns an error unless the command and the args of the exec auth are allowed by the
+// cluster-exec-auth-commands setting and its env vars reference secrets
+func Validate(execAuth *v32.ClusterExecAuth) error {
+	allowed, err := allowedCommands()
+	if err != nil {
+		return err
+	}
+
+	err = fmt.Errorf("exec auth command [%s] is not allowed by the cluster-exec-auth-commands setting", execAuth.Command)
+	for _, command := range allowed {
+		if command.Command == "" || command.Command != execAuth.Command {
+			continue
+		}
+		// the same command may be allowed with different args
+		if err = argsAllowed(execAuth.Args, command.Args); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, envVar := range execAuth.Env {
+		if envVar.Name == "" || envVar.SecretName == "" || envVar.Key == "" {
+			return fmt.Errorf("exec auth env var [%s] must have a name and the secret name and key of its value", envVar.Name)
+		}
+	}
+	return nil
+}
+
+// Digest returns the digest of the exec auth recorded by the ApprovalAnnotation
+func Digest(execAuth *v32.ClusterExecAuth) string {
+	data, _ := json.Marshal(execAuth)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Approval returns the value of the ApprovalAnnotation recording that the user configured the exec auth
+func Approval(userID string, execAuth *v32.ClusterExecAuth) string {
+	return userID + ":" + Digest(execAuth)
+}
+
+func allowedCommands() ([]AllowedCommand, error) {
+	value := strings.TrimSpace(settings.ClusterExecAuthCommands.Get())
+	if value == "" {
+		return nil, nil
+	}
+	var allowed []AllowedCommand
+	if err := json.Unmarshal([]byte(value), &allowed); err != nil {
+		return nil, fmt.Errorf("invalid cluster-exec-auth-commands setting: %w", err)
+	}
+	return allowed, nil
+}
+
+func argsAllowed(args, patterns []string) error {
+	if len(args) != len(patterns) {
+		return fmt.Errorf("exec auth args %v do not match the args %v allowed by the cluster-exec-auth-commands setting", args, patterns)
+	}
+	for i, arg := range args {
+		pattern := patterns[i]
+		if arg == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(arg, strings.TrimSuffix(pattern, "*"))) {
+			continue
+		}
+		return fmt.Errorf("exec auth arg [%s] does not match the arg [%s] allowed by the cluster-exec-auth-commands setting", arg, pattern)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package execauth
+
+import (
+	"context"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newTestResolver() *Resolver {
+	return NewResolver(context.Background(),
+		&corefakes.SecretListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.Secret, error) {
+				if namespace == "cattle-global-data" && name == "kubelogin" {
+					return &corev1.Secret{Data: map[string][]byte{"client-secret": []byte("secret")}}, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+			},
+		},
+		&fakes.UserAttributeListerMock{
+			GetFunc: func(namespace string, name string) (*v3.UserAttribute, error) {
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+			},
+		},
+		&fake.SubjectAccessReviews{Admins: map[string]bool{"u-admin": true}})
+}
+
+func newTestCluster(execAuth *v32.ClusterExecAuth, approver string) *v3.Cluster {
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-test"}}
+	cluster.Spec.ExecAuth = execAuth
+	if approver != "" {
+		cluster.Annotations = map[string]string{ApprovalAnnotation: Approval(approver, execAuth)}
+	}
+	return cluster
+}
+
+func setAllowedCommands(t *testing.T, value string) {
+	orig := settings.ClusterExecAuthCommands.Get()
+	require.NoError(t, settings.ClusterExecAuthCommands.Set(value))
+	t.Cleanup(func() {
+		settings.ClusterExecAuthCommands.Set(orig)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	setAllowedCommands(t, `[{"command": "/usr/local/bin/kubelogin", "args": ["get-token", "--oidc-issuer-url=https://issuer.example.com", "--oidc-client-id=*"]}, {"command": "/usr/bin/aws-iam-authenticator", "args": ["token", "-i", "*"]}]`)
+
+	tests := []struct {
+		name     string
+		execAuth *v32.ClusterExecAuth
+		wantErr  string
+	}{
+		{
+			name:     "allowed",
+			execAuth: &v32.ClusterExecAuth{Command: "/usr/local/bin/kubelogin", Args: []string{"get-token", "--oidc-issuer-url=https://issuer.example.com", "--oidc-client-id=rancher"}},
+		},
+		{
+			name:     "second allowed command",
+			execAuth: &v32.ClusterExecAuth{Command: "/usr/bin/aws-iam-authenticator", Args: []string{"token", "-i", "my-cluster"}},
+		},
+		{
+			name:     "command not allowed",
+			execAuth: &v32.ClusterExecAuth{Command: "/bin/sh", Args: []string{"-c", "id"}},
+			wantErr:  "exec auth command [/bin/sh] is not allowed by the cluster-exec-auth-commands setting",
+		},
+		{
+			name:     "arg not allowed",
+			execAuth: &v32.ClusterExecAuth{Command: "/usr/local/bin/kubelogin", Args: []string{"get-token", "--oidc-issuer-url=https://evil.example.com", "--oidc-client-id=rancher"}},
+			wantErr:  "exec auth arg [--oidc-issuer-url=https://evil.example.com] does not match the arg [--oidc-issuer-url=https://issuer.example.com] allowed by the cluster-exec-auth-commands setting",
+		},
+		{
+			name:     "extra arg",
+			execAuth: &v32.ClusterExecAuth{Command: "/usr/bin/aws-iam-authenticator", Args: []string{"token", "-i", "my-cluster", "--role", "admin"}},
+			wantErr:  "do not match the args",
+		},
+		{
+			name: "env without secret",
+			execAuth: &v32.ClusterExecAuth{Command: "/usr/bin/aws-iam-authenticator", Args: []string{"token", "-i", "my-cluster"},
+				Env: []v32.ClusterExecAuthEnvVar{{Name: "AWS_SECRET_ACCESS_KEY"}}},
+			wantErr: "exec auth env var [AWS_SECRET_ACCESS_KEY] must have a name and the secret name and key of its value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.execAuth)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	setAllowedCommands(t, `[{"command": "/usr/local/bin/kubelogin", "args": ["get-token"]}]`)
+	execAuth := &v32.ClusterExecAuth{
+		Command: "/usr/local/bin/kubelogin",
+		Args:    []string{"get-token"},
+		Env:     []v32.ClusterExecAuthEnvVar{{Name: "KUBELOGIN_CLIENT_SECRET", SecretName: "kubelogin", Key: "client-secret"}},
+	}
+	r := newTestResolver()
+
+	config, err := r.Config(newTestCluster(execAuth, "u-admin"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", config.Env[0].Value)
+
+	config, err = r.Config(newTestCluster(nil, ""))
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	_, err = r.Config(newTestCluster(execAuth, ""))
+	assert.EqualError(t, err, "exec auth of cluster [c-test] was not configured by an admin")
+
+	_, err = r.Config(newTestCluster(execAuth, "u-member"))
+	assert.EqualError(t, err, "exec auth of cluster [c-test] was configured by [u-member] who is not an admin")
+
+	changed := newTestCluster(execAuth, "u-admin")
+	changed.Spec.ExecAuth = &v32.ClusterExecAuth{Command: "/usr/local/bin/kubelogin", Args: []string{"get-token"}}
+	_, err = r.Config(changed)
+	assert.EqualError(t, err, "exec auth of cluster [c-test] was not configured by an admin")
+
+	missingKey := &v32.ClusterExecAuth{
+		Command: "/usr/local/bin/kubelogin",
+		Args:    []string{"get-token"},
+		Env:     []v32.ClusterExecAuthEnvVar{{Name: "KUBELOGIN_CLIENT_SECRET", SecretName: "kubelogin", Key: "token"}},
+	}
+	_, err = r.Config(newTestCluster(missingKey, "u-admin"))
+	assert.EqualError(t, err, "secret [kubelogin] of the exec auth env var [KUBELOGIN_CLIENT_SECRET] of cluster [c-test] has no key [token]")
+}
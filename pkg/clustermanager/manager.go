@@ -9,6 +9,7 @@
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +18,7 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
 	"github.com/rancher/rancher/pkg/clusterrouter"
 	clusterController "github.com/rancher/rancher/pkg/controllers/managementuser"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
@@ -28,27 +30,28 @@
 	"github.com/rancher/rke/pki/cert"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	rbacv1 "github.com/rancher/wrangler/pkg/generated/controllers/rbac/v1"
-	"github.com/rancher/wrangler/pkg/ratelimit"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/sync/semaphore"
 	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	authv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 type Manager struct {
-	httpsPort     int
-	ScaledContext *config.ScaledContext
-	clusterLister v3.ClusterLister
-	clusters      v3.ClusterInterface
-	controllers   sync.Map
-	accessControl types.AccessControl
-	rbac          rbacv1.Interface
-	dialer        dialer.Factory
-	startSem      *semaphore.Weighted
+	httpsPort      int
+	ScaledContext  *config.ScaledContext
+	clusterLister  v3.ClusterLister
+	clusters       v3.ClusterInterface
+	controllers    sync.Map
+	accessControl  types.AccessControl
+	rbac           rbacv1.Interface
+	dialer         dialer.Factory
+	startLimiter   *startLimiter
+	grbIndexer     cache.Indexer
+	serverVersions *serverVersionCache
 }
 
 type record struct {
@@ -62,14 +65,63 @@ type record struct {
 	cancel        context.CancelFunc
 }
 
-func NewManager(httpsPort int, context *config.ScaledContext, rbacControllers rbacv1.Interface, asl accesscontrol.AccessSetLookup) *Manager {
-	return &Manager{
-		httpsPort:     httpsPort,
-		ScaledContext: context,
-		accessControl: rbac.NewAccessControlWithASL("", rbacControllers, asl),
-		clusterLister: context.Management.Clusters("").Controller().Lister(),
-		clusters:      context.Management.Clusters(""),
-		startSem:      semaphore.NewWeighted(int64(settings.ClusterControllerStartCount.GetInt())),
+const grbByUserIndex = "clustermanager.cattle.io/grb-by-user"
+
+func NewManager(httpsPort int, context *config.ScaledContext, rbacControllers rbacv1.Interface, asl accesscontrol.AccessSetLookup) (*Manager, error) {
+	grbInformer := context.Management.GlobalRoleBindings("").Controller().Informer()
+	if err := grbInformer.AddIndexers(cache.Indexers{grbByUserIndex: grbByUser}); err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		httpsPort:      httpsPort,
+		ScaledContext:  context,
+		accessControl:  rbac.NewAccessControlWithASL("", rbacControllers, asl, nil),
+		clusterLister:  context.Management.Clusters("").Controller().Lister(),
+		clusters:       context.Management.Clusters(""),
+		startLimiter:   newStartLimiter(int64(settings.ClusterControllerStartCount.GetInt())),
+		grbIndexer:     grbInformer.GetIndexer(),
+		serverVersions: newServerVersionCache(),
+	}
+	context.Wrangler.Mgmt.Setting().OnChange(context.RunContext, "cluster-controller-start-count", m.onStartCountSetting)
+	return m, nil
+}
+
+func grbByUser(obj interface{}) ([]string, error) {
+	grb, ok := obj.(*v3.GlobalRoleBinding)
+	if !ok || grb.UserName == "" {
+		return []string{}, nil
+	}
+	return []string{grb.UserName}, nil
+}
+
+// globalRoles returns the lookup of the global roles of the users backing the admin fast path of the access control of
+// the cluster, the restricted-admin role is left out while the cluster is excluded from its reach
+func (m *Manager) globalRoles(clusterName string) rbac.GlobalRoleLookup {
+	return func(userName string) ([]string, error) {
+		objs, err := m.grbIndexer.ByIndex(grbByUserIndex, userName)
+		if err != nil {
+			return nil, err
+		}
+
+		var roles []string
+		for _, obj := range objs {
+			grb, ok := obj.(*v3.GlobalRoleBinding)
+			if !ok {
+				continue
+			}
+			if grb.GlobalRoleName == rbac.GlobalRestrictedAdmin {
+				cluster, err := m.clusterLister.Get("", clusterName)
+				if err != nil {
+					return nil, err
+				}
+				if rbac.RestrictedAdminExcluded(cluster) {
+					continue
+				}
+			}
+			roles = append(roles, grb.GlobalRoleName)
+		}
+		return roles, nil
 	}
 }
 
@@ -169,10 +221,19 @@ func (m *Manager) changed(r *record, cluster *v3.Cluster, controllers, clusterOw
 	if existing.Status.APIEndpoint != cluster.Status.APIEndpoint ||
 		existing.Status.ServiceAccountToken != cluster.Status.ServiceAccountToken ||
 		existing.Status.CACert != cluster.Status.CACert ||
-		existing.Status.AppliedSpec.LocalClusterAuthEndpoint.Enabled != cluster.Status.AppliedSpec.LocalClusterAuthEndpoint.Enabled {
+		existing.Status.AppliedSpec.LocalClusterAuthEndpoint.Enabled != cluster.Status.AppliedSpec.LocalClusterAuthEndpoint.Enabled ||
+		!reflect.DeepEqual(existing.Spec.ExecAuth, cluster.Spec.ExecAuth) ||
+		existing.Annotations[execauth.ApprovalAnnotation] != cluster.Annotations[execauth.ApprovalAnnotation] {
 		return true
 	}
 
+	if !cluster.Spec.Internal {
+		qps, burst := ClientRateLimit(cluster)
+		if r.cluster.RESTConfig.QPS != qps || r.cluster.RESTConfig.Burst != burst {
+			return true
+		}
+	}
+
 	if controllers && r.started && clusterOwner != r.owner {
 		return true
 	}
@@ -206,10 +267,11 @@ func (m *Manager) doStart(rec *record, clusterOwner bool) (exit error) {
 		break
 	}
 
-	if err := m.startSem.Acquire(rec.ctx, 1); err != nil {
+	release, err := m.startLimiter.acquire(rec.ctx)
+	if err != nil {
 		return err
 	}
-	defer m.startSem.Release(1)
+	defer release()
 
 	transaction := controller.NewHandlerTransaction(rec.ctx)
 	if clusterOwner {
@@ -229,7 +291,7 @@ func (m *Manager) doStart(rec *record, clusterOwner bool) (exit error) {
 		defer close(done)
 
 		logrus.Debugf("[clustermanager] creating AccessControl for cluster %v", rec.cluster.ClusterName)
-		rec.accessControl = rbac.NewAccessControl(rec.ctx, rec.cluster.ClusterName, rec.cluster.RBACw)
+		rec.accessControl = rbac.NewAccessControl(rec.ctx, rec.cluster.ClusterName, rec.cluster.RBACw, m.globalRoles(rec.cluster.ClusterName))
 
 		err := rec.cluster.Start(rec.ctx)
 		if err == nil {
@@ -264,7 +326,8 @@ func ToRESTConfig(cluster *v3.Cluster, context *config.ScaledContext) (*rest.Con
 		return &context.RESTConfig, nil
 	}
 
-	if cluster.Status.APIEndpoint == "" || cluster.Status.CACert == "" || cluster.Status.ServiceAccountToken == "" {
+	if cluster.Status.APIEndpoint == "" || cluster.Status.CACert == "" ||
+		(cluster.Status.ServiceAccountToken == "" && cluster.Spec.ExecAuth == nil) {
 		return nil, nil
 	}
 
@@ -282,6 +345,10 @@ func ToRESTConfig(cluster *v3.Cluster, context *config.ScaledContext) (*rest.Con
 		return nil, err
 	}
 
+	if _, err := cert.ParseCertsPEM(caBytes); err != nil {
+		return nil, fmt.Errorf("cluster CACert is invalid for cluster [%s]: %w", cluster.Name, err)
+	}
+
 	clusterDialer, err := context.Dialer.ClusterDialer(cluster.Name)
 	if err != nil {
 		return nil, err
@@ -304,9 +371,8 @@ func ToRESTConfig(cluster *v3.Cluster, context *config.ScaledContext) (*rest.Con
 			CAData:     append(caBytes, suffix...),
 			NextProtos: []string{"http/1.1"},
 		},
-		Timeout:     45 * time.Second,
-		RateLimiter: ratelimit.None,
-		UserAgent:   rest.DefaultKubernetesUserAgent() + " cluster " + cluster.Name,
+		Timeout:   45 * time.Second,
+		UserAgent: rest.DefaultKubernetesUserAgent() + " cluster " + cluster.Name,
 		WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
 			if ht, ok := rt.(*http.Transport); ok {
 				if tlsDialer == nil {
@@ -326,6 +392,13 @@ func ToRESTConfig(cluster *v3.Cluster, context *config.ScaledContext) (*rest.Con
 			return rt
 		},
 	}
+	SetClientRateLimit(rc, cluster)
+
+	if cluster.Spec.ExecAuth != nil {
+		if err := setExecAuth(rc, cluster, execauth.ResolverFor(context)); err != nil {
+			return nil, err
+		}
+	}
 
 	return rc, nil
 }
@@ -344,21 +417,39 @@ func nameIgnoringTLSDialer(dialer dialer.Dialer, caBytes []byte) (func(string, s
 	}
 
 	return func(network, address string) (net.Conn, error) {
-		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(15*time.Second))
+		deadline := time.Now().Add(rkeTLSDialTimeout())
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
 		defer cancel()
 		rawConn, err := dialer(ctx, network, address)
 		if err != nil {
 			return nil, err
 		}
+		// the deadline covers both the dial and the TLS handshake
+		if err := rawConn.SetDeadline(deadline); err != nil {
+			rawConn.Close()
+			return nil, err
+		}
 		tlsConn := tls.Client(rawConn, tlsConfig)
 		if err := tlsConn.Handshake(); err != nil {
 			rawConn.Close()
 			return nil, err
 		}
-		return tlsConn, err
+		if err := rawConn.SetDeadline(time.Time{}); err != nil {
+			rawConn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
 	}, nil
 }
 
+func rkeTLSDialTimeout() time.Duration {
+	timeout := settings.RKETLSDialTimeoutSeconds.GetInt()
+	if timeout <= 0 {
+		timeout = 15
+	}
+	return time.Duration(timeout) * time.Second
+}
+
 func VerifyIgnoreDNSName(caCertsPEM []byte) (func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error, error) {
 	rootCAs := x509.NewCertPool()
 	if len(caCertsPEM) > 0 {
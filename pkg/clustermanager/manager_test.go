@@ -0,0 +1,468 @@
+package clustermanager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	sarfake "github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
+	normancorev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+	"github.com/rancher/wrangler/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	authorizationv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type trackingConn struct {
+	net.Conn
+	sync.Mutex
+	closed bool
+}
+
+func (c *trackingConn) Close() error {
+	c.Lock()
+	c.closed = true
+	c.Unlock()
+	return c.Conn.Close()
+}
+
+func (c *trackingConn) isClosed() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.closed
+}
+
+// newTestCerts returns a PEM encoded CA and a server certificate signed by it
+func newTestCerts(t *testing.T) ([]byte, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "kube-apiserver"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, caCert, &serverKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	return caPEM, tls.Certificate{
+		Certificate: [][]byte{serverDER},
+		PrivateKey:  serverKey,
+	}
+}
+
+// fakeDialer returns a dialer that waits for delay before connecting to a TLS server serving
+// serverCert. If handshake is false the server never answers the handshake.
+func fakeDialer(delay time.Duration, serverCert tls.Certificate, handshake bool, conns chan<- *trackingConn) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			if !handshake {
+				<-ctx.Done()
+				return
+			}
+			tlsServer := tls.Server(server, &tls.Config{Certificates: []tls.Certificate{serverCert}})
+			tlsServer.Handshake()
+			buf := make([]byte, 1)
+			tlsServer.Read(buf)
+		}()
+
+		conn := &trackingConn{Conn: client}
+		conns <- conn
+		return conn, nil
+	}
+}
+
+func TestNameIgnoringTLSDialerTimeout(t *testing.T) {
+	original := settings.RKETLSDialTimeoutSeconds.Get()
+	defer settings.RKETLSDialTimeoutSeconds.Set(original)
+	require.NoError(t, settings.RKETLSDialTimeoutSeconds.Set("1"))
+
+	caPEM, serverCert := newTestCerts(t)
+
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		handshake bool
+		wantErr   bool
+	}{
+		{
+			name:      "dial within deadline",
+			delay:     100 * time.Millisecond,
+			handshake: true,
+		},
+		{
+			name:      "dial past deadline",
+			delay:     2 * time.Second,
+			handshake: true,
+			wantErr:   true,
+		},
+		{
+			name:      "handshake past deadline",
+			handshake: false,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conns := make(chan *trackingConn, 1)
+			dial, err := nameIgnoringTLSDialer(fakeDialer(tt.delay, serverCert, tt.handshake, conns), caPEM)
+			require.NoError(t, err)
+
+			start := time.Now()
+			conn, err := dial("tcp", "10.0.0.1:6443")
+			assert.Less(t, int64(time.Since(start)), int64(2*time.Second), "expected the dial to honor the deadline")
+			if !tt.wantErr {
+				require.NoError(t, err)
+				conn.Close()
+				return
+			}
+
+			assert.Error(t, err)
+			select {
+			case raw := <-conns:
+				assert.True(t, raw.isClosed(), "expected the raw connection to be closed on failure")
+			default:
+			}
+		})
+	}
+}
+
+type fakeDialerFactory struct{}
+
+func (fakeDialerFactory) ClusterDialer(clusterName string) (dialer.Dialer, error) {
+	return (&net.Dialer{}).DialContext, nil
+}
+
+func (fakeDialerFactory) DockerDialer(clusterName, machineName string) (dialer.Dialer, error) {
+	return (&net.Dialer{}).DialContext, nil
+}
+
+func (fakeDialerFactory) NodeDialer(clusterName, machineName string) (dialer.Dialer, error) {
+	return (&net.Dialer{}).DialContext, nil
+}
+
+func newRESTConfigTestCluster(driver, caCert string) *v32.Cluster {
+	cluster := &v32.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-test"},
+		Status: v32.ClusterStatus{
+			Driver:              driver,
+			APIEndpoint:         "https://10.0.0.1:6443",
+			CACert:              caCert,
+			ServiceAccountToken: "token",
+		},
+	}
+	v32.ClusterConditionProvisioned.True(cluster)
+	return cluster
+}
+
+func TestToRESTConfigCACert(t *testing.T) {
+	caPEM, _ := newTestCerts(t)
+	scaledContext := &config.ScaledContext{Dialer: fakeDialerFactory{}}
+
+	tests := []struct {
+		name       string
+		driver     string
+		caCert     string
+		wantConfig bool
+		wantErr    bool
+	}{
+		{
+			name:       "valid rke",
+			driver:     v32.ClusterDriverRKE,
+			caCert:     base64.StdEncoding.EncodeToString(caPEM),
+			wantConfig: true,
+		},
+		{
+			name:       "valid imported",
+			driver:     v32.ClusterDriverImported,
+			caCert:     base64.StdEncoding.EncodeToString(caPEM),
+			wantConfig: true,
+		},
+		{
+			name:   "empty",
+			driver: v32.ClusterDriverRKE,
+		},
+		{
+			name:    "corrupt rke",
+			driver:  v32.ClusterDriverRKE,
+			caCert:  base64.StdEncoding.EncodeToString([]byte("-----BEGIN CERTIFICATE-----\nbm90IGEgY2VydA==\n-----END CERTIFICATE-----\n")),
+			wantErr: true,
+		},
+		{
+			name:    "corrupt imported",
+			driver:  v32.ClusterDriverImported,
+			caCert:  base64.StdEncoding.EncodeToString([]byte("not a cert")),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := ToRESTConfig(newRESTConfigTestCluster(tt.driver, tt.caCert), scaledContext)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "cluster CACert is invalid")
+				assert.Contains(t, err.Error(), "c-test")
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantConfig {
+				require.NotNil(t, rc)
+				assert.Equal(t, "https://10.0.0.1:6443", rc.Host)
+			} else {
+				assert.Nil(t, rc)
+			}
+		})
+	}
+}
+
+func TestToRESTConfigRateLimit(t *testing.T) {
+	caPEM, _ := newTestCerts(t)
+	scaledContext := &config.ScaledContext{Dialer: fakeDialerFactory{}, RESTConfig: rest.Config{RateLimiter: ratelimit.None}}
+
+	require.NoError(t, settings.ClusterClientQPS.Set("20"))
+	defer settings.ClusterClientQPS.Set("100")
+	require.NoError(t, settings.ClusterClientBurst.Set("40"))
+	defer settings.ClusterClientBurst.Set("200")
+
+	cluster := newRESTConfigTestCluster(v32.ClusterDriverImported, base64.StdEncoding.EncodeToString(caPEM))
+	rc, err := ToRESTConfig(cluster, scaledContext)
+	require.NoError(t, err)
+	require.NotNil(t, rc.RateLimiter)
+	assert.Equal(t, float32(20), rc.RateLimiter.QPS(), "expected the limiter of the setting")
+	assert.Equal(t, 40, rc.Burst)
+
+	cluster.Annotations = map[string]string{
+		v32.ClusterClientQPSAnnotation:   "2.5",
+		v32.ClusterClientBurstAnnotation: "not a number",
+	}
+	rc, err = ToRESTConfig(cluster, scaledContext)
+	require.NoError(t, err)
+	assert.Equal(t, float32(2.5), rc.RateLimiter.QPS(), "expected the annotation to override the setting")
+	assert.Equal(t, 40, rc.Burst, "expected an invalid annotation to be ignored")
+
+	cluster.Spec.Internal = true
+	rc, err = ToRESTConfig(cluster, scaledContext)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.None, rc.RateLimiter, "expected the local cluster not to be limited")
+}
+
+func TestChangedClientRateLimit(t *testing.T) {
+	caPEM, _ := newTestCerts(t)
+	scaledContext := &config.ScaledContext{Dialer: fakeDialerFactory{}}
+	m := &Manager{}
+
+	cluster := newRESTConfigTestCluster(v32.ClusterDriverImported, base64.StdEncoding.EncodeToString(caPEM))
+	rc, err := ToRESTConfig(cluster, scaledContext)
+	require.NoError(t, err)
+	r := &record{clusterRec: cluster, cluster: &config.UserContext{RESTConfig: *rc}}
+	assert.False(t, m.changed(r, cluster, false, false))
+
+	updated := cluster.DeepCopy()
+	updated.Annotations = map[string]string{v32.ClusterClientBurstAnnotation: "10"}
+	assert.True(t, m.changed(r, updated, false, false), "expected a new burst to invalidate the record")
+
+	require.NoError(t, settings.ClusterClientQPS.Set("20"))
+	defer settings.ClusterClientQPS.Set("100")
+	assert.True(t, m.changed(r, cluster, false, false), "expected a new setting to invalidate the record")
+}
+
+type fakeCore struct {
+	normancorev1.Interface
+	secrets map[string]*corev1.Secret
+}
+
+func (f fakeCore) Secrets(namespace string) normancorev1.SecretInterface {
+	lister := &corefakes.SecretListerMock{
+		GetFunc: func(namespace string, name string) (*corev1.Secret, error) {
+			if secret, ok := f.secrets[namespace+":"+name]; ok {
+				return secret, nil
+			}
+			return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+		},
+	}
+	return &corefakes.SecretInterfaceMock{
+		ControllerFunc: func() normancorev1.SecretController {
+			return &corefakes.SecretControllerMock{ListerFunc: func() normancorev1.SecretLister { return lister }}
+		},
+	}
+}
+
+type fakeManagement struct {
+	v3.Interface
+}
+
+func (f fakeManagement) UserAttributes(namespace string) v3.UserAttributeInterface {
+	lister := &fakes.UserAttributeListerMock{
+		GetFunc: func(namespace string, name string) (*v3.UserAttribute, error) {
+			return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+		},
+	}
+	return &fakes.UserAttributeInterfaceMock{
+		ControllerFunc: func() v3.UserAttributeController {
+			return &fakes.UserAttributeControllerMock{ListerFunc: func() v3.UserAttributeLister { return lister }}
+		},
+	}
+}
+
+type fakeK8sClient struct {
+	kubernetes.Interface
+	authorizationv1.AuthorizationV1Interface
+	reviews *sarfake.SubjectAccessReviews
+}
+
+func (f fakeK8sClient) AuthorizationV1() authorizationv1.AuthorizationV1Interface {
+	return f
+}
+
+func (f fakeK8sClient) SubjectAccessReviews() authorizationv1.SubjectAccessReviewInterface {
+	return f.reviews
+}
+
+func TestToRESTConfigExecAuth(t *testing.T) {
+	caPEM, _ := newTestCerts(t)
+	scaledContext := &config.ScaledContext{
+		Dialer:     fakeDialerFactory{},
+		RunContext: context.Background(),
+		Core: fakeCore{secrets: map[string]*corev1.Secret{
+			"cattle-global-data:kubelogin": {Data: map[string][]byte{"client-secret": []byte("secret")}},
+		}},
+		Management: fakeManagement{},
+		K8sClient:  fakeK8sClient{reviews: &sarfake.SubjectAccessReviews{Admins: map[string]bool{"u-admin": true}}},
+	}
+
+	cluster := newRESTConfigTestCluster(v32.ClusterDriverImported, base64.StdEncoding.EncodeToString(caPEM))
+	rc, err := ToRESTConfig(cluster, scaledContext)
+	require.NoError(t, err)
+	assert.Equal(t, "token", rc.BearerToken)
+	assert.Nil(t, rc.ExecProvider, "expected the service account token unless exec auth is configured")
+
+	cluster.Spec.ExecAuth = &v32.ClusterExecAuth{
+		Command: "/usr/local/bin/kubelogin",
+		Args:    []string{"get-token", "--oidc-issuer-url=https://issuer.example.com"},
+		Env:     []v32.ClusterExecAuthEnvVar{{Name: "KUBELOGIN_CLIENT_SECRET", SecretName: "kubelogin", Key: "client-secret"}},
+	}
+	cluster.Annotations = map[string]string{execauth.ApprovalAnnotation: execauth.Approval("u-admin", cluster.Spec.ExecAuth)}
+	_, err = ToRESTConfig(cluster, scaledContext)
+	assert.EqualError(t, err, "invalid exec auth of cluster [c-test]: exec auth command [/usr/local/bin/kubelogin] is not allowed by the cluster-exec-auth-commands setting")
+
+	require.NoError(t, settings.ClusterExecAuthCommands.Set(`[{"command": "/usr/bin/aws"}, {"command": "/usr/local/bin/kubelogin", "args": ["get-token", "--oidc-issuer-url=*"]}]`))
+	defer settings.ClusterExecAuthCommands.Set("")
+
+	cluster.Status.ServiceAccountToken = ""
+	rc, err = ToRESTConfig(cluster, scaledContext)
+	require.NoError(t, err)
+	require.NotNil(t, rc, "expected no service account token to be needed with exec auth")
+	assert.Empty(t, rc.BearerToken)
+	assert.Equal(t, &clientcmdapi.ExecConfig{
+		Command:    "/usr/local/bin/kubelogin",
+		Args:       []string{"get-token", "--oidc-issuer-url=https://issuer.example.com"},
+		Env:        []clientcmdapi.ExecEnvVar{{Name: "KUBELOGIN_CLIENT_SECRET", Value: "secret"}},
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+	}, rc.ExecProvider)
+	assert.Contains(t, string(rc.TLSClientConfig.CAData), string(caPEM), "expected the CA of the cluster to still be trusted")
+	assert.NotNil(t, rc.WrapTransport, "expected the cluster dialer to still be used")
+
+	r := &record{clusterRec: cluster, cluster: &config.UserContext{RESTConfig: *rc}}
+	updated := cluster.DeepCopy()
+	updated.Spec.ExecAuth.Args = append(updated.Spec.ExecAuth.Args, "--oidc-client-id=rancher")
+	assert.True(t, (&Manager{}).changed(r, updated, false, false), "expected a new exec auth to invalidate the record")
+	updated = cluster.DeepCopy()
+	updated.Annotations[execauth.ApprovalAnnotation] = execauth.Approval("u-other-admin", cluster.Spec.ExecAuth)
+	assert.True(t, (&Manager{}).changed(r, updated, false, false), "expected a new approval to invalidate the record")
+}
+
+func TestGlobalRolesRestrictedAdminExclusion(t *testing.T) {
+	grbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{grbByUserIndex: grbByUser})
+	for name, role := range map[string]string{"grb-admin": rbac.GlobalAdmin, "grb-restricted": rbac.GlobalRestrictedAdmin} {
+		require.NoError(t, grbIndexer.Add(&v3.GlobalRoleBinding{
+			ObjectMeta:     metav1.ObjectMeta{Name: name},
+			UserName:       "u-" + role,
+			GlobalRoleName: role,
+		}))
+	}
+
+	clusters := map[string]*v3.Cluster{
+		"c-downstream": {ObjectMeta: metav1.ObjectMeta{Name: "c-downstream"}},
+		"c-excluded": {ObjectMeta: metav1.ObjectMeta{
+			Name:        "c-excluded",
+			Annotations: map[string]string{rbac.RestrictedAdminExcludeAnnotation: "true"},
+		}},
+	}
+	m := &Manager{
+		grbIndexer: grbIndexer,
+		clusterLister: &fakes.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Cluster, error) {
+				return clusters[name], nil
+			},
+		},
+	}
+
+	roles, err := m.globalRoles("c-downstream")("u-" + rbac.GlobalRestrictedAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, []string{rbac.GlobalRestrictedAdmin}, roles)
+
+	roles, err = m.globalRoles("c-excluded")("u-" + rbac.GlobalRestrictedAdmin)
+	require.NoError(t, err)
+	assert.Empty(t, roles, "expected restricted-admin to be denied the fast path of an excluded cluster")
+
+	roles, err = m.globalRoles("c-excluded")("u-" + rbac.GlobalAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, []string{rbac.GlobalAdmin}, roles, "expected admins to be unaffected by the exclusion")
+}
@@ -0,0 +1,54 @@
+package clustermanager
+
+import (
+	"strconv"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// ClientRateLimit returns the QPS and burst of the requests to the API server of the cluster. The annotations of the
+// cluster override the cluster-client-qps and cluster-client-burst settings, invalid values fall back to the settings.
+func ClientRateLimit(cluster *v3.Cluster) (float32, int) {
+	qps, _ := parseQPS(settings.ClusterClientQPS.Get())
+	if qps <= 0 {
+		qps, _ = parseQPS(settings.ClusterClientQPS.Default)
+	}
+	burst, _ := strconv.Atoi(settings.ClusterClientBurst.Get())
+	if burst <= 0 {
+		burst, _ = strconv.Atoi(settings.ClusterClientBurst.Default)
+	}
+
+	if value, ok := cluster.Annotations[v32.ClusterClientQPSAnnotation]; ok {
+		if override, err := parseQPS(value); err == nil && override > 0 {
+			qps = override
+		} else {
+			logrus.Warnf("ignoring invalid %s annotation [%s] of cluster [%s]", v32.ClusterClientQPSAnnotation, value, cluster.Name)
+		}
+	}
+	if value, ok := cluster.Annotations[v32.ClusterClientBurstAnnotation]; ok {
+		if override, err := strconv.Atoi(value); err == nil && override > 0 {
+			burst = override
+		} else {
+			logrus.Warnf("ignoring invalid %s annotation [%s] of cluster [%s]", v32.ClusterClientBurstAnnotation, value, cluster.Name)
+		}
+	}
+
+	return qps, burst
+}
+
+// SetClientRateLimit limits the requests made with the rest config to the API server of the cluster. The limiter is
+// shared by all the clients built from the config, so that the cluster is throttled as a whole.
+func SetClientRateLimit(rc *rest.Config, cluster *v3.Cluster) {
+	rc.QPS, rc.Burst = ClientRateLimit(cluster)
+	rc.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(rc.QPS, rc.Burst)
+}
+
+func parseQPS(value string) (float32, error) {
+	qps, err := strconv.ParseFloat(value, 32)
+	return float32(qps), err
+}
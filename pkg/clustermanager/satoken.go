@@ -0,0 +1,171 @@
+package clustermanager
+
+import (
+	"context"
+	"fmt"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/kontainer-engine/drivers/util"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+)
+
+// tokenSecretNamespace is the namespace of the token secrets created by util.CreateServiceAccountToken
+const tokenSecretNamespace = "cattle-system"
+
+var rotatableDrivers = map[string]bool{
+	v32.ClusterDriverImported: true,
+	v32.ClusterDriverK3s:      true,
+	v32.ClusterDriverRke2:     true,
+	v32.ClusterDriverAKS:      true,
+	v32.ClusterDriverEKS:      true,
+	v32.ClusterDriverGKE:      true,
+}
+
+// CanRotateServiceAccountToken returns true for the imported and hosted clusters whose service account token can be
+// rotated
+func CanRotateServiceAccountToken(cluster *v32.Cluster) bool {
+	return !cluster.Spec.Internal && rotatableDrivers[cluster.Status.Driver] && cluster.Status.ServiceAccountToken != ""
+}
+
+// tokenRotator rotates a service account token in an order that leaves the old token working if any step fails: the
+// new token is created and verified, then swapped in, and the old token is deleted last.
+type tokenRotator struct {
+	create     func() (token, secretName string, err error)
+	verify     func(token string) error
+	swap       func(token string) error
+	invalidate func()
+	deleteNew  func(secretName string) error
+	deleteOld  func(token string) (bool, error)
+}
+
+// rotate returns whether the old token was revoked. It isn't when it belongs to a service account whose tokens Rancher
+// doesn't manage, such as the one of the cluster agent that reported the token of an imported cluster.
+func (r tokenRotator) rotate(oldToken string) (bool, error) {
+	token, secretName, err := r.create()
+	if err != nil {
+		r.cleanup(secretName)
+		return false, fmt.Errorf("failed to create service account token: %w", err)
+	}
+
+	if err := r.verify(token); err != nil {
+		r.cleanup(secretName)
+		return false, fmt.Errorf("failed to verify new service account token: %w", err)
+	}
+
+	if err := r.swap(token); err != nil {
+		r.cleanup(secretName)
+		return false, fmt.Errorf("failed to update cluster with new service account token: %w", err)
+	}
+
+	r.invalidate()
+
+	revoked, err := r.deleteOld(oldToken)
+	if err != nil {
+		return false, fmt.Errorf("service account token rotated, but failed to delete the old token: %w", err)
+	}
+	return revoked, nil
+}
+
+func (r tokenRotator) cleanup(secretName string) {
+	if secretName == "" {
+		return
+	}
+	if err := r.deleteNew(secretName); err != nil {
+		logrus.Errorf("failed to delete unused service account token secret [%s]: %v", secretName, err)
+	}
+}
+
+// RotateServiceAccountToken replaces the service account token Rancher uses to connect to an imported or hosted
+// cluster. The new token is created through the existing connection to the cluster and verified before it replaces the
+// old one in the cluster status, the old token is then deleted. It returns whether the old token was revoked.
+func (m *Manager) RotateServiceAccountToken(ctx context.Context, clusterName string) (bool, error) {
+	cluster, err := m.clusters.Get(clusterName, v1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if !CanRotateServiceAccountToken(cluster) {
+		return false, fmt.Errorf("service account token of cluster [%s] can't be rotated", clusterName)
+	}
+
+	userContext, err := m.UserContext(clusterName)
+	if err != nil {
+		return false, err
+	}
+	clientset := userContext.K8sClient
+
+	r := tokenRotator{
+		create: func() (string, string, error) {
+			return util.CreateServiceAccountToken(clientset)
+		},
+		verify: func(token string) error {
+			return verifyServiceAccountToken(ctx, userContext.RESTConfig, token)
+		},
+		swap: func(token string) error {
+			return m.swapServiceAccountToken(clusterName, token)
+		},
+		invalidate: func() {
+			m.invalidate(clusterName)
+		},
+		deleteNew: func(secretName string) error {
+			return clientset.CoreV1().Secrets(tokenSecretNamespace).Delete(ctx, secretName, v1.DeleteOptions{})
+		},
+		deleteOld: func(token string) (bool, error) {
+			return util.DeleteServiceAccountToken(clientset, token)
+		},
+	}
+
+	logrus.Infof("Rotating service account token for cluster [%s]", clusterName)
+	return r.rotate(cluster.Status.ServiceAccountToken)
+}
+
+// verifyServiceAccountToken checks the token can be used to get a namespace of the cluster
+func verifyServiceAccountToken(ctx context.Context, restConfig rest.Config, token string) error {
+	restConfig.BearerToken = token
+	restConfig.BearerTokenFile = ""
+	clientset, err := kubernetes.NewForConfig(&restConfig)
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, "kube-system", v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (m *Manager) swapServiceAccountToken(clusterName, token string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := m.clusters.Get(clusterName, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		cluster.Status.ServiceAccountToken = token
+		if cluster.Annotations == nil {
+			cluster.Annotations = map[string]string{}
+		}
+		cluster.Annotations[v32.ClusterRotatedServiceAccountTokenAnnotation] = "true"
+		_, err = m.clusters.Update(cluster)
+		return err
+	})
+}
+
+// invalidate stops the record of the cluster if it still uses the previous service account token, so that new
+// connections to the cluster are made with the current token once the cluster controllers start it again
+func (m *Manager) invalidate(clusterName string) {
+	cluster, err := m.clusters.Get(clusterName, v1.GetOptions{})
+	if err != nil {
+		return
+	}
+	obj, ok := m.controllers.Load(cluster.UID)
+	if !ok {
+		return
+	}
+	if obj.(*record).clusterRec.Status.ServiceAccountToken != cluster.Status.ServiceAccountToken {
+		m.Stop(cluster)
+	}
+}
@@ -0,0 +1,146 @@
+package clustermanager
+
+import (
+	"errors"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type rotationTest struct {
+	calls      []string
+	fail       string
+	notRevoked bool
+}
+
+func (r *rotationTest) step(name string) error {
+	r.calls = append(r.calls, name)
+	if r.fail == name {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func (r *rotationTest) rotator() tokenRotator {
+	return tokenRotator{
+		create: func() (string, string, error) {
+			return "new-token", "kontainer-engine-token-abcde", r.step("create")
+		},
+		verify: func(token string) error {
+			return r.step("verify " + token)
+		},
+		swap: func(token string) error {
+			return r.step("swap " + token)
+		},
+		invalidate: func() {
+			r.step("invalidate")
+		},
+		deleteNew: func(secretName string) error {
+			return r.step("delete secret " + secretName)
+		},
+		deleteOld: func(token string) (bool, error) {
+			err := r.step("delete " + token)
+			return err == nil && !r.notRevoked, err
+		},
+	}
+}
+
+func TestRotateServiceAccountToken(t *testing.T) {
+	tests := []struct {
+		name            string
+		fail            string
+		notRevoked      bool
+		expectedCalls   []string
+		expectedErr     string
+		expectedRevoked bool
+	}{
+		{
+			name: "old token deleted after the swap",
+			expectedCalls: []string{
+				"create",
+				"verify new-token",
+				"swap new-token",
+				"invalidate",
+				"delete old-token",
+			},
+			expectedRevoked: true,
+		},
+		{
+			name:       "old token of another service account reported as not revoked",
+			notRevoked: true,
+			expectedCalls: []string{
+				"create",
+				"verify new-token",
+				"swap new-token",
+				"invalidate",
+				"delete old-token",
+			},
+		},
+		{
+			name: "new token that doesn't work isn't swapped in",
+			fail: "verify new-token",
+			expectedCalls: []string{
+				"create",
+				"verify new-token",
+				"delete secret kontainer-engine-token-abcde",
+			},
+			expectedErr: "failed to verify new service account token",
+		},
+		{
+			name: "old token kept if the cluster can't be updated",
+			fail: "swap new-token",
+			expectedCalls: []string{
+				"create",
+				"verify new-token",
+				"swap new-token",
+				"delete secret kontainer-engine-token-abcde",
+			},
+			expectedErr: "failed to update cluster",
+		},
+		{
+			name: "old token not deleted",
+			fail: "delete old-token",
+			expectedCalls: []string{
+				"create",
+				"verify new-token",
+				"swap new-token",
+				"invalidate",
+				"delete old-token",
+			},
+			expectedErr: "service account token rotated, but failed to delete the old token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &rotationTest{fail: tt.fail, notRevoked: tt.notRevoked}
+
+			revoked, err := r.rotator().rotate("old-token")
+
+			assert.Equal(t, tt.expectedCalls, r.calls)
+			assert.Equal(t, tt.expectedRevoked, revoked)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCanRotateServiceAccountToken(t *testing.T) {
+	newCluster := func(driver, token string, internal bool) *v32.Cluster {
+		cluster := &v32.Cluster{}
+		cluster.Spec.Internal = internal
+		cluster.Status.Driver = driver
+		cluster.Status.ServiceAccountToken = token
+		return cluster
+	}
+
+	assert.True(t, CanRotateServiceAccountToken(newCluster(v32.ClusterDriverImported, "token", false)))
+	assert.True(t, CanRotateServiceAccountToken(newCluster(v32.ClusterDriverEKS, "token", false)))
+	assert.False(t, CanRotateServiceAccountToken(newCluster(v32.ClusterDriverRKE, "token", false)))
+	assert.False(t, CanRotateServiceAccountToken(newCluster(v32.ClusterDriverImported, "", false)))
+	assert.False(t, CanRotateServiceAccountToken(newCluster(v32.ClusterDriverImported, "token", true)))
+}
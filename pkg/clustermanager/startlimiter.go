@@ -0,0 +1,129 @@
+package clustermanager
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+)
+
+// startLimiter bounds the number of cluster controllers starting concurrently. It is resized by replacing its
+// semaphore, the starts in progress are carried over to the new semaphore and the starts waiting on the old one
+// wait on the new one instead.
+type startLimiter struct {
+	sync.Mutex
+	sem  *semaphore.Weighted
+	size int64
+	// active is the number of starts holding the limiter, excess the number of them the semaphore doesn't account
+	// for since it was resized below active
+	active  int64
+	excess  int64
+	resized chan struct{}
+}
+
+func newStartLimiter(size int64) *startLimiter {
+	return &startLimiter{
+		sem:     semaphore.NewWeighted(size),
+		size:    size,
+		resized: make(chan struct{}),
+	}
+}
+
+// acquire blocks until a start is allowed or the context is done, the returned func has to be called once the start
+// is over
+func (l *startLimiter) acquire(ctx context.Context) (func(), error) {
+	for {
+		l.Lock()
+		sem, resized := l.sem, l.resized
+		l.Unlock()
+
+		acquireCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-resized:
+				cancel()
+			case <-acquireCtx.Done():
+			}
+		}()
+		err := sem.Acquire(acquireCtx, 1)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// the limiter was resized, wait on the new semaphore
+			continue
+		}
+
+		l.Lock()
+		if l.sem != sem {
+			l.Unlock()
+			sem.Release(1)
+			continue
+		}
+		l.active++
+		l.Unlock()
+		return l.release, nil
+	}
+}
+
+func (l *startLimiter) release() {
+	l.Lock()
+	defer l.Unlock()
+
+	l.active--
+	if l.excess > 0 {
+		l.excess--
+		return
+	}
+	l.sem.Release(1)
+}
+
+// resize replaces the semaphore of the limiter by one of the given size
+func (l *startLimiter) resize(size int64) {
+	l.Lock()
+	defer l.Unlock()
+
+	if size == l.size {
+		return
+	}
+
+	sem := semaphore.NewWeighted(size)
+	migrated := l.active
+	if migrated > size {
+		migrated = size
+	}
+	sem.TryAcquire(migrated)
+
+	l.sem = sem
+	l.size = size
+	l.excess = l.active - migrated
+	close(l.resized)
+	l.resized = make(chan struct{})
+}
+
+// onStartCountSetting resizes the start limiter of the manager when the cluster-controller-start-count setting changes
+func (m *Manager) onStartCountSetting(key string, setting *v32.Setting) (*v32.Setting, error) {
+	if setting == nil || setting.Name != settings.ClusterControllerStartCount.Name {
+		return setting, nil
+	}
+
+	value := setting.Value
+	if value == "" {
+		value = setting.Default
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 {
+		logrus.Errorf("[clustermanager] invalid value %q of setting %s, it must be a positive integer", value, setting.Name)
+		return setting, nil
+	}
+
+	logrus.Debugf("[clustermanager] allowing %d cluster controllers to start concurrently", count)
+	m.startLimiter.resize(int64(count))
+	return setting, nil
+}
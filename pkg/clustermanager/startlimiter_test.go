@@ -0,0 +1,101 @@
+package clustermanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// startAsync acquires the limiter in the background, the returned channel receives the release func once acquired
+func startAsync(ctx context.Context, l *startLimiter) <-chan func() {
+	started := make(chan func(), 1)
+	go func() {
+		if release, err := l.acquire(ctx); err == nil {
+			started <- release
+		}
+	}()
+	return started
+}
+
+func assertStarted(t *testing.T, started <-chan func()) func() {
+	select {
+	case release := <-started:
+		return release
+	case <-time.After(time.Second):
+		require.FailNow(t, "expected the start to be allowed")
+		return nil
+	}
+}
+
+func assertWaiting(t *testing.T, started <-chan func()) {
+	select {
+	case <-started:
+		assert.FailNow(t, "expected the start to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func startCountSetting(value string) *v32.Setting {
+	return &v32.Setting{
+		ObjectMeta: metav1.ObjectMeta{Name: settings.ClusterControllerStartCount.Name},
+		Value:      value,
+		Default:    "50",
+	}
+}
+
+func TestStartLimiterIncrease(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &Manager{startLimiter: newStartLimiter(1)}
+	first := assertStarted(t, startAsync(ctx, m.startLimiter))
+	second := startAsync(ctx, m.startLimiter)
+	assertWaiting(t, second)
+
+	_, err := m.onStartCountSetting(settings.ClusterControllerStartCount.Name, startCountSetting("2"))
+	require.NoError(t, err)
+	assertStarted(t, second)
+
+	// both starts in progress are accounted for by the resized limiter
+	third := startAsync(ctx, m.startLimiter)
+	assertWaiting(t, third)
+	first()
+	assertStarted(t, third)
+}
+
+func TestStartLimiterDecrease(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &Manager{startLimiter: newStartLimiter(2)}
+	first := assertStarted(t, startAsync(ctx, m.startLimiter))
+	second := assertStarted(t, startAsync(ctx, m.startLimiter))
+
+	_, err := m.onStartCountSetting(settings.ClusterControllerStartCount.Name, startCountSetting("1"))
+	require.NoError(t, err)
+
+	third := startAsync(ctx, m.startLimiter)
+	first()
+	assertWaiting(t, third)
+	second()
+	release := assertStarted(t, third)
+	release()
+}
+
+func TestStartLimiterInvalidSetting(t *testing.T) {
+	m := &Manager{startLimiter: newStartLimiter(1)}
+
+	_, err := m.onStartCountSetting(settings.ClusterControllerStartCount.Name, startCountSetting("0"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), m.startLimiter.size, "expected an invalid value to be ignored")
+
+	_, err = m.onStartCountSetting(settings.ClusterControllerStartCount.Name, startCountSetting(""))
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), m.startLimiter.size, "expected the default to apply without a value")
+}
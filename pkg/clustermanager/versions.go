@@ -0,0 +1,197 @@
+package clustermanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/auth/util"
+	"github.com/rancher/rancher/pkg/types/config"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/version"
+	typedauthzv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	serverVersionTTL     = time.Minute
+	serverVersionTimeout = 10 * time.Second
+)
+
+// ClusterVersion is the version of the Kubernetes API server of a downstream cluster, or why it couldn't be queried
+type ClusterVersion struct {
+	GitVersion string `json:"gitVersion,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ServerVersions are the versions of the API servers of every cluster
+type ServerVersions struct {
+	Clusters map[string]ClusterVersion `json:"clusters"`
+}
+
+type serverVersionEntry struct {
+	version ClusterVersion
+	expires time.Time
+}
+
+// serverVersionCache keeps the versions of the API servers of the clusters, so that listing them doesn't query every
+// cluster on every request. The errors are cached as well so that unreachable clusters don't slow down every request.
+type serverVersionCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	timeout time.Duration
+	entries map[string]serverVersionEntry
+}
+
+func newServerVersionCache() *serverVersionCache {
+	return &serverVersionCache{
+		ttl:     serverVersionTTL,
+		timeout: serverVersionTimeout,
+		entries: map[string]serverVersionEntry{},
+	}
+}
+
+func (c *serverVersionCache) get(ctx context.Context, clusterName string, client rest.Interface) ClusterVersion {
+	c.Lock()
+	entry, ok := c.entries[clusterName]
+	c.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.version
+	}
+
+	version := c.query(ctx, client)
+	if ctx.Err() != nil {
+		// the request was cancelled, which says nothing about the cluster
+		return version
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.entries[clusterName] = serverVersionEntry{
+		version: version,
+		expires: time.Now().Add(c.ttl),
+	}
+	return version
+}
+
+// query gets the version of the API server, giving up after the timeout of the cache since the requests to an
+// unreachable cluster may take much longer to fail, or once the context is cancelled
+func (c *serverVersionCache) query(ctx context.Context, client rest.Interface) ClusterVersion {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	body, err := client.Get().AbsPath("/version").Do(ctx).Raw()
+	if ctx.Err() == context.DeadlineExceeded {
+		return ClusterVersion{Error: fmt.Sprintf("timed out after %v querying the server version", c.timeout)}
+	} else if err != nil {
+		return ClusterVersion{Error: err.Error()}
+	}
+
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return ClusterVersion{Error: fmt.Sprintf("unable to parse the server version: %v", err)}
+	}
+	return ClusterVersion{GitVersion: info.GitVersion}
+}
+
+// retain drops the versions of the clusters which aren't listed anymore
+func (c *serverVersionCache) retain(versions map[string]ClusterVersion) {
+	c.Lock()
+	defer c.Unlock()
+	for clusterName := range c.entries {
+		if _, ok := versions[clusterName]; !ok {
+			delete(c.entries, clusterName)
+		}
+	}
+}
+
+// ServerVersions returns the versions of the API servers of the clusters, keyed by cluster name. The clusters whose
+// controllers are started on this replica are queried, the versions of the others are the ones recorded on their status
+// by the replica running their controllers, as the clusters are spread across the replicas.
+func (m *Manager) ServerVersions(ctx context.Context) (map[string]ClusterVersion, error) {
+	clusters, err := m.clusterLister.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		lock     sync.Mutex
+		wg       sync.WaitGroup
+		versions = map[string]ClusterVersion{}
+	)
+
+	m.controllers.Range(func(_, obj interface{}) bool {
+		rec := obj.(*record)
+		rec.Lock()
+		started := rec.started
+		rec.Unlock()
+		if !started {
+			return true
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			version := m.serverVersions.get(ctx, rec.cluster.ClusterName, rec.cluster.K8sClient.Discovery().RESTClient())
+			lock.Lock()
+			versions[rec.cluster.ClusterName] = version
+			lock.Unlock()
+		}()
+		return true
+	})
+	wg.Wait()
+
+	if ctx.Err() == nil {
+		m.serverVersions.retain(versions)
+	}
+
+	for _, cluster := range clusters {
+		if _, ok := versions[cluster.Name]; ok {
+			continue
+		}
+		if cluster.Status.Version != nil {
+			versions[cluster.Name] = ClusterVersion{GitVersion: cluster.Status.Version.GitVersion}
+		} else {
+			versions[cluster.Name] = ClusterVersion{Error: "the version of the server was not recorded yet"}
+		}
+	}
+	return versions, nil
+}
+
+type serverVersionsHandler struct {
+	sarClient      typedauthzv1.SubjectAccessReviewInterface
+	serverVersions func(ctx context.Context) (map[string]ClusterVersion, error)
+}
+
+// NewServerVersionsHandler serves the versions of the API servers of the clusters to the admins, whether they are
+// admins through their own global role bindings or the ones of their groups
+func NewServerVersionsHandler(scaledContext *config.ScaledContext, manager *Manager) http.Handler {
+	return &serverVersionsHandler{
+		sarClient:      scaledContext.K8sClient.AuthorizationV1().SubjectAccessReviews(),
+		serverVersions: manager.ServerVersions,
+	}
+}
+
+func (h *serverVersionsHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	isAdmin, err := sar.IsAdmin(req, h.sarClient)
+	if err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !isAdmin {
+		util.ReturnHTTPError(rw, req, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	versions, err := h.serverVersions(req.Context())
+	if err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(ServerVersions{Clusters: versions})
+}
@@ -0,0 +1,175 @@
+package clustermanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fakeCluster serves the version of its API server and counts the queries of it
+type fakeCluster struct {
+	*httptest.Server
+	queries int32
+}
+
+func newFakeCluster(gitVersion string, delay time.Duration) *fakeCluster {
+	c := &fakeCluster{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&c.queries, 1)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return
+		}
+		json.NewEncoder(rw).Encode(version.Info{GitVersion: gitVersion})
+	}))
+	return c
+}
+
+func newVersionRecord(clusterName string, cluster *fakeCluster, started bool) *record {
+	return &record{
+		cluster: &config.UserContext{
+			ClusterName: clusterName,
+			K8sClient:   kubernetes.NewForConfigOrDie(&rest.Config{Host: cluster.URL}),
+		},
+		started: started,
+	}
+}
+
+// newVersionsManager returns a manager listing the clusters, the versions of the ones given are recorded on their status
+func newVersionsManager(clusterVersions map[string]string) *Manager {
+	var clusters []*v3.Cluster
+	for name, gitVersion := range clusterVersions {
+		cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if gitVersion != "" {
+			cluster.Status.Version = &version.Info{GitVersion: gitVersion}
+		}
+		clusters = append(clusters, cluster)
+	}
+	return &Manager{
+		clusterLister: &fakes.ClusterListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.Cluster, error) {
+				return clusters, nil
+			},
+		},
+		serverVersions: newServerVersionCache(),
+	}
+}
+
+func TestServerVersions(t *testing.T) {
+	m := newVersionsManager(map[string]string{
+		"c-abcde": "v1.20.7+rke2r1",
+		"c-fghij": "",
+		"c-klmno": "v1.19.12",
+		"c-pqrst": "",
+		"c-uvwxy": "",
+	})
+	m.serverVersions.timeout = 100 * time.Millisecond
+
+	started := newFakeCluster("v1.20.8+rke2r1", 0)
+	defer started.Close()
+	unreachable := newFakeCluster("", 0)
+	unreachable.Close()
+	stopped := newFakeCluster("v1.19.12", 0)
+	defer stopped.Close()
+	slow := newFakeCluster("v1.21.2", time.Second)
+	defer slow.Close()
+	m.controllers.Store(types.UID("abcde"), newVersionRecord("c-abcde", started, true))
+	m.controllers.Store(types.UID("fghij"), newVersionRecord("c-fghij", unreachable, true))
+	m.controllers.Store(types.UID("klmno"), newVersionRecord("c-klmno", stopped, false))
+	m.controllers.Store(types.UID("pqrst"), newVersionRecord("c-pqrst", slow, true))
+
+	versions, err := m.ServerVersions(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, versions, 5)
+	assert.Equal(t, ClusterVersion{GitVersion: "v1.20.8+rke2r1"}, versions["c-abcde"], "expected the clusters started on the replica to be queried")
+	assert.NotEmpty(t, versions["c-fghij"].Error, "expected an unreachable cluster to be reported")
+	assert.Equal(t, ClusterVersion{Error: "timed out after 100ms querying the server version"}, versions["c-pqrst"])
+	assert.Equal(t, ClusterVersion{GitVersion: "v1.19.12"}, versions["c-klmno"], "expected the version recorded by another replica to be reported")
+	assert.Zero(t, atomic.LoadInt32(&stopped.queries), "expected the clusters which aren't started to be skipped")
+	assert.Equal(t, ClusterVersion{Error: "the version of the server was not recorded yet"}, versions["c-uvwxy"])
+
+	// the versions are cached
+	cached, err := m.ServerVersions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, versions, cached)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&started.queries))
+
+	m.serverVersions.ttl = 0
+	m.controllers.Delete(types.UID("pqrst"))
+	_, err = m.ServerVersions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&started.queries), "expected the versions to be queried again once expired")
+	assert.NotContains(t, m.serverVersions.entries, "c-pqrst", "expected the version of a removed cluster to be dropped")
+}
+
+func TestServerVersionsCancelled(t *testing.T) {
+	m := newVersionsManager(map[string]string{"c-pqrst": ""})
+	slow := newFakeCluster("v1.21.2", time.Minute)
+	defer slow.Close()
+	m.controllers.Store(types.UID("pqrst"), newVersionRecord("c-pqrst", slow, true))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	versions, err := m.ServerVersions(ctx)
+	require.NoError(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second), "expected the queries to stop once the request is cancelled")
+	assert.NotEmpty(t, versions["c-pqrst"].Error)
+	assert.Empty(t, m.serverVersions.entries, "expected the result of a cancelled query not to be cached")
+}
+
+func TestServerVersionsHandler(t *testing.T) {
+	h := &serverVersionsHandler{
+		sarClient: &fake.SubjectAccessReviews{Admins: map[string]bool{
+			"u-admin":               true,
+			"okta_group://platform": true,
+		}},
+		serverVersions: func(ctx context.Context) (map[string]ClusterVersion, error) {
+			return map[string]ClusterVersion{"c-abcde": {GitVersion: "v1.20.8+rke2r1"}}, nil
+		},
+	}
+
+	for user, code := range map[string]int{"": http.StatusForbidden, "u-user": http.StatusForbidden, "u-admin": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/v3/clusterversions", nil)
+		req.Header.Set("Impersonate-User", user)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, code, rec.Code, "unexpected status for user %q", user)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v3/clusterversions", nil)
+	req.Header.Set("Impersonate-User", "u-member")
+	req.Header.Add("Impersonate-Group", "okta_group://platform")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "expected the members of an admin group to be admins")
+
+	req = httptest.NewRequest(http.MethodGet, "/v3/clusterversions", nil)
+	req.Header.Set("Impersonate-User", "u-admin")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var versions ServerVersions
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &versions))
+	assert.Equal(t, ServerVersions{
+		Clusters: map[string]ClusterVersion{"c-abcde": {GitVersion: "v1.20.8+rke2r1"}},
+	}, versions)
+}
@@ -5,6 +5,7 @@
 	"sync"
 
 	"github.com/moby/locker"
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
 	"github.com/rancher/rancher/pkg/clusterrouter/proxy"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config/dialer"
@@ -19,15 +20,17 @@ type factory struct {
 	serverLock    *locker.Locker
 	servers       sync.Map
 	localConfig   *rest.Config
+	execAuth      *execauth.Resolver
 }
 
-func newFactory(localConfig *rest.Config, dialer dialer.Factory, lookup ClusterLookup, clusterLister v3.ClusterLister) *factory {
+func newFactory(localConfig *rest.Config, dialer dialer.Factory, lookup ClusterLookup, clusterLister v3.ClusterLister, execAuth *execauth.Resolver) *factory {
 	return &factory{
 		dialerFactory: dialer,
 		serverLock:    locker.New(),
 		clusterLookup: lookup,
 		clusterLister: clusterLister,
 		localConfig:   localConfig,
+		execAuth:      execAuth,
 	}
 }
 
@@ -73,5 +76,5 @@ func (s *factory) get(req *http.Request) (*v3.Cluster, http.Handler, error) {
 }
 
 func (s *factory) newServer(c *v3.Cluster) (server, error) {
-	return proxy.New(s.localConfig, c, s.clusterLister, s.dialerFactory)
+	return proxy.New(s.localConfig, c, s.clusterLister, s.dialerFactory, s.execAuth)
 }
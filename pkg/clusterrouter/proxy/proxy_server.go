@@ -9,11 +9,13 @@
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"reflect"
 	"strings"
 	"sync"
 
 	"github.com/rancher/norman/httperror"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
 	dialer2 "github.com/rancher/rancher/pkg/dialer"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/kontainer-engine/drivers/gke"
@@ -33,10 +35,14 @@ type RemoteService struct {
 	url       urlGetter
 	auth      authGetter
 
-	factory       dialer.Factory
-	clusterLister v3.ClusterLister
-	caCert        string
-	httpTransport *http.Transport
+	factory          dialer.Factory
+	clusterLister    v3.ClusterLister
+	caCert           string
+	execAuth         *v32.ClusterExecAuth
+	execApproval     string
+	execAuthResolver *execauth.Resolver
+	httpTransport    *http.Transport
+	roundTripper     http.RoundTripper
 }
 
 var (
@@ -61,11 +67,11 @@ func prefix(cluster *v3.Cluster) string {
 	return "/k8s/clusters/" + cluster.Name
 }
 
-func New(localConfig *rest.Config, cluster *v3.Cluster, clusterLister v3.ClusterLister, factory dialer.Factory) (*RemoteService, error) {
+func New(localConfig *rest.Config, cluster *v3.Cluster, clusterLister v3.ClusterLister, factory dialer.Factory, execAuth *execauth.Resolver) (*RemoteService, error) {
 	if cluster.Spec.Internal {
 		return NewLocal(localConfig, cluster)
 	}
-	return NewRemote(cluster, clusterLister, factory)
+	return NewRemote(cluster, clusterLister, factory, execAuth)
 }
 
 func NewLocal(localConfig *rest.Config, cluster *v3.Cluster) (*RemoteService, error) {
@@ -102,7 +108,7 @@ func NewLocal(localConfig *rest.Config, cluster *v3.Cluster) (*RemoteService, er
 	return rs, nil
 }
 
-func NewRemote(cluster *v3.Cluster, clusterLister v3.ClusterLister, factory dialer.Factory) (*RemoteService, error) {
+func NewRemote(cluster *v3.Cluster, clusterLister v3.ClusterLister, factory dialer.Factory, execAuth *execauth.Resolver) (*RemoteService, error) {
 	if !v32.ClusterConditionProvisioned.IsTrue(cluster) {
 		return nil, httperror.NewAPIError(httperror.ClusterUnavailable, "cluster not provisioned")
 	}
@@ -125,16 +131,21 @@ func NewRemote(cluster *v3.Cluster, clusterLister v3.ClusterLister, factory dial
 		if err != nil {
 			return "", err
 		}
+		if newCluster.Spec.ExecAuth != nil {
+			// the transport authenticates with the token of the exec credential plugin
+			return "", nil
+		}
 
 		return "Bearer " + newCluster.Status.ServiceAccountToken, nil
 	}
 
 	return &RemoteService{
-		cluster:       cluster,
-		url:           urlGetter,
-		auth:          authGetter,
-		clusterLister: clusterLister,
-		factory:       factory,
+		cluster:          cluster,
+		url:              urlGetter,
+		auth:             authGetter,
+		clusterLister:    clusterLister,
+		factory:          factory,
+		execAuthResolver: execAuth,
 	}, nil
 }
 
@@ -151,8 +162,9 @@ func (r *RemoteService) getTransport() (http.RoundTripper, error) {
 	r.Lock()
 	defer r.Unlock()
 
-	if r.httpTransport != nil && !r.cacertChanged(newCluster) {
-		return r.httpTransport, nil
+	if r.httpTransport != nil && !r.cacertChanged(newCluster) && reflect.DeepEqual(r.execAuth, newCluster.Spec.ExecAuth) &&
+		r.execApproval == newCluster.Annotations[execauth.ApprovalAnnotation] {
+		return r.roundTripper, nil
 	}
 
 	transport := &http.Transport{}
@@ -179,13 +191,21 @@ func (r *RemoteService) getTransport() (http.RoundTripper, error) {
 		}
 	}
 
+	roundTripper, err := r.execAuthResolver.WrapTransport(newCluster, transport)
+	if err != nil {
+		return nil, err
+	}
+
 	r.caCert = newCluster.Status.CACert
+	r.execAuth = newCluster.Spec.ExecAuth
+	r.execApproval = newCluster.Annotations[execauth.ApprovalAnnotation]
 	if r.httpTransport != nil {
 		r.httpTransport.CloseIdleConnections()
 	}
 	r.httpTransport = transport
+	r.roundTripper = roundTripper
 
-	return transport, nil
+	return roundTripper, nil
 }
 
 func (r *RemoteService) cacertChanged(cluster *v3.Cluster) bool {
@@ -243,7 +263,11 @@ func (r *RemoteService) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			er.Error(rw, req, err)
 			return
 		}
-		req.Header.Set("Authorization", token)
+		if token == "" {
+			req.Header.Del("Authorization")
+		} else {
+			req.Header.Set("Authorization", token)
+		}
 	}
 
 	if httpstream.IsUpgradeRequest(req) {
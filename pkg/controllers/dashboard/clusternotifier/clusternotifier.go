@@ -0,0 +1,210 @@
+package clusternotifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/features"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	conditionProvisioned = "Provisioned"
+	conditionUpdated     = "Updated"
+	conditionReady       = "Ready"
+)
+
+// condition is the subset of the conditions of the management and provisioning clusters the notifications are built from
+type condition struct {
+	Type               string
+	Status             string
+	Reason             string
+	Message            string
+	LastTransitionTime string
+}
+
+type handler struct {
+	mgmtClusters mgmtcontrollers.ClusterController
+	clusters     rocontrollers.ClusterController
+	dispatch     func(Destination, notification)
+	now          func() time.Time
+
+	lock sync.Mutex
+	// notified holds the last transition time of the conditions notified by cluster
+	notified map[string]map[string]string
+}
+
+// Register notifies the webhooks of the cluster-condition-webhooks setting when a cluster fails to provision or update,
+// or stays not ready for longer than the cluster-condition-webhook-ready-delay setting
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		mgmtClusters: clients.Mgmt.Cluster(),
+		clusters:     clients.Provisioning.Cluster(),
+		dispatch:     newDispatcher(clients.Core.Secret().Cache()).dispatch,
+		now:          time.Now,
+		notified:     map[string]map[string]string{},
+	}
+	clients.Mgmt.Cluster().OnChange(ctx, "cluster-condition-notifier", h.onMgmtClusterChange)
+	if features.ProvisioningV2.Enabled() {
+		clients.Provisioning.Cluster().OnChange(ctx, "cluster-condition-notifier", h.onClusterChange)
+	}
+}
+
+func (h *handler) onMgmtClusterChange(key string, obj *v3.Cluster) (*v3.Cluster, error) {
+	if obj == nil {
+		h.forget(key)
+		return nil, nil
+	}
+
+	var conditions []condition
+	for _, c := range obj.Status.Conditions {
+		conditions = append(conditions, condition{
+			Type:               string(c.Type),
+			Status:             string(c.Status),
+			Reason:             c.Reason,
+			Message:            c.Message,
+			LastTransitionTime: c.LastTransitionTime,
+		})
+	}
+
+	if after := h.notify(obj.Name, notification{
+		APIVersion:  "management.cattle.io/v3",
+		Kind:        "Cluster",
+		Name:        obj.Name,
+		ClusterName: obj.Name,
+		DisplayName: obj.Spec.DisplayName,
+	}, conditions); after > 0 {
+		h.mgmtClusters.EnqueueAfter(obj.Name, after)
+	}
+	return obj, nil
+}
+
+func (h *handler) onClusterChange(key string, obj *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	if obj == nil {
+		h.forget(key)
+		return nil, nil
+	}
+
+	var conditions []condition
+	for _, c := range obj.Status.Conditions {
+		conditions = append(conditions, condition{
+			Type:               c.Type,
+			Status:             string(c.Status),
+			Reason:             c.Reason,
+			Message:            c.Message,
+			LastTransitionTime: c.LastTransitionTime,
+		})
+	}
+
+	// the conditions copied from the management cluster have the same transition time, keying them by the management
+	// cluster notifies them once
+	id := key
+	if obj.Status.ClusterName != "" {
+		id = obj.Status.ClusterName
+	}
+	if after := h.notify(id, notification{
+		APIVersion:  "provisioning.cattle.io/v1",
+		Kind:        "Cluster",
+		Namespace:   obj.Namespace,
+		Name:        obj.Name,
+		ClusterName: obj.Status.ClusterName,
+	}, conditions); after > 0 {
+		h.clusters.EnqueueAfter(obj.Namespace, obj.Name, after)
+	}
+	return obj, nil
+}
+
+// notify dispatches the failed conditions of the cluster to the destinations, once per transition. It returns when the
+// cluster must be checked again for the conditions which haven't been failing for long enough yet.
+func (h *handler) notify(id string, target notification, conditions []condition) time.Duration {
+	dests, err := destinations()
+	if err != nil {
+		logrus.Errorf("[clusternotifier] %v", err)
+		return 0
+	}
+	if len(dests) == 0 {
+		return 0
+	}
+
+	var after time.Duration
+	for _, c := range conditions {
+		failed, wait := h.failed(c)
+		if wait > 0 && (after == 0 || wait < after) {
+			after = wait
+		}
+		if !h.transitioned(id, c, failed) {
+			continue
+		}
+
+		n := target
+		n.Condition = c.Type
+		n.Status = c.Status
+		n.Reason = c.Reason
+		n.Message = redact(c.Message)
+		n.LastTransitionTime = c.LastTransitionTime
+		for _, dest := range dests {
+			if dest.matches(c.Type) {
+				h.dispatch(dest, n)
+			}
+		}
+	}
+	return after
+}
+
+// failed returns whether the condition is failing, or how long it must still be false before it is
+func (h *handler) failed(c condition) (bool, time.Duration) {
+	if c.Status != "False" {
+		return false, 0
+	}
+
+	switch c.Type {
+	case conditionProvisioned:
+		return true, 0
+	case conditionUpdated:
+		// the message holds the error of the sync
+		return c.Message != "", 0
+	case conditionReady:
+		since, err := time.Parse(time.RFC3339, c.LastTransitionTime)
+		if err != nil {
+			return false, 0
+		}
+		delay := time.Duration(settings.ClusterConditionWebhookReadyDelay.GetInt()) * time.Minute
+		if elapsed := h.now().Sub(since); elapsed < delay {
+			return false, delay - elapsed
+		}
+		return true, 0
+	}
+	return false, 0
+}
+
+// transitioned records the failed conditions of the cluster, it returns whether the condition failed since it was last
+// notified. A condition failing again has another transition time.
+func (h *handler) transitioned(id string, c condition, failed bool) bool {
+	if !failed {
+		return false
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.notified[id] == nil {
+		h.notified[id] = map[string]string{}
+	} else if transition, ok := h.notified[id][c.Type]; ok && transition == c.LastTransitionTime {
+		return false
+	}
+	h.notified[id][c.Type] = c.LastTransitionTime
+	return true
+}
+
+func (h *handler) forget(id string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.notified, id)
+}
@@ -0,0 +1,183 @@
+package clusternotifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/genericcondition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeMgmtClusters struct {
+	mgmtcontrollers.ClusterController
+	enqueued map[string]time.Duration
+}
+
+func (f fakeMgmtClusters) EnqueueAfter(name string, duration time.Duration) {
+	f.enqueued[name] = duration
+}
+
+type dispatched struct {
+	dest Destination
+	n    notification
+}
+
+func setWebhooks(t *testing.T, webhooks []Destination, readyDelay string) func() {
+	data, err := json.Marshal(webhooks)
+	require.NoError(t, err)
+	oldWebhooks, oldDelay := settings.ClusterConditionWebhooks.Get(), settings.ClusterConditionWebhookReadyDelay.Get()
+	require.NoError(t, settings.ClusterConditionWebhooks.Set(string(data)))
+	require.NoError(t, settings.ClusterConditionWebhookReadyDelay.Set(readyDelay))
+	return func() {
+		settings.ClusterConditionWebhooks.Set(oldWebhooks)
+		settings.ClusterConditionWebhookReadyDelay.Set(oldDelay)
+	}
+}
+
+func newTestHandler(now time.Time) (*handler, *[]dispatched, fakeMgmtClusters) {
+	var result []dispatched
+	mgmtClusters := fakeMgmtClusters{enqueued: map[string]time.Duration{}}
+	return &handler{
+		mgmtClusters: mgmtClusters,
+		dispatch: func(dest Destination, n notification) {
+			result = append(result, dispatched{dest: dest, n: n})
+		},
+		now:      func() time.Time { return now },
+		notified: map[string]map[string]string{},
+	}, &result, mgmtClusters
+}
+
+func TestNotifyFailedConditions(t *testing.T) {
+	all := Destination{URL: "https://all.example.com"}
+	readyOnly := Destination{URL: "https://ready.example.com", Conditions: []string{conditionReady}}
+	defer setWebhooks(t, []Destination{all, readyOnly}, "10")()
+
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	h, result, mgmtClusters := newTestHandler(now)
+	cluster := &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"},
+		Spec:       v3.ClusterSpec{DisplayName: "test"},
+		Status: v3.ClusterStatus{Conditions: []v3.ClusterCondition{
+			{Type: "Provisioned", Status: "False", Message: "failed to provision with token=abcdef", LastTransitionTime: "2021-09-01T11:00:00Z"},
+			{Type: "Updated", Status: "False", LastTransitionTime: "2021-09-01T11:00:00Z"},
+			{Type: "Ready", Status: "False", LastTransitionTime: "2021-09-01T11:55:00Z"},
+			{Type: "Pending", Status: "False", Message: "pending", LastTransitionTime: "2021-09-01T11:00:00Z"},
+		}},
+	}
+
+	_, err := h.onMgmtClusterChange(cluster.Name, cluster)
+	require.NoError(t, err)
+	assert.Equal(t, []dispatched{{dest: all, n: notification{
+		APIVersion:         "management.cattle.io/v3",
+		Kind:               "Cluster",
+		Name:               "c-abcde",
+		ClusterName:        "c-abcde",
+		DisplayName:        "test",
+		Condition:          "Provisioned",
+		Status:             "False",
+		Message:            "failed to provision with token=[redacted]",
+		LastTransitionTime: "2021-09-01T11:00:00Z",
+	}}}, *result, "expected the updates without error and the clusters not ready for long enough not to be notified")
+	assert.Equal(t, 5*time.Minute, mgmtClusters.enqueued["c-abcde"], "expected the cluster to be checked again once not ready for long enough")
+
+	*result = nil
+	h.now = func() time.Time { return now.Add(5 * time.Minute) }
+	_, err = h.onMgmtClusterChange(cluster.Name, cluster)
+	require.NoError(t, err)
+	require.Len(t, *result, 2, "expected the failed provisioning to be notified once")
+	assert.Equal(t, "Ready", (*result)[0].n.Condition)
+	assert.Equal(t, all, (*result)[0].dest)
+	assert.Equal(t, readyOnly, (*result)[1].dest)
+
+	*result = nil
+	cluster.Status.Conditions[0].LastTransitionTime = "2021-09-01T12:04:00Z"
+	_, err = h.onMgmtClusterChange(cluster.Name, cluster)
+	require.NoError(t, err)
+	require.Len(t, *result, 1, "expected a condition failing again to be notified")
+	assert.Equal(t, "Provisioned", (*result)[0].n.Condition)
+}
+
+func TestNotifyProvisioningCluster(t *testing.T) {
+	defer setWebhooks(t, []Destination{{URL: "https://all.example.com"}}, "10")()
+
+	h, result, _ := newTestHandler(time.Now())
+	mgmtCluster := &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-m-abcdefgh"},
+		Status: v3.ClusterStatus{Conditions: []v3.ClusterCondition{
+			{Type: "Updated", Status: "False", Message: "sync failed", LastTransitionTime: "2021-09-01T11:00:00Z"},
+		}},
+	}
+	cluster := &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+		Status: rancherv1.ClusterStatus{
+			ClusterName: "c-m-abcdefgh",
+			Conditions: []genericcondition.GenericCondition{
+				{Type: "Updated", Status: "False", Message: "sync failed", LastTransitionTime: "2021-09-01T11:00:00Z"},
+				{Type: "Provisioned", Status: "False", Message: "waiting for etcd", LastTransitionTime: "2021-09-01T11:00:00Z"},
+			},
+		},
+	}
+
+	_, err := h.onMgmtClusterChange(mgmtCluster.Name, mgmtCluster)
+	require.NoError(t, err)
+	_, err = h.onClusterChange("fleet-default/test", cluster)
+	require.NoError(t, err)
+
+	require.Len(t, *result, 2, "expected the conditions copied from the management cluster to be notified once")
+	assert.Equal(t, "Updated", (*result)[0].n.Condition)
+	assert.Equal(t, notification{
+		APIVersion:         "provisioning.cattle.io/v1",
+		Kind:               "Cluster",
+		Namespace:          "fleet-default",
+		Name:               "test",
+		ClusterName:        "c-m-abcdefgh",
+		Condition:          "Provisioned",
+		Status:             "False",
+		Message:            "waiting for etcd",
+		LastTransitionTime: "2021-09-01T11:00:00Z",
+	}, (*result)[1].n)
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	received := make(chan notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get(SignatureHeader) != Sign([]byte("s3cr3t"), body) {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var n notification
+		json.Unmarshal(body, &n)
+		received <- n
+	}))
+	defer server.Close()
+	defer setWebhooks(t, []Destination{{URL: server.URL, SecretName: "webhook"}}, "10")()
+
+	h, _, _ := newTestHandler(time.Now())
+	h.dispatch = newTestDispatcher().dispatch
+	_, err := h.onMgmtClusterChange("c-abcde", &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"},
+		Status: v3.ClusterStatus{Conditions: []v3.ClusterCondition{
+			{Type: "Provisioned", Status: "False", Message: "failed", LastTransitionTime: "2021-09-01T11:00:00Z"},
+		}},
+	})
+	require.NoError(t, err)
+
+	select {
+	case n := <-received:
+		assert.Equal(t, "c-abcde", n.Name)
+		assert.Equal(t, "Provisioned", n.Condition)
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the webhook to be notified")
+	}
+}
@@ -0,0 +1,240 @@
+package clusternotifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// SignatureHeader holds the HMAC-SHA256 signature of the payload, as sha256=<hex>, when the destination has a secret
+	SignatureHeader = "X-Rancher-Signature"
+	// SigningKey is the key of the secret of a destination holding the HMAC key
+	SigningKey = "key"
+
+	breakerThreshold = 5
+	breakerCooldown  = 5 * time.Minute
+)
+
+// Destination is a webhook notified of the provisioning failures of clusters, the cluster-condition-webhooks setting
+// holds a JSON list of them.
+type Destination struct {
+	URL string `json:"url"`
+	// SecretName is the name of a secret of the cattle-system namespace whose key signs the payloads
+	SecretName string `json:"secretName,omitempty"`
+	// Conditions are the conditions notified among Provisioned, Updated and Ready, all of them when empty
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+func (d Destination) matches(conditionType string) bool {
+	if len(d.Conditions) == 0 {
+		return true
+	}
+	for _, c := range d.Conditions {
+		if c == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+func destinations() ([]Destination, error) {
+	value := settings.ClusterConditionWebhooks.Get()
+	if value == "" {
+		return nil, nil
+	}
+	var result []Destination
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return nil, fmt.Errorf("invalid value for setting %s: %w", settings.ClusterConditionWebhooks.Name, err)
+	}
+	return result, nil
+}
+
+// notification is the payload posted to the destinations
+type notification struct {
+	APIVersion         string `json:"apiVersion"`
+	Kind               string `json:"kind"`
+	Namespace          string `json:"namespace,omitempty"`
+	Name               string `json:"name"`
+	ClusterName        string `json:"clusterName,omitempty"`
+	DisplayName        string `json:"displayName,omitempty"`
+	Condition          string `json:"condition"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
+
+// breaker stops the deliveries to a destination failing repeatedly until its cooldown is over, the next delivery then
+// closes it again or reopens it
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+type dispatcher struct {
+	client  *http.Client
+	secrets corecontrollers.SecretCache
+	backoff wait.Backoff
+	now     func() time.Time
+
+	lock     sync.Mutex
+	breakers map[string]*breaker
+}
+
+func newDispatcher(secrets corecontrollers.SecretCache) *dispatcher {
+	return &dispatcher{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		secrets: secrets,
+		backoff: wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    5,
+		},
+		now:      time.Now,
+		breakers: map[string]*breaker{},
+	}
+}
+
+// dispatch delivers the notification to the destination in the background
+func (d *dispatcher) dispatch(dest Destination, n notification) {
+	go func() {
+		if err := d.deliver(dest, n); err != nil {
+			logrus.Errorf("[clusternotifier] failed to notify %s of condition %s of cluster %s: %v", redactURL(dest.URL), n.Condition, n.Name, err)
+		}
+	}()
+}
+
+func (d *dispatcher) deliver(dest Destination, n notification) error {
+	if !d.allow(dest.URL) {
+		return fmt.Errorf("deliveries suspended after %d consecutive failures", breakerThreshold)
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	var key []byte
+	if dest.SecretName != "" {
+		secret, err := d.secrets.Get(namespace.System, dest.SecretName)
+		if err != nil {
+			return fmt.Errorf("failed to get secret [%s/%s] signing the payloads: %w", namespace.System, dest.SecretName, err)
+		}
+		key = secret.Data[SigningKey]
+	}
+
+	var lastErr error
+	err = wait.ExponentialBackoff(d.backoff, func() (bool, error) {
+		retry, err := d.post(dest.URL, body, key)
+		if err != nil && !retry {
+			return false, err
+		}
+		lastErr = err
+		return err == nil, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		err = lastErr
+	}
+	d.record(dest.URL, err == nil)
+	return err
+}
+
+// post sends the payload, it returns whether the failed deliveries may be retried
+func (d *dispatcher) post(url string, body, key []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(key) > 0 {
+		req.Header.Set(SignatureHeader, Sign(key, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+func (d *dispatcher) allow(url string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	b, ok := d.breakers[url]
+	return !ok || b.failures < breakerThreshold || !d.now().Before(b.openUntil)
+}
+
+func (d *dispatcher) record(url string, delivered bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if delivered {
+		delete(d.breakers, url)
+		return
+	}
+	b, ok := d.breakers[url]
+	if !ok {
+		b = &breaker{}
+		d.breakers[url] = b
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = d.now().Add(breakerCooldown)
+	}
+}
+
+// Sign returns the signature of the payload sent in the SignatureHeader
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var urlCredentials = regexp.MustCompile(`(://)[^/\s:@]+:[^/\s@]+@`)
+
+var redactions = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`), "${1}[redacted]"},
+	{regexp.MustCompile(`(?i)(--token[\s=]+)[^\s"',]+`), "${1}[redacted]"},
+	{regexp.MustCompile(`(?i)((?:token|password|secret|key)["']?\s*[:=]\s*["']?)[^\s"',]+`), "${1}[redacted]"},
+	{urlCredentials, "${1}[redacted]@"},
+	{regexp.MustCompile(`\b[bcdfghjklmnpqrstvwxz2456789]{54,64}\b`), "[redacted]"},
+}
+
+// redact hides the tokens, passwords and credentials of URLs the condition messages may contain
+func redact(message string) string {
+	for _, r := range redactions {
+		message = r.re.ReplaceAllString(message, r.replacement)
+	}
+	return message
+}
+
+func redactURL(url string) string {
+	return urlCredentials.ReplaceAllString(url, "${1}[redacted]@")
+}
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/wrangler"
@@ -26,6 +27,8 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 	}
 	clients.Mgmt.ClusterRegistrationToken().OnChange(ctx, "cluster-registration-token", h.onChange)
 	clients.Mgmt.Cluster().OnChange(ctx, "cluster-registration-token-trigger", h.onClusterChange)
+	clients.Mgmt.ClusterRegistrationToken().OnChange(ctx, "cluster-registration-token-rotation",
+		newRotator(clients.Mgmt.ClusterRegistrationToken()).onChange)
 
 }
 
@@ -69,6 +72,7 @@ func (h *handler) onChange(key string, obj *v3.ClusterRegistrationToken) (_ *v3.
 	if err != nil {
 		return nil, err
 	}
+	obj.Status.TokenIssuedAt = time.Now().UTC().Format(time.RFC3339)
 
 	return h.clusterRegistrationTokenController.Update(obj)
 }
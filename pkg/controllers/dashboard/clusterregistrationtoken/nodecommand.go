@@ -0,0 +1,159 @@
+package clusterregistrationtoken
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const defaultNodeCommandVolumes = "-v /etc/kubernetes:/etc/kubernetes -v /var/run:/var/run"
+
+var (
+	// safeValue matches values that are passed through a shell unquoted without being interpreted
+	safeValue   = regexp.MustCompile(`^[A-Za-z0-9_./:=,@+%~-]+$`)
+	envName     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	capability  = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	mountOption = regexp.MustCompile(`^(ro|rw|z|Z|shared|rshared|slave|rslave|private|rprivate|nocopy|cached|delegated|consistent)$`)
+
+	// allowedExtraFlags are the docker run flags that may be added to the node command. Each flag must be
+	// given in --flag=value form.
+	allowedExtraFlags = map[string]bool{
+		"--cgroupns":      true,
+		"--cgroup-parent": true,
+		"--pid":           true,
+		"--ipc":           true,
+		"--uts":           true,
+		"--userns":        true,
+		"--security-opt":  true,
+		"--device":        true,
+		"--ulimit":        true,
+		"--dns":           true,
+		"--add-host":      true,
+	}
+)
+
+// ValidateAgentNodeCommandCustomization returns an error if the customization contains anything that
+// could change the node command beyond the allowed overrides.
+func ValidateAgentNodeCommandCustomization(c *v32.AgentNodeCommandCustomization) error {
+	if c == nil {
+		return nil
+	}
+
+	for _, env := range c.Env {
+		if !envName.MatchString(env.Name) {
+			return fmt.Errorf("invalid env var name [%s]", env.Name)
+		}
+		if env.Value != "" && !safeValue.MatchString(env.Value) {
+			return fmt.Errorf("env var [%s] contains characters that are not allowed", env.Name)
+		}
+	}
+
+	for _, mount := range c.VolumeMounts {
+		if err := validateVolumeMount(mount); err != nil {
+			return err
+		}
+	}
+
+	for _, capAdd := range c.CapAdd {
+		if !capability.MatchString(capAdd) {
+			return fmt.Errorf("invalid capability [%s]", capAdd)
+		}
+	}
+
+	for _, flag := range c.ExtraFlags {
+		parts := strings.SplitN(flag, "=", 2)
+		if !allowedExtraFlags[parts[0]] {
+			return fmt.Errorf("docker run flag [%s] is not allowed", parts[0])
+		}
+		if len(parts) != 2 || !safeValue.MatchString(parts[1]) {
+			return fmt.Errorf("docker run flag [%s] must be set as %s=<value> with a valid value", parts[0], parts[0])
+		}
+	}
+
+	return nil
+}
+
+// ValidateAgentImageOverride returns an error if the agent image overriding the agent-image setting for the node
+// commands of a cluster is set but blank, or can't be passed to the node commands unquoted.
+func ValidateAgentImageOverride(agentImage string) error {
+	if agentImage == "" {
+		return nil
+	}
+	if strings.TrimSpace(agentImage) == "" {
+		return fmt.Errorf("agent image may not be blank when set")
+	}
+	if !safeValue.MatchString(agentImage) {
+		return fmt.Errorf("agent image [%s] must be an image reference without spaces or special characters", agentImage)
+	}
+	return nil
+}
+
+// ValidateDockerAgentEnvVars returns an error if an agent env var takes its value from another object, since the
+// docker node command can only pass literal values to the agent.
+func ValidateDockerAgentEnvVars(envVars []corev1.EnvVar) error {
+	for _, envVar := range envVars {
+		if envVar.ValueFrom != nil {
+			return fmt.Errorf("agent env var [%s] may not use valueFrom, the docker node command only supports literal values", envVar.Name)
+		}
+	}
+	return nil
+}
+
+func validateVolumeMount(mount string) error {
+	parts := strings.Split(mount, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("volume mount [%s] must be in host-path:container-path[:options] form", mount)
+	}
+	for _, p := range parts[:2] {
+		if !path.IsAbs(p) || !safeValue.MatchString(p) {
+			return fmt.Errorf("volume mount [%s] must use absolute paths without special characters", mount)
+		}
+	}
+	if len(parts) == 3 {
+		for _, opt := range strings.Split(parts[2], ",") {
+			if !mountOption.MatchString(opt) {
+				return fmt.Errorf("volume mount [%s] has invalid option [%s]", mount, opt)
+			}
+		}
+	}
+	return nil
+}
+
+// nodeCommandFlags renders the docker run flags of the node command for the cluster, applying
+// spec.agentNodeCommandCustomization on top of the defaults.
+func nodeCommandFlags(cluster *v3.Cluster) (string, error) {
+	var custom *v32.AgentNodeCommandCustomization
+	if cluster != nil {
+		custom = cluster.Spec.AgentNodeCommandCustomization
+	}
+	if err := ValidateAgentNodeCommandCustomization(custom); err != nil {
+		return "", fmt.Errorf("invalid agentNodeCommandCustomization for cluster [%s]: %w", cluster.Name, err)
+	}
+	if custom == nil {
+		return "--privileged --restart=unless-stopped --net=host " + defaultNodeCommandVolumes, nil
+	}
+
+	var flags []string
+	if len(custom.CapAdd) == 0 {
+		flags = append(flags, "--privileged")
+	}
+	for _, capAdd := range custom.CapAdd {
+		flags = append(flags, "--cap-add="+capAdd)
+	}
+	flags = append(flags, "--restart=unless-stopped", "--net=host", defaultNodeCommandVolumes)
+	for _, mount := range custom.VolumeMounts {
+		flags = append(flags, "-v "+mount)
+	}
+	flags = append(flags, custom.ExtraFlags...)
+	for _, env := range custom.Env {
+		if env.Value != "" {
+			flags = append(flags, fmt.Sprintf("-e \"%s=%s\"", env.Name, env.Value))
+		}
+	}
+	return strings.Join(flags, " "), nil
+}
@@ -0,0 +1,191 @@
+package clusterregistrationtoken
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestCluster(custom *v32.AgentNodeCommandCustomization) *v32.Cluster {
+	return &v32.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-test"},
+		Spec: v32.ClusterSpec{
+			ClusterSpecBase: v32.ClusterSpecBase{
+				AgentNodeCommandCustomization: custom,
+			},
+		},
+	}
+}
+
+func TestNodeCommandFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		custom   *v32.AgentNodeCommandCustomization
+		expected string
+	}{
+		{
+			name:     "defaults",
+			expected: "--privileged --restart=unless-stopped --net=host -v /etc/kubernetes:/etc/kubernetes -v /var/run:/var/run",
+		},
+		{
+			name: "cap-add replaces privileged",
+			custom: &v32.AgentNodeCommandCustomization{
+				CapAdd: []string{"SYS_ADMIN", "NET_ADMIN"},
+			},
+			expected: "--cap-add=SYS_ADMIN --cap-add=NET_ADMIN --restart=unless-stopped --net=host -v /etc/kubernetes:/etc/kubernetes -v /var/run:/var/run",
+		},
+		{
+			name: "all overrides",
+			custom: &v32.AgentNodeCommandCustomization{
+				Env:          []v1.EnvVar{{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"}, {Name: "EMPTY"}},
+				VolumeMounts: []string{"/etc/pki/custom:/etc/pki/custom:ro"},
+				ExtraFlags:   []string{"--cgroupns=host", "--pid=host"},
+			},
+			expected: "--privileged --restart=unless-stopped --net=host -v /etc/kubernetes:/etc/kubernetes -v /var/run:/var/run " +
+				"-v /etc/pki/custom:/etc/pki/custom:ro --cgroupns=host --pid=host -e \"HTTP_PROXY=http://proxy.example.com:3128\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := nodeCommandFlags(newTestCluster(tt.custom))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, flags)
+		})
+	}
+}
+
+func TestNodeCommandUsesCustomization(t *testing.T) {
+	original := settings.ServerURL.Get()
+	defer settings.ServerURL.Set(original)
+	require.NoError(t, settings.ServerURL.Set("https://rancher.example.com"))
+
+	cmd, err := NodeCommand("token", newTestCluster(&v32.AgentNodeCommandCustomization{
+		CapAdd: []string{"SYS_ADMIN"},
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "sudo docker run -d --cap-add=SYS_ADMIN --restart=unless-stopped --net=host")
+	assert.NotContains(t, cmd, "--privileged")
+	assert.Contains(t, cmd, "--server https://rancher.example.com --token token")
+
+	_, err = NodeCommand("token", newTestCluster(&v32.AgentNodeCommandCustomization{
+		ExtraFlags: []string{"--pid=host;rm"},
+	}))
+	assert.Error(t, err)
+}
+
+func TestValidateAgentNodeCommandCustomization(t *testing.T) {
+	tests := []struct {
+		name    string
+		custom  *v32.AgentNodeCommandCustomization
+		wantErr bool
+	}{
+		{name: "nil"},
+		{
+			name: "valid",
+			custom: &v32.AgentNodeCommandCustomization{
+				Env:          []v1.EnvVar{{Name: "NO_PROXY", Value: "localhost,127.0.0.1,.svc"}},
+				VolumeMounts: []string{"/opt/ca:/opt/ca", "/etc/pki:/etc/pki:ro,z"},
+				CapAdd:       []string{"SYS_ADMIN"},
+				ExtraFlags:   []string{"--security-opt=apparmor=unconfined", "--ulimit=nofile=65536:65536"},
+			},
+		},
+		{
+			name:    "flag not in allowlist",
+			custom:  &v32.AgentNodeCommandCustomization{ExtraFlags: []string{"--entrypoint=/bin/sh"}},
+			wantErr: true,
+		},
+		{
+			name:    "flag without value",
+			custom:  &v32.AgentNodeCommandCustomization{ExtraFlags: []string{"--pid"}},
+			wantErr: true,
+		},
+		{
+			name:    "flag with command substitution",
+			custom:  &v32.AgentNodeCommandCustomization{ExtraFlags: []string{"--dns=$(id)"}},
+			wantErr: true,
+		},
+		{
+			name:    "flag with whitespace",
+			custom:  &v32.AgentNodeCommandCustomization{ExtraFlags: []string{"--pid=host --privileged"}},
+			wantErr: true,
+		},
+		{
+			name:    "env value with quote",
+			custom:  &v32.AgentNodeCommandCustomization{Env: []v1.EnvVar{{Name: "FOO", Value: `bar" && reboot`}}},
+			wantErr: true,
+		},
+		{
+			name:    "env name with metacharacter",
+			custom:  &v32.AgentNodeCommandCustomization{Env: []v1.EnvVar{{Name: "FOO;BAR", Value: "baz"}}},
+			wantErr: true,
+		},
+		{
+			name:    "relative volume mount",
+			custom:  &v32.AgentNodeCommandCustomization{VolumeMounts: []string{"certs:/certs"}},
+			wantErr: true,
+		},
+		{
+			name:    "volume mount with pipe",
+			custom:  &v32.AgentNodeCommandCustomization{VolumeMounts: []string{"/certs:/certs|sh"}},
+			wantErr: true,
+		},
+		{
+			name:    "volume mount with unknown option",
+			custom:  &v32.AgentNodeCommandCustomization{VolumeMounts: []string{"/certs:/certs:exec"}},
+			wantErr: true,
+		},
+		{
+			name:    "lowercase capability",
+			custom:  &v32.AgentNodeCommandCustomization{CapAdd: []string{"all`id`"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAgentNodeCommandCustomization(tt.custom)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNodeCommandRefusesAgentEnvVarsFromSecrets(t *testing.T) {
+	original := settings.ServerURL.Get()
+	defer settings.ServerURL.Set(original)
+	require.NoError(t, settings.ServerURL.Set("https://rancher.example.com"))
+
+	cluster := newTestCluster(nil)
+	cluster.Spec.AgentEnvVars = []v1.EnvVar{{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"}}
+	cmd, err := NodeCommand("token", cluster)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "-e \"HTTP_PROXY=http://proxy.example.com:3128\"")
+
+	cluster.Spec.AgentEnvVars = append(cluster.Spec.AgentEnvVars, v1.EnvVar{
+		Name: "PROXY_PASSWORD",
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "proxy"}, Key: "password"},
+		},
+	})
+	_, err = NodeCommand("token", cluster)
+	assert.EqualError(t, err, "invalid agentEnvVars for cluster [c-test]: agent env var [PROXY_PASSWORD] may not use valueFrom, the docker node command only supports literal values")
+	assert.Equal(t, "HTTP_PROXY=\"http://proxy.example.com:3128\"", AgentEnvVars(cluster, false),
+		"expected the env vars from secrets to be left out of the RKE2 node command")
+}
+
+func TestValidateAgentImageOverride(t *testing.T) {
+	assert.NoError(t, ValidateAgentImageOverride(""), "expected an unset override to use the setting")
+	assert.NoError(t, ValidateAgentImageOverride("registry.example.com/rancher/rancher-agent:v2.6.0-patched"))
+	assert.NoError(t, ValidateAgentImageOverride("rancher/rancher-agent@sha256:0123456789abcdef"))
+	assert.EqualError(t, ValidateAgentImageOverride("  "), "agent image may not be blank when set")
+	assert.Error(t, ValidateAgentImageOverride("rancher/rancher-agent:v2.6.0; rm -rf /"))
+}
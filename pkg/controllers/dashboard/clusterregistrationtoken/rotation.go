@@ -0,0 +1,139 @@
+package clusterregistrationtoken
+
+import (
+	"strconv"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v32 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/randomtoken"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// rotateTokenAnnotation requests the token to be rotated regardless of its TTL
+const rotateTokenAnnotation = "management.cattle.io/rotate-token"
+
+// rotator rotates the token of cluster registration tokens without interrupting the agents using it. A rotation goes
+// through three phases:
+//  1. a next token is generated and accepted along with the token, agents are reconfigured with the next token
+//  2. after the grace period the next token replaces the token, the replaced token is kept as the previous token
+//  3. after another grace period the previous token expires and is no longer accepted
+type rotator struct {
+	clusterRegistrationTokenController v32.ClusterRegistrationTokenController
+	now                                func() time.Time
+	ttl                                func() time.Duration
+	grace                              func() time.Duration
+}
+
+func newRotator(clusterRegistrationTokens v32.ClusterRegistrationTokenController) *rotator {
+	return &rotator{
+		clusterRegistrationTokenController: clusterRegistrationTokens,
+		now:                                time.Now,
+		ttl: func() time.Duration {
+			return secondsSetting(settings.ClusterRegistrationTokenTTL)
+		},
+		grace: func() time.Duration {
+			return secondsSetting(settings.ClusterRegistrationTokenGrace)
+		},
+	}
+}
+
+func secondsSetting(setting settings.Setting) time.Duration {
+	seconds, err := strconv.Atoi(setting.Get())
+	if err != nil || seconds < 0 {
+		logrus.Errorf("[clusterregistrationtoken] invalid value %q for setting %s, expected a number of seconds", setting.Get(), setting.Name)
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (r *rotator) onChange(key string, obj *v3.ClusterRegistrationToken) (*v3.ClusterRegistrationToken, error) {
+	if obj == nil || obj.DeletionTimestamp != nil || obj.Status.Token == "" {
+		return obj, nil
+	}
+
+	now := r.now().UTC()
+	grace := r.grace()
+	ttl := r.ttl()
+
+	newObj := obj.DeepCopy()
+	status := &newObj.Status
+
+	if status.PreviousToken != "" && !now.Before(parseTime(status.PreviousTokenExpiresAt, now)) {
+		logrus.Infof("[clusterregistrationtoken] previous token of %s/%s expired", obj.Namespace, obj.Name)
+		status.PreviousToken = ""
+		status.PreviousTokenExpiresAt = ""
+	}
+
+	if status.NextToken != "" && !now.Before(parseTime(status.NextTokenIssuedAt, now).Add(grace)) {
+		logrus.Infof("[clusterregistrationtoken] promoting next token of %s/%s", obj.Namespace, obj.Name)
+		newObj.PromoteToken(status.NextToken, now, grace)
+	}
+
+	_, requested := newObj.Annotations[rotateTokenAnnotation]
+	expired := ttl > 0 && !now.Before(issuedAt(newObj).Add(ttl))
+	if status.NextToken == "" && (requested || expired) {
+		logrus.Infof("[clusterregistrationtoken] rotating token of %s/%s", obj.Namespace, obj.Name)
+		token, err := randomtoken.Generate()
+		if err != nil {
+			return obj, err
+		}
+		status.NextToken = token
+		status.NextTokenIssuedAt = now.Format(time.RFC3339)
+		delete(newObj.Annotations, rotateTokenAnnotation)
+	}
+
+	if !equality.Semantic.DeepEqual(obj, newObj) {
+		var err error
+		if obj, err = r.clusterRegistrationTokenController.Update(newObj); err != nil {
+			return obj, err
+		}
+	}
+
+	if after, ok := nextRotationDeadline(obj, now, ttl, grace); ok {
+		r.clusterRegistrationTokenController.EnqueueAfter(obj.Namespace, obj.Name, after)
+	}
+	return obj, nil
+}
+
+// nextRotationDeadline returns the time until the next phase of the rotation of the token is due
+func nextRotationDeadline(obj *v3.ClusterRegistrationToken, now time.Time, ttl, grace time.Duration) (time.Duration, bool) {
+	var deadlines []time.Time
+	if obj.Status.PreviousToken != "" {
+		deadlines = append(deadlines, parseTime(obj.Status.PreviousTokenExpiresAt, now))
+	}
+	if obj.Status.NextToken != "" {
+		deadlines = append(deadlines, parseTime(obj.Status.NextTokenIssuedAt, now).Add(grace))
+	} else if ttl > 0 {
+		deadlines = append(deadlines, issuedAt(obj).Add(ttl))
+	}
+
+	if len(deadlines) == 0 {
+		return 0, false
+	}
+	next := deadlines[0]
+	for _, deadline := range deadlines[1:] {
+		if deadline.Before(next) {
+			next = deadline
+		}
+	}
+	if after := next.Sub(now); after > time.Second {
+		return after, true
+	}
+	return time.Second, true
+}
+
+func issuedAt(obj *v3.ClusterRegistrationToken) time.Time {
+	return parseTime(obj.Status.TokenIssuedAt, obj.CreationTimestamp.Time)
+}
+
+// parseTime parses an RFC3339 time, returning def if it isn't set or is invalid
+func parseTime(value string, def time.Time) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return def
+	}
+	return t
+}
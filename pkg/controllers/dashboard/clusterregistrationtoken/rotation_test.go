@@ -0,0 +1,141 @@
+package clusterregistrationtoken
+
+import (
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v32 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeClusterRegistrationTokenController struct {
+	v32.ClusterRegistrationTokenController
+	updates      int
+	enqueueAfter time.Duration
+}
+
+func (f *fakeClusterRegistrationTokenController) Update(obj *v3.ClusterRegistrationToken) (*v3.ClusterRegistrationToken, error) {
+	f.updates++
+	return obj, nil
+}
+
+func (f *fakeClusterRegistrationTokenController) EnqueueAfter(namespace, name string, duration time.Duration) {
+	f.enqueueAfter = duration
+}
+
+type rotationTest struct {
+	rotator    *rotator
+	controller *fakeClusterRegistrationTokenController
+	now        time.Time
+}
+
+func newRotationTest(ttl, grace time.Duration) *rotationTest {
+	r := &rotationTest{
+		controller: &fakeClusterRegistrationTokenController{},
+		now:        time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	r.rotator = &rotator{
+		clusterRegistrationTokenController: r.controller,
+		now:                                func() time.Time { return r.now },
+		ttl:                                func() time.Duration { return ttl },
+		grace:                              func() time.Duration { return grace },
+	}
+	return r
+}
+
+func newToken(issuedAt time.Time, annotations map[string]string) *v3.ClusterRegistrationToken {
+	return &v3.ClusterRegistrationToken{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c-1", Name: "default-token", Annotations: annotations},
+		Status: v3.ClusterRegistrationTokenStatus{
+			Token:         "old",
+			TokenIssuedAt: issuedAt.Format(time.RFC3339),
+		},
+	}
+}
+
+func TestRotationRequestedByAnnotation(t *testing.T) {
+	r := newRotationTest(0, time.Hour)
+
+	crt, err := r.rotator.onChange("", newToken(r.now, map[string]string{rotateTokenAnnotation: "true"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "old", crt.Status.Token)
+	assert.NotEmpty(t, crt.Status.NextToken)
+	assert.Equal(t, crt.Status.NextToken, crt.AgentToken(), "expected agents to be switched to the next token")
+	assert.NotContains(t, crt.Annotations, rotateTokenAnnotation)
+	assert.Equal(t, time.Hour, r.controller.enqueueAfter, "expected the promotion to be scheduled after the grace period")
+}
+
+func TestRotationNotDue(t *testing.T) {
+	r := newRotationTest(24*time.Hour, time.Hour)
+
+	crt, err := r.rotator.onChange("", newToken(r.now.Add(-time.Hour), nil))
+	require.NoError(t, err)
+
+	assert.Empty(t, crt.Status.NextToken)
+	assert.Zero(t, r.controller.updates)
+	assert.Equal(t, 23*time.Hour, r.controller.enqueueAfter)
+}
+
+func TestRotationDisabled(t *testing.T) {
+	r := newRotationTest(0, time.Hour)
+
+	crt, err := r.rotator.onChange("", newToken(r.now.Add(-365*24*time.Hour), nil))
+	require.NoError(t, err)
+
+	assert.Empty(t, crt.Status.NextToken)
+	assert.Zero(t, r.controller.updates)
+	assert.Zero(t, r.controller.enqueueAfter)
+}
+
+// TestRotationInFlightConnections follows an agent connected with the old token through a rotation triggered by the
+// TTL, the old token must be accepted until the previous token expires
+func TestRotationInFlightConnections(t *testing.T) {
+	r := newRotationTest(24*time.Hour, time.Hour)
+	start := r.now
+
+	crt, err := r.rotator.onChange("", newToken(r.now.Add(-24*time.Hour), nil))
+	require.NoError(t, err)
+	next := crt.Status.NextToken
+	require.NotEmpty(t, next)
+	assert.True(t, crt.AcceptsToken("old", r.now), "expected the old token to be accepted while agents switch")
+	assert.True(t, crt.AcceptsToken(next, r.now))
+
+	// the grace period passes, the next token is promoted
+	r.now = start.Add(time.Hour)
+	crt, err = r.rotator.onChange("", crt)
+	require.NoError(t, err)
+	assert.Equal(t, next, crt.Status.Token)
+	assert.Empty(t, crt.Status.NextToken)
+	assert.Equal(t, "old", crt.Status.PreviousToken)
+	assert.True(t, crt.AcceptsToken("old", r.now.Add(59*time.Minute)), "expected the old token to be accepted until it expires")
+	assert.False(t, crt.AcceptsToken("old", r.now.Add(time.Hour)))
+	assert.Equal(t, time.Hour, r.controller.enqueueAfter)
+
+	// the previous token expires
+	r.now = start.Add(2 * time.Hour)
+	crt, err = r.rotator.onChange("", crt)
+	require.NoError(t, err)
+	assert.Empty(t, crt.Status.PreviousToken)
+	assert.False(t, crt.AcceptsToken("old", r.now))
+	assert.True(t, crt.AcceptsToken(next, r.now))
+	assert.Equal(t, []string{next}, crt.Tokens())
+	assert.Equal(t, 23*time.Hour, r.controller.enqueueAfter, "expected the next rotation to be scheduled from the promotion")
+}
+
+func TestAcceptsToken(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	crt := &v3.ClusterRegistrationToken{Status: v3.ClusterRegistrationTokenStatus{
+		Token:                  "current",
+		PreviousToken:          "previous",
+		PreviousTokenExpiresAt: "invalid",
+	}}
+
+	assert.True(t, crt.AcceptsToken("current", now))
+	assert.False(t, crt.AcceptsToken("previous", now), "expected a previous token without a valid expiry to be rejected")
+	assert.False(t, crt.AcceptsToken("", now), "expected an empty token to be rejected")
+	assert.False(t, crt.AcceptsToken("other", now))
+}
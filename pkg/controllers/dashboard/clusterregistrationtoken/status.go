@@ -16,21 +16,27 @@
 )
 
 const (
-	commandFormat                 = "kubectl apply -f %s"
-	insecureCommandFormat         = "curl --insecure -sfL %s | kubectl apply -f -"
-	nodeCommandFormat             = "sudo docker run -d --privileged --restart=unless-stopped --net=host -v /etc/kubernetes:/etc/kubernetes -v /var/run:/var/run %s %s --server %s --token %s%s"
-	shareMntCommandFormat         = "agent --node-name %s --server %s --token %s%s --no-register --only-write-certs"
-	rke2NodeCommandFormat         = "curl -fL %s | sudo %s sh -s - --server %s --token %s%s"
-	rke2InsecureNodeCommandFormat = "curl --insecure -fL %s | sudo %s sh -s - --server %s --token %s%s"
-	loginCommandFormat            = "echo \"%s\" | sudo docker login --username %s --password-stdin %s"
-	windowsNodeCommandFormat      = `PowerShell -NoLogo -NonInteractive -Command "& {docker run -v c:\:c:\host %s%s bootstrap --server %s --token %s%s%s | iex}"`
+	commandFormat                       = "kubectl apply -f %s"
+	insecureCommandFormat               = "curl --insecure -sfL %s | kubectl apply -f -"
+	nodeCommandFormat                   = "sudo docker run -d %s %s %s --server %s --token %s%s"
+	shareMntCommandFormat               = "agent --node-name %s --server %s --token %s%s --no-register --only-write-certs"
+	rke2NodeCommandFormat               = "curl -fL %s | sudo %s sh -s - --server %s --token %s%s"
+	rke2InsecureNodeCommandFormat       = "curl --insecure -fL %s | sudo %s sh -s - --server %s --token %s%s"
+	rke2PinnedNodeCommandFormat         = "curl -fL %s -o system-agent-install.sh && echo %s | sha256sum -c - && sudo %s sh system-agent-install.sh --server %s --token %s%s"
+	rke2InsecurePinnedNodeCommandFormat = "curl --insecure -fL %s -o system-agent-install.sh && echo %s | sha256sum -c - && sudo %s sh system-agent-install.sh --server %s --token %s%s"
+	loginCommandFormat                  = "echo \"%s\" | sudo docker login --username %s --password-stdin %s"
+	windowsNodeCommandFormat            = `PowerShell -NoLogo -NonInteractive -Command "& {docker run -v c:\:c:\host %s%s bootstrap --server %s --token %s%s%s | iex}"`
 )
 
-func (h *handler) isRKE2(clusterID string) bool {
-	cluster, err := h.clusters.Get(clusterID)
-	if err != nil {
-		return false
-	}
+// Node command OS and runtimes, see NodeCommandFor
+const (
+	OSLinux       = "linux"
+	OSWindows     = "windows"
+	RuntimeDocker = "docker"
+	RuntimeRKE2   = "rke2"
+)
+
+func isRKE2(cluster *v3.Cluster) bool {
 	return cluster.Annotations["objectset.rio.cattle.io/owner-gvk"] == "provisioning.cattle.io/v1, Kind=Cluster"
 }
 
@@ -40,14 +46,14 @@ func (h *handler) assignStatus(crt *v32.ClusterRegistrationToken) (v32.ClusterRe
 		ca = " --ca-checksum " + ca
 	}
 
-	token := crt.Status.Token
+	// the commands register the agents with the token they should use, the next one while the token is rotated
+	token := crt.AgentToken()
 	clusterID := convert.ToString(crt.Spec.ClusterName)
 	if token == "" {
 		return crt.Status, nil
 	}
 
 	crtStatus := crt.Status.DeepCopy()
-	crtStatus.Token = token
 
 	url, err := getURL(token, clusterID)
 	if err != nil {
@@ -72,45 +78,178 @@ func (h *handler) assignStatus(crt *v32.ClusterRegistrationToken) (v32.ClusterRe
 		return crt.Status, err
 	}
 
-	agentImage := image.ResolveWithCluster(settings.AgentImage.Get(), cluster)
-	if h.isRKE2(clusterID) {
+	if isRKE2(cluster) {
 		// for linux
-		crtStatus.NodeCommand = fmt.Sprintf(rke2NodeCommandFormat,
-			rootURL+"/system-agent-install.sh",
-			AgentEnvVars(cluster, false),
-			rootURL,
-			token,
-			ca)
-		crtStatus.InsecureNodeCommand = fmt.Sprintf(rke2InsecureNodeCommandFormat,
-			rootURL+"/system-agent-install.sh",
-			AgentEnvVars(cluster, false),
-			rootURL,
-			token,
-			ca)
+		crtStatus.NodeCommand = rke2NodeCommand(false, cluster, rootURL, token, ca)
+		crtStatus.InsecureNodeCommand = rke2NodeCommand(true, cluster, rootURL, token, ca)
 	} else {
 		// for linux
-		crtStatus.NodeCommand = fmt.Sprintf(nodeCommandFormat,
-			AgentEnvVars(cluster, true),
-			agentImage,
-			rootURL,
-			token,
-			ca)
+		crtStatus.NodeCommand, err = dockerNodeCommand(cluster, rootURL, token, ca)
+		if err != nil {
+			return crt.Status, err
+		}
 	}
 	// for windows
+	crtStatus.WindowsNodeCommand, err = windowsNodeCommand(cluster, rootURL, token, ca)
+	if err != nil {
+		return crt.Status, err
+	}
+
+	return *crtStatus, nil
+}
+
+// NodeCommandFor returns the command registering a node of the OS in the cluster with the registration token, using the
+// runtime of the cluster unless another one is given. Insecure commands don't verify the certificate of Rancher, they
+// are only available for rke2.
+func NodeCommandFor(cluster *v3.Cluster, token, os, runtime string, insecure bool) (string, error) {
+	if os == "" {
+		os = OSLinux
+	}
+	if runtime == "" {
+		runtime = RuntimeDocker
+		if isRKE2(cluster) {
+			runtime = RuntimeRKE2
+		}
+	}
+
+	ca := systemtemplate.CAChecksum()
+	if ca != "" {
+		ca = " --ca-checksum " + ca
+	}
+	rootURL, err := getRootURL()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case os == OSLinux && runtime == RuntimeRKE2:
+		return rke2NodeCommand(insecure, cluster, rootURL, token, ca), nil
+	case insecure:
+		return "", fmt.Errorf("insecure node commands are only available for %s", RuntimeRKE2)
+	case os == OSLinux && runtime == RuntimeDocker:
+		return dockerNodeCommand(cluster, rootURL, token, ca)
+	case os == OSWindows && runtime == RuntimeDocker:
+		return windowsNodeCommand(cluster, rootURL, token, ca)
+	}
+	return "", fmt.Errorf("node commands are not available for %s nodes with %s", os, runtime)
+}
+
+func dockerNodeCommand(cluster *v3.Cluster, rootURL, token, ca string) (string, error) {
+	flags, err := nodeCommandFlags(cluster)
+	if err != nil {
+		return "", err
+	}
+	if cluster != nil {
+		if err := ValidateDockerAgentEnvVars(cluster.Spec.AgentEnvVars); err != nil {
+			return "", fmt.Errorf("invalid agentEnvVars for cluster [%s]: %w", cluster.Name, err)
+		}
+	}
+	agentImage, err := NodeAgentImage(cluster)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(nodeCommandFormat,
+		flags,
+		AgentEnvVars(cluster, true),
+		agentImage,
+		rootURL,
+		token,
+		ca), nil
+}
+
+func windowsNodeCommand(cluster *v3.Cluster, rootURL, token, ca string) (string, error) {
+	agentImage, err := NodeAgentImage(cluster)
+	if err != nil {
+		return "", err
+	}
 	var agentImageDockerEnv string
-	if util.GetPrivateRepoURL(cluster) != "" {
+	if util.GetPrivateRepoURL(cluster) != "" || cluster.Spec.AgentImageOverride != "" {
 		// patch the AGENT_IMAGE env
 		agentImageDockerEnv = fmt.Sprintf("-e AGENT_IMAGE=%s ", agentImage)
 	}
-	crtStatus.WindowsNodeCommand = fmt.Sprintf(windowsNodeCommandFormat,
+	return fmt.Sprintf(windowsNodeCommandFormat,
 		agentImageDockerEnv,
 		agentImage,
 		rootURL,
 		token,
 		ca,
-		getWindowsPrefixPathArg(cluster.Spec.RancherKubernetesEngineConfig))
+		getWindowsPrefixPathArg(cluster.Spec.RancherKubernetesEngineConfig)), nil
+}
 
-	return *crtStatus, nil
+// NodeAgentImage returns the agent image of the node commands of the cluster, its agentImageOverride unless it isn't set
+func NodeAgentImage(cluster *v3.Cluster) (string, error) {
+	if cluster == nil || cluster.Spec.AgentImageOverride == "" {
+		return image.ResolveWithCluster(settings.AgentImage.Get(), cluster), nil
+	}
+	if err := ValidateAgentImageOverride(cluster.Spec.AgentImageOverride); err != nil {
+		return "", fmt.Errorf("invalid agentImageOverride for cluster [%s]: %w", cluster.Name, err)
+	}
+	return cluster.Spec.AgentImageOverride, nil
+}
+
+func rke2NodeCommand(insecure bool, cluster *v3.Cluster, rootURL, token, ca string) string {
+	scriptURL, checksum, authenticated := installScriptSource(cluster, rootURL)
+	if authenticated {
+		scriptURL = fmt.Sprintf("-H \"Authorization: Bearer %s\" %s", token, shellQuote(scriptURL))
+	}
+	if checksum != "" {
+		format := rke2PinnedNodeCommandFormat
+		if insecure {
+			format = rke2InsecurePinnedNodeCommandFormat
+		}
+		return fmt.Sprintf(format,
+			scriptURL,
+			shellQuote(checksum+"  system-agent-install.sh"),
+			AgentEnvVars(cluster, false),
+			rootURL,
+			token,
+			ca)
+	}
+
+	format := rke2NodeCommandFormat
+	if insecure {
+		format = rke2InsecureNodeCommandFormat
+	}
+	return fmt.Sprintf(format,
+		scriptURL,
+		AgentEnvVars(cluster, false),
+		rootURL,
+		token,
+		ca)
+}
+
+// installScriptSource returns the URL the install script of the cluster is downloaded from, along with its checksum
+// when the node has to verify it. The install scripts of clusters taking them from a ConfigMap, or with agent env vars
+// taking their value from a secret, are served by Rancher to the nodes authenticating with the registration token, while
+// the other ones are downloaded and verified by the nodes.
+func installScriptSource(cluster *v3.Cluster, rootURL string) (string, string, bool) {
+	script := cluster.Spec.AgentInstallScript
+	if (script != nil && script.ConfigMapRef != nil) || hasSecretEnvVars(cluster) {
+		return rootURL + "/system-agent-install.sh?" + url.Values{
+			"clusterNamespace": []string{cluster.Spec.FleetWorkspaceName},
+			"clusterName":      []string{cluster.Spec.DisplayName},
+		}.Encode(), "", true
+	}
+	if script == nil {
+		return systemAgentInstallScriptURL(rootURL), "", false
+	}
+	if script.URL == "" {
+		return systemAgentInstallScriptURL(rootURL), script.SHA256, false
+	}
+	return shellQuote(script.URL), script.SHA256, false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// systemAgentInstallScriptURL returns the URL of the system agent install script served by Rancher, unless it is
+// hosted elsewhere as set by the system-agent-install-script-url setting
+func systemAgentInstallScriptURL(rootURL string) string {
+	if scriptURL := settings.SystemAgentInstallScriptURL.Get(); scriptURL != "" {
+		return scriptURL
+	}
+	return rootURL + "/system-agent-install.sh"
 }
 
 func getWindowsPrefixPathArg(rkeConfig *rketypes.RancherKubernetesEngineConfig) string {
@@ -132,6 +271,9 @@ func getWindowsPrefixPathArg(rkeConfig *rketypes.RancherKubernetesEngineConfig)
 	return ""
 }
 
+// AgentEnvVars renders the agent env vars of the cluster for the node command. The env vars taking their value from a
+// secret are left out, they are resolved in the install script served by Rancher and refused for the docker node
+// command.
 func AgentEnvVars(cluster *v3.Cluster, docker bool) string {
 	var agentEnvVars []string
 	if cluster != nil {
@@ -148,6 +290,15 @@ func AgentEnvVars(cluster *v3.Cluster, docker bool) string {
 	return strings.Join(agentEnvVars, " ")
 }
 
+func hasSecretEnvVars(cluster *v3.Cluster) bool {
+	for _, envVar := range cluster.Spec.AgentEnvVars {
+		if envVar.ValueFrom != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func NodeCommand(token string, cluster *v3.Cluster) (string, error) {
 	ca := systemtemplate.CAChecksum()
 	if ca != "" {
@@ -158,12 +309,8 @@ func NodeCommand(token string, cluster *v3.Cluster) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf(nodeCommandFormat,
-		AgentEnvVars(cluster, true),
-		image.ResolveWithCluster(settings.AgentImage.Get(), cluster),
-		rootURL,
-		token,
-		ca), nil
+
+	return dockerNodeCommand(cluster, rootURL, token, ca)
 }
 
 func ShareMntCommand(nodeName, token string, cluster *v3.Cluster) ([]string, error) {
@@ -0,0 +1,220 @@
+package clusterregistrationtoken
+
+import (
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNodeCommandFor(t *testing.T) {
+	originalServerURL, originalCACerts := settings.ServerURL.Get(), settings.CACerts.Get()
+	defer func() {
+		settings.ServerURL.Set(originalServerURL)
+		settings.CACerts.Set(originalCACerts)
+	}()
+	require.NoError(t, settings.ServerURL.Set("https://rancher.example.com"))
+	require.NoError(t, settings.CACerts.Set(""))
+
+	rke2Cluster := newTestCluster(nil)
+	rke2Cluster.Annotations = map[string]string{"objectset.rio.cattle.io/owner-gvk": "provisioning.cattle.io/v1, Kind=Cluster"}
+
+	tests := []struct {
+		name           string
+		rke2           bool
+		os             string
+		runtime        string
+		insecure       bool
+		expectedPrefix string
+		expectedErr    string
+	}{
+		{
+			name:           "linux docker",
+			os:             OSLinux,
+			runtime:        RuntimeDocker,
+			expectedPrefix: "sudo docker run -d --privileged --restart=unless-stopped --net=host",
+		},
+		{
+			name:           "linux rke2",
+			os:             OSLinux,
+			runtime:        RuntimeRKE2,
+			expectedPrefix: "curl -fL https://rancher.example.com/system-agent-install.sh | sudo  sh -s - --server https://rancher.example.com --token token",
+		},
+		{
+			name:           "linux rke2 insecure",
+			os:             OSLinux,
+			runtime:        RuntimeRKE2,
+			insecure:       true,
+			expectedPrefix: "curl --insecure -fL https://rancher.example.com/system-agent-install.sh",
+		},
+		{
+			name:           "windows docker",
+			os:             OSWindows,
+			runtime:        RuntimeDocker,
+			expectedPrefix: `PowerShell -NoLogo -NonInteractive -Command "& {docker run -v c:\:c:\host`,
+		},
+		{
+			name:        "windows rke2",
+			os:          OSWindows,
+			runtime:     RuntimeRKE2,
+			expectedErr: "node commands are not available for windows nodes with rke2",
+		},
+		{
+			name:        "linux docker insecure",
+			os:          OSLinux,
+			runtime:     RuntimeDocker,
+			insecure:    true,
+			expectedErr: "insecure node commands are only available for rke2",
+		},
+		{
+			name:           "defaults to the runtime of a docker cluster",
+			expectedPrefix: "sudo docker run -d",
+		},
+		{
+			name:           "defaults to the runtime of an rke2 cluster",
+			rke2:           true,
+			expectedPrefix: "curl -fL https://rancher.example.com/system-agent-install.sh",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := newTestCluster(nil)
+			if tt.rke2 {
+				cluster = rke2Cluster
+			}
+
+			command, err := NodeCommandFor(cluster, "token", tt.os, tt.runtime, tt.insecure)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(command, tt.expectedPrefix), "unexpected node command %q", command)
+			assert.Contains(t, command, "--server https://rancher.example.com --token token")
+		})
+	}
+}
+
+func TestRKE2NodeCommandInstallScriptURL(t *testing.T) {
+	original := settings.SystemAgentInstallScriptURL.Get()
+	defer settings.SystemAgentInstallScriptURL.Set(original)
+
+	tests := []struct {
+		name             string
+		setting          string
+		expectedSecure   string
+		expectedInsecure string
+	}{
+		{
+			name:             "served by rancher",
+			expectedSecure:   "curl -fL https://rancher.example.com/system-agent-install.sh | sudo  sh -s - --server https://rancher.example.com --token token --ca-checksum abc",
+			expectedInsecure: "curl --insecure -fL https://rancher.example.com/system-agent-install.sh | sudo  sh -s - --server https://rancher.example.com --token token --ca-checksum abc",
+		},
+		{
+			name:             "hosted elsewhere",
+			setting:          "https://mirror.example.com/install.sh",
+			expectedSecure:   "curl -fL https://mirror.example.com/install.sh | sudo  sh -s - --server https://rancher.example.com --token token --ca-checksum abc",
+			expectedInsecure: "curl --insecure -fL https://mirror.example.com/install.sh | sudo  sh -s - --server https://rancher.example.com --token token --ca-checksum abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, settings.SystemAgentInstallScriptURL.Set(tt.setting))
+
+			cluster := newTestCluster(nil)
+			assert.Equal(t, tt.expectedSecure,
+				rke2NodeCommand(false, cluster, "https://rancher.example.com", "token", " --ca-checksum abc"))
+			assert.Equal(t, tt.expectedInsecure,
+				rke2NodeCommand(true, cluster, "https://rancher.example.com", "token", " --ca-checksum abc"))
+		})
+	}
+}
+
+func TestRKE2NodeCommandClusterInstallScript(t *testing.T) {
+	original := settings.SystemAgentInstallScriptURL.Get()
+	defer settings.SystemAgentInstallScriptURL.Set(original)
+	require.NoError(t, settings.SystemAgentInstallScriptURL.Set("https://global.example.com/install.sh"))
+
+	tests := []struct {
+		name     string
+		script   *v32.AgentInstallScript
+		expected string
+	}{
+		{
+			name:     "url",
+			script:   &v32.AgentInstallScript{URL: "https://mirror.example.com/install.sh"},
+			expected: "curl -fL 'https://mirror.example.com/install.sh' | sudo  sh -s - --server https://rancher.example.com --token token --ca-checksum abc",
+		},
+		{
+			name:     "pinned url",
+			script:   &v32.AgentInstallScript{URL: "https://mirror.example.com/install.sh", SHA256: "abc123"},
+			expected: `curl -fL 'https://mirror.example.com/install.sh' -o system-agent-install.sh && echo 'abc123  system-agent-install.sh' | sha256sum -c - && sudo  sh system-agent-install.sh --server https://rancher.example.com --token token --ca-checksum abc`,
+		},
+		{
+			name:     "pinned checksum",
+			script:   &v32.AgentInstallScript{SHA256: "abc123"},
+			expected: `curl -fL https://global.example.com/install.sh -o system-agent-install.sh && echo 'abc123  system-agent-install.sh' | sha256sum -c - && sudo  sh system-agent-install.sh --server https://rancher.example.com --token token --ca-checksum abc`,
+		},
+		{
+			name:     "quoted url",
+			script:   &v32.AgentInstallScript{URL: "https://mirror.example.com/install.sh?a=1&b=$(id)", SHA256: "abc'123"},
+			expected: `curl -fL 'https://mirror.example.com/install.sh?a=1&b=$(id)' -o system-agent-install.sh && echo 'abc'\''123  system-agent-install.sh' | sha256sum -c - && sudo  sh system-agent-install.sh --server https://rancher.example.com --token token --ca-checksum abc`,
+		},
+		{
+			name: "configmap",
+			script: &v32.AgentInstallScript{
+				ConfigMapRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "install"}, Key: "install.sh"},
+				SHA256:       "abc123",
+			},
+			expected: `curl -fL -H "Authorization: Bearer token" 'https://rancher.example.com/system-agent-install.sh?clusterName=test&clusterNamespace=fleet-default' | sudo  sh -s - --server https://rancher.example.com --token token --ca-checksum abc`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := newTestCluster(nil)
+			cluster.Spec.DisplayName = "test"
+			cluster.Spec.FleetWorkspaceName = "fleet-default"
+			cluster.Spec.AgentInstallScript = tt.script
+
+			assert.Equal(t, tt.expected, rke2NodeCommand(false, cluster, "https://rancher.example.com", "token", " --ca-checksum abc"))
+		})
+	}
+}
+
+func TestNodeCommandAgentImageOverride(t *testing.T) {
+	originalServerURL, originalAgentImage := settings.ServerURL.Get(), settings.AgentImage.Get()
+	defer func() {
+		settings.ServerURL.Set(originalServerURL)
+		settings.AgentImage.Set(originalAgentImage)
+	}()
+	require.NoError(t, settings.ServerURL.Set("https://rancher.example.com"))
+	require.NoError(t, settings.AgentImage.Set("rancher/rancher-agent:v2.6.0"))
+
+	cluster := newTestCluster(nil)
+	command, err := NodeCommandFor(cluster, "token", OSLinux, RuntimeDocker, false)
+	require.NoError(t, err)
+	assert.Contains(t, command, " rancher/rancher-agent:v2.6.0 --server", "expected the agent image of the setting by default")
+	command, err = NodeCommandFor(cluster, "token", OSWindows, RuntimeDocker, false)
+	require.NoError(t, err)
+	assert.NotContains(t, command, "AGENT_IMAGE")
+
+	cluster.Spec.AgentImageOverride = "registry.example.com/rancher-agent:v2.6.0-patched"
+	command, err = NodeCommandFor(cluster, "token", OSLinux, RuntimeDocker, false)
+	require.NoError(t, err)
+	assert.Contains(t, command, " registry.example.com/rancher-agent:v2.6.0-patched --server")
+	assert.NotContains(t, command, "rancher/rancher-agent:v2.6.0 ")
+	command, err = NodeCommandFor(cluster, "token", OSWindows, RuntimeDocker, false)
+	require.NoError(t, err)
+	assert.Contains(t, command, "-e AGENT_IMAGE=registry.example.com/rancher-agent:v2.6.0-patched registry.example.com/rancher-agent:v2.6.0-patched bootstrap")
+
+	cluster.Spec.AgentImageOverride = " "
+	_, err = NodeCommandFor(cluster, "token", OSLinux, RuntimeDocker, false)
+	assert.EqualError(t, err, "invalid agentImageOverride for cluster [c-test]: agent image may not be blank when set")
+}
@@ -5,6 +5,7 @@
 
 	"github.com/rancher/rancher/pkg/controllers/dashboard/apiservice"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/clusterindex"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/clusternotifier"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/clusterregistrationtoken"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/fleetcharts"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/helm"
@@ -46,6 +47,7 @@ func Register(ctx context.Context, wrangler *wrangler.Context) error {
 
 	if features.ProvisioningV2.Enabled() || features.MCM.Enabled() {
 		clusterregistrationtoken.Register(ctx, wrangler)
+		clusternotifier.Register(ctx, wrangler)
 	}
 
 	if features.ProvisioningV2.Enabled() {
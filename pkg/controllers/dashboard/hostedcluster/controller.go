@@ -41,8 +41,15 @@
 	}
 )
 
+type chartsManager interface {
+	Ensure(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, requestedBy string) error
+	EnsureVersion(namespace, name, version string, values map[string]interface{}, forceAdopt bool, requestedBy string) error
+}
+
+var _ chartsManager = (*system.Manager)(nil)
+
 type handler struct {
-	manager *system.Manager
+	manager chartsManager
 	secrets v1.SecretCache
 }
 
@@ -62,23 +69,12 @@ func (h handler) onClusterChange(key string, cluster *v3.Cluster) (*v3.Cluster,
 		return cluster, nil
 	}
 
-	var toInstallCrdChart, toInstallChart *chart.Definition
-	if cluster.Spec.AKSConfig != nil {
-		toInstallCrdChart = &AksCrdChart
-		toInstallChart = &AksChart
-	} else if cluster.Spec.EKSConfig != nil {
-		toInstallCrdChart = &EksCrdChart
-		toInstallChart = &EksChart
-	} else if cluster.Spec.GKEConfig != nil {
-		toInstallCrdChart = &GkeCrdChart
-		toInstallChart = &GkeChart
-	}
-
+	toInstallCrdChart, toInstallChart, version := OperatorCharts(cluster)
 	if toInstallCrdChart == nil || toInstallChart == nil {
 		return cluster, nil
 	}
 
-	if err := h.manager.Ensure(toInstallCrdChart.ReleaseNamespace, toInstallCrdChart.ChartName, "", nil, true); err != nil {
+	if err := h.ensure(cluster.Name, toInstallCrdChart, version, nil); err != nil {
 		return cluster, err
 	}
 
@@ -101,13 +97,36 @@ func (h handler) onClusterChange(key string, cluster *v3.Cluster) (*v3.Cluster,
 		"additionalTrustedCAs": additionalCA != nil,
 	}
 
-	if err := h.manager.Ensure(toInstallChart.ReleaseNamespace, toInstallChart.ChartName, "", chartValues, true); err != nil {
+	if err := h.ensure(cluster.Name, toInstallChart, version, chartValues); err != nil {
 		return cluster, err
 	}
 
 	return cluster, nil
 }
 
+// OperatorCharts returns the CRD and operator charts of the hosted cluster, and the version they are pinned to, if
+// any. The charts are nil for clusters which aren't hosted.
+func OperatorCharts(cluster *v3.Cluster) (*chart.Definition, *chart.Definition, string) {
+	switch {
+	case cluster.Spec.AKSConfig != nil:
+		return &AksCrdChart, &AksChart, settings.AKSOperatorVersion.Get()
+	case cluster.Spec.EKSConfig != nil:
+		return &EksCrdChart, &EksChart, settings.EKSOperatorVersion.Get()
+	case cluster.Spec.GKEConfig != nil:
+		return &GkeCrdChart, &GkeChart, ""
+	}
+	return nil, nil, ""
+}
+
+// ensure installs the chart at the pinned version, or the latest version if none is pinned, on behalf of the cluster
+func (h handler) ensure(clusterName string, def *chart.Definition, version string, values map[string]interface{}) error {
+	requestedBy := "cluster/" + clusterName
+	if version == "" {
+		return h.manager.Ensure(def.ReleaseNamespace, def.ChartName, "", values, true, requestedBy)
+	}
+	return h.manager.EnsureVersion(def.ReleaseNamespace, def.ChartName, version, values, true, requestedBy)
+}
+
 func getAdditionalCA(secretsCache v1.SecretCache) ([]byte, error) {
 	secret, err := secretsCache.Get(namespace.System, "tls-ca-additional")
 	if err != nil && !errors.IsNotFound(err) {
@@ -0,0 +1,115 @@
+package hostedcluster
+
+import (
+	"testing"
+
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	v1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type ensureCall struct {
+	name       string
+	minVersion string
+	version    string
+}
+
+type fakeChartsManager struct {
+	calls []ensureCall
+}
+
+func (f *fakeChartsManager) Ensure(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, requestedBy string) error {
+	f.calls = append(f.calls, ensureCall{name: name, minVersion: minVersion})
+	return nil
+}
+
+func (f *fakeChartsManager) EnsureVersion(namespace, name, version string, values map[string]interface{}, forceAdopt bool, requestedBy string) error {
+	f.calls = append(f.calls, ensureCall{name: name, version: version})
+	return nil
+}
+
+type fakeSecretCache struct{}
+
+func (fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+func (fakeSecretCache) List(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+	return nil, nil
+}
+
+func (fakeSecretCache) AddIndexer(indexName string, indexer v1.SecretIndexer) {}
+
+func (fakeSecretCache) GetByIndex(indexName, key string) ([]*corev1.Secret, error) {
+	return nil, nil
+}
+
+func TestOperatorVersionPinning(t *testing.T) {
+	eksCluster := &v3.Cluster{}
+	eksCluster.Spec.EKSConfig = &eksv1.EKSClusterConfigSpec{}
+	aksCluster := &v3.Cluster{}
+	aksCluster.Spec.AKSConfig = &aksv1.AKSClusterConfigSpec{}
+
+	tests := []struct {
+		name     string
+		cluster  *v3.Cluster
+		setting  settings.Setting
+		version  string
+		expected []ensureCall
+	}{
+		{
+			name:    "eks latest",
+			cluster: eksCluster,
+			setting: settings.EKSOperatorVersion,
+			expected: []ensureCall{
+				{name: EksCrdChart.ChartName},
+				{name: EksChart.ChartName},
+			},
+		},
+		{
+			name:    "eks pinned",
+			cluster: eksCluster,
+			setting: settings.EKSOperatorVersion,
+			version: "100.0.1+up1.1.1",
+			expected: []ensureCall{
+				{name: EksCrdChart.ChartName, version: "100.0.1+up1.1.1"},
+				{name: EksChart.ChartName, version: "100.0.1+up1.1.1"},
+			},
+		},
+		{
+			name:    "aks pinned",
+			cluster: aksCluster,
+			setting: settings.AKSOperatorVersion,
+			version: "100.0.2+up1.0.1",
+			expected: []ensureCall{
+				{name: AksCrdChart.ChartName, version: "100.0.2+up1.0.1"},
+				{name: AksChart.ChartName, version: "100.0.2+up1.0.1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.setting.Get()
+			defer tt.setting.Set(original)
+			require.NoError(t, tt.setting.Set(tt.version))
+
+			manager := &fakeChartsManager{}
+			h := handler{
+				manager: manager,
+				secrets: fakeSecretCache{},
+			}
+			_, err := h.onClusterChange("", tt.cluster)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, manager.calls)
+		})
+	}
+}
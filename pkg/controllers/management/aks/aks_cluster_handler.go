@@ -2,10 +2,7 @@
 
 import (
 	"context"
-	stderrors "errors"
 	"fmt"
-	"net"
-	"net/url"
 	"reflect"
 	"time"
 
@@ -13,10 +10,10 @@
 	"github.com/rancher/aks-operator/controller"
 	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
 	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/clustermanager"
 	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
 	"github.com/rancher/rancher/pkg/controllers/management/clusterupstreamrefresher"
 	"github.com/rancher/rancher/pkg/controllers/management/rbac"
-	"github.com/rancher/rancher/pkg/dialer"
 	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/systemaccount"
@@ -66,7 +63,8 @@ func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.M
 		SystemAccountManager: systemaccount.NewManager(mgmtCtx),
 		DynamicClient:        aksCCDynamicClient,
 		ClientDialer:         mgmtCtx.Dialer,
-		Discovery:            wContext.K8s.Discovery(),
+		CRDGate:              clusteroperator.NewCRDReadinessGate(wContext.K8s.Discovery(), aksV1),
+		EndpointDrift:        clusteroperator.NewEndpointDriftTracker(),
 	}}
 
 	wContext.Mgmt.Cluster().OnChange(ctx, "aks-operator-controller", e.onClusterChange)
@@ -84,8 +82,8 @@ func (e *aksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 		return e.ClusterClient.Update(cluster)
 	}
 
-	cluster, err := e.CheckCrdReady(cluster, "aks")
-	if err != nil {
+	cluster, ready, err := e.CheckCrdReady(cluster, "aks")
+	if err != nil || !ready {
 		return cluster, err
 	}
 
@@ -118,8 +116,11 @@ func (e *aksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 	}
 
 	// check for changes between aks spec on cluster and the aks spec on the aksClusterConfig object
-	if !reflect.DeepEqual(aksClusterConfigMap, aksClusterConfigDynamic.Object["spec"]) {
-		logrus.Infof("change detected for cluster [%s], updating AKSClusterConfig", cluster.Name)
+	if diff := clusteroperator.SpecDiff(aksClusterConfigMap, aksClusterConfigDynamic.Object["spec"]); len(diff) > 0 {
+		cluster, err = e.RecordConfigUpdate(cluster, "AKSClusterConfig", diff)
+		if err != nil {
+			return cluster, err
+		}
 		return e.updateAKSClusterConfig(cluster, aksClusterConfigDynamic, aksClusterConfigMap)
 	}
 
@@ -173,6 +174,11 @@ func (e *aksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			return e.RecordCAAndAPIEndpoint(cluster)
 		}
 
+		cluster, err = e.DetectCAAndAPIEndpointDrift(cluster)
+		if err != nil {
+			return cluster, err
+		}
+
 		if cluster.Status.AKSStatus.PrivateRequiresTunnel == nil &&
 			to.Bool(cluster.Status.AKSStatus.UpstreamSpec.PrivateCluster) {
 			// In this case, the API endpoint is private and it has not been determined if Rancher must tunnel to communicate with it.
@@ -193,21 +199,7 @@ func (e *aksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 		if cluster.Status.ServiceAccountToken == "" {
 			cluster, err = e.generateAndSetServiceAccount(cluster)
 			if err != nil {
-				var statusErr error
-				if err == dialer.ErrAgentDisconnected {
-					// In this case, the API endpoint is private and rancher is waiting for the import cluster command to be run.
-					cluster, statusErr = e.SetUnknown(cluster, apimgmtv3.ClusterConditionWaiting, "waiting for cluster agent to be deployed")
-					if statusErr == nil {
-						e.ClusterEnqueueAfter(cluster.Name, enqueueTime)
-					}
-					return cluster, statusErr
-				}
-				cluster, statusErr = e.SetFalse(cluster, apimgmtv3.ClusterConditionWaiting,
-					fmt.Sprintf("failed to communicate with cluster: %v", err))
-				if statusErr != nil {
-					return cluster, statusErr
-				}
-				return cluster, err
+				return e.HandleSATokenError(cluster, err)
 			}
 		}
 
@@ -315,7 +307,7 @@ func (e *aksOperatorController) updateAKSClusterConfig(cluster *mgmtv3.Cluster,
 func (e *aksOperatorController) generateAndSetServiceAccount(cluster *mgmtv3.Cluster) (*mgmtv3.Cluster, error) {
 	restConfig, err := e.getRestConfig(cluster)
 	if err != nil {
-		return cluster, fmt.Errorf("error getting service account token: %v", err)
+		return cluster, fmt.Errorf("error getting service account token: %w", err)
 	}
 
 	clusterDialer, err := e.ClientDialer.ClusterDialer(cluster.Name)
@@ -326,7 +318,7 @@ func (e *aksOperatorController) generateAndSetServiceAccount(cluster *mgmtv3.Clu
 	restConfig.Dial = clusterDialer
 	saToken, err := clusteroperator.GenerateSAToken(restConfig)
 	if err != nil {
-		return cluster, fmt.Errorf("error getting service account token: %v", err)
+		return cluster, fmt.Errorf("error getting service account token: %w", err)
 	}
 
 	cluster = cluster.DeepCopy()
@@ -378,48 +370,14 @@ func (e *aksOperatorController) recordAppliedSpec(cluster *mgmtv3.Cluster) (*mgm
 	return e.ClusterClient.Update(cluster)
 }
 
-// generateSATokenWithPublicAPI tries to get a service account token from the cluster using the public API endpoint.
-// This function is called if the cluster has only privateEndpoint enabled and is not publicly available.
-// If Rancher is able to communicate with the cluster through its API endpoint even though it is private, then this function will retrieve
-// a service account token and the *bool returned will refer to a false value (doesn't have to tunnel).
-//
-// If the Rancher server cannot connect to the cluster's API endpoint, then one of the two errors below will happen.
-// In this case, we know that Rancher must use the cluster agent tunnel for communication. This function will return an empty service account token,
-// and the *bool return value will refer to a true value (must tunnel).
-//
-// If an error different from the two below occur, then the *bool return value will be nil, indicating that Rancher was not able to determine if
-// tunneling is required to communicate with the cluster.
+// generateSATokenWithPublicAPI tries to get a service account token from the cluster using its private API endpoint,
+// see clusteroperator.GenerateSATokenWithPublicAPI.
 func (e *aksOperatorController) generateSATokenWithPublicAPI(cluster *mgmtv3.Cluster) (string, *bool, error) {
 	restConfig, err := e.getRestConfig(cluster)
 	if err != nil {
 		return "", nil, err
 	}
-
-	requiresTunnel := new(bool)
-	restConfig.Dial = (&net.Dialer{
-		Timeout:   30 * time.Second,
-		KeepAlive: 30 * time.Second,
-	}).DialContext
-	serviceToken, err := clusteroperator.GenerateSAToken(restConfig)
-	if err != nil {
-		*requiresTunnel = true
-		var dnsError *net.DNSError
-		if stderrors.As(err, &dnsError) && !dnsError.IsTemporary {
-			return "", requiresTunnel, nil
-		}
-
-		// In the existence of a proxy, it may be the case that the following error occurs,
-		// in which case rancher should use the tunnel connection to communicate with the cluster.
-		var urlError *url.Error
-		if stderrors.As(err, &urlError) && urlError.Timeout() {
-			return "", requiresTunnel, nil
-		}
-
-		// Not able to determine if tunneling is required.
-		requiresTunnel = nil
-	}
-
-	return serviceToken, requiresTunnel, err
+	return clusteroperator.GenerateSATokenWithPublicAPI(restConfig)
 }
 
 func (e *aksOperatorController) getRestConfig(cluster *mgmtv3.Cluster) (*rest.Config, error) {
@@ -428,5 +386,6 @@ func (e *aksOperatorController) getRestConfig(cluster *mgmtv3.Cluster) (*rest.Co
 	if err != nil {
 		return nil, err
 	}
+	clustermanager.SetClientRateLimit(restConfig, cluster)
 	return restConfig, nil
 }
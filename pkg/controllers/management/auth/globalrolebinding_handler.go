@@ -341,6 +341,14 @@ func (grb *globalRoleBindingLifecycle) createRestrictedAdminCRBsForUserClusters(
 	var returnErr error
 	for _, cr := range crs {
 		clusterName := cr.Labels[rbac.RestrictedAdminCRForClusters]
+		cluster, err := grb.clusterLister.Get("", clusterName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			returnErr = multierror.Append(returnErr, err)
+			continue
+		}
+		if cluster == nil || rbac.RestrictedAdminExcluded(cluster) {
+			continue
+		}
 		crbName := clusterName + rbac.RestrictedAdminCRBForClusters + globalRoleBinding.Name
 		crb, err := grb.crbLister.Get("", crbName)
 		if err != nil && !apierrors.IsNotFound(err) {
@@ -375,7 +383,7 @@ func (grb *globalRoleBindingLifecycle) grantRestrictedAdminUserClusterPermission
 		return err
 	}
 	for _, cluster := range clusters {
-		if cluster.Name == "local" {
+		if rbac.RestrictedAdminExcluded(cluster) {
 			continue
 		}
 		rbName := fmt.Sprintf("%s-%s", globalRoleBinding.Name, rbac.RestrictedAdminClusterRoleBinding)
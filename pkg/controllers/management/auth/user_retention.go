@@ -0,0 +1,352 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rancher/rancher/pkg/eventrecorder"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	userRetentionController = "mgmt-auth-user-retention"
+
+	// retentionDisabledAtAnnotation is set on the users disabled by the retention, so that they can be told apart from
+	// the users disabled by an admin and deleted once they stayed disabled for user-retention-delete-after
+	retentionDisabledAtAnnotation = "management.cattle.io/retention-disabled-at"
+	// retentionReactivatedAtAnnotation is set when an admin enables a user disabled by the retention again, the
+	// user is then considered active since it was enabled
+	retentionReactivatedAtAnnotation = "management.cattle.io/retention-reactivated-at"
+	// retentionFirstSeenAtAnnotation is set on the users whose activity was never recorded when the retention first
+	// checks them, such as the users which didn't log in since the logins are recorded. They are considered active
+	// since then rather than since they were created.
+	retentionFirstSeenAtAnnotation = "management.cattle.io/retention-first-seen-at"
+
+	UserRetentionDisabledReason    = "UserRetentionDisabled"
+	UserRetentionDeletedReason     = "UserRetentionDeleted"
+	UserRetentionReactivatedReason = "UserRetentionReactivated"
+	UserRetentionDryRunReason      = "UserRetentionDryRun"
+
+	defaultAdminLabelKey   = "authz.management.cattle.io/bootstrapping"
+	defaultAdminLabelValue = "admin-user"
+	clusterOwnerRole       = "cluster-owner"
+)
+
+// userRetention disables the users which didn't log in nor use their tokens for user-retention-disable-after and
+// deletes them once they stayed disabled for user-retention-delete-after, their tokens, preferences and dashboard
+// settings are removed with them by the user lifecycle. The default admin, the system users and the sole owners of a cluster are never touched.
+type userRetention struct {
+	users               v3.UserInterface
+	userLister          v3.UserLister
+	userAttributeLister v3.UserAttributeLister
+	tokens              v3.TokenInterface
+	tokenIndexer        cache.Indexer
+	crtbIndexer         cache.Indexer
+	crtbLister          v3.ClusterRoleTemplateBindingLister
+	recorder            record.EventRecorder
+	enqueueAfter        func(name string, after time.Duration)
+	now                 func() time.Time
+}
+
+func registerUserRetention(ctx context.Context, management *config.ManagementContext) {
+	users := management.Management.Users("")
+	r := &userRetention{
+		users:               users,
+		userLister:          users.Controller().Lister(),
+		userAttributeLister: management.Management.UserAttributes("").Controller().Lister(),
+		tokens:              management.Management.Tokens(""),
+		tokenIndexer:        management.Management.Tokens("").Controller().Informer().GetIndexer(),
+		crtbIndexer:         management.Management.ClusterRoleTemplateBindings("").Controller().Informer().GetIndexer(),
+		crtbLister:          management.Management.ClusterRoleTemplateBindings("").Controller().Lister(),
+		recorder:            eventrecorder.New(ctx, management.K8sClient, "user-retention"),
+		enqueueAfter: func(name string, after time.Duration) {
+			users.Controller().EnqueueAfter("", name, after)
+		},
+		now: time.Now,
+	}
+	users.AddHandler(ctx, userRetentionController, r.sync)
+	management.Management.Settings("").AddHandler(ctx, userRetentionController, r.syncSetting)
+}
+
+// syncSetting checks all the users again when the retention policy changes
+func (r *userRetention) syncSetting(key string, setting *v3.Setting) (runtime.Object, error) {
+	if setting == nil || !strings.HasPrefix(setting.Name, "user-retention-") {
+		return setting, nil
+	}
+
+	users, err := r.userLister.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		r.enqueueAfter(user.Name, 0)
+	}
+	return setting, nil
+}
+
+func (r *userRetention) sync(key string, user *v3.User) (runtime.Object, error) {
+	if user == nil || user.DeletionTimestamp != nil {
+		return user, nil
+	}
+
+	disableAfter, deleteAfter, err := retentionPolicy()
+	if err != nil {
+		logrus.Errorf("[%v] %v", userRetentionController, err)
+		return user, nil
+	}
+	if disableAfter == 0 {
+		return user, nil
+	}
+
+	excluded, err := r.excluded(user)
+	if err != nil || excluded {
+		return user, err
+	}
+
+	if user.Enabled == nil || *user.Enabled {
+		if _, ok := user.Annotations[retentionDisabledAtAnnotation]; ok {
+			return r.reactivate(user)
+		}
+
+		lastActive, recorded, err := r.lastActive(user)
+		if err != nil {
+			return nil, err
+		}
+		if !recorded {
+			return r.markFirstSeen(user, disableAfter)
+		}
+		if wait := lastActive.Add(disableAfter).Sub(r.now()); wait > 0 {
+			r.enqueueAfter(user.Name, wait)
+			return user, nil
+		}
+		return r.disable(user, lastActive)
+	}
+
+	// only the users disabled by the retention are deleted, the users disabled by an admin are left alone
+	disabledAt, ok := retentionTime(user, retentionDisabledAtAnnotation)
+	if !ok || deleteAfter == 0 {
+		return user, nil
+	}
+	if wait := disabledAt.Add(deleteAfter).Sub(r.now()); wait > 0 {
+		r.enqueueAfter(user.Name, wait)
+		return user, nil
+	}
+	return r.purge(user, disabledAt)
+}
+
+// disable disables the user and deletes its tokens, the user can log in again once an admin enables it
+func (r *userRetention) disable(user *v3.User, lastActive time.Time) (runtime.Object, error) {
+	if settings.UserRetentionDryRun.Get() == "true" {
+		r.audit(user, UserRetentionDryRunReason, "User %s would be disabled, it is inactive since %s", user.Name, lastActive.Format(time.RFC3339))
+		return user, nil
+	}
+
+	user = user.DeepCopy()
+	enabled := false
+	user.Enabled = &enabled
+	if user.Annotations == nil {
+		user.Annotations = map[string]string{}
+	}
+	user.Annotations[retentionDisabledAtAnnotation] = r.now().UTC().Format(time.RFC3339)
+	delete(user.Annotations, retentionReactivatedAtAnnotation)
+
+	updated, err := r.users.Update(user)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.deleteTokens(user.Name); err != nil {
+		return nil, err
+	}
+
+	r.audit(updated, UserRetentionDisabledReason, "User %s was disabled and its tokens deleted, it is inactive since %s", user.Name, lastActive.Format(time.RFC3339))
+	return updated, nil
+}
+
+// markFirstSeen records when the retention first checked a user whose activity was never recorded
+func (r *userRetention) markFirstSeen(user *v3.User, disableAfter time.Duration) (runtime.Object, error) {
+	user = user.DeepCopy()
+	if user.Annotations == nil {
+		user.Annotations = map[string]string{}
+	}
+	user.Annotations[retentionFirstSeenAtAnnotation] = r.now().UTC().Format(time.RFC3339)
+
+	updated, err := r.users.Update(user)
+	if err != nil {
+		return nil, err
+	}
+	r.enqueueAfter(user.Name, disableAfter)
+	return updated, nil
+}
+
+// reactivate records that an admin enabled a user disabled by the retention, so that it isn't disabled again until it
+// is inactive for user-retention-disable-after
+func (r *userRetention) reactivate(user *v3.User) (runtime.Object, error) {
+	user = user.DeepCopy()
+	delete(user.Annotations, retentionDisabledAtAnnotation)
+	user.Annotations[retentionReactivatedAtAnnotation] = r.now().UTC().Format(time.RFC3339)
+
+	updated, err := r.users.Update(user)
+	if err != nil {
+		return nil, err
+	}
+
+	r.audit(updated, UserRetentionReactivatedReason, "User %s was enabled again", user.Name)
+	return updated, nil
+}
+
+// purge deletes the user, the user lifecycle removes its bindings, tokens and namespace, which holds its
+// preferences and dashboard settings
+func (r *userRetention) purge(user *v3.User, disabledAt time.Time) (runtime.Object, error) {
+	if settings.UserRetentionDryRun.Get() == "true" {
+		r.audit(user, UserRetentionDryRunReason, "User %s would be deleted, it is disabled since %s", user.Name, disabledAt.Format(time.RFC3339))
+		return user, nil
+	}
+
+	if err := r.users.Delete(user.Name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	r.audit(user, UserRetentionDeletedReason, "User %s was deleted, it is disabled since %s", user.Name, disabledAt.Format(time.RFC3339))
+	return user, nil
+}
+
+func (r *userRetention) deleteTokens(userName string) error {
+	objs, err := r.tokenIndexer.ByIndex(tokenByUserRefKey, userName)
+	if err != nil {
+		return fmt.Errorf("error getting indexed tokens: %v", err)
+	}
+
+	for _, obj := range objs {
+		token, ok := obj.(*v3.Token)
+		if !ok {
+			return fmt.Errorf("could not convert to *v3.Token: %v", obj)
+		}
+		if err := r.tokens.Delete(token.Name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting token %v of user %v: %v", token.Name, userName, err)
+		}
+	}
+	return nil
+}
+
+func (r *userRetention) audit(user *v3.User, reason, format string, args ...interface{}) {
+	logrus.Infof("[%v] "+format, append([]interface{}{userRetentionController}, args...)...)
+	r.recorder.Eventf(user, v12.EventTypeNormal, reason, format, args...)
+}
+
+// lastActive returns the last time the user logged in or used one of its tokens, or the time the user was created,
+// enabled again or first seen by the retention if it is later. It returns false if none of them was recorded besides
+// the creation of the user.
+func (r *userRetention) lastActive(user *v3.User) (time.Time, bool, error) {
+	lastActive := user.CreationTimestamp.Time
+	recorded := false
+	for _, annotation := range []string{retentionReactivatedAtAnnotation, retentionFirstSeenAtAnnotation} {
+		if t, ok := retentionTime(user, annotation); ok {
+			recorded = true
+			if t.After(lastActive) {
+				lastActive = t
+			}
+		}
+	}
+
+	attribs, err := r.userAttributeLister.Get("", user.Name)
+	if errors.IsNotFound(err) {
+		return lastActive, recorded, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+	for _, value := range []string{attribs.LastLogin, attribs.LastActivity} {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			recorded = true
+			if t.After(lastActive) {
+				lastActive = t
+			}
+		}
+	}
+	return lastActive, recorded, nil
+}
+
+// excluded returns whether the retention must not touch the user: the default admin, the system users and the users
+// which are the only owner of a cluster
+func (r *userRetention) excluded(user *v3.User) (bool, error) {
+	if user.Labels[defaultAdminLabelKey] == defaultAdminLabelValue {
+		return true, nil
+	}
+	for _, principalID := range user.PrincipalIDs {
+		if strings.HasPrefix(principalID, "system://") {
+			return true, nil
+		}
+	}
+	return r.soleClusterOwner(user.Name)
+}
+
+func (r *userRetention) soleClusterOwner(userName string) (bool, error) {
+	objs, err := r.crtbIndexer.ByIndex(crtbByUserRefKey, userName)
+	if err != nil {
+		return false, fmt.Errorf("error getting cluster roles: %v", err)
+	}
+
+	for _, obj := range objs {
+		crtb, ok := obj.(*v3.ClusterRoleTemplateBinding)
+		if !ok || crtb.RoleTemplateName != clusterOwnerRole || crtb.DeletionTimestamp != nil {
+			continue
+		}
+
+		crtbs, err := r.crtbLister.List(crtb.Namespace, labels.Everything())
+		if err != nil {
+			return false, err
+		}
+		sole := true
+		for _, other := range crtbs {
+			if other.RoleTemplateName == clusterOwnerRole && other.DeletionTimestamp == nil &&
+				(other.GroupPrincipalName != "" || (other.UserName != "" && other.UserName != userName)) {
+				sole = false
+				break
+			}
+		}
+		if sole {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func retentionTime(user *v3.User, annotation string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, user.Annotations[annotation])
+	return t, err == nil
+}
+
+// retentionPolicy returns the durations of the user retention settings, zero if the phase is turned off
+func retentionPolicy() (time.Duration, time.Duration, error) {
+	disableAfter, err := parseRetentionDuration(settings.UserRetentionDisableAfter.Name, settings.UserRetentionDisableAfter.Get())
+	if err != nil {
+		return 0, 0, err
+	}
+	deleteAfter, err := parseRetentionDuration(settings.UserRetentionDeleteAfter.Name, settings.UserRetentionDeleteAfter.Get())
+	if err != nil {
+		return 0, 0, err
+	}
+	return disableAfter, deleteAfter, nil
+}
+
+func parseRetentionDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s setting [%s], the user retention is turned off", name, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,355 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+const day = 24 * time.Hour
+
+var retentionNow = time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+
+type retentionTest struct {
+	r             *userRetention
+	recorder      *record.FakeRecorder
+	updated       []*v3.User
+	deletedUsers  []string
+	deletedTokens []string
+	enqueued      map[string]time.Duration
+	now           time.Time
+	// lastActivities are the last times the users used their tokens
+	lastActivities map[string]time.Time
+}
+
+func newRetentionTest(t *testing.T, lastLogins map[string]time.Time, crtbs []*v3.ClusterRoleTemplateBinding, tokens []*v3.Token) *retentionTest {
+	test := &retentionTest{
+		recorder:       record.NewFakeRecorder(10),
+		enqueued:       map[string]time.Duration{},
+		now:            retentionNow,
+		lastActivities: map[string]time.Time{},
+	}
+
+	tokenIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{tokenByUserRefKey: tokenByUserRefFunc})
+	for _, token := range tokens {
+		require.NoError(t, tokenIndexer.Add(token))
+	}
+	crtbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{crtbByUserRefKey: crtbByUserRefFunc})
+	for _, crtb := range crtbs {
+		require.NoError(t, crtbIndexer.Add(crtb))
+	}
+
+	test.r = &userRetention{
+		users: &fakes.UserInterfaceMock{
+			UpdateFunc: func(user *v3.User) (*v3.User, error) {
+				test.updated = append(test.updated, user)
+				return user, nil
+			},
+			DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+				test.deletedUsers = append(test.deletedUsers, name)
+				return nil
+			},
+		},
+		userAttributeLister: &fakes.UserAttributeListerMock{
+			GetFunc: func(namespace, name string) (*v3.UserAttribute, error) {
+				lastLogin, loggedIn := lastLogins[name]
+				lastActivity, active := test.lastActivities[name]
+				if !loggedIn && !active {
+					return nil, errors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+				}
+				attribs := &v3.UserAttribute{ObjectMeta: metav1.ObjectMeta{Name: name}}
+				if loggedIn {
+					attribs.LastLogin = lastLogin.Format(time.RFC3339)
+				}
+				if active {
+					attribs.LastActivity = lastActivity.Format(time.RFC3339)
+				}
+				return attribs, nil
+			},
+		},
+		tokens: &fakes.TokenInterfaceMock{
+			DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+				test.deletedTokens = append(test.deletedTokens, name)
+				return nil
+			},
+		},
+		tokenIndexer: tokenIndexer,
+		crtbIndexer:  crtbIndexer,
+		crtbLister: &fakes.ClusterRoleTemplateBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.ClusterRoleTemplateBinding, error) {
+				var result []*v3.ClusterRoleTemplateBinding
+				for _, crtb := range crtbs {
+					if crtb.Namespace == namespace {
+						result = append(result, crtb)
+					}
+				}
+				return result, nil
+			},
+		},
+		recorder: test.recorder,
+		enqueueAfter: func(name string, after time.Duration) {
+			test.enqueued[name] = after
+		},
+		now: func() time.Time { return test.now },
+	}
+	return test
+}
+
+func (test *retentionTest) event(t *testing.T) string {
+	select {
+	case event := <-test.recorder.Events:
+		return event
+	default:
+		t.Fatal("expected an event to be recorded")
+		return ""
+	}
+}
+
+func newRetentionUser(name string) *v3.User {
+	return &v3.User{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(retentionNow.Add(-365 * day)),
+		},
+		PrincipalIDs: []string{"local://" + name},
+	}
+}
+
+func newOwnerBinding(name, cluster, userName, groupPrincipalName string) *v3.ClusterRoleTemplateBinding {
+	return &v3.ClusterRoleTemplateBinding{
+		ObjectMeta:         metav1.ObjectMeta{Name: name, Namespace: cluster},
+		ClusterName:        cluster,
+		RoleTemplateName:   clusterOwnerRole,
+		UserName:           userName,
+		GroupPrincipalName: groupPrincipalName,
+	}
+}
+
+func setRetentionPolicy(t *testing.T, disableAfter, deleteAfter, dryRun string) func() {
+	require.NoError(t, settings.UserRetentionDisableAfter.Set(disableAfter))
+	require.NoError(t, settings.UserRetentionDeleteAfter.Set(deleteAfter))
+	require.NoError(t, settings.UserRetentionDryRun.Set(dryRun))
+	return func() {
+		settings.UserRetentionDisableAfter.Set("")
+		settings.UserRetentionDeleteAfter.Set("")
+		settings.UserRetentionDryRun.Set("false")
+	}
+}
+
+func TestUserRetentionPhases(t *testing.T) {
+	defer setRetentionPolicy(t, "720h", "1440h", "false")()
+
+	test := newRetentionTest(t, map[string]time.Time{
+		"u-active":  retentionNow.Add(-10 * day),
+		"u-dormant": retentionNow.Add(-40 * day),
+	}, nil, []*v3.Token{
+		{ObjectMeta: metav1.ObjectMeta{Name: "token-1"}, UserID: "u-dormant"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "token-2"}, UserID: "u-active"},
+	})
+
+	_, err := test.r.sync("u-active", newRetentionUser("u-active"))
+	require.NoError(t, err)
+	assert.Empty(t, test.updated, "expected a user which logged in recently not to be disabled")
+	assert.Equal(t, 20*day, test.enqueued["u-active"], "expected the user to be checked again once it is dormant")
+
+	obj, err := test.r.sync("u-dormant", newRetentionUser("u-dormant"))
+	require.NoError(t, err)
+	disabled := obj.(*v3.User)
+	require.NotNil(t, disabled.Enabled)
+	assert.False(t, *disabled.Enabled)
+	assert.Equal(t, retentionNow.Format(time.RFC3339), disabled.Annotations[retentionDisabledAtAnnotation])
+	assert.Equal(t, []string{"token-1"}, test.deletedTokens)
+	assert.Empty(t, test.deletedUsers, "expected the user to be disabled before it is deleted")
+	assert.Equal(t, "Normal UserRetentionDisabled User u-dormant was disabled and its tokens deleted, it is inactive since 2021-07-23T00:00:00Z", test.event(t))
+
+	_, err = test.r.sync("u-dormant", disabled)
+	require.NoError(t, err)
+	assert.Empty(t, test.deletedUsers)
+	assert.Equal(t, 60*day, test.enqueued["u-dormant"])
+
+	test.now = retentionNow.Add(60 * day)
+	_, err = test.r.sync("u-dormant", disabled)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u-dormant"}, test.deletedUsers)
+	assert.Equal(t, "Normal UserRetentionDeleted User u-dormant was deleted, it is disabled since 2021-09-01T00:00:00Z", test.event(t))
+}
+
+func TestUserRetentionReactivated(t *testing.T) {
+	defer setRetentionPolicy(t, "720h", "1440h", "false")()
+
+	test := newRetentionTest(t, map[string]time.Time{"u-dormant": retentionNow.Add(-40 * day)}, nil, nil)
+	user := newRetentionUser("u-dormant")
+	user.Annotations = map[string]string{retentionDisabledAtAnnotation: retentionNow.Add(-day).Format(time.RFC3339)}
+
+	obj, err := test.r.sync("u-dormant", user)
+	require.NoError(t, err)
+	reactivated := obj.(*v3.User)
+	assert.NotContains(t, reactivated.Annotations, retentionDisabledAtAnnotation)
+	assert.Equal(t, retentionNow.Format(time.RFC3339), reactivated.Annotations[retentionReactivatedAtAnnotation])
+	assert.Equal(t, "Normal UserRetentionReactivated User u-dormant was enabled again", test.event(t))
+
+	test.updated = nil
+	_, err = test.r.sync("u-dormant", reactivated)
+	require.NoError(t, err)
+	assert.Empty(t, test.updated, "expected a user enabled again by an admin not to be disabled before it is dormant again")
+	assert.Equal(t, 30*day, test.enqueued["u-dormant"])
+}
+
+func TestUserRetentionDisabledByAdmin(t *testing.T) {
+	defer setRetentionPolicy(t, "720h", "1h", "false")()
+
+	test := newRetentionTest(t, nil, nil, nil)
+	user := newRetentionUser("u-disabled")
+	enabled := false
+	user.Enabled = &enabled
+
+	_, err := test.r.sync("u-disabled", user)
+	require.NoError(t, err)
+	assert.Empty(t, test.updated)
+	assert.Empty(t, test.deletedUsers, "expected a user disabled by an admin not to be deleted")
+}
+
+func TestUserRetentionDryRun(t *testing.T) {
+	defer setRetentionPolicy(t, "720h", "1440h", "true")()
+
+	test := newRetentionTest(t, map[string]time.Time{"u-dormant": retentionNow.Add(-365 * day)}, nil,
+		[]*v3.Token{{ObjectMeta: metav1.ObjectMeta{Name: "token-1"}, UserID: "u-dormant"}})
+
+	_, err := test.r.sync("u-dormant", newRetentionUser("u-dormant"))
+	require.NoError(t, err)
+	assert.Empty(t, test.updated)
+	assert.Empty(t, test.deletedTokens)
+	assert.Equal(t, "Normal UserRetentionDryRun User u-dormant would be disabled, it is inactive since 2020-09-01T00:00:00Z", test.event(t))
+
+	user := newRetentionUser("u-disabled")
+	enabled := false
+	user.Enabled = &enabled
+	user.Annotations = map[string]string{retentionDisabledAtAnnotation: retentionNow.Add(-90 * day).Format(time.RFC3339)}
+	_, err = test.r.sync("u-disabled", user)
+	require.NoError(t, err)
+	assert.Empty(t, test.deletedUsers)
+	assert.Equal(t, "Normal UserRetentionDryRun User u-disabled would be deleted, it is disabled since 2021-06-03T00:00:00Z", test.event(t))
+}
+
+func TestUserRetentionExclusions(t *testing.T) {
+	defer setRetentionPolicy(t, "720h", "1440h", "false")()
+
+	admin := newRetentionUser("user-admin")
+	admin.Labels = map[string]string{defaultAdminLabelKey: defaultAdminLabelValue}
+	system := newRetentionUser("u-system")
+	system.PrincipalIDs = []string{"system://c-abcde"}
+
+	tests := []struct {
+		name     string
+		user     *v3.User
+		crtbs    []*v3.ClusterRoleTemplateBinding
+		disabled bool
+	}{
+		{
+			name: "default admin",
+			user: admin,
+		},
+		{
+			name: "system user",
+			user: system,
+		},
+		{
+			name:  "sole cluster owner",
+			user:  newRetentionUser("u-owner"),
+			crtbs: []*v3.ClusterRoleTemplateBinding{newOwnerBinding("crtb-1", "c-1", "u-owner", "")},
+		},
+		{
+			name: "sole owner of one of its clusters",
+			user: newRetentionUser("u-owner"),
+			crtbs: []*v3.ClusterRoleTemplateBinding{
+				newOwnerBinding("crtb-1", "c-1", "u-owner", ""),
+				newOwnerBinding("crtb-2", "c-1", "u-other", ""),
+				newOwnerBinding("crtb-3", "c-2", "u-owner", ""),
+			},
+		},
+		{
+			name: "cluster owned by another user",
+			user: newRetentionUser("u-owner"),
+			crtbs: []*v3.ClusterRoleTemplateBinding{
+				newOwnerBinding("crtb-1", "c-1", "u-owner", ""),
+				newOwnerBinding("crtb-2", "c-1", "u-other", ""),
+			},
+			disabled: true,
+		},
+		{
+			name: "cluster owned by a group",
+			user: newRetentionUser("u-owner"),
+			crtbs: []*v3.ClusterRoleTemplateBinding{
+				newOwnerBinding("crtb-1", "c-1", "u-owner", ""),
+				newOwnerBinding("crtb-2", "c-1", "", "okta_group://admins"),
+			},
+			disabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newRetentionTest(t, map[string]time.Time{"u-owner": retentionNow.Add(-40 * day)}, tt.crtbs, nil)
+			_, err := test.r.sync(tt.user.Name, tt.user)
+			require.NoError(t, err)
+			if tt.disabled {
+				assert.Len(t, test.updated, 1)
+			} else {
+				assert.Empty(t, test.updated)
+				assert.Empty(t, test.enqueued)
+			}
+		})
+	}
+}
+
+func TestUserRetentionFirstSeen(t *testing.T) {
+	defer setRetentionPolicy(t, "720h", "1440h", "false")()
+
+	test := newRetentionTest(t, nil, nil, []*v3.Token{{ObjectMeta: metav1.ObjectMeta{Name: "token-1"}, UserID: "u-unknown"}})
+
+	obj, err := test.r.sync("u-unknown", newRetentionUser("u-unknown"))
+	require.NoError(t, err)
+	seen := obj.(*v3.User)
+	assert.Nil(t, seen.Enabled, "expected a user whose activity was never recorded not to be disabled")
+	assert.Equal(t, retentionNow.Format(time.RFC3339), seen.Annotations[retentionFirstSeenAtAnnotation])
+	assert.Empty(t, test.deletedTokens)
+	assert.Equal(t, 30*day, test.enqueued["u-unknown"])
+
+	test.updated = nil
+	test.now = retentionNow.Add(10 * day)
+	_, err = test.r.sync("u-unknown", seen)
+	require.NoError(t, err)
+	assert.Empty(t, test.updated)
+	assert.Equal(t, 20*day, test.enqueued["u-unknown"])
+
+	test.now = retentionNow.Add(30 * day)
+	obj, err = test.r.sync("u-unknown", seen)
+	require.NoError(t, err)
+	disabled := obj.(*v3.User)
+	require.NotNil(t, disabled.Enabled)
+	assert.False(t, *disabled.Enabled)
+	assert.Equal(t, []string{"token-1"}, test.deletedTokens)
+}
+
+func TestUserRetentionTokenActivity(t *testing.T) {
+	defer setRetentionPolicy(t, "720h", "1440h", "false")()
+
+	test := newRetentionTest(t, map[string]time.Time{"u-api": retentionNow.Add(-40 * day)}, nil, nil)
+	test.lastActivities["u-api"] = retentionNow.Add(-5 * day)
+
+	_, err := test.r.sync("u-api", newRetentionUser("u-api"))
+	require.NoError(t, err)
+	assert.Empty(t, test.updated, "expected a user using its tokens not to be disabled")
+	assert.Equal(t, 25*day, test.enqueued["u-api"])
+}
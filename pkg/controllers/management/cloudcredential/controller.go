@@ -25,6 +25,7 @@ func Register(ctx context.Context, management *config.ManagementContext) {
 		managementContext: management,
 	}
 	management.Core.Secrets("").AddHandler(ctx, "management-cloudcredential-controller", m.ccSync)
+	registerRotation(ctx, management)
 }
 
 func (n *Controller) ccSync(key string, cloudCredential *v1.Secret) (runtime.Object, error) {
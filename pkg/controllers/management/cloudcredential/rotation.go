@@ -0,0 +1,324 @@
+package cloudcredential
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/eventrecorder"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	typesv1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	nodeTemplateByCloudCredentialIndex = "cloudcredential.cattle.io/node-template-by-cloud-credential"
+	clusterByCloudCredentialIndex      = "cloudcredential.cattle.io/cluster-by-cloud-credential"
+	// dataHashAnnotation records the hash of the credential data last seen on the cloud credential, and on the
+	// operator configs the hash of the credential they were last refreshed for
+	dataHashAnnotation = "cloudcredential.cattle.io/data-hash"
+
+	credentialRejectedReason = "CloudCredentialRejected"
+
+	// credentialValidationTimeout bounds the validation of a rotated credential against its provider
+	credentialValidationTimeout = 30 * time.Second
+)
+
+// credentialValidator checks a credential against its provider, it gives up once the context is done
+type credentialValidator func(ctx context.Context, fields map[string]string) error
+
+// credentialValidators check a credential against its provider, keyed by the config name of the credential fields
+var credentialValidators = map[string]credentialValidator{
+	"amazonec2credentialConfig": validateAmazonCredential,
+	"azurecredentialConfig":     validateAzureCredential,
+}
+
+type rotationController struct {
+	ctx                 context.Context
+	secrets             typesv1.SecretInterface
+	nodeTemplateCache   mgmtcontrollers.NodeTemplateCache
+	clusterCache        mgmtcontrollers.ClusterCache
+	enqueueNodeTemplate func(namespace, name string)
+	enqueueCluster      func(name string)
+	dynamicClient       dynamic.Interface
+	eventRecorder       record.EventRecorder
+	validators          map[string]credentialValidator
+	validationTimeout   time.Duration
+}
+
+func registerRotation(ctx context.Context, management *config.ManagementContext) {
+	nodeTemplates := management.Wrangler.Mgmt.NodeTemplate()
+	clusters := management.Wrangler.Mgmt.Cluster()
+	nodeTemplates.Cache().AddIndexer(nodeTemplateByCloudCredentialIndex, nodeTemplateByCloudCredential)
+	clusters.Cache().AddIndexer(clusterByCloudCredentialIndex, clusterByCloudCredential)
+
+	r := &rotationController{
+		ctx:                 ctx,
+		secrets:             management.Core.Secrets(""),
+		nodeTemplateCache:   nodeTemplates.Cache(),
+		clusterCache:        clusters.Cache(),
+		enqueueNodeTemplate: nodeTemplates.Enqueue,
+		enqueueCluster:      clusters.Enqueue,
+		dynamicClient:       management.DynamicClient,
+		eventRecorder:       eventrecorder.New(ctx, management.K8sClient, "cloudcredential-rotation"),
+		validators:          credentialValidators,
+		validationTimeout:   credentialValidationTimeout,
+	}
+	management.Core.Secrets("").AddHandler(ctx, "management-cloudcredential-rotation", r.sync)
+}
+
+func nodeTemplateByCloudCredential(obj *v3.NodeTemplate) ([]string, error) {
+	if obj.Spec.CloudCredentialName == "" {
+		return nil, nil
+	}
+	return []string{obj.Spec.CloudCredentialName}, nil
+}
+
+func clusterByCloudCredential(obj *v3.Cluster) ([]string, error) {
+	var credential string
+	switch {
+	case obj.Spec.EKSConfig != nil:
+		credential = obj.Spec.EKSConfig.AmazonCredentialSecret
+	case obj.Spec.AKSConfig != nil:
+		credential = obj.Spec.AKSConfig.AzureCredentialSecret
+	case obj.Spec.GKEConfig != nil:
+		credential = obj.Spec.GKEConfig.GoogleCredentialSecret
+	}
+	if credential == "" {
+		return nil, nil
+	}
+	return []string{credential}, nil
+}
+
+// sync fans out a change of the data of a cloud credential to the node templates and hosted clusters referencing it.
+// The first time a cloud credential is seen its data hash is only recorded, so nothing is refreshed on startup.
+func (r *rotationController) sync(key string, cloudCredential *v1.Secret) (runtime.Object, error) {
+	if cloudCredential == nil || cloudCredential.DeletionTimestamp != nil ||
+		cloudCredential.Namespace != namespace.GlobalNamespace || !configExists(cloudCredential.Data) {
+		return cloudCredential, nil
+	}
+
+	hash := dataHash(cloudCredential.Data)
+	previous, seen := cloudCredential.Annotations[dataHashAnnotation]
+	if previous == hash {
+		return cloudCredential, nil
+	}
+
+	if seen {
+		logrus.Infof("[cloudcredential] cloud credential %s was rotated, refreshing the node templates and clusters using it", cloudCredential.Name)
+		if err := r.rotate(cloudCredential, hash); err != nil {
+			return cloudCredential, err
+		}
+	}
+
+	cloudCredential = cloudCredential.DeepCopy()
+	if cloudCredential.Annotations == nil {
+		cloudCredential.Annotations = map[string]string{}
+	}
+	cloudCredential.Annotations[dataHashAnnotation] = hash
+	if _, err := r.secrets.Update(cloudCredential); err != nil {
+		return nil, err
+	}
+	return cloudCredential, nil
+}
+
+func (r *rotationController) rotate(cloudCredential *v1.Secret, hash string) error {
+	credentialID := cloudCredential.Namespace + ":" + cloudCredential.Name
+
+	nodeTemplates, err := r.nodeTemplateCache.GetByIndex(nodeTemplateByCloudCredentialIndex, credentialID)
+	if err != nil {
+		return err
+	}
+	clusters, err := r.clusterCache.GetByIndex(clusterByCloudCredentialIndex, credentialID)
+	if err != nil {
+		return err
+	}
+
+	for _, nodeTemplate := range nodeTemplates {
+		r.enqueueNodeTemplate(nodeTemplate.Namespace, nodeTemplate.Name)
+	}
+	for _, cluster := range clusters {
+		if err := r.refreshOperatorConfig(cluster, hash); err != nil {
+			return err
+		}
+		r.enqueueCluster(cluster.Name)
+	}
+
+	configName, fields := credentialFields(cloudCredential.Data)
+	if validate := r.validators[configName]; validate != nil && (len(nodeTemplates) > 0 || len(clusters) > 0) {
+		if configName == "amazonec2credentialConfig" && fields["defaultRegion"] == "" {
+			fields["defaultRegion"] = eksRegion(clusters)
+		}
+		// the provider is called outside of the handler so that a slow provider doesn't hold up the secrets
+		go r.validate(credentialID, validate, fields, nodeTemplates, clusters)
+	}
+	return nil
+}
+
+// validate checks the rotated credential against its provider and records an event on the node templates and clusters
+// using it if the provider rejects it
+func (r *rotationController) validate(credentialID string, validate credentialValidator, fields map[string]string, nodeTemplates []*v3.NodeTemplate, clusters []*v3.Cluster) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.validationTimeout)
+	defer cancel()
+
+	rejected := validate(ctx, fields)
+	if rejected == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		logrus.Warnf("[cloudcredential] gave up validating the rotated cloud credential %s: %v", credentialID, rejected)
+		return
+	}
+
+	for _, nodeTemplate := range nodeTemplates {
+		r.eventRecorder.Eventf(nodeTemplate, v1.EventTypeWarning, credentialRejectedReason,
+			"cloud credential %s was rotated but the provider rejected it: %v", credentialID, rejected)
+	}
+	for _, cluster := range clusters {
+		r.eventRecorder.Eventf(cluster, v1.EventTypeWarning, credentialRejectedReason,
+			"cloud credential %s was rotated but the provider rejected it: %v", credentialID, rejected)
+	}
+}
+
+// eksRegion returns the region of the first EKS cluster, the region the credential is used in when it has no default
+// region
+func eksRegion(clusters []*v3.Cluster) string {
+	for _, cluster := range clusters {
+		if cluster.Spec.EKSConfig != nil && cluster.Spec.EKSConfig.Region != "" {
+			return cluster.Spec.EKSConfig.Region
+		}
+	}
+	return ""
+}
+
+// refreshOperatorConfig updates the operator config of the hosted cluster so the operator reconciles it again with the
+// new credential
+func (r *rotationController) refreshOperatorConfig(cluster *v3.Cluster, hash string) error {
+	var resource schema.GroupVersionResource
+	switch {
+	case cluster.Spec.EKSConfig != nil:
+		resource = schema.GroupVersionResource{Group: "eks.cattle.io", Version: "v1", Resource: "eksclusterconfigs"}
+	case cluster.Spec.AKSConfig != nil:
+		resource = schema.GroupVersionResource{Group: "aks.cattle.io", Version: "v1", Resource: "aksclusterconfigs"}
+	case cluster.Spec.GKEConfig != nil:
+		resource = schema.GroupVersionResource{Group: "gke.cattle.io", Version: "v1", Resource: "gkeclusterconfigs"}
+	default:
+		return nil
+	}
+
+	client := r.dynamicClient.Resource(resource).Namespace(namespace.GlobalNamespace)
+	operatorConfig, err := client.Get(context.TODO(), cluster.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	annotations := operatorConfig.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotations[dataHashAnnotation] == hash {
+		return nil
+	}
+	annotations[dataHashAnnotation] = hash
+	operatorConfig.SetAnnotations(annotations)
+	_, err = client.Update(context.TODO(), operatorConfig, metav1.UpdateOptions{})
+	return err
+}
+
+func dataHash(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write(data[key])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// credentialFields returns the config name of the cloud credential, such as amazonec2credentialConfig, and its fields
+func credentialFields(data map[string][]byte) (string, map[string]string) {
+	var configName string
+	fields := map[string]string{}
+	for key, val := range data {
+		splitKey := strings.Split(key, "-")
+		if len(splitKey) == 2 && strings.HasSuffix(splitKey[0], "Config") {
+			configName = splitKey[0]
+			fields[splitKey[1]] = string(val)
+		}
+	}
+	return configName, fields
+}
+
+// validateAmazonCredential checks the credential in its default region, or the region of the clusters using it. The
+// credential isn't checked if neither is known, as the partition of the credential depends on the region.
+func validateAmazonCredential(ctx context.Context, fields map[string]string) error {
+	region := fields["defaultRegion"]
+	if region == "" {
+		logrus.Debugf("[cloudcredential] not validating the rotated amazon credential, its region is unknown")
+		return nil
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(region),
+		Credentials: credentials.NewStaticCredentials(fields["accessKey"], fields["secretKey"], ""),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = sts.New(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	return err
+}
+
+func validateAzureCredential(ctx context.Context, fields map[string]string) error {
+	if fields["tenantId"] == "" {
+		// node driver credentials don't require the tenant, it is looked up when provisioning
+		return nil
+	}
+	env := azure.PublicCloud
+	if fields["environment"] != "" {
+		var err error
+		if env, err = azure.EnvironmentFromName(fields["environment"]); err != nil {
+			return err
+		}
+	}
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, fields["tenantId"])
+	if err != nil {
+		return err
+	}
+	spt, err := adal.NewServicePrincipalToken(*oauthConfig, fields["clientId"], fields["clientSecret"], env.ResourceManagerEndpoint)
+	if err != nil {
+		return err
+	}
+	if err := spt.RefreshWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to authenticate: %v", err)
+	}
+	return nil
+}
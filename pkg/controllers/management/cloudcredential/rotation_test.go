@@ -0,0 +1,278 @@
+package cloudcredential
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeNodeTemplateCache struct {
+	mgmtcontrollers.NodeTemplateCache
+	objs    []*v3.NodeTemplate
+	indexer mgmtcontrollers.NodeTemplateIndexer
+}
+
+func (f *fakeNodeTemplateCache) GetByIndex(indexName, key string) ([]*v3.NodeTemplate, error) {
+	var result []*v3.NodeTemplate
+	for _, obj := range f.objs {
+		keys, _ := f.indexer(obj)
+		for _, k := range keys {
+			if k == key {
+				result = append(result, obj)
+			}
+		}
+	}
+	return result, nil
+}
+
+type fakeClusterCache struct {
+	mgmtcontrollers.ClusterCache
+	objs    []*v3.Cluster
+	indexer mgmtcontrollers.ClusterIndexer
+}
+
+func (f *fakeClusterCache) GetByIndex(indexName, key string) ([]*v3.Cluster, error) {
+	var result []*v3.Cluster
+	for _, obj := range f.objs {
+		keys, _ := f.indexer(obj)
+		for _, k := range keys {
+			if k == key {
+				result = append(result, obj)
+			}
+		}
+	}
+	return result, nil
+}
+
+func nodeTemplate(name, credential string) *v3.NodeTemplate {
+	return &v3.NodeTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-nt", Name: name},
+		Spec:       v3.NodeTemplateSpec{CloudCredentialName: credential},
+	}
+}
+
+func eksCluster(name, credential string) *v3.Cluster {
+	return &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v3.ClusterSpec{EKSConfig: &eksv1.EKSClusterConfigSpec{
+			AmazonCredentialSecret: credential,
+			Region:                 "eu-west-3",
+		}},
+	}
+}
+
+func TestCloudCredentialIndexers(t *testing.T) {
+	aks := &v3.Cluster{Spec: v3.ClusterSpec{AKSConfig: &aksv1.AKSClusterConfigSpec{AzureCredentialSecret: "cattle-global-data:cc-azure"}}}
+	keys, err := clusterByCloudCredential(aks)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cattle-global-data:cc-azure"}, keys)
+
+	keys, err = clusterByCloudCredential(eksCluster("c-1", "cattle-global-data:cc-aws"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cattle-global-data:cc-aws"}, keys)
+
+	keys, err = clusterByCloudCredential(&v3.Cluster{})
+	require.NoError(t, err)
+	assert.Empty(t, keys, "expected clusters without a hosted config to not be indexed")
+
+	keys, err = nodeTemplateByCloudCredential(nodeTemplate("nt-1", ""))
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+type rotationFakes struct {
+	controller     *rotationController
+	dynamic        *dynamicfake.FakeDynamicClient
+	recorder       *record.FakeRecorder
+	updated        *v1.Secret
+	nodeTemplates  []string
+	clusters       []string
+	validatorError error
+	// validated receives the fields of the credentials validated
+	validated chan map[string]string
+}
+
+var eksClusterConfigs = schema.GroupVersionResource{Group: "eks.cattle.io", Version: "v1", Resource: "eksclusterconfigs"}
+
+func newRotationFakes() *rotationFakes {
+	f := &rotationFakes{
+		recorder:  record.NewFakeRecorder(10),
+		validated: make(chan map[string]string, 10),
+	}
+	eksConfig := &unstructured.Unstructured{}
+	eksConfig.SetAPIVersion("eks.cattle.io/v1")
+	eksConfig.SetKind("EKSClusterConfig")
+	eksConfig.SetNamespace("cattle-global-data")
+	eksConfig.SetName("c-1")
+	f.dynamic = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), eksConfig)
+
+	f.controller = &rotationController{
+		ctx: context.Background(),
+		secrets: &fakes.SecretInterfaceMock{
+			UpdateFunc: func(in1 *v1.Secret) (*v1.Secret, error) {
+				f.updated = in1
+				return in1, nil
+			},
+		},
+		nodeTemplateCache: &fakeNodeTemplateCache{
+			objs: []*v3.NodeTemplate{
+				nodeTemplate("nt-1", "cattle-global-data:cc-aws"),
+				nodeTemplate("nt-2", "cattle-global-data:cc-other"),
+			},
+			indexer: nodeTemplateByCloudCredential,
+		},
+		clusterCache: &fakeClusterCache{
+			objs: []*v3.Cluster{
+				eksCluster("c-1", "cattle-global-data:cc-aws"),
+				eksCluster("c-2", "cattle-global-data:cc-other"),
+			},
+			indexer: clusterByCloudCredential,
+		},
+		enqueueNodeTemplate: func(namespace, name string) {
+			f.nodeTemplates = append(f.nodeTemplates, namespace+":"+name)
+		},
+		enqueueCluster: func(name string) {
+			f.clusters = append(f.clusters, name)
+		},
+		dynamicClient: f.dynamic,
+		eventRecorder: f.recorder,
+		validators: map[string]credentialValidator{
+			"amazonec2credentialConfig": func(ctx context.Context, fields map[string]string) error {
+				f.validated <- fields
+				if f.validatorError != nil {
+					return f.validatorError
+				}
+				return ctx.Err()
+			},
+		},
+		validationTimeout: time.Minute,
+	}
+	return f
+}
+
+func credential(accessKey string, annotations map[string]string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-data", Name: "cc-aws", Annotations: annotations},
+		Data: map[string][]byte{
+			"amazonec2credentialConfig-accessKey": []byte(accessKey),
+			"amazonec2credentialConfig-secretKey": []byte("secret"),
+		},
+	}
+}
+
+func TestRotationFanOut(t *testing.T) {
+	f := newRotationFakes()
+	previous := dataHash(credential("old", nil).Data)
+
+	_, err := f.controller.sync("", credential("new", map[string]string{dataHashAnnotation: previous}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"cattle-global-nt:nt-1"}, f.nodeTemplates)
+	assert.Equal(t, []string{"c-1"}, f.clusters)
+	require.NotNil(t, f.updated)
+	hash := dataHash(credential("new", nil).Data)
+	assert.Equal(t, hash, f.updated.Annotations[dataHashAnnotation])
+
+	eksConfig, err := f.dynamic.Resource(eksClusterConfigs).Namespace("cattle-global-data").Get(context.TODO(), "c-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, hash, eksConfig.GetAnnotations()[dataHashAnnotation], "expected the operator config to be refreshed")
+	assert.Empty(t, f.recorder.Events)
+}
+
+func TestRotationFirstSeen(t *testing.T) {
+	f := newRotationFakes()
+
+	_, err := f.controller.sync("", credential("new", nil))
+	require.NoError(t, err)
+
+	assert.Empty(t, f.nodeTemplates, "expected nothing to be refreshed for a credential seen for the first time")
+	assert.Empty(t, f.clusters)
+	require.NotNil(t, f.updated)
+	assert.Equal(t, dataHash(credential("new", nil).Data), f.updated.Annotations[dataHashAnnotation])
+}
+
+func TestRotationUnchanged(t *testing.T) {
+	f := newRotationFakes()
+	hash := dataHash(credential("same", nil).Data)
+
+	_, err := f.controller.sync("", credential("same", map[string]string{dataHashAnnotation: hash}))
+	require.NoError(t, err)
+
+	assert.Empty(t, f.nodeTemplates)
+	assert.Nil(t, f.updated)
+}
+
+func TestRotationRejected(t *testing.T) {
+	f := newRotationFakes()
+	f.validatorError = errors.New("InvalidClientTokenId")
+	previous := dataHash(credential("old", nil).Data)
+
+	_, err := f.controller.sync("", credential("new", map[string]string{dataHashAnnotation: previous}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"cattle-global-nt:nt-1"}, f.nodeTemplates, "expected dependents to be refreshed even if rejected")
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-f.recorder.Events:
+			assert.Contains(t, event, credentialRejectedReason)
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected an event on the node template and on the cluster")
+		}
+	}
+}
+
+func TestRotationValidationRegion(t *testing.T) {
+	f := newRotationFakes()
+	previous := dataHash(credential("old", nil).Data)
+
+	_, err := f.controller.sync("", credential("new", map[string]string{dataHashAnnotation: previous}))
+	require.NoError(t, err)
+	select {
+	case fields := <-f.validated:
+		assert.Equal(t, "eu-west-3", fields["defaultRegion"], "expected the credential to be validated in the region of the cluster using it")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the rotated credential to be validated")
+	}
+
+	withRegion := credential("newer", map[string]string{dataHashAnnotation: previous})
+	withRegion.Data["amazonec2credentialConfig-defaultRegion"] = []byte("us-west-2")
+	_, err = f.controller.sync("", withRegion)
+	require.NoError(t, err)
+	select {
+	case fields := <-f.validated:
+		assert.Equal(t, "us-west-2", fields["defaultRegion"], "expected the default region of the credential to take precedence")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the rotated credential to be validated")
+	}
+}
+
+func TestRotationValidationTimeout(t *testing.T) {
+	f := newRotationFakes()
+	f.controller.validationTimeout = 0
+	previous := dataHash(credential("old", nil).Data)
+
+	_, err := f.controller.sync("", credential("new", map[string]string{dataHashAnnotation: previous}))
+	require.NoError(t, err)
+	<-f.validated
+	select {
+	case event := <-f.recorder.Events:
+		t.Fatalf("expected a validation which timed out not to be reported as a rejection, got %s", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -6,7 +6,9 @@
 
 	"github.com/rancher/rancher/pkg/api/steve/proxy"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	util "github.com/rancher/rancher/pkg/cluster"
 	managementcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/remotedialer"
 	"github.com/rancher/wrangler/pkg/condition"
@@ -26,6 +28,8 @@ func Register(ctx context.Context, wrangler *wrangler.Context) {
 		clusterCache: wrangler.Mgmt.Cluster().Cache(),
 		clusters:     wrangler.Mgmt.Cluster(),
 		tunnelServer: wrangler.TunnelServer,
+		heartbeats:   util.NewAgentHeartbeats(),
+		now:          time.Now,
 	}
 
 	go func() {
@@ -41,6 +45,8 @@ type checker struct {
 	clusterCache managementcontrollers.ClusterCache
 	clusters     managementcontrollers.ClusterClient
 	tunnelServer *remotedialer.Server
+	heartbeats   *util.AgentHeartbeats
+	now          func() time.Time
 }
 
 func (c *checker) check() error {
@@ -63,11 +69,8 @@ func (c *checker) checkCluster(cluster *v3.Cluster) error {
 	}
 
 	hasSession := c.tunnelServer.HasSession(proxy.Prefix + cluster.Name)
-	// The simpler condition of hasSession == Connected.IsTrue(cluster) is not
-	// used because it treat a non-existent conditions as False
-	if hasSession && Connected.IsTrue(cluster) {
-		return nil
-	} else if !hasSession && Connected.IsFalse(cluster) {
+	updated, changed := c.updateStatus(cluster, hasSession)
+	if !changed {
 		return nil
 	}
 
@@ -76,14 +79,15 @@ func (c *checker) checkCluster(cluster *v3.Cluster) error {
 	)
 
 	for i := 0; i < 3; i++ {
-		cluster = cluster.DeepCopy()
-		Connected.SetStatusBool(cluster, hasSession)
-		_, err = c.clusters.Update(cluster)
+		_, err = c.clusters.Update(updated)
 		if apierror.IsConflict(err) {
 			cluster, err = c.clusters.Get(cluster.Name, metav1.GetOptions{})
 			if err != nil {
 				return err
 			}
+			if updated, changed = c.updateStatus(cluster, hasSession); !changed {
+				return nil
+			}
 			continue
 		} else if err != nil {
 			return err
@@ -93,3 +97,46 @@ func (c *checker) checkCluster(cluster *v3.Cluster) error {
 
 	return err
 }
+
+// updateStatus returns a copy of the cluster with its connectivity, the agent heartbeat of an open tunnel session and
+// the staleness of the heartbeat updated, and true if any of them changed.
+func (c *checker) updateStatus(cluster *v3.Cluster, hasSession bool) (*v3.Cluster, bool) {
+	var lastSeen string
+	heartbeatDue := false
+	if hasSession {
+		lastSeen, heartbeatDue = c.heartbeats.Due(cluster, "")
+	}
+
+	stale := util.AgentHeartbeatStale(cluster, time.Duration(settings.AgentHeartbeatStaleThreshold.GetInt())*time.Second, c.now())
+	if heartbeatDue {
+		stale = false
+	}
+
+	// The simpler condition of hasSession == Connected.IsTrue(cluster) is not
+	// used because it treat a non-existent conditions as False
+	connectedChanged := hasSession && !Connected.IsTrue(cluster) || !hasSession && !Connected.IsFalse(cluster)
+	staleChanged := stale && !v3.ClusterConditionAgentHeartbeatStale.IsTrue(cluster) ||
+		!stale && v3.ClusterConditionAgentHeartbeatStale.IsTrue(cluster)
+	if !connectedChanged && !heartbeatDue && !staleChanged {
+		return cluster, false
+	}
+
+	cluster = cluster.DeepCopy()
+	if connectedChanged {
+		Connected.SetStatusBool(cluster, hasSession)
+	}
+	if heartbeatDue {
+		cluster.Status.AgentLastSeen = lastSeen
+	}
+	if staleChanged {
+		if stale {
+			logrus.Infof("Agent of cluster [%s] last checked in at %s", cluster.Name, cluster.Status.AgentLastSeen)
+			v3.ClusterConditionAgentHeartbeatStale.True(cluster)
+			v3.ClusterConditionAgentHeartbeatStale.Message(cluster, "agent last checked in at "+cluster.Status.AgentLastSeen)
+		} else {
+			v3.ClusterConditionAgentHeartbeatStale.False(cluster)
+			v3.ClusterConditionAgentHeartbeatStale.Message(cluster, "")
+		}
+	}
+	return cluster, true
+}
@@ -0,0 +1,60 @@
+package clusterconnected
+
+import (
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	util "github.com/rancher/rancher/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateStatusAgentHeartbeatStale(t *testing.T) {
+	now := time.Now()
+	c := &checker{
+		heartbeats: util.NewAgentHeartbeats(),
+		now:        func() time.Time { return now },
+	}
+	cluster := &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"},
+		Status:     v3.ClusterStatus{AgentLastSeen: now.Add(-time.Minute).UTC().Format(time.RFC3339)},
+	}
+	Connected.SetStatusBool(cluster, false)
+
+	_, changed := c.updateStatus(cluster, false)
+	assert.False(t, changed, "expected a recent heartbeat not to be stale")
+
+	now = now.Add(10 * time.Minute)
+	cluster, changed = c.updateStatus(cluster, false)
+	assert.True(t, changed)
+	assert.True(t, v3.ClusterConditionAgentHeartbeatStale.IsTrue(cluster), "expected the heartbeat to be stale after the threshold")
+	assert.Contains(t, v3.ClusterConditionAgentHeartbeatStale.GetMessage(cluster), cluster.Status.AgentLastSeen)
+
+	_, changed = c.updateStatus(cluster, false)
+	assert.False(t, changed, "expected no update while the heartbeat stays stale")
+
+	now = time.Now()
+	cluster, changed = c.updateStatus(cluster, true)
+	assert.True(t, changed)
+	assert.True(t, Connected.IsTrue(cluster))
+	assert.True(t, v3.ClusterConditionAgentHeartbeatStale.IsFalse(cluster), "expected the tunnel session to refresh the heartbeat")
+	lastSeen, err := time.Parse(time.RFC3339, cluster.Status.AgentLastSeen)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, now, lastSeen, time.Minute)
+
+	_, changed = c.updateStatus(cluster, true)
+	assert.False(t, changed, "expected the heartbeat of the tunnel session to be rate limited")
+}
+
+func TestUpdateStatusAgentNeverSeen(t *testing.T) {
+	c := &checker{
+		heartbeats: util.NewAgentHeartbeats(),
+		now:        time.Now,
+	}
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	Connected.SetStatusBool(cluster, false)
+
+	_, changed := c.updateStatus(cluster, false)
+	assert.False(t, changed, "expected no staleness for a cluster whose agent never checked in")
+}
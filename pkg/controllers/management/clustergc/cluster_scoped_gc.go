@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -9,6 +10,8 @@
 	"github.com/rancher/norman/resource"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/jailer"
+	"github.com/rancher/rancher/pkg/systemaccount"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/wrangler/pkg/generic"
 	"github.com/sirupsen/logrus"
@@ -24,14 +27,16 @@
 
 func Register(ctx context.Context, management *config.ManagementContext) {
 	gc := &gcLifecycle{
-		mgmt: management,
+		mgmt:                 management,
+		systemAccountManager: systemaccount.NewManager(management),
 	}
 
 	management.Management.Clusters("").AddLifecycle(ctx, "cluster-scoped-gc", gc)
 }
 
 type gcLifecycle struct {
-	mgmt *config.ManagementContext
+	mgmt                 *config.ManagementContext
+	systemAccountManager *systemaccount.Manager
 }
 
 func (c *gcLifecycle) Create(obj *v3.Cluster) (runtime.Object, error) {
@@ -87,6 +92,21 @@ func (c *gcLifecycle) waitForNodeRemoval(cluster *v3.Cluster) error {
 	return nil
 }
 
+// removeClusterResources removes the jail directory of the nodes of the cluster and the system user and token created
+// for the cluster, every step is a no-op once done so that a partial failure is retried as a whole
+func (c *gcLifecycle) removeClusterResources(cluster *v3.Cluster) error {
+	if err := jailer.RemoveJail(cluster.Name); err != nil {
+		return fmt.Errorf("failed to remove the jail of cluster [%s]: %w", cluster.Name, err)
+	}
+	if err := c.systemAccountManager.RemoveSystemClusterToken(cluster.Name); err != nil {
+		return fmt.Errorf("failed to remove the system token of cluster [%s]: %w", cluster.Name, err)
+	}
+	if err := c.systemAccountManager.RemoveSystemAccount(cluster.Name); err != nil {
+		return fmt.Errorf("failed to remove the system user of cluster [%s]: %w", cluster.Name, err)
+	}
+	return nil
+}
+
 // Remove check all objects that have had a cluster scoped finalizer added to them to ensure dangling finalizers do not
 // remain on objects that no longer have handlers associated with them
 func (c *gcLifecycle) Remove(cluster *v3.Cluster) (runtime.Object, error) {
@@ -94,6 +114,10 @@ func (c *gcLifecycle) Remove(cluster *v3.Cluster) (runtime.Object, error) {
 		return cluster, err // ErrSkip if we still need to wait
 	}
 
+	if err := c.removeClusterResources(cluster); err != nil {
+		return cluster, err
+	}
+
 	RESTconfig := c.mgmt.RESTConfig
 	// due to the large number of api calls, temporary raise the burst limit in order to reduce client throttling
 	RESTconfig.Burst = 25
@@ -0,0 +1,62 @@
+package clusteroperator
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+)
+
+const crdRecheckInterval = 5 * time.Second
+
+// CRDReadinessGate reports whether the CRDs of a cluster operator are served. The check is shared by all clusters of
+// the operator: once the CRDs are served the result is cached, and while they are not only one discovery request is
+// made at a time and no more than once per crdRecheckInterval, other callers get the last result without waiting.
+type CRDReadinessGate struct {
+	discovery    discovery.DiscoveryInterface
+	groupVersion string
+
+	ready     int32
+	checking  int32
+	lastCheck int64
+}
+
+func NewCRDReadinessGate(discovery discovery.DiscoveryInterface, groupVersion string) *CRDReadinessGate {
+	return &CRDReadinessGate{
+		discovery:    discovery,
+		groupVersion: groupVersion,
+	}
+}
+
+// Ready returns true once the CRDs of the group version are served by the API server
+func (g *CRDReadinessGate) Ready() bool {
+	if atomic.LoadInt32(&g.ready) == 1 {
+		return true
+	}
+
+	if time.Since(time.Unix(0, atomic.LoadInt64(&g.lastCheck))) < crdRecheckInterval {
+		return false
+	}
+	if !atomic.CompareAndSwapInt32(&g.checking, 0, 1) {
+		// another cluster is already checking, don't wait on it
+		return false
+	}
+	defer atomic.StoreInt32(&g.checking, 0)
+	defer atomic.StoreInt64(&g.lastCheck, time.Now().UnixNano())
+
+	resources, err := g.discovery.ServerResourcesForGroupVersion(g.groupVersion)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logrus.Debugf("error checking if %s crds are served: %v", g.groupVersion, err)
+		}
+		return false
+	}
+	if resources == nil || len(resources.APIResources) == 0 {
+		return false
+	}
+
+	atomic.StoreInt32(&g.ready, 1)
+	return true
+}
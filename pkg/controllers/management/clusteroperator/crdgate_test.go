@@ -0,0 +1,64 @@
+package clusteroperator
+
+import (
+	"testing"
+	"time"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+type fakeDiscovery struct {
+	discovery.DiscoveryInterface
+	resources *metav1.APIResourceList
+	calls     int
+}
+
+func (f *fakeDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	f.calls++
+	if f.resources == nil {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: "aks.cattle.io"}, groupVersion)
+	}
+	return f.resources, nil
+}
+
+func TestCheckCrdReadyNotReady(t *testing.T) {
+	var requeue time.Duration
+	e := &OperatorController{
+		ClusterClient: fakeClusterClient{},
+		ClusterEnqueueAfter: func(name string, duration time.Duration) {
+			requeue = duration
+		},
+		CRDGate: NewCRDReadinessGate(&fakeDiscovery{}, "aks.cattle.io/v1"),
+	}
+	cluster := &mgmtv3.Cluster{}
+	cluster.Name = "c-test"
+
+	cluster, ready, err := e.CheckCrdReady(cluster, "aks")
+	require.NoError(t, err, "expected a not ready crd to re-enqueue without an error")
+	assert.False(t, ready)
+	assert.Equal(t, crdRecheckInterval, requeue)
+	assert.Equal(t, "Unknown", apimgmtv3.ClusterConditionWaiting.GetStatus(cluster))
+	assert.Equal(t, "Waiting on aks crd to be initialized", apimgmtv3.ClusterConditionWaiting.GetMessage(cluster))
+}
+
+func TestCRDReadinessGate(t *testing.T) {
+	d := &fakeDiscovery{}
+	gate := NewCRDReadinessGate(d, "aks.cattle.io/v1")
+
+	assert.False(t, gate.Ready())
+	assert.False(t, gate.Ready())
+	assert.Equal(t, 1, d.calls, "expected discovery to be rate limited while the crds are not served")
+
+	d.resources = &metav1.APIResourceList{APIResources: []metav1.APIResource{{Name: "aksclusterconfigs"}}}
+	gate.lastCheck = 0
+	assert.True(t, gate.Ready())
+	assert.True(t, gate.Ready())
+	assert.Equal(t, 2, d.calls, "expected the ready result to be cached for all clusters")
+}
@@ -0,0 +1,50 @@
+package clusteroperator
+
+import (
+	"sync"
+)
+
+type endpointObservation struct {
+	apiEndpoint string
+	caCert      string
+}
+
+// EndpointDriftTracker tracks API endpoint and CA cert changes reported by a cluster operator. Operators can briefly
+// report an empty or stale endpoint while a hosted cluster is updated, so a change is only accepted once the same
+// values have been observed twice in a row.
+type EndpointDriftTracker struct {
+	lock    sync.Mutex
+	pending map[string]endpointObservation
+}
+
+func NewEndpointDriftTracker() *EndpointDriftTracker {
+	return &EndpointDriftTracker{
+		pending: map[string]endpointObservation{},
+	}
+}
+
+// Observe records the API endpoint and CA cert reported for a cluster. It returns changed as true when the cluster
+// status should be switched to the reported values, and pending as true when a change was seen for the first time
+// and must be observed again before it is accepted.
+func (d *EndpointDriftTracker) Observe(clusterName, apiEndpoint, caCert, currentAPIEndpoint, currentCACert string) (changed, pending bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// never switch to an empty endpoint or CA, the operator is still reconciling
+	if apiEndpoint == "" || caCert == "" || (apiEndpoint == currentAPIEndpoint && caCert == currentCACert) {
+		delete(d.pending, clusterName)
+		return false, false
+	}
+
+	observation := endpointObservation{
+		apiEndpoint: apiEndpoint,
+		caCert:      caCert,
+	}
+	if previous, ok := d.pending[clusterName]; ok && previous == observation {
+		delete(d.pending, clusterName)
+		return true, false
+	}
+
+	d.pending[clusterName] = observation
+	return false, true
+}
@@ -0,0 +1,84 @@
+package clusteroperator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	oldEndpoint = "https://public.example.com"
+	newEndpoint = "https://private.example.com"
+	oldCA       = "b2xkLWNh"
+	newCA       = "bmV3LWNh"
+)
+
+func TestEndpointDriftUnchanged(t *testing.T) {
+	d := NewEndpointDriftTracker()
+	for i := 0; i < 3; i++ {
+		changed, pending := d.Observe("c-1", oldEndpoint, oldCA, oldEndpoint, oldCA)
+		assert.False(t, changed)
+		assert.False(t, pending)
+	}
+}
+
+func TestEndpointDriftRequiresTwoObservations(t *testing.T) {
+	d := NewEndpointDriftTracker()
+
+	changed, pending := d.Observe("c-1", newEndpoint, oldCA, oldEndpoint, oldCA)
+	assert.False(t, changed)
+	assert.True(t, pending)
+
+	changed, pending = d.Observe("c-1", newEndpoint, oldCA, oldEndpoint, oldCA)
+	assert.True(t, changed)
+	assert.False(t, pending)
+
+	// a CA change alone is drift as well
+	d.Observe("c-1", oldEndpoint, newCA, oldEndpoint, oldCA)
+	changed, _ = d.Observe("c-1", oldEndpoint, newCA, oldEndpoint, oldCA)
+	assert.True(t, changed)
+}
+
+func TestEndpointDriftIgnoresEmptyEndpoint(t *testing.T) {
+	d := NewEndpointDriftTracker()
+
+	for i := 0; i < 3; i++ {
+		changed, pending := d.Observe("c-1", "", oldCA, oldEndpoint, oldCA)
+		assert.False(t, changed)
+		assert.False(t, pending)
+	}
+
+	// an empty report between two observations of a new endpoint resets the confirmation
+	d.Observe("c-1", newEndpoint, newCA, oldEndpoint, oldCA)
+	d.Observe("c-1", "", "", oldEndpoint, oldCA)
+	changed, pending := d.Observe("c-1", newEndpoint, newCA, oldEndpoint, oldCA)
+	assert.False(t, changed)
+	assert.True(t, pending)
+}
+
+func TestEndpointDriftFlapping(t *testing.T) {
+	d := NewEndpointDriftTracker()
+
+	// alternating reports never get confirmed
+	for i := 0; i < 4; i++ {
+		endpoint := newEndpoint
+		if i%2 == 1 {
+			endpoint = "https://other.example.com"
+		}
+		changed, pending := d.Observe("c-1", endpoint, oldCA, oldEndpoint, oldCA)
+		assert.False(t, changed)
+		assert.True(t, pending)
+	}
+
+	// returning to the current values drops the pending change
+	d.Observe("c-1", newEndpoint, oldCA, oldEndpoint, oldCA)
+	d.Observe("c-1", oldEndpoint, oldCA, oldEndpoint, oldCA)
+	changed, pending := d.Observe("c-1", newEndpoint, oldCA, oldEndpoint, oldCA)
+	assert.False(t, changed)
+	assert.True(t, pending)
+
+	// clusters are tracked independently
+	changed, pending = d.Observe("c-2", newEndpoint, oldCA, oldEndpoint, oldCA)
+	assert.False(t, changed)
+	assert.True(t, pending)
+}
@@ -0,0 +1,142 @@
+package clusteroperator
+
+import (
+	"crypto/x509"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/dialer"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	agentRetryInterval       = 5 * time.Second
+	unreachableRetryInterval = 10 * time.Second
+	userActionRetryInterval  = 5 * time.Minute
+)
+
+var (
+	// ErrUnauthorized means the credentials used to generate the service account token were rejected
+	ErrUnauthorized = stderrors.New("unauthorized")
+	// ErrUnreachable means the cluster API endpoint could not be reached, which is usually temporary
+	ErrUnreachable = stderrors.New("cluster API endpoint is unreachable")
+	// ErrCAMismatch means the cluster API endpoint presented a certificate not signed by the recorded CA
+	ErrCAMismatch = stderrors.New("cluster CA certificate does not match")
+)
+
+// ClassifySATokenError wraps err with ErrUnauthorized, ErrUnreachable or ErrCAMismatch depending on the cause
+// found in its error chain. Errors of any other cause are returned unchanged.
+func ClassifySATokenError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.IsUnauthorized(err):
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	case isCAMismatch(err):
+		return fmt.Errorf("%w: %v", ErrCAMismatch, err)
+	case isUnreachable(err):
+		return fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	return err
+}
+
+func isCAMismatch(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	if stderrors.As(err, &unknownAuthority) {
+		return true
+	}
+	var unknownAuthorityPtr *x509.UnknownAuthorityError
+	return stderrors.As(err, &unknownAuthorityPtr)
+}
+
+// isUnreachable returns true if the cluster API endpoint was never reached: the connection was refused, the host or
+// network was unreachable, the name didn't resolve or the dial timed out. Errors from a server which was reached, such
+// as TLS or read errors, are not unreachable.
+func isUnreachable(err error) bool {
+	if errors.IsServiceUnavailable(err) {
+		return true
+	}
+	if stderrors.Is(err, syscall.ECONNREFUSED) || stderrors.Is(err, syscall.EHOSTUNREACH) || stderrors.Is(err, syscall.ENETUNREACH) {
+		return true
+	}
+
+	var dnsError *net.DNSError
+	if stderrors.As(err, &dnsError) {
+		return dnsError.IsNotFound || dnsError.Err == "no such host"
+	}
+	var opError *net.OpError
+	return stderrors.As(err, &opError) && opError.Op == "dial" && opError.Timeout()
+}
+
+// isTransient returns true if err is an API error which may not happen again on retry: a conflict, too many requests
+// or a server error. Network errors are not retried, a cluster API endpoint which can't be reached has to be reached
+// through the tunnel of its agent without waiting for retries.
+func isTransient(err error) bool {
+	var statusErr errors.APIStatus
+	if !stderrors.As(err, &statusErr) {
+		return false
+	}
+	code := statusErr.Status().Code
+	return code == http.StatusConflict || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// HandleSATokenError sets the Waiting condition of the cluster according to the classification of an error returned
+// while generating the service account token. Private clusters waiting for their cluster agent and unreachable
+// endpoints are retried quietly and soon, errors that need the user to fix the cloud credential or CA are retried
+// rarely. Unclassified errors are returned to the caller.
+func (e *OperatorController) HandleSATokenError(cluster *mgmtv3.Cluster, err error) (*mgmtv3.Cluster, error) {
+	var statusErr error
+	if stderrors.Is(err, dialer.ErrAgentDisconnected) {
+		// In this case, the API endpoint is private and rancher is waiting for the import cluster command to be run.
+		cluster, statusErr = e.SetUnknown(cluster, apimgmtv3.ClusterConditionWaiting, "waiting for cluster agent to be deployed")
+		if statusErr != nil {
+			return cluster, statusErr
+		}
+		e.ClusterEnqueueAfter(cluster.Name, agentRetryInterval)
+		return cluster, nil
+	}
+
+	err = ClassifySATokenError(err)
+	switch {
+	case stderrors.Is(err, ErrUnreachable):
+		logrus.Debugf("cluster [%s] API endpoint is unreachable, retrying: %v", cluster.Name, err)
+		cluster, statusErr = e.SetUnknown(cluster, apimgmtv3.ClusterConditionWaiting, "waiting for cluster API endpoint to be reachable")
+		if statusErr != nil {
+			return cluster, statusErr
+		}
+		e.ClusterEnqueueAfter(cluster.Name, unreachableRetryInterval)
+		return cluster, nil
+	case stderrors.Is(err, ErrUnauthorized):
+		cluster, statusErr = e.SetFalse(cluster, apimgmtv3.ClusterConditionWaiting,
+			fmt.Sprintf("cloud credential is not authorized to access the cluster, please update the cloud credential: %v", err))
+		if statusErr != nil {
+			return cluster, statusErr
+		}
+		e.ClusterEnqueueAfter(cluster.Name, userActionRetryInterval)
+		return cluster, nil
+	case stderrors.Is(err, ErrCAMismatch):
+		cluster, statusErr = e.SetFalse(cluster, apimgmtv3.ClusterConditionWaiting,
+			fmt.Sprintf("cluster CA certificate does not match the API endpoint: %v", err))
+		if statusErr != nil {
+			return cluster, statusErr
+		}
+		e.ClusterEnqueueAfter(cluster.Name, userActionRetryInterval)
+		return cluster, nil
+	}
+
+	cluster, statusErr = e.SetFalse(cluster, apimgmtv3.ClusterConditionWaiting,
+		fmt.Sprintf("failed to communicate with cluster: %v", err))
+	if statusErr != nil {
+		return cluster, statusErr
+	}
+	return cluster, err
+}
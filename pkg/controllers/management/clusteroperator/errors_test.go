@@ -0,0 +1,191 @@
+package clusteroperator
+
+import (
+	"crypto/x509"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/dialer"
+	v3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeClusterClient struct {
+	v3.ClusterClient
+}
+
+func (fakeClusterClient) Update(cluster *mgmtv3.Cluster) (*mgmtv3.Cluster, error) {
+	return cluster, nil
+}
+
+func urlErr(err error) error {
+	return &url.Error{Op: "Post", URL: "https://10.0.0.1/api/v1/namespaces", Err: err}
+}
+
+var (
+	unauthorizedErr = errors.NewUnauthorized("Unauthorized")
+	caMismatchErr   = urlErr(x509.UnknownAuthorityError{})
+	refusedErr      = urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)})
+	dnsErr          = urlErr(&net.DNSError{Err: "no such host", Name: "example.eks.amazonaws.com"})
+	otherErr        = errors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "cattle-system", stderrors.New("denied"))
+)
+
+func TestClassifySATokenError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "unauthorized", err: unauthorizedErr, expected: ErrUnauthorized},
+		{name: "wrapped unauthorized", err: fmt.Errorf("error getting service account token: %w", unauthorizedErr), expected: ErrUnauthorized},
+		{name: "unknown authority", err: caMismatchErr, expected: ErrCAMismatch},
+		{name: "wrapped unknown authority", err: fmt.Errorf("error getting service account token: %w", caMismatchErr), expected: ErrCAMismatch},
+		{name: "connection refused", err: refusedErr, expected: ErrUnreachable},
+		{name: "wrapped connection refused", err: fmt.Errorf("error creating service account: %w", refusedErr), expected: ErrUnreachable},
+		{name: "host unreachable", err: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}), expected: ErrUnreachable},
+		{name: "wrapped host unreachable", err: fmt.Errorf("error getting secret: %w", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)})), expected: ErrUnreachable},
+		{name: "dns", err: dnsErr, expected: ErrUnreachable},
+		{name: "wrapped dns not found", err: fmt.Errorf("error creating role bindings: %w", urlErr(&net.DNSError{Err: "no such host", Name: "example.eks.amazonaws.com", IsNotFound: true})), expected: ErrUnreachable},
+		{name: "dial timeout", err: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: timeoutErr{}}), expected: ErrUnreachable},
+		{name: "wrapped dial timeout", err: fmt.Errorf("error creating admin role: %w", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: timeoutErr{}})), expected: ErrUnreachable},
+		{name: "service unavailable", err: errors.NewServiceUnavailable("unavailable"), expected: ErrUnreachable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClassifySATokenError(tt.err)
+			assert.True(t, stderrors.Is(err, tt.expected), "expected %v to be classified as %v", tt.err, tt.expected)
+		})
+	}
+
+	assert.Nil(t, ClassifySATokenError(nil))
+	assert.Equal(t, otherErr, ClassifySATokenError(otherErr))
+}
+
+func TestClassifySATokenErrorReachedServer(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "read timeout", err: urlErr(&net.OpError{Op: "read", Net: "tcp", Err: timeoutErr{}})},
+		{name: "wrapped read timeout", err: fmt.Errorf("error getting secret: %w", urlErr(&net.OpError{Op: "read", Net: "tcp", Err: timeoutErr{}}))},
+		{name: "connection reset", err: urlErr(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)})},
+		{name: "tls handshake", err: fmt.Errorf("error getting service account: %w", urlErr(&net.OpError{Op: "remote error", Err: stderrors.New("tls: handshake failure")}))},
+		{name: "dial failure other than timeout", err: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: stderrors.New("permission denied")})},
+		{name: "temporary dns failure", err: urlErr(&net.DNSError{Err: "server misbehaving", Name: "example.eks.amazonaws.com", IsTemporary: true})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.False(t, stderrors.Is(ClassifySATokenError(tt.err), ErrUnreachable), "expected %v not to be classified as unreachable", tt.err)
+		})
+	}
+}
+
+func TestHandleSATokenError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantStatus    string
+		wantMessage   string
+		wantRequeue   time.Duration
+		wantReturnErr bool
+	}{
+		{
+			name:        "private cluster waits for the cluster agent",
+			err:         fmt.Errorf("error generating service account token: %w", dialer.ErrAgentDisconnected),
+			wantStatus:  "Unknown",
+			wantMessage: "waiting for cluster agent to be deployed",
+			wantRequeue: agentRetryInterval,
+		},
+		{
+			name:        "unreachable retries quietly",
+			err:         refusedErr,
+			wantStatus:  "Unknown",
+			wantMessage: "waiting for cluster API endpoint to be reachable",
+			wantRequeue: unreachableRetryInterval,
+		},
+		{
+			name:        "unauthorized waits for the user",
+			err:         unauthorizedErr,
+			wantStatus:  "False",
+			wantMessage: "cloud credential is not authorized to access the cluster",
+			wantRequeue: userActionRetryInterval,
+		},
+		{
+			name:        "CA mismatch waits for the user",
+			err:         caMismatchErr,
+			wantStatus:  "False",
+			wantMessage: "cluster CA certificate does not match the API endpoint",
+			wantRequeue: userActionRetryInterval,
+		},
+		{
+			name:          "unclassified errors are returned",
+			err:           otherErr,
+			wantStatus:    "False",
+			wantMessage:   "failed to communicate with cluster",
+			wantReturnErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requeue time.Duration
+			e := &OperatorController{
+				ClusterClient: fakeClusterClient{},
+				ClusterEnqueueAfter: func(name string, duration time.Duration) {
+					requeue = duration
+				},
+			}
+			cluster := &mgmtv3.Cluster{}
+			cluster.Name = "c-test"
+
+			cluster, err := e.HandleSATokenError(cluster, tt.err)
+			if tt.wantReturnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStatus, apimgmtv3.ClusterConditionWaiting.GetStatus(cluster))
+			assert.Contains(t, apimgmtv3.ClusterConditionWaiting.GetMessage(cluster), tt.wantMessage)
+			assert.Equal(t, tt.wantRequeue, requeue)
+		})
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "conflict", err: errors.NewConflict(schema.GroupResource{Resource: "secrets"}, "token", stderrors.New("changed")), expected: true},
+		{name: "too many requests", err: errors.NewTooManyRequests("slow down", 1), expected: true},
+		{name: "internal error", err: errors.NewInternalError(stderrors.New("etcd")), expected: true},
+		{name: "service unavailable", err: errors.NewServiceUnavailable("unavailable"), expected: true},
+		{name: "server timeout", err: errors.NewServerTimeout(schema.GroupResource{Resource: "serviceaccounts"}, "get", 1), expected: true},
+		{name: "wrapped conflict", err: fmt.Errorf("error creating role bindings: %w", errors.NewConflict(schema.GroupResource{Resource: "clusterrolebindings"}, "binding", stderrors.New("changed"))), expected: true},
+		{name: "connection refused", err: refusedErr},
+		{name: "dial timeout", err: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: timeoutErr{}})},
+		{name: "no such host", err: dnsErr},
+		{name: "unauthorized", err: unauthorizedErr},
+		{name: "unknown authority", err: caMismatchErr},
+		{name: "forbidden", err: otherErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isTransient(tt.err))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package clusteroperator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LastConfigDiffAnnotation holds the key paths that changed the last time the operator config of a cluster was
+	// updated from the cluster spec
+	LastConfigDiffAnnotation = "clusteroperator.cattle.io/last-config-diff"
+
+	maxDiffSummaryPaths = 20
+)
+
+// SpecDiff returns the sorted key paths that differ between the spec built from the cluster and the spec of the
+// operator config. Nil and empty slices and maps, and null and missing keys, are considered equal as the cluster spec
+// and the operator config don't always serialize empty values the same way.
+func SpecDiff(desired, current interface{}) []string {
+	var paths []string
+	specDiff("", normalize(desired), normalize(current), &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func specDiff(path string, desired, current interface{}, paths *[]string) {
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	currentMap, currentIsMap := current.(map[string]interface{})
+	if desiredIsMap && currentIsMap {
+		keys := map[string]bool{}
+		for key := range desiredMap {
+			keys[key] = true
+		}
+		for key := range currentMap {
+			keys[key] = true
+		}
+		for key := range keys {
+			specDiff(joinPath(path, key), desiredMap[key], currentMap[key], paths)
+		}
+		return
+	}
+
+	desiredSlice, desiredIsSlice := desired.([]interface{})
+	currentSlice, currentIsSlice := current.([]interface{})
+	if desiredIsSlice && currentIsSlice && len(desiredSlice) == len(currentSlice) {
+		for i := range desiredSlice {
+			specDiff(fmt.Sprintf("%s[%d]", path, i), desiredSlice[i], currentSlice[i], paths)
+		}
+		return
+	}
+
+	if !scalarEqual(desired, current) {
+		if path == "" {
+			path = "."
+		}
+		*paths = append(*paths, path)
+	}
+}
+
+// normalize drops null values and empty slices and maps so they compare equal to missing keys
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, value := range v {
+			if value = normalize(value); value != nil {
+				result[key] = value
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		return result
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = normalize(value)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// scalarEqual compares values, numbers are compared by value as their type depends on how the spec was decoded
+func scalarEqual(desired, current interface{}) bool {
+	desiredNumber, desiredIsNumber := toFloat(desired)
+	currentNumber, currentIsNumber := toFloat(current)
+	if desiredIsNumber && currentIsNumber {
+		return desiredNumber == currentNumber
+	}
+	return reflect.DeepEqual(desired, current)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// DiffSummary joins the changed key paths, limited to the first paths so that it fits in an annotation
+func DiffSummary(paths []string) string {
+	if len(paths) <= maxDiffSummaryPaths {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:maxDiffSummaryPaths], ", "), len(paths)-maxDiffSummaryPaths)
+}
+
+// RecordConfigUpdate logs the key paths that changed between the cluster spec and the spec of its operator config,
+// stores them on the cluster and counts the update so that clusters updating their config in a loop can be detected.
+func (e *OperatorController) RecordConfigUpdate(cluster *mgmtv3.Cluster, configKind string, paths []string) (*mgmtv3.Cluster, error) {
+	summary := DiffSummary(paths)
+	logrus.Infof("change detected for cluster [%s], updating %s, changed: %s", cluster.Name, configKind, summary)
+	metrics.IncClusterConfigUpdates(cluster.Name, configKind)
+
+	if cluster.Annotations[LastConfigDiffAnnotation] == summary {
+		return cluster, nil
+	}
+	cluster = cluster.DeepCopy()
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[LastConfigDiffAnnotation] = summary
+	return e.ClusterClient.Update(cluster)
+}
@@ -0,0 +1,125 @@
+package clusteroperator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		desired  map[string]interface{}
+		current  interface{}
+		expected []string
+	}{
+		{
+			name: "equal specs",
+			desired: map[string]interface{}{
+				"region":  "us-west-2",
+				"subnets": []interface{}{"subnet-1", "subnet-2"},
+			},
+			current: map[string]interface{}{
+				"region":  "us-west-2",
+				"subnets": []interface{}{"subnet-1", "subnet-2"},
+			},
+		},
+		{
+			name: "nil and empty slices",
+			desired: map[string]interface{}{
+				"subnets":        []interface{}{},
+				"securityGroups": nil,
+			},
+			current: map[string]interface{}{
+				"subnets":        nil,
+				"securityGroups": []interface{}{},
+			},
+		},
+		{
+			name: "nil and empty maps",
+			desired: map[string]interface{}{
+				"tags":   map[string]interface{}{},
+				"labels": nil,
+			},
+			current: map[string]interface{}{
+				"tags": nil,
+			},
+		},
+		{
+			name: "empty values and missing keys in nested lists",
+			desired: map[string]interface{}{
+				"nodeGroups": []interface{}{
+					map[string]interface{}{
+						"nodegroupName": "ng-1",
+						"labels":        map[string]interface{}{},
+						"subnets":       []interface{}{},
+						"tags":          nil,
+					},
+				},
+			},
+			current: map[string]interface{}{
+				"nodeGroups": []interface{}{
+					map[string]interface{}{
+						"nodegroupName": "ng-1",
+					},
+				},
+			},
+		},
+		{
+			name:    "empty spec and missing spec",
+			desired: map[string]interface{}{"subnets": []interface{}{}},
+			current: nil,
+		},
+		{
+			name:     "numbers decoded with different types",
+			desired:  map[string]interface{}{"desiredSize": int64(3), "diskSize": int64(20)},
+			current:  map[string]interface{}{"desiredSize": float64(3), "diskSize": int64(40)},
+			expected: []string{"diskSize"},
+		},
+		{
+			name: "changed key paths",
+			desired: map[string]interface{}{
+				"kubernetesVersion": "1.20",
+				"tags":              map[string]interface{}{"team": "a"},
+				"nodeGroups": []interface{}{
+					map[string]interface{}{"nodegroupName": "ng-1", "maxSize": int64(5)},
+				},
+				"subnets": []interface{}{"subnet-1", "subnet-2"},
+			},
+			current: map[string]interface{}{
+				"kubernetesVersion": "1.19",
+				"tags":              map[string]interface{}{"team": "b", "owner": "c"},
+				"nodeGroups": []interface{}{
+					map[string]interface{}{"nodegroupName": "ng-1", "maxSize": int64(3)},
+				},
+				"subnets": []interface{}{"subnet-1"},
+			},
+			expected: []string{
+				"kubernetesVersion",
+				"nodeGroups[0].maxSize",
+				"subnets",
+				"tags.owner",
+				"tags.team",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SpecDiff(tt.desired, tt.current))
+		})
+	}
+}
+
+func TestDiffSummary(t *testing.T) {
+	assert.Equal(t, "a, b", DiffSummary([]string{"a", "b"}))
+
+	var paths []string
+	for i := 0; i < maxDiffSummaryPaths+2; i++ {
+		paths = append(paths, fmt.Sprintf("nodeGroups[%d].maxSize", i))
+	}
+	summary := DiffSummary(paths)
+	assert.Contains(t, summary, "nodeGroups[19].maxSize and 2 more")
+	assert.NotContains(t, summary, "nodeGroups[20]")
+}
@@ -0,0 +1,89 @@
+package clusteroperator
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"k8s.io/client-go/rest"
+)
+
+const defaultPublicAPIDialTimeout = 30 * time.Second
+
+var (
+	// proxyFromEnvironment returns the proxy of the requests to the API endpoint, it is replaced in tests
+	proxyFromEnvironment = http.ProxyFromEnvironment
+	// dialPublicAPI dials the API endpoint with the dialer, it is replaced in tests
+	dialPublicAPI = func(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext
+	}
+)
+
+// GenerateSATokenWithPublicAPI tries to get a service account token from the cluster by dialing its API endpoint
+// directly. It is called for clusters that only have a private API endpoint, to find out if Rancher can reach it anyway.
+//
+// If Rancher can reach the API endpoint, the service account token is returned and the *bool refers to false (doesn't
+// have to tunnel). If the API endpoint can't be reached (see RequiresTunnel), Rancher must use the tunnel of the
+// cluster agent: an empty token is returned and the *bool refers to true (must tunnel).
+//
+// The API endpoint is dialed through the proxy set with the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables,
+// if any, as Rancher may only reach the public endpoint of the cluster through it.
+//
+// If any other error occurs, the *bool is nil, as it could not be determined if tunneling is required.
+func GenerateSATokenWithPublicAPI(restConfig *rest.Config) (string, *bool, error) {
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.Dial = dialPublicAPI(&net.Dialer{
+		Timeout:   publicAPIDialTimeout(),
+		KeepAlive: 30 * time.Second,
+	})
+	if restConfig.Proxy == nil {
+		restConfig.Proxy = proxyFromEnvironment
+	}
+
+	requiresTunnel := new(bool)
+	serviceToken, err := GenerateSAToken(restConfig)
+	if err != nil {
+		if !RequiresTunnel(err) {
+			return "", nil, err
+		}
+		*requiresTunnel = true
+		return "", requiresTunnel, nil
+	}
+
+	return serviceToken, requiresTunnel, nil
+}
+
+// publicAPIDialTimeout returns the timeout of the cluster-public-api-dial-timeout setting, slow links may need more
+// time to reach an endpoint which doesn't require the tunnel
+func publicAPIDialTimeout() time.Duration {
+	seconds, err := strconv.Atoi(settings.ClusterPublicAPIDialTimeout.Get())
+	if err != nil || seconds <= 0 {
+		return defaultPublicAPIDialTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// RequiresTunnel returns true if err means the API endpoint of the cluster can't be reached directly from Rancher:
+// the endpoint name doesn't resolve, or dialing the endpoint or the request timed out. Temporary DNS failures don't
+// require a tunnel.
+func RequiresTunnel(err error) bool {
+	var dnsError *net.DNSError
+	if stderrors.As(err, &dnsError) {
+		return !dnsError.IsTemporary
+	}
+
+	var opError *net.OpError
+	if stderrors.As(err, &opError) && opError.Op == "dial" {
+		return true
+	}
+
+	// In the existence of a proxy, it may be the case that the request times out instead,
+	// in which case rancher should use the tunnel connection to communicate with the cluster.
+	var urlError *url.Error
+	return stderrors.As(err, &urlError) && urlError.Timeout()
+}
@@ -0,0 +1,139 @@
+package clusteroperator
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiresTunnel(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "no such host", err: dnsErr, expected: true},
+		{name: "temporary dns failure", err: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "server misbehaving", Name: "example.eks.amazonaws.com", IsTemporary: true}})},
+		{name: "connection refused", err: refusedErr, expected: true},
+		{name: "dial timeout", err: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: context.DeadlineExceeded}), expected: true},
+		{name: "wrapped dial error", err: fmt.Errorf("error getting service account token: %w", refusedErr), expected: true},
+		{name: "proxy timeout", err: urlErr(&net.OpError{Op: "proxyconnect", Net: "tcp", Err: timeoutErr{}}), expected: true},
+		{name: "proxy refused", err: urlErr(&net.OpError{Op: "proxyconnect", Net: "tcp", Err: stderrors.New("connection refused")})},
+		{name: "unauthorized", err: unauthorizedErr},
+		{name: "unknown authority", err: caMismatchErr},
+		{name: "forbidden", err: otherErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RequiresTunnel(tt.err))
+		})
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+// closedURL returns the URL of an address nothing listens on
+func closedURL(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l.Close()
+	return "http://" + l.Addr().String()
+}
+
+func TestGenerateSATokenWithPublicAPIProxy(t *testing.T) {
+	restConfig, cleanup := setupSATokenTest(t)
+	defer cleanup()
+	endpoint := restConfig.Host
+
+	backend, err := url.Parse(endpoint)
+	require.NoError(t, err)
+	reverseProxy := httputil.NewSingleHostReverseProxy(backend)
+	proxied := 0
+	proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		proxied++
+		reverseProxy.ServeHTTP(rw, req)
+	}))
+	defer proxy.Close()
+
+	defer func(old func(*http.Request) (*url.URL, error)) { proxyFromEnvironment = old }(proxyFromEnvironment)
+	noProxy := func(*http.Request) (*url.URL, error) { return nil, nil }
+	withProxy := func(*http.Request) (*url.URL, error) { return url.Parse(proxy.URL) }
+
+	// the endpoint is reached directly
+	proxyFromEnvironment = noProxy
+	token, requiresTunnel, err := GenerateSATokenWithPublicAPI(restConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "token-v1.20.7-eks-8be107", token)
+	assert.False(t, *requiresTunnel)
+
+	// the endpoint can't be reached directly
+	restConfig.Host = closedURL(t)
+	token, requiresTunnel, err = GenerateSATokenWithPublicAPI(restConfig)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+	assert.True(t, *requiresTunnel)
+	assert.Zero(t, proxied)
+
+	// the endpoint is only reached through the proxy
+	proxyFromEnvironment = withProxy
+	token, requiresTunnel, err = GenerateSATokenWithPublicAPI(restConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "token-v1.20.7-eks-8be107", token)
+	assert.False(t, *requiresTunnel, "expected an endpoint reached through the proxy not to require the tunnel")
+	assert.Equal(t, 1, proxied)
+}
+
+// slowLinkDialer dials over a link taking latency to connect, the dial times out if the latency exceeds the timeout of
+// the dialer
+func slowLinkDialer(latency time.Duration) func(*net.Dialer) func(context.Context, string, string) (net.Conn, error) {
+	return func(dialer *net.Dialer) func(context.Context, string, string) (net.Conn, error) {
+		return func(ctx context.Context, network, address string) (net.Conn, error) {
+			if latency > dialer.Timeout {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: timeoutErr{}}
+			}
+			return dialer.DialContext(ctx, network, address)
+		}
+	}
+}
+
+func TestGenerateSATokenWithPublicAPITimeout(t *testing.T) {
+	restConfig, cleanup := setupSATokenTest(t)
+	defer cleanup()
+
+	oldDial := dialPublicAPI
+	defer func() { dialPublicAPI = oldDial }()
+	dialPublicAPI = slowLinkDialer(45 * time.Second)
+	defer settings.ClusterPublicAPIDialTimeout.Set("30")
+
+	token, requiresTunnel, err := GenerateSATokenWithPublicAPI(restConfig)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+	assert.True(t, *requiresTunnel, "expected the dial to time out after the default timeout")
+
+	require.NoError(t, settings.ClusterPublicAPIDialTimeout.Set("60"))
+	token, requiresTunnel, err = GenerateSATokenWithPublicAPI(restConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "token-v1.20.7-eks-8be107", token)
+	assert.False(t, *requiresTunnel, "expected the configured timeout to be long enough for the slow link")
+
+	require.NoError(t, settings.ClusterPublicAPIDialTimeout.Set("not a number"))
+	_, requiresTunnel, err = GenerateSATokenWithPublicAPI(restConfig)
+	require.NoError(t, err)
+	assert.True(t, *requiresTunnel, "expected an invalid timeout to fall back to the default")
+}
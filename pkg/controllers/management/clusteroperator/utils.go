@@ -22,7 +22,6 @@
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -32,6 +31,20 @@
 const (
 	localCluster = "local"
 	systemNS     = "cattle-system"
+
+	endpointDriftRecheckInterval = 10 * time.Second
+)
+
+var (
+	// saTokenBackoff bounds the attempts of GenerateSAToken on transient API errors
+	saTokenBackoff = wait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    3,
+	}
+
+	generateServiceAccountToken = util.GenerateServiceAccountToken
 )
 
 type OperatorController struct {
@@ -47,7 +60,8 @@ type OperatorController struct {
 	SystemAccountManager *systemaccount.Manager
 	DynamicClient        dynamic.NamespaceableResourceInterface
 	ClientDialer         typesDialer.Factory
-	Discovery            discovery.DiscoveryInterface
+	CRDGate              *CRDReadinessGate
+	EndpointDrift        *EndpointDriftTracker
 }
 
 func (e *OperatorController) SetUnknown(cluster *mgmtv3.Cluster, condition condition.Cond, message string) (*mgmtv3.Cluster, error) {
@@ -122,11 +136,7 @@ func (e *OperatorController) RecordCAAndAPIEndpoint(cluster *mgmtv3.Cluster) (*m
 		return cluster, fmt.Errorf("failed waiting for cluster [%s] secret: %s", cluster.Name, err)
 	}
 
-	apiEndpoint := string(caSecret.Data["endpoint"])
-	if !strings.HasPrefix(apiEndpoint, "https://") {
-		apiEndpoint = "https://" + apiEndpoint
-	}
-	caCert, err := addAdditionalCA(e.SecretsCache, string(caSecret.Data["ca"]))
+	apiEndpoint, caCert, err := e.caAndAPIEndpoint(caSecret)
 	if err != nil {
 		return cluster, err
 	}
@@ -149,29 +159,98 @@ func (e *OperatorController) RecordCAAndAPIEndpoint(cluster *mgmtv3.Cluster) (*m
 	return currentCluster, err
 }
 
-// checkCRDReady checks whether necessary CRD(AKSConfig/EKSConfig/GKEConfig), has been created yet
-func (e *OperatorController) CheckCrdReady(cluster *mgmtv3.Cluster, clusterType string) (*mgmtv3.Cluster, error) {
-	resources, err := e.Discovery.ServerResourcesForGroupVersion(fmt.Sprintf("%s.cattle.io/v1", clusterType))
-	if err != nil && !errors.IsNotFound(err) {
+// DetectCAAndAPIEndpointDrift compares the CA cert and API endpoint in the cluster config's secret with the cluster
+// status. Hosted clusters can change their endpoint, for example when switching from a public to a private endpoint.
+// Once a change has been observed twice in a row the status is updated and the service account token is cleared so
+// that it is regenerated against the new endpoint.
+func (e *OperatorController) DetectCAAndAPIEndpointDrift(cluster *mgmtv3.Cluster) (*mgmtv3.Cluster, error) {
+	caSecret, err := e.SecretsCache.Get(namespace.GlobalNamespace, cluster.Name)
+	if errors.IsNotFound(err) {
+		return cluster, nil
+	} else if err != nil {
 		return cluster, err
 	}
-	if errors.IsNotFound(err) || len(resources.APIResources) == 0 {
-		cluster, err = e.SetUnknown(cluster, apimgmtv3.ClusterConditionProvisioned, fmt.Sprintf("Waiting on %s crd to be initialized", clusterType))
+
+	apiEndpoint, caCert, err := e.caAndAPIEndpoint(caSecret)
+	if err != nil {
+		return cluster, err
+	}
+
+	changed, pending := e.EndpointDrift.Observe(cluster.Name, apiEndpoint, caCert, cluster.Status.APIEndpoint, cluster.Status.CACert)
+	if pending {
+		logrus.Infof("API endpoint or CA cert of cluster [%s] changed, waiting for the change to be confirmed", cluster.Name)
+		e.ClusterEnqueueAfter(cluster.Name, endpointDriftRecheckInterval)
+		return cluster, nil
+	}
+	if !changed {
+		return cluster, nil
+	}
+
+	logrus.Infof("updating API endpoint of cluster [%s] from [%s] to [%s]", cluster.Name, cluster.Status.APIEndpoint, apiEndpoint)
+	var currentCluster *mgmtv3.Cluster
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentCluster, err = e.ClusterClient.Get(cluster.Name, v1.GetOptions{})
 		if err != nil {
-			return cluster, err
+			return err
 		}
-		return cluster, fmt.Errorf("waiting %s crd to be initialized, cluster: %v", clusterType, cluster.Name)
+		currentCluster.Status.APIEndpoint = apiEndpoint
+		currentCluster.Status.CACert = caCert
+		currentCluster.Status.ServiceAccountToken = ""
+		// whether the new endpoint can be reached without the tunnel has to be determined again
+		currentCluster.Status.EKSStatus.PrivateRequiresTunnel = nil
+		currentCluster.Status.AKSStatus.PrivateRequiresTunnel = nil
+		currentCluster.Status.GKEStatus.PrivateRequiresTunnel = nil
+		currentCluster, err = e.ClusterClient.Update(currentCluster)
+		return err
+	})
+
+	return currentCluster, err
+}
+
+func (e *OperatorController) caAndAPIEndpoint(caSecret *corev1.Secret) (string, string, error) {
+	apiEndpoint := string(caSecret.Data["endpoint"])
+	if apiEndpoint != "" && !strings.HasPrefix(apiEndpoint, "https://") {
+		apiEndpoint = "https://" + apiEndpoint
 	}
-	return cluster, nil
+	caCert := string(caSecret.Data["ca"])
+	if caCert == "" {
+		return apiEndpoint, caCert, nil
+	}
+	caCert, err := addAdditionalCA(e.SecretsCache, caCert)
+	return apiEndpoint, caCert, err
 }
 
-func GenerateSAToken(restConfig *rest.Config) (string, error) {
-	clientSet, err := kubernetes.NewForConfig(restConfig)
+// checkCRDReady checks whether necessary CRD(AKSConfig/EKSConfig/GKEConfig), has been created yet
+// CheckCrdReady returns false without an error when the CRDs of the cluster operator are not served yet. The cluster is
+// then marked as waiting and re-enqueued, so clusters are not held in the rate limited error backoff of the handler.
+func (e *OperatorController) CheckCrdReady(cluster *mgmtv3.Cluster, clusterType string) (*mgmtv3.Cluster, bool, error) {
+	if e.CRDGate.Ready() {
+		return cluster, true, nil
+	}
+
+	cluster, err := e.SetUnknown(cluster, apimgmtv3.ClusterConditionWaiting, fmt.Sprintf("Waiting on %s crd to be initialized", clusterType))
 	if err != nil {
-		return "", fmt.Errorf("error creating clientset: %v", err)
+		return cluster, false, err
 	}
+	e.ClusterEnqueueAfter(cluster.Name, crdRecheckInterval)
+	return cluster, false, nil
+}
 
-	return util.GenerateServiceAccountToken(clientSet)
+// GenerateSAToken gets a service account token from the cluster. Transient API errors are retried with backoff before
+// giving up, so that the cluster doesn't flap to a failure condition on a single failed request. Network errors are
+// returned right away so that private clusters fall back to the tunnel of their agent.
+func GenerateSAToken(restConfig *rest.Config) (string, error) {
+	var token string
+	err := retry.OnError(saTokenBackoff, isTransient, func() error {
+		clientSet, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("error creating clientset: %w", err)
+		}
+
+		token, err = generateServiceAccountToken(clientSet)
+		return err
+	})
+	return token, err
 }
 
 func addAdditionalCA(secretsCache wranglerv1.SecretCache, caCert string) (string, error) {
@@ -0,0 +1,121 @@
+package clusteroperator
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// failingDialer fails the first dials with err, then dials the address
+type failingDialer struct {
+	failures int
+	err      error
+	dials    int
+}
+
+func (d *failingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.dials++
+	if d.dials <= d.failures {
+		return nil, d.err
+	}
+	return (&net.Dialer{}).DialContext(ctx, network, address)
+}
+
+// setupSATokenTest serves the version of the cluster API and generates the token from it, the first requests fail with
+// the given statuses and the backoff is shortened
+func setupSATokenTest(t *testing.T, failures ...int) (*rest.Config, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if len(failures) > 0 {
+			rw.WriteHeader(failures[0])
+			failures = failures[1:]
+			return
+		}
+		rw.Write([]byte(`{"gitVersion": "v1.20.7-eks-8be107"}`))
+	}))
+
+	oldBackoff, oldGenerate := saTokenBackoff, generateServiceAccountToken
+	saTokenBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+	generateServiceAccountToken = func(clientset kubernetes.Interface) (string, error) {
+		version, err := clientset.Discovery().ServerVersion()
+		if err != nil {
+			return "", err
+		}
+		return "token-" + version.GitVersion, nil
+	}
+
+	return &rest.Config{Host: server.URL}, func() {
+		server.Close()
+		saTokenBackoff, generateServiceAccountToken = oldBackoff, oldGenerate
+	}
+}
+
+func TestGenerateSATokenRetriesTransientErrors(t *testing.T) {
+	restConfig, cleanup := setupSATokenTest(t, http.StatusConflict, http.StatusServiceUnavailable)
+	defer cleanup()
+
+	token, err := GenerateSAToken(restConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "token-v1.20.7-eks-8be107", token)
+}
+
+func TestGenerateSATokenGivesUp(t *testing.T) {
+	restConfig, cleanup := setupSATokenTest(t, http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError)
+	defer cleanup()
+
+	_, err := GenerateSAToken(restConfig)
+	assert.True(t, errors.IsInternalError(err), "expected the last error to be returned, got %v", err)
+}
+
+func TestGenerateSATokenNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}},
+		{name: "dial timeout", err: &net.OpError{Op: "dial", Net: "tcp", Err: timeoutErr{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restConfig, cleanup := setupSATokenTest(t)
+			defer cleanup()
+
+			dialer := &failingDialer{failures: 5, err: tt.err}
+			restConfig.Dial = dialer.DialContext
+
+			_, err := GenerateSAToken(restConfig)
+			assert.Error(t, err)
+			assert.True(t, RequiresTunnel(err), "expected the tunnel fallback to run")
+			assert.Equal(t, 1, dialer.dials, "expected the network error to be returned without retrying")
+		})
+	}
+}
+
+func TestGenerateSATokenDNSNotFound(t *testing.T) {
+	restConfig, cleanup := setupSATokenTest(t)
+	defer cleanup()
+
+	dialer := &failingDialer{
+		failures: 1,
+		err:      &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.eks.amazonaws.com", IsNotFound: true}},
+	}
+	restConfig.Dial = dialer.DialContext
+
+	_, err := GenerateSAToken(restConfig)
+	assert.True(t, RequiresTunnel(err), "expected a name which doesn't resolve to require the tunnel")
+	assert.Equal(t, 1, dialer.dials, "expected a name which doesn't resolve not to be retried")
+}
@@ -26,6 +26,7 @@
 	"github.com/rancher/rancher/pkg/controllers/management/rbac"
 	"github.com/rancher/rancher/pkg/controllers/management/restrictedadminrbac"
 	"github.com/rancher/rancher/pkg/controllers/management/rkeworkerupgrader"
+	"github.com/rancher/rancher/pkg/controllers/management/settingaudit"
 	"github.com/rancher/rancher/pkg/controllers/management/usercontrollers"
 	"github.com/rancher/rancher/pkg/controllers/managementlegacy"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -58,6 +59,7 @@ func Register(ctx context.Context, management *config.ManagementContext, manager
 	clustertemplate.Register(ctx, management)
 	nodetemplate.Register(ctx, management)
 	rkeworkerupgrader.Register(ctx, management, manager.ScaledContext)
+	settingaudit.Register(ctx, management)
 	rbac.Register(ctx, management)
 	restrictedadminrbac.Register(ctx, management, wrangler)
 	managementlegacy.Register(ctx, management, manager)
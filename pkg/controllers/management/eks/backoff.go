@@ -0,0 +1,62 @@
+package eks
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+)
+
+const maxFailureBackoff = 5 * time.Minute
+
+type failureState struct {
+	message string
+	spec    *eksv1.EKSClusterConfigSpec
+	delay   time.Duration
+}
+
+// failureBackoff tracks how long to wait before reconciling a cluster whose EKSClusterConfig reports a failure. The
+// delay doubles, up to maxFailureBackoff, for as long as the same failure is reported for the same spec.
+type failureBackoff struct {
+	lock     sync.Mutex
+	failures map[string]failureState
+}
+
+func newFailureBackoff() *failureBackoff {
+	return &failureBackoff{
+		failures: map[string]failureState{},
+	}
+}
+
+// next returns the delay before the cluster should be reconciled again given the failure it reports, it is only called
+// when the cluster is requeued for a failure so that the other reconciles don't grow the delay.
+func (f *failureBackoff) next(clusterName, failureMessage string, spec *eksv1.EKSClusterConfigSpec) time.Duration {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	state, ok := f.failures[clusterName]
+	if !ok || state.message != failureMessage || !reflect.DeepEqual(state.spec, spec) {
+		f.failures[clusterName] = failureState{
+			message: failureMessage,
+			spec:    spec.DeepCopy(),
+			delay:   enqueueTime,
+		}
+		return enqueueTime
+	}
+
+	state.delay *= 2
+	if state.delay > maxFailureBackoff {
+		state.delay = maxFailureBackoff
+	}
+	f.failures[clusterName] = state
+	return state.delay
+}
+
+// reset restarts the backoff of the cluster once it no longer reports a failure
+func (f *failureBackoff) reset(clusterName string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	delete(f.failures, clusterName)
+}
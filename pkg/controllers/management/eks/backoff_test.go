@@ -0,0 +1,75 @@
+package eks
+
+import (
+	"testing"
+	"time"
+
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFailureBackoffGrowsWhileFailurePersists(t *testing.T) {
+	f := newFailureBackoff()
+	spec := &eksv1.EKSClusterConfigSpec{DisplayName: "test", Region: "us-west-2"}
+
+	expected := []time.Duration{enqueueTime, 2 * enqueueTime, 4 * enqueueTime, 8 * enqueueTime}
+	for i, delay := range expected {
+		assert.Equal(t, delay, f.next("c-1", "failed to create cluster", spec), "unexpected delay for reconcile %d", i)
+	}
+
+	// the delay is capped
+	for i := 0; i < 20; i++ {
+		f.next("c-1", "failed to create cluster", spec)
+	}
+	assert.Equal(t, maxFailureBackoff, f.next("c-1", "failed to create cluster", spec))
+
+	// other clusters are tracked independently
+	assert.Equal(t, enqueueTime, f.next("c-2", "failed to create cluster", spec))
+}
+
+func TestFailureBackoffResets(t *testing.T) {
+	f := newFailureBackoff()
+	spec := &eksv1.EKSClusterConfigSpec{DisplayName: "test", Region: "us-west-2"}
+
+	f.next("c-1", "failed", spec)
+	f.next("c-1", "failed", spec)
+	assert.Equal(t, 4*enqueueTime, f.next("c-1", "failed", spec))
+
+	// a different failure message restarts the backoff
+	assert.Equal(t, enqueueTime, f.next("c-1", "failed differently", spec))
+	assert.Equal(t, 2*enqueueTime, f.next("c-1", "failed differently", spec))
+
+	// a spec change restarts the backoff
+	changed := spec.DeepCopy()
+	changed.Region = "us-east-1"
+	assert.Equal(t, enqueueTime, f.next("c-1", "failed differently", changed))
+	assert.Equal(t, 2*enqueueTime, f.next("c-1", "failed differently", changed))
+
+	// clearing the failure restarts the backoff
+	f.reset("c-1")
+	assert.Equal(t, enqueueTime, f.next("c-1", "failed differently", changed))
+}
+
+func TestFailureBackoffDroppedForRemovedClusters(t *testing.T) {
+	e := &eksOperatorController{failureBackoff: newFailureBackoff()}
+	spec := &eksv1.EKSClusterConfigSpec{DisplayName: "test", Region: "us-west-2"}
+	for _, name := range []string{"c-deleted", "c-deleting", "c-imported"} {
+		e.failureBackoff.next(name, "failed", spec)
+	}
+
+	_, err := e.onClusterChange("c-deleted", nil)
+	require.NoError(t, err)
+
+	now := v1.Now()
+	_, err = e.onClusterChange("c-deleting", &mgmtv3.Cluster{ObjectMeta: v1.ObjectMeta{Name: "c-deleting", DeletionTimestamp: &now}})
+	require.NoError(t, err)
+
+	// a cluster which is no longer an EKS cluster
+	_, err = e.onClusterChange("c-imported", &mgmtv3.Cluster{ObjectMeta: v1.ObjectMeta{Name: "c-imported"}})
+	require.NoError(t, err)
+
+	assert.Empty(t, e.failureBackoff.failures)
+}
@@ -5,27 +5,31 @@
 	"encoding/base64"
 	stderrors "errors"
 	"fmt"
-	"net"
-	"net/url"
 	"reflect"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/rancher/eks-operator/controller"
 	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
 	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/clustermanager"
 	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
 	"github.com/rancher/rancher/pkg/controllers/management/clusterupstreamrefresher"
 	"github.com/rancher/rancher/pkg/controllers/management/rbac"
-	"github.com/rancher/rancher/pkg/dialer"
+	wranglermgmtv3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/systemaccount"
 	"github.com/rancher/rancher/pkg/types/config"
 	typesDialer "github.com/rancher/rancher/pkg/types/config/dialer"
 	"github.com/rancher/rancher/pkg/wrangler"
+	wranglerv1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -33,6 +37,7 @@
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	authzv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
 )
@@ -47,8 +52,16 @@
 	importedAnno        = "eks.cattle.io/imported"
 )
 
+// errCredentialRequired is returned for the EKS clusters without a cloud credential which may not use the default AWS
+// credential chain of Rancher
+var errCredentialRequired = stderrors.New("an amazon cloud credential is required")
+
 type eksOperatorController struct {
 	clusteroperator.OperatorController
+	failureBackoff *failureBackoff
+	ctx            context.Context
+	sarClient      authzv1client.SubjectAccessReviewInterface
+	userAttributes wranglermgmtv3.UserAttributeCache
 }
 
 func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.ManagementContext) {
@@ -59,7 +72,7 @@ func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.M
 	}
 
 	eksCCDynamicClient := mgmtCtx.DynamicClient.Resource(eksClusterConfigResource)
-	e := &eksOperatorController{clusteroperator.OperatorController{
+	e := &eksOperatorController{OperatorController: clusteroperator.OperatorController{
 		ClusterEnqueueAfter:  wContext.Mgmt.Cluster().EnqueueAfter,
 		SecretsCache:         wContext.Core.Secret().Cache(),
 		TemplateCache:        wContext.Mgmt.CatalogTemplate().Cache(),
@@ -72,23 +85,28 @@ func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.M
 		SystemAccountManager: systemaccount.NewManager(mgmtCtx),
 		DynamicClient:        eksCCDynamicClient,
 		ClientDialer:         mgmtCtx.Dialer,
-		Discovery:            wContext.K8s.Discovery(),
-	}}
+		CRDGate:              clusteroperator.NewCRDReadinessGate(wContext.K8s.Discovery(), eksV1),
+		EndpointDrift:        clusteroperator.NewEndpointDriftTracker(),
+	},
+		failureBackoff: newFailureBackoff(),
+		ctx:            ctx,
+		sarClient:      wContext.K8s.AuthorizationV1().SubjectAccessReviews(),
+		userAttributes: wContext.Mgmt.UserAttribute().Cache(),
+	}
 
 	wContext.Mgmt.Cluster().OnChange(ctx, "eks-operator-controller", e.onClusterChange)
 }
 
 func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Cluster) (*mgmtv3.Cluster, error) {
-	if cluster == nil || cluster.DeletionTimestamp != nil {
-		return cluster, nil
-	}
-
-	if cluster.Spec.EKSConfig == nil {
+	if cluster == nil || cluster.DeletionTimestamp != nil || cluster.Spec.EKSConfig == nil {
+		// the backoff of the clusters which are removed, or no longer EKS clusters, is dropped, the key of a cluster
+		// is its name
+		e.failureBackoff.reset(key)
 		return cluster, nil
 	}
 
-	cluster, err := e.CheckCrdReady(cluster, "eks")
-	if err != nil {
+	cluster, ready, err := e.CheckCrdReady(cluster, "eks")
+	if err != nil || !ready {
 		return cluster, err
 	}
 
@@ -133,8 +151,11 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 	}
 
 	// check for changes between EKS spec on cluster and the EKS spec on the EKSClusterConfig object
-	if !reflect.DeepEqual(eksClusterConfigMap, eksClusterConfigDynamic.Object["spec"]) {
-		logrus.Infof("change detected for cluster [%s], updating EKSClusterConfig", cluster.Name)
+	if diff := clusteroperator.SpecDiff(eksClusterConfigMap, eksClusterConfigDynamic.Object["spec"]); len(diff) > 0 {
+		cluster, err = e.RecordConfigUpdate(cluster, "EKSClusterConfig", diff)
+		if err != nil {
+			return cluster, err
+		}
 		return e.updateEKSClusterConfig(cluster, eksClusterConfigDynamic, eksClusterConfigMap)
 	}
 
@@ -145,6 +166,10 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 	if strings.Contains(failureMessage, "403") {
 		failureMessage = fmt.Sprintf("cannot access EKS, check cloud credential: %s", failureMessage)
 	}
+	// the backoff restarts once the failure is resolved
+	if failureMessage == "" {
+		e.failureBackoff.reset(cluster.Name)
+	}
 	switch phase {
 	case "creating":
 		if cluster.Status.EKSStatus.UpstreamSpec == nil {
@@ -157,7 +182,7 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			return cluster, nil
 		}
 
-		e.ClusterEnqueueAfter(cluster.Name, enqueueTime)
+		e.ClusterEnqueueAfter(cluster.Name, e.requeueAfter(cluster, failureMessage))
 		if failureMessage == "" {
 			logrus.Infof("waiting for cluster EKS [%s] to finish creating", cluster.Name)
 			return e.SetUnknown(cluster, apimgmtv3.ClusterConditionProvisioned, "")
@@ -242,6 +267,11 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			return e.RecordCAAndAPIEndpoint(cluster)
 		}
 
+		cluster, err = e.DetectCAAndAPIEndpointDrift(cluster)
+		if err != nil {
+			return cluster, err
+		}
+
 		if cluster.Status.EKSStatus.PrivateRequiresTunnel == nil && !*cluster.Status.EKSStatus.UpstreamSpec.PublicAccess {
 			// In this case, the API endpoint is private and it has not been determined if Rancher must tunnel to communicate with it.
 			// Check to see if we can still use the public API endpoint even though
@@ -261,21 +291,7 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 		if cluster.Status.ServiceAccountToken == "" {
 			cluster, err = e.generateAndSetServiceAccount(cluster)
 			if err != nil {
-				var statusErr error
-				if err == dialer.ErrAgentDisconnected {
-					// In this case, the API endpoint is private and rancher is waiting for the import cluster command to be run.
-					cluster, statusErr = e.SetUnknown(cluster, apimgmtv3.ClusterConditionWaiting, "waiting for cluster agent to be deployed")
-					if statusErr == nil {
-						e.ClusterEnqueueAfter(cluster.Name, enqueueTime)
-					}
-					return cluster, statusErr
-				}
-				cluster, statusErr = e.SetFalse(cluster, apimgmtv3.ClusterConditionWaiting,
-					fmt.Sprintf("failed to communicate with cluster: %v", err))
-				if statusErr != nil {
-					return cluster, statusErr
-				}
-				return cluster, err
+				return e.HandleSATokenError(cluster, err)
 			}
 		}
 
@@ -315,7 +331,7 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			return cluster, err
 		}
 
-		e.ClusterEnqueueAfter(cluster.Name, enqueueTime)
+		e.ClusterEnqueueAfter(cluster.Name, e.requeueAfter(cluster, failureMessage))
 		if failureMessage == "" {
 			logrus.Infof("waiting for cluster EKS [%s] to update", cluster.Name)
 			return e.SetUnknown(cluster, apimgmtv3.ClusterConditionUpdated, "")
@@ -333,7 +349,7 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			logrus.Infof("waiting for cluster create [%s] to start", cluster.Name)
 		}
 
-		e.ClusterEnqueueAfter(cluster.Name, enqueueTime)
+		e.ClusterEnqueueAfter(cluster.Name, e.requeueAfter(cluster, failureMessage))
 		if failureMessage == "" {
 			if cluster.Spec.EKSConfig.Imported {
 				cluster, err = e.SetUnknown(cluster, apimgmtv3.ClusterConditionPending, "")
@@ -351,6 +367,15 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 	}
 }
 
+// requeueAfter returns the delay before the cluster is reconciled again, backing off from clusters that keep reporting
+// the same failure
+func (e *eksOperatorController) requeueAfter(cluster *mgmtv3.Cluster, failureMessage string) time.Duration {
+	if failureMessage == "" {
+		return enqueueTime
+	}
+	return e.failureBackoff.next(cluster.Name, failureMessage, cluster.Spec.EKSConfig)
+}
+
 func (e *eksOperatorController) setInitialUpstreamSpec(cluster *mgmtv3.Cluster) (*mgmtv3.Cluster, error) {
 	logrus.Infof("setting initial upstreamSpec on cluster [%s]", cluster.Name)
 	cluster = cluster.DeepCopy()
@@ -470,51 +495,69 @@ func (e *eksOperatorController) recordAppliedSpec(cluster *mgmtv3.Cluster) (*mgm
 	return e.ClusterClient.Update(cluster)
 }
 
-// generateSATokenWithPublicAPI tries to get a service account token from the cluster using the public API endpoint.
-// This function is called if the cluster has only privateEndpoint enabled and not publicly available.
-// If Rancher is able to communicate with the cluster through its API endpoint even though it is private, then this function will retrieve
-// a service account token and the *bool returned will refer to a false value (doesn't have to tunnel).
-//
-// If the Rancher server cannot connect to the cluster's API endpoint, then one of the two errors below will happen.
-// In this case, we know that Rancher must use the cluster agent tunnel for communication. This function will return an empty service account token,
-// and the *bool return value will refer to a true value (must tunnel).
-//
-// If an error different from the two below occur, then the *bool return value will be nil, indicating that Rancher was not able to determine if
-// tunneling is required to communicate with the cluster.
+// generateSATokenWithPublicAPI tries to get a service account token from the cluster using its private API endpoint,
+// see clusteroperator.GenerateSATokenWithPublicAPI.
 func (e *eksOperatorController) generateSATokenWithPublicAPI(cluster *mgmtv3.Cluster) (string, *bool, error) {
-	restConfig, err := e.getRestConfig(cluster, (&net.Dialer{
-		Timeout:   30 * time.Second,
-		KeepAlive: 30 * time.Second,
-	}).DialContext)
+	restConfig, err := e.getRestConfig(cluster, nil)
 	if err != nil {
 		return "", nil, err
 	}
+	return clusteroperator.GenerateSATokenWithPublicAPI(restConfig)
+}
 
-	requiresTunnel := new(bool)
-	serviceToken, err := clusteroperator.GenerateSAToken(restConfig)
-	if err != nil {
-		*requiresTunnel = true
-		var dnsError *net.DNSError
-		if stderrors.As(err, &dnsError) && !dnsError.IsTemporary {
-			return "", requiresTunnel, nil
+// newAWSSession starts a session with the cloud credential referenced by the EKSConfig. If no cloud credential
+// is referenced the default AWS credential chain is used instead, which allows Rancher to authenticate with
+// the role of its pod (IRSA) or instance.
+func newAWSSession(secretsCache wranglerv1.SecretCache, spec eksv1.EKSClusterConfigSpec) (*session.Session, error) {
+	if spec.AmazonCredentialSecret == "" {
+		awsConfig := aws.Config{}
+		if spec.Region != "" {
+			awsConfig.Region = aws.String(spec.Region)
 		}
+		return session.NewSessionWithOptions(session.Options{
+			Config:            awsConfig,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+	}
 
-		// In the existence of a proxy, it may be the case that the following error occurs,
-		// in which case rancher should use the tunnel connection to communicate with the cluster.
-		var urlError *url.Error
-		if stderrors.As(err, &urlError) && urlError.Timeout() {
-			return "", requiresTunnel, nil
-		}
+	sess, _, err := controller.StartAWSSessions(secretsCache, spec)
+	return sess, err
+}
 
-		// Not able to determine if tunneling is required.
-		requiresTunnel = nil
+// checkDefaultCredentialChain returns errCredentialRequired unless the cluster may use the default AWS credential chain
+// of Rancher: the admins must have enabled it and the creator of the cluster must be an admin, as the chain grants the
+// AWS permissions of the Rancher server. It is checked here rather than only by the API so that clusters written
+// through any API are checked.
+func (e *eksOperatorController) checkDefaultCredentialChain(cluster *mgmtv3.Cluster) error {
+	if settings.EKSDefaultCredentialChain.Get() != "true" {
+		return errCredentialRequired
 	}
 
-	return serviceToken, requiresTunnel, err
+	creatorID := cluster.Annotations[rbac.CreatorIDAnn]
+	if creatorID == "" {
+		return errCredentialRequired
+	}
+	attribs, err := e.userAttributes.Get(creatorID)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	isAdmin, err := sar.UserIsAdmin(e.ctx, e.sarClient, creatorID, sar.UserAttributeGroups(attribs))
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return fmt.Errorf("%w, only the clusters created by admins may use the default AWS credential chain", errCredentialRequired)
+	}
+	return nil
 }
 
 func (e *eksOperatorController) getAccessToken(cluster *mgmtv3.Cluster) (string, error) {
-	sess, _, err := controller.StartAWSSessions(e.SecretsCache, *cluster.Spec.EKSConfig)
+	if cluster.Spec.EKSConfig.AmazonCredentialSecret == "" {
+		if err := e.checkDefaultCredentialChain(cluster); err != nil {
+			return "", err
+		}
+	}
+	sess, err := newAWSSession(e.SecretsCache, *cluster.Spec.EKSConfig)
 	if err != nil {
 		return "", err
 	}
@@ -545,14 +588,16 @@ func (e *eksOperatorController) getRestConfig(cluster *mgmtv3.Cluster, dialer ty
 		return nil, err
 	}
 
-	return &rest.Config{
+	restConfig := &rest.Config{
 		Host: cluster.Status.APIEndpoint,
 		TLSClientConfig: rest.TLSClientConfig{
 			CAData: decodedCA,
 		},
 		BearerToken: accessToken,
 		Dial:        dialer,
-	}, nil
+	}
+	clustermanager.SetClientRateLimit(restConfig, cluster)
+	return restConfig, nil
 }
 
 func notFound(err error) bool {
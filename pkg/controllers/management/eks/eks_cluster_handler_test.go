@@ -0,0 +1,163 @@
+package eks
+
+import (
+	"context"
+	stderrors "errors"
+	"os"
+	"testing"
+
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	"github.com/rancher/rancher/pkg/controllers/management/rbac"
+	wranglermgmtv3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	wranglerv1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeSecretCache struct {
+	secrets map[string]*corev1.Secret
+}
+
+func (f *fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	if secret, ok := f.secrets[namespace+":"+name]; ok {
+		return secret, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+func (f *fakeSecretCache) List(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+	return nil, nil
+}
+
+func (f *fakeSecretCache) AddIndexer(indexName string, indexer wranglerv1.SecretIndexer) {}
+
+func (f *fakeSecretCache) GetByIndex(indexName, key string) ([]*corev1.Secret, error) {
+	return nil, nil
+}
+
+func setEnv(t *testing.T, key, value string) {
+	original, ok := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, original)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestNewAWSSession(t *testing.T) {
+	setEnv(t, "AWS_ACCESS_KEY_ID", "default-chain-key")
+	setEnv(t, "AWS_SECRET_ACCESS_KEY", "default-chain-secret")
+
+	secretsCache := &fakeSecretCache{secrets: map[string]*corev1.Secret{
+		"cattle-global-data:cc-test": {
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-data", Name: "cc-test"},
+			Data: map[string][]byte{
+				"amazonec2credentialConfig-accessKey": []byte("explicit-key"),
+				"amazonec2credentialConfig-secretKey": []byte("explicit-secret"),
+			},
+		},
+	}}
+
+	tests := []struct {
+		name          string
+		spec          eksv1.EKSClusterConfigSpec
+		wantAccessKey string
+	}{
+		{
+			name: "explicit credential",
+			spec: eksv1.EKSClusterConfigSpec{
+				AmazonCredentialSecret: "cattle-global-data:cc-test",
+				Region:                 "us-west-2",
+			},
+			wantAccessKey: "explicit-key",
+		},
+		{
+			name: "default credential chain",
+			spec: eksv1.EKSClusterConfigSpec{
+				Region: "us-west-2",
+			},
+			wantAccessKey: "default-chain-key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess, err := newAWSSession(secretsCache, tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, "us-west-2", *sess.Config.Region)
+
+			creds, err := sess.Config.Credentials.Get()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAccessKey, creds.AccessKeyID)
+		})
+	}
+}
+
+type fakeUserAttributeCache struct {
+	wranglermgmtv3.UserAttributeCache
+	attributes map[string]*apimgmtv3.UserAttribute
+}
+
+func (f *fakeUserAttributeCache) Get(name string) (*apimgmtv3.UserAttribute, error) {
+	if attribs, ok := f.attributes[name]; ok {
+		return attribs, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+}
+
+func TestCheckDefaultCredentialChain(t *testing.T) {
+	e := &eksOperatorController{
+		ctx:       context.Background(),
+		sarClient: &fake.SubjectAccessReviews{Admins: map[string]bool{"u-admin": true, "admins": true}},
+		userAttributes: &fakeUserAttributeCache{attributes: map[string]*apimgmtv3.UserAttribute{
+			"u-grouped": {GroupPrincipals: map[string]apimgmtv3.Principals{"local": {Items: []apimgmtv3.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "local://admins"}}}}}},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		creator string
+		wantErr bool
+	}{
+		{name: "disabled", creator: "u-admin", wantErr: true},
+		{name: "admin", enabled: true, creator: "u-admin"},
+		{name: "member of an admin group", enabled: true, creator: "u-grouped"},
+		{name: "member", enabled: true, creator: "u-member", wantErr: true},
+		{name: "no creator", enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := "false"
+			if tt.enabled {
+				value = "true"
+			}
+			orig := settings.EKSDefaultCredentialChain.Get()
+			require.NoError(t, settings.EKSDefaultCredentialChain.Set(value))
+			defer settings.EKSDefaultCredentialChain.Set(orig)
+
+			cluster := &mgmtv3.Cluster{}
+			if tt.creator != "" {
+				cluster.Annotations = map[string]string{rbac.CreatorIDAnn: tt.creator}
+			}
+			err := e.checkDefaultCredentialChain(cluster)
+			if tt.wantErr {
+				assert.True(t, stderrors.Is(err, errCredentialRequired), "expected a credential required error, got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
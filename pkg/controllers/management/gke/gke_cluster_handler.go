@@ -3,11 +3,8 @@
 import (
 	"context"
 	"encoding/base64"
-	stderrors "errors"
 	"fmt"
-	"net"
 	"net/http"
-	"net/url"
 	"reflect"
 	"strings"
 	"time"
@@ -15,10 +12,10 @@
 	"github.com/rancher/gke-operator/controller"
 	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
 	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/clustermanager"
 	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
 	"github.com/rancher/rancher/pkg/controllers/management/clusterupstreamrefresher"
 	"github.com/rancher/rancher/pkg/controllers/management/rbac"
-	"github.com/rancher/rancher/pkg/dialer"
 	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/systemaccount"
@@ -70,7 +67,8 @@ func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.M
 		SystemAccountManager: systemaccount.NewManager(mgmtCtx),
 		DynamicClient:        gkeCCDynamicClient,
 		ClientDialer:         mgmtCtx.Dialer,
-		Discovery:            wContext.K8s.Discovery(),
+		CRDGate:              clusteroperator.NewCRDReadinessGate(wContext.K8s.Discovery(), gkeV1),
+		EndpointDrift:        clusteroperator.NewEndpointDriftTracker(),
 	}}
 
 	wContext.Mgmt.Cluster().OnChange(ctx, "gke-operator-controller", e.onClusterChange)
@@ -92,8 +90,8 @@ func (e *gkeOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 		}
 	}
 
-	cluster, err := e.CheckCrdReady(cluster, "gke")
-	if err != nil {
+	cluster, ready, err := e.CheckCrdReady(cluster, "gke")
+	if err != nil || !ready {
 		return cluster, err
 	}
 
@@ -183,6 +181,11 @@ func (e *gkeOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			return e.RecordCAAndAPIEndpoint(cluster)
 		}
 
+		cluster, err = e.DetectCAAndAPIEndpointDrift(cluster)
+		if err != nil {
+			return cluster, err
+		}
+
 		if cluster.Status.GKEStatus.PrivateRequiresTunnel == nil &&
 			cluster.Status.GKEStatus.UpstreamSpec.PrivateClusterConfig != nil &&
 			cluster.Status.GKEStatus.UpstreamSpec.PrivateClusterConfig.EnablePrivateEndpoint {
@@ -204,21 +207,7 @@ func (e *gkeOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 		if cluster.Status.ServiceAccountToken == "" {
 			cluster, err = e.generateAndSetServiceAccount(cluster)
 			if err != nil {
-				var statusErr error
-				if err == dialer.ErrAgentDisconnected {
-					// In this case, the API endpoint is private and rancher is waiting for the import cluster command to be run.
-					cluster, statusErr = e.SetUnknown(cluster, apimgmtv3.ClusterConditionWaiting, "waiting for cluster agent to be deployed")
-					if statusErr == nil {
-						e.ClusterEnqueueAfter(cluster.Name, enqueueTime)
-					}
-					return cluster, statusErr
-				}
-				cluster, statusErr = e.SetFalse(cluster, apimgmtv3.ClusterConditionWaiting,
-					fmt.Sprintf("failed to communicate with cluster: %v", err))
-				if statusErr != nil {
-					return cluster, statusErr
-				}
-				return cluster, err
+				return e.HandleSATokenError(cluster, err)
 			}
 		}
 
@@ -401,45 +390,14 @@ func (e *gkeOperatorController) recordAppliedSpec(cluster *mgmtv3.Cluster) (*mgm
 	return e.ClusterClient.Update(cluster)
 }
 
-// generateSATokenWithPublicAPI tries to get a service account token from the cluster using the public API endpoint.
-// This function is called if the cluster has only privateEndpoint enabled and not publicly available.
-// If Rancher is able to communicate with the cluster through its API endpoint even though it is private, then this function will retrieve
-// a service account token and the *bool returned will refer to a false value (doesn't have to tunnel).
-//
-// If the Rancher server cannot connect to the cluster's API endpoint, then one of the two errors below will happen.
-// In this case, we know that Rancher must use the cluster agent tunnel for communication. This function will return an empty service account token,
-// and the *bool return value will refer to a true value (must tunnel).
-//
-// If an error different from the two below occur, then the *bool return value will be nil, indicating that Rancher was not able to determine if
-// tunneling is required to communicate with the cluster.
+// generateSATokenWithPublicAPI tries to get a service account token from the cluster using its private API endpoint,
+// see clusteroperator.GenerateSATokenWithPublicAPI.
 func (e *gkeOperatorController) generateSATokenWithPublicAPI(cluster *mgmtv3.Cluster) (string, *bool, error) {
-	restConfig, err := e.getRestConfig(cluster, (&net.Dialer{
-		Timeout:   30 * time.Second,
-		KeepAlive: 30 * time.Second,
-	}).DialContext)
+	restConfig, err := e.getRestConfig(cluster, nil)
 	if err != nil {
 		return "", nil, err
 	}
-	requiresTunnel := new(bool)
-	serviceToken, err := clusteroperator.GenerateSAToken(restConfig)
-	if err != nil {
-		*requiresTunnel = true
-		if strings.Contains(err.Error(), "dial tcp") {
-			return "", requiresTunnel, nil
-		}
-
-		// In the existence of a proxy, it may be the case that the following error occurs,
-		// in which case rancher should use the tunnel connection to communicate with the cluster.
-		var urlError *url.Error
-		if stderrors.As(err, &urlError) && urlError.Timeout() {
-			return "", requiresTunnel, nil
-		}
-
-		// Not able to determine if tunneling is required.
-		requiresTunnel = nil
-	}
-
-	return serviceToken, requiresTunnel, err
+	return clusteroperator.GenerateSATokenWithPublicAPI(restConfig)
 }
 
 func (e *gkeOperatorController) getRestConfig(cluster *mgmtv3.Cluster, dialer typesDialer.Dialer) (*rest.Config, error) {
@@ -454,7 +412,7 @@ func (e *gkeOperatorController) getRestConfig(cluster *mgmtv3.Cluster, dialer ty
 		return nil, err
 	}
 
-	return &rest.Config{
+	restConfig := &rest.Config{
 		Host: cluster.Status.APIEndpoint,
 		TLSClientConfig: rest.TLSClientConfig{
 			CAData: decodedCA,
@@ -466,5 +424,7 @@ func (e *gkeOperatorController) getRestConfig(cluster *mgmtv3.Cluster, dialer ty
 			}
 		},
 		Dial: dialer,
-	}, nil
+	}
+	clustermanager.SetClientRateLimit(restConfig, cluster)
+	return restConfig, nil
 }
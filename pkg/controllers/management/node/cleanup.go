@@ -21,6 +21,7 @@
 	coreV1 "k8s.io/api/core/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
@@ -77,6 +78,15 @@ func (m *Lifecycle) drainNode(node *v3.Node) error {
 		return nil
 	}
 
+	reason, err := m.drainSkipReason(nodeCopy)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		logrus.Infof("node [%s] skipping drain, %s", nodeCopy.Spec.RequestedHostname, reason)
+		return nil
+	}
+
 	logrus.Infof("node [%s] requires draining before delete", nodeCopy.Spec.RequestedHostname)
 	kubeConfig, _, err := m.getKubeConfig(cluster)
 	if err != nil {
@@ -123,6 +133,41 @@ func (m *Lifecycle) drainNode(node *v3.Node) error {
 	})
 }
 
+// drainSkipReason returns why draining the node is pointless, or an empty string if it must be drained. A drain can't
+// complete when no other node of the cluster can run the evicted pods, and would only hang until it times out.
+func (m *Lifecycle) drainSkipReason(node *v3.Node) (string, error) {
+	nodes, err := m.nodeLister.List(node.Namespace, labels.Everything())
+	if err != nil {
+		return "", err
+	}
+
+	remaining, workers := 0, 0
+	for _, other := range nodes {
+		if other.Name == node.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		remaining++
+		if isWorker(other) {
+			workers++
+		}
+	}
+
+	switch {
+	case remaining == 0:
+		return "it is the only node of the cluster", nil
+	case isWorker(node) && workers == 0:
+		return "it is the last worker node of the cluster", nil
+	}
+	return "", nil
+}
+
+func isWorker(node *v3.Node) bool {
+	if node.Status.NodeConfig != nil {
+		return nodehelper.IsWorker(node.Status.NodeConfig)
+	}
+	return node.Spec.Worker
+}
+
 func (m *Lifecycle) cleanRKENode(node *v3.Node) error {
 	cluster, err := m.clusterLister.Get("", node.Namespace)
 	if err != nil {
@@ -0,0 +1,70 @@
+package node
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newDrainTestNode(name string, roles ...string) *v3.Node {
+	return &v3.Node{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: name},
+		Status: v32.NodeStatus{
+			NodeConfig: &rketypes.RKEConfigNode{Role: roles},
+		},
+	}
+}
+
+func newDrainTestLifecycle(nodes ...*v3.Node) *Lifecycle {
+	return &Lifecycle{
+		nodeLister: &fakes.NodeListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.Node, error) {
+				return nodes, nil
+			},
+		},
+	}
+}
+
+func TestDrainSkipReasonSingleNode(t *testing.T) {
+	node := newDrainTestNode("m-abcde", "worker")
+	m := newDrainTestLifecycle(node)
+
+	reason, err := m.drainSkipReason(node)
+	require.NoError(t, err)
+	assert.Equal(t, "it is the only node of the cluster", reason)
+}
+
+func TestDrainSkipReasonLastWorker(t *testing.T) {
+	node := newDrainTestNode("m-abcde", "worker")
+	deleting := newDrainTestNode("m-fghij", "worker")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	m := newDrainTestLifecycle(node, deleting, newDrainTestNode("m-klmno", "etcd", "controlplane"))
+
+	reason, err := m.drainSkipReason(node)
+	require.NoError(t, err)
+	assert.Equal(t, "it is the last worker node of the cluster", reason,
+		"expected the drain to be skipped when the other workers are being deleted")
+}
+
+func TestDrainSkipReasonMultiNode(t *testing.T) {
+	node := newDrainTestNode("m-abcde", "worker")
+	m := newDrainTestLifecycle(node, newDrainTestNode("m-fghij", "worker"), newDrainTestNode("m-klmno", "etcd", "controlplane"))
+
+	reason, err := m.drainSkipReason(node)
+	require.NoError(t, err)
+	assert.Empty(t, reason, "expected a node of a multi-node cluster to be drained")
+
+	controlPlane := newDrainTestNode("m-pqrst", "controlplane")
+	m = newDrainTestLifecycle(controlPlane, newDrainTestNode("m-fghij", "worker"))
+	reason, err = m.drainSkipReason(controlPlane)
+	require.NoError(t, err)
+	assert.Empty(t, reason, "expected a node that isn't a worker to be drained while other nodes remain")
+}
@@ -13,6 +13,7 @@
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/rancher/norman/condition"
 	"github.com/rancher/norman/objectclient"
 	"github.com/rancher/norman/types/convert"
 	"github.com/rancher/norman/types/values"
@@ -22,12 +23,14 @@
 	"github.com/rancher/rancher/pkg/controllers/dashboard/clusterregistrationtoken"
 	"github.com/rancher/rancher/pkg/controllers/management/drivers/nodedriver"
 	"github.com/rancher/rancher/pkg/encryptedstore"
+	"github.com/rancher/rancher/pkg/eventrecorder"
 	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/jailer"
 	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/nodeconfig"
 	"github.com/rancher/rancher/pkg/ref"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/systemaccount"
 	"github.com/rancher/rancher/pkg/taints"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -41,7 +44,9 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	typedauthzv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -51,6 +56,7 @@
 	userNodeRemoveCleanupAnnotationOld = "nodes.management.cattle.io/user-node-remove-cleanup"
 	userNodeRemoveFinalizerPrefix      = "clusterscoped.controller.cattle.io/user-node-remove_"
 	userNodeRemoveAnnotationPrefix     = "lifecycle.cattle.io/create.user-node-remove_"
+	credentialNotFoundReason           = "CredentialNotFound"
 )
 
 // SchemaToDriverFields maps Schema field => driver field
@@ -81,6 +87,7 @@ func Register(ctx context.Context, management *config.ManagementContext, cluster
 		systemAccountManager:      systemaccount.NewManager(management),
 		secretStore:               secretStore,
 		nodeClient:                nodeClient,
+		nodeLister:                nodeClient.Controller().Lister(),
 		nodeTemplateClient:        management.Management.NodeTemplates(""),
 		nodePoolLister:            management.Management.NodePools("").Controller().Lister(),
 		nodePoolController:        management.Management.NodePools("").Controller(),
@@ -88,10 +95,15 @@ func Register(ctx context.Context, management *config.ManagementContext, cluster
 		configMapGetter:           management.K8sClient.CoreV1(),
 		clusterLister:             management.Management.Clusters("").Controller().Lister(),
 		schemaLister:              management.Management.DynamicSchemas("").Controller().Lister(),
+		nodeDriverLister:          management.Management.NodeDrivers("").Controller().Lister(),
+		provisionSlots:            newProvisionSlots(),
 		credLister:                management.Core.Secrets("").Controller().Lister(),
 		userManager:               management.UserManager,
 		systemTokens:              management.SystemTokens,
 		clusterManager:            clusterManager,
+		eventRecorder:             eventrecorder.New(ctx, management.K8sClient, "node-controller"),
+		subjectAccessReviews:      management.K8sClient.AuthorizationV1().SubjectAccessReviews(),
+		userAttributeLister:       management.Management.UserAttributes("").Controller().Lister(),
 		devMode:                   os.Getenv("CATTLE_DEV_MODE") != "",
 	}
 
@@ -105,16 +117,22 @@ type Lifecycle struct {
 	secretStore               *encryptedstore.GenericEncryptedStore
 	nodeTemplateGenericClient objectclient.GenericClient
 	nodeClient                v3.NodeInterface
+	nodeLister                v3.NodeLister
 	nodeTemplateClient        v3.NodeTemplateInterface
 	nodePoolLister            v3.NodePoolLister
 	nodePoolController        v3.NodePoolController
 	configMapGetter           typedv1.ConfigMapsGetter
 	clusterLister             v3.ClusterLister
 	schemaLister              v3.DynamicSchemaLister
+	nodeDriverLister          v3.NodeDriverLister
+	provisionSlots            *provisionSlots
 	credLister                corev1.SecretLister
 	userManager               user.Manager
 	systemTokens              systemtokens.Interface
 	clusterManager            *clustermanager.Manager
+	eventRecorder             record.EventRecorder
+	subjectAccessReviews      typedauthzv1.SubjectAccessReviewInterface
+	userAttributeLister       v3.UserAttributeLister
 	devMode                   bool
 }
 
@@ -190,7 +208,7 @@ func (m *Lifecycle) Create(obj *v3.Node) (runtime.Object, error) {
 
 		err = m.refreshNodeConfig(nodeConfig, obj)
 		if err != nil {
-			return nil, errors.WithMessagef(err, "unable to create config for node %v", obj.Name)
+			return obj, errors.WithMessagef(err, "unable to create config for node %v", obj.Name)
 		}
 
 		template, err := m.getNodeTemplate(obj.Spec.NodeTemplateName)
@@ -198,11 +216,19 @@ func (m *Lifecycle) Create(obj *v3.Node) (runtime.Object, error) {
 			return obj, err
 		}
 		obj.Status.NodeTemplateSpec = &template.Spec
+		// the hostnames of the nodes of a pool are generated from its prefix, like the ones defaulted to the node name
+		explicit := obj.Spec.RequestedHostname != "" && obj.Spec.NodePoolName == ""
 		if obj.Spec.RequestedHostname == "" {
 			obj.Spec.RequestedHostname = obj.Name
 		}
+		hostname, err := normalizeHostname(template.Spec.Driver, obj.Spec.RequestedHostname, explicit)
+		if err != nil {
+			return obj, err
+		}
+		obj.Status.NormalizedHostname = hostname
 
-		if obj.Status.NodeTemplateSpec.EngineInstallURL == "" {
+		// a checksum can't verify the default installer, the template has to name the installer it verifies
+		if obj.Status.NodeTemplateSpec.EngineInstallURL == "" && obj.Status.NodeTemplateSpec.EngineInstallURLChecksum == "" {
 			obj.Status.NodeTemplateSpec.EngineInstallURL = defaultEngineInstallURL
 		}
 
@@ -233,13 +259,9 @@ func (m *Lifecycle) Remove(obj *v3.Node) (runtime.Object, error) {
 	}
 
 	newObj, err := v32.NodeConditionRemoved.DoUntilTrue(obj, func() (runtime.Object, error) {
-		found, err := m.isNodeInAppliedSpec(obj)
-		if err != nil {
+		if err := m.waitForAppliedSpecRemoval(obj); err != nil {
 			return obj, err
 		}
-		if found {
-			return obj, errors.New("waiting for node to be removed from cluster")
-		}
 
 		if !m.devMode {
 			err := jailer.CreateJail(obj.Namespace)
@@ -261,7 +283,7 @@ func (m *Lifecycle) Remove(obj *v3.Node) (runtime.Object, error) {
 
 		err = m.refreshNodeConfig(config, obj)
 		if err != nil {
-			return nil, errors.WithMessagef(err, "unable to refresh config for node %v", obj.Name)
+			return obj, errors.WithMessagef(err, "unable to refresh config for node %v", obj.Name)
 		}
 
 		mExists, err := nodeExists(config.Dir(), obj)
@@ -271,10 +293,7 @@ func (m *Lifecycle) Remove(obj *v3.Node) (runtime.Object, error) {
 
 		if mExists {
 			logrus.Infof("Removing node %s", obj.Spec.RequestedHostname)
-			if err := m.drainNode(obj); err != nil {
-				return obj, err
-			}
-			if err := deleteNode(config.Dir(), obj); err != nil {
+			if err := m.newMachineRemover(config.Remove).remove(config.Dir(), obj); err != nil {
 				return obj, err
 			}
 			logrus.Infof("Removing node %s done", obj.Spec.RequestedHostname)
@@ -290,12 +309,16 @@ func (m *Lifecycle) Remove(obj *v3.Node) (runtime.Object, error) {
 	return m.deleteV1Node(newObj.(*v3.Node))
 }
 
-func (m *Lifecycle) provision(driverConfig, nodeDir string, obj *v3.Node) (*v3.Node, error) {
+func (m *Lifecycle) provision(driverConfig, nodeDir string, obj *v3.Node, saveNow chan<- struct{}) (*v3.Node, error) {
 	configRawMap := map[string]interface{}{}
 	if err := json.Unmarshal([]byte(driverConfig), &configRawMap); err != nil {
 		return obj, errors.Wrap(err, "failed to unmarshal node config")
 	}
 
+	if err := verifyEngineInstallURL(obj.Status.NodeTemplateSpec); err != nil {
+		return obj, err
+	}
+
 	// Since we know this will take a long time persist so user sees status
 	obj, err := m.nodeClient.Update(obj)
 	if err != nil {
@@ -307,11 +330,22 @@ func (m *Lifecycle) provision(driverConfig, nodeDir string, obj *v3.Node) (*v3.N
 		return obj, err
 	}
 
+	driverSchema, err := m.schemaLister.Get("", obj.Status.NodeTemplateSpec.Driver+"config")
+	if err != nil && !kerror.IsNotFound(err) {
+		return obj, err
+	}
+	if driverSchema != nil {
+		normalizeBoolFields(configRawMap, driverSchema.Spec.ResourceFields)
+	}
+
 	createCommandsArgs := buildCreateCommand(obj, configRawMap)
 	cmd, err := buildCommand(nodeDir, obj, createCommandsArgs)
 	if err != nil {
 		return obj, err
 	}
+	jailer.SetProcessGroup(cmd)
+	limits := m.driverLimits(obj.Status.NodeTemplateSpec.Driver)
+	jailer.LimitCommand(cmd, limits.resources)
 
 	logrus.Infof("Provisioning node %s", obj.Spec.RequestedHostname)
 
@@ -319,16 +353,21 @@ func (m *Lifecycle) provision(driverConfig, nodeDir string, obj *v3.Node) (*v3.N
 	if err != nil {
 		return obj, err
 	}
+	// The process group is killed on timeout so that the readers are closed and the config can still be saved
+	timeout := killAfter(cmd, limits.timeout)
+	defer timeout.Stop()
 	defer stdoutReader.Close()
 	defer stderrReader.Close()
 	defer cmd.Wait()
 
-	obj, err = m.reportStatus(stdoutReader, stderrReader, obj)
-	if err != nil {
-		return obj, err
+	obj, err = m.reportStatus(stdoutReader, stderrReader, obj, saveNow)
+	if err == nil {
+		err = cmd.Wait()
 	}
-
-	if err := cmd.Wait(); err != nil {
+	if timeout.Killed() {
+		return obj, errors.Errorf("node driver %s didn't provision node %s within %v", obj.Status.NodeTemplateSpec.Driver, obj.Spec.RequestedHostname, limits.timeout)
+	}
+	if err != nil {
 		return obj, err
 	}
 
@@ -465,6 +504,13 @@ func (m *Lifecycle) authenticateRegistry(nodeDir string, node *v3.Node, cluster
 }
 
 func (m *Lifecycle) ready(obj *v3.Node) (*v3.Node, error) {
+	release, ok := m.provisionSlots.acquire(obj.Namespace)
+	if !ok {
+		return obj, errors.Errorf("waiting for other nodes of cluster %s to finish provisioning, %d nodes are provisioned at once",
+			obj.Namespace, settings.NodeProvisionConcurrency.GetInt())
+	}
+	defer release()
+
 	config, err := nodeconfig.NewNodeConfig(m.secretStore, obj)
 	if err != nil {
 		return obj, err
@@ -477,7 +523,7 @@ func (m *Lifecycle) ready(obj *v3.Node) (*v3.Node, error) {
 
 	err = m.refreshNodeConfig(config, obj)
 	if err != nil {
-		return nil, errors.WithMessagef(err, "unable to refresh config for node %v", obj.Name)
+		return obj, errors.WithMessagef(err, "unable to refresh config for node %v", obj.Name)
 	}
 
 	driverConfig, err := config.DriverConfig()
@@ -487,22 +533,15 @@ func (m *Lifecycle) ready(obj *v3.Node) (*v3.Node, error) {
 
 	// Provision in the background so we can poll and save the config
 	done := make(chan error)
+	saveNow := make(chan struct{}, 1)
 	go func() {
-		newObj, err := m.provision(driverConfig, config.Dir(), obj)
+		newObj, err := m.provision(driverConfig, config.Dir(), obj, saveNow)
 		obj = newObj
 		done <- err
 	}()
 
 	// Poll and save config
-outer:
-	for {
-		select {
-		case err = <-done:
-			break outer
-		case <-time.After(5 * time.Second):
-			config.Save()
-		}
-	}
+	err = pollAndSave(done, saveNow, saveInterval(), config.Save)
 
 	newObj, saveError := v32.NodeConditionConfigSaved.Once(obj, func() (runtime.Object, error) {
 		return m.saveConfig(config, config.FullDir(), obj)
@@ -514,6 +553,28 @@ func (m *Lifecycle) ready(obj *v3.Node) (*v3.Node, error) {
 	return obj, err
 }
 
+// pollAndSave calls save every interval, and whenever saveNow is signaled, until done returns
+func pollAndSave(done <-chan error, saveNow <-chan struct{}, interval time.Duration, save func() error) error {
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-saveNow:
+			save()
+		case <-time.After(interval):
+			save()
+		}
+	}
+}
+
+func saveInterval() time.Duration {
+	interval := settings.NodeConfigSaveInterval.GetInt()
+	if interval <= 0 {
+		interval = 5
+	}
+	return time.Duration(interval) * time.Second
+}
+
 func (m *Lifecycle) sync(key string, obj *v3.Node) (runtime.Object, error) {
 	if obj == nil || obj.DeletionTimestamp != nil {
 		return nil, nil
@@ -524,7 +585,7 @@ func (m *Lifecycle) sync(key string, obj *v3.Node) (runtime.Object, error) {
 		return m.userNodeRemoveCleanup(obj)
 	}
 
-	return obj, nil
+	return m.checkRegistrationTimeout(obj)
 }
 
 func (m *Lifecycle) Updated(obj *v3.Node) (runtime.Object, error) {
@@ -534,6 +595,14 @@ func (m *Lifecycle) Updated(obj *v3.Node) (runtime.Object, error) {
 			return obj, nil
 		}
 
+		template, err := m.getNodeTemplate(obj.Spec.NodeTemplateName)
+		if err != nil {
+			return obj, err
+		}
+		if err := m.checkCredentialAccess(obj, template); err != nil {
+			return obj, err
+		}
+
 		if !m.devMode {
 			logrus.Infof("Creating jail for %v", obj.Namespace)
 			err := jailer.CreateJail(obj.Namespace)
@@ -602,7 +671,7 @@ func (m *Lifecycle) saveConfig(config *nodeconfig.NodeConfig, nodeDir string, ob
 		InternalAddress:  interalAddress,
 		User:             sshUser,
 		Role:             roles(obj),
-		HostnameOverride: obj.Spec.RequestedHostname,
+		HostnameOverride: machineName(obj),
 		SSHKey:           sshKey,
 		Labels:           template.Labels,
 	}
@@ -650,7 +719,7 @@ func (m *Lifecycle) refreshNodeConfig(nc *nodeconfig.NodeConfig, obj *v3.Node) e
 		return fmt.Errorf("refreshNodeConfig: node config not specified for node %v", obj.Name)
 	}
 
-	if err := m.updateRawConfigFromCredential(data, rawConfig, template); err != nil {
+	if err := m.updateRawConfigFromCredential(obj, data, rawConfig, template); err != nil {
 		logrus.Debugf("refreshNodeConfig: error calling updateRawConfigFromCredential for [%v]: %v", obj.Name, err)
 		return err
 	}
@@ -659,6 +728,7 @@ func (m *Lifecycle) refreshNodeConfig(nc *nodeconfig.NodeConfig, obj *v3.Node) e
 
 	if template.Spec.Driver == amazonec2 {
 		setEc2ClusterIDTag(rawConfig, obj.Namespace)
+		setEc2IMDSv2Options(rawConfig, template)
 		logrus.Debug("refreshNodeConfig: Updating amazonec2 machine config")
 		//TODO: Update to not be amazon specific, this needs to be moved to the driver
 		update, err = nc.UpdateAmazonAuth(rawConfig)
@@ -691,6 +761,27 @@ func (m *Lifecycle) refreshNodeConfig(nc *nodeconfig.NodeConfig, obj *v3.Node) e
 	return nil
 }
 
+// waitForAppliedSpecRemoval returns an error while the node is still in the applied spec of its cluster. Once the
+// node-remove-applied-spec-timeout passed since the deletion of the node, the removal proceeds with a warning so
+// that a cluster whose applied spec never updates doesn't keep the node in Removing forever.
+func (m *Lifecycle) waitForAppliedSpecRemoval(node *v3.Node) error {
+	found, err := m.isNodeInAppliedSpec(node)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	timeout := time.Duration(settings.NodeRemoveAppliedSpecTimeout.GetInt()) * time.Second
+	if timeout <= 0 || node.DeletionTimestamp == nil || time.Since(node.DeletionTimestamp.Time) < timeout {
+		return errors.New("waiting for node to be removed from cluster")
+	}
+	logrus.Warnf("[node-controller] node %s/%s is still in the applied spec of its cluster after %v, removing it anyway",
+		node.Namespace, node.Name, timeout)
+	return nil
+}
+
 func (m *Lifecycle) isNodeInAppliedSpec(node *v3.Node) (bool, error) {
 	// worker/controlplane nodes can just be immediately deleted
 	if !node.Spec.Etcd {
@@ -777,12 +868,20 @@ func (m *Lifecycle) setCredFields(data interface{}, fields map[string]v32.Field,
 	if err != nil {
 		return err
 	}
+	resolver, err := credentialResolver(cred)
+	if err != nil {
+		return err
+	}
 	if ans := convert.ToMapInterface(data); len(ans) > 0 {
-		for key, val := range cred.Data {
+		for key := range cred.Data {
 			splitKey := strings.Split(key, "-")
 			if len(splitKey) == 2 && strings.HasSuffix(splitKey[0], "Config") {
 				if _, ok := fields[splitKey[1]]; ok {
-					ans[splitKey[1]] = string(val)
+					val, err := resolver.Resolve(cred, key)
+					if err != nil {
+						return fmt.Errorf("failed to resolve field [%s] of cloud credential [%s]: %w", splitKey[1], credID, err)
+					}
+					ans[splitKey[1]] = val
 				}
 			}
 		}
@@ -790,7 +889,10 @@ func (m *Lifecycle) setCredFields(data interface{}, fields map[string]v32.Field,
 	return nil
 }
 
-func (m *Lifecycle) updateRawConfigFromCredential(data map[string]interface{}, rawConfig interface{}, template *v3.NodeTemplate) error {
+// updateRawConfigFromCredential copies the fields of the cloud credential of the node template to its raw config. The
+// CredentialValid condition of the node is set to false with the name of the credential if it doesn't exist anymore,
+// so that it is clear the credential needs fixing rather than the template.
+func (m *Lifecycle) updateRawConfigFromCredential(node *v3.Node, data map[string]interface{}, rawConfig interface{}, template *v3.NodeTemplate) error {
 	credID := convert.ToString(values.GetValueN(data, "spec", "cloudCredentialName"))
 	if credID != "" {
 		existingSchema, err := m.schemaLister.Get("", template.Spec.Driver+"config")
@@ -799,9 +901,20 @@ func (m *Lifecycle) updateRawConfigFromCredential(data map[string]interface{}, r
 		}
 		logrus.Debugf("setCredFields for credentialName %s", credID)
 		err = m.setCredFields(rawConfig, existingSchema.Spec.ResourceFields, credID)
+		if kerror.IsNotFound(err) {
+			message := fmt.Sprintf("cloud credential [%s] of node template [%s] not found, update the node template with an existing credential",
+				credID, template.Name)
+			v32.NodeConditionCredentialValid.False(node)
+			v32.NodeConditionCredentialValid.Reason(node, credentialNotFoundReason)
+			v32.NodeConditionCredentialValid.Message(node, message)
+			return condition.Error(credentialNotFoundReason, errors.New(message))
+		}
 		if err != nil {
 			return errors.Wrap(err, "failed to set credential fields")
 		}
+		v32.NodeConditionCredentialValid.True(node)
+		v32.NodeConditionCredentialValid.Reason(node, "")
+		v32.NodeConditionCredentialValid.Message(node, "")
 	}
 	return nil
 }
@@ -5,11 +5,23 @@
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/controllers/management/drivers/nodedriver"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	rketypes "github.com/rancher/rke/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestAliasMaps(t *testing.T) {
@@ -76,3 +88,175 @@ func createFakeConfig(fields map[string]string) (map[string]interface{}, []strin
 	return testData, fakeContents
 
 }
+
+func TestReportStatusRequestsSaveOnCreatingMachine(t *testing.T) {
+	assert := assert.New(t)
+	m := &Lifecycle{
+		nodeClient: &fakes.NodeInterfaceMock{
+			UpdateFunc: func(in1 *v3.Node) (*v3.Node, error) {
+				return in1, nil
+			},
+		},
+	}
+	node := &v3.Node{}
+	node.Spec.RequestedHostname = "test-node"
+
+	stdout := strings.NewReader("Running pre-create checks...\nCreating machine...\nWaiting for machine to be running, this may take a few minutes...\n")
+	saveNow := make(chan struct{}, 1)
+
+	_, err := m.reportStatus(stdout, strings.NewReader(""), node, saveNow)
+	assert.Nil(err)
+	assert.Len(saveNow, 1, "expected a save to be requested at the creating machine milestone")
+
+	stdout = strings.NewReader("Running pre-create checks...\n")
+	saveNow = make(chan struct{}, 1)
+
+	_, err = m.reportStatus(stdout, strings.NewReader(""), node, saveNow)
+	assert.Nil(err)
+	assert.Len(saveNow, 0, "expected no save to be requested without the creating machine milestone")
+}
+
+func TestPollAndSaveOnMilestone(t *testing.T) {
+	assert := assert.New(t)
+	done := make(chan error)
+	saveNow := make(chan struct{}, 1)
+	saved := make(chan struct{})
+
+	go func() {
+		saveNow <- struct{}{}
+		<-saved
+		done <- nil
+	}()
+
+	// the interval is long enough that any save must come from the milestone
+	saves := 0
+	err := pollAndSave(done, saveNow, time.Hour, func() error {
+		saves++
+		saved <- struct{}{}
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(1, saves)
+}
+
+func TestSetEc2IMDSv2Options(t *testing.T) {
+	assert := assert.New(t)
+
+	template := &v3.NodeTemplate{}
+	config := map[string]interface{}{}
+	setEc2IMDSv2Options(config, template)
+	assert.Empty(config, "expected no IMDS options unless the template requires IMDSv2")
+
+	template.Spec.RequireIMDSv2 = true
+	setEc2IMDSv2Options(config, template)
+	assert.Equal("enabled", config[ec2HTTPEndpointFlag])
+	assert.Equal("required", config[ec2HTTPTokensFlag])
+	assert.Equal(ec2DefaultHopLimit, config[ec2HopLimitFlag])
+
+	config = map[string]interface{}{ec2HopLimitFlag: "3", ec2HTTPTokensFlag: "optional"}
+	setEc2IMDSv2Options(config, template)
+	assert.Equal("required", config[ec2HTTPTokensFlag])
+	assert.Equal("3", config[ec2HopLimitFlag], "expected an explicit hop limit to be kept")
+}
+
+func newAppliedSpecTestLifecycle(node *v3.Node) *Lifecycle {
+	return &Lifecycle{
+		clusterLister: &fakes.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Cluster, error) {
+				cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}}
+				cluster.Status.AppliedSpec.RancherKubernetesEngineConfig = &rketypes.RancherKubernetesEngineConfig{
+					Nodes: []rketypes.RKEConfigNode{{NodeName: node.Namespace + ":" + node.Name}},
+				}
+				return cluster, nil
+			},
+		},
+	}
+}
+
+func TestWaitForAppliedSpecRemovalWithinTimeout(t *testing.T) {
+	original := settings.NodeRemoveAppliedSpecTimeout.Get()
+	defer settings.NodeRemoveAppliedSpecTimeout.Set(original)
+	require.NoError(t, settings.NodeRemoveAppliedSpecTimeout.Set("600"))
+
+	deleted := metav1.NewTime(time.Now().Add(-time.Minute))
+	node := &v3.Node{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "m-abcde", DeletionTimestamp: &deleted}}
+	node.Spec.Etcd = true
+	m := newAppliedSpecTestLifecycle(node)
+
+	assert.EqualError(t, m.waitForAppliedSpecRemoval(node), "waiting for node to be removed from cluster")
+
+	require.NoError(t, settings.NodeRemoveAppliedSpecTimeout.Set("0"))
+	deleted = metav1.NewTime(time.Now().Add(-24 * time.Hour))
+	assert.Error(t, m.waitForAppliedSpecRemoval(node), "expected a timeout of 0 to wait forever")
+}
+
+func TestWaitForAppliedSpecRemovalPastTimeout(t *testing.T) {
+	original := settings.NodeRemoveAppliedSpecTimeout.Get()
+	defer settings.NodeRemoveAppliedSpecTimeout.Set(original)
+	require.NoError(t, settings.NodeRemoveAppliedSpecTimeout.Set("600"))
+
+	deleted := metav1.NewTime(time.Now().Add(-11 * time.Minute))
+	node := &v3.Node{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "m-abcde", DeletionTimestamp: &deleted}}
+	node.Spec.Etcd = true
+	m := newAppliedSpecTestLifecycle(node)
+
+	assert.NoError(t, m.waitForAppliedSpecRemoval(node), "expected the removal to proceed once the timeout passed")
+
+	node.Spec.Etcd = false
+	node.DeletionTimestamp = nil
+	assert.NoError(t, m.waitForAppliedSpecRemoval(node), "expected a node that isn't etcd not to wait")
+}
+
+func newCredentialTestLifecycle(secrets map[string]*corev1.Secret) *Lifecycle {
+	return &Lifecycle{
+		schemaLister: &fakes.DynamicSchemaListerMock{
+			GetFunc: func(namespace string, name string) (*v3.DynamicSchema, error) {
+				dynamicSchema := &v3.DynamicSchema{ObjectMeta: metav1.ObjectMeta{Name: name}}
+				dynamicSchema.Spec.ResourceFields = map[string]v32.Field{"accessKey": {Type: "string"}, "region": {Type: "string"}}
+				return dynamicSchema, nil
+			},
+		},
+		credLister: &corefakes.SecretListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.Secret, error) {
+				if secret, ok := secrets[name]; ok {
+					return secret, nil
+				}
+				return nil, kerror.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+			},
+		},
+	}
+}
+
+func TestUpdateRawConfigFromCredentialMissing(t *testing.T) {
+	m := newCredentialTestLifecycle(nil)
+	node := &v3.Node{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "m-abcde"}}
+	template := &v3.NodeTemplate{ObjectMeta: metav1.ObjectMeta{Name: "nt-abcde"}, Spec: v32.NodeTemplateSpec{Driver: amazonec2}}
+	data := map[string]interface{}{"spec": map[string]interface{}{"cloudCredentialName": "cattle-global-data:cc-abcde"}}
+
+	err := m.updateRawConfigFromCredential(node, data, map[string]interface{}{"region": "us-west-2"}, template)
+	require.Error(t, err)
+	assert.True(t, v32.NodeConditionCredentialValid.IsFalse(node), "expected the credential of the node to be invalid")
+	assert.Equal(t, credentialNotFoundReason, v32.NodeConditionCredentialValid.GetReason(node))
+	assert.Contains(t, v32.NodeConditionCredentialValid.GetMessage(node), "cattle-global-data:cc-abcde")
+	assert.Contains(t, v32.NodeConditionCredentialValid.GetMessage(node), "nt-abcde")
+}
+
+func TestUpdateRawConfigFromCredentialPresent(t *testing.T) {
+	m := newCredentialTestLifecycle(map[string]*corev1.Secret{
+		"cc-abcde": {Data: map[string][]byte{"amazonec2credentialConfig-accessKey": []byte("access-key")}},
+	})
+	node := &v3.Node{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "m-abcde"}}
+	v32.NodeConditionCredentialValid.False(node)
+	template := &v3.NodeTemplate{ObjectMeta: metav1.ObjectMeta{Name: "nt-abcde"}, Spec: v32.NodeTemplateSpec{Driver: amazonec2}}
+	data := map[string]interface{}{"spec": map[string]interface{}{"cloudCredentialName": "cattle-global-data:cc-abcde"}}
+	rawConfig := map[string]interface{}{"region": "us-west-2"}
+
+	require.NoError(t, m.updateRawConfigFromCredential(node, data, rawConfig, template))
+	assert.Equal(t, "access-key", rawConfig["accessKey"])
+	assert.True(t, v32.NodeConditionCredentialValid.IsTrue(node), "expected the credential to be valid again once it is found")
+	assert.Empty(t, v32.NodeConditionCredentialValid.GetMessage(node))
+
+	node = &v3.Node{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "m-fghij"}}
+	require.NoError(t, m.updateRawConfigFromCredential(node, map[string]interface{}{}, rawConfig, template))
+	assert.Empty(t, node.Status.Conditions, "expected no credential condition for a template without a credential")
+}
@@ -0,0 +1,105 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/rancher/norman/condition"
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/controllers/management/rbac"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	nodehelper "github.com/rancher/rancher/pkg/node"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/sirupsen/logrus"
+	authzv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	credentialAccessDeniedReason = "CredentialAccessDenied"
+)
+
+// checkCredentialAccess verifies that the creator of the node pool, or of the node template for nodes without a pool
+// or pools without a recorded creator, can still get the cloud credential of the node template. Provisioning with a
+// credential that was unshared since the pool was created, or whose user can't be established, is blocked, the
+// returned error sets the reason of the provisioning condition of the node.
+func (m *Lifecycle) checkCredentialAccess(node *v3.Node, template *v3.NodeTemplate) error {
+	if template.Spec.CloudCredentialName == "" {
+		return nil
+	}
+
+	var (
+		owner       runtime.Object = template
+		annotations                = template.Annotations
+		creatorID                  = template.Annotations[rbac.CreatorIDAnn]
+	)
+	if node.Spec.NodePoolName != "" {
+		pool, err := m.getNodePool(node.Spec.NodePoolName)
+		if err != nil && !kerror.IsNotFound(err) {
+			return err
+		} else if err == nil {
+			owner, annotations = pool, pool.Annotations
+			if poolCreatorID := pool.Annotations[rbac.CreatorIDAnn]; poolCreatorID != "" {
+				creatorID = poolCreatorID
+			}
+		}
+	}
+
+	skip, err := m.skipCredentialAccessCheck(annotations[nodehelper.SkipCredentialAccessCheckAnnotation])
+	if err != nil || skip {
+		return err
+	}
+
+	var message string
+	if creatorID == "" {
+		message = fmt.Sprintf("the creator of the nodes is unknown, so its access to cloud credential [%s] can't be checked",
+			template.Spec.CloudCredentialName)
+	} else {
+		attribs, err := m.userAttributeLister.Get("", creatorID)
+		if err != nil && !kerror.IsNotFound(err) {
+			return err
+		}
+		namespace, name := ref.Parse(template.Spec.CloudCredentialName)
+		allowed, err := sar.UserCanDo(m.ctx, m.subjectAccessReviews, creatorID, sar.UserAttributeGroups(attribs), authzv1.ResourceAttributes{
+			Verb:      "get",
+			Resource:  "secrets",
+			Namespace: namespace,
+			Name:      name,
+		})
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+		message = fmt.Sprintf("creator [%s] can no longer access cloud credential [%s]", creatorID, template.Spec.CloudCredentialName)
+	}
+
+	message += fmt.Sprintf(", an admin may set the annotation [%s] to provision anyway", nodehelper.SkipCredentialAccessCheckAnnotation)
+	logrus.Infof("[node-controller] not provisioning node [%s/%s]: %s", node.Namespace, node.Name, message)
+	m.eventRecorder.Event(owner, v1.EventTypeWarning, credentialAccessDeniedReason, message)
+	return condition.Error(credentialAccessDeniedReason, fmt.Errorf(message))
+}
+
+// skipCredentialAccessCheck returns true if the user who set the annotation skipping the credential access check,
+// recorded as its value, is an admin. The annotation is not honored for anyone else, as it may be written outside of the
+// API which checks who sets it.
+func (m *Lifecycle) skipCredentialAccessCheck(approverID string) (bool, error) {
+	if approverID == "" {
+		return false, nil
+	}
+	attribs, err := m.userAttributeLister.Get("", approverID)
+	if err != nil && !kerror.IsNotFound(err) {
+		return false, err
+	}
+	isAdmin, err := sar.UserIsAdmin(m.ctx, m.subjectAccessReviews, approverID, sar.UserAttributeGroups(attribs))
+	if err != nil {
+		return false, err
+	}
+	if !isAdmin {
+		logrus.Warnf("[node-controller] ignoring annotation [%s], it was not set by an admin but by [%s]",
+			nodehelper.SkipCredentialAccessCheckAnnotation, approverID)
+	}
+	return isAdmin, nil
+}
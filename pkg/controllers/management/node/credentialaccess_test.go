@@ -0,0 +1,152 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	"github.com/rancher/rancher/pkg/controllers/management/rbac"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	nodehelper "github.com/rancher/rancher/pkg/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func newCredentialAccessLifecycle(pool *v3.NodePool, allowed map[string][]string) (*Lifecycle, *fake.SubjectAccessReviews, *record.FakeRecorder) {
+	reviews := &fake.SubjectAccessReviews{Allowed: allowed}
+	recorder := record.NewFakeRecorder(10)
+	return &Lifecycle{
+		ctx: context.Background(),
+		nodePoolLister: &fakes.NodePoolListerMock{
+			GetFunc: func(namespace string, name string) (*v3.NodePool, error) {
+				return pool, nil
+			},
+		},
+		userAttributeLister: &fakes.UserAttributeListerMock{
+			GetFunc: func(namespace string, name string) (*v3.UserAttribute, error) {
+				return &v3.UserAttribute{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					GroupPrincipals: map[string]v32.Principals{
+						"okta": {Items: []v32.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "okta_group://devs"}}}},
+					},
+				}, nil
+			},
+		},
+		subjectAccessReviews: reviews,
+		eventRecorder:        recorder,
+	}, reviews, recorder
+}
+
+func newCredentialAccessObjects() (*v3.Node, *v3.NodePool, *v3.NodeTemplate) {
+	node := &v3.Node{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "m-abcde"},
+		Spec: v3.NodeSpec{
+			NodePoolName:     "c-abcde:np-abcde",
+			NodeTemplateName: "cattle-global-nt:nt-abcde",
+		},
+	}
+	pool := &v3.NodePool{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "c-abcde",
+			Name:        "np-abcde",
+			Annotations: map[string]string{rbac.CreatorIDAnn: "u-creator"},
+		},
+	}
+	template := &v3.NodeTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-nt", Name: "nt-abcde"},
+		Spec:       v3.NodeTemplateSpec{CloudCredentialName: "cattle-global-data:cc-abcde"},
+	}
+	return node, pool, template
+}
+
+func TestCheckCredentialAccessAllowed(t *testing.T) {
+	node, pool, template := newCredentialAccessObjects()
+	m, reviews, recorder := newCredentialAccessLifecycle(pool, map[string][]string{"u-creator": {"secrets/cattle-global-data/cc-abcde"}})
+
+	require.NoError(t, m.checkCredentialAccess(node, template))
+	require.Len(t, reviews.Reviews, 1)
+	assert.Equal(t, "u-creator", reviews.Reviews[0].User)
+	assert.ElementsMatch(t, []string{"okta_group://devs", "system:authenticated", "system:cattle:authenticated"}, reviews.Reviews[0].Groups)
+	assert.Equal(t, &authzv1.ResourceAttributes{
+		Verb:      "get",
+		Resource:  "secrets",
+		Namespace: "cattle-global-data",
+		Name:      "cc-abcde",
+	}, reviews.Reviews[0].ResourceAttributes)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestCheckCredentialAccessSharedWithGroup(t *testing.T) {
+	node, pool, template := newCredentialAccessObjects()
+	m, _, recorder := newCredentialAccessLifecycle(pool, map[string][]string{"okta_group://devs": {"secrets/cattle-global-data/cc-abcde"}})
+
+	require.NoError(t, m.checkCredentialAccess(node, template))
+	assert.Empty(t, recorder.Events)
+}
+
+func TestCheckCredentialAccessRevoked(t *testing.T) {
+	node, pool, template := newCredentialAccessObjects()
+	m, _, recorder := newCredentialAccessLifecycle(pool, nil)
+
+	_, err := v32.NodeConditionProvisioned.Once(node, func() (runtime.Object, error) {
+		return node, m.checkCredentialAccess(node, template)
+	})
+	require.Error(t, err)
+	assert.Equal(t, credentialAccessDeniedReason, v32.NodeConditionProvisioned.GetReason(node),
+		"expected the reason to be set on the provisioning condition")
+	assert.Contains(t, v32.NodeConditionProvisioned.GetMessage(node), "cattle-global-data:cc-abcde")
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning "+credentialAccessDeniedReason)
+}
+
+func TestCheckCredentialAccessOverride(t *testing.T) {
+	node, pool, template := newCredentialAccessObjects()
+	pool.Annotations[nodehelper.SkipCredentialAccessCheckAnnotation] = "u-admin"
+	m, reviews, recorder := newCredentialAccessLifecycle(pool, nil)
+	reviews.Admins = map[string]bool{"u-admin": true}
+
+	require.NoError(t, m.checkCredentialAccess(node, template))
+	require.Len(t, reviews.Reviews, 1)
+	assert.Equal(t, "u-admin", reviews.Reviews[0].User)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestCheckCredentialAccessOverrideNotSetByAdmin(t *testing.T) {
+	node, pool, template := newCredentialAccessObjects()
+	pool.Annotations[nodehelper.SkipCredentialAccessCheckAnnotation] = "true"
+	m, reviews, recorder := newCredentialAccessLifecycle(pool, nil)
+
+	require.Error(t, m.checkCredentialAccess(node, template))
+	require.Len(t, reviews.Reviews, 2)
+	assert.Equal(t, "u-creator", reviews.Reviews[1].User)
+	require.Len(t, recorder.Events, 1)
+}
+
+func TestCheckCredentialAccessTemplateCreator(t *testing.T) {
+	node, pool, template := newCredentialAccessObjects()
+	delete(pool.Annotations, rbac.CreatorIDAnn)
+	template.Annotations = map[string]string{rbac.CreatorIDAnn: "u-template-creator"}
+	m, reviews, _ := newCredentialAccessLifecycle(pool, map[string][]string{"u-template-creator": {"secrets/cattle-global-data/cc-abcde"}})
+
+	require.NoError(t, m.checkCredentialAccess(node, template))
+	require.Len(t, reviews.Reviews, 1)
+	assert.Equal(t, "u-template-creator", reviews.Reviews[0].User)
+}
+
+func TestCheckCredentialAccessWithoutCreator(t *testing.T) {
+	node, pool, template := newCredentialAccessObjects()
+	delete(pool.Annotations, rbac.CreatorIDAnn)
+	m, reviews, recorder := newCredentialAccessLifecycle(pool, nil)
+
+	err := m.checkCredentialAccess(node, template)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "creator of the nodes is unknown")
+	assert.Empty(t, reviews.Reviews)
+	require.Len(t, recorder.Events, 1)
+}
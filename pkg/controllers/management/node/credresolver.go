@@ -0,0 +1,53 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// credentialResolverAnnotation on a cloud credential names the CredentialResolver its fields are resolved with, the
+// fields of a credential without it are read from the data of its secret
+const credentialResolverAnnotation = "cattle.io/credential-resolver"
+
+// CredentialResolver resolves the value of a field of a cloud credential, so that the values can be kept in an
+// external KMS or secret manager with only a reference to them in the data of the secret of the credential.
+type CredentialResolver interface {
+	// Resolve returns the value of the field of the credential stored under key in the data of its secret
+	Resolve(cred *v1.Secret, key string) (string, error)
+}
+
+var (
+	credentialResolversLock sync.RWMutex
+	credentialResolvers     = map[string]CredentialResolver{}
+)
+
+// RegisterCredentialResolver makes the resolver available to the cloud credentials annotated with its name
+func RegisterCredentialResolver(name string, resolver CredentialResolver) {
+	credentialResolversLock.Lock()
+	defer credentialResolversLock.Unlock()
+	credentialResolvers[name] = resolver
+}
+
+// secretCredentialResolver reads the values inline from the data of the secret of the credential
+type secretCredentialResolver struct{}
+
+func (secretCredentialResolver) Resolve(cred *v1.Secret, key string) (string, error) {
+	return string(cred.Data[key]), nil
+}
+
+func credentialResolver(cred *v1.Secret) (CredentialResolver, error) {
+	name := cred.Annotations[credentialResolverAnnotation]
+	if name == "" {
+		return secretCredentialResolver{}, nil
+	}
+
+	credentialResolversLock.RLock()
+	defer credentialResolversLock.RUnlock()
+	resolver, ok := credentialResolvers[name]
+	if !ok {
+		return nil, fmt.Errorf("credential resolver [%s] of cloud credential [%s] is not registered", name, cred.Name)
+	}
+	return resolver, nil
+}
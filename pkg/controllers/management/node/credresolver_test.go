@@ -0,0 +1,82 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeExternalResolver resolves the references stored in the secret of the credential from its values
+type fakeExternalResolver struct {
+	values map[string]string
+}
+
+func (f fakeExternalResolver) Resolve(cred *corev1.Secret, key string) (string, error) {
+	ref := string(cred.Data[key])
+	value, ok := f.values[ref]
+	if !ok {
+		return "", fmt.Errorf("reference %s not found", ref)
+	}
+	return value, nil
+}
+
+func newResolverCredential(resolver string, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cc-abcde", Namespace: "cattle-global-data"},
+		Data:       map[string][]byte{},
+	}
+	if resolver != "" {
+		secret.Annotations = map[string]string{credentialResolverAnnotation: resolver}
+	}
+	for key, value := range data {
+		secret.Data[key] = []byte(value)
+	}
+	return secret
+}
+
+func TestSetCredFieldsDefaultResolver(t *testing.T) {
+	m := newCredentialTestLifecycle(map[string]*corev1.Secret{
+		"cc-abcde": newResolverCredential("", map[string]string{
+			"amazonec2credentialConfig-accessKey": "access-key",
+			"amazonec2credentialConfig-unknown":   "ignored",
+		}),
+	})
+	rawConfig := map[string]interface{}{"region": "us-west-2"}
+
+	require.NoError(t, m.setCredFields(rawConfig, map[string]v32.Field{"accessKey": {}, "region": {}}, "cattle-global-data:cc-abcde"))
+	assert.Equal(t, map[string]interface{}{"region": "us-west-2", "accessKey": "access-key"}, rawConfig)
+}
+
+func TestSetCredFieldsExternalResolver(t *testing.T) {
+	RegisterCredentialResolver("fake-kms", fakeExternalResolver{values: map[string]string{"kms://aws/access-key": "access-key"}})
+	defer func() {
+		credentialResolversLock.Lock()
+		delete(credentialResolvers, "fake-kms")
+		credentialResolversLock.Unlock()
+	}()
+	fields := map[string]v32.Field{"accessKey": {}, "region": {}}
+
+	m := newCredentialTestLifecycle(map[string]*corev1.Secret{
+		"cc-abcde": newResolverCredential("fake-kms", map[string]string{"amazonec2credentialConfig-accessKey": "kms://aws/access-key"}),
+	})
+	rawConfig := map[string]interface{}{"region": "us-west-2"}
+	require.NoError(t, m.setCredFields(rawConfig, fields, "cattle-global-data:cc-abcde"))
+	assert.Equal(t, "access-key", rawConfig["accessKey"], "expected the reference to be resolved by the provider")
+
+	m = newCredentialTestLifecycle(map[string]*corev1.Secret{
+		"cc-abcde": newResolverCredential("fake-kms", map[string]string{"amazonec2credentialConfig-accessKey": "kms://aws/missing"}),
+	})
+	err := m.setCredFields(map[string]interface{}{"region": "us-west-2"}, fields, "cattle-global-data:cc-abcde")
+	assert.EqualError(t, err, "failed to resolve field [accessKey] of cloud credential [cattle-global-data:cc-abcde]: reference kms://aws/missing not found")
+
+	m = newCredentialTestLifecycle(map[string]*corev1.Secret{
+		"cc-abcde": newResolverCredential("vault", map[string]string{"amazonec2credentialConfig-accessKey": "vault://aws/access-key"}),
+	})
+	err = m.setCredFields(map[string]interface{}{"region": "us-west-2"}, fields, "cattle-global-data:cc-abcde")
+	assert.EqualError(t, err, "credential resolver [vault] of cloud credential [cc-abcde] is not registered")
+}
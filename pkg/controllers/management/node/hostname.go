@@ -0,0 +1,109 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+)
+
+// hostnameHashLength is the number of hex characters of the hash suffixed to the hostnames which had to be altered
+const hostnameHashLength = 8
+
+var (
+	validHostname        = regexp.MustCompile("^[a-z0-9]([a-z0-9-]*[a-z0-9])?$")
+	invalidHostnameChars = regexp.MustCompile("[^a-z0-9-]+")
+)
+
+// hostnameConstraints are the constraints the hostname of a node has to satisfy for a node driver. The hostname is
+// also the name of the Kubernetes node, so it is always folded to lowercase and restricted to alphanumeric characters
+// and '-' on top of the constraints of the driver.
+type hostnameConstraints struct {
+	maxLength     int
+	leadingLetter bool
+}
+
+var defaultHostnameConstraints = hostnameConstraints{maxLength: 63}
+
+var driverHostnameConstraints = map[string]hostnameConstraints{
+	"amazonec2":     {maxLength: 63},
+	"azure":         {maxLength: 63},
+	"digitalocean":  {maxLength: 63},
+	"google":        {maxLength: 63, leadingLetter: true},
+	"harvester":     {maxLength: 63},
+	"linode":        {maxLength: 32, leadingLetter: true},
+	"openstack":     {maxLength: 63},
+	"vmwarevsphere": {maxLength: 63},
+}
+
+func hostnameConstraintsFor(driver string) hostnameConstraints {
+	if c, ok := driverHostnameConstraints[strings.ToLower(driver)]; ok {
+		return c
+	}
+	return defaultHostnameConstraints
+}
+
+func (c hostnameConstraints) validate(hostname string) error {
+	if len(hostname) > c.maxLength {
+		return fmt.Errorf("hostname may be at most %d characters", c.maxLength)
+	}
+	if !validHostname.MatchString(hostname) {
+		return fmt.Errorf("hostname may only contain alphanumeric characters and '-' and must start and end with an alphanumeric character")
+	}
+	if c.leadingLetter && (hostname[0] < 'a' || hostname[0] > 'z') {
+		return fmt.Errorf("hostname must start with a letter")
+	}
+	return nil
+}
+
+// normalizeHostname returns the hostname a node is provisioned with by the driver for the requested hostname. A
+// hostname requested explicitly is only folded to lowercase and has to satisfy the constraints of the driver otherwise,
+// while a generated one is rewritten to satisfy them. A generated hostname which has to be altered beyond case folding
+// is suffixed with a hash of the requested hostname, so that hostnames sharing a prefix don't collide once truncated.
+func normalizeHostname(driver, requested string, explicit bool) (string, error) {
+	c := hostnameConstraintsFor(driver)
+	hostname := strings.ToLower(requested)
+
+	if explicit {
+		if err := c.validate(hostname); err != nil {
+			return "", fmt.Errorf("requested hostname [%s] is invalid for node driver [%s]: %v", requested, driver, err)
+		}
+		return hostname, nil
+	}
+
+	if c.validate(hostname) == nil {
+		return hostname, nil
+	}
+
+	hostname = strings.Trim(invalidHostnameChars.ReplaceAllString(hostname, "-"), "-")
+	if hostname == "" {
+		return "", fmt.Errorf("requested hostname [%s] can't be normalized for node driver [%s]", requested, driver)
+	}
+	if c.leadingLetter && (hostname[0] < 'a' || hostname[0] > 'z') {
+		hostname = "n" + hostname
+	}
+
+	sum := sha256.Sum256([]byte(requested))
+	suffix := "-" + hex.EncodeToString(sum[:])[:hostnameHashLength]
+	if len(hostname)+len(suffix) > c.maxLength {
+		hostname = strings.TrimRight(hostname[:c.maxLength-len(suffix)], "-")
+	}
+	hostname += suffix
+
+	if err := c.validate(hostname); err != nil {
+		return "", fmt.Errorf("requested hostname [%s] can't be normalized for node driver [%s]: %v", requested, driver, err)
+	}
+	return hostname, nil
+}
+
+// machineName returns the name of the machine of the node in rancher-machine, which is the hostname the node is
+// provisioned with. Nodes provisioned before the hostname was normalized use the requested hostname.
+func machineName(node *v3.Node) string {
+	if node.Status.NormalizedHostname != "" {
+		return node.Status.NormalizedHostname
+	}
+	return node.Spec.RequestedHostname
+}
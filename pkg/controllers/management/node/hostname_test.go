@@ -0,0 +1,147 @@
+package node
+
+import (
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNormalizeHostname(t *testing.T) {
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		name        string
+		driver      string
+		requested   string
+		explicit    bool
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:      "valid",
+			driver:    "amazonec2",
+			requested: "worker-1",
+			expected:  "worker-1",
+		},
+		{
+			name:      "case folded",
+			driver:    "vmwarevsphere",
+			requested: "Worker-1",
+			explicit:  true,
+			expected:  "worker-1",
+		},
+		{
+			name:      "underscores",
+			driver:    "azure",
+			requested: "my_pool_1",
+			expected:  "my-pool-1-ba2d61a7",
+		},
+		{
+			name:      "truncated",
+			driver:    "vmwarevsphere",
+			requested: long,
+			expected:  strings.Repeat("a", 54) + "-6bd5e503",
+		},
+		{
+			name:      "truncated for linode",
+			driver:    "linode",
+			requested: long,
+			expected:  strings.Repeat("a", 23) + "-6bd5e503",
+		},
+		{
+			name:      "leading digit for google",
+			driver:    "google",
+			requested: "1-worker",
+			expected:  "n1-worker-6bdd2e99",
+		},
+		{
+			name:      "leading digit for digitalocean",
+			driver:    "digitalocean",
+			requested: "1-worker",
+			expected:  "1-worker",
+		},
+		{
+			name:      "unknown driver",
+			driver:    "custom-driver",
+			requested: "worker.example.com",
+			expected:  "worker-example-com-93256695",
+		},
+		{
+			name:        "nothing left",
+			driver:      "openstack",
+			requested:   "___",
+			expectedErr: "requested hostname [___] can't be normalized for node driver [openstack]",
+		},
+		{
+			name:        "explicit too long",
+			driver:      "linode",
+			requested:   long,
+			explicit:    true,
+			expectedErr: "requested hostname [" + long + "] is invalid for node driver [linode]: hostname may be at most 32 characters",
+		},
+		{
+			name:        "explicit underscores",
+			driver:      "harvester",
+			requested:   "my_node",
+			explicit:    true,
+			expectedErr: "requested hostname [my_node] is invalid for node driver [harvester]: hostname may only contain alphanumeric characters and '-' and must start and end with an alphanumeric character",
+		},
+		{
+			name:        "explicit leading digit for google",
+			driver:      "google",
+			requested:   "1-worker",
+			explicit:    true,
+			expectedErr: "requested hostname [1-worker] is invalid for node driver [google]: hostname must start with a letter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostname, err := normalizeHostname(tt.driver, tt.requested, tt.explicit)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hostname)
+			assert.NoError(t, hostnameConstraintsFor(tt.driver).validate(hostname))
+		})
+	}
+}
+
+func TestNormalizeHostnameUnique(t *testing.T) {
+	prefix := strings.Repeat("worker", 11)
+
+	first, err := normalizeHostname("vmwarevsphere", prefix+"-1", false)
+	require.NoError(t, err)
+	second, err := normalizeHostname("vmwarevsphere", prefix+"-2", false)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second, "expected hostnames only differing after the truncation not to collide")
+
+	again, err := normalizeHostname("vmwarevsphere", prefix+"-1", false)
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "expected the normalization to be deterministic")
+
+	underscored, err := normalizeHostname("vmwarevsphere", "my_node", false)
+	require.NoError(t, err)
+	assert.NotEqual(t, "my-node", underscored, "expected a rewritten hostname not to collide with a valid one")
+}
+
+func TestMachineName(t *testing.T) {
+	node := &v3.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "m-abcde"},
+		Spec:       v32.NodeSpec{RequestedHostname: "my_node"},
+		Status:     v32.NodeStatus{NodeTemplateSpec: &v32.NodeTemplateSpec{Driver: "vmwarevsphere"}},
+	}
+	assert.Equal(t, "my_node", machineName(node), "expected nodes provisioned before the normalization to keep their name")
+
+	node.Status.NormalizedHostname = "my-node-0123abcd"
+	assert.Equal(t, "my-node-0123abcd", machineName(node))
+	cmd := buildCreateCommand(node, nil)
+	assert.Equal(t, "my-node-0123abcd", cmd[len(cmd)-1], "expected the machine to be created with the normalized hostname")
+}
@@ -0,0 +1,153 @@
+package node
+
+import (
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/jailer"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// driverTimeoutAnnotation overrides the machine-driver-timeout setting for a node driver
+	driverTimeoutAnnotation = "io.cattle.nodedriver/timeout"
+	// driverCPULimitAnnotation overrides the machine-driver-cpu-limit setting for a node driver
+	driverCPULimitAnnotation = "io.cattle.nodedriver/cpu-limit"
+	// driverMemoryLimitAnnotation overrides the machine-driver-memory-limit setting for a node driver
+	driverMemoryLimitAnnotation = "io.cattle.nodedriver/memory-limit"
+)
+
+// driverLimits are the timeout and the resource limits of the node driver process provisioning a node
+type driverLimits struct {
+	timeout   time.Duration
+	resources jailer.Limits
+}
+
+// driverLimits returns the limits of the node driver, the annotations of the NodeDriver override the settings
+func (m *Lifecycle) driverLimits(driver string) driverLimits {
+	timeout := int64(settings.MachineDriverTimeout.GetInt())
+	cpu := int64(settings.MachineDriverCPULimit.GetInt())
+	memory := int64(settings.MachineDriverMemoryLimit.GetInt())
+
+	nodeDriver, err := m.getNodeDriver(driver)
+	if err != nil {
+		logrus.Warnf("[node-controller] failed to get node driver %s, using the default limits: %v", driver, err)
+	} else if nodeDriver != nil {
+		timeout = annotationInt(nodeDriver, driverTimeoutAnnotation, timeout)
+		cpu = annotationInt(nodeDriver, driverCPULimitAnnotation, cpu)
+		memory = annotationInt(nodeDriver, driverMemoryLimitAnnotation, memory)
+	}
+
+	var limits driverLimits
+	if timeout > 0 {
+		limits.timeout = time.Duration(timeout) * time.Second
+	}
+	if cpu > 0 {
+		limits.resources.CPUSeconds = uint64(cpu)
+	}
+	if memory > 0 {
+		limits.resources.MemoryBytes = uint64(memory) * 1024 * 1024
+	}
+	return limits
+}
+
+func (m *Lifecycle) getNodeDriver(driver string) (*v3.NodeDriver, error) {
+	nodeDrivers, err := m.nodeDriverLister.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, nodeDriver := range nodeDrivers {
+		if nodeDriver.Spec.DisplayName == driver {
+			return nodeDriver, nil
+		}
+	}
+	return nil, nil
+}
+
+func annotationInt(nodeDriver *v3.NodeDriver, annotation string, defaultValue int64) int64 {
+	value, ok := nodeDriver.Annotations[annotation]
+	if !ok {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logrus.Warnf("[node-controller] invalid %s annotation [%s] on node driver %s, using %d", annotation, value, nodeDriver.Name, defaultValue)
+		return defaultValue
+	}
+	return i
+}
+
+// processTimeout kills the process group of a command once its timeout passed
+type processTimeout struct {
+	timer  *time.Timer
+	killed int32
+}
+
+// killAfter kills the process group of the started command once the timeout passed, a zero timeout never kills it
+func killAfter(cmd *exec.Cmd, timeout time.Duration) *processTimeout {
+	t := &processTimeout{}
+	if timeout <= 0 {
+		return t
+	}
+
+	t.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&t.killed, 1)
+		logrus.Warnf("[node-controller] killing %v, it has been running for more than %v", cmd.Args[0], timeout)
+		if err := jailer.KillProcessGroup(cmd); err != nil {
+			logrus.Errorf("[node-controller] failed to kill %v: %v", cmd.Args[0], err)
+		}
+	})
+	return t
+}
+
+// Killed returns whether the process group was killed
+func (t *processTimeout) Killed() bool {
+	return atomic.LoadInt32(&t.killed) == 1
+}
+
+// Stop stops the timer of the process, the process is no longer killed once it is stopped
+func (t *processTimeout) Stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// provisionSlots caps the number of nodes provisioned at once in each cluster
+type provisionSlots struct {
+	lock         sync.Mutex
+	provisioning map[string]int
+	limit        func() int
+}
+
+func newProvisionSlots() *provisionSlots {
+	return &provisionSlots{
+		provisioning: map[string]int{},
+		limit:        settings.NodeProvisionConcurrency.GetInt,
+	}
+}
+
+// acquire takes a provisioning slot of the cluster, it returns false if all the slots are taken. The returned function
+// releases the slot.
+func (s *provisionSlots) acquire(cluster string) (func(), bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if limit := s.limit(); limit > 0 && s.provisioning[cluster] >= limit {
+		return nil, false
+	}
+	s.provisioning[cluster]++
+
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.provisioning[cluster]--; s.provisioning[cluster] <= 0 {
+			delete(s.provisioning, cluster)
+		}
+	}, true
+}
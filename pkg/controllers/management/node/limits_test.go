@@ -0,0 +1,205 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/jailer"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// TestHelperProcess isn't a test, it is the fake node driver run by the tests of the driver timeout. It starts a
+// child sharing its stdout when asked to, so that the readers are only closed once the whole process group is killed.
+func TestHelperProcess(t *testing.T) {
+	switch os.Getenv("GO_WANT_HELPER_PROCESS") {
+	case "parent":
+		child := exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+		child.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=sleep")
+		child.Stdout = os.Stdout
+		if err := child.Start(); err != nil {
+			os.Exit(2)
+		}
+		time.Sleep(time.Hour)
+	case "sleep":
+		time.Sleep(time.Hour)
+	case "exit":
+	default:
+		return
+	}
+	os.Exit(0)
+}
+
+func helperCommand(mode string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS="+mode)
+	jailer.SetProcessGroup(cmd)
+	return cmd
+}
+
+func TestKillAfterTimeout(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process groups are only killed on linux")
+	}
+
+	cmd := helperCommand("parent")
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	timeout := killAfter(cmd, 500*time.Millisecond)
+	defer timeout.Stop()
+
+	read := make(chan struct{})
+	go func() {
+		ioutil.ReadAll(stdout)
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(30 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("expected the output of the process group to be closed once the timeout passed")
+	}
+
+	assert.Error(t, cmd.Wait())
+	assert.True(t, timeout.Killed())
+}
+
+func TestKillAfterWithinTimeout(t *testing.T) {
+	cmd := helperCommand("exit")
+	require.NoError(t, cmd.Start())
+
+	timeout := killAfter(cmd, time.Minute)
+	assert.NoError(t, cmd.Wait())
+	timeout.Stop()
+	assert.False(t, timeout.Killed(), "expected a process exiting within its timeout not to be killed")
+
+	cmd = helperCommand("exit")
+	require.NoError(t, cmd.Start())
+	timeout = killAfter(cmd, 0)
+	assert.NoError(t, cmd.Wait())
+	timeout.Stop()
+	assert.False(t, timeout.Killed())
+}
+
+func TestDriverLimits(t *testing.T) {
+	for _, setting := range []settings.Setting{settings.MachineDriverTimeout, settings.MachineDriverCPULimit, settings.MachineDriverMemoryLimit} {
+		original := setting.Get()
+		defer setting.Set(original)
+	}
+	require.NoError(t, settings.MachineDriverTimeout.Set("1800"))
+	require.NoError(t, settings.MachineDriverCPULimit.Set("0"))
+	require.NoError(t, settings.MachineDriverMemoryLimit.Set("512"))
+
+	m := &Lifecycle{
+		nodeDriverLister: &fakes.NodeDriverListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.NodeDriver, error) {
+				return []*v3.NodeDriver{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "nd-abcde",
+							Annotations: map[string]string{
+								driverTimeoutAnnotation:     "3600",
+								driverCPULimitAnnotation:    "600",
+								driverMemoryLimitAnnotation: "lots",
+							},
+						},
+						Spec: v32.NodeDriverSpec{DisplayName: "vmwarevsphere"},
+					},
+				}, nil
+			},
+		},
+	}
+
+	assert.Equal(t, driverLimits{
+		timeout:   30 * time.Minute,
+		resources: jailer.Limits{MemoryBytes: 512 * 1024 * 1024},
+	}, m.driverLimits("amazonec2"), "expected the settings to apply to a node driver without annotations")
+
+	assert.Equal(t, driverLimits{
+		timeout:   time.Hour,
+		resources: jailer.Limits{CPUSeconds: 600, MemoryBytes: 512 * 1024 * 1024},
+	}, m.driverLimits("vmwarevsphere"), "expected the annotations of the node driver to override the settings")
+}
+
+func TestProvisionSlotsConcurrency(t *testing.T) {
+	slots := newProvisionSlots()
+	slots.limit = func() int { return 2 }
+
+	var (
+		lock                sync.Mutex
+		running, maxRunning int
+		wg                  sync.WaitGroup
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				release, ok := slots.acquire("c-abcde")
+				if !ok {
+					time.Sleep(time.Millisecond)
+					continue
+				}
+				lock.Lock()
+				if running++; running > maxRunning {
+					maxRunning = running
+				}
+				lock.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				lock.Lock()
+				running--
+				lock.Unlock()
+				release()
+				return
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxRunning, 2, "expected at most 2 nodes of the cluster to provision at once")
+	assert.Empty(t, slots.provisioning, "expected all the slots to be released")
+}
+
+func TestProvisionSlotsPerCluster(t *testing.T) {
+	slots := newProvisionSlots()
+	slots.limit = func() int { return 1 }
+
+	release, ok := slots.acquire("c-abcde")
+	require.True(t, ok)
+	_, ok = slots.acquire("c-abcde")
+	assert.False(t, ok, "expected the slots of the cluster to be taken")
+	otherRelease, ok := slots.acquire("c-fghij")
+	require.True(t, ok, "expected the slots to be counted per cluster")
+	otherRelease()
+
+	m := &Lifecycle{provisionSlots: slots}
+	node := &v3.Node{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "m-abcde"}}
+	_, err := m.ready(node)
+	assert.Error(t, err, "expected the provisioning to wait for a slot")
+
+	release()
+	release, ok = slots.acquire("c-abcde")
+	assert.True(t, ok, "expected a released slot to be available")
+	release()
+
+	slots.limit = func() int { return 0 }
+	for i := 0; i < 100; i++ {
+		_, ok = slots.acquire("c-abcde")
+		require.True(t, ok, "expected a limit of 0 to be unlimited")
+	}
+}
@@ -0,0 +1,128 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const registrationTimeoutReason = "RegistrationTimeout"
+
+// checkRegistrationTimeout marks a provisioned node that did not register with Kubernetes within the registration
+// timeout of its pool or template as not registered, and deletes it when its pool replaces unregistered nodes.
+// Custom nodes are only checked when the custom-node-registration-timeout setting is set.
+func (m *Lifecycle) checkRegistrationTimeout(node *v3.Node) (*v3.Node, error) {
+	if !v32.NodeConditionProvisioned.IsTrue(node) || v32.NodeConditionRegistered.IsTrue(node) ||
+		v32.NodeConditionRegistered.IsFalse(node) {
+		return node, nil
+	}
+
+	timeout, pool, err := m.registrationTimeout(node)
+	if err != nil || timeout <= 0 {
+		return node, err
+	}
+
+	provisioned := provisionedTime(node)
+	if provisioned.IsZero() {
+		return node, nil
+	}
+	if remaining := timeout - time.Since(provisioned); remaining > 0 {
+		m.nodeClient.Controller().EnqueueAfter(node.Namespace, node.Name, remaining)
+		return node, nil
+	}
+
+	message := registrationTimeoutMessage(node, timeout)
+	logrus.Infof("[node-controller] node [%s/%s] %s", node.Namespace, node.Name, message)
+
+	node = node.DeepCopy()
+	v32.NodeConditionRegistered.False(node)
+	v32.NodeConditionRegistered.Reason(node, registrationTimeoutReason)
+	v32.NodeConditionRegistered.Message(node, message)
+	node, err = m.nodeClient.Update(node)
+	if err != nil {
+		return node, err
+	}
+	m.eventRecorder.Event(node, v1.EventTypeWarning, registrationTimeoutReason, message)
+
+	if pool == nil || !pool.Spec.ReplaceUnregisteredNodes {
+		return node, nil
+	}
+
+	logrus.Infof("[node-controller] replacing node [%s/%s] of node pool [%s] that did not register", node.Namespace, node.Name, node.Spec.NodePoolName)
+	f := metav1.DeletePropagationBackground
+	err = m.nodeClient.DeleteNamespaced(node.Namespace, node.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &f,
+	})
+	if kerror.IsNotFound(err) {
+		return node, nil
+	}
+	return node, err
+}
+
+// registrationTimeout returns the registration timeout of the node, the node pool timeout takes precedence over the
+// node template timeout. The node pool is returned for nodes that belong to one.
+func (m *Lifecycle) registrationTimeout(node *v3.Node) (time.Duration, *v3.NodePool, error) {
+	if node.Spec.NodePoolName == "" {
+		if node.Status.NodeTemplateSpec != nil || !isCustom(node) {
+			return 0, nil, nil
+		}
+		return time.Duration(settings.CustomNodeRegistrationTimeout.GetInt()) * time.Second, nil, nil
+	}
+
+	pool, err := m.getNodePool(node.Spec.NodePoolName)
+	if kerror.IsNotFound(err) {
+		return 0, nil, nil
+	} else if err != nil {
+		return 0, nil, err
+	}
+
+	timeout := pool.Spec.RegistrationTimeoutSecs
+	if timeout <= 0 && node.Status.NodeTemplateSpec != nil {
+		timeout = node.Status.NodeTemplateSpec.RegistrationTimeoutSecs
+	}
+	return timeout * time.Second, pool, nil
+}
+
+// provisionedTime returns the time the Provisioned condition of the node became true
+func provisionedTime(node *v3.Node) time.Time {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type != v32.NodeConditionProvisioned {
+			continue
+		}
+		ts := cond.LastTransitionTime
+		if ts == "" {
+			ts = cond.LastUpdateTime
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return time.Time{}
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func registrationTimeoutMessage(node *v3.Node, timeout time.Duration) string {
+	var addresses []string
+	for _, address := range node.Status.InternalNodeStatus.Addresses {
+		addresses = append(addresses, address.Address)
+	}
+	if node.Status.NodeConfig != nil && len(addresses) == 0 {
+		addresses = append(addresses, node.Status.NodeConfig.Address)
+	}
+
+	message := fmt.Sprintf("did not register with Kubernetes within %v of being provisioned, check that the node can reach the Rancher server URL, addresses [%s]",
+		timeout, strings.Join(addresses, ", "))
+	if step := v32.NodeConditionProvisioned.GetMessage(node); step != "" {
+		message += fmt.Sprintf(", last provisioning step [%s]", step)
+	}
+	return message
+}
@@ -0,0 +1,154 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type registrationFakes struct {
+	lifecycle *Lifecycle
+	recorder  *record.FakeRecorder
+	updated   *v3.Node
+	deleted   bool
+	requeue   time.Duration
+}
+
+func newRegistrationFakes(pool *v3.NodePool) *registrationFakes {
+	f := &registrationFakes{
+		recorder: record.NewFakeRecorder(10),
+	}
+	f.lifecycle = &Lifecycle{
+		nodeClient: &fakes.NodeInterfaceMock{
+			UpdateFunc: func(in1 *v3.Node) (*v3.Node, error) {
+				f.updated = in1
+				return in1, nil
+			},
+			DeleteNamespacedFunc: func(namespace string, name string, options *metav1.DeleteOptions) error {
+				f.deleted = true
+				return nil
+			},
+			ControllerFunc: func() v3.NodeController {
+				return &fakes.NodeControllerMock{
+					EnqueueAfterFunc: func(namespace string, name string, after time.Duration) {
+						f.requeue = after
+					},
+				}
+			},
+		},
+		nodePoolLister: &fakes.NodePoolListerMock{
+			GetFunc: func(namespace string, name string) (*v3.NodePool, error) {
+				return pool, nil
+			},
+		},
+		eventRecorder: f.recorder,
+	}
+	return f
+}
+
+func newProvisionedNode(provisionedAgo time.Duration) *v3.Node {
+	node := &v3.Node{}
+	node.Namespace = "c-test"
+	node.Name = "m-test"
+	node.Spec.NodePoolName = "c-test:np-test"
+	node.Status.NodeTemplateSpec = &v32.NodeTemplateSpec{}
+	node.Status.InternalNodeStatus.Addresses = []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "10.0.0.10"}}
+	node.Status.Conditions = []v32.NodeCondition{
+		{
+			Type:               v32.NodeConditionProvisioned,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: time.Now().Add(-provisionedAgo).Format(time.RFC3339),
+			Message:            "Installing Docker...",
+		},
+		{
+			Type:    v32.NodeConditionRegistered,
+			Status:  v1.ConditionUnknown,
+			Message: "waiting to register with Kubernetes",
+		},
+	}
+	return node
+}
+
+func TestRegistrationTimeout(t *testing.T) {
+	pool := &v3.NodePool{}
+	pool.Spec.RegistrationTimeoutSecs = 600
+	f := newRegistrationFakes(pool)
+
+	node, err := f.lifecycle.checkRegistrationTimeout(newProvisionedNode(15 * time.Minute))
+	require.NoError(t, err)
+	assert.True(t, v32.NodeConditionRegistered.IsFalse(node))
+	assert.Equal(t, registrationTimeoutReason, v32.NodeConditionRegistered.GetReason(node))
+	assert.Contains(t, v32.NodeConditionRegistered.GetMessage(node), "10.0.0.10")
+	assert.Contains(t, v32.NodeConditionRegistered.GetMessage(node), "Installing Docker...")
+	assert.NotNil(t, f.updated)
+	assert.False(t, f.deleted, "expected the node to be kept without auto-replace")
+	require.Len(t, f.recorder.Events, 1)
+	assert.Contains(t, <-f.recorder.Events, registrationTimeoutReason)
+}
+
+func TestRegistrationTimeoutPending(t *testing.T) {
+	pool := &v3.NodePool{}
+	pool.Spec.RegistrationTimeoutSecs = 600
+	f := newRegistrationFakes(pool)
+
+	node, err := f.lifecycle.checkRegistrationTimeout(newProvisionedNode(5 * time.Minute))
+	require.NoError(t, err)
+	assert.True(t, v32.NodeConditionRegistered.IsUnknown(node))
+	assert.Nil(t, f.updated)
+	assert.True(t, f.requeue > 4*time.Minute && f.requeue <= 5*time.Minute, "unexpected requeue %v", f.requeue)
+}
+
+func TestRegistrationTimeoutTemplate(t *testing.T) {
+	f := newRegistrationFakes(&v3.NodePool{})
+	node := newProvisionedNode(15 * time.Minute)
+	node.Status.NodeTemplateSpec.RegistrationTimeoutSecs = 600
+
+	node, err := f.lifecycle.checkRegistrationTimeout(node)
+	require.NoError(t, err)
+	assert.True(t, v32.NodeConditionRegistered.IsFalse(node), "expected the node template timeout to apply")
+}
+
+func TestRegistrationTimeoutReplace(t *testing.T) {
+	pool := &v3.NodePool{}
+	pool.Spec.RegistrationTimeoutSecs = 600
+	pool.Spec.ReplaceUnregisteredNodes = true
+	f := newRegistrationFakes(pool)
+
+	_, err := f.lifecycle.checkRegistrationTimeout(newProvisionedNode(15 * time.Minute))
+	require.NoError(t, err)
+	assert.True(t, f.deleted, "expected the node to be deleted so the pool recreates it")
+}
+
+func TestRegistrationTimeoutCustomNode(t *testing.T) {
+	original := settings.CustomNodeRegistrationTimeout.Get()
+	defer settings.CustomNodeRegistrationTimeout.Set(original)
+
+	newCustomNode := func() *v3.Node {
+		node := newProvisionedNode(15 * time.Minute)
+		node.Spec.NodePoolName = ""
+		node.Status.NodeTemplateSpec = nil
+		node.Spec.CustomConfig = &v32.CustomConfig{Address: "10.0.0.10"}
+		return node
+	}
+
+	f := newRegistrationFakes(nil)
+	node, err := f.lifecycle.checkRegistrationTimeout(newCustomNode())
+	require.NoError(t, err)
+	assert.True(t, v32.NodeConditionRegistered.IsUnknown(node), "expected custom nodes to be excluded by default")
+	assert.Nil(t, f.updated)
+
+	require.NoError(t, settings.CustomNodeRegistrationTimeout.Set("600"))
+	node, err = f.lifecycle.checkRegistrationTimeout(newCustomNode())
+	require.NoError(t, err)
+	assert.True(t, v32.NodeConditionRegistered.IsFalse(node), "expected opted in custom nodes to time out")
+	assert.False(t, f.deleted, "expected custom nodes to never be replaced")
+}
@@ -0,0 +1,103 @@
+package node
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// forceDeleteAnnotation lets a node be removed when its machine can't be deleted with rancher-machine, the
+	// machine in the cloud provider is left behind
+	forceDeleteAnnotation = "cleanup.cattle.io/force-delete-node"
+
+	machineUnreachableReason = "MachineUnreachable"
+	machineOrphanedReason    = "MachineOrphaned"
+
+	sshCheckTimeout = 10 * time.Second
+)
+
+// machineRemover drains and deletes the machine of a node provisioned with a node template. The steps are fields so
+// the removal of unreachable machines can be tested without a machine.
+type machineRemover struct {
+	checkSSH      func(node *v3.Node) error
+	drain         func(node *v3.Node) error
+	deleteMachine func(nodeDir string, node *v3.Node) error
+	// cleanup removes the node config secret and the machine state of the node from the jail
+	cleanup       func() error
+	eventRecorder record.EventRecorder
+}
+
+func (m *Lifecycle) newMachineRemover(cleanup func() error) *machineRemover {
+	return &machineRemover{
+		checkSSH:      checkMachineSSH,
+		drain:         m.drainNode,
+		deleteMachine: deleteNode,
+		cleanup:       cleanup,
+		eventRecorder: m.eventRecorder,
+	}
+}
+
+// remove drains the node and deletes its machine. The drain is skipped when the machine can't be reached over ssh
+// with the stored key, as it would only time out. If the machine can't be deleted and the node has the force delete
+// annotation, the node config is cleaned up and the node is removed anyway.
+func (r *machineRemover) remove(nodeDir string, node *v3.Node) error {
+	if err := r.checkSSH(node); err != nil {
+		message := fmt.Sprintf("skipping drain, machine is not reachable over ssh with the stored key: %v", err)
+		logrus.Warnf("[node-controller] node [%s] %s", node.Spec.RequestedHostname, message)
+		r.eventRecorder.Event(node, v1.EventTypeWarning, machineUnreachableReason, message)
+	} else if err := r.drain(node); err != nil {
+		return err
+	}
+
+	err := r.deleteMachine(nodeDir, node)
+	if err == nil {
+		return nil
+	}
+	if node.Annotations[forceDeleteAnnotation] != "true" {
+		return errors.WithMessagef(err, "failed to delete machine, set annotation %s=true on the node to remove it anyway", forceDeleteAnnotation)
+	}
+
+	message := fmt.Sprintf("force deleting node, the machine in the cloud provider may be orphaned and must be removed manually: %v", err)
+	logrus.Warnf("[node-controller] node [%s] %s", node.Spec.RequestedHostname, message)
+	r.eventRecorder.Event(node, v1.EventTypeWarning, machineOrphanedReason, message)
+	return r.cleanup()
+}
+
+// checkMachineSSH opens an ssh connection to the machine of the node with the key stored in its node config
+func checkMachineSSH(node *v3.Node) error {
+	nodeConfig := node.Status.NodeConfig
+	if nodeConfig == nil || nodeConfig.Address == "" || nodeConfig.SSHKey == "" {
+		return errors.New("node has no stored ssh address or key")
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(nodeConfig.SSHKey))
+	if err != nil {
+		return errors.Wrap(err, "stored ssh key is invalid")
+	}
+
+	port := nodeConfig.Port
+	if port == "" {
+		port = "22"
+	}
+	config := &ssh.ClientConfig{
+		User: nodeConfig.User,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         sshCheckTimeout,
+	}
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(nodeConfig.Address, port), config)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
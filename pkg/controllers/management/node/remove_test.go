@@ -0,0 +1,143 @@
+package node
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	rketypes "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+)
+
+type removerFakes struct {
+	remover  *machineRemover
+	recorder *record.FakeRecorder
+	drained  bool
+	deleted  bool
+	cleaned  bool
+}
+
+func newRemoverFakes(sshErr, deleteErr error) *removerFakes {
+	f := &removerFakes{
+		recorder: record.NewFakeRecorder(10),
+	}
+	f.remover = &machineRemover{
+		checkSSH: func(node *v3.Node) error {
+			return sshErr
+		},
+		drain: func(node *v3.Node) error {
+			f.drained = true
+			return nil
+		},
+		deleteMachine: func(nodeDir string, node *v3.Node) error {
+			f.deleted = true
+			return deleteErr
+		},
+		cleanup: func() error {
+			f.cleaned = true
+			return nil
+		},
+		eventRecorder: f.recorder,
+	}
+	return f
+}
+
+func newRemovedNode() *v3.Node {
+	node := &v3.Node{}
+	node.Namespace = "c-test"
+	node.Name = "m-test"
+	node.Spec.RequestedHostname = "test-node"
+	return node
+}
+
+func TestRemoveMachine(t *testing.T) {
+	f := newRemoverFakes(nil, nil)
+
+	require.NoError(t, f.remover.remove("/nodes/test-node", newRemovedNode()))
+	assert.True(t, f.drained)
+	assert.True(t, f.deleted)
+	assert.False(t, f.cleaned, "cleanup is left to the caller when the machine is deleted")
+	assert.Len(t, f.recorder.Events, 0)
+}
+
+func TestRemoveUnreachableMachine(t *testing.T) {
+	f := newRemoverFakes(errors.New("ssh: unable to authenticate"), nil)
+
+	require.NoError(t, f.remover.remove("/nodes/test-node", newRemovedNode()))
+	assert.False(t, f.drained, "expected the drain to be skipped")
+	assert.True(t, f.deleted, "expected the machine to be deleted anyway")
+	require.Len(t, f.recorder.Events, 1)
+	assert.Contains(t, <-f.recorder.Events, machineUnreachableReason)
+}
+
+func TestRemoveUnreachableMachineDeleteFails(t *testing.T) {
+	f := newRemoverFakes(errors.New("ssh: unable to authenticate"), errors.New("exit status 1"))
+
+	err := f.remover.remove("/nodes/test-node", newRemovedNode())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), forceDeleteAnnotation)
+	}
+	assert.False(t, f.cleaned, "expected the node config to be kept without the force delete annotation")
+}
+
+func TestRemoveMachineForceDelete(t *testing.T) {
+	f := newRemoverFakes(errors.New("ssh: unable to authenticate"), errors.New("exit status 1"))
+	node := newRemovedNode()
+	node.Annotations = map[string]string{forceDeleteAnnotation: "true"}
+
+	require.NoError(t, f.remover.remove("/nodes/test-node", node))
+	assert.False(t, f.drained)
+	assert.True(t, f.cleaned, "expected the node config secret and machine state to be removed")
+	require.Len(t, f.recorder.Events, 2)
+	assert.Contains(t, <-f.recorder.Events, machineUnreachableReason)
+	assert.Contains(t, <-f.recorder.Events, machineOrphanedReason)
+}
+
+func TestRemoveMachineCleanupFails(t *testing.T) {
+	f := newRemoverFakes(nil, errors.New("exit status 1"))
+	f.remover.cleanup = func() error {
+		return errors.New("secret delete failed")
+	}
+	node := newRemovedNode()
+	node.Annotations = map[string]string{forceDeleteAnnotation: "true"}
+
+	assert.Error(t, f.remover.remove("/nodes/test-node", node), "expected the removal to be retried")
+}
+
+func TestCheckMachineSSH(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	sshKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	// a host that closes the connection during the handshake, like a host that no longer accepts the key
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	node := newRemovedNode()
+	assert.Error(t, checkMachineSSH(node), "expected a node without a node config to be unreachable")
+
+	node.Status.NodeConfig = &rketypes.RKEConfigNode{Address: host, Port: port, User: "docker", SSHKey: "not a key"}
+	assert.Error(t, checkMachineSSH(node), "expected an invalid key to be unreachable")
+
+	node.Status.NodeConfig.SSHKey = sshKey
+	assert.Error(t, checkMachineSSH(node), "expected a failed handshake to be unreachable")
+}
@@ -2,14 +2,20 @@
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +25,7 @@
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/jailer"
+	nodehelper "github.com/rancher/rancher/pkg/node"
 	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -26,6 +33,11 @@
 
 var regExHyphen = regexp.MustCompile("([a-z])([A-Z])")
 
+// maxEngineInstallScriptSize is the maximum size of the docker installer downloaded to verify its checksum
+const maxEngineInstallScriptSize = 5 << 20
+
+var engineInstallClient = &http.Client{Timeout: 30 * time.Second}
+
 var (
 	RegExNodeDirEnv      = regexp.MustCompile("^" + nodeDirEnvKey + ".*")
 	RegExNodePluginToken = regexp.MustCompile("^" + "MACHINE_PLUGIN_TOKEN=" + ".*")
@@ -34,21 +46,27 @@
 
 const (
 	errorCreatingNode = "Error creating machine: "
+	creatingNode      = "Creating machine"
 	nodeDirEnvKey     = "MACHINE_STORAGE_PATH="
 	nodeCmd           = "rancher-machine"
 	ec2TagFlag        = "tags"
+
+	ec2HTTPEndpointFlag = "httpEndpoint"
+	ec2HTTPTokensFlag   = "httpTokens"
+	ec2HopLimitFlag     = "httpPutResponseHopLimit"
+	ec2DefaultHopLimit  = "2"
 )
 
 func buildAgentCommand(node *v3.Node, dockerRun string) []string {
 	drun := strings.Fields(dockerRun)
-	cmd := []string{"--native-ssh", "ssh", node.Spec.RequestedHostname}
+	cmd := []string{"--native-ssh", "ssh", machineName(node)}
 	cmd = append(cmd, drun...)
 	cmd = append(cmd, "-r", "-n", node.Name)
 	return cmd
 }
 
 func buildLoginCommand(node *v3.Node, login string) []string {
-	cmd := []string{"--native-ssh", "ssh", node.Spec.RequestedHostname}
+	cmd := []string{"--native-ssh", "ssh", machineName(node)}
 	cmd = append(cmd, strings.Fields(login)...)
 	return cmd
 }
@@ -65,8 +83,22 @@ func buildCreateCommand(node *v3.Node, configMap map[string]interface{}) []strin
 	cmd = append(cmd, buildEngineOpts("--engine-registry-mirror", node.Status.NodeTemplateSpec.EngineRegistryMirror)...)
 	cmd = append(cmd, buildEngineOpts("--engine-storage-driver", []string{node.Status.NodeTemplateSpec.EngineStorageDriver})...)
 
-	for k, v := range configMap {
-		dmField := "--" + sDriver + "-" + strings.ToLower(regExHyphen.ReplaceAllString(k, "${1}-${2}"))
+	// Emit the driver flags sorted by name so that the same config always results in the same command
+	flags := make(map[string]string, len(configMap))
+	keys := make([]string, 0, len(configMap))
+	for k := range configMap {
+		flags[k] = "--" + sDriver + "-" + strings.ToLower(regExHyphen.ReplaceAllString(k, "${1}-${2}"))
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if flags[keys[i]] != flags[keys[j]] {
+			return flags[keys[i]] < flags[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, k := range keys {
+		dmField, v := flags[k], configMap[k]
 		if v == nil {
 			continue
 		}
@@ -91,10 +123,53 @@ func buildCreateCommand(node *v3.Node, configMap map[string]interface{}) []strin
 		}
 	}
 	logrus.Tracef("create cmd %v", cmd)
-	cmd = append(cmd, node.Spec.RequestedHostname)
+	cmd = append(cmd, machineName(node))
 	return cmd
 }
 
+// verifyEngineInstallURL returns an error if the docker installer served at the engine install URL of the template
+// doesn't match its checksum. rancher-machine doesn't verify the installer it runs on the node, so it is downloaded and
+// verified when the node is provisioned: this catches an installer that was replaced since the checksum was set, but
+// the node still downloads the installer again on its own.
+func verifyEngineInstallURL(spec *v32.NodeTemplateSpec) error {
+	if spec.EngineInstallURLChecksum == "" {
+		return nil
+	}
+	if spec.EngineInstallURL == "" {
+		return fmt.Errorf("engineInstallURLChecksum is set without an engineInstallURL, the docker installer can't be verified")
+	}
+	if !nodehelper.ValidEngineInstallURLChecksum(spec.EngineInstallURLChecksum) {
+		return fmt.Errorf("engineInstallURLChecksum [%s] is not a sha256 checksum, the docker installer at %s can't be verified",
+			spec.EngineInstallURLChecksum, spec.EngineInstallURL)
+	}
+
+	u, err := url.Parse(spec.EngineInstallURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("the docker installer at %s can't be verified, it isn't an http or https URL", spec.EngineInstallURL)
+	}
+	resp, err := engineInstallClient.Get(u.String())
+	if err != nil {
+		// the error of the request isn't reported, so that templates can't be used to probe the network of Rancher
+		logrus.Debugf("failed to download the docker installer at %s: %v", spec.EngineInstallURL, err)
+		return fmt.Errorf("failed to download the docker installer at %s to verify it", spec.EngineInstallURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download the docker installer at %s to verify it", spec.EngineInstallURL)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.LimitReader(resp.Body, maxEngineInstallScriptSize)); err != nil {
+		return fmt.Errorf("failed to download the docker installer at %s to verify it", spec.EngineInstallURL)
+	}
+	expected := strings.ToLower(strings.TrimPrefix(spec.EngineInstallURLChecksum, "sha256:"))
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("the docker installer at %s doesn't match engineInstallURLChecksum, its sha256 checksum is %s",
+			spec.EngineInstallURL, actual)
+	}
+	return nil
+}
+
 func buildEngineOpts(name string, values []string) []string {
 	var opts []string
 	for _, value := range values {
@@ -111,9 +186,28 @@ func mapToSlice(m map[string]string) []string {
 	for k, v := range m {
 		ret = append(ret, fmt.Sprintf("%s=%s", k, v))
 	}
+	sort.Strings(ret)
 	return ret
 }
 
+// normalizeBoolFields converts the string values of the boolean fields of the driver config, like the ones
+// copied from a cloud credential, so that a "false" is left out of the create command instead of being passed
+// as the value of the flag.
+func normalizeBoolFields(configMap map[string]interface{}, fields map[string]v32.Field) {
+	for name, field := range fields {
+		if field.Type != "boolean" {
+			continue
+		}
+		s, ok := configMap[name].(string)
+		if !ok {
+			continue
+		}
+		if b, err := strconv.ParseBool(s); err == nil {
+			configMap[name] = b
+		}
+	}
+}
+
 func buildCommand(nodeDir string, node *v3.Node, cmdArgs []string) (*exec.Cmd, error) {
 	// only in trace because machine has sensitive details and we can't control who debugs what in there easily
 	if logrus.GetLevel() >= logrus.TraceLevel {
@@ -192,9 +286,12 @@ func getSSHKey(nodeDir, keyPath string, obj *v3.Node) (string, error) {
 	return getSSHPrivateKey(nodeDir, keyName, obj)
 }
 
-func (m *Lifecycle) reportStatus(stdoutReader io.Reader, stderrReader io.Reader, node *v3.Node) (*v3.Node, error) {
+// reportStatus copies the rancher-machine output into the node's provisioned condition. When the
+// "Creating machine" milestone is seen, a config save is requested on saveNow so the machine state
+// is persisted as soon as possible.
+func (m *Lifecycle) reportStatus(stdoutReader io.Reader, stderrReader io.Reader, node *v3.Node, saveNow chan<- struct{}) (*v3.Node, error) {
 	scanner := bufio.NewScanner(stdoutReader)
-	debugPrefix := fmt.Sprintf("(%s) DBG | ", node.Spec.RequestedHostname)
+	debugPrefix := fmt.Sprintf("(%s) DBG | ", machineName(node))
 	for scanner.Scan() {
 		msg := scanner.Text()
 		if strings.Contains(msg, "To see how to connect") {
@@ -204,6 +301,12 @@ func (m *Lifecycle) reportStatus(stdoutReader io.Reader, stderrReader io.Reader,
 		if err != nil {
 			return node, err
 		}
+		if saveNow != nil && strings.Contains(msg, creatingNode) {
+			select {
+			case saveNow <- struct{}{}:
+			default:
+			}
+		}
 		if strings.HasPrefix(msg, debugPrefix) {
 			// calls in machine with log.Debug are all prefixed and spammy so only log
 			// under trace and don't add to the v3.NodeConditionProvisioned.Message
@@ -232,7 +335,7 @@ func filterDockerMessage(msg string, node *v3.Node) (string, error) {
 	if strings.Contains(msg, errorCreatingNode) {
 		return "", errors.New(msg)
 	}
-	if strings.Contains(msg, node.Spec.RequestedHostname) {
+	if strings.Contains(msg, machineName(node)) {
 		return "", nil
 	}
 	return msg, nil
@@ -257,7 +360,7 @@ func nodeExists(nodeDir string, node *v3.Node) (bool, error) {
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		foundName := scanner.Text()
-		if foundName == node.Spec.RequestedHostname {
+		if foundName == machineName(node) {
 			return true, nil
 		}
 	}
@@ -273,7 +376,7 @@ func nodeExists(nodeDir string, node *v3.Node) (bool, error) {
 }
 
 func deleteNode(nodeDir string, node *v3.Node) error {
-	command, err := buildCommand(nodeDir, node, []string{"rm", "-f", node.Spec.RequestedHostname})
+	command, err := buildCommand(nodeDir, node, []string{"rm", "-f", machineName(node)})
 	if err != nil {
 		return err
 	}
@@ -298,7 +401,7 @@ func deleteNode(nodeDir string, node *v3.Node) error {
 }
 
 func getSSHPrivateKey(nodeDir, keyName string, node *v3.Node) (string, error) {
-	keyPath := filepath.Join(nodeDir, "machines", node.Spec.RequestedHostname, keyName)
+	keyPath := filepath.Join(nodeDir, "machines", machineName(node), keyName)
 	data, err := ioutil.ReadFile(keyPath)
 	if err != nil {
 		return "", nil
@@ -307,7 +410,7 @@ func getSSHPrivateKey(nodeDir, keyName string, node *v3.Node) (string, error) {
 }
 
 func waitUntilSSHKey(nodeDir, keyName string, node *v3.Node) error {
-	keyPath := filepath.Join(nodeDir, "machines", node.Spec.RequestedHostname, keyName)
+	keyPath := filepath.Join(nodeDir, "machines", machineName(node), keyName)
 	startTime := time.Now()
 	increments := 1
 	for {
@@ -335,6 +438,21 @@ func setEc2ClusterIDTag(data interface{}, clusterID string) {
 	}
 }
 
+// setEc2IMDSv2Options requires IMDSv2 on the instance if the node template requires it. The hop limit
+// defaults to 2 so that containers on the instance can still reach the metadata service.
+func setEc2IMDSv2Options(data interface{}, template *v3.NodeTemplate) {
+	if !template.Spec.RequireIMDSv2 {
+		return
+	}
+	if m, ok := data.(map[string]interface{}); ok {
+		m[ec2HTTPEndpointFlag] = "enabled"
+		m[ec2HTTPTokensFlag] = "required"
+		if hopLimit, ok := m[ec2HopLimitFlag]; !ok || convert.ToString(hopLimit) == "" {
+			m[ec2HopLimitFlag] = ec2DefaultHopLimit
+		}
+	}
+}
+
 func (m *Lifecycle) getKubeConfig(cluster *v3.Cluster) (*clientcmdapi.Config, string, error) {
 	user, err := m.systemAccountManager.GetSystemUser(cluster.Name)
 	if err != nil {
@@ -0,0 +1,194 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCreateCommandTestNode() *v3.Node {
+	node := &v3.Node{}
+	node.Spec.RequestedHostname = "test-node"
+	node.Status.NodeTemplateSpec = &v32.NodeTemplateSpec{
+		Driver: "amazonec2",
+		EngineOpt: map[string]string{
+			"log-level":  "debug",
+			"data-root":  "/var/lib/docker",
+			"debug":      "false",
+			"max-frames": "10",
+		},
+		EngineEnv:   map[string]string{"HTTP_PROXY": "proxy", "HTTPS_PROXY": "proxy", "NO_PROXY": "localhost"},
+		EngineLabel: map[string]string{"zone": "a", "env": "test"},
+	}
+	return node
+}
+
+func newCreateCommandTestConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"region":             "us-west-2",
+		"instanceType":       "t3.medium",
+		"rootSize":           float64(16),
+		"securityGroup":      []interface{}{"sg-b", "sg-a", "sg-c"},
+		"tags":               "",
+		"privateAddressOnly": false,
+		"useEbsOptimized":    true,
+		"zone":               nil,
+	}
+}
+
+func TestBuildCreateCommandDeterministic(t *testing.T) {
+	node := newCreateCommandTestNode()
+	expected := []string{
+		"create", "-d", "amazonec2",
+		"--engine-opt", "data-root=/var/lib/docker",
+		"--engine-opt", "debug=false",
+		"--engine-opt", "log-level=debug",
+		"--engine-opt", "max-frames=10",
+		"--engine-env", "HTTPS_PROXY=proxy",
+		"--engine-env", "HTTP_PROXY=proxy",
+		"--engine-env", "NO_PROXY=localhost",
+		"--engine-label", "env=test",
+		"--engine-label", "zone=a",
+		"--amazonec2-instance-type", "t3.medium",
+		"--amazonec2-region", "us-west-2",
+		"--amazonec2-root-size", "16",
+		"--amazonec2-security-group", "sg-b",
+		"--amazonec2-security-group", "sg-a",
+		"--amazonec2-security-group", "sg-c",
+		"--amazonec2-use-ebs-optimized",
+		"test-node",
+	}
+
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, expected, buildCreateCommand(node, newCreateCommandTestConfig()),
+			"expected the create command to be identical across runs")
+	}
+}
+
+func TestBuildCreateCommandAliasedFields(t *testing.T) {
+	os.Setenv("CATTLE_DEV_MODE", "true")
+	defer os.Unsetenv("CATTLE_DEV_MODE")
+
+	node := newCreateCommandTestNode()
+	build := func() []string {
+		config := newCreateCommandTestConfig()
+		config["sshKeyContents"] = "fakecontent-key"
+		config["userdata"] = "fakecontent-userdata"
+		require.NoError(t, aliasToPath("amazonec2", config, "fake"))
+		return buildCreateCommand(node, config)
+	}
+
+	first := build()
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, first, build(), "expected the create command to be identical across runs")
+	}
+
+	sshKeyPath, userdata := indexOf(first, "--amazonec2-ssh-keypath"), indexOf(first, "--amazonec2-userdata")
+	require.NotEqual(t, -1, sshKeyPath, "expected the aliased ssh key to be passed as a path")
+	require.NotEqual(t, -1, userdata, "expected the aliased userdata to be passed as a path")
+	assert.Less(t, indexOf(first, "--amazonec2-security-group"), sshKeyPath)
+	assert.Less(t, sshKeyPath, indexOf(first, "--amazonec2-use-ebs-optimized"))
+	assert.Less(t, indexOf(first, "--amazonec2-use-ebs-optimized"), userdata)
+	assert.NotContains(t, first, "--amazonec2-ssh-key-contents")
+
+	os.Remove(first[sshKeyPath+1])
+	os.Remove(first[userdata+1])
+}
+
+func TestBuildCreateCommandBoolFields(t *testing.T) {
+	node := newCreateCommandTestNode()
+	config := map[string]interface{}{
+		"privateAddressOnly":  "false",
+		"useEbsOptimized":     "true",
+		"requestSpotInstance": "False",
+		"region":              "false",
+	}
+	normalizeBoolFields(config, map[string]v32.Field{
+		"privateAddressOnly":  {Type: "boolean"},
+		"useEbsOptimized":     {Type: "boolean"},
+		"requestSpotInstance": {Type: "boolean"},
+		"region":              {Type: "string"},
+	})
+
+	cmd := buildCreateCommand(node, config)
+	assert.NotContains(t, cmd, "--amazonec2-private-address-only", "expected a false boolean not to be passed")
+	assert.NotContains(t, cmd, "--amazonec2-request-spot-instance", "expected a false boolean not to be passed")
+	i := indexOf(cmd, "--amazonec2-use-ebs-optimized")
+	require.NotEqual(t, -1, i)
+	assert.Equal(t, "test-node", cmd[i+1], "expected a true boolean to be passed as a bare flag")
+	assert.Equal(t, "false", cmd[indexOf(cmd, "--amazonec2-region")+1], "expected a string field to keep its value")
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestBuildCreateCommandEngineInstallURLChecksum(t *testing.T) {
+	node := newCreateCommandTestNode()
+	node.Status.NodeTemplateSpec.EngineInstallURL = "https://releases.rancher.com/install-docker/20.10.sh"
+	node.Status.NodeTemplateSpec.EngineInstallURLChecksum = "sha256:0b6d2d8a3bd7b5a1b4dbc8c1a1e5e9d0c5e57e4d3a8b9f6c1d2e3f4a5b6c7d8e"
+
+	cmd := buildCreateCommand(node, map[string]interface{}{})
+	assert.NotContains(t, cmd, "--engine-install-url-checksum", "expected the checksum to be verified by Rancher, rancher-machine has no such flag")
+	assert.Equal(t, "https://releases.rancher.com/install-docker/20.10.sh", cmd[indexOf(cmd, "--engine-install-url")+1])
+}
+
+func TestVerifyEngineInstallURL(t *testing.T) {
+	installer := "#!/bin/sh\necho installing docker\n"
+	hash := sha256.Sum256([]byte(installer))
+	checksum := hex.EncodeToString(hash[:])
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/install.sh" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write([]byte(installer))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		checksum string
+		wantErr  bool
+	}{
+		{name: "no checksum", url: server.URL + "/install.sh"},
+		{name: "checksum", url: server.URL + "/install.sh", checksum: checksum},
+		{name: "prefixed checksum", url: server.URL + "/install.sh", checksum: "sha256:" + checksum},
+		{name: "uppercase checksum", url: server.URL + "/install.sh", checksum: strings.ToUpper(checksum)},
+		{name: "checksum of another installer", url: server.URL + "/install.sh", checksum: strings.Repeat("0", 64), wantErr: true},
+		{name: "missing installer", url: server.URL + "/missing.sh", checksum: checksum, wantErr: true},
+		{name: "not an http url", url: "file:///etc/passwd", checksum: checksum, wantErr: true},
+		{name: "checksum without url", checksum: checksum, wantErr: true},
+		{name: "malformed checksum", url: server.URL + "/install.sh", checksum: "md5:abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyEngineInstallURL(&v32.NodeTemplateSpec{
+				NodeCommonParams: v32.NodeCommonParams{
+					EngineInstallURL:         tt.url,
+					EngineInstallURLChecksum: tt.checksum,
+				},
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
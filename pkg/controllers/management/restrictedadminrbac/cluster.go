@@ -2,6 +2,7 @@
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
@@ -9,6 +10,7 @@
 	k8srbac "k8s.io/api/rbac/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -22,20 +24,29 @@ func (r *rbaccontroller) clusterRBACSync(key string, cluster *v3.Cluster) (runti
 		return nil, nil
 	}
 
+	// the access previously granted to a downstream cluster excluded from the reach of restricted-admin is revoked
+	if rbac.RestrictedAdminExcluded(cluster) {
+		return nil, r.removeRestrictedAdminClusterAccess(cluster)
+	}
+
+	crName := fmt.Sprintf("%s-%s", cluster.Name, rbac.RestrictedAdminCRForClusters)
+	if _, err := r.crLister.Get("", crName); k8serrors.IsNotFound(err) {
+		// the cluster is new or no longer excluded, its projects are synced again to grant access to them
+		if err := r.enqueueProjects(cluster); err != nil {
+			return nil, err
+		}
+	}
+
 	grbs, err := r.grbIndexer.ByIndex(grbByRoleIndex, rbac.GlobalRestrictedAdmin)
 	if err != nil {
 		return nil, err
 	}
 
-	var subjects []k8srbac.Subject
+	var restrictedAdmins []*v3.GlobalRoleBinding
 	var returnErr error
 	for _, x := range grbs {
 		grb, _ := x.(*v3.GlobalRoleBinding)
-		restrictedAdminUserName := grb.UserName
-		subjects = append(subjects, k8srbac.Subject{
-			Kind: "User",
-			Name: restrictedAdminUserName,
-		})
+		restrictedAdmins = append(restrictedAdmins, grb)
 		rbName := fmt.Sprintf("%s-%s", grb.Name, rbac.RestrictedAdminClusterRoleBinding)
 		rb, err := r.rbLister.Get(cluster.Name, rbName)
 		if err != nil && !k8serrors.IsNotFound(err) {
@@ -63,12 +74,7 @@ func (r *rbaccontroller) clusterRBACSync(key string, cluster *v3.Cluster) (runti
 				Name: rbac.ClusterCRDsClusterRole,
 				Kind: "ClusterRole",
 			},
-			Subjects: []k8srbac.Subject{
-				{
-					Kind: "User",
-					Name: restrictedAdminUserName,
-				},
-			},
+			Subjects: []k8srbac.Subject{rbac.GetGRBSubject(grb)},
 		})
 		if err != nil && !k8serrors.IsAlreadyExists(err) {
 			returnErr = multierror.Append(returnErr, err)
@@ -79,12 +85,12 @@ func (r *rbaccontroller) clusterRBACSync(key string, cluster *v3.Cluster) (runti
 		return nil, returnErr
 	}
 
-	return nil, r.createCRAndCRBForRestrictedAdminClusterAccess(cluster, subjects)
+	return nil, r.createCRAndCRBForRestrictedAdminClusterAccess(cluster, restrictedAdmins)
 }
 
 /* createCRAndCRBForRestrictedAdminClusterAccess creates a CR with the resourceName field containing current cluster's ID. It also creates
 a CRB for binding this CR to all the restricted admins. This way all restricted admins become owners of the cluster*/
-func (r *rbaccontroller) createCRAndCRBForRestrictedAdminClusterAccess(cluster *v3.Cluster, subjects []k8srbac.Subject) error {
+func (r *rbaccontroller) createCRAndCRBForRestrictedAdminClusterAccess(cluster *v3.Cluster, restrictedAdmins []*v3.GlobalRoleBinding) error {
 	var returnErr error
 
 	crName := fmt.Sprintf("%s-%s", cluster.Name, rbac.RestrictedAdminCRForClusters)
@@ -121,8 +127,9 @@ func (r *rbaccontroller) createCRAndCRBForRestrictedAdminClusterAccess(cluster *
 		}
 
 		crbNamePrefix := fmt.Sprintf("%s-%s", cluster.Name, rbac.RestrictedAdminCRBForClusters)
-		for _, subject := range subjects {
-			crbName := fmt.Sprintf("%s-%s", crbNamePrefix, subject.Name)
+		for _, grb := range restrictedAdmins {
+			// the name of a group is hashed into a valid name
+			crbName := fmt.Sprintf("%s-%s", crbNamePrefix, rbac.GetGRBTargetKey(grb))
 			existingCrb, err := r.crbLister.Get("", crbName)
 			if err != nil && !k8serrors.IsNotFound(err) {
 				returnErr = multierror.Append(returnErr, err)
@@ -147,7 +154,7 @@ func (r *rbaccontroller) createCRAndCRBForRestrictedAdminClusterAccess(cluster *
 					Kind: "ClusterRole",
 					Name: crName,
 				},
-				Subjects: []k8srbac.Subject{subject},
+				Subjects: []k8srbac.Subject{rbac.GetGRBSubject(grb)},
 			}
 
 			_, err = r.clusterRoleBindings.Create(&crb)
@@ -158,3 +165,78 @@ func (r *rbaccontroller) createCRAndCRBForRestrictedAdminClusterAccess(cluster *
 	}
 	return returnErr
 }
+
+// removeRestrictedAdminClusterAccess removes the bindings granting the restricted-admins access to the cluster and to
+// its projects, including the ones created by the global role binding handler.
+func (r *rbaccontroller) removeRestrictedAdminClusterAccess(cluster *v3.Cluster) error {
+	crName := fmt.Sprintf("%s-%s", cluster.Name, rbac.RestrictedAdminCRForClusters)
+	crbs, err := r.crbLister.List("", labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var returnErr error
+	for _, crb := range crbs {
+		if crb.RoleRef.Kind != "ClusterRole" || crb.RoleRef.Name != crName {
+			continue
+		}
+		if err := r.clusterRoleBindings.Delete(crb.Name, &metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			returnErr = multierror.Append(returnErr, err)
+		}
+	}
+
+	if _, err := r.crLister.Get("", crName); err == nil {
+		if err := r.clusterRoles.Delete(crName, &metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			returnErr = multierror.Append(returnErr, err)
+		}
+	} else if !k8serrors.IsNotFound(err) {
+		returnErr = multierror.Append(returnErr, err)
+	}
+
+	if err := r.removeRestrictedAdminRoleBindings(cluster.Name, rbac.RestrictedAdminClusterRoleBinding); err != nil {
+		returnErr = multierror.Append(returnErr, err)
+	}
+
+	projects, err := r.projectLister.List(cluster.Name, labels.Everything())
+	if err != nil {
+		return multierror.Append(returnErr, err)
+	}
+	for _, project := range projects {
+		if err := r.removeRestrictedAdminRoleBindings(project.Name, rbac.RestrictedAdminProjectRoleBinding); err != nil {
+			returnErr = multierror.Append(returnErr, err)
+		}
+	}
+
+	return returnErr
+}
+
+// removeRestrictedAdminRoleBindings removes the role bindings of the restricted-admins in the namespace, they are named
+// after the global role binding with the suffix
+func (r *rbaccontroller) removeRestrictedAdminRoleBindings(namespace, suffix string) error {
+	rbs, err := r.rbLister.List(namespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var returnErr error
+	for _, rb := range rbs {
+		if !strings.HasSuffix(rb.Name, "-"+suffix) {
+			continue
+		}
+		if err := r.roleBindings.DeleteNamespaced(namespace, rb.Name, &metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			returnErr = multierror.Append(returnErr, err)
+		}
+	}
+	return returnErr
+}
+
+func (r *rbaccontroller) enqueueProjects(cluster *v3.Cluster) error {
+	projects, err := r.projectLister.List(cluster.Name, labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, project := range projects {
+		r.projects.Controller().Enqueue(project.Namespace, project.Name)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package restrictedadminrbac
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	mgmtfakes "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	rbacfakes "github.com/rancher/rancher/pkg/generated/norman/rbac.authorization.k8s.io/v1/fakes"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8srbac "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestClusterRBACSyncExcludedCluster(t *testing.T) {
+	cluster := &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "c-abcde",
+			Annotations: map[string]string{rbac.RestrictedAdminExcludeAnnotation: "true"},
+		},
+	}
+	crName := "c-abcde-" + rbac.RestrictedAdminCRForClusters
+
+	crbs := []*k8srbac.ClusterRoleBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde-restricted-admin-crb-clusters-u-abcde"}, RoleRef: k8srbac.RoleRef{Kind: "ClusterRole", Name: crName}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-abcderestricted-admin-crb-clustersgrb-abcde"}, RoleRef: k8srbac.RoleRef{Kind: "ClusterRole", Name: crName}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-fghij-restricted-admin-crb-clusters-u-abcde"}, RoleRef: k8srbac.RoleRef{Kind: "ClusterRole", Name: "c-fghij-" + rbac.RestrictedAdminCRForClusters}},
+	}
+	rbs := map[string][]*k8srbac.RoleBinding{
+		"c-abcde": {
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "grb-abcde-" + rbac.RestrictedAdminClusterRoleBinding}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "crtb-abcde"}},
+		},
+		"p-abcde": {
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "p-abcde", Name: "grb-abcde-" + rbac.RestrictedAdminProjectRoleBinding}},
+		},
+	}
+
+	var deletedCRBs, deletedCRs, deletedRBs []string
+	r := &rbaccontroller{
+		crbLister: &rbacfakes.ClusterRoleBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*k8srbac.ClusterRoleBinding, error) {
+				return crbs, nil
+			},
+		},
+		clusterRoleBindings: &rbacfakes.ClusterRoleBindingInterfaceMock{
+			DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+				deletedCRBs = append(deletedCRBs, name)
+				return nil
+			},
+		},
+		crLister: &rbacfakes.ClusterRoleListerMock{
+			GetFunc: func(namespace string, name string) (*k8srbac.ClusterRole, error) {
+				return &k8srbac.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+			},
+		},
+		clusterRoles: &rbacfakes.ClusterRoleInterfaceMock{
+			DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+				deletedCRs = append(deletedCRs, name)
+				return nil
+			},
+		},
+		rbLister: &rbacfakes.RoleBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*k8srbac.RoleBinding, error) {
+				return rbs[namespace], nil
+			},
+		},
+		roleBindings: &rbacfakes.RoleBindingInterfaceMock{
+			DeleteNamespacedFunc: func(namespace string, name string, options *metav1.DeleteOptions) error {
+				deletedRBs = append(deletedRBs, namespace+"/"+name)
+				return nil
+			},
+		},
+		projectLister: &mgmtfakes.ProjectListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.Project, error) {
+				return []*v3.Project{{ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "p-abcde"}}}, nil
+			},
+		},
+	}
+
+	_, err := r.clusterRBACSync(cluster.Name, cluster)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{crbs[0].Name, crbs[1].Name}, deletedCRBs,
+		"expected the bindings listing the cluster for restricted-admins to be removed")
+	assert.Equal(t, []string{crName}, deletedCRs)
+	assert.ElementsMatch(t, []string{
+		"c-abcde/grb-abcde-" + rbac.RestrictedAdminClusterRoleBinding,
+		"p-abcde/grb-abcde-" + rbac.RestrictedAdminProjectRoleBinding,
+	}, deletedRBs)
+}
+
+func TestClusterRBACSyncUserAndGroupSubjects(t *testing.T) {
+	grbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		grbByRoleIndex: func(obj interface{}) ([]string, error) {
+			return []string{obj.(*v3.GlobalRoleBinding).GlobalRoleName}, nil
+		},
+	})
+	userGRB := &v3.GlobalRoleBinding{
+		ObjectMeta:     metav1.ObjectMeta{Name: "grb-user"},
+		UserName:       "u-abcde",
+		GlobalRoleName: rbac.GlobalRestrictedAdmin,
+	}
+	groupGRB := &v3.GlobalRoleBinding{
+		ObjectMeta:         metav1.ObjectMeta{Name: "grb-group"},
+		GroupPrincipalName: "github_team://1234",
+		GlobalRoleName:     rbac.GlobalRestrictedAdmin,
+	}
+	require.NoError(t, grbIndexer.Add(userGRB))
+	require.NoError(t, grbIndexer.Add(groupGRB))
+
+	notFound := func(name string) error {
+		return k8serrors.NewNotFound(schema.GroupResource{}, name)
+	}
+	rbs := map[string]*k8srbac.RoleBinding{}
+	crbs := map[string]*k8srbac.ClusterRoleBinding{}
+	r := &rbaccontroller{
+		grbIndexer: grbIndexer,
+		rbLister: &rbacfakes.RoleBindingListerMock{
+			GetFunc: func(namespace string, name string) (*k8srbac.RoleBinding, error) {
+				return nil, notFound(name)
+			},
+		},
+		roleBindings: &rbacfakes.RoleBindingInterfaceMock{
+			CreateFunc: func(in1 *k8srbac.RoleBinding) (*k8srbac.RoleBinding, error) {
+				rbs[in1.Name] = in1
+				return in1, nil
+			},
+		},
+		crLister: &rbacfakes.ClusterRoleListerMock{
+			GetFunc: func(namespace string, name string) (*k8srbac.ClusterRole, error) {
+				return nil, notFound(name)
+			},
+		},
+		clusterRoles: &rbacfakes.ClusterRoleInterfaceMock{
+			CreateFunc: func(in1 *k8srbac.ClusterRole) (*k8srbac.ClusterRole, error) {
+				return in1, nil
+			},
+		},
+		crbLister: &rbacfakes.ClusterRoleBindingListerMock{
+			GetFunc: func(namespace string, name string) (*k8srbac.ClusterRoleBinding, error) {
+				return nil, notFound(name)
+			},
+		},
+		clusterRoleBindings: &rbacfakes.ClusterRoleBindingInterfaceMock{
+			CreateFunc: func(in1 *k8srbac.ClusterRoleBinding) (*k8srbac.ClusterRoleBinding, error) {
+				crbs[in1.Name] = in1
+				return in1, nil
+			},
+		},
+		projectLister: &mgmtfakes.ProjectListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.Project, error) {
+				return nil, nil
+			},
+		},
+	}
+
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	_, err := r.clusterRBACSync(cluster.Name, cluster)
+	require.NoError(t, err)
+
+	userSubject := k8srbac.Subject{Kind: "User", Name: "u-abcde", APIGroup: k8srbac.GroupName}
+	groupSubject := k8srbac.Subject{Kind: "Group", Name: "github_team://1234", APIGroup: k8srbac.GroupName}
+
+	require.Contains(t, rbs, "grb-user-"+rbac.RestrictedAdminClusterRoleBinding)
+	assert.Equal(t, []k8srbac.Subject{userSubject}, rbs["grb-user-"+rbac.RestrictedAdminClusterRoleBinding].Subjects)
+	require.Contains(t, rbs, "grb-group-"+rbac.RestrictedAdminClusterRoleBinding)
+	assert.Equal(t, []k8srbac.Subject{groupSubject}, rbs["grb-group-"+rbac.RestrictedAdminClusterRoleBinding].Subjects)
+
+	crbPrefix := "c-abcde-" + rbac.RestrictedAdminCRBForClusters + "-"
+	require.Contains(t, crbs, crbPrefix+"u-abcde", "expected the binding of a user to be named after the user")
+	assert.Equal(t, []k8srbac.Subject{userSubject}, crbs[crbPrefix+"u-abcde"].Subjects)
+	groupCRBName := crbPrefix + rbac.GetGRBTargetKey(groupGRB)
+	require.Contains(t, crbs, groupCRBName, "expected the binding of a group to be named after the hash of the group")
+	assert.Equal(t, []k8srbac.Subject{groupSubject}, crbs[groupCRBName].Subjects)
+	assert.Equal(t, "c-abcde-"+rbac.RestrictedAdminCRForClusters, crbs[groupCRBName].RoleRef.Name)
+
+	_, err = r.clusterRBACSync(cluster.Name, cluster)
+	require.NoError(t, err)
+	assert.Len(t, crbs, 2, "expected the names of the bindings to be deterministic")
+}
@@ -54,14 +54,14 @@ func (r *rbaccontroller) ensureRestricedAdminForFleet(key string, obj *v3.Global
 		if fw.Name == "fleet-local" {
 			continue
 		}
-		if err := r.ensureRolebinding(fw.Name, obj.UserName, obj); err != nil {
+		if err := r.ensureRolebinding(fw.Name, obj); err != nil {
 			finalError = multierror.Append(finalError, err)
 		}
 	}
 	return obj, finalError
 }
 
-func (r *rbaccontroller) ensureRolebinding(namespace, userName string, grb *v3.GlobalRoleBinding) error {
+func (r *rbaccontroller) ensureRolebinding(namespace string, grb *v3.GlobalRoleBinding) error {
 	rbName := fmt.Sprintf("%s-fleetworkspace-%s", grb.Name, rbac.RestrictedAdminClusterRoleBinding)
 	rb := &k8srbac.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
@@ -81,12 +81,7 @@ func (r *rbaccontroller) ensureRolebinding(namespace, userName string, grb *v3.G
 			Name: "fleetworkspace-admin",
 			Kind: "ClusterRole",
 		},
-		Subjects: []k8srbac.Subject{
-			{
-				Kind: "User",
-				Name: userName,
-			},
-		},
+		Subjects: []k8srbac.Subject{rbac.GetGRBSubject(grb)},
 	}
 	_, err := r.rbLister.Get(namespace, rbName)
 	if err != nil && !k8serrors.IsNotFound(err) {
@@ -18,7 +18,13 @@ func (r *rbaccontroller) projectRBACSync(key string, project *apimgmtv3.Project)
 		return nil, nil
 	}
 
-	if project.Namespace == "local" {
+	cluster, err := r.clusterLister.Get("", project.Namespace)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if rbac.RestrictedAdminExcluded(cluster) {
 		return nil, nil
 	}
 
@@ -29,7 +35,6 @@ func (r *rbaccontroller) projectRBACSync(key string, project *apimgmtv3.Project)
 	}
 	for _, x := range grbs {
 		grb, _ := x.(*v3.GlobalRoleBinding)
-		restrictedAdminUserName := grb.UserName
 		rbName := fmt.Sprintf("%s-%s", grb.Name, rbac.RestrictedAdminProjectRoleBinding)
 		rb, err := r.rbLister.Get(project.Name, rbName)
 		if err != nil && !k8serrors.IsNotFound(err) {
@@ -57,12 +62,7 @@ func (r *rbaccontroller) projectRBACSync(key string, project *apimgmtv3.Project)
 				Name: rbac.ProjectCRDsClusterRole,
 				Kind: "ClusterRole",
 			},
-			Subjects: []k8srbac.Subject{
-				{
-					Kind: "User",
-					Name: restrictedAdminUserName,
-				},
-			},
+			Subjects: []k8srbac.Subject{rbac.GetGRBSubject(grb)},
 		})
 		if err != nil && !k8serrors.IsAlreadyExists(err) {
 			returnErr = multierror.Append(returnErr, err)
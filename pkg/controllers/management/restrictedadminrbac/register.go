@@ -18,7 +18,9 @@ type rbaccontroller struct {
 	roleBindings        v1.RoleBindingInterface
 	rbLister            v1.RoleBindingLister
 	clusters            v3.ClusterInterface
+	clusterLister       v3.ClusterLister
 	projects            v3.ProjectInterface
+	projectLister       v3.ProjectLister
 	clusterRoles        v1.ClusterRoleInterface
 	crLister            v1.ClusterRoleLister
 	crbLister           v1.ClusterRoleBindingLister
@@ -35,7 +37,9 @@ func Register(ctx context.Context, management *config.ManagementContext, wrangle
 	informer := management.Management.GlobalRoleBindings("").Controller().Informer()
 	r := rbaccontroller{
 		clusters:            management.Management.Clusters(""),
+		clusterLister:       management.Management.Clusters("").Controller().Lister(),
 		projects:            management.Management.Projects(""),
+		projectLister:       management.Management.Projects("").Controller().Lister(),
 		grbLister:           management.Management.GlobalRoleBindings("").Controller().Lister(),
 		globalRoleBindings:  management.Management.GlobalRoleBindings(""),
 		grbIndexer:          informer.GetIndexer(),
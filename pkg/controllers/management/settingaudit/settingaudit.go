@@ -0,0 +1,76 @@
+package settingaudit
+
+import (
+	"context"
+	"sync"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/eventrecorder"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// SettingChangedReason is the reason of the event recorded on every change of the value of a setting
+	SettingChangedReason = "SettingChanged"
+
+	// systemUser is who the changes that didn't go through the API are attributed to
+	systemUser = "system"
+)
+
+// This records an audit event on every change of the value of a setting. The values are only kept in memory, so the
+// first time a setting is seen its value is only recorded and nothing is audited on startup.
+
+type handler struct {
+	recorder record.EventRecorder
+
+	lock   sync.Mutex
+	values map[string]string
+}
+
+func Register(ctx context.Context, management *config.ManagementContext) {
+	h := &handler{
+		recorder: eventrecorder.New(ctx, management.K8sClient, "setting-audit"),
+		values:   map[string]string{},
+	}
+	management.Wrangler.Mgmt.Setting().OnChange(ctx, "setting-audit", h.sync)
+}
+
+func (h *handler) sync(key string, setting *v3.Setting) (*v3.Setting, error) {
+	if setting == nil || setting.DeletionTimestamp != nil {
+		h.lock.Lock()
+		delete(h.values, key)
+		h.lock.Unlock()
+		return setting, nil
+	}
+
+	oldValue, changed := h.observe(setting.Name, setting.Value)
+	if !changed {
+		return setting, nil
+	}
+
+	user := settings.UpdatedByUser(setting.Annotations[settings.UpdatedByAnnotation], setting.Value)
+	if user == "" {
+		user = systemUser
+	}
+	oldValue, newValue := settings.Redact(setting.Name, oldValue), settings.Redact(setting.Name, setting.Value)
+
+	logrus.Infof("[settingaudit] setting %s changed from %q to %q by %s", setting.Name, oldValue, newValue, user)
+	h.recorder.Eventf(setting, corev1.EventTypeNormal, SettingChangedReason, "Setting %s changed from %q to %q by %s",
+		setting.Name, oldValue, newValue, user)
+	return setting, nil
+}
+
+// observe records the value of the setting. It returns the previous value, and whether the value changed since the
+// setting was last seen.
+func (h *handler) observe(name, value string) (string, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	oldValue, ok := h.values[name]
+	h.values[name] = value
+	return oldValue, ok && oldValue != value
+}
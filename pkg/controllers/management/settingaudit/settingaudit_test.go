@@ -0,0 +1,82 @@
+package settingaudit
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func newSetting(name, value, user string) *v3.Setting {
+	setting := &v3.Setting{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Value:      value,
+	}
+	if user != "" {
+		setting.Annotations = map[string]string{settings.UpdatedByAnnotation: settings.UpdatedBy(user, value)}
+	}
+	return setting
+}
+
+func newTestHandler() (*handler, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	return &handler{recorder: recorder, values: map[string]string{}}, recorder
+}
+
+func TestSettingChangeAudited(t *testing.T) {
+	h, recorder := newTestHandler()
+
+	_, err := h.sync("server-url", newSetting("server-url", "https://old.example.com", ""))
+	require.NoError(t, err)
+	assert.Empty(t, recorder.Events, "expected nothing to be audited the first time a setting is seen")
+
+	_, err = h.sync("server-url", newSetting("server-url", "https://old.example.com", "u-admin"))
+	require.NoError(t, err)
+	assert.Empty(t, recorder.Events, "expected nothing to be audited when the value didn't change")
+
+	_, err = h.sync("server-url", newSetting("server-url", "https://new.example.com", "u-admin"))
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, `Normal SettingChanged Setting server-url changed from "https://old.example.com" to "https://new.example.com" by u-admin`,
+		<-recorder.Events)
+
+	_, err = h.sync("server-url", newSetting("server-url", "", ""))
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, `Normal SettingChanged Setting server-url changed from "https://new.example.com" to "" by system`,
+		<-recorder.Events, "expected a change without acting user to be attributed to the system")
+}
+
+func TestStaleAttributionIgnored(t *testing.T) {
+	h, recorder := newTestHandler()
+
+	_, err := h.sync("server-url", newSetting("server-url", "https://old.example.com", "u-admin"))
+	require.NoError(t, err)
+
+	// the value is changed outside of the API, leaving the annotation of the last user in place
+	changed := newSetting("server-url", "https://old.example.com", "u-admin")
+	changed.Value = "https://new.example.com"
+	_, err = h.sync("server-url", changed)
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, `Normal SettingChanged Setting server-url changed from "https://old.example.com" to "https://new.example.com" by system`,
+		<-recorder.Events, "expected a change not made by the annotated user not to be attributed to them")
+}
+
+func TestSecretSettingRedacted(t *testing.T) {
+	h, recorder := newTestHandler()
+
+	_, err := h.sync("system-charts-oci-secret", newSetting("system-charts-oci-secret", "cattle-system:old", ""))
+	require.NoError(t, err)
+	_, err = h.sync("system-charts-oci-secret", newSetting("system-charts-oci-secret", "cattle-system:new", "u-admin"))
+	require.NoError(t, err)
+
+	require.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Equal(t, `Normal SettingChanged Setting system-charts-oci-secret changed from "[redacted]" to "[redacted]" by u-admin`, event)
+	assert.NotContains(t, event, "cattle-system")
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/rancher/norman/clientbase"
@@ -15,19 +16,7 @@ func SortSchema(schemas map[string]types.Schema) []string {
 			if inserted[k] {
 				continue
 			}
-			ready := true
-			for fieldName, field := range schema.ResourceFields {
-				if strings.Contains(field.Type, "reference") {
-					reference := GetReference(field.Type)
-					if isNamespaceIDRef(reference, k) {
-						continue
-					}
-					if !inserted[reference] && fieldName != "creatorId" && k != reference {
-						ready = false
-					}
-				}
-			}
-			if ready {
+			if referencesInserted(k, schema, inserted) {
 				inserted[k] = true
 				result = append(result, k)
 			}
@@ -36,6 +25,46 @@ func SortSchema(schemas map[string]types.Schema) []string {
 	return result
 }
 
+// SchemaLevels groups the schemas in levels, the schemas of a level only reference schemas of the previous levels so
+// the schemas of a level can be processed concurrently. Like SortSchema, schemas referencing unknown schemas are
+// dropped.
+func SchemaLevels(schemas map[string]types.Schema) [][]string {
+	inserted := map[string]bool{}
+	var levels [][]string
+	for len(inserted) < len(schemas) {
+		var level []string
+		for k, schema := range schemas {
+			if !inserted[k] && referencesInserted(k, schema, inserted) {
+				level = append(level, k)
+			}
+		}
+		if len(level) == 0 {
+			break
+		}
+		sort.Strings(level)
+		for _, k := range level {
+			inserted[k] = true
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+func referencesInserted(schemaType string, schema types.Schema, inserted map[string]bool) bool {
+	for fieldName, field := range schema.ResourceFields {
+		if strings.Contains(field.Type, "reference") {
+			reference := GetReference(field.Type)
+			if isNamespaceIDRef(reference, schemaType) {
+				continue
+			}
+			if !inserted[reference] && fieldName != "creatorId" && schemaType != reference {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 var (
 	namespacedSchema = map[string]bool{
 		"project": true,
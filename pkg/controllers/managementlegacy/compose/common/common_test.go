@@ -0,0 +1,39 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/rancher/norman/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaLevels(t *testing.T) {
+	schemas := map[string]types.Schema{
+		"user": {ResourceFields: map[string]types.Field{
+			"creatorId": {Type: "reference[user]"},
+		}},
+		"globalRole": {ResourceFields: map[string]types.Field{
+			"creatorId": {Type: "reference[user]"},
+		}},
+		"globalRoleBinding": {ResourceFields: map[string]types.Field{
+			"creatorId":    {Type: "reference[user]"},
+			"userId":       {Type: "reference[user]"},
+			"globalRoleId": {Type: "reference[/v3/schemas/globalRole]"},
+		}},
+		"project": {ResourceFields: map[string]types.Field{
+			"namespaceId": {Type: "reference[namespace]"},
+			"clusterId":   {Type: "reference[cluster]"},
+		}},
+		"cluster": {ResourceFields: map[string]types.Field{
+			"creatorId": {Type: "reference[user]"},
+		}},
+		"app": {ResourceFields: map[string]types.Field{
+			"targetIds": {Type: "array[reference[missing]]"},
+		}},
+	}
+
+	assert.Equal(t, [][]string{
+		{"cluster", "globalRole", "user"},
+		{"globalRoleBinding", "project"},
+	}, SchemaLevels(schemas), "expected dependent schemas in a later level and schemas with unknown references dropped")
+}
@@ -0,0 +1,45 @@
+package compose
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// runConcurrently calls apply for each schema with up to workers calls at once. A worker only applies one schema at a
+// time, so apply may use state owned by the worker. Every schema is applied even if some fail, the errors are
+// aggregated.
+func runConcurrently(schemaKeys []string, workers int, apply func(worker int, schemaKey string) error) error {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+
+	queue := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for schemaKey := range queue {
+				if err := apply(worker, schemaKey); err != nil {
+					lock.Lock()
+					errs = append(errs, errors.Wrapf(err, "failed to apply %s", schemaKey))
+					lock.Unlock()
+				}
+			}
+		}(i)
+	}
+	for _, schemaKey := range schemaKeys {
+		queue <- schemaKey
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return utilerrors.NewAggregate(errs)
+}
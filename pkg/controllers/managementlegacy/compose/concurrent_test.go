@@ -0,0 +1,86 @@
+package compose
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunConcurrentlyIndependentSchemas(t *testing.T) {
+	schemaKeys := []string{"globalRole", "podSecurityPolicyTemplate", "nodeTemplate", "catalog"}
+
+	// every apply waits until all of them started, which only happens if they run concurrently
+	var started sync.WaitGroup
+	started.Add(len(schemaKeys))
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+
+	var lock sync.Mutex
+	applied := map[string]int{}
+	err := runConcurrently(schemaKeys, len(schemaKeys), func(worker int, schemaKey string) error {
+		started.Done()
+		select {
+		case <-allStarted:
+		case <-time.After(5 * time.Second):
+			return errors.New("schemas were not applied concurrently")
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		applied[schemaKey] = worker
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, applied, len(schemaKeys))
+}
+
+func TestRunConcurrentlyBounded(t *testing.T) {
+	var running, maxRunning int32
+	busy := map[int]bool{}
+	var lock sync.Mutex
+
+	err := runConcurrently([]string{"a", "b", "c", "d", "e", "f", "g"}, 2, func(worker int, schemaKey string) error {
+		lock.Lock()
+		assert.False(t, busy[worker], "expected a worker to apply one schema at a time")
+		busy[worker] = true
+		lock.Unlock()
+
+		n := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		lock.Lock()
+		busy[worker] = false
+		lock.Unlock()
+		return nil
+	})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, maxRunning, int32(2))
+}
+
+func TestRunConcurrentlyAggregatesErrors(t *testing.T) {
+	var calls int32
+	err := runConcurrently([]string{"a", "b", "c"}, 3, func(worker int, schemaKey string) error {
+		atomic.AddInt32(&calls, 1)
+		if schemaKey == "b" {
+			return nil
+		}
+		return errors.New("forbidden")
+	})
+	require.Error(t, err)
+	assert.Equal(t, int32(3), calls, "expected every schema to be applied even if some fail")
+	assert.Equal(t, "[failed to apply a: forbidden, failed to apply c: forbidden]", err.Error())
+}
@@ -30,12 +30,24 @@
 )
 
 const (
+	// maxConcurrentSchemas is the number of schemas without references between them that are applied at once
+	maxConcurrentSchemas = 4
+
+	// validateOnlyAnnotation set to "true" validates the compose config against the schemas without creating any resource
+	validateOnlyAnnotation = "compose.cattle.io/validate-only"
+
+	// teardownFinalizer keeps a compose config with teardownOnDelete set until the resources it created are deleted
+	teardownFinalizer = "compose.cattle.io/teardown"
+
 	composeTokenPrefix = "compose-token-"
 	description        = "token for compose"
 	url                = "https://localhost:%v/v3"
 )
 
 // Lifecycle for GlobalComposeConfig is a controller which watches composeConfig and execute the yaml config and create a bunch of global resources. There is no sync logic between yaml file and resources, which means config is only executed once. And resource is not deleted even if the compose config is deleted.
+// With prune set, the config is executed again whenever it changes and the resources it created that were removed from the yaml are deleted.
+// With the validate-only annotation set, the config is only validated against the schemas and the errors are reported on the status.
+// With teardownOnDelete set, the resources created by the config, but not the existing resources it updated, are deleted when the compose config is deleted.
 type Lifecycle struct {
 	TokenClient     v3.TokenInterface
 	UserClient      v3.UserInterface
@@ -64,6 +76,28 @@ func (l Lifecycle) sync(key string, obj *v3.ComposeConfig) (runtime.Object, erro
 	if key == "" || obj == nil {
 		return nil, nil
 	}
+	if obj.DeletionTimestamp != nil {
+		return l.teardown(obj)
+	}
+	obj, err := l.ensureTeardownFinalizer(obj)
+	if err != nil {
+		return obj, err
+	}
+	if obj.Annotations[validateOnlyAnnotation] == "true" {
+		if obj.Status.ValidatedChecksum == checksum(obj.Spec.RancherCompose) {
+			return obj, nil
+		}
+		newObj, err := l.Validate(obj.DeepCopy())
+		if err != nil {
+			return obj, err
+		}
+		return l.ComposeClient.Update(newObj)
+	}
+	if obj.Spec.Prune && obj.Status.AppliedChecksum != checksum(obj.Spec.RancherCompose) {
+		// the compose config changed since it was last applied, apply it again
+		obj = obj.DeepCopy()
+		v32.ComposeConditionExecuted.Unknown(obj)
+	}
 	newObj, err := v32.ComposeConditionExecuted.Once(obj, func() (runtime.Object, error) {
 		obj, err := l.Create(obj)
 		if err != nil {
@@ -78,16 +112,17 @@ func (l Lifecycle) sync(key string, obj *v3.ComposeConfig) (runtime.Object, erro
 	return obj, err
 }
 
-func (l Lifecycle) Create(obj *v3.ComposeConfig) (*v3.ComposeConfig, error) {
+// withToken calls f with a token of the creator of the compose config, the token is deleted once f returns
+func (l Lifecycle) withToken(obj *v3.ComposeConfig, f func(token string) error) error {
 	userID := obj.Annotations["field.cattle.io/creatorId"]
 	user, err := l.UserClient.Get(userID, metav1.GetOptions{})
 	if err != nil {
-		return obj, err
+		return err
 	}
 	tokenPrefix := composeTokenPrefix + user.Name
 	token, err := l.systemTokens.EnsureSystemToken(tokenPrefix, description, "compose", user.Name, nil, true)
 	if err != nil {
-		return obj, err
+		return err
 	}
 	tokenName, _ := tokens.SplitTokenParts(token)
 	defer func() {
@@ -95,16 +130,60 @@ func (l Lifecycle) Create(obj *v3.ComposeConfig) (*v3.ComposeConfig, error) {
 			logrus.Errorf("cleanup for compose token [%s] failed, will not retry: %v", tokenName, err)
 		}
 	}()
+	return f(token)
+}
 
+func (l Lifecycle) Create(obj *v3.ComposeConfig) (*v3.ComposeConfig, error) {
+	err := l.withToken(obj, func(token string) error {
+		return l.create(obj, token)
+	})
+	return obj, err
+}
+
+func (l Lifecycle) create(obj *v3.ComposeConfig, token string) error {
 	config := &compose.Config{}
 	if err := yaml.Unmarshal([]byte(obj.Spec.RancherCompose), config); err != nil {
-		return obj, err
+		return err
 	}
-	if err := up(token, l.HTTPSPortGetter.GetHTTPSPort(), config); err != nil {
-		return obj, err
+	if !obj.Spec.Prune {
+		_, created, err := up(token, l.HTTPSPortGetter.GetHTTPSPort(), config, nil, false)
+		l.trackCreated(obj, created, nil)
+		if err != nil {
+			return err
+		}
+		v32.ComposeConditionExecuted.True(obj)
+		return nil
+	}
+
+	// a failed apply is not retried until the compose config changes
+	obj.Status.AppliedChecksum = checksum(obj.Spec.RancherCompose)
+	applied, created, err := up(token, l.HTTPSPortGetter.GetHTTPSPort(), config, obj.Status.Resources, true)
+	if err != nil {
+		// keep tracking everything created so far, it is pruned once a later apply succeeds
+		obj.Status.Resources = mergeResources(obj.Status.Resources, created)
+		l.trackCreated(obj, created, nil)
+		return err
 	}
+	obj.Status.Resources = ownedResources(obj.Status.Resources, applied, created)
+	l.trackCreated(obj, created, applied)
 	v32.ComposeConditionExecuted.True(obj)
-	return obj, nil
+	return nil
+}
+
+// trackCreated records the resources created by the compose config when it is torn down on delete. Once pruned,
+// only the created resources that are still applied are kept.
+func (l Lifecycle) trackCreated(obj *v3.ComposeConfig, created, applied map[string]map[string]string) {
+	if !obj.Spec.TeardownOnDelete {
+		obj.Status.Created = nil
+		return
+	}
+	obj.Status.Created = mergeResources(obj.Status.Created, created)
+	if applied != nil {
+		obj.Status.Created = intersectResources(obj.Status.Created, applied)
+	}
+	if len(obj.Status.Created) == 0 {
+		obj.Status.Created = nil
+	}
 }
 
 func GetSchemas(token string, port int) (map[string]types.Schema, map[string]types.Schema, map[string]types.Schema, error) {
@@ -135,34 +214,203 @@ func GetSchemas(token string, port int) (map[string]types.Schema, map[string]typ
 	return cc.Types, mc.Types, pc.Types, nil
 }
 
-func up(token string, port int, config *compose.Config) error {
+// up creates or updates the resources of the compose config and returns the remove links of the resources it applied,
+// and of the resources among them it created, by schema and resource ID. With prune, the resources of previous that
+// were not applied are deleted, previous must only hold resources created by the compose config.
+func up(token string, port int, config *compose.Config, previous map[string]map[string]string, prune bool) (map[string]map[string]string, map[string]map[string]string, error) {
+	// applied is a map of schemaType with id -> remove link
+	applied := map[string]map[string]string{}
+	created := map[string]map[string]string{}
+
 	clusterSchemas, managementSchemas, projectSchemas, err := GetSchemas(token, port)
 	if err != nil {
-		return err
+		return applied, created, err
 	}
 
 	// referenceMap is a map of schemaType with name -> id value
 	referenceMap := map[string]map[string]string{}
 
+	rawMap, err := configToMap(config)
+	if err != nil {
+		return applied, created, err
+	}
+	allSchemas := getAllSchemas(clusterSchemas, managementSchemas, projectSchemas)
+	sortedSchemas := common.SortSchema(allSchemas)
+
+	// levels only keeps the schemas of the compose config
+	var levels [][]string
+	workerCount := 1
+	for _, level := range common.SchemaLevels(allSchemas) {
+		var schemaKeys []string
+		for _, schemaKey := range level {
+			if _, ok := rawMap[allSchemas[schemaKey].PluralName].(map[string]interface{}); ok {
+				schemaKeys = append(schemaKeys, schemaKey)
+			}
+		}
+		if len(schemaKeys) > 0 {
+			levels = append(levels, schemaKeys)
+		}
+		if len(schemaKeys) > workerCount {
+			workerCount = len(schemaKeys)
+		}
+	}
+	if workerCount > maxConcurrentSchemas {
+		workerCount = maxConcurrentSchemas
+	}
+
+	// each worker applies schemas with its own clients, as configuring a client for a cluster or project changes its url
+	workers := make([]*configClientManager, workerCount)
+	for i := range workers {
+		if workers[i], err = newConfigClientManager(token, port, clusterSchemas, managementSchemas, projectSchemas); err != nil {
+			return applied, created, err
+		}
+	}
+
+	for _, schemaKeys := range levels {
+		// the schemas of a level don't reference each other, so each worker fills in its own copy of the reference
+		// map and the copies are merged once the level is applied
+		workerReferenceMaps := make([]map[string]map[string]string, len(workers))
+		workerApplied := make([]map[string]map[string]string, len(workers))
+		workerCreated := make([]map[string]map[string]string, len(workers))
+		for i := range workers {
+			workerReferenceMaps[i] = copyReferenceMap(referenceMap)
+			workerApplied[i] = map[string]map[string]string{}
+			workerCreated[i] = map[string]map[string]string{}
+		}
+
+		err := runConcurrently(schemaKeys, len(workers), func(worker int, schemaKey string) error {
+			value := rawMap[allSchemas[schemaKey].PluralName].(map[string]interface{})
+			return workers[worker].applySchema(schemaKey, allSchemas[schemaKey], value, workerReferenceMaps[worker], workerApplied[worker], workerCreated[worker])
+		})
+		for i := range workers {
+			for schemaType, ids := range workerReferenceMaps[i] {
+				if _, ok := referenceMap[schemaType]; !ok {
+					referenceMap[schemaType] = ids
+				}
+			}
+			applied = mergeResources(applied, workerApplied[i])
+			created = mergeResources(created, workerCreated[i])
+		}
+		if err != nil {
+			return applied, created, err
+		}
+	}
+
+	if prune {
+		if err := pruneResources(previous, applied, sortedSchemas, func(schemaType, id, link string) error {
+			return removeResource(workers[0].baseManagementClient, schemaType, id, link)
+		}); err != nil {
+			return applied, created, err
+		}
+	}
+	return applied, created, nil
+}
+
+// applySchema creates or updates the resources of a schema and fills in the reference map for the schema. The
+// resources it applied are tracked in applied, the resources it created rather than updated are also tracked in created.
+func (c *configClientManager) applySchema(schemaKey string, schema types.Schema, value map[string]interface{}, referenceMap, applied, created map[string]map[string]string) error {
+	var (
+		baseClient *clientbase.APIBaseClient
+		err        error
+	)
+	for name, data := range value {
+		dataMap, ok := data.(map[string]interface{})
+		if !ok {
+			break
+		}
+		baseClient, err = c.ConfigBaseClient(schemaKey, dataMap, referenceMap, "")
+		if err != nil {
+			return err
+		}
+		if err := common.ReplaceGlobalReference(schema, dataMap, referenceMap, c.baseManagementClient); err != nil {
+			return err
+		}
+		clusterID := convert.ToString(dataMap["clusterId"])
+		baseClient, err = c.ConfigBaseClient(schemaKey, dataMap, referenceMap, clusterID)
+		if err != nil {
+			return err
+		}
+		dataMap["name"] = name
+		respObj := map[string]interface{}{}
+		// in here we have to make sure the same name won't be created twice
+		existingIDs := map[string]string{}
+		if err := baseClient.List(schemaKey, &types.ListOpts{}, &respObj); err != nil {
+			return err
+		}
+		if data, ok := respObj["data"]; ok {
+			if collections, ok := data.([]interface{}); ok {
+				for _, obj := range collections {
+					if objMap, ok := obj.(map[string]interface{}); ok {
+						createdName := common.GetValue(objMap, "name")
+						if createdName != "" {
+							existingIDs[createdName] = common.GetValue(objMap, "id")
+						}
+					}
+				}
+			}
+		}
+
+		id := ""
+		if v, ok := existingIDs[name]; ok {
+			id = v
+			existing := &types.Resource{}
+			if err := baseClient.ByID(schemaKey, id, existing); err != nil {
+				return err
+			}
+			if err := baseClient.Update(schemaKey, existing, dataMap, nil); err != nil {
+				return err
+			}
+			trackApplied(applied, schemaKey, id, existing.Links["remove"])
+		} else {
+			if err := baseClient.Create(schemaKey, dataMap, &respObj); err != nil && !strings.Contains(err.Error(), "already exist") {
+				return err
+			} else if err != nil && strings.Contains(err.Error(), "already exist") {
+				break
+			}
+			v, ok := respObj["id"]
+			if !ok {
+				return errors.Errorf("id is missing after creating %s obj", schemaKey)
+			}
+			id = v.(string)
+			links, _ := respObj["links"].(map[string]interface{})
+			trackApplied(applied, schemaKey, id, convert.ToString(links["remove"]))
+			trackApplied(created, schemaKey, id, convert.ToString(links["remove"]))
+		}
+	}
+	// fill in reference map name -> id
+	return common.FillInReferenceMap(baseClient, schemaKey, referenceMap, nil)
+}
+
+// configToMap returns the resources of the compose config by plural schema name
+func configToMap(config *compose.Config) (map[string]interface{}, error) {
 	rawData, err := json.Marshal(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	rawMap := map[string]interface{}{}
 	if err := json.Unmarshal(rawData, &rawMap); err != nil {
-		return err
+		return nil, err
 	}
 	delete(rawMap, "version")
-	allSchemas := getAllSchemas(clusterSchemas, managementSchemas, projectSchemas)
-	sortedSchemas := common.SortSchema(allSchemas)
+	return rawMap, nil
+}
 
+func copyReferenceMap(referenceMap map[string]map[string]string) map[string]map[string]string {
+	result := make(map[string]map[string]string, len(referenceMap))
+	for schemaType, ids := range referenceMap {
+		result[schemaType] = ids
+	}
+	return result
+}
+
+func newConfigClientManager(token string, port int, clusterSchemas, managementSchemas, projectSchemas map[string]types.Schema) (*configClientManager, error) {
 	baseClusterClient, err := clientbase.NewAPIClient(&clientbase.ClientOpts{
 		URL:      fmt.Sprintf(url, port) + "/cluster",
 		TokenKey: token,
 		Insecure: true,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	baseManagementClient, err := clientbase.NewAPIClient(&clientbase.ClientOpts{
 		URL:      fmt.Sprintf(url, port),
@@ -170,7 +418,7 @@ func up(token string, port int, config *compose.Config) error {
 		Insecure: true,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	baseProjectClient, err := clientbase.NewAPIClient(&clientbase.ClientOpts{
 		URL:      fmt.Sprintf(url, port) + "/project",
@@ -178,96 +426,17 @@ func up(token string, port int, config *compose.Config) error {
 		Insecure: true,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	baseURL := fmt.Sprintf(url, port)
-	configManager := configClientManager{
+	return &configClientManager{
 		clusterSchemas:       clusterSchemas,
 		managementSchemas:    managementSchemas,
 		projectSchemas:       projectSchemas,
 		baseClusterClient:    &baseClusterClient,
 		baseManagementClient: &baseManagementClient,
 		baseProjectClient:    &baseProjectClient,
-		baseURL:              baseURL,
-	}
-
-	for _, schemaKey := range sortedSchemas {
-		key := allSchemas[schemaKey].PluralName
-		v, ok := rawMap[key]
-		if !ok {
-			continue
-		}
-		value, ok := v.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		var baseClient *clientbase.APIBaseClient
-		for name, data := range value {
-			dataMap, ok := data.(map[string]interface{})
-			if !ok {
-				break
-			}
-			baseClient, err = configManager.ConfigBaseClient(schemaKey, dataMap, referenceMap, "")
-			if err != nil {
-				return err
-			}
-			if err := common.ReplaceGlobalReference(allSchemas[schemaKey], dataMap, referenceMap, &baseManagementClient); err != nil {
-				return err
-			}
-			clusterID := convert.ToString(dataMap["clusterId"])
-			baseClient, err = configManager.ConfigBaseClient(schemaKey, dataMap, referenceMap, clusterID)
-			if err != nil {
-				return err
-			}
-			dataMap["name"] = name
-			respObj := map[string]interface{}{}
-			// in here we have to make sure the same name won't be created twice
-			created := map[string]string{}
-			if err := baseClient.List(schemaKey, &types.ListOpts{}, &respObj); err != nil {
-				return err
-			}
-			if data, ok := respObj["data"]; ok {
-				if collections, ok := data.([]interface{}); ok {
-					for _, obj := range collections {
-						if objMap, ok := obj.(map[string]interface{}); ok {
-							createdName := common.GetValue(objMap, "name")
-							if createdName != "" {
-								created[createdName] = common.GetValue(objMap, "id")
-							}
-						}
-					}
-				}
-			}
-
-			id := ""
-			if v, ok := created[name]; ok {
-				id = v
-				existing := &types.Resource{}
-				if err := baseClient.ByID(schemaKey, id, existing); err != nil {
-					return err
-				}
-				if err := baseClient.Update(schemaKey, existing, dataMap, nil); err != nil {
-					return err
-				}
-			} else {
-				if err := baseClient.Create(schemaKey, dataMap, &respObj); err != nil && !strings.Contains(err.Error(), "already exist") {
-					return err
-				} else if err != nil && strings.Contains(err.Error(), "already exist") {
-					break
-				}
-				v, ok := respObj["id"]
-				if !ok {
-					return errors.Errorf("id is missing after creating %s obj", schemaKey)
-				}
-				id = v.(string)
-			}
-		}
-		// fill in reference map name -> id
-		if err := common.FillInReferenceMap(baseClient, schemaKey, referenceMap, nil); err != nil {
-			return err
-		}
-	}
-	return nil
+		baseURL:              fmt.Sprintf(url, port),
+	}, nil
 }
 
 type configClientManager struct {
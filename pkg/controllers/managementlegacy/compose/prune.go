@@ -0,0 +1,80 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/rancher/norman/clientbase"
+	"github.com/rancher/norman/types"
+	"github.com/sirupsen/logrus"
+)
+
+func checksum(rancherCompose string) string {
+	hash := sha256.Sum256([]byte(rancherCompose))
+	return hex.EncodeToString(hash[:])
+}
+
+func trackApplied(applied map[string]map[string]string, schemaType, id, link string) {
+	if link == "" {
+		// resources that can't be removed are not pruned
+		return
+	}
+	if applied[schemaType] == nil {
+		applied[schemaType] = map[string]string{}
+	}
+	applied[schemaType][id] = link
+}
+
+func mergeResources(previous, applied map[string]map[string]string) map[string]map[string]string {
+	result := map[string]map[string]string{}
+	for _, resources := range []map[string]map[string]string{previous, applied} {
+		for schemaType, links := range resources {
+			for id, link := range links {
+				trackApplied(result, schemaType, id, link)
+			}
+		}
+	}
+	return result
+}
+
+// ownedResources returns the resources the compose config created and still applies: the resources it created earlier
+// that were applied again, and the ones it just created. The resources that existed before are only updated by the
+// compose config, so they are never tracked and pruned.
+func ownedResources(previous, applied, created map[string]map[string]string) map[string]map[string]string {
+	return mergeResources(intersectResources(previous, applied), created)
+}
+
+// pruneResources removes the resources of previous that are not in current. Schemas are pruned in the reverse order of
+// sortedSchemas, so resources are removed before the resources they reference.
+func pruneResources(previous, current map[string]map[string]string, sortedSchemas []string, remove func(schemaType, id, link string) error) error {
+	for i := len(sortedSchemas) - 1; i >= 0; i-- {
+		schemaType := sortedSchemas[i]
+		var ids []string
+		for id := range previous[schemaType] {
+			if _, ok := current[schemaType][id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			logrus.Infof("[compose] pruning %s %s removed from the compose config", schemaType, id)
+			if err := remove(schemaType, id, previous[schemaType][id]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func removeResource(client *clientbase.APIBaseClient, schemaType, id, link string) error {
+	err := client.Delete(&types.Resource{
+		ID:    id,
+		Type:  schemaType,
+		Links: map[string]string{"remove": link},
+	})
+	if clientbase.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/controllers/managementlegacy/compose/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type removed struct {
+	schemaType, id, link string
+}
+
+func schemas() map[string]types.Schema {
+	return map[string]types.Schema{
+		"user": {
+			ResourceFields: map[string]types.Field{
+				"creatorId": {Type: "reference[user]"},
+			},
+		},
+		"globalRoleBinding": {
+			ResourceFields: map[string]types.Field{
+				"creatorId": {Type: "reference[user]"},
+				"userId":    {Type: "reference[user]"},
+			},
+		},
+	}
+}
+
+func TestPruneRemovedItems(t *testing.T) {
+	// the previous compose config created two users and a global role binding, the new one only has user-a
+	previous := map[string]map[string]string{
+		"user": {
+			"u-a": "https://localhost/v3/users/u-a",
+			"u-b": "https://localhost/v3/users/u-b",
+		},
+		"globalRoleBinding": {
+			"grb-b": "https://localhost/v3/globalRoleBindings/grb-b",
+		},
+	}
+	current := map[string]map[string]string{
+		"user": {
+			"u-a": "https://localhost/v3/users/u-a",
+		},
+	}
+
+	var result []removed
+	err := pruneResources(previous, current, common.SortSchema(schemas()), func(schemaType, id, link string) error {
+		result = append(result, removed{schemaType, id, link})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []removed{
+		{"globalRoleBinding", "grb-b", "https://localhost/v3/globalRoleBindings/grb-b"},
+		{"user", "u-b", "https://localhost/v3/users/u-b"},
+	}, result, "expected the binding to be removed before the user it references")
+}
+
+func TestPruneNothingRemoved(t *testing.T) {
+	resources := map[string]map[string]string{
+		"user": {"u-a": "https://localhost/v3/users/u-a"},
+	}
+
+	err := pruneResources(resources, resources, common.SortSchema(schemas()), func(schemaType, id, link string) error {
+		t.Errorf("unexpected removal of %s %s", schemaType, id)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestPruneStopsOnError(t *testing.T) {
+	previous := map[string]map[string]string{
+		"user":              {"u-b": "https://localhost/v3/users/u-b"},
+		"globalRoleBinding": {"grb-b": "https://localhost/v3/globalRoleBindings/grb-b"},
+	}
+
+	var calls int
+	err := pruneResources(previous, nil, common.SortSchema(schemas()), func(schemaType, id, link string) error {
+		calls++
+		return errors.New("forbidden")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls, "expected the user to be kept when its binding could not be removed")
+}
+
+func TestMergeResources(t *testing.T) {
+	previous := map[string]map[string]string{
+		"user": {"u-a": "link-a", "u-b": "link-b"},
+	}
+	applied := map[string]map[string]string{
+		"user":              {"u-a": "link-a"},
+		"globalRoleBinding": {"grb-a": "link-grb-a", "grb-c": ""},
+	}
+
+	assert.Equal(t, map[string]map[string]string{
+		"user":              {"u-a": "link-a", "u-b": "link-b"},
+		"globalRoleBinding": {"grb-a": "link-grb-a"},
+	}, mergeResources(previous, applied))
+}
+
+func TestOwnedResourcesSkipsExisting(t *testing.T) {
+	// u-a was created by an earlier apply, u-admin existed before and was only updated, u-c was just created
+	previous := map[string]map[string]string{
+		"user": {"u-a": "link-a", "u-b": "link-b"},
+	}
+	applied := map[string]map[string]string{
+		"user": {"u-a": "link-a", "u-admin": "link-admin", "u-c": "link-c"},
+	}
+	created := map[string]map[string]string{
+		"user": {"u-c": "link-c"},
+	}
+
+	assert.Equal(t, map[string]map[string]string{
+		"user": {"u-a": "link-a", "u-c": "link-c"},
+	}, ownedResources(previous, applied, created), "expected only the resources created by the compose config to be pruned later")
+}
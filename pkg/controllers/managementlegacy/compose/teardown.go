@@ -0,0 +1,141 @@
+package compose
+
+import (
+	"sort"
+
+	"github.com/rancher/norman/types/slice"
+	"github.com/rancher/rancher/pkg/controllers/managementlegacy/compose/common"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ensureTeardownFinalizer adds the teardown finalizer to a compose config with teardownOnDelete set, and removes it
+// once teardownOnDelete is unset
+func (l Lifecycle) ensureTeardownFinalizer(obj *v3.ComposeConfig) (*v3.ComposeConfig, error) {
+	hasFinalizer := slice.ContainsString(obj.Finalizers, teardownFinalizer)
+	if obj.Spec.TeardownOnDelete == hasFinalizer {
+		return obj, nil
+	}
+	obj = obj.DeepCopy()
+	if obj.Spec.TeardownOnDelete {
+		obj.Finalizers = append(obj.Finalizers, teardownFinalizer)
+	} else {
+		obj.Finalizers = removeFinalizer(obj.Finalizers, teardownFinalizer)
+		obj.Status.Created = nil
+	}
+	return l.ComposeClient.Update(obj)
+}
+
+// teardown deletes the resources created by a deleted compose config before removing its finalizer. The resources
+// that could not be deleted are kept on the status, so that they are deleted when the teardown is retried.
+func (l Lifecycle) teardown(obj *v3.ComposeConfig) (runtime.Object, error) {
+	if !slice.ContainsString(obj.Finalizers, teardownFinalizer) {
+		return obj, nil
+	}
+
+	obj = obj.DeepCopy()
+	if len(obj.Status.Created) > 0 {
+		err := l.withToken(obj, func(token string) error {
+			remaining, err := l.removeCreated(token, obj.Status.Created)
+			obj.Status.Created = remaining
+			return err
+		})
+		if err != nil {
+			if newObj, updateErr := l.ComposeClient.Update(obj); updateErr == nil {
+				return newObj, err
+			}
+			return obj, err
+		}
+	}
+
+	obj.Finalizers = removeFinalizer(obj.Finalizers, teardownFinalizer)
+	return l.ComposeClient.Update(obj)
+}
+
+func (l Lifecycle) removeCreated(token string, created map[string]map[string]string) (map[string]map[string]string, error) {
+	port := l.HTTPSPortGetter.GetHTTPSPort()
+	clusterSchemas, managementSchemas, projectSchemas, err := GetSchemas(token, port)
+	if err != nil {
+		return created, err
+	}
+	clients, err := newConfigClientManager(token, port, clusterSchemas, managementSchemas, projectSchemas)
+	if err != nil {
+		return created, err
+	}
+
+	sortedSchemas := common.SortSchema(getAllSchemas(clusterSchemas, managementSchemas, projectSchemas))
+	return teardownResources(created, sortedSchemas, func(schemaType, id, link string) error {
+		return removeResource(clients.baseManagementClient, schemaType, id, link)
+	})
+}
+
+// teardownResources removes all the resources, in the reverse order of sortedSchemas like pruneResources, and returns
+// the resources that are left when removing one of them fails.
+func teardownResources(resources map[string]map[string]string, sortedSchemas []string, remove func(schemaType, id, link string) error) (map[string]map[string]string, error) {
+	remaining := mergeResources(resources, nil)
+
+	known := map[string]bool{}
+	for i := len(sortedSchemas) - 1; i >= 0; i-- {
+		schemaType := sortedSchemas[i]
+		known[schemaType] = true
+		if err := teardownSchema(remaining, schemaType, remove); err != nil {
+			return remaining, err
+		}
+	}
+
+	// resources of schemas that are not sorted anymore don't reference any other resource of the compose config
+	var unknown []string
+	for schemaType := range remaining {
+		if !known[schemaType] {
+			unknown = append(unknown, schemaType)
+		}
+	}
+	sort.Strings(unknown)
+	for _, schemaType := range unknown {
+		if err := teardownSchema(remaining, schemaType, remove); err != nil {
+			return remaining, err
+		}
+	}
+	return nil, nil
+}
+
+func teardownSchema(remaining map[string]map[string]string, schemaType string, remove func(schemaType, id, link string) error) error {
+	var ids []string
+	for id := range remaining[schemaType] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		logrus.Infof("[compose] tearing down %s %s created by the compose config", schemaType, id)
+		if err := remove(schemaType, id, remaining[schemaType][id]); err != nil {
+			return err
+		}
+		delete(remaining[schemaType], id)
+	}
+	delete(remaining, schemaType)
+	return nil
+}
+
+// intersectResources returns the resources of resources that are also in other
+func intersectResources(resources, other map[string]map[string]string) map[string]map[string]string {
+	result := map[string]map[string]string{}
+	for schemaType, links := range resources {
+		for id, link := range links {
+			if _, ok := other[schemaType][id]; ok {
+				trackApplied(result, schemaType, id, link)
+			}
+		}
+	}
+	return result
+}
+
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	var result []string
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
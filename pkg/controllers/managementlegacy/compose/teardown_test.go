@@ -0,0 +1,104 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/managementlegacy/compose/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackCreated(t *testing.T) {
+	obj := &v32.ComposeConfig{}
+	obj.Spec.TeardownOnDelete = true
+	obj.Status.Created = map[string]map[string]string{
+		"user": {"u-b": "link-b"},
+	}
+
+	// u-a was created by this apply, u-existing already existed and was only updated
+	applied := map[string]map[string]string{
+		"user": {"u-a": "link-a", "u-existing": "link-existing"},
+	}
+	created := map[string]map[string]string{
+		"user": {"u-a": "link-a"},
+	}
+
+	Lifecycle{}.trackCreated(obj, created, nil)
+	assert.Equal(t, map[string]map[string]string{
+		"user": {"u-a": "link-a", "u-b": "link-b"},
+	}, obj.Status.Created, "expected created resources to be added to the previous ones")
+
+	Lifecycle{}.trackCreated(obj, created, applied)
+	assert.Equal(t, map[string]map[string]string{
+		"user": {"u-a": "link-a"},
+	}, obj.Status.Created, "expected the pruned resource to be dropped and the updated resource to never be tracked")
+
+	obj.Spec.TeardownOnDelete = false
+	Lifecycle{}.trackCreated(obj, created, applied)
+	assert.Nil(t, obj.Status.Created)
+}
+
+func TestTeardownReverseOrder(t *testing.T) {
+	created := map[string]map[string]string{
+		"user": {
+			"u-a": "https://localhost/v3/users/u-a",
+			"u-b": "https://localhost/v3/users/u-b",
+		},
+		"globalRoleBinding": {
+			"grb-a": "https://localhost/v3/globalRoleBindings/grb-a",
+		},
+	}
+
+	var result []removed
+	remaining, err := teardownResources(created, common.SortSchema(schemas()), func(schemaType, id, link string) error {
+		result = append(result, removed{schemaType, id, link})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+	assert.Equal(t, []removed{
+		{"globalRoleBinding", "grb-a", "https://localhost/v3/globalRoleBindings/grb-a"},
+		{"user", "u-a", "https://localhost/v3/users/u-a"},
+		{"user", "u-b", "https://localhost/v3/users/u-b"},
+	}, result, "expected the binding to be removed before the users")
+	assert.Len(t, created["user"], 2, "expected the status of the compose config to be left as is")
+}
+
+func TestTeardownKeepsRemainingOnError(t *testing.T) {
+	created := map[string]map[string]string{
+		"user":              {"u-a": "link-a"},
+		"globalRoleBinding": {"grb-a": "link-grb-a", "grb-b": "link-grb-b"},
+	}
+
+	remaining, err := teardownResources(created, common.SortSchema(schemas()), func(schemaType, id, link string) error {
+		if id == "grb-b" {
+			return errors.New("forbidden")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"user":              {"u-a": "link-a"},
+		"globalRoleBinding": {"grb-b": "link-grb-b"},
+	}, remaining, "expected the resources that were not removed to be retried")
+}
+
+func TestTeardownUnknownSchema(t *testing.T) {
+	created := map[string]map[string]string{
+		"user":    {"u-a": "link-a"},
+		"catalog": {"c-a": "link-c-a"},
+	}
+
+	var result []removed
+	remaining, err := teardownResources(created, common.SortSchema(schemas()), func(schemaType, id, link string) error {
+		result = append(result, removed{schemaType, id, link})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+	assert.Equal(t, []removed{
+		{"user", "u-a", "link-a"},
+		{"catalog", "c-a", "link-c-a"},
+	}, result)
+}
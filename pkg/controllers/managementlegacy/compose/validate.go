@@ -0,0 +1,232 @@
+package compose
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/definition"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// Validate validates the compose config against the schemas of the API and reports the errors on the status of the
+// compose config, nothing is created. An error is only returned if the schemas can't be fetched.
+func (l Lifecycle) Validate(obj *v3.ComposeConfig) (*v3.ComposeConfig, error) {
+	err := l.withToken(obj, func(token string) error {
+		clusterSchemas, managementSchemas, projectSchemas, err := GetSchemas(token, l.HTTPSPortGetter.GetHTTPSPort())
+		if err != nil {
+			return err
+		}
+		setValidationErrors(obj, validateConfig(obj.Spec.RancherCompose, clusterSchemas, managementSchemas, projectSchemas))
+		return nil
+	})
+	return obj, err
+}
+
+func setValidationErrors(obj *v3.ComposeConfig, errs []string) {
+	obj.Status.ValidatedChecksum = checksum(obj.Spec.RancherCompose)
+	obj.Status.ValidationErrors = errs
+	if len(errs) == 0 {
+		v32.ComposeConditionValidated.True(obj)
+		v32.ComposeConditionValidated.Message(obj, "")
+		return
+	}
+	v32.ComposeConditionValidated.False(obj)
+	v32.ComposeConditionValidated.Message(obj, strings.Join(errs, "; "))
+}
+
+// validateConfig parses the compose config and validates every resource against the schema of its type
+func validateConfig(rancherCompose string, clusterSchemas, managementSchemas, projectSchemas map[string]types.Schema) []string {
+	// the raw config is validated as parsing it in a compose.Config drops unknown fields
+	rawMap := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(rancherCompose), &rawMap); err != nil {
+		return []string{err.Error()}
+	}
+	delete(rawMap, "version")
+
+	v := validator{
+		schemas:         getAllSchemas(clusterSchemas, managementSchemas, projectSchemas),
+		embeddedSchemas: map[string]types.Schema{},
+	}
+	for _, schemas := range []map[string]types.Schema{projectSchemas, clusterSchemas, managementSchemas} {
+		for schemaType, schema := range schemas {
+			v.embeddedSchemas[schemaType] = schema
+		}
+	}
+	schemasByPluralName := map[string]string{}
+	for schemaType, schema := range v.schemas {
+		schemasByPluralName[schema.PluralName] = schemaType
+	}
+
+	for _, pluralName := range sortedKeys(rawMap) {
+		schemaType, ok := schemasByPluralName[pluralName]
+		if !ok {
+			v.errorf(pluralName, "unknown resource type")
+			continue
+		}
+		resources, ok := rawMap[pluralName].(map[string]interface{})
+		if !ok {
+			v.errorf(pluralName, "expected a map of %s by name", pluralName)
+			continue
+		}
+		for _, name := range sortedKeys(resources) {
+			path := pluralName + "." + name
+			data, ok := resources[name].(map[string]interface{})
+			if !ok {
+				v.errorf(path, "expected a %s", schemaType)
+				continue
+			}
+			v.validateResource(path, v.schemas[schemaType], data)
+		}
+	}
+	return v.errs
+}
+
+var stringTypes = map[string]bool{
+	"string":             true,
+	"password":           true,
+	"date":               true,
+	"dnsLabel":           true,
+	"dnsLabelRestricted": true,
+	"hostname":           true,
+	"base64":             true,
+}
+
+type validator struct {
+	// schemas are the schemas of the resources that can be created by a compose config
+	schemas map[string]types.Schema
+	// embeddedSchemas are all the schemas, used to validate the fields of embedded types
+	embeddedSchemas map[string]types.Schema
+	errs            []string
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) validateResource(path string, schema types.Schema, data map[string]interface{}) {
+	for _, fieldName := range sortedKeys(data) {
+		field, ok := schema.ResourceFields[fieldName]
+		if !ok {
+			v.errorf(path, "unknown field %s", fieldName)
+			continue
+		}
+		if !field.Create {
+			v.errorf(path, "field %s can't be set on create", fieldName)
+			continue
+		}
+		v.validateValue(path+"."+fieldName, field.Type, field, data[fieldName])
+	}
+
+	for _, fieldName := range sortedFieldNames(schema.ResourceFields) {
+		field := schema.ResourceFields[fieldName]
+		// the name is set from the key of the resource
+		if fieldName == "name" || !field.Required || !field.Create || field.Default != nil {
+			continue
+		}
+		if _, ok := data[fieldName]; !ok {
+			v.errorf(path, "missing required field %s", fieldName)
+		}
+	}
+}
+
+func (v *validator) validateValue(path, fieldType string, field types.Field, value interface{}) {
+	if value == nil {
+		if !field.Nullable && field.Required {
+			v.errorf(path, "can't be null")
+		}
+		return
+	}
+
+	switch {
+	case definition.IsArrayType(fieldType):
+		values, ok := value.([]interface{})
+		if !ok {
+			v.errorf(path, "expected an array")
+			return
+		}
+		for i, value := range values {
+			v.validateValue(fmt.Sprintf("%s[%d]", path, i), definition.SubType(fieldType), types.Field{}, value)
+		}
+	case definition.IsMapType(fieldType):
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			v.errorf(path, "expected a map")
+			return
+		}
+		for _, key := range sortedKeys(values) {
+			v.validateValue(path+"."+key, definition.SubType(fieldType), types.Field{}, values[key])
+		}
+	case definition.IsReferenceType(fieldType):
+		// references are given by name and replaced by IDs when the resources are created
+		if _, ok := value.(string); !ok {
+			v.errorf(path, "expected the name of a %s", definition.SubType(fieldType))
+		}
+	case stringTypes[fieldType]:
+		// scalars are converted to strings when the compose config is parsed
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			v.errorf(path, "expected a string")
+		}
+	case fieldType == "int":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			v.errorf(path, "expected an integer")
+		}
+	case fieldType == "float":
+		if _, ok := value.(float64); !ok {
+			v.errorf(path, "expected a number")
+		}
+	case fieldType == "boolean":
+		if _, ok := value.(bool); !ok {
+			v.errorf(path, "expected a boolean")
+		}
+	case fieldType == "enum":
+		option, ok := value.(string)
+		if !ok || (len(field.Options) > 0 && !contains(field.Options, option)) {
+			v.errorf(path, "expected one of %s", strings.Join(field.Options, ", "))
+		}
+	default:
+		// other types such as json are not validated
+		schema, ok := v.embeddedSchemas[fieldType]
+		if !ok {
+			return
+		}
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			v.errorf(path, "expected a %s", fieldType)
+			return
+		}
+		v.validateResource(path, schema, data)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFieldNames(fields map[string]types.Field) []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
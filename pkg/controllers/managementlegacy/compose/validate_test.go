@@ -0,0 +1,155 @@
+package compose
+
+import (
+	"testing"
+
+	"github.com/rancher/norman/types"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func managementSchemas() map[string]types.Schema {
+	return map[string]types.Schema{
+		"user": {
+			PluralName: "users",
+			ResourceFields: map[string]types.Field{
+				"creatorId":          {Type: "reference[user]", Create: true},
+				"name":               {Type: "string", Create: true, Required: true},
+				"username":           {Type: "string", Create: true, Required: true},
+				"password":           {Type: "password", Create: true},
+				"mustChangePassword": {Type: "boolean", Create: true},
+				"state":              {Type: "string"},
+			},
+		},
+		"roleTemplate": {
+			PluralName: "roleTemplates",
+			ResourceFields: map[string]types.Field{
+				"creatorId": {Type: "reference[user]", Create: true},
+				"context":   {Type: "enum", Create: true, Options: []string{"project", "cluster"}},
+				"rules":     {Type: "array[policyRule]", Create: true},
+			},
+		},
+		"project": {
+			PluralName: "projects",
+			ResourceFields: map[string]types.Field{
+				"creatorId":     {Type: "reference[user]", Create: true},
+				"clusterId":     {Type: "reference[cluster]", Create: true, Required: true},
+				"resourceQuota": {Type: "projectResourceQuota", Create: true, Nullable: true},
+			},
+		},
+		"policyRule": {
+			ResourceFields: map[string]types.Field{
+				"verbs":     {Type: "array[string]", Create: true},
+				"resources": {Type: "array[string]", Create: true},
+			},
+		},
+		"projectResourceQuota": {
+			ResourceFields: map[string]types.Field{
+				"limit": {Type: "resourceQuotaLimit", Create: true},
+			},
+		},
+		"resourceQuotaLimit": {
+			ResourceFields: map[string]types.Field{
+				"pods": {Type: "string", Create: true},
+			},
+		},
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		rancherCompose string
+		expected       []string
+	}{
+		{
+			name: "valid config",
+			rancherCompose: `
+version: v3
+users:
+  admin2:
+    username: admin2
+    password: secret
+    mustChangePassword: true
+roleTemplates:
+  viewer:
+    context: project
+    rules:
+    - verbs: [get, list]
+      resources: [pods]
+projects:
+  p1:
+    clusterId: local
+    resourceQuota:
+      limit:
+        pods: 10
+`,
+		},
+		{
+			name: "schema-invalid config",
+			rancherCompose: `
+users:
+  admin2:
+    password: secret
+    mustChangePassword: "yes"
+    state: active
+    email: admin@example.com
+roleTemplates:
+  viewer:
+    context: global
+    rules:
+    - verbs: get
+projects:
+  p1:
+    clusterId: [local]
+    resourceQuota:
+      limit:
+        pods: 10
+        services: 10
+clusterAlerts:
+  alert: {}
+`,
+			expected: []string{
+				"clusterAlerts: unknown resource type",
+				"projects.p1.clusterId: expected the name of a cluster",
+				"projects.p1.resourceQuota.limit: unknown field services",
+				"roleTemplates.viewer.context: expected one of project, cluster",
+				"roleTemplates.viewer.rules[0].verbs: expected an array",
+				"users.admin2: unknown field email",
+				"users.admin2.mustChangePassword: expected a boolean",
+				"users.admin2: field state can't be set on create",
+				"users.admin2: missing required field username",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateConfig(tt.rancherCompose, nil, managementSchemas(), nil)
+			assert.Equal(t, tt.expected, errs)
+		})
+	}
+}
+
+func TestValidateConfigUnparsable(t *testing.T) {
+	errs := validateConfig("users: [admin2", nil, managementSchemas(), nil)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0], "did not find expected")
+	}
+}
+
+func TestSetValidationErrors(t *testing.T) {
+	obj := &v3.ComposeConfig{Spec: v32.ComposeSpec{RancherCompose: "users: {}"}}
+
+	setValidationErrors(obj, []string{"users.a: unknown field email", "users.a: missing required field username"})
+	assert.True(t, v32.ComposeConditionValidated.IsFalse(obj))
+	assert.Equal(t, "users.a: unknown field email; users.a: missing required field username", v32.ComposeConditionValidated.GetMessage(obj))
+	assert.Equal(t, checksum("users: {}"), obj.Status.ValidatedChecksum)
+	assert.False(t, v32.ComposeConditionExecuted.IsTrue(obj), "expected nothing to be executed")
+
+	setValidationErrors(obj, nil)
+	assert.True(t, v32.ComposeConditionValidated.IsTrue(obj))
+	assert.Empty(t, v32.ComposeConditionValidated.GetMessage(obj))
+	assert.Empty(t, obj.Status.ValidationErrors)
+}
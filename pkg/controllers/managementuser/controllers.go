@@ -13,6 +13,7 @@
 	"github.com/rancher/rancher/pkg/controllers/managementuser/pspdelete"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/rbac"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/rbac/podsecuritypolicy"
+	"github.com/rancher/rancher/pkg/controllers/managementuser/registrysecret"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/resourcequota"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/secret"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/settings"
@@ -37,6 +38,7 @@ func Register(ctx context.Context, cluster *config.UserContext, clusterRec *mana
 	podsecuritypolicy.RegisterServiceAccount(ctx, cluster)
 	podsecuritypolicy.RegisterTemplate(ctx, cluster)
 	secret.Register(ctx, cluster)
+	registrysecret.Register(ctx, cluster)
 	resourcequota.Register(ctx, cluster)
 	certsexpiration.Register(ctx, cluster)
 	windows.Register(ctx, clusterRec, cluster)
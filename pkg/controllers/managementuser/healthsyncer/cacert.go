@@ -0,0 +1,77 @@
+package healthsyncer
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/cert"
+)
+
+const (
+	caCertMismatchReason = "CACertMismatch"
+	caCertRotatedReason  = "CACertRotated"
+)
+
+// isCACertMismatch returns true if err is caused by the cluster presenting a certificate that isn't signed by the CA
+// cert of the cluster, or by the CA cert of the cluster having expired
+func isCACertMismatch(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) {
+		return true
+	}
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &invalid) && invalid.Reason == x509.Expired
+}
+
+// agentCACert returns the CA cert reported by the cluster agent if it can replace the CA cert of the cluster
+func agentCACert(cluster *v3.Cluster) string {
+	caCert := cluster.Annotations[v32.ClusterAgentCACertAnnotation]
+	if caCert == "" || caCert == cluster.Status.CACert {
+		return ""
+	}
+	caBytes, err := base64.StdEncoding.DecodeString(caCert)
+	if err != nil {
+		logrus.Errorf("[healthsyncer] cluster [%s] agent reported an invalid CA cert: %v", cluster.Name, err)
+		return ""
+	}
+	if _, err := cert.ParseCertsPEM(caBytes); err != nil {
+		logrus.Errorf("[healthsyncer] cluster [%s] agent reported an invalid CA cert: %v", cluster.Name, err)
+		return ""
+	}
+	return caCert
+}
+
+// updateCACertMismatch sets the CACertMismatch condition of the cluster from the error of the health check. If the CA
+// cert doesn't match and updating it is enabled, the CA cert is replaced with the one reported by the cluster agent and
+// true is returned.
+func updateCACertMismatch(cluster *v3.Cluster, healthErr error) bool {
+	if !isCACertMismatch(healthErr) {
+		if v32.ClusterConditionCACertMismatch.IsTrue(cluster) {
+			v32.ClusterConditionCACertMismatch.False(cluster)
+			v32.ClusterConditionCACertMismatch.Message(cluster, "")
+		}
+		return false
+	}
+
+	if settings.ClusterCACertAutoUpdate.Get() == "true" {
+		if caCert := agentCACert(cluster); caCert != "" {
+			logrus.Infof("[healthsyncer] CA cert of cluster [%s] was rotated, updating it with the CA cert reported by the cluster agent", cluster.Name)
+			cluster.Status.CACert = caCert
+			delete(cluster.Annotations, v32.ClusterAgentCACertAnnotation)
+			v32.ClusterConditionCACertMismatch.False(cluster)
+			v32.ClusterConditionCACertMismatch.Message(cluster, "")
+			return true
+		}
+	}
+
+	v32.ClusterConditionCACertMismatch.True(cluster)
+	v32.ClusterConditionCACertMismatch.Reason(cluster, caCertMismatchReason)
+	v32.ClusterConditionCACertMismatch.Message(cluster, "the cluster presents a certificate that is not signed by the CA cert "+
+		"of the cluster, the CA of the cluster may have been rotated or expired: "+healthErr.Error())
+	return false
+}
@@ -0,0 +1,196 @@
+package healthsyncer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, notAfter time.Time) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kube-ca"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// serve starts a TLS server presenting a certificate signed by the CA
+func (ca *testCA) serve(t *testing.T) *httptest.Server {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "kube-apiserver"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"127.0.0.1"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// get connects to the server trusting only the given CA, verifying the chain but not the server name like the
+// clients of RKE clusters do
+func get(server *httptest.Server, ca *testCA) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			_, err = cert.Verify(x509.VerifyOptions{Roots: roots})
+			return err
+		},
+	}}}
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func TestIsCACertMismatch(t *testing.T) {
+	oldCA := newTestCA(t, time.Now().Add(24*time.Hour))
+	newCA := newTestCA(t, time.Now().Add(24*time.Hour))
+	expiredCA := newTestCA(t, time.Now().Add(-24*time.Hour))
+
+	require.NoError(t, get(newCA.serve(t), newCA))
+
+	err := get(newCA.serve(t), oldCA)
+	require.Error(t, err)
+	assert.True(t, isCACertMismatch(err), "expected a rotated CA to be detected, got %v", err)
+
+	err = get(expiredCA.serve(t), expiredCA)
+	require.Error(t, err)
+	assert.True(t, isCACertMismatch(err), "expected an expired CA to be detected, got %v", err)
+
+	assert.False(t, isCACertMismatch(errors.New("connection refused")))
+	assert.False(t, isCACertMismatch(nil))
+}
+
+func newCluster(caCert, agentCACert []byte) *v3.Cluster {
+	cluster := &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-1", Annotations: map[string]string{}},
+	}
+	cluster.Status.CACert = base64.StdEncoding.EncodeToString(caCert)
+	if agentCACert != nil {
+		cluster.Annotations[v32.ClusterAgentCACertAnnotation] = base64.StdEncoding.EncodeToString(agentCACert)
+	}
+	return cluster
+}
+
+func setCACertAutoUpdate(t *testing.T, value string) {
+	previous := settings.ClusterCACertAutoUpdate.Get()
+	require.NoError(t, settings.ClusterCACertAutoUpdate.Set(value))
+	t.Cleanup(func() {
+		settings.ClusterCACertAutoUpdate.Set(previous)
+	})
+}
+
+func TestUpdateCACertMismatch(t *testing.T) {
+	oldCA := newTestCA(t, time.Now().Add(24*time.Hour))
+	newCA := newTestCA(t, time.Now().Add(24*time.Hour))
+	healthErr := get(newCA.serve(t), oldCA)
+	require.Error(t, healthErr)
+
+	t.Run("disabled", func(t *testing.T) {
+		setCACertAutoUpdate(t, "false")
+		cluster := newCluster(oldCA.pem, newCA.pem)
+
+		assert.False(t, updateCACertMismatch(cluster, healthErr))
+		assert.True(t, v32.ClusterConditionCACertMismatch.IsTrue(cluster))
+		assert.Equal(t, caCertMismatchReason, v32.ClusterConditionCACertMismatch.GetReason(cluster))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(oldCA.pem), cluster.Status.CACert)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		setCACertAutoUpdate(t, "true")
+		cluster := newCluster(oldCA.pem, newCA.pem)
+
+		assert.True(t, updateCACertMismatch(cluster, healthErr))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(newCA.pem), cluster.Status.CACert)
+		assert.NotContains(t, cluster.Annotations, v32.ClusterAgentCACertAnnotation)
+		assert.False(t, v32.ClusterConditionCACertMismatch.IsTrue(cluster))
+	})
+
+	t.Run("enabled without an agent CA cert", func(t *testing.T) {
+		setCACertAutoUpdate(t, "true")
+		cluster := newCluster(oldCA.pem, nil)
+
+		assert.False(t, updateCACertMismatch(cluster, healthErr))
+		assert.True(t, v32.ClusterConditionCACertMismatch.IsTrue(cluster))
+	})
+
+	t.Run("enabled with an invalid agent CA cert", func(t *testing.T) {
+		setCACertAutoUpdate(t, "true")
+		cluster := newCluster(oldCA.pem, []byte("not a certificate"))
+
+		assert.False(t, updateCACertMismatch(cluster, healthErr))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(oldCA.pem), cluster.Status.CACert)
+	})
+
+	t.Run("recovered", func(t *testing.T) {
+		cluster := newCluster(newCA.pem, nil)
+		v32.ClusterConditionCACertMismatch.True(cluster)
+
+		assert.False(t, updateCACertMismatch(cluster, nil))
+		assert.True(t, v32.ClusterConditionCACertMismatch.IsFalse(cluster))
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		cluster := newCluster(oldCA.pem, newCA.pem)
+
+		assert.False(t, updateCACertMismatch(cluster, errors.New("connection refused")))
+		assert.Empty(t, cluster.Status.Conditions, "expected the condition to only be added on a mismatch")
+	})
+}
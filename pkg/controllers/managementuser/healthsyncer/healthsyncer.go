@@ -10,6 +10,7 @@
 	"github.com/rancher/norman/condition"
 	"github.com/rancher/norman/types/slice"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/eventrecorder"
 	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -20,6 +21,7 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -44,6 +46,7 @@ type HealthSyncer struct {
 	componentStatuses corev1.ComponentStatusInterface
 	namespaces        corev1.NamespaceInterface
 	k8s               kubernetes.Interface
+	eventRecorder     record.EventRecorder
 }
 
 func Register(ctx context.Context, workload *config.UserContext) {
@@ -55,6 +58,7 @@ func Register(ctx context.Context, workload *config.UserContext) {
 		componentStatuses: workload.Core.ComponentStatuses(""),
 		namespaces:        workload.Core.Namespaces(""),
 		k8s:               workload.K8sClient,
+		eventRecorder:     eventrecorder.New(ctx, workload.Management.K8sClient, "cluster-health-syncer"),
 	}
 
 	go h.syncHealth(ctx, syncInterval)
@@ -77,6 +81,9 @@ func (h *HealthSyncer) getComponentStatus(cluster *v3.Cluster) error {
 	// As of k8s v1.14, kubeapi returns a successful ComponentStatuses response even if etcd is not available.
 	// To work around this, now we try to get a namespace from the API, even if not found, it means the API is up.
 	if _, err := h.k8s.CoreV1().Namespaces().Get(ctx, "kube-system", metav1.GetOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		if isCACertMismatch(err) {
+			return err
+		}
 		return condition.Error("ComponentStatsFetchingFailure", errors.Wrap(err, "Failed to communicate with API server during namespace check"))
 	}
 
@@ -118,7 +125,7 @@ func (h *HealthSyncer) updateClusterHealth() error {
 	newObj, err := v32.ClusterConditionReady.Do(cluster, func() (runtime.Object, error) {
 		for i := 0; ; i++ {
 			err := h.getComponentStatus(cluster)
-			if err == nil || i > 1 {
+			if err == nil || i > 1 || isCACertMismatch(err) {
 				return cluster, errors.Wrap(err, "cluster health check failed")
 			}
 			select {
@@ -133,12 +140,17 @@ func (h *HealthSyncer) updateClusterHealth() error {
 		v32.ClusterConditionWaiting.True(newObj)
 		v32.ClusterConditionWaiting.Message(newObj, "")
 	}
+	caCertRotated := updateCACertMismatch(newObj.(*v3.Cluster), err)
 
 	if !reflect.DeepEqual(oldCluster, newObj) {
 		if _, err := h.clusters.Update(newObj.(*v3.Cluster)); err != nil {
 			return errors.Wrapf(err, "[updateClusterHealth] Failed to update cluster [%s]", cluster.Name)
 		}
 	}
+	if caCertRotated {
+		h.eventRecorder.Event(oldCluster, v1.EventTypeNormal, caCertRotatedReason,
+			"CA cert of the cluster was rotated, it was updated with the CA cert reported by the cluster agent")
+	}
 
 	// Purposefully not return error.  This is so when the cluster goes unavailable we don't just keep failing
 	// which will essentially keep the controller alive forever, instead of shutting down.
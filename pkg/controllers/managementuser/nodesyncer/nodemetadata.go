@@ -0,0 +1,87 @@
+package nodesyncer
+
+import (
+	"sort"
+	"strings"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const metadataDriftReason = "ManagedMetadataDrift"
+
+// nodeMetadataPolicy returns the node metadata policy of the cluster of the node, nil when the cluster doesn't limit
+// the labels and taints reconciled by Rancher
+func (m *nodesSyncer) nodeMetadataPolicy(obj *v3.Node) (*v32.NodeMetadataPolicy, error) {
+	cluster, err := m.clusterLister.Get("", obj.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return cluster.Spec.NodeMetadataPolicy, nil
+}
+
+func hasPrefixPolicy(prefixes []string) canChangeValuePolicy {
+	return func(key string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// labelPolicy returns the plan labels to reconcile on the node and the policy for changing the value of the labels
+// already on the node. With managed label prefixes, only the plan labels with those prefixes are reconciled and their
+// values are always enforced, other labels are left alone.
+func labelPolicy(policy *v32.NodeMetadataPolicy, planLabels map[string]string) (map[string]string, canChangeValuePolicy) {
+	if policy == nil || len(policy.ManagedLabelPrefixes) == 0 {
+		return planLabels, onlyKubeLabels
+	}
+
+	managed := hasPrefixPolicy(policy.ManagedLabelPrefixes)
+	result := map[string]string{}
+	for k, v := range planLabels {
+		if managed(k) {
+			result[k] = v
+		}
+	}
+	return result, managed
+}
+
+// driftedKeys returns the sorted keys of the plan whose value on the node differs from the plan, ignoring the keys
+// being updated through the API
+func driftedKeys(currentState map[string]string, planValues map[string]string, delta v32.MapDelta) []string {
+	var keys []string
+	for k, v := range planValues {
+		if _, ok := delta.Add[k]; ok {
+			continue
+		}
+		if _, ok := delta.Delete[k]; ok {
+			continue
+		}
+		if currentValue, ok := currentState[k]; !ok || currentValue != v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// managedTaintsToDelete filters the taints to delete from the node down to the taints with managed key prefixes, so
+// that the taints added by other tools are preserved
+func managedTaintsToDelete(policy *v32.NodeMetadataPolicy, toDel map[int]corev1.Taint) map[int]corev1.Taint {
+	if policy == nil || len(policy.ManagedTaintPrefixes) == 0 {
+		return toDel
+	}
+
+	managed := hasPrefixPolicy(policy.ManagedTaintPrefixes)
+	result := map[int]corev1.Taint{}
+	for index, taint := range toDel {
+		if managed(taint.Key) {
+			result[index] = taint
+		}
+	}
+	return result
+}
@@ -11,6 +11,7 @@
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	kd "github.com/rancher/rancher/pkg/controllers/management/kontainerdrivermetadata"
 	"github.com/rancher/rancher/pkg/controllers/managementlegacy/compose/common"
+	"github.com/rancher/rancher/pkg/eventrecorder"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/librke"
@@ -25,6 +26,7 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -53,6 +55,7 @@ type nodesSyncer struct {
 	serviceOptions       v3.RkeK8sServiceOptionInterface
 	sysImagesLister      v3.RkeK8sSystemImageLister
 	sysImages            v3.RkeK8sSystemImageInterface
+	eventRecorder        record.EventRecorder
 }
 
 type nodeDrain struct {
@@ -84,6 +87,7 @@ func Register(ctx context.Context, cluster *config.UserContext, kubeConfigGetter
 		serviceOptions:       cluster.Management.Management.RkeK8sServiceOptions(""),
 		sysImagesLister:      cluster.Management.Management.RkeK8sSystemImages("").Controller().Lister(),
 		sysImages:            cluster.Management.Management.RkeK8sSystemImages(""),
+		eventRecorder:        eventrecorder.New(ctx, cluster.Management.K8sClient, "node-syncer"),
 	}
 
 	n := &nodeSyncer{
@@ -188,8 +192,16 @@ func (m *nodesSyncer) updateNodeAndNode(node *corev1.Node, obj *v3.Node) (*corev
 	return node, obj, nil
 }
 
-func (m *nodesSyncer) updateLabels(node *corev1.Node, obj *v3.Node, nodePlan rketypes.RKEConfigNodePlan) (*corev1.Node, *v3.Node, error) {
-	finalMap, changed := computeDelta(node.Labels, nodePlan.Labels, obj.Spec.MetadataUpdate.Labels, onlyKubeLabels)
+func (m *nodesSyncer) updateLabels(node *corev1.Node, obj *v3.Node, nodePlan rketypes.RKEConfigNodePlan, policy *v32.NodeMetadataPolicy) (*corev1.Node, *v3.Node, error) {
+	planLabels, canChangeValue := labelPolicy(policy, nodePlan.Labels)
+	if policy != nil && len(policy.ManagedLabelPrefixes) > 0 {
+		if drifted := driftedKeys(node.Labels, planLabels, obj.Spec.MetadataUpdate.Labels); len(drifted) > 0 {
+			m.eventRecorder.Eventf(obj, corev1.EventTypeWarning, metadataDriftReason,
+				"Managed labels [%s] of node %s drifted from the plan, restoring them", strings.Join(drifted, ", "), node.Name)
+		}
+	}
+
+	finalMap, changed := computeDelta(node.Labels, planLabels, obj.Spec.MetadataUpdate.Labels, canChangeValue)
 	if !changed {
 		return node, obj, nil
 	}
@@ -257,7 +269,12 @@ func (m *nodesSyncer) syncLabels(key string, obj *v3.Node) (runtime.Object, erro
 		return obj, err
 	}
 
-	node, obj, err = m.updateLabels(node, obj, nodePlan)
+	policy, err := m.nodeMetadataPolicy(obj)
+	if err != nil {
+		return obj, err
+	}
+
+	node, obj, err = m.updateLabels(node, obj, nodePlan, policy)
 	if err != nil {
 		return obj, err
 	}
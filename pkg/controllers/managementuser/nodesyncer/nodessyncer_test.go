@@ -136,3 +136,94 @@ func TestDetermineNodeRole(t *testing.T) {
 		assert.EqualValues(t, tt.expectedNode, tt.node)
 	}
 }
+
+func TestComputeDeltaManagedLabelPrefixes(t *testing.T) {
+	planLabels := map[string]string{
+		"node-role.kubernetes.io/worker": "true",
+		"rancher.io/pool":                "pool1",
+		"team":                           "a",
+	}
+
+	tests := []struct {
+		name            string
+		policy          *v3.NodeMetadataPolicy
+		current         map[string]string
+		delta           v3.MapDelta
+		expected        map[string]string
+		expectedChanged bool
+		expectedDrift   []string
+	}{
+		{
+			name: "no policy keeps current behavior",
+			current: map[string]string{
+				"node-role.kubernetes.io/worker": "false",
+				"rancher.io/pool":                "pool2",
+			},
+			expected: map[string]string{
+				"node-role.kubernetes.io/worker": "true",
+				"rancher.io/pool":                "pool2",
+				"team":                           "a",
+			},
+			expectedChanged: true,
+		},
+		{
+			name:   "managed labels are enforced",
+			policy: &v3.NodeMetadataPolicy{ManagedLabelPrefixes: []string{"rancher.io/"}},
+			current: map[string]string{
+				"node-role.kubernetes.io/worker": "true",
+				"rancher.io/pool":                "pool2",
+				"team":                           "a",
+			},
+			expected: map[string]string{
+				"node-role.kubernetes.io/worker": "true",
+				"rancher.io/pool":                "pool1",
+				"team":                           "a",
+			},
+			expectedChanged: true,
+			expectedDrift:   []string{"rancher.io/pool"},
+		},
+		{
+			name:   "unmanaged labels are left alone",
+			policy: &v3.NodeMetadataPolicy{ManagedLabelPrefixes: []string{"rancher.io/"}},
+			current: map[string]string{
+				"node-role.kubernetes.io/worker": "false",
+				"rancher.io/pool":                "pool1",
+				"added-by-tooling":               "true",
+			},
+			expected: map[string]string{
+				"node-role.kubernetes.io/worker": "false",
+				"rancher.io/pool":                "pool1",
+				"added-by-tooling":               "true",
+			},
+		},
+		{
+			name:   "API updates apply to all labels",
+			policy: &v3.NodeMetadataPolicy{ManagedLabelPrefixes: []string{"rancher.io/"}},
+			current: map[string]string{
+				"rancher.io/pool":  "pool1",
+				"added-by-tooling": "true",
+			},
+			delta: v3.MapDelta{
+				Add:    map[string]string{"rancher.io/pool": "pool3", "team": "b"},
+				Delete: map[string]bool{"added-by-tooling": true},
+			},
+			expected: map[string]string{
+				"rancher.io/pool": "pool3",
+				"team":            "b",
+			},
+			expectedChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, canChangeValue := labelPolicy(tt.policy, planLabels)
+			result, changed := computeDelta(tt.current, labels, tt.delta, canChangeValue)
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.expectedChanged, changed)
+			if tt.policy != nil {
+				assert.Equal(t, tt.expectedDrift, driftedKeys(tt.current, labels, tt.delta))
+			}
+		})
+	}
+}
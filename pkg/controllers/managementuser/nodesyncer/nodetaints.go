@@ -31,7 +31,12 @@ func (m *nodesSyncer) syncTaints(key string, obj *v3.Node) (runtime.Object, erro
 	if err != nil || node == nil {
 		return obj, err
 	}
+	policy, err := m.nodeMetadataPolicy(obj)
+	if err != nil {
+		return obj, err
+	}
 	toAdd, toDel := taints.GetToDiffTaints(node.Spec.Taints, obj.Spec.DesiredNodeTaints)
+	toDel = managedTaintsToDelete(policy, toDel)
 	newObj := obj.DeepCopy()
 	if len(toAdd) != 0 || len(toDel) != 0 {
 		newNode := node.DeepCopy()
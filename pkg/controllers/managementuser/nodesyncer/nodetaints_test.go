@@ -2,6 +2,7 @@
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
@@ -126,6 +127,11 @@ func TestSyncNodeTaints(t *testing.T) {
 		nodeClient: &fake1.NodeInterfaceMock{
 			UpdateFunc: getNodeInterfaceUpdateFunc(t, testCases),
 		},
+		clusterLister: &fake3.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v32.Cluster, error) {
+				return &v32.Cluster{}, nil
+			},
+		},
 	}
 	for _, c := range testCases {
 		if _, err := syncer.syncTaints(c.machine.Name, &c.machine); err != nil {
@@ -140,6 +146,80 @@ func TestSyncNodeTaints(t *testing.T) {
 	}
 }
 
+func TestSyncNodeTaintsManagedPrefixes(t *testing.T) {
+	trueValue := true
+	machine := &v3.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test1", Namespace: "c-abcde", Labels: map[string]string{nodehelper.LabelNodeName: "test1"}},
+		Status: v32.NodeStatus{
+			Conditions: []v32.NodeCondition{
+				{
+					Type:   v32.NodeConditionRegistered,
+					Status: v1.ConditionTrue,
+				},
+			},
+			NodeName: "test1",
+		},
+		Spec: v32.NodeSpec{
+			DesiredNodeTaints: []v1.Taint{
+				{Key: "rancher.io/desired", Value: "true", Effect: v1.TaintEffectNoSchedule},
+			},
+			UpdateTaintsFromAPI: &trueValue,
+		},
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test1"},
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{
+				{Key: "rancher.io/removed", Value: "true", Effect: v1.TaintEffectNoSchedule},
+				{Key: "autoscaler.example.com/unmanaged", Value: "true", Effect: v1.TaintEffectNoSchedule},
+			},
+		},
+	}
+
+	var updated *v1.Node
+	syncer := nodesSyncer{
+		machines: &fake3.NodeInterfaceMock{
+			UpdateFunc: func(in1 *v3.Node) (*v3.Node, error) {
+				return in1, nil
+			},
+		},
+		nodeLister: &fake1.NodeListerMock{
+			GetFunc: func(namespace string, name string) (*v1.Node, error) {
+				return node, nil
+			},
+		},
+		nodeClient: &fake1.NodeInterfaceMock{
+			UpdateFunc: func(in1 *v1.Node) (*v1.Node, error) {
+				updated = in1
+				return in1, nil
+			},
+		},
+		clusterLister: &fake3.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v32.Cluster, error) {
+				cluster := &v32.Cluster{}
+				cluster.Spec.NodeMetadataPolicy = &v32.NodeMetadataPolicy{
+					ManagedTaintPrefixes: []string{"rancher.io/"},
+				}
+				return cluster, nil
+			},
+		},
+	}
+
+	if _, err := syncer.syncTaints(machine.Name, machine); err != nil {
+		t.Fatalf("syncTaints returned error %s", err.Error())
+	}
+	if updated == nil {
+		t.Fatalf("expected node to be updated")
+	}
+	expected := []v1.Taint{
+		{Key: "autoscaler.example.com/unmanaged", Value: "true", Effect: v1.TaintEffectNoSchedule},
+		{Key: "rancher.io/desired", Value: "true", Effect: v1.TaintEffectNoSchedule},
+	}
+	if !reflect.DeepEqual(expected, updated.Spec.Taints) {
+		t.Fatalf("expected node taints %v but got %v", expected, updated.Spec.Taints)
+	}
+}
+
 func getMachineUpdateFunc(t *testing.T, cases []*syncTaintsTestCase) func(*v3.Node) (*v3.Node, error) {
 	machineSet := caseByMachine(t, cases)
 	return func(in1 *v3.Node) (*v3.Node, error) {
@@ -62,60 +62,92 @@ func (h *clusterHandler) sync(key string, obj *v3.Cluster) (runtime.Object, erro
 		}
 		return h.clusters.Update(obj)
 	}
-	return obj, nil
+	return obj, h.syncRestrictedAdminExclusion(obj)
+}
+
+// syncRestrictedAdminExclusion removes the cluster-admin bindings of the restricted-admins once the cluster is excluded
+// from their reach, and creates them again once it no longer is
+func (h *clusterHandler) syncRestrictedAdminExclusion(cluster *v3.Cluster) error {
+	if cluster.Name == "local" {
+		return nil
+	}
+	if !rbac.RestrictedAdminExcluded(cluster) {
+		return h.ensureAdminBindings(rbac.GlobalRestrictedAdmin)
+	}
+
+	grbs, err := h.grbIndexer.ByIndex(grbByRoleIndex, rbac.GlobalRestrictedAdmin)
+	if err != nil {
+		return err
+	}
+	for _, x := range grbs {
+		grb, _ := x.(*v3.GlobalRoleBinding)
+		bindingName := rbac.GrbCRBName(grb)
+		if _, err := h.userGRBLister.Get("", bindingName); k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := h.userGRB.Delete(bindingName, &metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
 }
 
 func (h *clusterHandler) doSync(cluster *v3.Cluster) error {
 	_, err := v32.ClusterConditionGlobalAdminsSynced.DoUntilTrue(cluster, func() (runtime.Object, error) {
 		// Sync both admin types
 		for _, roleName := range []string{rbac.GlobalAdmin, rbac.GlobalRestrictedAdmin} {
-			// Do not sync restricted-admin to the local cluster as 'cluster-admin'
-			if cluster.Name == "local" && roleName == rbac.GlobalRestrictedAdmin {
+			// Do not sync restricted-admin to the local cluster or to a cluster excluded from its reach as 'cluster-admin'
+			if roleName == rbac.GlobalRestrictedAdmin && rbac.RestrictedAdminExcluded(cluster) {
 				continue
 			}
-			grbs, err := h.grbIndexer.ByIndex(grbByRoleIndex, roleName)
-			if err != nil {
+			if err := h.ensureAdminBindings(roleName); err != nil {
 				return nil, err
 			}
-
-			for _, x := range grbs {
-				grb, _ := x.(*v3.GlobalRoleBinding)
-				bindingName := rbac.GrbCRBName(grb)
-				b, err := h.userGRBLister.Get("", bindingName)
-				if err != nil && !k8serrors.IsNotFound(err) {
-					return nil, err
-				}
-
-				if b != nil {
-					// binding exists, nothing to do
-					continue
-				}
-
-				_, err = h.userGRB.Create(&k8srbac.ClusterRoleBinding{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: bindingName,
-					},
-					Subjects: []k8srbac.Subject{
-						{
-							Kind: "User",
-							Name: grb.UserName,
-						},
-					},
-					RoleRef: k8srbac.RoleRef{
-						Name: "cluster-admin",
-						Kind: "ClusterRole",
-					},
-				})
-				if err != nil && !k8serrors.IsAlreadyExists(err) {
-					return nil, err
-				}
-			}
 		}
 		return nil, nil
 	})
 	return err
 }
 
+// ensureAdminBindings binds the users of the global role to the cluster-admin role of the cluster
+func (h *clusterHandler) ensureAdminBindings(roleName string) error {
+	grbs, err := h.grbIndexer.ByIndex(grbByRoleIndex, roleName)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range grbs {
+		grb, _ := x.(*v3.GlobalRoleBinding)
+		bindingName := rbac.GrbCRBName(grb)
+		b, err := h.userGRBLister.Get("", bindingName)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+
+		if b != nil {
+			// binding exists, nothing to do
+			continue
+		}
+
+		_, err = h.userGRB.Create(&k8srbac.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: bindingName,
+			},
+			Subjects: []k8srbac.Subject{rbac.GetGRBSubject(grb)},
+			RoleRef: k8srbac.RoleRef{
+				Name: "cluster-admin",
+				Kind: "ClusterRole",
+			},
+		})
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 func grbByRole(obj interface{}) ([]string, error) {
 	grb, ok := obj.(*v3.GlobalRoleBinding)
 	if !ok {
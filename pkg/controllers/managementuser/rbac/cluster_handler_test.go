@@ -0,0 +1,68 @@
+package rbac
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/rbac.authorization.k8s.io/v1/fakes"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8srbac "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSyncRestrictedAdminExclusion(t *testing.T) {
+	grbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{grbByRoleIndex: grbByRole})
+	grb := &v3.GlobalRoleBinding{
+		ObjectMeta:     metav1.ObjectMeta{Name: "grb-abcde"},
+		UserName:       "u-abcde",
+		GlobalRoleName: rbac.GlobalRestrictedAdmin,
+	}
+	require.NoError(t, grbIndexer.Add(grb))
+
+	bindings := map[string]*k8srbac.ClusterRoleBinding{}
+	h := &clusterHandler{
+		clusterName: "c-abcde",
+		grbIndexer:  grbIndexer,
+		userGRBLister: &fakes.ClusterRoleBindingListerMock{
+			GetFunc: func(namespace string, name string) (*k8srbac.ClusterRoleBinding, error) {
+				if binding, ok := bindings[name]; ok {
+					return binding, nil
+				}
+				return nil, k8serrors.NewNotFound(schema.GroupResource{}, name)
+			},
+		},
+		userGRB: &fakes.ClusterRoleBindingInterfaceMock{
+			CreateFunc: func(in1 *k8srbac.ClusterRoleBinding) (*k8srbac.ClusterRoleBinding, error) {
+				bindings[in1.Name] = in1
+				return in1, nil
+			},
+			DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+				delete(bindings, name)
+				return nil
+			},
+		},
+	}
+
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	require.NoError(t, h.syncRestrictedAdminExclusion(cluster))
+	require.Contains(t, bindings, rbac.GrbCRBName(grb))
+	assert.Equal(t, "cluster-admin", bindings[rbac.GrbCRBName(grb)].RoleRef.Name)
+
+	cluster.Annotations = map[string]string{rbac.RestrictedAdminExcludeAnnotation: "true"}
+	require.NoError(t, h.syncRestrictedAdminExclusion(cluster))
+	assert.Empty(t, bindings, "expected the restricted-admin to lose access to an excluded cluster")
+
+	delete(cluster.Annotations, rbac.RestrictedAdminExcludeAnnotation)
+	require.NoError(t, h.syncRestrictedAdminExclusion(cluster))
+	assert.Contains(t, bindings, rbac.GrbCRBName(grb), "expected the access to be restored once the cluster is no longer excluded")
+}
+
+func TestSyncRestrictedAdminExclusionLocal(t *testing.T) {
+	h := &clusterHandler{clusterName: "local"}
+	assert.NoError(t, h.syncRestrictedAdminExclusion(&v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "local"}}))
+}
@@ -59,6 +59,7 @@ func newGlobalRoleBindingHandler(workload *config.UserContext) v3.GlobalRoleBind
 		clusterRoleBindings: workload.RBAC.ClusterRoleBindings(""),
 		crbLister:           workload.RBAC.ClusterRoleBindings("").Controller().Lister(),
 		grLister:            workload.Management.Management.GlobalRoles("").Controller().Lister(),
+		clusterLister:       workload.Management.Management.Clusters("").Controller().Lister(),
 	}
 
 	return h.sync
@@ -72,6 +73,7 @@ type grbHandler struct {
 	crbLister           rbacv1.ClusterRoleBindingLister
 	grbIndexer          cache.Indexer
 	grLister            v3.GlobalRoleLister
+	clusterLister       v3.ClusterLister
 }
 
 func (c *grbHandler) sync(key string, obj *v3.GlobalRoleBinding) (runtime.Object, error) {
@@ -86,9 +88,18 @@ func (c *grbHandler) sync(key string, obj *v3.GlobalRoleBinding) (runtime.Object
 		return obj, nil
 	}
 
-	// Do not sync restricted-admin to the local cluster as 'cluster-admin'
-	if c.clusterName == "local" && obj.GlobalRoleName == rbac.GlobalRestrictedAdmin {
-		return obj, nil
+	// Do not sync restricted-admin to the local cluster or to a cluster excluded from its reach as 'cluster-admin'
+	if obj.GlobalRoleName == rbac.GlobalRestrictedAdmin {
+		if c.clusterName == "local" {
+			return obj, nil
+		}
+		cluster, err := c.clusterLister.Get("", c.clusterName)
+		if err != nil {
+			return obj, err
+		}
+		if rbac.RestrictedAdminExcluded(cluster) {
+			return obj, nil
+		}
 	}
 
 	logrus.Debugf("%v is an admin role", obj.GlobalRoleName)
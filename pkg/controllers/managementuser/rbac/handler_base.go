@@ -109,6 +109,7 @@ func Register(ctx context.Context, workload *config.UserContext) {
 		LimitRange:          workload.Core.LimitRanges(""),
 		LimitRangeLister:    workload.Core.LimitRanges("").Controller().Lister(),
 		ProjectLister:       workload.Management.Management.Projects(workload.ClusterName).Controller().Lister(),
+		EventRecorder:       resourcequota.NewEventRecorder(ctx, workload.K8sClient),
 	}
 
 	workload.Core.Namespaces("").AddLifecycle(ctx, "namespace-auth", newNamespaceLifecycle(r, sync))
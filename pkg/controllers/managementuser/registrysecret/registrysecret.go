@@ -0,0 +1,274 @@
+package registrysecret
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The registry secret controller replicates the registry credentials (dockerconfigjson secrets
+// created through the API) of a project into every namespace of that project and adds them to the
+// imagePullSecrets of the namespace's default service account. Replicated secrets are labeled with
+// the project they were copied from so they can be removed when the namespace leaves the project.
+// Copies made by the secret controller before, which are not labeled, are removed once their
+// project secret is gone.
+
+const (
+	projectIDAnnotation    = "field.cattle.io/projectId"
+	optOutAnnotation       = "secret.user.cattle.io/skip-registry-propagation"
+	userSecretAnnotation   = "secret.user.cattle.io/secret"
+	sourceProjectLabel     = "secret.user.cattle.io/registry-project"
+	creatorLabel           = "cattle.io/creator"
+	defaultServiceAccount  = "default"
+	registrySecretsHandler = "registrySecretsController"
+)
+
+type controller struct {
+	clusterName          string
+	namespaces           v1.NamespaceController
+	namespaceLister      v1.NamespaceLister
+	secrets              v1.SecretInterface
+	secretLister         v1.SecretLister
+	serviceAccounts      v1.ServiceAccountInterface
+	serviceAccountLister v1.ServiceAccountLister
+	managementSecrets    v1.SecretLister
+	projectLister        v3.ProjectLister
+}
+
+// IsRegistrySecret returns true for the registry credentials created through the API, the project
+// secrets replicated by this controller.
+func IsRegistrySecret(secret *corev1.Secret) bool {
+	return secret.Type == corev1.SecretTypeDockerConfigJson && secret.Labels[creatorLabel] == "norman"
+}
+
+func Register(ctx context.Context, cluster *config.UserContext) {
+	c := &controller{
+		clusterName:          cluster.ClusterName,
+		namespaces:           cluster.Core.Namespaces("").Controller(),
+		namespaceLister:      cluster.Core.Namespaces("").Controller().Lister(),
+		secrets:              cluster.Core.Secrets(""),
+		secretLister:         cluster.Core.Secrets("").Controller().Lister(),
+		serviceAccounts:      cluster.Core.ServiceAccounts(""),
+		serviceAccountLister: cluster.Core.ServiceAccounts("").Controller().Lister(),
+		managementSecrets:    cluster.Management.Core.Secrets("").Controller().Lister(),
+		projectLister:        cluster.Management.Management.Projects(cluster.ClusterName).Controller().Lister(),
+	}
+
+	cluster.Core.Namespaces("").AddHandler(ctx, registrySecretsHandler, c.sync)
+	cluster.Management.Core.Secrets("").AddHandler(ctx, registrySecretsHandler, c.syncProjectSecret)
+}
+
+// syncProjectSecret enqueues every namespace of the project owning a changed registry secret, so
+// that rotations and removals are propagated.
+func (c *controller) syncProjectSecret(key string, obj *corev1.Secret) (runtime.Object, error) {
+	if obj != nil && !IsRegistrySecret(obj) {
+		return nil, nil
+	}
+
+	// on the management side, the secret's namespace name equals the project name
+	projectName := strings.SplitN(key, "/", 2)[0]
+	if _, err := c.projectLister.Get(c.clusterName, projectName); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	namespaces, err := c.namespaceLister.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range namespaces {
+		if _, project := c.projectOf(ns); project == projectName {
+			c.namespaces.Enqueue("", ns.Name)
+		}
+	}
+	return nil, nil
+}
+
+func (c *controller) sync(key string, ns *corev1.Namespace) (runtime.Object, error) {
+	if ns == nil || ns.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	desired, legacySources, err := c.desiredSecrets(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := c.secretLister.List(ns.Name, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var add, remove []string
+	for _, secret := range existing {
+		if _, ok := secret.Labels[sourceProjectLabel]; !ok && !isLegacyCopy(secret, legacySources) {
+			continue
+		}
+		if _, ok := desired[secret.Name]; ok {
+			continue
+		}
+		logrus.Infof("registrySecretsController: deleting registry secret [%s] from namespace [%s]", secret.Name, ns.Name)
+		if err := c.secrets.DeleteNamespaced(ns.Name, secret.Name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		remove = append(remove, secret.Name)
+	}
+
+	for _, secret := range desired {
+		if err := c.ensureSecret(secret); err != nil {
+			return nil, err
+		}
+		add = append(add, secret.Name)
+	}
+
+	return nil, c.updateImagePullSecrets(ns.Name, add, remove)
+}
+
+// projectOf returns the cluster and project name a namespace belongs to.
+func (c *controller) projectOf(ns *corev1.Namespace) (string, string) {
+	parts := strings.Split(ns.Annotations[projectIDAnnotation], ":")
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// desiredSecrets returns the registry secrets that should exist in the namespace, keyed by name,
+// and the names of the other secrets of the project, which the secret controller copies.
+func (c *controller) desiredSecrets(ns *corev1.Namespace) (map[string]*corev1.Secret, map[string]bool, error) {
+	desired := map[string]*corev1.Secret{}
+	others := map[string]bool{}
+
+	clusterName, projectName := c.projectOf(ns)
+	if clusterName != c.clusterName || projectName == "" {
+		return desired, others, nil
+	}
+
+	secrets, err := c.managementSecrets.List(projectName, labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, secret := range secrets {
+		if !IsRegistrySecret(secret) {
+			others[secret.Name] = true
+			continue
+		}
+		if secret.DeletionTimestamp != nil || ns.Annotations[optOutAnnotation] == "true" {
+			continue
+		}
+		desired[secret.Name] = getNamespacedSecret(secret, ns.Name, projectName)
+	}
+	return desired, others, nil
+}
+
+// isLegacyCopy returns true for the registry secrets copied into the namespace by the secret
+// controller before this controller, unless the secret controller still copies a project secret
+// of the same name.
+func isLegacyCopy(secret *corev1.Secret, others map[string]bool) bool {
+	return secret.Type == corev1.SecretTypeDockerConfigJson && secret.Annotations[userSecretAnnotation] == "true" &&
+		!others[secret.Name]
+}
+
+func (c *controller) ensureSecret(secret *corev1.Secret) error {
+	existing, err := c.secretLister.Get(secret.Namespace, secret.Name)
+	if errors.IsNotFound(err) {
+		logrus.Infof("registrySecretsController: copying registry secret [%s] into namespace [%s]", secret.Name, secret.Namespace)
+		_, err = c.secrets.Create(secret)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(existing.Data, secret.Data) && existing.Type == secret.Type &&
+		existing.Labels[sourceProjectLabel] == secret.Labels[sourceProjectLabel] {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Data = secret.Data
+	updated.Type = secret.Type
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	updated.Labels[sourceProjectLabel] = secret.Labels[sourceProjectLabel]
+	logrus.Infof("registrySecretsController: updating registry secret [%s] in namespace [%s]", secret.Name, secret.Namespace)
+	_, err = c.secrets.Update(updated)
+	return err
+}
+
+// updateImagePullSecrets adds and removes the given secrets from the imagePullSecrets of the default
+// service account in the namespace.
+func (c *controller) updateImagePullSecrets(namespace string, add, remove []string) error {
+	if len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	sa, err := c.serviceAccountLister.Get(namespace, defaultServiceAccount)
+	if errors.IsNotFound(err) {
+		// the default service account is created asynchronously with the namespace
+		if len(add) > 0 {
+			return err
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	removed := map[string]bool{}
+	for _, name := range remove {
+		removed[name] = true
+	}
+
+	var pullSecrets []corev1.LocalObjectReference
+	present := map[string]bool{}
+	for _, ref := range sa.ImagePullSecrets {
+		if removed[ref.Name] {
+			continue
+		}
+		present[ref.Name] = true
+		pullSecrets = append(pullSecrets, ref)
+	}
+	for _, name := range add {
+		if !present[name] {
+			pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: name})
+		}
+	}
+
+	if reflect.DeepEqual(pullSecrets, sa.ImagePullSecrets) {
+		return nil
+	}
+
+	sa = sa.DeepCopy()
+	sa.ImagePullSecrets = pullSecrets
+	_, err = c.serviceAccounts.Update(sa)
+	return err
+}
+
+func getNamespacedSecret(obj *corev1.Secret, namespace, projectName string) *corev1.Secret {
+	namespacedSecret := &corev1.Secret{}
+	namespacedSecret.Name = obj.Name
+	namespacedSecret.Namespace = namespace
+	namespacedSecret.Data = obj.Data
+	namespacedSecret.Type = obj.Type
+	namespacedSecret.Labels = map[string]string{
+		sourceProjectLabel: projectName,
+	}
+	namespacedSecret.Annotations = map[string]string{}
+	for k, v := range obj.Annotations {
+		namespacedSecret.Annotations[k] = v
+	}
+	namespacedSecret.Annotations[userSecretAnnotation] = "true"
+	return namespacedSecret
+}
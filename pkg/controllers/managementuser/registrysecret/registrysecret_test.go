@@ -0,0 +1,302 @@
+package registrysecret
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	mgmtfakes "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	testCluster   = "c-test"
+	testProject   = "p-test"
+	testNamespace = "ns-test"
+)
+
+type testState struct {
+	managementSecrets map[string]*corev1.Secret
+	clusterSecrets    map[string]*corev1.Secret
+	serviceAccount    *corev1.ServiceAccount
+	enqueued          []string
+}
+
+func newTestController(state *testState) *controller {
+	notFound := func(resource, name string) error {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+	}
+	return &controller{
+		clusterName: testCluster,
+		namespaces: &fakes.NamespaceControllerMock{
+			EnqueueFunc: func(namespace string, name string) {
+				state.enqueued = append(state.enqueued, name)
+			},
+		},
+		namespaceLister: &fakes.NamespaceListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Namespace, error) {
+				return []*corev1.Namespace{
+					newNamespace(testNamespace, testCluster+":"+testProject),
+					newNamespace("other", testCluster+":p-other"),
+				}, nil
+			},
+		},
+		secrets: &fakes.SecretInterfaceMock{
+			CreateFunc: func(in1 *corev1.Secret) (*corev1.Secret, error) {
+				state.clusterSecrets[in1.Name] = in1
+				return in1, nil
+			},
+			UpdateFunc: func(in1 *corev1.Secret) (*corev1.Secret, error) {
+				state.clusterSecrets[in1.Name] = in1
+				return in1, nil
+			},
+			DeleteNamespacedFunc: func(namespace string, name string, options *metav1.DeleteOptions) error {
+				delete(state.clusterSecrets, name)
+				return nil
+			},
+		},
+		secretLister: &fakes.SecretListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.Secret, error) {
+				if secret, ok := state.clusterSecrets[name]; ok {
+					return secret, nil
+				}
+				return nil, notFound("secrets", name)
+			},
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+				var secrets []*corev1.Secret
+				for _, secret := range state.clusterSecrets {
+					secrets = append(secrets, secret)
+				}
+				return secrets, nil
+			},
+		},
+		serviceAccounts: &fakes.ServiceAccountInterfaceMock{
+			UpdateFunc: func(in1 *corev1.ServiceAccount) (*corev1.ServiceAccount, error) {
+				state.serviceAccount = in1
+				return in1, nil
+			},
+		},
+		serviceAccountLister: &fakes.ServiceAccountListerMock{
+			GetFunc: func(namespace string, name string) (*corev1.ServiceAccount, error) {
+				return state.serviceAccount, nil
+			},
+		},
+		managementSecrets: &fakes.SecretListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+				var secrets []*corev1.Secret
+				if namespace != testProject {
+					return secrets, nil
+				}
+				for _, secret := range state.managementSecrets {
+					secrets = append(secrets, secret)
+				}
+				return secrets, nil
+			},
+		},
+		projectLister: &mgmtfakes.ProjectListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Project, error) {
+				if name != testProject {
+					return nil, notFound("projects", name)
+				}
+				return &v3.Project{}, nil
+			},
+		},
+	}
+}
+
+func newTestState() *testState {
+	return &testState{
+		managementSecrets: map[string]*corev1.Secret{
+			"registry": newRegistrySecret("registry", testProject, "creds"),
+			"opaque": {
+				ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: testProject},
+				Type:       corev1.SecretTypeOpaque,
+			},
+		},
+		clusterSecrets: map[string]*corev1.Secret{},
+		serviceAccount: &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: defaultServiceAccount, Namespace: testNamespace},
+		},
+	}
+}
+
+func newNamespace(name, projectID string) *corev1.Namespace {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}},
+	}
+	if projectID != "" {
+		ns.Annotations[projectIDAnnotation] = projectID
+	}
+	return ns
+}
+
+func newRegistrySecret(name, namespace, data string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{creatorLabel: "norman"}},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(data)},
+	}
+}
+
+func TestSyncJoin(t *testing.T) {
+	assert := assert.New(t)
+	state := newTestState()
+	c := newTestController(state)
+
+	_, err := c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+
+	assert.Len(state.clusterSecrets, 1)
+	secret := state.clusterSecrets["registry"]
+	if assert.NotNil(secret) {
+		assert.Equal(testNamespace, secret.Namespace)
+		assert.Equal(testProject, secret.Labels[sourceProjectLabel])
+		assert.Equal("creds", string(secret.Data[corev1.DockerConfigJsonKey]))
+	}
+	assert.Equal([]corev1.LocalObjectReference{{Name: "registry"}}, state.serviceAccount.ImagePullSecrets)
+}
+
+func TestSyncJoinOtherCluster(t *testing.T) {
+	assert := assert.New(t)
+	state := newTestState()
+	c := newTestController(state)
+
+	_, err := c.sync(testNamespace, newNamespace(testNamespace, "c-other:"+testProject))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 0)
+	assert.Len(state.serviceAccount.ImagePullSecrets, 0)
+}
+
+func TestSyncLeave(t *testing.T) {
+	assert := assert.New(t)
+	state := newTestState()
+	c := newTestController(state)
+
+	_, err := c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 1)
+
+	// secrets that were not replicated by the controller must be left alone
+	state.clusterSecrets["user"] = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: testNamespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+	}
+	state.serviceAccount.ImagePullSecrets = append(state.serviceAccount.ImagePullSecrets, corev1.LocalObjectReference{Name: "user"})
+
+	_, err = c.sync(testNamespace, newNamespace(testNamespace, ""))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 1)
+	assert.Contains(state.clusterSecrets, "user")
+	assert.Equal([]corev1.LocalObjectReference{{Name: "user"}}, state.serviceAccount.ImagePullSecrets)
+}
+
+func TestSyncRotation(t *testing.T) {
+	assert := assert.New(t)
+	state := newTestState()
+	c := newTestController(state)
+
+	_, err := c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+
+	rotated := newRegistrySecret("registry", testProject, "rotated")
+	state.managementSecrets["registry"] = rotated
+	_, err = c.syncProjectSecret(testProject+"/registry", rotated)
+	assert.Nil(err)
+	assert.Equal([]string{testNamespace}, state.enqueued)
+
+	_, err = c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+	assert.Equal("rotated", string(state.clusterSecrets["registry"].Data[corev1.DockerConfigJsonKey]))
+	assert.Equal([]corev1.LocalObjectReference{{Name: "registry"}}, state.serviceAccount.ImagePullSecrets)
+
+	// removing the project secret removes the copy
+	delete(state.managementSecrets, "registry")
+	_, err = c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 0)
+	assert.Len(state.serviceAccount.ImagePullSecrets, 0)
+}
+
+func TestSyncOptOut(t *testing.T) {
+	assert := assert.New(t)
+	state := newTestState()
+	c := newTestController(state)
+
+	ns := newNamespace(testNamespace, testCluster+":"+testProject)
+	ns.Annotations[optOutAnnotation] = "true"
+	_, err := c.sync(testNamespace, ns)
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 0)
+	assert.Len(state.serviceAccount.ImagePullSecrets, 0)
+
+	// opting out after the secrets were replicated removes them
+	_, err = c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 1)
+
+	_, err = c.sync(testNamespace, ns)
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 0)
+	assert.Len(state.serviceAccount.ImagePullSecrets, 0)
+}
+
+func TestSyncSkipsUserPullSecrets(t *testing.T) {
+	assert := assert.New(t)
+	state := newTestState()
+	state.managementSecrets["user"] = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: testProject},
+		Type:       corev1.SecretTypeDockerConfigJson,
+	}
+	c := newTestController(state)
+
+	_, err := c.syncProjectSecret(testProject+"/user", state.managementSecrets["user"])
+	assert.Nil(err)
+	assert.Len(state.enqueued, 0)
+
+	_, err = c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 1)
+	assert.Contains(state.clusterSecrets, "registry")
+}
+
+func TestSyncLegacyCopies(t *testing.T) {
+	assert := assert.New(t)
+	state := newTestState()
+	state.managementSecrets["user"] = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: testProject},
+		Type:       corev1.SecretTypeDockerConfigJson,
+	}
+	legacyCopy := func(name string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Annotations: map[string]string{userSecretAnnotation: "true"}},
+			Type:       corev1.SecretTypeDockerConfigJson,
+		}
+	}
+	// copies made by the secret controller, of a deleted registry secret, of a registry secret
+	// and of a secret the secret controller still copies
+	state.clusterSecrets["deleted"] = legacyCopy("deleted")
+	state.clusterSecrets["registry"] = legacyCopy("registry")
+	state.clusterSecrets["user"] = legacyCopy("user")
+	c := newTestController(state)
+
+	_, err := c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 2)
+	assert.Contains(state.clusterSecrets, "user")
+	if assert.Contains(state.clusterSecrets, "registry") {
+		assert.Equal(testProject, state.clusterSecrets["registry"].Labels[sourceProjectLabel])
+	}
+
+	// the legacy copy of a registry secret being deleted is removed
+	state.managementSecrets["registry"].DeletionTimestamp = &metav1.Time{}
+	state.clusterSecrets["registry"] = legacyCopy("registry")
+	_, err = c.sync(testNamespace, newNamespace(testNamespace, testCluster+":"+testProject))
+	assert.Nil(err)
+	assert.Len(state.clusterSecrets, 1)
+	assert.Contains(state.clusterSecrets, "user")
+}
@@ -3,9 +3,12 @@
 import (
 	"context"
 
+	"github.com/rancher/rancher/pkg/eventrecorder"
 	"github.com/rancher/rancher/pkg/types/config"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -27,6 +30,7 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 		LimitRange:          cluster.Core.LimitRanges(""),
 		LimitRangeLister:    cluster.Core.LimitRanges("").Controller().Lister(),
 		ProjectLister:       cluster.Management.Management.Projects(cluster.ClusterName).Controller().Lister(),
+		EventRecorder:       NewEventRecorder(ctx, cluster.K8sClient),
 	}
 	cluster.Core.Namespaces("").AddHandler(ctx, "resourceQuotaSyncController", sync.syncResourceQuota)
 
@@ -42,6 +46,7 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 		projectLister: cluster.Management.Management.Projects(cluster.ClusterName).Controller().Lister(),
 		projects:      cluster.Management.Management.Projects(cluster.ClusterName),
 		clusterName:   cluster.ClusterName,
+		eventRecorder: NewEventRecorder(ctx, cluster.Management.K8sClient),
 	}
 	cluster.Core.Namespaces("").AddHandler(ctx, "resourceQuotaUsedLimitController", calculate.calculateResourceQuotaUsed)
 	cluster.Management.Management.Projects(cluster.ClusterName).AddHandler(ctx, "resourceQuotaProjectUsedLimitController", calculate.calculateResourceQuotaUsedProject)
@@ -53,6 +58,12 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 	cluster.Management.Management.Projects(cluster.ClusterName).AddHandler(ctx, "namespaceResourceQuotaResetController", reset.resetNamespaceQuota)
 }
 
+// NewEventRecorder returns a recorder for the resource quota events of the objects of the given cluster, which stops
+// once the context is done
+func NewEventRecorder(ctx context.Context, k8sClient kubernetes.Interface) record.EventRecorder {
+	return eventrecorder.New(ctx, k8sClient, "resource-quota-controller")
+}
+
 func nsByProjectID(obj interface{}) ([]string, error) {
 	ns, ok := obj.(*corev1.Namespace)
 	if !ok {
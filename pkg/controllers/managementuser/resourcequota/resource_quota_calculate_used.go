@@ -3,7 +3,10 @@
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	namespaceutil "github.com/rancher/rancher/pkg/namespace"
 	validate "github.com/rancher/rancher/pkg/resourcequota"
@@ -11,17 +14,22 @@
 	"k8s.io/apimachinery/pkg/runtime"
 	quota "k8s.io/apiserver/pkg/quota/v1"
 	clientcache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
+const resourceQuotaOverCommittedReason = "ResourceQuotaOverCommitted"
+
 /*
 collectController is responsible for calculate the combined limit set on the project's Namespaces,
-and setting this information in the project
+and setting this information in the project. Projects whose limit is lowered below the combined limit
+are marked as over-committed, the quotas of their namespaces are left untouched.
 */
 type calculateLimitController struct {
 	projectLister v3.ProjectLister
 	projects      v3.ProjectInterface
 	nsIndexer     clientcache.Indexer
 	clusterName   string
+	eventRecorder record.EventRecorder
 }
 
 func (c *calculateLimitController) calculateResourceQuotaUsed(key string, ns *corev1.Namespace) (runtime.Object, error) {
@@ -46,9 +54,12 @@ func (c *calculateLimitController) calculateResourceQuotaUsedProject(key string,
 func (c *calculateLimitController) calculateProjectResourceQuota(projectID string) error {
 	projectNamespace, projectName := getProjectNamespaceName(projectID)
 	project, err := c.projectLister.Get(projectNamespace, projectName)
-	if err != nil || project.Spec.ResourceQuota == nil {
+	if err != nil {
 		return err
 	}
+	if project.Spec.ResourceQuota == nil {
+		return c.clearProjectResourceQuotaUsage(project)
+	}
 
 	namespaces, err := c.nsIndexer.ByIndex(nsByProjectIndex, projectID)
 	if err != nil {
@@ -82,12 +93,88 @@ func (c *calculateLimitController) calculateProjectResourceQuota(projectID strin
 		return err
 	}
 
-	if reflect.DeepEqual(project.Spec.ResourceQuota.UsedLimit, limit) {
-		return nil
+	usage, overCommitted, err := quotaUsage(nssResourceList, &project.Spec.ResourceQuota.Limit)
+	if err != nil {
+		return err
 	}
 
 	toUpdate := project.DeepCopy()
 	toUpdate.Spec.ResourceQuota.UsedLimit = *limit
-	_, err = c.projects.Update(toUpdate)
+	toUpdate.Status.ResourceQuotaUsage = usage
+	msg := ""
+	if len(overCommitted) > 0 {
+		msg = fmt.Sprintf("Resource quota of namespaces exceeds project limit on fields: %s", strings.Join(overCommitted, ","))
+	}
+	setOverCommitted(toUpdate, msg)
+
+	if reflect.DeepEqual(project, toUpdate) {
+		return nil
+	}
+	updated, err := c.projects.Update(toUpdate)
+	if err != nil {
+		return err
+	}
+	if msg != "" && msg != v32.ProjectConditionResourceQuotaOverCommitted.GetMessage(project) {
+		c.eventRecorder.Event(updated, corev1.EventTypeWarning, resourceQuotaOverCommittedReason, msg)
+	}
+	return nil
+}
+
+func (c *calculateLimitController) clearProjectResourceQuotaUsage(project *v3.Project) error {
+	toUpdate := project.DeepCopy()
+	toUpdate.Status.ResourceQuotaUsage = nil
+	setOverCommitted(toUpdate, "")
+	if reflect.DeepEqual(project, toUpdate) {
+		return nil
+	}
+	_, err := c.projects.Update(toUpdate)
 	return err
 }
+
+// setOverCommitted marks the project as over-committed with msg, or as not over-committed if msg is empty
+func setOverCommitted(project *v3.Project, msg string) {
+	if msg == "" {
+		if v32.ProjectConditionResourceQuotaOverCommitted.IsTrue(project) {
+			v32.ProjectConditionResourceQuotaOverCommitted.False(project)
+			v32.ProjectConditionResourceQuotaOverCommitted.Message(project, "")
+		}
+		return
+	}
+	if !v32.ProjectConditionResourceQuotaOverCommitted.IsTrue(project) ||
+		v32.ProjectConditionResourceQuotaOverCommitted.GetMessage(project) != msg {
+		v32.ProjectConditionResourceQuotaOverCommitted.True(project)
+		v32.ProjectConditionResourceQuotaOverCommitted.Reason(project, resourceQuotaOverCommittedReason)
+		v32.ProjectConditionResourceQuotaOverCommitted.Message(project, msg)
+	}
+}
+
+// quotaUsage reports the used quota of every resource limited by the project, and returns the resources whose used
+// quota exceeds the limit
+func quotaUsage(used corev1.ResourceList, projectLimit *v32.ResourceQuotaLimit) ([]v32.ResourceQuotaUsage, []string, error) {
+	limit, err := validate.ConvertLimitToResourceList(projectLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	for name := range limit {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var usage []v32.ResourceQuotaUsage
+	var overCommitted []string
+	for _, name := range names {
+		limitQuantity := limit[corev1.ResourceName(name)]
+		usedQuantity := used[corev1.ResourceName(name)]
+		usage = append(usage, v32.ResourceQuotaUsage{
+			Resource: name,
+			Used:     usedQuantity.String(),
+			Limit:    limitQuantity.String(),
+		})
+		if usedQuantity.Cmp(limitQuantity) > 0 {
+			overCommitted = append(overCommitted, name)
+		}
+	}
+	return usage, overCommitted, nil
+}
@@ -0,0 +1,148 @@
+package resourcequota
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestQuotaUsage(t *testing.T) {
+	used := corev1.ResourceList{
+		"requestsCpu":    resource.MustParse("1500m"),
+		"requestsMemory": resource.MustParse("1Gi"),
+	}
+	limit := &v32.ResourceQuotaLimit{
+		Pods:           "10",
+		RequestsCPU:    "1",
+		RequestsMemory: "2Gi",
+	}
+
+	usage, overCommitted, err := quotaUsage(used, limit)
+	require.NoError(t, err)
+
+	assert.Equal(t, []v32.ResourceQuotaUsage{
+		{Resource: "pods", Used: "0", Limit: "10"},
+		{Resource: "requestsCpu", Used: "1500m", Limit: "1"},
+		{Resource: "requestsMemory", Used: "1Gi", Limit: "2Gi"},
+	}, usage)
+	assert.Equal(t, []string{"requestsCpu"}, overCommitted)
+}
+
+type calculateTest struct {
+	controller *calculateLimitController
+	recorder   *record.FakeRecorder
+	updated    *v3.Project
+}
+
+func newCalculateTest(t *testing.T, project *v3.Project, namespaces ...*corev1.Namespace) *calculateTest {
+	c := &calculateTest{recorder: record.NewFakeRecorder(10)}
+	c.controller = &calculateLimitController{
+		projectLister: newProjectLister(project),
+		projects: &fakes.ProjectInterfaceMock{
+			UpdateFunc: func(in1 *v3.Project) (*v3.Project, error) {
+				c.updated = in1
+				return in1, nil
+			},
+		},
+		nsIndexer:     newNsIndexer(t, namespaces...),
+		clusterName:   "c-1",
+		eventRecorder: c.recorder,
+	}
+	return c
+}
+
+func TestCalculateProjectResourceQuota(t *testing.T) {
+	tests := []struct {
+		name          string
+		limitCPU      string
+		namespaces    []*corev1.Namespace
+		expectedUsed  string
+		overCommitted bool
+	}{
+		{
+			name:     "namespaces within the project limit",
+			limitCPU: "1",
+			namespaces: []*corev1.Namespace{
+				newNamespace(t, "ns-1", "c-1:p-1", "300m", true),
+				newNamespace(t, "ns-2", "c-1:p-1", "400m", true),
+			},
+			expectedUsed: "700m",
+		},
+		{
+			name:     "namespaces using the whole project limit",
+			limitCPU: "1",
+			namespaces: []*corev1.Namespace{
+				newNamespace(t, "ns-1", "c-1:p-1", "500m", true),
+				newNamespace(t, "ns-2", "c-1:p-1", "500m", true),
+			},
+			expectedUsed: "1",
+		},
+		{
+			name:     "project limit lowered below the namespaces quota",
+			limitCPU: "600m",
+			namespaces: []*corev1.Namespace{
+				newNamespace(t, "ns-1", "c-1:p-1", "500m", true),
+				newNamespace(t, "ns-2", "c-1:p-1", "500m", true),
+			},
+			expectedUsed:  "1",
+			overCommitted: true,
+		},
+		{
+			name:     "namespaces not validated are not counted",
+			limitCPU: "600m",
+			namespaces: []*corev1.Namespace{
+				newNamespace(t, "ns-1", "c-1:p-1", "500m", true),
+				newNamespace(t, "ns-2", "c-1:p-1", "500m", false),
+			},
+			expectedUsed: "500m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCalculateTest(t, newProject("p-1", tt.limitCPU), tt.namespaces...)
+
+			require.NoError(t, c.controller.calculateProjectResourceQuota("c-1:p-1"))
+
+			require.NotNil(t, c.updated)
+			assert.Equal(t, tt.expectedUsed, c.updated.Spec.ResourceQuota.UsedLimit.RequestsCPU)
+			assert.Equal(t, []v32.ResourceQuotaUsage{
+				{Resource: "requestsCpu", Used: tt.expectedUsed, Limit: tt.limitCPU},
+			}, c.updated.Status.ResourceQuotaUsage)
+			assert.Equal(t, tt.overCommitted, v32.ProjectConditionResourceQuotaOverCommitted.IsTrue(c.updated))
+
+			if tt.overCommitted {
+				assert.Contains(t, v32.ProjectConditionResourceQuotaOverCommitted.GetMessage(c.updated), "requestsCpu")
+				require.Len(t, c.recorder.Events, 1)
+				assert.Contains(t, <-c.recorder.Events, resourceQuotaOverCommittedReason)
+			} else {
+				assert.Empty(t, c.recorder.Events)
+			}
+		})
+	}
+}
+
+func TestCalculateProjectResourceQuotaRecovered(t *testing.T) {
+	project := newProject("p-1", "1")
+	v32.ProjectConditionResourceQuotaOverCommitted.True(project)
+	c := newCalculateTest(t, project, newNamespace(t, "ns-1", "c-1:p-1", "500m", true))
+
+	require.NoError(t, c.controller.calculateProjectResourceQuota("c-1:p-1"))
+
+	require.NotNil(t, c.updated)
+	assert.True(t, v32.ProjectConditionResourceQuotaOverCommitted.IsFalse(c.updated))
+	assert.Empty(t, c.recorder.Events)
+
+	// the project is not updated again once the usage is recorded
+	c.controller.projectLister = newProjectLister(c.updated)
+	c.updated = nil
+	require.NoError(t, c.controller.calculateProjectResourceQuota("c-1:p-1"))
+	assert.Nil(t, c.updated)
+}
@@ -22,15 +22,18 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	clientcache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
 	projectIDAnnotation             = "field.cattle.io/projectId"
 	resourceQuotaLabel              = "resourcequota.management.cattle.io/default-resource-quota"
+	resourceQuotaProjectAnnotation  = "resourcequota.management.cattle.io/project-id"
 	resourceQuotaAnnotation         = "field.cattle.io/resourceQuota"
 	limitRangeAnnotation            = "field.cattle.io/containerDefaultResourceLimit"
 	ResourceQuotaValidatedCondition = "ResourceQuotaValidated"
 	ResourceQuotaInitCondition      = "ResourceQuotaInit"
+	resourceQuotaExceededReason     = "ResourceQuotaExceeded"
 )
 
 /*
@@ -45,6 +48,7 @@ type SyncController struct {
 	LimitRange          v1.LimitRangeInterface
 	LimitRangeLister    v1.LimitRangeLister
 	NsIndexer           clientcache.Indexer
+	EventRecorder       record.EventRecorder
 }
 
 func (c *SyncController) syncResourceQuota(key string, ns *corev1.Namespace) (runtime.Object, error) {
@@ -119,7 +123,7 @@ func (c *SyncController) CreateResourceQuota(ns *corev1.Namespace) (runtime.Obje
 		return ns, err
 	}
 
-	projectLimit, _, err := getProjectResourceQuotaLimit(ns, c.ProjectLister)
+	projectLimit, projectID, err := getProjectResourceQuotaLimit(ns, c.ProjectLister)
 	if err != nil {
 		return ns, err
 	}
@@ -137,6 +141,16 @@ func (c *SyncController) CreateResourceQuota(ns *corev1.Namespace) (runtime.Obje
 		return ns, err
 	}
 
+	// a namespace joining a project has to be validated against the quota of the project even if its own quota is unchanged
+	projectChanged := existing != nil && quotaProjectID(existing) != "" && quotaProjectID(existing) != projectID
+	if projectChanged && quotaSpec != nil {
+		// the quota of the namespace was granted by its previous project, it gets the namespace default quota of the new one
+		quotaToUpdate, err = c.getProjectDefaultQuotaToUpdate(ns)
+		if err != nil {
+			return ns, err
+		}
+	}
+
 	operation := "none"
 	if existing == nil {
 		if quotaSpec != nil {
@@ -145,8 +159,10 @@ func (c *SyncController) CreateResourceQuota(ns *corev1.Namespace) (runtime.Obje
 	} else {
 		if quotaSpec == nil {
 			operation = "delete"
-		} else if quotaToUpdate != "" || !apiequality.Semantic.DeepEqual(existing.Spec.Hard, quotaSpec.Hard) {
+		} else if quotaToUpdate != "" || projectChanged || !apiequality.Semantic.DeepEqual(existing.Spec.Hard, quotaSpec.Hard) {
 			operation = "update"
+		} else if quotaProjectID(existing) == "" {
+			operation = "adopt"
 		}
 	}
 
@@ -165,13 +181,29 @@ func (c *SyncController) CreateResourceQuota(ns *corev1.Namespace) (runtime.Obje
 				return updated, err
 			}
 		}
-		err = c.createDefaultResourceQuota(ns, quotaSpec)
+		err = c.createDefaultResourceQuota(ns, quotaSpec, projectID)
 	case "update":
 		isFit, updated, err = c.validateAndSetNamespaceQuota(ns, quotaToUpdate)
-		if err != nil || !isFit {
+		if err != nil {
+			return updated, err
+		}
+		if isFit {
+			// the quota set on the namespace by the validation
+			quotaSpec, err = c.getNamespaceResourceQuota(updated)
+		} else {
+			if !projectChanged {
+				return updated, nil
+			}
+			// the quota granted by the previous project doesn't apply to the new one
+			quotaSpec, err = getDefaultQuotaSpec()
+		}
+		if err != nil {
 			return updated, err
 		}
-		err = c.updateResourceQuota(existing, quotaSpec)
+		err = c.updateResourceQuota(existing, quotaSpec, projectID)
+	case "adopt":
+		// quotas created before the project was recorded on them belong to the project the namespace is in
+		err = c.updateResourceQuota(existing, &existing.Spec, projectID)
 	case "delete":
 		err = c.deleteResourceQuota(existing)
 	}
@@ -194,9 +226,13 @@ func (c *SyncController) CreateResourceQuota(ns *corev1.Namespace) (runtime.Obje
 
 }
 
-func (c *SyncController) updateResourceQuota(quota *corev1.ResourceQuota, spec *corev1.ResourceQuotaSpec) error {
+func (c *SyncController) updateResourceQuota(quota *corev1.ResourceQuota, spec *corev1.ResourceQuotaSpec, projectID string) error {
 	toUpdate := quota.DeepCopy()
 	toUpdate.Spec = *spec
+	if toUpdate.Annotations == nil {
+		toUpdate.Annotations = map[string]string{}
+	}
+	toUpdate.Annotations[resourceQuotaProjectAnnotation] = projectID
 	logrus.Infof("Updating default resource quota for namespace %v", toUpdate.Namespace)
 	_, err := c.ResourceQuotas.Update(toUpdate)
 	return err
@@ -276,12 +312,13 @@ func getDefaultQuotaSpec() (*corev1.ResourceQuotaSpec, error) {
 	LimitsMemory:           "0",
 }
 
-func (c *SyncController) createDefaultResourceQuota(ns *corev1.Namespace, spec *corev1.ResourceQuotaSpec) error {
+func (c *SyncController) createDefaultResourceQuota(ns *corev1.Namespace, spec *corev1.ResourceQuotaSpec, projectID string) error {
 	resourceQuota := &corev1.ResourceQuota{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: "default-",
 			Namespace:    ns.Name,
 			Labels:       map[string]string{resourceQuotaLabel: "true"},
+			Annotations:  map[string]string{resourceQuotaProjectAnnotation: projectID},
 		},
 		Spec: *spec,
 	}
@@ -343,8 +380,8 @@ func (c *SyncController) validateAndSetNamespaceQuota(ns *corev1.Namespace, quot
 	var nsLimits []*v32.ResourceQuotaLimit
 	for _, o := range objects {
 		other := o.(*corev1.Namespace)
-		// skip itself
-		if other.Name == ns.Name {
+		// skip itself and namespaces releasing their quota
+		if other.Name == ns.Name || other.DeletionTimestamp != nil {
 			continue
 		}
 		nsLimit, err := getNamespaceResourceQuotaLimit(other)
@@ -382,7 +419,19 @@ func (c *SyncController) setValidated(ns *corev1.Namespace, value bool, msg stri
 	if err != nil {
 		return ns, err
 	}
-	return c.Namespaces.Update(toUpdate)
+	updated, err := c.Namespaces.Update(toUpdate)
+	if err != nil {
+		return updated, err
+	}
+	if !value {
+		c.EventRecorder.Event(updated, corev1.EventTypeWarning, resourceQuotaExceededReason, msg)
+	}
+	return updated, nil
+}
+
+// quotaProjectID returns the project the default resource quota was last validated against
+func quotaProjectID(quota *corev1.ResourceQuota) string {
+	return quota.Annotations[resourceQuotaProjectAnnotation]
 }
 
 func (c *SyncController) getResourceQuotaToUpdate(ns *corev1.Namespace) (string, error) {
@@ -423,6 +472,20 @@ func (c *SyncController) getResourceQuotaToUpdate(ns *corev1.Namespace) (string,
 	return string(b), nil
 }
 
+// getProjectDefaultQuotaToUpdate returns the namespace default quota of the project of the namespace, which replaces
+// the quota of a namespace moved into the project
+func (c *SyncController) getProjectDefaultQuotaToUpdate(ns *corev1.Namespace) (string, error) {
+	defaultQuota, err := getProjectNamespaceDefaultQuota(ns, c.ProjectLister)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(defaultQuota)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 func (c *SyncController) getResourceLimitToUpdate(ns *corev1.Namespace) (*corev1.LimitRangeSpec, error) {
 	nsLimit, err := getNamespaceContainerResourceLimit(ns)
 	if err != nil {
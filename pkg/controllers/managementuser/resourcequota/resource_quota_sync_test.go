@@ -3,13 +3,23 @@
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	namespaceutil "github.com/rancher/rancher/pkg/namespace"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientcache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestLimitsChanged(t *testing.T) {
@@ -107,3 +117,215 @@ func TestSemanticDeepEqual(t *testing.T) {
 	}
 
 }
+
+func newProject(name, limitCPU string) *v3.Project {
+	return &v3.Project{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c-1", Name: name},
+		Spec: v32.ProjectSpec{
+			ResourceQuota: &v32.ProjectResourceQuota{
+				Limit: v32.ResourceQuotaLimit{RequestsCPU: limitCPU},
+			},
+			NamespaceDefaultResourceQuota: &v32.NamespaceResourceQuota{
+				Limit: v32.ResourceQuotaLimit{RequestsCPU: "100m"},
+			},
+		},
+	}
+}
+
+func newNamespace(t *testing.T, name, projectID, requestsCPU string, validated bool) *corev1.Namespace {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				projectIDAnnotation:     projectID,
+				resourceQuotaAnnotation: `{"limit":{"requestsCpu":"` + requestsCPU + `"}}`,
+			},
+		},
+	}
+	if validated {
+		require.NoError(t, namespaceutil.SetNamespaceCondition(ns, time.Second, ResourceQuotaValidatedCondition, true, ""))
+	}
+	return ns
+}
+
+func newNsIndexer(t *testing.T, namespaces ...*corev1.Namespace) clientcache.Indexer {
+	indexer := clientcache.NewIndexer(clientcache.MetaNamespaceKeyFunc, clientcache.Indexers{nsByProjectIndex: nsByProjectID})
+	for _, ns := range namespaces {
+		require.NoError(t, indexer.Add(ns))
+	}
+	return indexer
+}
+
+func newProjectLister(projects ...*v3.Project) *fakes.ProjectListerMock {
+	return &fakes.ProjectListerMock{
+		GetFunc: func(namespace string, name string) (*v3.Project, error) {
+			for _, p := range projects {
+				if p.Namespace == namespace && p.Name == name {
+					return p, nil
+				}
+			}
+			return nil, nil
+		},
+	}
+}
+
+type syncTest struct {
+	controller *SyncController
+	recorder   *record.FakeRecorder
+	created    *corev1.ResourceQuota
+	updated    *corev1.ResourceQuota
+}
+
+func newSyncTest(t *testing.T, existing *corev1.ResourceQuota, project *v3.Project, namespaces ...*corev1.Namespace) *syncTest {
+	s := &syncTest{recorder: record.NewFakeRecorder(10)}
+	s.controller = &SyncController{
+		ProjectLister: newProjectLister(project),
+		Namespaces: &corefakes.NamespaceInterfaceMock{
+			UpdateFunc: func(in1 *corev1.Namespace) (*corev1.Namespace, error) {
+				return in1, nil
+			},
+		},
+		ResourceQuotas: &corefakes.ResourceQuotaInterfaceMock{
+			CreateFunc: func(in1 *corev1.ResourceQuota) (*corev1.ResourceQuota, error) {
+				s.created = in1
+				return in1, nil
+			},
+			UpdateFunc: func(in1 *corev1.ResourceQuota) (*corev1.ResourceQuota, error) {
+				s.updated = in1
+				return in1, nil
+			},
+		},
+		ResourceQuotaLister: &corefakes.ResourceQuotaListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.ResourceQuota, error) {
+				if existing == nil {
+					return nil, nil
+				}
+				return []*corev1.ResourceQuota{existing}, nil
+			},
+		},
+		NsIndexer:     newNsIndexer(t, namespaces...),
+		EventRecorder: s.recorder,
+	}
+	return s
+}
+
+func defaultResourceQuota(projectID, requestsCPU string) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns-1",
+			Name:        "default-abcde",
+			Labels:      map[string]string{resourceQuotaLabel: "true"},
+			Annotations: map[string]string{resourceQuotaProjectAnnotation: projectID},
+		},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(requestsCPU)},
+		},
+	}
+}
+
+func TestCreateResourceQuotaAllocation(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    *corev1.ResourceQuota
+		others      []*corev1.Namespace
+		expectedCPU string
+		fits        bool
+	}{
+		{
+			name:        "namespace fits in the remaining project quota",
+			others:      []*corev1.Namespace{newNamespace(t, "ns-2", "c-1:p-1", "400m", true)},
+			expectedCPU: "500m",
+			fits:        true,
+		},
+		{
+			name:        "namespace exceeds the remaining project quota",
+			others:      []*corev1.Namespace{newNamespace(t, "ns-2", "c-1:p-1", "600m", true)},
+			expectedCPU: "0",
+		},
+		{
+			name:        "namespaces of other projects are not counted",
+			others:      []*corev1.Namespace{newNamespace(t, "ns-2", "c-1:p-2", "900m", true)},
+			expectedCPU: "500m",
+			fits:        true,
+		},
+		{
+			name:        "namespace joins a project with room for its default quota",
+			existing:    defaultResourceQuota("c-1:p-2", "500m"),
+			others:      []*corev1.Namespace{newNamespace(t, "ns-2", "c-1:p-1", "600m", true)},
+			expectedCPU: "100m",
+			fits:        true,
+		},
+		{
+			name:        "namespace joins a project without room for its default quota",
+			existing:    defaultResourceQuota("c-1:p-2", "500m"),
+			others:      []*corev1.Namespace{newNamespace(t, "ns-2", "c-1:p-1", "950m", true)},
+			expectedCPU: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := newNamespace(t, "ns-1", "c-1:p-1", "500m", false)
+			s := newSyncTest(t, tt.existing, newProject("p-1", "1"), append(tt.others, ns)...)
+
+			_, err := s.controller.CreateResourceQuota(ns)
+			require.NoError(t, err)
+
+			quota := s.created
+			if tt.existing != nil {
+				quota = s.updated
+			}
+			require.NotNil(t, quota)
+			cpu := quota.Spec.Hard[corev1.ResourceRequestsCPU]
+			assert.Equal(t, tt.expectedCPU, cpu.String())
+			assert.Equal(t, "c-1:p-1", quota.Annotations[resourceQuotaProjectAnnotation])
+
+			if tt.fits {
+				assert.Empty(t, s.recorder.Events)
+			} else {
+				require.Len(t, s.recorder.Events, 1)
+				assert.Contains(t, <-s.recorder.Events, resourceQuotaExceededReason)
+			}
+		})
+	}
+}
+
+func TestCreateResourceQuotaTerminatingNamespace(t *testing.T) {
+	terminating := newNamespace(t, "ns-2", "c-1:p-1", "900m", true)
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	ns := newNamespace(t, "ns-1", "c-1:p-1", "500m", false)
+	s := newSyncTest(t, nil, newProject("p-1", "1"), terminating, ns)
+
+	_, err := s.controller.CreateResourceQuota(ns)
+	require.NoError(t, err)
+
+	require.NotNil(t, s.created)
+	cpu := s.created.Spec.Hard[corev1.ResourceRequestsCPU]
+	assert.Equal(t, "500m", cpu.String(), "expected the quota of a terminating namespace to be released")
+}
+
+func TestCreateResourceQuotaLoweredProjectLimit(t *testing.T) {
+	ns := newNamespace(t, "ns-1", "c-1:p-1", "500m", true)
+	other := newNamespace(t, "ns-2", "c-1:p-1", "500m", true)
+	s := newSyncTest(t, defaultResourceQuota("c-1:p-1", "500m"), newProject("p-1", "600m"), ns, other)
+
+	_, err := s.controller.CreateResourceQuota(ns)
+	require.NoError(t, err)
+
+	assert.Nil(t, s.updated, "expected the quota of the namespace to be kept when the project limit is lowered")
+	assert.Empty(t, s.recorder.Events)
+}
+
+func TestCreateResourceQuotaAdopt(t *testing.T) {
+	existing := defaultResourceQuota("", "500m")
+	delete(existing.Annotations, resourceQuotaProjectAnnotation)
+	ns := newNamespace(t, "ns-1", "c-1:p-1", "500m", true)
+	s := newSyncTest(t, existing, newProject("p-1", "1"), ns)
+
+	_, err := s.controller.CreateResourceQuota(ns)
+	require.NoError(t, err)
+
+	require.NotNil(t, s.updated)
+	assert.Equal(t, "c-1:p-1", s.updated.Annotations[resourceQuotaProjectAnnotation])
+	assert.Equal(t, existing.Spec, s.updated.Spec)
+}
@@ -9,6 +9,8 @@
 	"fmt"
 
 	"github.com/rancher/norman/controller"
+	"github.com/rancher/norman/lifecycle"
+	"github.com/rancher/rancher/pkg/controllers/managementuser/registrysecret"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -58,8 +60,8 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 	}
 	cluster.Core.Namespaces("").AddHandler(ctx, "secretsController", n.sync)
 
-	sync := v1.NewSecretLifecycleAdapter(fmt.Sprintf("secretsController_%s", cluster.ClusterName), true,
-		cluster.Management.Core.Secrets(""), s)
+	lifecycleName := fmt.Sprintf("secretsController_%s", cluster.ClusterName)
+	sync := v1.NewSecretLifecycleAdapter(lifecycleName, true, cluster.Management.Core.Secrets(""), s)
 
 	cluster.Management.Core.Secrets("").AddHandler(ctx, "secretsController", func(key string, obj *corev1.Secret) (runtime.Object, error) {
 		if obj == nil {
@@ -76,6 +78,13 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 			return nil, nil
 		}
 
+		// registry secrets are propagated by the registrysecret controller, which also removes their
+		// copies, so only remove the finalizer the lifecycle added to them before
+		if registrysecret.IsRegistrySecret(obj) {
+			logrus.Tracef("secretsController: AddHandler: obj [%s] is a registry secret, removing its finalizer", obj.Name)
+			return removeFinalizer(cluster.Management.Core.Secrets(""), obj, lifecycle.ScopedFinalizerKey+lifecycleName)
+		}
+
 		if obj.Labels != nil {
 			if obj.Labels["cattle.io/creator"] == "norman" {
 				logrus.Tracef("secretsController: AddHandler: obj [%s] labels in [%s] contain cattle.io/creator=norman, calling sync", obj.Name, cluster.ClusterName)
@@ -114,6 +123,10 @@ func (n *NamespaceController) sync(key string, obj *corev1.Namespace) (runtime.O
 					logrus.Tracef("secretsController: AddHandler: secret [%s] is Service Account token, skipping", secret.Name)
 					continue
 				}
+				// registry secrets are propagated by the registrysecret controller
+				if registrysecret.IsRegistrySecret(secret) {
+					continue
+				}
 				namespacedSecret := getNamespacedSecret(secret, obj.Name)
 				logrus.Infof("Creating secret [%s] into namespace [%s]", namespacedSecret.Name, obj.Name)
 				_, err := n.clusterSecretsClient.Create(namespacedSecret)
@@ -237,6 +250,21 @@ func (s *Controller) createOrUpdate(obj *corev1.Secret, action string) error {
 	return nil
 }
 
+func removeFinalizer(secrets v1.SecretInterface, obj *corev1.Secret, finalizer string) (runtime.Object, error) {
+	var finalizers []string
+	for _, f := range obj.Finalizers {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	if len(finalizers) == len(obj.Finalizers) {
+		return obj, nil
+	}
+	obj = obj.DeepCopy()
+	obj.Finalizers = finalizers
+	return secrets.Update(obj)
+}
+
 func getNamespacedSecret(obj *corev1.Secret, namespace string) *corev1.Secret {
 	namespacedSecret := &corev1.Secret{}
 	namespacedSecret.Name = obj.Name
@@ -241,6 +241,13 @@ func (h *handler) createNewCluster(cluster *v1.Cluster, status v1.ClusterStatus,
 			CACerts: cluster.Spec.RKEConfig.LocalClusterAuthEndpoint.CACerts,
 			Enabled: cluster.Spec.RKEConfig.LocalClusterAuthEndpoint.Enabled,
 		}
+		if script := cluster.Spec.RKEConfig.AgentInstallScript; script != nil {
+			spec.AgentInstallScript = &v3.AgentInstallScript{
+				URL:          script.URL,
+				ConfigMapRef: script.ConfigMapRef.DeepCopy(),
+				SHA256:       script.SHA256,
+			}
+		}
 	}
 
 	newCluster := &v3.Cluster{
@@ -59,7 +59,7 @@ func (h *handler) deployAgent(cluster *v1.Cluster, status v1.ClusterStatus) (boo
 		return false, err
 	}
 
-	tokenValue := tokens[0].Status.Token
+	tokenValue := tokens[0].AgentToken()
 	if tokenValue == "" {
 		h.clusters.EnqueueAfter(cluster.Namespace, cluster.Name, 2*time.Second)
 		return false, nil
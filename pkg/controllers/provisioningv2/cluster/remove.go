@@ -96,7 +96,8 @@ func (h *handler) doClusterRemove(cluster *v1.Cluster) (string, error) {
 
 	_, err = h.capiClusters.Get(cluster.Namespace, cluster.Name)
 	if apierrors.IsNotFound(err) {
-		return "", nil
+		// the kubeconfig user of the cluster is only needed until everything else is removed
+		return "", h.kubeconfigManager.DeleteUser(cluster)
 	}
 	return fmt.Sprintf("waiting for cluster-api cluster [%s] to delete", cluster.Name), nil
 }
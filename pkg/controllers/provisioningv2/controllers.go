@@ -9,8 +9,10 @@
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/managedchart"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/managedos"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/bootstrap"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/clusterprofile"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/dynamicschema"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machinedrain"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machineinfra"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machinenodelookup"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machineorphan"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machineprovision"
@@ -47,6 +49,7 @@ func Register(ctx context.Context, clients *wrangler.Context) error {
 		}
 		rkecluster.Register(ctx, clients)
 		provisioningcluster.Register(ctx, clients)
+		clusterprofile.Register(ctx, clients)
 		bootstrap.Register(ctx, clients)
 		machinenodelookup.Register(ctx, clients)
 		planner.Register(ctx, clients, rkePlanner)
@@ -57,6 +60,7 @@ func Register(ctx context.Context, clients *wrangler.Context) error {
 		managesystemagent.Register(ctx, clients)
 		machinedrain.Register(ctx, clients)
 		machineorphan.Register(ctx, clients)
+		machineinfra.Register(ctx, clients)
 	}
 
 	if features.EmbeddedClusterAPI.Enabled() {
@@ -64,13 +68,13 @@ func Register(ctx context.Context, clients *wrangler.Context) error {
 		if err != nil {
 			return err
 		}
-		clients.OnLeader(func(ctx context.Context) error {
+		clients.OnLeader(clients.Readiness.Track(func(ctx context.Context) error {
 			if err := capiStart(ctx); err != nil {
 				logrus.Fatal(err)
 			}
 			logrus.Info("Cluster API is started")
 			return nil
-		})
+		}))
 	}
 
 	return nil
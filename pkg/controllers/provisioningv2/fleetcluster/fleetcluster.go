@@ -9,6 +9,7 @@
 	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/systemtemplate"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/generic"
@@ -100,7 +101,7 @@ func (h *handler) createCluster(cluster *v1.Cluster, status v1.ClusterStatus) ([
 		},
 		Spec: fleet.ClusterSpec{
 			KubeConfigSecret: status.ClientSecretName,
-			AgentEnvVars:     mgmtCluster.Spec.AgentEnvVars,
+			AgentEnvVars:     systemtemplate.LiteralEnvVars(mgmtCluster),
 		},
 	}}, status, nil
 }
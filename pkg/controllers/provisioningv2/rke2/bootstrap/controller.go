@@ -4,6 +4,8 @@
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
@@ -20,6 +22,7 @@
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
@@ -30,6 +33,7 @@
 	planSecret       = "rke.cattle.io/plan-secret-name"
 	roleLabel        = "rke.cattle.io/service-account-role"
 	rkeBootstrapName = "rke.cattle.io/rkebootstrap-name"
+	envVarsHash      = "env-vars-hash"
 	roleBootstrap    = "bootstrap"
 	rolePlan         = "plan"
 )
@@ -41,6 +45,7 @@
 type handler struct {
 	serviceAccountCache corecontrollers.ServiceAccountCache
 	secretCache         corecontrollers.SecretCache
+	configMapCache      corecontrollers.ConfigMapCache
 	machineCache        capicontrollers.MachineCache
 	capiClusters        capicontrollers.ClusterCache
 	rkeControlPlanes    rkecontroller.RKEControlPlaneCache
@@ -50,6 +55,7 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 	h := &handler{
 		serviceAccountCache: clients.Core.ServiceAccount().Cache(),
 		secretCache:         clients.Core.Secret().Cache(),
+		configMapCache:      clients.Core.ConfigMap().Cache(),
 		machineCache:        clients.CAPI.Machine().Cache(),
 		capiClusters:        clients.CAPI.Cluster().Cache(),
 		rkeControlPlanes:    clients.RKE.RKEControlPlane().Cache(),
@@ -81,18 +87,78 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 			}
 		}
 		if machine, ok := obj.(*capi.Machine); ok {
-			if machine.Spec.Bootstrap.ConfigRef != nil && machine.Spec.Bootstrap.ConfigRef.Kind == "RKEBootstrap" {
-				return []relatedresource.Key{{
-					Namespace: machine.Namespace,
-					Name:      machine.Spec.Bootstrap.ConfigRef.Name,
-				}}, nil
+			if key, ok := bootstrapKey(machine); ok {
+				return []relatedresource.Key{key}, nil
 			}
 		}
+		if cp, ok := obj.(*rkev1.RKEControlPlane); ok {
+			return h.bootstrapsForControlPlane(cp)
+		}
 		return nil, nil
-	}, clients.RKE.RKEBootstrap(), clients.Core.ServiceAccount(), clients.CAPI.Machine())
+	}, clients.RKE.RKEBootstrap(), clients.Core.ServiceAccount(), clients.CAPI.Machine(), clients.RKE.RKEControlPlane())
+
+	clients.RKE.RKEControlPlane().Cache().AddIndexer(byAgentEnvVarSecret, byAgentEnvVarSecretIndex)
+	relatedresource.Watch(ctx, "rke-machine-agent-env-var-trigger", h.bootstrapsForAgentEnvVarSecret,
+		clients.RKE.RKEBootstrap(), clients.Core.Secret())
+
+	clients.RKE.RKEControlPlane().Cache().AddIndexer(byAgentInstallScriptConfigMap, byAgentInstallScriptConfigMapIndex)
+	relatedresource.Watch(ctx, "rke-machine-agent-install-script-trigger", h.bootstrapsForAgentInstallScriptConfigMap,
+		clients.RKE.RKEBootstrap(), clients.Core.ConfigMap())
+}
+
+func bootstrapKey(machine *capi.Machine) (relatedresource.Key, bool) {
+	if machine.Spec.Bootstrap.ConfigRef == nil || machine.Spec.Bootstrap.ConfigRef.Kind != "RKEBootstrap" {
+		return relatedresource.Key{}, false
+	}
+	return relatedresource.Key{
+		Namespace: machine.Namespace,
+		Name:      machine.Spec.Bootstrap.ConfigRef.Name,
+	}, true
 }
 
-func (h *handler) getBootstrapSecret(namespace, name string, envVars []corev1.EnvVar) (*corev1.Secret, error) {
+// bootstrapsForControlPlane enqueues the bootstraps of the machines of the cluster of the control plane, so that
+// their bootstrap secrets are regenerated when the agent env vars or the install script source change
+func (h *handler) bootstrapsForControlPlane(cp *rkev1.RKEControlPlane) ([]relatedresource.Key, error) {
+	capiClusters, err := h.capiClusters.List(cp.Namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, capiCluster := range capiClusters {
+		ref := capiCluster.Spec.ControlPlaneRef
+		if ref == nil || ref.Kind != "RKEControlPlane" || ref.Name != cp.Name {
+			continue
+		}
+
+		machines, err := h.machineCache.List(cp.Namespace, labels.SelectorFromSet(map[string]string{
+			capi.ClusterLabelName: capiCluster.Name,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		for _, machine := range machines {
+			if key, ok := bootstrapKey(machine); ok {
+				result = append(result, key)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// hashEnvVars returns a hash of the env vars written in the bootstrap secret, so that a change of the agent env vars
+// of the control plane always changes the secret
+func hashEnvVars(envVars []corev1.EnvVar) (string, error) {
+	data, err := json.Marshal(envVars)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (h *handler) getBootstrapSecret(namespace, name string, envVars []corev1.EnvVar, source *installer.Source) (*corev1.Secret, error) {
 	sa, err := h.serviceAccountCache.Get(namespace, name)
 	if apierror.IsNotFound(err) {
 		return nil, nil
@@ -109,7 +175,12 @@ func (h *handler) getBootstrapSecret(namespace, name string, envVars []corev1.En
 		}
 
 		hash := sha256.Sum256(secret.Data["token"])
-		data, err := installer.InstallScript(base64.URLEncoding.EncodeToString(hash[:]), envVars)
+		data, err := installer.InstallScriptFromSource(source, base64.URLEncoding.EncodeToString(hash[:]), envVars)
+		if err != nil {
+			return nil, err
+		}
+
+		envHash, err := hashEnvVars(envVars)
 		if err != nil {
 			return nil, err
 		}
@@ -120,7 +191,8 @@ func (h *handler) getBootstrapSecret(namespace, name string, envVars []corev1.En
 				Namespace: namespace,
 			},
 			Data: map[string][]byte{
-				"value": data,
+				"value":     data,
+				envVarsHash: []byte(envHash),
 			},
 			Type: "rke.cattle.io/bootstrap",
 		}, nil
@@ -204,7 +276,7 @@ func (h *handler) getMachine(obj *rkev1.RKEBootstrap) (*capi.Machine, error) {
 	return nil, generic.ErrSkip
 }
 
-func (h *handler) getEnvVar(machine *capi.Machine) ([]corev1.EnvVar, error) {
+func (h *handler) getControlPlane(machine *capi.Machine) (*rkev1.RKEControlPlane, error) {
 	capiCluster, err := h.capiClusters.Get(machine.Namespace, machine.Spec.ClusterName)
 	if apierror.IsNotFound(err) {
 		return nil, nil
@@ -216,12 +288,7 @@ func (h *handler) getEnvVar(machine *capi.Machine) ([]corev1.EnvVar, error) {
 		return nil, nil
 	}
 
-	cp, err := h.rkeControlPlanes.Get(machine.Namespace, capiCluster.Spec.ControlPlaneRef.Name)
-	if err != nil {
-		return nil, err
-	}
-
-	return cp.Spec.AgentEnvVars, nil
+	return h.rkeControlPlanes.Get(machine.Namespace, capiCluster.Spec.ControlPlaneRef.Name)
 }
 
 func (h *handler) assignBootStrapSecret(machine *capi.Machine, obj *rkev1.RKEBootstrap) (*corev1.Secret, []runtime.Object, error) {
@@ -232,10 +299,24 @@ func (h *handler) assignBootStrapSecret(machine *capi.Machine, obj *rkev1.RKEBoo
 		return nil, nil, nil
 	}
 
-	envVars, err := h.getEnvVar(machine)
+	var (
+		envVars []corev1.EnvVar
+		source  *installer.Source
+	)
+	cp, err := h.getControlPlane(machine)
 	if err != nil {
 		return nil, nil, err
 	}
+	if cp != nil {
+		envVars, err = h.resolveEnvVars(cp.Namespace, cp.Spec.AgentEnvVars)
+		if err != nil {
+			return nil, nil, err
+		}
+		source, err = installer.ResolveSource(h.configMapCache, cp.Namespace, cp.Spec.AgentInstallScript)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
 	secretName := name.SafeConcatName(obj.Name, "machine", "bootstrap")
 
@@ -251,7 +332,7 @@ func (h *handler) assignBootStrapSecret(machine *capi.Machine, obj *rkev1.RKEBoo
 		},
 	}
 
-	bootstrapSecret, err := h.getBootstrapSecret(sa.Namespace, sa.Name, envVars)
+	bootstrapSecret, err := h.getBootstrapSecret(sa.Namespace, sa.Name, envVars, source)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,117 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/installer"
+	"github.com/rancher/rancher/pkg/settings"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeServiceAccountCache struct {
+	corecontrollers.ServiceAccountCache
+	serviceAccount *corev1.ServiceAccount
+}
+
+func (f fakeServiceAccountCache) Get(namespace, name string) (*corev1.ServiceAccount, error) {
+	return f.serviceAccount, nil
+}
+
+type fakeSecretCache struct {
+	corecontrollers.SecretCache
+	secret *corev1.Secret
+}
+
+func (f fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	return f.secret, nil
+}
+
+func TestGetBootstrapSecretEnvVars(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("echo install"))
+	}))
+	defer server.Close()
+
+	original := settings.SystemAgentInstallScript.Get()
+	defer settings.SystemAgentInstallScript.Set(original)
+	require.NoError(t, settings.SystemAgentInstallScript.Set(server.URL))
+
+	h := &handler{
+		serviceAccountCache: fakeServiceAccountCache{serviceAccount: &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine-bootstrap"},
+			Secrets:    []corev1.ObjectReference{{Name: "machine-bootstrap-token"}},
+		}},
+		secretCache: fakeSecretCache{secret: &corev1.Secret{
+			Data: map[string][]byte{"token": []byte("token")},
+		}},
+	}
+
+	secret, err := h.getBootstrapSecret("fleet-default", "machine-bootstrap", []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+	}, nil)
+	require.NoError(t, err)
+
+	unchanged, err := h.getBootstrapSecret("fleet-default", "machine-bootstrap", []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, secret.Data, unchanged.Data)
+
+	changed, err := h.getBootstrapSecret("fleet-default", "machine-bootstrap", []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://other-proxy.example.com"},
+	}, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, secret.Data["value"], changed.Data["value"])
+	assert.NotEqual(t, secret.Data[envVarsHash], changed.Data[envVarsHash])
+
+	// env vars without a value are not written in the install script, but still change the secret
+	emptyValue, err := h.getBootstrapSecret("fleet-default", "machine-bootstrap", []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+		{Name: "NO_PROXY"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, secret.Data["value"], emptyValue.Data["value"])
+	assert.NotEqual(t, secret.Data[envVarsHash], emptyValue.Data[envVarsHash])
+}
+
+func TestGetBootstrapSecretInstallScriptSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("echo install"))
+	}))
+	defer server.Close()
+
+	original := settings.SystemAgentInstallScript.Get()
+	defer settings.SystemAgentInstallScript.Set(original)
+	require.NoError(t, settings.SystemAgentInstallScript.Set(server.URL))
+
+	h := &handler{
+		serviceAccountCache: fakeServiceAccountCache{serviceAccount: &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine-bootstrap"},
+			Secrets:    []corev1.ObjectReference{{Name: "machine-bootstrap-token"}},
+		}},
+		secretCache: fakeSecretCache{secret: &corev1.Secret{
+			Data: map[string][]byte{"token": []byte("token")},
+		}},
+	}
+
+	secret, err := h.getBootstrapSecret("fleet-default", "machine-bootstrap", nil, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(secret.Data["value"]), "echo install")
+
+	inline, err := h.getBootstrapSecret("fleet-default", "machine-bootstrap", nil, &installer.Source{Content: []byte("echo air-gapped")})
+	require.NoError(t, err)
+	assert.Contains(t, string(inline.Data["value"]), "echo air-gapped")
+	assert.NotContains(t, string(inline.Data["value"]), "echo install", "expected the source of the cluster to replace the one of the settings")
+
+	_, err = h.getBootstrapSecret("fleet-default", "machine-bootstrap", nil, &installer.Source{
+		Content: []byte("echo air-gapped"),
+		SHA256:  "0000000000000000000000000000000000000000000000000000000000000000",
+	})
+	assert.Error(t, err, "expected an install script with another checksum to be rejected")
+}
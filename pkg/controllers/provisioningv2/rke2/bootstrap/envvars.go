@@ -0,0 +1,46 @@
+package bootstrap
+
+import (
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/installer"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const byAgentEnvVarSecret = "by-agent-env-var-secret"
+
+// resolveEnvVars returns the agent env vars with the values of the secrets they reference, so that the secrets are
+// only written in the bootstrap secret of the machines. The secrets are looked up in the namespace of the control plane.
+func (h *handler) resolveEnvVars(namespace string, envVars []corev1.EnvVar) ([]corev1.EnvVar, error) {
+	return installer.ResolveEnvVars(h.secretCache, namespace, envVars)
+}
+
+func byAgentEnvVarSecretIndex(obj *rkev1.RKEControlPlane) ([]string, error) {
+	var result []string
+	for _, envVar := range obj.Spec.AgentEnvVars {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+			result = append(result, obj.Namespace+"/"+envVar.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+	return result, nil
+}
+
+// bootstrapsForAgentEnvVarSecret enqueues the bootstraps of the machines of the control planes whose agent env vars
+// reference a secret when it changes
+func (h *handler) bootstrapsForAgentEnvVarSecret(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	cps, err := h.rkeControlPlanes.GetByIndex(byAgentEnvVarSecret, namespace+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, cp := range cps {
+		keys, err := h.bootstrapsForControlPlane(cp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, keys...)
+	}
+	return result, nil
+}
@@ -0,0 +1,160 @@
+package bootstrap
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/installer"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+type fakeSecrets struct {
+	corecontrollers.SecretCache
+	secrets map[string]*corev1.Secret
+}
+
+func (f fakeSecrets) Get(namespace, name string) (*corev1.Secret, error) {
+	if secret, ok := f.secrets[namespace+"/"+name]; ok {
+		return secret, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+type fakeControlPlanes struct {
+	rkecontroller.RKEControlPlaneCache
+	controlPlanes []*rkev1.RKEControlPlane
+}
+
+func (f fakeControlPlanes) GetByIndex(indexName, key string) (result []*rkev1.RKEControlPlane, _ error) {
+	for _, cp := range f.controlPlanes {
+		keys, _ := byAgentEnvVarSecretIndex(cp)
+		for _, k := range keys {
+			if k == key {
+				result = append(result, cp)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+type fakeCAPIClusters struct {
+	capicontrollers.ClusterCache
+	clusters []*capi.Cluster
+}
+
+func (f fakeCAPIClusters) List(namespace string, selector labels.Selector) ([]*capi.Cluster, error) {
+	return f.clusters, nil
+}
+
+type fakeMachines struct {
+	capicontrollers.MachineCache
+	machines []*capi.Machine
+}
+
+func (f fakeMachines) List(namespace string, selector labels.Selector) (result []*capi.Machine, _ error) {
+	for _, machine := range f.machines {
+		if selector.Matches(labels.Set(machine.Labels)) {
+			result = append(result, machine)
+		}
+	}
+	return result, nil
+}
+
+func secretEnvVar(name, secretName, key string, optional bool) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+				Optional:             &optional,
+			},
+		},
+	}
+}
+
+func TestResolveEnvVars(t *testing.T) {
+	h := &handler{
+		secretCache: fakeSecrets{secrets: map[string]*corev1.Secret{
+			"fleet-default/proxy": {
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{installer.AgentEnvVarSecretLabel: "true"}},
+				Data:       map[string][]byte{"password": []byte("s3cr3t")},
+			},
+			"fleet-default/other-kubeconfig": {Data: map[string][]byte{"value": []byte("kubeconfig")}},
+		}},
+	}
+
+	envVars, err := h.resolveEnvVars("fleet-default", []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+		secretEnvVar("PROXY_PASSWORD", "proxy", "password", false),
+		secretEnvVar("PROXY_USER", "proxy", "user", true),
+		secretEnvVar("NO_PROXY", "no-proxy", "value", true),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+		{Name: "PROXY_PASSWORD", Value: "s3cr3t"},
+	}, envVars, "expected the optional env vars without value to be left out")
+
+	_, err = h.resolveEnvVars("fleet-default", []corev1.EnvVar{secretEnvVar("PROXY_USER", "proxy", "user", false)})
+	assert.EqualError(t, err, "key [user] of secret [fleet-default/proxy] of agent env var [PROXY_USER] not found")
+
+	_, err = h.resolveEnvVars("fleet-default", []corev1.EnvVar{secretEnvVar("NO_PROXY", "no-proxy", "value", false)})
+	assert.True(t, apierror.IsNotFound(err), "expected a missing secret to fail the resolution")
+
+	_, err = h.resolveEnvVars("fleet-default", []corev1.EnvVar{{
+		Name:      "NODE_NAME",
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+	}})
+	assert.EqualError(t, err, "agent env var [NODE_NAME] may only reference the value of a secret")
+
+	_, err = h.resolveEnvVars("fleet-default", []corev1.EnvVar{secretEnvVar("KUBECONFIG", "other-kubeconfig", "value", true)})
+	assert.EqualError(t, err, "secret [fleet-default/other-kubeconfig] of agent env var [KUBECONFIG] is not labeled rke.cattle.io/agent-env-var=true",
+		"expected the secrets which aren't marked for agent env vars not to be readable")
+}
+
+func TestBootstrapsForAgentEnvVarSecret(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+		Spec: rkev1.RKEControlPlaneSpec{
+			AgentEnvVars: []corev1.EnvVar{secretEnvVar("PROXY_PASSWORD", "proxy", "password", false)},
+		},
+	}
+	h := &handler{
+		rkeControlPlanes: fakeControlPlanes{controlPlanes: []*rkev1.RKEControlPlane{cp}},
+		capiClusters: fakeCAPIClusters{clusters: []*capi.Cluster{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+			Spec:       capi.ClusterSpec{ControlPlaneRef: &corev1.ObjectReference{Kind: "RKEControlPlane", Name: "test"}},
+		}}},
+		machineCache: fakeMachines{machines: []*capi.Machine{{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "fleet-default",
+				Name:      "test-pool-abcde",
+				Labels:    map[string]string{capi.ClusterLabelName: "test"},
+			},
+			Spec: capi.MachineSpec{
+				Bootstrap: capi.Bootstrap{ConfigRef: &corev1.ObjectReference{Kind: "RKEBootstrap", Name: "test-pool-bootstrap-abcde"}},
+			},
+		}}},
+	}
+
+	keys, err := h.bootstrapsForAgentEnvVarSecret("fleet-default", "proxy", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []relatedresource.Key{{Namespace: "fleet-default", Name: "test-pool-bootstrap-abcde"}}, keys)
+
+	keys, err = h.bootstrapsForAgentEnvVarSecret("fleet-default", "other", nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "expected the secrets which aren't referenced not to enqueue any bootstrap")
+}
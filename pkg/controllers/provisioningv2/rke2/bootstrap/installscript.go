@@ -0,0 +1,36 @@
+package bootstrap
+
+import (
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const byAgentInstallScriptConfigMap = "by-agent-install-script-configmap"
+
+func byAgentInstallScriptConfigMapIndex(obj *rkev1.RKEControlPlane) ([]string, error) {
+	script := obj.Spec.AgentInstallScript
+	if script == nil || script.ConfigMapRef == nil {
+		return nil, nil
+	}
+	return []string{obj.Namespace + "/" + script.ConfigMapRef.Name}, nil
+}
+
+// bootstrapsForAgentInstallScriptConfigMap enqueues the bootstraps of the machines of the control planes whose install
+// script is read from a ConfigMap when it changes, so that the pending bootstrap secrets use the new script
+func (h *handler) bootstrapsForAgentInstallScriptConfigMap(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	cps, err := h.rkeControlPlanes.GetByIndex(byAgentInstallScriptConfigMap, namespace+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, cp := range cps {
+		keys, err := h.bootstrapsForControlPlane(cp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, keys...)
+	}
+	return result, nil
+}
@@ -0,0 +1,200 @@
+package clusterprofile
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/condition"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	byClusterProfile = "by-cluster-profile"
+	Validated        = condition.Cond("Validated")
+	Drifted          = condition.Cond("Drifted")
+)
+
+type handler struct {
+	clusters            rocontrollers.ClusterClient
+	clusterCache        rocontrollers.ClusterCache
+	clusterProfileCache rocontrollers.ClusterProfileCache
+}
+
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := handler{
+		clusters:            clients.Provisioning.Cluster(),
+		clusterCache:        clients.Provisioning.Cluster().Cache(),
+		clusterProfileCache: clients.Provisioning.ClusterProfile().Cache(),
+	}
+
+	clients.Provisioning.Cluster().Cache().AddIndexer(byClusterProfile, byClusterProfileIndex)
+
+	rocontrollers.RegisterClusterProfileStatusHandler(ctx,
+		clients.Provisioning.ClusterProfile(),
+		Validated,
+		"cluster-profile-revision",
+		h.OnProfileChange)
+	clients.Provisioning.Cluster().OnChange(ctx, "cluster-profile", h.OnClusterChange)
+
+	relatedresource.Watch(ctx, "cluster-profile-trigger", h.clustersForProfile,
+		clients.Provisioning.Cluster(), clients.Provisioning.ClusterProfile())
+}
+
+func byClusterProfileIndex(obj *rancherv1.Cluster) ([]string, error) {
+	if obj.Spec.ClusterProfileName == "" {
+		return nil, nil
+	}
+	return []string{obj.Namespace + "/" + obj.Spec.ClusterProfileName}, nil
+}
+
+func (h *handler) clustersForProfile(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	clusters, err := h.clusterCache.GetByIndex(byClusterProfile, namespace+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, cluster := range clusters {
+		result = append(result, relatedresource.Key{
+			Namespace: cluster.Namespace,
+			Name:      cluster.Name,
+		})
+	}
+	return result, nil
+}
+
+// OnProfileChange increments the revision of the profile when its kubernetes version range or its template change
+func (h *handler) OnProfileChange(profile *rancherv1.ClusterProfile, status rancherv1.ClusterProfileStatus) (rancherv1.ClusterProfileStatus, error) {
+	if profile.Spec.KubernetesVersionRange != "" {
+		if _, err := semver.NewConstraint(profile.Spec.KubernetesVersionRange); err != nil {
+			return status, fmt.Errorf("invalid kubernetes version range %q: %w", profile.Spec.KubernetesVersionRange, err)
+		}
+	}
+
+	hash, err := profileHash(profile.Spec)
+	if err != nil {
+		return status, err
+	}
+
+	if status.Hash != hash {
+		status.Revision++
+		status.Hash = hash
+	}
+	status.ObservedGeneration = profile.Generation
+	return status, nil
+}
+
+// OnClusterChange renders the spec of a cluster from its profile when the cluster starts using the profile, or when
+// the rollout of a new revision of the profile is confirmed. Otherwise it only reports if the cluster drifted from
+// the profile.
+func (h *handler) OnClusterChange(key string, cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	if cluster == nil || !cluster.DeletionTimestamp.IsZero() || cluster.Spec.ClusterProfileName == "" {
+		return cluster, nil
+	}
+
+	profile, err := h.clusterProfileCache.Get(cluster.Namespace, cluster.Spec.ClusterProfileName)
+	if apierror.IsNotFound(err) {
+		return h.setDriftUnknown(cluster, "ProfileNotFound", fmt.Sprintf("cluster profile %s not found", cluster.Spec.ClusterProfileName))
+	} else if err != nil {
+		return cluster, err
+	}
+
+	if profile.Status.Revision == 0 {
+		// the profile is enqueued once its first revision is set
+		return cluster, nil
+	}
+
+	revision := renderedRevision(cluster)
+	if revision == 0 || (revision < profile.Status.Revision && profile.Spec.RolloutRevision == profile.Status.Revision) {
+		return h.render(cluster, profile)
+	}
+
+	return h.updateDrift(cluster, profile, revision)
+}
+
+// renderedRevision returns the revision of the profile the spec of the cluster was last rendered from, or 0 if it wasn't
+// rendered from the profile it uses
+func renderedRevision(cluster *rancherv1.Cluster) int64 {
+	if cluster.Annotations[rancherv1.ClusterProfileAnnotation] != cluster.Spec.ClusterProfileName {
+		return 0
+	}
+	revision, err := strconv.ParseInt(cluster.Annotations[rancherv1.ClusterProfileRevisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+func (h *handler) render(cluster *rancherv1.Cluster, profile *rancherv1.ClusterProfile) (*rancherv1.Cluster, error) {
+	spec, err := Render(cluster.Spec, profile)
+	if err != nil {
+		return h.setDriftUnknown(cluster, "RenderFailed", err.Error())
+	}
+
+	logrus.Infof("[clusterprofile] rendering cluster %s/%s from revision %d of cluster profile %s",
+		cluster.Namespace, cluster.Name, profile.Status.Revision, profile.Name)
+
+	cluster = cluster.DeepCopy()
+	cluster.Spec = *spec
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[rancherv1.ClusterProfileAnnotation] = profile.Name
+	cluster.Annotations[rancherv1.ClusterProfileRevisionAnnotation] = strconv.FormatInt(profile.Status.Revision, 10)
+	return h.clusters.Update(cluster)
+}
+
+func (h *handler) updateDrift(cluster *rancherv1.Cluster, profile *rancherv1.ClusterProfile, revision int64) (*rancherv1.Cluster, error) {
+	status := cluster.Status.DeepCopy()
+
+	if revision < profile.Status.Revision {
+		Drifted.True(status)
+		Drifted.Reason(status, "RolloutPending")
+		Drifted.Message(status, fmt.Sprintf("cluster is on revision %d of cluster profile %s, revision %d is waiting for its rollout to be confirmed",
+			revision, profile.Name, profile.Status.Revision))
+		return h.updateStatus(cluster, status)
+	}
+
+	spec, err := Render(cluster.Spec, profile)
+	if err != nil {
+		return h.setDriftUnknown(cluster, "RenderFailed", err.Error())
+	}
+
+	if fields := driftedFields(&cluster.Spec, spec); len(fields) > 0 {
+		Drifted.True(status)
+		Drifted.Reason(status, "Modified")
+		Drifted.Message(status, fmt.Sprintf("fields managed by cluster profile %s were modified: %s", profile.Name, strings.Join(fields, ", ")))
+	} else {
+		Drifted.False(status)
+		Drifted.Reason(status, "")
+		Drifted.Message(status, "")
+	}
+	return h.updateStatus(cluster, status)
+}
+
+func (h *handler) setDriftUnknown(cluster *rancherv1.Cluster, reason, message string) (*rancherv1.Cluster, error) {
+	status := cluster.Status.DeepCopy()
+	Drifted.Unknown(status)
+	Drifted.Reason(status, reason)
+	Drifted.Message(status, message)
+	return h.updateStatus(cluster, status)
+}
+
+func (h *handler) updateStatus(cluster *rancherv1.Cluster, status *rancherv1.ClusterStatus) (*rancherv1.Cluster, error) {
+	if equality.Semantic.DeepEqual(&cluster.Status, status) {
+		return cluster, nil
+	}
+	cluster = cluster.DeepCopy()
+	cluster.Status = *status
+	return h.clusters.UpdateStatus(cluster)
+}
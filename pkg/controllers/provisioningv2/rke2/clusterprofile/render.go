@@ -0,0 +1,151 @@
+package clusterprofile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// Render returns the spec of a cluster with the fields managed by the profile set from the template of the profile.
+// The other fields of the spec are the parameters of the cluster and are left as is. Agent env vars and control plane
+// config keys that the profile doesn't set are kept, so clusters can add their own.
+func Render(spec rancherv1.ClusterSpec, profile *rancherv1.ClusterProfile) (*rancherv1.ClusterSpec, error) {
+	if spec.RKEConfig == nil {
+		return nil, fmt.Errorf("cluster profile %s can only be used by clusters with an rkeConfig", profile.Name)
+	}
+
+	template := profile.Spec.Template.DeepCopy()
+	result := spec.DeepCopy()
+
+	if template.KubernetesVersion != "" {
+		result.KubernetesVersion = template.KubernetesVersion
+	}
+	if err := checkKubernetesVersion(result.KubernetesVersion, profile.Spec.KubernetesVersionRange); err != nil {
+		return nil, err
+	}
+
+	if template.DefaultPodSecurityPolicyTemplateName != "" {
+		result.DefaultPodSecurityPolicyTemplateName = template.DefaultPodSecurityPolicyTemplateName
+	}
+
+	if template.Registries != nil {
+		result.RKEConfig.Registries = template.Registries
+	}
+
+	result.AgentEnvVars = mergeEnvVars(result.AgentEnvVars, template.AgentEnvVars)
+
+	if len(template.ControlPlaneConfig.Data) > 0 {
+		if result.RKEConfig.ControlPlaneConfig.Data == nil {
+			result.RKEConfig.ControlPlaneConfig.Data = map[string]interface{}{}
+		}
+		for k, v := range template.ControlPlaneConfig.Data {
+			result.RKEConfig.ControlPlaneConfig.Data[k] = v
+		}
+	}
+
+	return result, nil
+}
+
+// mergeEnvVars replaces the env vars with the name of a managed env var, and appends the managed env vars that are
+// missing
+func mergeEnvVars(envVars, managed []corev1.EnvVar) []corev1.EnvVar {
+	if len(managed) == 0 {
+		return envVars
+	}
+
+	byName := map[string]corev1.EnvVar{}
+	for _, envVar := range managed {
+		byName[envVar.Name] = envVar
+	}
+
+	var result []corev1.EnvVar
+	seen := map[string]bool{}
+	for _, envVar := range envVars {
+		if managedEnvVar, ok := byName[envVar.Name]; ok {
+			if seen[envVar.Name] {
+				continue
+			}
+			seen[envVar.Name] = true
+			envVar = managedEnvVar
+		}
+		result = append(result, envVar)
+	}
+
+	for _, envVar := range managed {
+		if !seen[envVar.Name] {
+			seen[envVar.Name] = true
+			result = append(result, envVar)
+		}
+	}
+
+	return result
+}
+
+func checkKubernetesVersion(version, versionRange string) error {
+	if versionRange == "" {
+		return nil
+	}
+
+	constraint, err := semver.NewConstraint(versionRange)
+	if err != nil {
+		return fmt.Errorf("invalid kubernetes version range %q: %w", versionRange, err)
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return fmt.Errorf("invalid kubernetes version %q: %w", version, err)
+	}
+
+	if !constraint.Check(v) {
+		return fmt.Errorf("kubernetes version %s is not in the range %s of the cluster profile", version, versionRange)
+	}
+	return nil
+}
+
+// driftedFields returns the managed fields of the spec of a cluster that don't match the spec rendered from the profile
+func driftedFields(spec, rendered *rancherv1.ClusterSpec) []string {
+	var fields []string
+	if spec.KubernetesVersion != rendered.KubernetesVersion {
+		fields = append(fields, "kubernetesVersion")
+	}
+	if spec.DefaultPodSecurityPolicyTemplateName != rendered.DefaultPodSecurityPolicyTemplateName {
+		fields = append(fields, "defaultPodSecurityPolicyTemplateName")
+	}
+	if (len(spec.AgentEnvVars) > 0 || len(rendered.AgentEnvVars) > 0) &&
+		!equality.Semantic.DeepEqual(spec.AgentEnvVars, rendered.AgentEnvVars) {
+		fields = append(fields, "agentEnvVars")
+	}
+	if spec.RKEConfig == nil || rendered.RKEConfig == nil {
+		return fields
+	}
+	if !equality.Semantic.DeepEqual(spec.RKEConfig.Registries, rendered.RKEConfig.Registries) {
+		fields = append(fields, "rkeConfig.registries")
+	}
+	if (len(spec.RKEConfig.ControlPlaneConfig.Data) > 0 || len(rendered.RKEConfig.ControlPlaneConfig.Data) > 0) &&
+		!equality.Semantic.DeepEqual(spec.RKEConfig.ControlPlaneConfig.Data, rendered.RKEConfig.ControlPlaneConfig.Data) {
+		fields = append(fields, "rkeConfig.controlPlaneConfig")
+	}
+	return fields
+}
+
+// profileHash is the hash of the fields of a profile that make a new revision when they change
+func profileHash(spec rancherv1.ClusterProfileSpec) (string, error) {
+	data, err := json.Marshal(struct {
+		KubernetesVersionRange string                           `json:"kubernetesVersionRange"`
+		Template               rancherv1.ClusterProfileTemplate `json:"template"`
+	}{
+		KubernetesVersionRange: spec.KubernetesVersionRange,
+		Template:               spec.Template,
+	})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
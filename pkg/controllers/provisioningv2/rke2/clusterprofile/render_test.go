@@ -0,0 +1,250 @@
+package clusterprofile
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newProfile(versionRange string, template rancherv1.ClusterProfileTemplate) *rancherv1.ClusterProfile {
+	return &rancherv1.ClusterProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "fleet-default",
+			Name:      "approved",
+		},
+		Spec: rancherv1.ClusterProfileSpec{
+			KubernetesVersionRange: versionRange,
+			Template:               template,
+		},
+		Status: rancherv1.ClusterProfileStatus{
+			Revision: 1,
+		},
+	}
+}
+
+func newClusterSpec(version string) rancherv1.ClusterSpec {
+	return rancherv1.ClusterSpec{
+		CloudCredentialSecretName: "cc-abcde",
+		KubernetesVersion:         version,
+		RKEConfig: &rancherv1.RKEConfig{
+			MachinePools: []rancherv1.RKEMachinePool{
+				{Name: "pool1", EtcdRole: true, ControlPlaneRole: true, WorkerRole: true},
+			},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	registries := &rkev1.Registry{
+		Mirrors: map[string]rkev1.Mirror{
+			"docker.io": {Endpoints: []string{"https://mirror.example.com"}},
+		},
+	}
+	profile := newProfile(">= 1.21, < 1.22", rancherv1.ClusterProfileTemplate{
+		AgentEnvVars: []corev1.EnvVar{
+			{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+			{Name: "NO_PROXY", Value: "127.0.0.1"},
+		},
+		DefaultPodSecurityPolicyTemplateName: "restricted",
+		Registries:                           registries,
+		ControlPlaneConfig: rkev1.GenericMap{Data: map[string]interface{}{
+			"profile": "cis-1.6",
+		}},
+	})
+
+	spec := newClusterSpec("v1.21.4+rke2r2")
+	spec.AgentEnvVars = []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://other.example.com"},
+		{Name: "CUSTOM", Value: "value"},
+	}
+	spec.RKEConfig.ControlPlaneConfig = rkev1.GenericMap{Data: map[string]interface{}{
+		"profile":                  "none",
+		"disable-cloud-controller": true,
+	}}
+
+	rendered, err := Render(spec, profile)
+	require.NoError(t, err)
+
+	// managed fields come from the profile
+	assert.Equal(t, "restricted", rendered.DefaultPodSecurityPolicyTemplateName)
+	assert.Equal(t, registries, rendered.RKEConfig.Registries)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+		{Name: "CUSTOM", Value: "value"},
+		{Name: "NO_PROXY", Value: "127.0.0.1"},
+	}, rendered.AgentEnvVars)
+	assert.Equal(t, map[string]interface{}{
+		"profile":                  "cis-1.6",
+		"disable-cloud-controller": true,
+	}, rendered.RKEConfig.ControlPlaneConfig.Data)
+
+	// parameters come from the cluster
+	assert.Equal(t, "v1.21.4+rke2r2", rendered.KubernetesVersion)
+	assert.Equal(t, "cc-abcde", rendered.CloudCredentialSecretName)
+	assert.Equal(t, spec.RKEConfig.MachinePools, rendered.RKEConfig.MachinePools)
+
+	// the spec it was rendered from is left as is
+	assert.Equal(t, "none", spec.RKEConfig.ControlPlaneConfig.Data["profile"])
+	assert.Nil(t, spec.RKEConfig.Registries)
+
+	rerendered, err := Render(*rendered, profile)
+	require.NoError(t, err)
+	assert.Equal(t, rendered, rerendered)
+	assert.Empty(t, driftedFields(rendered, rerendered))
+}
+
+func TestRenderKubernetesVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		versionRange    string
+		templateVersion string
+		clusterVersion  string
+		expectedVersion string
+		expectedErr     string
+	}{
+		{
+			name:            "version set by the profile",
+			templateVersion: "v1.21.4+rke2r2",
+			clusterVersion:  "v1.20.10+rke2r1",
+			expectedVersion: "v1.21.4+rke2r2",
+		},
+		{
+			name:            "version of the cluster in the range",
+			versionRange:    ">= 1.21, < 1.22",
+			clusterVersion:  "v1.21.5+rke2r1",
+			expectedVersion: "v1.21.5+rke2r1",
+		},
+		{
+			name:           "version of the cluster not in the range",
+			versionRange:   ">= 1.21, < 1.22",
+			clusterVersion: "v1.20.10+rke2r1",
+			expectedErr:    "kubernetes version v1.20.10+rke2r1 is not in the range >= 1.21, < 1.22 of the cluster profile",
+		},
+		{
+			name:           "version of the cluster not set",
+			versionRange:   ">= 1.21, < 1.22",
+			clusterVersion: "",
+			expectedErr:    "invalid kubernetes version",
+		},
+		{
+			name:           "invalid range",
+			versionRange:   "1.21 or later",
+			clusterVersion: "v1.21.5+rke2r1",
+			expectedErr:    "invalid kubernetes version range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := newProfile(tt.versionRange, rancherv1.ClusterProfileTemplate{
+				KubernetesVersion: tt.templateVersion,
+			})
+
+			rendered, err := Render(newClusterSpec(tt.clusterVersion), profile)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedVersion, rendered.KubernetesVersion)
+		})
+	}
+}
+
+func TestRenderWithoutRKEConfig(t *testing.T) {
+	_, err := Render(rancherv1.ClusterSpec{KubernetesVersion: "v1.21.4+rke2r2"}, newProfile("", rancherv1.ClusterProfileTemplate{}))
+	assert.Error(t, err)
+}
+
+func TestMergeEnvVars(t *testing.T) {
+	envVars := []corev1.EnvVar{
+		{Name: "A", Value: "cluster"},
+		{Name: "B", Value: "cluster"},
+		{Name: "A", Value: "duplicate"},
+	}
+
+	assert.Equal(t, envVars, mergeEnvVars(envVars, nil))
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "A", Value: "profile"},
+		{Name: "B", Value: "cluster"},
+		{Name: "C", Value: "profile"},
+	}, mergeEnvVars(envVars, []corev1.EnvVar{
+		{Name: "C", Value: "profile"},
+		{Name: "A", Value: "profile"},
+	}))
+}
+
+func TestDriftedFields(t *testing.T) {
+	profile := newProfile("", rancherv1.ClusterProfileTemplate{
+		KubernetesVersion: "v1.21.4+rke2r2",
+		AgentEnvVars: []corev1.EnvVar{
+			{Name: "HTTP_PROXY", Value: "http://proxy.example.com"},
+		},
+		ControlPlaneConfig: rkev1.GenericMap{Data: map[string]interface{}{
+			"profile": "cis-1.6",
+		}},
+	})
+
+	rendered, err := Render(newClusterSpec(""), profile)
+	require.NoError(t, err)
+
+	rerendered, err := Render(*rendered, profile)
+	require.NoError(t, err)
+	assert.Empty(t, driftedFields(rendered, rerendered))
+
+	// a spec without a control plane config isn't drifted from the rendered spec with an empty one
+	plain := newClusterSpec("v1.21.4+rke2r2")
+	plainRendered, err := Render(plain, newProfile("", rancherv1.ClusterProfileTemplate{KubernetesVersion: "v1.21.4+rke2r2"}))
+	require.NoError(t, err)
+	assert.Empty(t, driftedFields(&plain, plainRendered))
+
+	modified := rendered.DeepCopy()
+	modified.KubernetesVersion = "v1.21.5+rke2r1"
+	modified.AgentEnvVars[0].Value = "http://other.example.com"
+	modified.RKEConfig.ControlPlaneConfig.Data["profile"] = "none"
+	// changing parameters isn't a drift
+	modified.CloudCredentialSecretName = "cc-fghij"
+
+	current, err := Render(*modified, profile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"kubernetesVersion",
+		"agentEnvVars",
+		"rkeConfig.controlPlaneConfig",
+	}, driftedFields(modified, current))
+}
+
+func TestProfileRevision(t *testing.T) {
+	h := handler{}
+	profile := newProfile(">= 1.21, < 1.22", rancherv1.ClusterProfileTemplate{
+		KubernetesVersion: "v1.21.4+rke2r2",
+	})
+
+	status, err := h.OnProfileChange(profile, rancherv1.ClusterProfileStatus{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Revision)
+
+	status, err = h.OnProfileChange(profile, status)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Revision, "unchanged profile keeps its revision")
+
+	profile.Spec.RolloutRevision = 1
+	status, err = h.OnProfileChange(profile, status)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Revision, "confirming a rollout doesn't make a new revision")
+
+	profile.Spec.Template.KubernetesVersion = "v1.21.5+rke2r1"
+	status, err = h.OnProfileChange(profile, status)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.Revision)
+
+	profile.Spec.KubernetesVersionRange = "not a range"
+	_, err = h.OnProfileChange(profile, status)
+	assert.Error(t, err)
+}
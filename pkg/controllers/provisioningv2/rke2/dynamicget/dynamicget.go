@@ -0,0 +1,116 @@
+package dynamicget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rancher/lasso/pkg/client"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// RetryDelay is how long callers should wait before retrying a lookup that returned ErrNotReady
+	RetryDelay = 2 * time.Second
+
+	defaultMaxAttempts = 5
+)
+
+// ErrNotReady is returned when an object could neither be read from the dynamic cache nor from the
+// apiserver, and the lookup should be retried later instead of being reported as a failure.
+var ErrNotReady = errors.New("dynamic cache is not ready")
+
+type Cache interface {
+	Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error)
+}
+
+type DirectGetter func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error)
+
+// Getter reads objects from the dynamic controller cache. Right after startup the informer for a
+// GVK may not exist or be synced yet, in which case Getter falls back to an uncached GET. Only
+// after maxAttempts consecutive failures for the same object is the underlying error returned.
+type Getter struct {
+	cache       Cache
+	direct      DirectGetter
+	maxAttempts int
+
+	lock     sync.Mutex
+	attempts map[string]int
+}
+
+func New(clients *wrangler.Context) *Getter {
+	return NewGetter(clients.Dynamic, NewDirectGetter(clients.SharedControllerFactory.SharedCacheFactory().SharedClientFactory()))
+}
+
+func NewGetter(cache Cache, direct DirectGetter) *Getter {
+	return &Getter{
+		cache:       cache,
+		direct:      direct,
+		maxAttempts: defaultMaxAttempts,
+		attempts:    map[string]int{},
+	}
+}
+
+func NewDirectGetter(clients client.SharedClientFactory) DirectGetter {
+	return func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+		c, err := clients.ForKind(gvk)
+		if err != nil {
+			return nil, err
+		}
+		obj := &unstructured.Unstructured{}
+		if err := c.Get(context.TODO(), namespace, name, obj, metav1.GetOptions{}); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+}
+
+// IsNotReady returns true if the lookup failed because the cache is not ready and should be retried.
+func IsNotReady(err error) bool {
+	return errors.Is(err, ErrNotReady)
+}
+
+func (g *Getter) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	key := fmt.Sprintf("%s %s/%s", gvk, namespace, name)
+
+	obj, err := g.cache.Get(gvk, namespace, name)
+	if err == nil || apierror.IsNotFound(err) {
+		g.reset(key)
+		return obj, err
+	}
+
+	logrus.Debugf("[dynamicget] failed to get %s from cache, falling back to apiserver: %v", key, err)
+	obj, err = g.direct(gvk, namespace, name)
+	if err == nil || apierror.IsNotFound(err) {
+		g.reset(key)
+		return obj, err
+	}
+
+	if g.attempt(key) < g.maxAttempts {
+		return nil, fmt.Errorf("%w: failed to get %s: %v", ErrNotReady, key, err)
+	}
+
+	g.reset(key)
+	return nil, err
+}
+
+func (g *Getter) attempt(key string) int {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.attempts[key]++
+	return g.attempts[key]
+}
+
+func (g *Getter) reset(key string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	delete(g.attempts, key)
+}
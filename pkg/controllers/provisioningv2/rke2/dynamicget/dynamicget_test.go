@@ -0,0 +1,104 @@
+package dynamicget
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testGVK = schema.GroupVersionKind{
+	Group:   "rke-machine.cattle.io",
+	Version: "v1",
+	Kind:    "Amazonec2Machine",
+}
+
+type fakeCache func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error)
+
+func (f fakeCache) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	return f(gvk, namespace, name)
+}
+
+// unsyncedCache simulates the error returned by the dynamic controller before the informer for a GVK is started
+func unsyncedCache(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	return nil, fmt.Errorf("failed to load informer for %v", gvk)
+}
+
+func notFound(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	return nil, apierror.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, name)
+}
+
+func TestGetFromCache(t *testing.T) {
+	assert := assert.New(t)
+	obj := &unstructured.Unstructured{}
+	g := NewGetter(fakeCache(func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+		return obj, nil
+	}), func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+		t.Fatal("direct get should not be called when the cache is ready")
+		return nil, nil
+	})
+
+	result, err := g.Get(testGVK, "fleet-default", "machine")
+	assert.Nil(err)
+	assert.Equal(obj, result)
+}
+
+func TestGetNotFoundInCache(t *testing.T) {
+	g := NewGetter(fakeCache(notFound), func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+		t.Fatal("direct get should not be called when the object is not found")
+		return nil, nil
+	})
+
+	_, err := g.Get(testGVK, "fleet-default", "machine")
+	assert.True(t, apierror.IsNotFound(err))
+}
+
+func TestGetUnsyncedCacheFallsBack(t *testing.T) {
+	assert := assert.New(t)
+	obj := &unstructured.Unstructured{}
+	directCalls := 0
+	g := NewGetter(fakeCache(unsyncedCache), func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+		directCalls++
+		return obj, nil
+	})
+
+	result, err := g.Get(testGVK, "fleet-default", "machine")
+	assert.Nil(err)
+	assert.Equal(obj, result)
+	assert.Equal(1, directCalls)
+
+	g = NewGetter(fakeCache(unsyncedCache), notFound)
+	_, err = g.Get(testGVK, "fleet-default", "machine")
+	assert.True(apierror.IsNotFound(err))
+	assert.False(IsNotReady(err))
+}
+
+func TestGetUnsyncedCacheBoundedRetries(t *testing.T) {
+	assert := assert.New(t)
+	directErr := errors.New("connection refused")
+	g := NewGetter(fakeCache(unsyncedCache), func(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+		return nil, directErr
+	})
+
+	for i := 1; i < defaultMaxAttempts; i++ {
+		_, err := g.Get(testGVK, "fleet-default", "machine")
+		assert.True(IsNotReady(err), "attempt %d should be retried", i)
+	}
+
+	// other objects have their own attempt count
+	_, err := g.Get(testGVK, "fleet-default", "other")
+	assert.True(IsNotReady(err))
+
+	_, err = g.Get(testGVK, "fleet-default", "machine")
+	assert.False(IsNotReady(err))
+	assert.Equal(directErr, err)
+
+	// the attempt count is reset after the error is surfaced
+	_, err = g.Get(testGVK, "fleet-default", "machine")
+	assert.True(IsNotReady(err))
+}
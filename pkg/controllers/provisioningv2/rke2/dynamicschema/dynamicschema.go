@@ -2,18 +2,27 @@
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"strings"
+	"sync"
+	"time"
 
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/crd"
 	"github.com/rancher/wrangler/pkg/data/convert"
-	"github.com/rancher/wrangler/pkg/generic"
+	apiextcontrollers "github.com/rancher/wrangler/pkg/generated/controllers/apiextensions.k8s.io/v1"
 	"github.com/rancher/wrangler/pkg/schemas"
 	"github.com/rancher/wrangler/pkg/schemas/openapi"
+	"github.com/sirupsen/logrus"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -22,27 +31,44 @@
 const (
 	machineAPIGroup       = "rke-machine.cattle.io"
 	machineConfigAPIGroup = "rke-machine-config.cattle.io"
+	// specHashAnnotation records on the CRDs the hash of the CRD generated from the dynamic schema
+	specHashAnnotation = "cattle.io/dynamic-schema-hash"
+	// fanOutDelay is the window the changes of nodetemplateconfig are coalesced in before every schema is regenerated
+	fanOutDelay = 2 * time.Second
 )
 
 type handler struct {
 	schemaCache       mgmtcontrollers.DynamicSchemaCache
 	schemasController mgmtcontrollers.DynamicSchemaController
+	crdCache          apiextcontrollers.CustomResourceDefinitionCache
+	crds              apiextcontrollers.CustomResourceDefinitionClient
+	apply             apply.Apply
+	after             func(time.Duration, func())
+
+	fanOutLock    sync.Mutex
+	fanOutPending bool
+
+	// applied are the resource versions of the CRDs once applied, a CRD changed since then is applied again
+	appliedLock sync.Mutex
+	applied     map[string]string
 }
 
 func Register(ctx context.Context, clients *wrangler.Context) {
-	h := handler{
+	h := &handler{
 		schemaCache:       clients.Mgmt.DynamicSchema().Cache(),
 		schemasController: clients.Mgmt.DynamicSchema(),
+		crdCache:          clients.CRD.CustomResourceDefinition().Cache(),
+		crds:              clients.CRD.CustomResourceDefinition(),
+		apply: clients.Apply.
+			WithCacheTypes(clients.CRD.CustomResourceDefinition()).
+			WithSetID("dynamic-driver-crd"),
+		after: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+		applied: map[string]string{},
 	}
-	mgmtcontrollers.RegisterDynamicSchemaGeneratingHandler(ctx,
-		clients.Mgmt.DynamicSchema(),
-		clients.Apply.WithCacheTypes(clients.CRD.CustomResourceDefinition()),
-		"",
-		"dynamic-driver-crd",
-		h.OnChange,
-		&generic.GeneratingHandlerOptions{
-			AllowClusterScoped: true,
-		})
+	clients.Mgmt.DynamicSchema().OnChange(ctx, "dynamic-driver-crd", h.OnChange)
+	clients.Mgmt.DynamicSchema().OnChange(ctx, "dynamic-driver-crd-remove", h.OnRemove)
 }
 
 func getStatusSchema(allSchemas *schemas.Schemas) (*schemas.Schema, error) {
@@ -213,11 +239,61 @@ func getSpecSchemas(name string, allSchemas *schemas.Schemas, spec *v3.DynamicSc
 	return allSchemas.Schema(specSchema.ID), nil
 }
 
-func (h *handler) OnChange(obj *v3.DynamicSchema, status v3.DynamicSchemaStatus) ([]runtime.Object, v3.DynamicSchemaStatus, error) {
+func (h *handler) OnChange(key string, obj *v3.DynamicSchema) (*v3.DynamicSchema, error) {
+	if obj == nil || !obj.DeletionTimestamp.IsZero() {
+		return obj, nil
+	}
+
 	if obj.Name == "nodetemplateconfig" {
+		h.enqueueAll()
+	}
+
+	objs, err := h.generateCRDs(obj)
+	if err != nil || len(objs) == 0 {
+		return obj, err
+	}
+
+	if h.upToDate(objs) {
+		return obj, nil
+	}
+
+	if err := h.apply.WithOwner(obj).ApplyObjects(objs...); err != nil {
+		return obj, err
+	}
+	return obj, h.recordApplied(objs)
+}
+
+// OnRemove deletes the CRDs generated for a dynamic schema once it is deleted
+func (h *handler) OnRemove(key string, obj *v3.DynamicSchema) (*v3.DynamicSchema, error) {
+	if obj != nil {
+		return obj, nil
+	}
+
+	owner := &v3.DynamicSchema{}
+	owner.Name = key
+	owner.SetGroupVersionKind(h.schemasController.GroupVersionKind())
+	return nil, h.apply.WithOwner(owner).ApplyObjects()
+}
+
+// enqueueAll regenerates every schema once the fanOutDelay elapsed, the changes within the delay trigger a single pass
+func (h *handler) enqueueAll() {
+	h.fanOutLock.Lock()
+	defer h.fanOutLock.Unlock()
+
+	if h.fanOutPending {
+		return
+	}
+	h.fanOutPending = true
+
+	h.after(fanOutDelay, func() {
+		h.fanOutLock.Lock()
+		h.fanOutPending = false
+		h.fanOutLock.Unlock()
+
 		all, err := h.schemaCache.List(labels.Everything())
 		if err != nil {
-			return nil, status, err
+			logrus.Errorf("failed to list dynamic schemas: %v", err)
+			return
 		}
 		for _, schema := range all {
 			if schema.Name == "nodetemplateconfig" {
@@ -225,20 +301,62 @@ func (h *handler) OnChange(obj *v3.DynamicSchema, status v3.DynamicSchemaStatus)
 			}
 			h.schemasController.Enqueue(schema.Name)
 		}
+	})
+}
+
+// upToDate returns true if every CRD exists with the hash of its generated spec and wasn't changed since it was
+// applied, in which case applying is a no-op
+func (h *handler) upToDate(objs []runtime.Object) bool {
+	h.appliedLock.Lock()
+	defer h.appliedLock.Unlock()
+
+	for _, obj := range objs {
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		existing, err := h.crdCache.Get(m.GetName())
+		if err != nil || existing.Annotations[specHashAnnotation] != m.GetAnnotations()[specHashAnnotation] ||
+			existing.ResourceVersion != h.applied[m.GetName()] {
+			return false
+		}
+	}
+	return true
+}
+
+// recordApplied records the resource versions of the live CRDs once applied, so that the CRDs changed by anything else
+// are applied again
+func (h *handler) recordApplied(objs []runtime.Object) error {
+	h.appliedLock.Lock()
+	defer h.appliedLock.Unlock()
+
+	for _, obj := range objs {
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		live, err := h.crds.Get(m.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		h.applied[m.GetName()] = live.ResourceVersion
 	}
+	return nil
+}
 
+func (h *handler) generateCRDs(obj *v3.DynamicSchema) ([]runtime.Object, error) {
 	name, node, _, err := h.getStyle(obj.Name)
 	if err != nil {
-		return nil, status, err
+		return nil, err
 	}
 
 	if !node { // only support nodes right now  && !cluster {
-		return nil, status, nil
+		return nil, nil
 	}
 
 	nodeConfigID, templateID, machineID, schemas, err := getSchemas(name, &obj.Spec)
 	if err != nil {
-		return nil, status, err
+		return nil, err
 	}
 
 	var result []runtime.Object
@@ -246,7 +364,7 @@ func (h *handler) OnChange(obj *v3.DynamicSchema, status v3.DynamicSchemaStatus)
 	for _, id := range []string{nodeConfigID, templateID, machineID} {
 		props, err := openapi.ToOpenAPI(id, schemas)
 		if err != nil {
-			return nil, status, err
+			return nil, err
 		}
 		crd := crd.CRD{
 			GVK: schema.GroupVersionKind{
@@ -268,12 +386,35 @@ func (h *handler) OnChange(obj *v3.DynamicSchema, status v3.DynamicSchemaStatus)
 
 		crdObj, err := crd.ToCustomResourceDefinition()
 		if err != nil {
-			return nil, status, err
+			return nil, err
+		}
+		if err := setSpecHash(crdObj); err != nil {
+			return nil, err
 		}
 		result = append(result, crdObj)
 	}
 
-	return result, status, nil
+	return result, nil
+}
+
+func setSpecHash(obj runtime.Object) error {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+
+	annotations := m.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[specHashAnnotation] = hex.EncodeToString(hash[:])
+	m.SetAnnotations(annotations)
+	return nil
 }
 
 func (h *handler) getStyle(name string) (string, bool, bool, error) {
@@ -0,0 +1,237 @@
+package dynamicschema
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/wrangler/pkg/apply"
+	apiextcontrollers "github.com/rancher/wrangler/pkg/generated/controllers/apiextensions.k8s.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeSchemaCache struct {
+	mgmtcontrollers.DynamicSchemaCache
+	schemas map[string]*v3.DynamicSchema
+}
+
+func (f *fakeSchemaCache) Get(name string) (*v3.DynamicSchema, error) {
+	if schema, ok := f.schemas[name]; ok {
+		return schema, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{}, name)
+}
+
+func (f *fakeSchemaCache) List(selector labels.Selector) ([]*v3.DynamicSchema, error) {
+	var result []*v3.DynamicSchema
+	for _, schema := range f.schemas {
+		result = append(result, schema)
+	}
+	return result, nil
+}
+
+type fakeSchemasController struct {
+	mgmtcontrollers.DynamicSchemaController
+	enqueued []string
+}
+
+func (f *fakeSchemasController) Enqueue(name string) {
+	f.enqueued = append(f.enqueued, name)
+}
+
+func (f *fakeSchemasController) GroupVersionKind() schema.GroupVersionKind {
+	return v3.SchemeGroupVersion.WithKind("DynamicSchema")
+}
+
+type fakeCRDCache struct {
+	apiextcontrollers.CustomResourceDefinitionCache
+	crds map[string]*apiextv1.CustomResourceDefinition
+}
+
+func (f *fakeCRDCache) Get(name string) (*apiextv1.CustomResourceDefinition, error) {
+	if crd, ok := f.crds[name]; ok {
+		return crd, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{}, name)
+}
+
+// fakeCRDClient reads the CRDs of the cache, as the cache is up to date in the tests
+type fakeCRDClient struct {
+	apiextcontrollers.CustomResourceDefinitionClient
+	cache *fakeCRDCache
+}
+
+func (f *fakeCRDClient) Get(name string, opts metav1.GetOptions) (*apiextv1.CustomResourceDefinition, error) {
+	return f.cache.Get(name)
+}
+
+// fakeApply stores the applied CRDs in the CRD cache, as the controller would once the CRDs are created
+type fakeApply struct {
+	apply.Apply
+	crds    *fakeCRDCache
+	applies int
+	owner   runtime.Object
+	version int
+}
+
+func (f *fakeApply) WithOwner(obj runtime.Object) apply.Apply {
+	f.owner = obj
+	return f
+}
+
+func (f *fakeApply) ApplyObjects(objs ...runtime.Object) error {
+	f.applies++
+	if len(objs) == 0 {
+		// the objects of the owner which aren't applied anymore are deleted
+		f.crds.crds = map[string]*apiextv1.CustomResourceDefinition{}
+	}
+	for _, obj := range objs {
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		f.version++
+		f.crds.crds[m.GetName()] = &apiextv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            m.GetName(),
+				Annotations:     m.GetAnnotations(),
+				ResourceVersion: strconv.Itoa(f.version),
+			},
+		}
+	}
+	return nil
+}
+
+func newDynamicSchema(name string, fields map[string]v3.Field) *v3.DynamicSchema {
+	return &v3.DynamicSchema{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v3.DynamicSchemaSpec{ResourceFields: fields},
+	}
+}
+
+func newTestHandler(schemas ...*v3.DynamicSchema) (*handler, *fakeApply, *fakeSchemasController, *[]func()) {
+	cache := &fakeSchemaCache{schemas: map[string]*v3.DynamicSchema{}}
+	for _, schema := range schemas {
+		cache.schemas[schema.Name] = schema
+	}
+	crds := &fakeCRDCache{crds: map[string]*apiextv1.CustomResourceDefinition{}}
+	applier := &fakeApply{crds: crds}
+	controller := &fakeSchemasController{}
+	scheduled := &[]func(){}
+
+	return &handler{
+		schemaCache:       cache,
+		schemasController: controller,
+		crdCache:          crds,
+		crds:              &fakeCRDClient{cache: crds},
+		apply:             applier,
+		after: func(d time.Duration, f func()) {
+			*scheduled = append(*scheduled, f)
+		},
+		applied: map[string]string{},
+	}, applier, controller, scheduled
+}
+
+func TestOnChangeSkipsUnchangedSchema(t *testing.T) {
+	nodeTemplateConfig := newDynamicSchema("nodetemplateconfig", map[string]v3.Field{
+		"amazonec2Config": {Type: "amazonec2Config"},
+	})
+	driverSchema := newDynamicSchema("amazonec2config", map[string]v3.Field{
+		"region": {Type: "string"},
+	})
+	h, applier, _, _ := newTestHandler(nodeTemplateConfig, driverSchema)
+
+	_, err := h.OnChange(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applier.applies)
+	assert.Len(t, applier.crds.crds, 3)
+
+	_, err = h.OnChange(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applier.applies, "expected no apply for an unchanged schema")
+
+	driverSchema.Spec.ResourceFields["zone"] = v3.Field{Type: "string"}
+	_, err = h.OnChange(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applier.applies, "expected a changed schema to be applied")
+
+	for name := range applier.crds.crds {
+		delete(applier.crds.crds, name)
+		break
+	}
+	_, err = h.OnChange(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 3, applier.applies, "expected a missing CRD to be applied")
+
+	for _, crd := range applier.crds.crds {
+		crd.ResourceVersion = "edited"
+		break
+	}
+	_, err = h.OnChange(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 4, applier.applies, "expected a CRD changed since it was applied to be applied again")
+
+	_, err = h.OnChange(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 4, applier.applies)
+}
+
+func TestOnRemoveDeletesCRDs(t *testing.T) {
+	driverSchema := newDynamicSchema("amazonec2config", map[string]v3.Field{
+		"region": {Type: "string"},
+	})
+	h, applier, _, _ := newTestHandler(newDynamicSchema("nodetemplateconfig", map[string]v3.Field{
+		"amazonec2Config": {Type: "amazonec2Config"},
+	}), driverSchema)
+
+	_, err := h.OnChange(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	require.Len(t, applier.crds.crds, 3)
+
+	_, err = h.OnRemove(driverSchema.Name, driverSchema)
+	require.NoError(t, err)
+	assert.Len(t, applier.crds.crds, 3, "expected the CRDs to be kept while the schema exists")
+
+	_, err = h.OnRemove(driverSchema.Name, nil)
+	require.NoError(t, err)
+	assert.Empty(t, applier.crds.crds, "expected the CRDs of a deleted schema to be deleted")
+	owner, err := meta.Accessor(applier.owner)
+	require.NoError(t, err)
+	assert.Equal(t, driverSchema.Name, owner.GetName())
+	assert.Equal(t, "DynamicSchema", applier.owner.GetObjectKind().GroupVersionKind().Kind)
+}
+
+func TestOnChangeCoalescesFanOut(t *testing.T) {
+	nodeTemplateConfig := newDynamicSchema("nodetemplateconfig", map[string]v3.Field{
+		"amazonec2Config":    {Type: "amazonec2Config"},
+		"digitaloceanConfig": {Type: "digitaloceanConfig"},
+	})
+	h, applier, controller, scheduled := newTestHandler(nodeTemplateConfig,
+		newDynamicSchema("amazonec2config", nil),
+		newDynamicSchema("digitaloceanconfig", nil))
+
+	for i := 0; i < 5; i++ {
+		_, err := h.OnChange(nodeTemplateConfig.Name, nodeTemplateConfig)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, applier.applies)
+	assert.Empty(t, controller.enqueued, "expected the fan-out to wait for the delay")
+	require.Len(t, *scheduled, 1, "expected a single fan-out for a batch of updates")
+
+	(*scheduled)[0]()
+	assert.ElementsMatch(t, []string{"amazonec2config", "digitaloceanconfig"}, controller.enqueued)
+
+	_, err := h.OnChange(nodeTemplateConfig.Name, nodeTemplateConfig)
+	require.NoError(t, err)
+	assert.Len(t, *scheduled, 2, "expected a new fan-out once the previous one ran")
+}
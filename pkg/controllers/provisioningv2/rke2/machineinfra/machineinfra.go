@@ -0,0 +1,169 @@
+package machineinfra
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/dynamicget"
+	"github.com/rancher/rancher/pkg/eventrecorder"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+)
+
+const (
+	infraGroup = "rke-machine.cattle.io"
+
+	// InfrastructureMissingReason is the reason of the event of a machine failed because its infrastructure is missing
+	InfrastructureMissingReason = "InfrastructureMissing"
+
+	// gracePeriod is how long the infrastructure of a machine has to be missing before the machine is failed
+	gracePeriod = 10 * time.Minute
+	// recheckInterval is how often a machine with missing infrastructure is checked again
+	recheckInterval = time.Minute
+	// requiredObservations is the number of consecutive checks that have to find the infrastructure missing, so that
+	// an infrastructure object being recreated doesn't fail its machine
+	requiredObservations = 3
+)
+
+// missingInfra tracks the consecutive checks that found the infrastructure of a machine missing
+type missingInfra struct {
+	first, last  time.Time
+	observations int
+}
+
+// This fails the machines whose rke-machine.cattle.io infrastructure object was deleted out of band. Cluster API keeps
+// counting such machines as existing, so the machine set never replaces them.
+
+type handler struct {
+	machines             capicontrollers.MachineController
+	dynamicGetter        *dynamicget.Getter
+	directGetter         dynamicget.DirectGetter
+	recorder             record.EventRecorder
+	gracePeriod          time.Duration
+	recheckInterval      time.Duration
+	requiredObservations int
+	now                  func() time.Time
+
+	lock    sync.Mutex
+	missing map[string]*missingInfra
+}
+
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		machines:             clients.CAPI.Machine(),
+		dynamicGetter:        dynamicget.New(clients),
+		directGetter:         dynamicget.NewDirectGetter(clients.SharedControllerFactory.SharedCacheFactory().SharedClientFactory()),
+		recorder:             eventrecorder.New(ctx, clients.K8s, "machine-infra-controller"),
+		gracePeriod:          gracePeriod,
+		recheckInterval:      recheckInterval,
+		requiredObservations: requiredObservations,
+		now:                  time.Now,
+		missing:              map[string]*missingInfra{},
+	}
+	clients.CAPI.Machine().OnChange(ctx, "machine-infra", h.OnChange)
+}
+
+func (h *handler) OnChange(key string, machine *capi.Machine) (*capi.Machine, error) {
+	if machine == nil ||
+		machine.DeletionTimestamp != nil ||
+		machine.Status.FailureReason != nil ||
+		machine.Spec.InfrastructureRef.GroupVersionKind().Group != infraGroup {
+		h.forget(key)
+		return machine, nil
+	}
+
+	infraRef := machine.Spec.InfrastructureRef
+	gvk := infraRef.GroupVersionKind()
+	_, err := h.dynamicGetter.Get(gvk, machine.Namespace, infraRef.Name)
+	if err == nil {
+		h.forget(key)
+		return machine, nil
+	} else if dynamicget.IsNotReady(err) {
+		h.machines.EnqueueAfter(machine.Namespace, machine.Name, dynamicget.RetryDelay)
+		return machine, nil
+	} else if !apierror.IsNotFound(err) {
+		return machine, err
+	}
+
+	missingSince, failed := h.observeMissing(key)
+	if !failed {
+		h.machines.EnqueueAfter(machine.Namespace, machine.Name, h.recheckInterval)
+		return machine, nil
+	}
+
+	// the cache can lag behind an infrastructure object that was just recreated, so the apiserver has the last word
+	if _, err := h.directGetter(gvk, machine.Namespace, infraRef.Name); err == nil {
+		h.forget(key)
+		return machine, nil
+	} else if !apierror.IsNotFound(err) {
+		return machine, err
+	}
+
+	return h.failMachine(key, machine, missingSince)
+}
+
+// observeMissing records that the infrastructure of the machine was found missing. It returns since when the
+// infrastructure is missing, and whether it has been missing for long enough to fail the machine.
+func (h *handler) observeMissing(key string) (time.Time, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	missing, ok := h.missing[key]
+	if !ok {
+		h.missing[key] = &missingInfra{first: now, last: now, observations: 1}
+		return now, false
+	}
+
+	// the machine is enqueued on every change, the checks closer than the recheck interval count as one
+	if now.Sub(missing.last) >= h.recheckInterval {
+		missing.observations++
+		missing.last = now
+	}
+	return missing.first, missing.observations >= h.requiredObservations && now.Sub(missing.first) >= h.gracePeriod
+}
+
+func (h *handler) forget(key string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.missing, key)
+}
+
+func (h *handler) failMachine(key string, machine *capi.Machine, missingSince time.Time) (*capi.Machine, error) {
+	infraRef := machine.Spec.InfrastructureRef
+	reason := capierrors.InvalidConfigurationMachineError
+	message := fmt.Sprintf("infrastructure %s %s/%s has been missing since %s", infraRef.Kind, machine.Namespace,
+		infraRef.Name, missingSince.UTC().Format(time.RFC3339))
+
+	failed := machine.DeepCopy()
+	failed.Status.FailureReason = &reason
+	failed.Status.FailureMessage = &message
+	failed, err := h.machines.UpdateStatus(failed)
+	if err != nil {
+		return machine, err
+	}
+	machine = failed
+	h.forget(key)
+
+	logrus.Warnf("[machineinfra] failing machine %s/%s: %s", machine.Namespace, machine.Name, message)
+	h.recorder.Eventf(machine, corev1.EventTypeWarning, InfrastructureMissingReason,
+		"%s, the machine is failed so that it gets replaced", message)
+
+	// a failed machine still counts as a replica of its machine set, delete it so that the machine set replaces it
+	if owner := metav1.GetControllerOf(machine); owner != nil && owner.Kind == "MachineSet" {
+		if err := h.machines.Delete(machine.Namespace, machine.Name, &metav1.DeleteOptions{}); err != nil && !apierror.IsNotFound(err) {
+			return machine, err
+		}
+	}
+
+	return machine, nil
+}
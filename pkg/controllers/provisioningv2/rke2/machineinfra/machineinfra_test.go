@@ -0,0 +1,206 @@
+package machineinfra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/dynamicget"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+type fakeMachineController struct {
+	capicontrollers.MachineController
+	updated  []*capi.Machine
+	deleted  []string
+	enqueued int
+}
+
+func (f *fakeMachineController) UpdateStatus(machine *capi.Machine) (*capi.Machine, error) {
+	f.updated = append(f.updated, machine)
+	return machine, nil
+}
+
+func (f *fakeMachineController) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	f.deleted = append(f.deleted, namespace+"/"+name)
+	return nil
+}
+
+func (f *fakeMachineController) EnqueueAfter(namespace, name string, duration time.Duration) {
+	f.enqueued++
+}
+
+// fakeInfra is both the cache and the apiserver of the infrastructure objects
+type fakeInfra struct {
+	cached, live bool
+}
+
+func (f *fakeInfra) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	if f.cached {
+		return &unstructured.Unstructured{}, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, name)
+}
+
+func (f *fakeInfra) direct(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	if f.live {
+		return &unstructured.Unstructured{}, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, name)
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestHandler(infra *fakeInfra, machines *fakeMachineController, clock *testClock) *handler {
+	return &handler{
+		machines:             machines,
+		dynamicGetter:        dynamicget.NewGetter(infra, infra.direct),
+		directGetter:         infra.direct,
+		recorder:             record.NewFakeRecorder(10),
+		gracePeriod:          10 * time.Minute,
+		recheckInterval:      time.Minute,
+		requiredObservations: 3,
+		now:                  clock.Now,
+		missing:              map[string]*missingInfra{},
+	}
+}
+
+func newMachine() *capi.Machine {
+	controller := true
+	return &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "fleet-default",
+			Name:      "pool1-abcde",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "MachineSet", Name: "pool1", Controller: &controller},
+			},
+		},
+		Spec: capi.MachineSpec{
+			InfrastructureRef: corev1.ObjectReference{
+				APIVersion: "rke-machine.cattle.io/v1",
+				Kind:       "Amazonec2Machine",
+				Name:       "pool1-abcde",
+			},
+		},
+	}
+}
+
+func TestMachineFailedAfterGracePeriod(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	machines := &fakeMachineController{}
+	h := newTestHandler(&fakeInfra{}, machines, clock)
+	machine := newMachine()
+
+	for i := 0; i < 10; i++ {
+		_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+		require.NoError(t, err)
+		assert.Empty(t, machines.updated, "expected the machine not to be failed within the grace period")
+		clock.now = clock.now.Add(time.Minute)
+	}
+
+	_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+	require.NoError(t, err)
+	require.Len(t, machines.updated, 1, "expected the machine to be failed once the grace period passed")
+	failed := machines.updated[0]
+	require.NotNil(t, failed.Status.FailureReason)
+	assert.Equal(t, "InvalidConfiguration", string(*failed.Status.FailureReason))
+	require.NotNil(t, failed.Status.FailureMessage)
+	assert.Contains(t, *failed.Status.FailureMessage, "Amazonec2Machine fleet-default/pool1-abcde")
+	assert.Equal(t, []string{"fleet-default/pool1-abcde"}, machines.deleted, "expected the machine of a machine set to be deleted")
+
+	event := <-h.recorder.(*record.FakeRecorder).Events
+	assert.Contains(t, event, InfrastructureMissingReason)
+	assert.Empty(t, h.missing)
+}
+
+func TestMachineNotFailedWithoutConsecutiveObservations(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	machines := &fakeMachineController{}
+	h := newTestHandler(&fakeInfra{}, machines, clock)
+	machine := newMachine()
+
+	// changes of the machine in a burst count as a single observation
+	for i := 0; i < 10; i++ {
+		_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+		require.NoError(t, err)
+	}
+	clock.now = clock.now.Add(11 * time.Minute)
+	_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+	require.NoError(t, err)
+	assert.Empty(t, machines.updated, "expected a machine observed missing twice not to be failed")
+
+	clock.now = clock.now.Add(time.Minute)
+	_, err = h.OnChange("fleet-default/pool1-abcde", machine)
+	require.NoError(t, err)
+	assert.Len(t, machines.updated, 1)
+}
+
+func TestMachineNotFailedWhenInfraRecreated(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	infra := &fakeInfra{}
+	machines := &fakeMachineController{}
+	h := newTestHandler(infra, machines, clock)
+	machine := newMachine()
+
+	for i := 0; i < 5; i++ {
+		_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+		require.NoError(t, err)
+		clock.now = clock.now.Add(time.Minute)
+	}
+
+	// the infrastructure object is recreated and found once, the observations start over
+	infra.cached = true
+	_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+	require.NoError(t, err)
+	infra.cached = false
+
+	for i := 0; i < 10; i++ {
+		_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+		require.NoError(t, err)
+		clock.now = clock.now.Add(time.Minute)
+	}
+	assert.Empty(t, machines.updated, "expected the grace period to start over once the infrastructure was found")
+
+	// the cache lags behind the apiserver where the infrastructure object was recreated
+	infra.live = true
+	_, err = h.OnChange("fleet-default/pool1-abcde", machine)
+	require.NoError(t, err)
+	assert.Empty(t, machines.updated, "expected the machine not to be failed while its infrastructure exists in the apiserver")
+	assert.Empty(t, h.missing)
+}
+
+func TestMachineIgnored(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	machines := &fakeMachineController{}
+	h := newTestHandler(&fakeInfra{}, machines, clock)
+
+	deleting := newMachine()
+	deleting.DeletionTimestamp = &metav1.Time{Time: clock.now}
+	otherInfra := newMachine()
+	otherInfra.Spec.InfrastructureRef.APIVersion = "infrastructure.cluster.x-k8s.io/v1alpha4"
+
+	for i := 0; i < 20; i++ {
+		for _, machine := range []*capi.Machine{deleting, otherInfra} {
+			_, err := h.OnChange("fleet-default/pool1-abcde", machine)
+			require.NoError(t, err)
+		}
+		clock.now = clock.now.Add(time.Minute)
+	}
+	assert.Empty(t, machines.updated)
+	assert.Zero(t, machines.enqueued)
+}
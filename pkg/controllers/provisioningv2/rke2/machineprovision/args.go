@@ -36,6 +36,7 @@ type driverArgs struct {
 	ImageName           string
 	ImagePullPolicy     corev1.PullPolicy
 	EnvSecret           *corev1.Secret
+	ImagePullSecret     *corev1.Secret
 	StateSecretName     string
 	BootstrapSecretName string
 	BootstrapOptional   bool
@@ -75,7 +76,18 @@ func (h *handler) getArgsEnvAndStatus(typeMeta meta.Type, meta metav1.Object, da
 		Data: map[string][]byte{},
 	}
 
-	bootstrapName, cloudCredentialSecretName, secrets, err := h.getSecretData(meta, data, create)
+	machine, err := h.getMachine(meta)
+	if err != nil {
+		return driverArgs{}, err
+	}
+
+	bootstrapName, cloudCredentialSecretName, secrets, err := h.getSecretData(meta, machine, data, create)
+	if err != nil {
+		return driverArgs{}, err
+	}
+
+	imageName := settings.PrefixPrivateRegistry(settings.MachineProvisionImage.Get())
+	pullSecret, err := h.getImagePullSecret(meta, machine, imageName)
 	if err != nil {
 		return driverArgs{}, err
 	}
@@ -110,9 +122,10 @@ func (h *handler) getArgsEnvAndStatus(typeMeta meta.Type, meta metav1.Object, da
 
 	return driverArgs{
 		DriverName:          driver,
-		ImageName:           settings.PrefixPrivateRegistry(settings.MachineProvisionImage.Get()),
+		ImageName:           imageName,
 		ImagePullPolicy:     corev1.PullAlways,
 		EnvSecret:           secret,
+		ImagePullSecret:     pullSecret,
 		StateSecretName:     secretName,
 		BootstrapSecretName: bootstrapName,
 		BootstrapOptional:   !create,
@@ -134,7 +147,7 @@ func (h *handler) getBootstrapSecret(machine *capi.Machine) (string, error) {
 
 	gvk := schema.FromAPIVersionAndKind(machine.Spec.Bootstrap.ConfigRef.APIVersion,
 		machine.Spec.Bootstrap.ConfigRef.Kind)
-	bootstrap, err := h.dynamic.Get(gvk, machine.Namespace, machine.Spec.Bootstrap.ConfigRef.Name)
+	bootstrap, err := h.dynamicGetter.Get(gvk, machine.Namespace, machine.Spec.Bootstrap.ConfigRef.Name)
 	if apierror.IsNotFound(err) {
 		return "", nil
 	} else if err != nil {
@@ -148,16 +161,12 @@ func (h *handler) getBootstrapSecret(machine *capi.Machine) (string, error) {
 	return d.String("status", "dataSecretName"), nil
 }
 
-func (h *handler) getSecretData(meta metav1.Object, obj data.Object, create bool) (string, string, map[string]string, error) {
+func (h *handler) getMachine(meta metav1.Object) (*capi.Machine, error) {
 	var (
 		err     error
 		machine *capi.Machine
-		result  = map[string]string{}
 	)
 
-	oldCredential := obj.String("status", "cloudCredentialSecretName")
-	cloudCredentialSecretName := obj.String("spec", "common", "cloudCredentialSecretName")
-
 	for _, ref := range meta.GetOwnerReferences() {
 		if ref.Kind != "Machine" {
 			continue
@@ -165,10 +174,18 @@ func (h *handler) getSecretData(meta metav1.Object, obj data.Object, create bool
 
 		machine, err = h.machines.Get(meta.GetNamespace(), ref.Name)
 		if err != nil && !apierror.IsNotFound(err) {
-			return "", "", nil, err
+			return nil, err
 		}
 	}
 
+	return machine, nil
+}
+
+func (h *handler) getSecretData(meta metav1.Object, machine *capi.Machine, obj data.Object, create bool) (string, string, map[string]string, error) {
+	result := map[string]string{}
+	oldCredential := obj.String("status", "cloudCredentialSecretName")
+	cloudCredentialSecretName := obj.String("spec", "common", "cloudCredentialSecretName")
+
 	if machine == nil && create {
 		return "", "", nil, generic.ErrSkip
 	}
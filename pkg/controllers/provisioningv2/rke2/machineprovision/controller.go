@@ -3,6 +3,7 @@
 import (
 	"context"
 	errors2 "errors"
+	"io"
 	"path"
 	"strings"
 	"time"
@@ -11,8 +12,10 @@
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/controllers/management/drivers/nodedriver"
 	"github.com/rancher/rancher/pkg/controllers/management/node"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/dynamicget"
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
 	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/condition"
@@ -22,6 +25,7 @@
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/pkg/generic"
 	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/sirupsen/logrus"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
@@ -37,12 +41,16 @@ type handler struct {
 	ctx             context.Context
 	apply           apply.Apply
 	jobs            batchcontrollers.JobCache
+	jobController   batchcontrollers.JobController
 	pods            corecontrollers.PodCache
+	podLogs         podLogsFunc
 	secrets         corecontrollers.SecretCache
 	machines        capicontrollers.MachineCache
+	clusters        provisioningcontrollers.ClusterCache
 	namespaces      corecontrollers.NamespaceCache
 	nodeDriverCache mgmtcontrollers.NodeDriverCache
 	dynamic         *dynamic.Controller
+	dynamicGetter   *dynamicget.Getter
 }
 
 func Register(ctx context.Context, clients *wrangler.Context) {
@@ -57,11 +65,17 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 				clients.Batch.Job()),
 		pods:            clients.Core.Pod().Cache(),
 		jobs:            clients.Batch.Job().Cache(),
+		jobController:   clients.Batch.Job(),
 		secrets:         clients.Core.Secret().Cache(),
 		machines:        clients.CAPI.Machine().Cache(),
+		clusters:        clients.Provisioning.Cluster().Cache(),
 		nodeDriverCache: clients.Mgmt.NodeDriver().Cache(),
 		namespaces:      clients.Core.Namespace().Cache(),
 		dynamic:         clients.Dynamic,
+		dynamicGetter:   dynamicget.New(clients),
+		podLogs: func(namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+			return clients.K8s.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(ctx)
+		},
 	}
 
 	removeHandler := generic.NewRemoveHandler("machine-provision-remove", clients.Dynamic.Update, h.OnRemove)
@@ -92,7 +106,7 @@ func (h *handler) OnJobChange(key string, job *batchv1.Job) (*batchv1.Job, error
 		return job, nil
 	}
 
-	infraMachine, err := h.dynamic.Get(schema.GroupVersionKind{
+	infraMachine, err := h.dynamicGetter.Get(schema.GroupVersionKind{
 		Group:   group,
 		Version: version,
 		Kind:    kind,
@@ -100,6 +114,9 @@ func (h *handler) OnJobChange(key string, job *batchv1.Job) (*batchv1.Job, error
 	if apierror.IsNotFound(err) {
 		// ignore err
 		return job, nil
+	} else if dynamicget.IsNotReady(err) {
+		h.jobController.EnqueueAfter(job.Namespace, job.Name, dynamicget.RetryDelay)
+		return job, nil
 	} else if err != nil {
 		return job, err
 	}
@@ -162,7 +179,16 @@ func (h *handler) getMachineStatus(job *batchv1.Job) (rkev1.RKEMachineStatus, er
 		}
 
 		if lastPod != nil {
-			return getMachineStatusFromPod(lastPod), nil
+			status := getMachineStatusFromPod(lastPod)
+			if status.FailureReason != "" {
+				failureLog, err := h.getFailureLog(lastPod)
+				if err != nil {
+					// the failure is still reported without the log
+					logrus.Warnf("failed to read the log of machine provision pod %s/%s: %v", lastPod.Namespace, lastPod.Name, err)
+				}
+				status.FailureLog = failureLog
+			}
+			return status, nil
 		}
 	}
 
@@ -208,7 +234,9 @@ func (h *handler) OnRemove(_ string, obj runtime.Object) (runtime.Object, error)
 	}
 
 	obj, err := h.run(obj, false)
-	if err != nil {
+	if dynamicget.IsNotReady(err) {
+		return nil, h.enqueueAfterNotReady(obj, generic.ErrSkip)
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -235,9 +263,26 @@ func (h *handler) OnChange(obj runtime.Object) (runtime.Object, error) {
 	if newObj == nil {
 		newObj = obj
 	}
+	if dynamicget.IsNotReady(err) {
+		// don't surface an error condition while caches are warming up
+		return newObj, h.enqueueAfterNotReady(newObj, nil)
+	}
 	return setCondition(h.dynamic, newObj, "CreateJob", err)
 }
 
+// enqueueAfterNotReady requeues the infra machine after a lookup returned dynamicget.ErrNotReady and
+// returns err if the requeue succeeded.
+func (h *handler) enqueueAfterNotReady(obj runtime.Object, err error) error {
+	meta, metaErr := meta.Accessor(obj)
+	if metaErr != nil {
+		return metaErr
+	}
+	if enqueueErr := h.dynamic.EnqueueAfter(obj.GetObjectKind().GroupVersionKind(), meta.GetNamespace(), meta.GetName(), dynamicget.RetryDelay); enqueueErr != nil {
+		return enqueueErr
+	}
+	return err
+}
+
 func (h *handler) run(obj runtime.Object, create bool) (runtime.Object, error) {
 	typeMeta, err := meta.TypeAccessor(obj)
 	if err != nil {
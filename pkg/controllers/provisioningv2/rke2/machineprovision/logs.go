@@ -0,0 +1,65 @@
+package machineprovision
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// failureLogLines is the number of lines of the machine container log recorded when the provision job fails
+	failureLogLines = 20
+	// maxFailureLogSize bounds the size of the recorded log, only the end of longer logs is kept
+	maxFailureLogSize = 4096
+)
+
+type podLogsFunc func(namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+
+// getFailureLog returns the last lines of the log of the machine container of the pod
+func (h *handler) getFailureLog(pod *corev1.Pod) (string, error) {
+	tailLines := int64(failureLogLines)
+	logs, err := h.podLogs(pod.Namespace, pod.Name, &corev1.PodLogOptions{
+		Container: "machine",
+		TailLines: &tailLines,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer logs.Close()
+
+	data, truncated, err := tail(logs, maxFailureLogSize)
+	if err != nil {
+		return "", err
+	}
+	if truncated {
+		// drop the partial first line
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// tail reads r to the end and returns at most the last size bytes of it and whether anything was dropped
+func tail(r io.Reader, size int) ([]byte, bool, error) {
+	var (
+		result    []byte
+		truncated bool
+		buf       = make([]byte, size)
+	)
+	for {
+		n, err := r.Read(buf)
+		result = append(result, buf[:n]...)
+		if len(result) > size {
+			result = append([]byte(nil), result[len(result)-size:]...)
+			truncated = true
+		}
+		if err == io.EOF {
+			return result, truncated, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package machineprovision
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type fakePodCache struct {
+	corecontrollers.PodCache
+	pods []*corev1.Pod
+}
+
+func (f fakePodCache) List(namespace string, selector labels.Selector) ([]*corev1.Pod, error) {
+	return f.pods, nil
+}
+
+func newFailedJob() *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine-provision"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": "machine-provision"}},
+		},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{
+				Type:   batchv1.JobFailed,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+}
+
+func newFailedPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine-provision-abcde"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "machine",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "failed to create instance"},
+				},
+			}},
+		},
+	}
+}
+
+func newLogHandler(log string, opts *corev1.PodLogOptions) *handler {
+	return &handler{
+		pods: fakePodCache{pods: []*corev1.Pod{newFailedPod()}},
+		podLogs: func(namespace, name string, o *corev1.PodLogOptions) (io.ReadCloser, error) {
+			if namespace != "fleet-default" || name != "machine-provision-abcde" {
+				return nil, fmt.Errorf("unexpected pod %s/%s", namespace, name)
+			}
+			*opts = *o
+			return ioutil.NopCloser(strings.NewReader(log)), nil
+		},
+	}
+}
+
+func TestGetMachineStatusFailureLog(t *testing.T) {
+	opts := &corev1.PodLogOptions{}
+	h := newLogHandler("creating instance\nerror: quota exceeded\n", opts)
+
+	status, err := h.getMachineStatus(newFailedJob())
+	require.NoError(t, err)
+	assert.Equal(t, "failed to create instance", status.FailureMessage)
+	assert.Equal(t, "creating instance\nerror: quota exceeded", status.FailureLog)
+	assert.Equal(t, "machine", opts.Container)
+	require.NotNil(t, opts.TailLines)
+	assert.Equal(t, int64(failureLogLines), *opts.TailLines)
+}
+
+func TestGetMachineStatusFailureLogBounded(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("line %d %s", i, strings.Repeat("x", 100)))
+	}
+	h := newLogHandler(strings.Join(lines, "\n"), &corev1.PodLogOptions{})
+
+	status, err := h.getMachineStatus(newFailedJob())
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(status.FailureLog), maxFailureLogSize)
+	assert.True(t, strings.HasSuffix(status.FailureLog, lines[len(lines)-1]), "expected the end of the log to be kept")
+	assert.True(t, strings.HasPrefix(status.FailureLog, "line "), "expected the log to start at a whole line")
+}
+
+func TestGetMachineStatusFailureLogError(t *testing.T) {
+	h := &handler{
+		pods: fakePodCache{pods: []*corev1.Pod{newFailedPod()}},
+		podLogs: func(namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+			return nil, fmt.Errorf("pod is gone")
+		},
+	}
+
+	status, err := h.getMachineStatus(newFailedJob())
+	require.NoError(t, err, "expected the failure to be reported without the log")
+	assert.Equal(t, "failed to create instance", status.FailureMessage)
+	assert.Empty(t, status.FailureLog)
+}
@@ -0,0 +1,95 @@
+package machineprovision
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+const defaultRegistry = "docker.io"
+
+type dockerAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// imageRegistry returns the registry host of the image, images without a registry are pulled from docker.io
+func imageRegistry(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+	return defaultRegistry
+}
+
+// getImagePullSecret returns a dockerconfigjson secret for pulling the image with the auth config the cluster of the
+// machine has for the registry of the image, or nil if the cluster has no auth config for it
+func (h *handler) getImagePullSecret(meta metav1.Object, machine *capi.Machine, image string) (*corev1.Secret, error) {
+	if machine == nil || machine.Spec.ClusterName == "" {
+		return nil, nil
+	}
+
+	cluster, err := h.clusters.Get(machine.Namespace, machine.Spec.ClusterName)
+	if apierror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if cluster.Spec.RKEConfig == nil || cluster.Spec.RKEConfig.Registries == nil {
+		return nil, nil
+	}
+
+	registry := imageRegistry(image)
+	config, ok := cluster.Spec.RKEConfig.Registries.Configs[registry]
+	if !ok || config.AuthConfigSecretName == "" {
+		return nil, nil
+	}
+
+	authSecret, err := h.secrets.Get(machine.Namespace, config.AuthConfigSecretName)
+	if err != nil {
+		return nil, err
+	}
+	if authSecret.Type != rkev1.AuthConfigSecretType {
+		return nil, fmt.Errorf("secret [%s] must be of type [%s]", config.AuthConfigSecretName, rkev1.AuthConfigSecretType)
+	}
+
+	auth := dockerAuth{
+		Username:      string(authSecret.Data[rkev1.UsernameAuthConfigSecretKey]),
+		Password:      string(authSecret.Data[rkev1.PasswordAuthConfigSecretKey]),
+		Auth:          string(authSecret.Data[rkev1.AuthAuthConfigSecretKey]),
+		IdentityToken: string(authSecret.Data[rkev1.IdentityTokenAuthConfigSecretKey]),
+	}
+	if auth.Auth == "" && auth.Username != "" {
+		auth.Auth = base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+	}
+
+	dockerConfig, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]dockerAuth{
+			registry: auth,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name2.SafeConcatName(meta.GetName(), "machine", "pull", "secret"),
+			Namespace: meta.GetNamespace(),
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: dockerConfig,
+		},
+	}, nil
+}
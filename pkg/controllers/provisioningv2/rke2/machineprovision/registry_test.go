@@ -0,0 +1,162 @@
+package machineprovision
+
+import (
+	"encoding/json"
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+type fakeClusterCache struct {
+	provisioningcontrollers.ClusterCache
+	cluster *rancherv1.Cluster
+}
+
+func (f fakeClusterCache) Get(namespace, name string) (*rancherv1.Cluster, error) {
+	if f.cluster == nil || f.cluster.Name != name {
+		return nil, apierror.NewNotFound(schema.GroupResource{Group: "provisioning.cattle.io", Resource: "clusters"}, name)
+	}
+	return f.cluster, nil
+}
+
+type fakeSecretCache struct {
+	corecontrollers.SecretCache
+	secrets map[string]*corev1.Secret
+}
+
+func (f fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	secret, ok := f.secrets[name]
+	if !ok {
+		return nil, apierror.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	return secret, nil
+}
+
+func newRegistryHandler(registries *rkev1.Registry) *handler {
+	cluster := &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "cluster"},
+		Spec: rancherv1.ClusterSpec{
+			RKEConfig: &rancherv1.RKEConfig{},
+		},
+	}
+	cluster.Spec.RKEConfig.Registries = registries
+
+	return &handler{
+		clusters: fakeClusterCache{cluster: cluster},
+		secrets: fakeSecretCache{secrets: map[string]*corev1.Secret{
+			"registry-auth": {
+				Type: rkev1.AuthConfigSecretType,
+				Data: map[string][]byte{
+					rkev1.UsernameAuthConfigSecretKey: []byte("admin"),
+					rkev1.PasswordAuthConfigSecretKey: []byte("s3cr3t"),
+				},
+			},
+			"registry-tls": {
+				Type: corev1.SecretTypeTLS,
+			},
+		}},
+	}
+}
+
+func newClusterMachine() *capi.Machine {
+	return &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine"},
+		Spec:       capi.MachineSpec{ClusterName: "cluster"},
+	}
+}
+
+func TestImageRegistry(t *testing.T) {
+	assert.Equal(t, "docker.io", imageRegistry("rancher/machine:v0.15.0"))
+	assert.Equal(t, "docker.io", imageRegistry("machine"))
+	assert.Equal(t, "registry.example.com", imageRegistry("registry.example.com/rancher/machine:v0.15.0"))
+	assert.Equal(t, "registry.example.com:5000", imageRegistry("registry.example.com:5000/rancher/machine:v0.15.0"))
+	assert.Equal(t, "localhost", imageRegistry("localhost/rancher/machine:v0.15.0"))
+}
+
+func TestGetImagePullSecret(t *testing.T) {
+	meta := &metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine"}
+	image := "registry.example.com/rancher/machine:v0.15.0"
+
+	h := newRegistryHandler(&rkev1.Registry{
+		Configs: map[string]rkev1.RegistryConfig{
+			"registry.example.com": {AuthConfigSecretName: "registry-auth"},
+		},
+	})
+	secret, err := h.getImagePullSecret(meta, newClusterMachine(), image)
+	require.NoError(t, err)
+	require.NotNil(t, secret)
+	assert.Equal(t, corev1.SecretTypeDockerConfigJson, secret.Type)
+	assert.Equal(t, "fleet-default", secret.Namespace)
+
+	config := struct {
+		Auths map[string]dockerAuth `json:"auths"`
+	}{}
+	require.NoError(t, json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config))
+	assert.Equal(t, dockerAuth{
+		Username: "admin",
+		Password: "s3cr3t",
+		Auth:     "YWRtaW46czNjcjN0",
+	}, config.Auths["registry.example.com"])
+
+	// the secret is only built for the registry of the image
+	secret, err = h.getImagePullSecret(meta, newClusterMachine(), "rancher/machine:v0.15.0")
+	require.NoError(t, err)
+	assert.Nil(t, secret)
+
+	// a machine without a cluster, as when deleting
+	secret, err = h.getImagePullSecret(meta, nil, image)
+	require.NoError(t, err)
+	assert.Nil(t, secret)
+
+	h = newRegistryHandler(nil)
+	secret, err = h.getImagePullSecret(meta, newClusterMachine(), image)
+	require.NoError(t, err)
+	assert.Nil(t, secret, "expected no pull secret without registries")
+
+	h = newRegistryHandler(&rkev1.Registry{
+		Configs: map[string]rkev1.RegistryConfig{
+			"registry.example.com": {AuthConfigSecretName: "registry-tls"},
+		},
+	})
+	_, err = h.getImagePullSecret(meta, newClusterMachine(), image)
+	assert.Error(t, err, "expected an auth config secret of another type to be rejected")
+}
+
+func TestJobImagePullSecrets(t *testing.T) {
+	h := &handler{}
+	args := driverArgs{
+		EnvSecret:       &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "machine-driver-secret"}},
+		StateSecretName: "machine-state",
+	}
+	typeMeta := &metav1.TypeMeta{APIVersion: "rke-machine.cattle.io/v1", Kind: "Amazonec2Machine"}
+	meta := &metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine"}
+
+	objs, err := h.objects(false, typeMeta, meta, args, nil)
+	require.NoError(t, err)
+	for _, obj := range objs {
+		if job, ok := obj.(*batchv1.Job); ok {
+			assert.Empty(t, job.Spec.Template.Spec.ImagePullSecrets)
+		}
+	}
+
+	args.ImagePullSecret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "machine-pull-secret"}}
+	objs, err = h.objects(false, typeMeta, meta, args, nil)
+	require.NoError(t, err)
+	assert.Contains(t, objs, args.ImagePullSecret)
+	for _, obj := range objs {
+		if job, ok := obj.(*batchv1.Job); ok {
+			assert.Equal(t, []corev1.LocalObjectReference{{Name: "machine-pull-secret"}}, job.Spec.Template.Spec.ImagePullSecrets)
+		}
+	}
+}
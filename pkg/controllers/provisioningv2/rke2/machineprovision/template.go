@@ -1,6 +1,10 @@
 package machineprovision
 
 import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rancher/rancher/pkg/settings"
 	name2 "github.com/rancher/wrangler/pkg/name"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -19,10 +23,43 @@
 	pathToMachineFiles = "/path/to/machine/files"
 )
 
+// defaultTolerations let the provision jobs run on the tainted control plane and etcd nodes of the local cluster
+var defaultTolerations = []corev1.Toleration{
+	{
+		Key:      "node-role.kubernetes.io/controlplane",
+		Operator: corev1.TolerationOpExists,
+	},
+	{
+		Key:      "node-role.kubernetes.io/control-plane",
+		Operator: corev1.TolerationOpExists,
+	},
+	{
+		Key:      "node-role.kubernetes.io/master",
+		Operator: corev1.TolerationOpExists,
+	},
+	{
+		Key:      "node-role.kubernetes.io/etcd",
+		Operator: corev1.TolerationOpExists,
+	},
+}
+
 func getJobName(name string) string {
 	return name2.SafeConcatName(name, "machine", "provision")
 }
 
+// jobTolerations returns the default tolerations and the tolerations of the machine-provision-job-tolerations setting
+func jobTolerations() ([]corev1.Toleration, error) {
+	tolerations := append([]corev1.Toleration{}, defaultTolerations...)
+	if value := settings.MachineProvisionJobTolerations.Get(); value != "" {
+		var custom []corev1.Toleration
+		if err := json.Unmarshal([]byte(value), &custom); err != nil {
+			return nil, fmt.Errorf("failed to parse setting %s: %w", settings.MachineProvisionJobTolerations.Name, err)
+		}
+		tolerations = append(tolerations, custom...)
+	}
+	return tolerations, nil
+}
+
 func (h *handler) objects(ready bool, typeMeta metav1.Type, meta metav1.Object, args driverArgs, filesSecret *corev1.Secret) ([]runtime.Object, error) {
 	var volumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
@@ -40,6 +77,16 @@ func (h *handler) objects(ready bool, typeMeta metav1.Type, meta metav1.Object,
 		return []runtime.Object{secret}, nil
 	}
 
+	tolerations, err := jobTolerations()
+	if err != nil {
+		return nil, err
+	}
+
+	var imagePullSecrets []corev1.LocalObjectReference
+	if args.ImagePullSecret != nil {
+		imagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReference{Name: args.ImagePullSecret.Name})
+	}
+
 	if args.BootstrapOptional && args.BootstrapSecretName == "" {
 		args.BootstrapSecretName = "not-found"
 	}
@@ -199,12 +246,14 @@ func (h *handler) objects(ready bool, typeMeta metav1.Type, meta metav1.Object,
 						},
 					},
 					ServiceAccountName: saName,
+					Tolerations:        tolerations,
+					ImagePullSecrets:   imagePullSecrets,
 				},
 			},
 		},
 	}
 
-	return []runtime.Object{
+	objs := []runtime.Object{
 		args.EnvSecret,
 		secret,
 		sa,
@@ -213,5 +262,9 @@ func (h *handler) objects(ready bool, typeMeta metav1.Type, meta metav1.Object,
 		filesSecret,
 		rb2,
 		job,
-	}, nil
+	}
+	if args.ImagePullSecret != nil {
+		objs = append(objs, args.ImagePullSecret)
+	}
+	return objs, nil
 }
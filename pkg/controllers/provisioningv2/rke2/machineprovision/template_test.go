@@ -0,0 +1,58 @@
+package machineprovision
+
+import (
+	"testing"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func provisionJob(t *testing.T) (*batchv1.Job, error) {
+	h := &handler{}
+	objs, err := h.objects(false,
+		&metav1.TypeMeta{APIVersion: "rke-machine.cattle.io/v1", Kind: "Amazonec2Machine"},
+		&metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine"},
+		driverArgs{
+			EnvSecret:       &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "machine-driver-secret"}},
+			StateSecretName: "machine-state",
+		}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if job, ok := obj.(*batchv1.Job); ok {
+			return job, nil
+		}
+	}
+	t.Fatal("no job in the objects")
+	return nil, nil
+}
+
+func TestJobTolerations(t *testing.T) {
+	original := settings.MachineProvisionJobTolerations.Get()
+	defer settings.MachineProvisionJobTolerations.Set(original)
+
+	require.NoError(t, settings.MachineProvisionJobTolerations.Set(""))
+	job, err := provisionJob(t)
+	require.NoError(t, err)
+	assert.Equal(t, defaultTolerations, job.Spec.Template.Spec.Tolerations)
+
+	require.NoError(t, settings.MachineProvisionJobTolerations.Set(`[{"key":"dedicated","operator":"Equal","value":"provisioning","effect":"NoSchedule"}]`))
+	job, err = provisionJob(t)
+	require.NoError(t, err)
+	assert.Subset(t, job.Spec.Template.Spec.Tolerations, defaultTolerations)
+	assert.Contains(t, job.Spec.Template.Spec.Tolerations, corev1.Toleration{
+		Key:      "dedicated",
+		Operator: corev1.TolerationOpEqual,
+		Value:    "provisioning",
+		Effect:   corev1.TaintEffectNoSchedule,
+	})
+
+	require.NoError(t, settings.MachineProvisionJobTolerations.Set(`{"key":"dedicated"}`))
+	_, err = provisionJob(t)
+	assert.Error(t, err)
+}
@@ -11,6 +11,7 @@
 
 	"github.com/rancher/lasso/pkg/dynamic"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/dynamicget"
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
 	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
@@ -44,6 +45,7 @@ type handler struct {
 	mgmtClusterCache     mgmtcontrollers.ClusterCache
 	rkeControlPlaneCache rkecontroller.RKEControlPlaneCache
 	dynamic              *dynamic.Controller
+	dynamicGetter        *dynamicget.Getter
 }
 
 func Register(ctx context.Context, clients *wrangler.Context) {
@@ -57,6 +59,7 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 		provClusterCache:     clients.Provisioning.Cluster().Cache(),
 		rkeControlPlaneCache: clients.RKE.RKEControlPlane().Cache(),
 		dynamic:              clients.Dynamic,
+		dynamicGetter:        dynamicget.New(clients),
 	}
 	clients.CAPI.Machine().OnChange(ctx, "machine-status", h.OnChange)
 
@@ -185,7 +188,10 @@ func (h *handler) OnChange(key string, machine *capi.Machine) (*capi.Machine, er
 	}
 
 	status, reason, message, providerID, err := h.getInfraMachineState(machine)
-	if err != nil {
+	if dynamicget.IsNotReady(err) {
+		h.machines.EnqueueAfter(machine.Namespace, machine.Name, dynamicget.RetryDelay)
+		return machine, nil
+	} else if err != nil {
 		return machine, err
 	}
 
@@ -253,7 +259,7 @@ func (h *handler) OnChange(key string, machine *capi.Machine) (*capi.Machine, er
 
 func (h *handler) getInfraMachineState(capiMachine *capi.Machine) (status corev1.ConditionStatus, reason, message, providerID string, err error) {
 	gvk := schema.FromAPIVersionAndKind(capiMachine.Spec.InfrastructureRef.APIVersion, capiMachine.Spec.InfrastructureRef.Kind)
-	machine, err := h.dynamic.Get(gvk, capiMachine.Namespace, capiMachine.Spec.InfrastructureRef.Name)
+	machine, err := h.dynamicGetter.Get(gvk, capiMachine.Namespace, capiMachine.Spec.InfrastructureRef.Name)
 	if apierror.IsNotFound(err) {
 		return corev1.ConditionUnknown, "NoMachineDefined", "waiting for machine to be defined", "", nil
 	} else if err != nil {
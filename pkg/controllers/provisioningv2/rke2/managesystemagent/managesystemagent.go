@@ -12,6 +12,7 @@
 	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/image"
 	namespaces "github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/systemtemplate"
@@ -27,6 +28,14 @@
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+const (
+	// RolloutGroupLabel puts a cluster in a rollout group of the managed system agent
+	RolloutGroupLabel = "provisioning.cattle.io/system-agent-rollout-group"
+	// RolloutGroupsAnnotation holds the comma separated rollout groups the managed system agent of the cluster is
+	// rolled out to, the cluster is only targeted once it is labeled with one of the groups
+	RolloutGroupsAnnotation = "provisioning.cattle.io/system-agent-rollout-groups"
+)
+
 type handler struct {
 	clusterRegistrationTokens v3.ClusterRegistrationTokenCache
 }
@@ -77,7 +86,7 @@ func (h *handler) OnChange(cluster *rancherv1.Cluster, status rancherv1.ClusterS
 
 		digest := sha256.New()
 		digest.Write([]byte(settings.InternalServerURL.Get()))
-		digest.Write([]byte(token.Status.Token))
+		digest.Write([]byte(token.AgentToken()))
 		digest.Write([]byte(systemtemplate.InternalCAChecksum()))
 		d := digest.Sum(nil)
 		secretName += hex.EncodeToString(d[:])[:12]
@@ -89,7 +98,7 @@ func (h *handler) OnChange(cluster *rancherv1.Cluster, status rancherv1.ClusterS
 			},
 			Data: map[string][]byte{
 				"CATTLE_SERVER":      []byte(settings.InternalServerURL.Get()),
-				"CATTLE_TOKEN":       []byte(token.Status.Token),
+				"CATTLE_TOKEN":       []byte(token.AgentToken()),
 				"CATTLE_CA_CHECKSUM": []byte(systemtemplate.InternalCAChecksum()),
 			},
 		})
@@ -110,31 +119,49 @@ func (h *handler) OnChange(cluster *rancherv1.Cluster, status rancherv1.ClusterS
 				DefaultNamespace: namespaces.System,
 			},
 			Resources: resources,
-			Targets: []v1alpha1.BundleTarget{
-				{
-					ClusterName: cluster.Name,
-					ClusterSelector: &metav1.LabelSelector{
-						MatchExpressions: []metav1.LabelSelectorRequirement{
-							{
-								Key:      "provisioning.cattle.io/unmanaged-system-agent",
-								Operator: metav1.LabelSelectorOpDoesNotExist,
-							},
-						},
-					},
-				},
-			},
+			Targets:   systemAgentTargets(cluster),
 		},
 	})
 
 	return result, status, nil
 }
 
-func installer(allWorkers bool, secretName string) []runtime.Object {
-	image := strings.SplitN(settings.SystemAgentUpgradeImage.Get(), ":", 2)
-	version := "latest"
-	if len(image) == 2 {
-		version = image[1]
+// systemAgentTargets targets the cluster unless its system agent is unmanaged. If the cluster has rollout groups, it
+// is only targeted if it is in one of the groups.
+func systemAgentTargets(cluster *rancherv1.Cluster) []v1alpha1.BundleTarget {
+	selector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      "provisioning.cattle.io/unmanaged-system-agent",
+				Operator: metav1.LabelSelectorOpDoesNotExist,
+			},
+		},
+	}
+
+	var groups []string
+	for _, group := range strings.Split(cluster.Annotations[RolloutGroupsAnnotation], ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) > 0 {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      RolloutGroupLabel,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   groups,
+		})
+	}
+
+	return []v1alpha1.BundleTarget{
+		{
+			ClusterName:     cluster.Name,
+			ClusterSelector: selector,
+		},
 	}
+}
+
+func installer(allWorkers bool, secretName string) []runtime.Object {
+	upgradeImage, version := image.SystemAgentUpgradeImage()
 
 	env := []corev1.EnvVar{{
 		Name:  "CATTLE_SERVER_QUERY",
@@ -167,7 +194,7 @@ func installer(allWorkers bool, secretName string) []runtime.Object {
 				NodeSelector:       &metav1.LabelSelector{},
 				ServiceAccountName: "system-agent-upgrader",
 				Upgrade: &upgradev1.ContainerSpec{
-					Image: settings.PrefixPrivateRegistry(image[0]),
+					Image: upgradeImage,
 					Env:   env,
 					EnvFrom: []corev1.EnvFromSource{{
 						SecretRef: &corev1.SecretEnvSource{
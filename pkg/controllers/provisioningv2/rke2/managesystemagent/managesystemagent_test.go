@@ -0,0 +1,85 @@
+package managesystemagent
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestSystemAgentTargets(t *testing.T) {
+	tests := []struct {
+		name          string
+		rolloutGroups string
+		clusterLabels map[string]string
+		targeted      bool
+	}{
+		{
+			name:     "cluster without rollout groups",
+			targeted: true,
+		},
+		{
+			name:          "cluster without rollout groups in a group",
+			clusterLabels: map[string]string{RolloutGroupLabel: "canary"},
+			targeted:      true,
+		},
+		{
+			name:          "unmanaged system agent",
+			clusterLabels: map[string]string{"provisioning.cattle.io/unmanaged-system-agent": "true"},
+		},
+		{
+			name:          "cluster in the rollout group",
+			rolloutGroups: "canary",
+			clusterLabels: map[string]string{RolloutGroupLabel: "canary"},
+			targeted:      true,
+		},
+		{
+			name:          "cluster in one of the rollout groups",
+			rolloutGroups: "canary, early",
+			clusterLabels: map[string]string{RolloutGroupLabel: "early"},
+			targeted:      true,
+		},
+		{
+			name:          "cluster in another group",
+			rolloutGroups: "canary",
+			clusterLabels: map[string]string{RolloutGroupLabel: "stable"},
+		},
+		{
+			name:          "cluster not in a group",
+			rolloutGroups: "canary",
+		},
+		{
+			name:          "unmanaged system agent in the rollout group",
+			rolloutGroups: "canary",
+			clusterLabels: map[string]string{
+				RolloutGroupLabel: "canary",
+				"provisioning.cattle.io/unmanaged-system-agent": "true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &rancherv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cluster",
+					Namespace: "fleet-default",
+				},
+			}
+			if tt.rolloutGroups != "" {
+				cluster.Annotations = map[string]string{RolloutGroupsAnnotation: tt.rolloutGroups}
+			}
+
+			targets := systemAgentTargets(cluster)
+			require.Len(t, targets, 1)
+			assert.Equal(t, "cluster", targets[0].ClusterName)
+
+			selector, err := metav1.LabelSelectorAsSelector(targets[0].ClusterSelector)
+			require.NoError(t, err)
+			assert.Equal(t, tt.targeted, selector.Matches(labels.Set(tt.clusterLabels)))
+		})
+	}
+}
@@ -11,6 +11,13 @@
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+const (
+	// SUCChartName and SUCChartVersion are the chart of the rancher-charts repo the system upgrade controller of the
+	// clusters provisioned by Rancher is installed from
+	SUCChartName    = "system-upgrade-controller"
+	SUCChartVersion = "100.0.0"
+)
+
 func (h *handler) OnChangeInstallSUC(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) ([]runtime.Object, rancherv1.ClusterStatus, error) {
 	if cluster.Spec.RKEConfig == nil {
 		return nil, status, nil
@@ -24,8 +31,8 @@ func (h *handler) OnChangeInstallSUC(cluster *rancherv1.Cluster, status rancherv
 		Spec: v3.ManagedChartSpec{
 			DefaultNamespace: namespaces.System,
 			RepoName:         "rancher-charts",
-			Chart:            "system-upgrade-controller",
-			Version:          "100.0.0",
+			Chart:            SUCChartName,
+			Version:          SUCChartVersion,
 			Values: &v1alpha1.GenericMap{
 				Data: map[string]interface{}{
 					"global": map[string]interface{}{
@@ -4,8 +4,10 @@
 	"context"
 	"errors"
 
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/bootstrap"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
 	v1 "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
 	"github.com/rancher/rancher/pkg/wrangler"
@@ -13,6 +15,7 @@
 	"github.com/rancher/wrangler/pkg/relatedresource"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
@@ -22,12 +25,14 @@
 )
 
 type handler struct {
-	planner *planner.Planner
+	planner      *planner.Planner
+	clusterCache rocontrollers.ClusterCache
 }
 
 func Register(ctx context.Context, clients *wrangler.Context, planner *planner.Planner) {
 	h := handler{
-		planner: planner,
+		planner:      planner,
+		clusterCache: clients.Provisioning.Cluster().Cache(),
 	}
 	v1.RegisterRKEControlPlaneStatusHandler(ctx,
 		clients.RKE.RKEControlPlane(), "", "planner", h.OnChange)
@@ -45,15 +50,31 @@ func Register(ctx context.Context, clients *wrangler.Context, planner *planner.P
 				Namespace: machine.Namespace,
 				Name:      machine.Spec.ClusterName,
 			}}, nil
+		} else if cluster, ok := obj.(*rancherv1.Cluster); ok {
+			// the plans are updated again once the cluster is resumed
+			return []relatedresource.Key{{
+				Namespace: cluster.Namespace,
+				Name:      cluster.Name,
+			}}, nil
 		}
 		return nil, nil
-	}, clients.RKE.RKEControlPlane(), clients.Core.Secret(), clients.CAPI.Machine())
+	}, clients.RKE.RKEControlPlane(), clients.Core.Secret(), clients.CAPI.Machine(), clients.Provisioning.Cluster())
 }
 
 func (h *handler) OnChange(cluster *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
 	status.ObservedGeneration = cluster.Generation
 
-	err := h.planner.Process(cluster)
+	if paused, err := h.paused(cluster); err != nil || paused {
+		return status, err
+	}
+
+	// the status shares the upgrade status with the cached object
+	status.UpgradeStatus = status.UpgradeStatus.DeepCopy()
+	if status.UpgradeStatus == nil {
+		status.UpgradeStatus = &rkev1.UpgradeStatus{}
+	}
+
+	err := h.planner.Process(cluster, status.UpgradeStatus)
 	var errWaiting planner.ErrWaiting
 	if errors.As(err, &errWaiting) {
 		logrus.Infof("rkecluster %s/%s: %v", cluster.Namespace, cluster.Name, err)
@@ -63,6 +84,26 @@ func (h *handler) OnChange(cluster *rkev1.RKEControlPlane, status rkev1.RKEContr
 		return status, nil
 	}
 
+	var errPlanTooLarge *planner.ErrPlanTooLarge
+	if errors.As(err, &errPlanTooLarge) {
+		// the plan won't fit until the cluster config changes, retrying doesn't help
+		logrus.Errorf("rkecluster %s/%s: %v", cluster.Namespace, cluster.Name, err)
+		Provisioned.SetError(&status, "PlanTooLarge", err)
+		return status, nil
+	}
+
 	Provisioned.SetError(&status, "", err)
 	return status, err
 }
+
+// paused returns whether the reconciliation of the provisioning cluster of the control plane is paused, the plans of
+// its machines are then left as is
+func (h *handler) paused(controlPlane *rkev1.RKEControlPlane) (bool, error) {
+	cluster, err := h.clusterCache.Get(controlPlane.Namespace, controlPlane.Spec.ClusterName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return cluster.Spec.Paused, nil
+}
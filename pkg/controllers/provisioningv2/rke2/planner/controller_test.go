@@ -0,0 +1,58 @@
+package planner
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeClusterCache struct {
+	rocontrollers.ClusterCache
+	clusters map[string]*rancherv1.Cluster
+}
+
+func (f fakeClusterCache) Get(namespace, name string) (*rancherv1.Cluster, error) {
+	if cluster, ok := f.clusters[namespace+"/"+name]; ok {
+		return cluster, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Group: "provisioning.cattle.io", Resource: "clusters"}, name)
+}
+
+func newControlPlane(clusterName string) *rkev1.RKEControlPlane {
+	return &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: clusterName, Generation: 2},
+		Spec:       rkev1.RKEControlPlaneSpec{ClusterName: clusterName},
+	}
+}
+
+func TestOnChangePaused(t *testing.T) {
+	paused := &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "paused"},
+		Spec:       rancherv1.ClusterSpec{Paused: true},
+	}
+	h := handler{
+		clusterCache: fakeClusterCache{clusters: map[string]*rancherv1.Cluster{"fleet-default/paused": paused}},
+	}
+
+	// the planner isn't set, it would fail if the plans were processed
+	status, err := h.OnChange(newControlPlane("paused"), rkev1.RKEControlPlaneStatus{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.ObservedGeneration)
+	assert.Nil(t, status.UpgradeStatus)
+
+	isPaused, err := h.paused(newControlPlane("removed"))
+	require.NoError(t, err)
+	assert.False(t, isPaused, "expected the plans of a control plane without cluster to be processed")
+
+	paused.Spec.Paused = false
+	isPaused, err = h.paused(newControlPlane("paused"))
+	require.NoError(t, err)
+	assert.False(t, isPaused, "expected the plans to be processed again once resumed")
+}
@@ -3,41 +3,72 @@
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/rancher/lasso/pkg/dynamic"
 	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/eventrecorder"
 	"github.com/rancher/rancher/pkg/features"
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
 	mgmtcontroller "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
 	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
 	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/condition"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/generic"
+	"github.com/rancher/wrangler/pkg/kv"
+	"github.com/rancher/wrangler/pkg/name"
 	"github.com/rancher/wrangler/pkg/relatedresource"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
 const (
-	byNodeInfra = "by-node-infra"
-	Provisioned = condition.Cond("Provisioned")
+	rkeClusterSetID   = "rke-cluster"
+	byNodeInfra       = "by-node-infra"
+	byCloudCredential = "by-cloud-credential"
+	Provisioned       = condition.Cond("Provisioned")
+	MachinePoolsReady = condition.Cond("MachinePoolsReady")
+	// KubeletArgsOverridden is true if the kubelet args of a machine pool override cluster-level kubelet args
+	KubeletArgsOverridden = condition.Cond("KubeletArgsOverridden")
+	// Paused is true while the reconciliation of the cluster is paused
+	Paused = condition.Cond("Paused")
+	// RestoreSucceeded is false if the etcd snapshot to restore was rejected or if the etcd members failed after the
+	// restore, and true once the restore is verified
+	RestoreSucceeded = condition.Cond("RestoreSucceeded")
+	// MachinePoolQuantitiesValid is false while a machine pool is scaled above the machine-pool-max-quantity setting
+	MachinePoolQuantitiesValid = condition.Cond("MachinePoolQuantitiesValid")
 )
 
 type handler struct {
+	apply             apply.Apply
 	dynamic           *dynamic.Controller
 	dynamicSchema     mgmtcontroller.DynamicSchemaCache
 	clusterCache      rocontrollers.ClusterCache
 	clusterController rocontrollers.ClusterController
 	secretCache       corecontrollers.SecretCache
 	secretClient      corecontrollers.SecretClient
+	userData          *userDataResolver
 	capiClusters      capicontrollers.ClusterCache
+	machineDeployment capicontrollers.MachineDeploymentCache
+	machines          capicontrollers.MachineCache
 	rkeControlPlane   rkecontroller.RKEControlPlaneCache
+	recorder          record.EventRecorder
+
+	scaleWarningsLock sync.Mutex
+	scaleWarnings     map[string]int32
 }
 
 func Register(ctx context.Context, clients *wrangler.Context) {
@@ -48,7 +79,17 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 		clusterCache:      clients.Provisioning.Cluster().Cache(),
 		clusterController: clients.Provisioning.Cluster(),
 		capiClusters:      clients.CAPI.Cluster().Cache(),
+		machineDeployment: clients.CAPI.MachineDeployment().Cache(),
+		machines:          clients.CAPI.Machine().Cache(),
 		rkeControlPlane:   clients.RKE.RKEControlPlane().Cache(),
+		recorder:          eventrecorder.New(ctx, clients.K8s, "provisioning-cluster-controller"),
+		userData: &userDataResolver{
+			ctx:                  ctx,
+			secrets:              clients.Core.Secret().Cache(),
+			configMaps:           clients.Core.ConfigMap().Cache(),
+			userAttributes:       clients.Mgmt.UserAttribute().Cache(),
+			subjectAccessReviews: clients.K8s.AuthorizationV1().SubjectAccessReviews(),
+		},
 	}
 
 	if features.MCM.Enabled() {
@@ -57,23 +98,28 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 
 	clients.Dynamic.OnChange(ctx, "rke", matchRKENodeGroup, h.infraWatch)
 	clients.Provisioning.Cluster().Cache().AddIndexer(byNodeInfra, byNodeInfraIndex)
+	clients.Provisioning.Cluster().Cache().AddIndexer(byCloudCredential, byCloudCredentialIndex)
+	clients.Provisioning.Cluster().Cache().AddIndexer(byUserData, byUserDataIndex)
+
+	h.apply = clients.Apply.
+		// Because capi wants to own objects we don't set ownerreference with apply
+		WithDynamicLookup().
+		WithCacheTypes(
+			clients.CAPI.Cluster(),
+			clients.CAPI.MachineDeployment(),
+			clients.RKE.RKEControlPlane(),
+			clients.RKE.RKECluster(),
+			clients.RKE.RKEBootstrapTemplate(),
+		)
 
-	rocontrollers.RegisterClusterGeneratingHandler(ctx,
+	// the generated objects are applied by OnChange rather than by a generating handler, so that they can be left as
+	// is while the cluster is paused
+	clients.Provisioning.Cluster().OnChange(ctx, rkeClusterSetID, h.OnRemove)
+	rocontrollers.RegisterClusterStatusHandler(ctx,
 		clients.Provisioning.Cluster(),
-		clients.Apply.
-			// Because capi wants to own objects we don't set ownerreference with apply
-			WithDynamicLookup().
-			WithCacheTypes(
-				clients.CAPI.Cluster(),
-				clients.CAPI.MachineDeployment(),
-				clients.RKE.RKEControlPlane(),
-				clients.RKE.RKECluster(),
-				clients.RKE.RKEBootstrapTemplate(),
-			),
 		"RKECluster",
-		"rke-cluster",
-		h.OnRancherClusterChange,
-		nil)
+		rkeClusterSetID,
+		h.OnChange)
 
 	relatedresource.Watch(ctx, "provisioning-cluster-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
 		if cp, ok := obj.(*rkev1.RKEControlPlane); ok {
@@ -82,8 +128,69 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 				Name:      cp.Spec.ClusterName,
 			}}, nil
 		}
+		if md, ok := obj.(*capi.MachineDeployment); ok {
+			return []relatedresource.Key{{
+				Namespace: namespace,
+				Name:      md.Spec.ClusterName,
+			}}, nil
+		}
+		if machine, ok := obj.(*capi.Machine); ok && machine.Labels[planner.EtcdRoleLabel] == "true" {
+			// the etcd members are verified after a restore
+			return []relatedresource.Key{{
+				Namespace: namespace,
+				Name:      machine.Spec.ClusterName,
+			}}, nil
+		}
 		return nil, nil
-	}, clients.Provisioning.Cluster(), clients.RKE.RKEControlPlane())
+	}, clients.Provisioning.Cluster(), clients.RKE.RKEControlPlane(), clients.CAPI.MachineDeployment(), clients.CAPI.Machine())
+
+	relatedresource.Watch(ctx, "provisioning-cluster-cloud-credential-trigger", h.clustersForCloudCredential,
+		clients.Provisioning.Cluster(), clients.Core.Secret())
+	relatedresource.Watch(ctx, "provisioning-cluster-user-data-trigger", h.clustersForUserData,
+		clients.Provisioning.Cluster(), clients.Core.Secret(), clients.Core.ConfigMap())
+}
+
+func byCloudCredentialIndex(obj *rancherv1.Cluster) ([]string, error) {
+	var result []string
+	seen := map[string]bool{}
+	add := func(secretName string) {
+		if secretName == "" || seen[secretName] {
+			return
+		}
+		seen[secretName] = true
+		result = append(result, toCloudCredentialKey(obj.Namespace, secretName))
+	}
+
+	add(obj.Spec.CloudCredentialSecretName)
+	if obj.Spec.RKEConfig != nil {
+		for _, np := range obj.Spec.RKEConfig.MachinePools {
+			add(np.CloudCredentialSecretName)
+		}
+	}
+
+	return result, nil
+}
+
+func toCloudCredentialKey(namespace, secretName string) string {
+	return namespace + "/" + secretName
+}
+
+// clustersForCloudCredential enqueues the clusters that use a secret as cloud credential, either for the cluster or
+// for one of its machine pools, when the secret changes
+func (h *handler) clustersForCloudCredential(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	clusters, err := h.clusterCache.GetByIndex(byCloudCredential, toCloudCredentialKey(namespace, name))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, cluster := range clusters {
+		result = append(result, relatedresource.Key{
+			Namespace: cluster.Namespace,
+			Name:      cluster.Name,
+		})
+	}
+	return result, nil
 }
 
 func byNodeInfraIndex(obj *rancherv1.Cluster) ([]string, error) {
@@ -147,6 +254,40 @@ func (h *handler) infraWatch(obj runtime.Object) (runtime.Object, error) {
 	return obj, nil
 }
 
+// OnRemove deletes the objects generated for a cluster once it is removed
+func (h *handler) OnRemove(key string, obj *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	if obj != nil {
+		return obj, nil
+	}
+
+	obj = &rancherv1.Cluster{}
+	obj.Namespace, obj.Name = kv.RSplit(key, "/")
+	obj.SetGroupVersionKind(rancherv1.SchemeGroupVersion.WithKind("Cluster"))
+
+	return nil, h.applyObjects(obj)
+}
+
+// OnChange applies the objects generated for a cluster, unless the cluster is paused
+func (h *handler) OnChange(obj *rancherv1.Cluster, status rancherv1.ClusterStatus) (rancherv1.ClusterStatus, error) {
+	if !obj.DeletionTimestamp.IsZero() {
+		return status, nil
+	}
+
+	objs, status, err := h.OnRancherClusterChange(obj, status)
+	if err != nil || obj.Spec.Paused {
+		return status, err
+	}
+
+	return status, h.applyObjects(obj, objs...)
+}
+
+func (h *handler) applyObjects(obj *rancherv1.Cluster, objs ...runtime.Object) error {
+	return generic.ConfigureApplyForObject(h.apply, obj, &generic.GeneratingHandlerOptions{}).
+		WithOwner(obj).
+		WithSetID(rkeClusterSetID).
+		ApplyObjects(objs...)
+}
+
 func (h *handler) OnRancherClusterChange(obj *rancherv1.Cluster, status rancherv1.ClusterStatus) ([]runtime.Object, rancherv1.ClusterStatus, error) {
 	if obj.Spec.RKEConfig == nil || obj.Status.ClusterName == "" {
 		return nil, status, nil
@@ -161,7 +302,35 @@ func (h *handler) OnRancherClusterChange(obj *rancherv1.Cluster, status rancherv
 		return nil, status, err
 	}
 
-	objs, err := objects(obj, h.dynamic, h.dynamicSchema, h.secretCache)
+	status, err = h.updateMachinePoolStatus(obj, status)
+	if err != nil {
+		return nil, status, err
+	}
+
+	status = updateKubeletArgsStatus(obj, status)
+	status = updatePausedStatus(obj, status)
+
+	status, err = h.updateRestoreStatus(obj, status)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if obj.Spec.Paused {
+		// the objects are not regenerated while paused, they are all applied again once resumed
+		return nil, status, nil
+	}
+
+	obj, status, err = h.validateMachinePoolQuantities(obj, status)
+	if err != nil {
+		return nil, status, err
+	}
+
+	obj, status, err = h.preflightRestore(obj, status)
+	if err != nil {
+		return nil, status, err
+	}
+
+	objs, err := objects(obj, h.dynamic, h.dynamicSchema, h.secretCache, h.userData)
 	return objs, status, err
 }
 
@@ -188,5 +357,127 @@ func (h *handler) updateClusterProvisioningStatus(cluster *rancherv1.Cluster, st
 	Provisioned.SetStatus(&status, Provisioned.GetStatus(cp))
 	Provisioned.Reason(&status, Provisioned.GetReason(cp))
 	Provisioned.Message(&status, Provisioned.GetMessage(cp))
+	if !equality.Semantic.DeepEqual(status.UpgradeStatus, cp.Status.UpgradeStatus) {
+		status.UpgradeStatus = cp.Status.UpgradeStatus.DeepCopy()
+	}
+	return status, nil
+}
+
+// updateMachinePoolStatus rolls up the readiness of the MachineDeployment of every machine pool
+func (h *handler) updateMachinePoolStatus(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) (rancherv1.ClusterStatus, error) {
+	var (
+		machinePools []rancherv1.MachinePoolStatus
+		notReady     []string
+	)
+
+	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		machinePoolStatus, err := h.machinePoolStatus(cluster, machinePool)
+		if err != nil {
+			return status, err
+		}
+		if machinePoolStatus.ReadyReplicas < machinePoolStatus.Replicas {
+			notReady = append(notReady, fmt.Sprintf("%s: %d/%d ready", machinePoolStatus.Name,
+				machinePoolStatus.ReadyReplicas, machinePoolStatus.Replicas))
+		}
+		machinePools = append(machinePools, machinePoolStatus)
+	}
+
+	if !equality.Semantic.DeepEqual(status.MachinePools, machinePools) {
+		status.MachinePools = machinePools
+	}
+	if len(machinePools) == 0 {
+		return status, nil
+	}
+
+	MachinePoolsReady.SetStatusBool(&status, len(notReady) == 0)
+	MachinePoolsReady.Message(&status, strings.Join(notReady, ", "))
 	return status, nil
 }
+
+// updateKubeletArgsStatus reports the kubelet args of the machine pools that override the kubelet-arg value of the
+// controlPlaneConfig or of a workerConfig of the cluster
+func updateKubeletArgsStatus(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) rancherv1.ClusterStatus {
+	clusterArgs := []interface{}{cluster.Spec.RKEConfig.ControlPlaneConfig.Data["kubelet-arg"]}
+	for _, nodeConfig := range cluster.Spec.RKEConfig.NodeConfig {
+		clusterArgs = append(clusterArgs, nodeConfig.Config.Data["kubelet-arg"])
+	}
+
+	var (
+		poolArgs  bool
+		overrides []string
+	)
+	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		if len(machinePool.KubeletArgs) == 0 {
+			continue
+		}
+		poolArgs = true
+
+		conflicts := map[string]bool{}
+		for _, args := range clusterArgs {
+			for _, conflict := range planner.KubeletArgConflicts(args, machinePool.KubeletArgs) {
+				conflicts[conflict] = true
+			}
+		}
+		if len(conflicts) == 0 {
+			continue
+		}
+
+		var names []string
+		for conflict := range conflicts {
+			names = append(names, conflict)
+		}
+		sort.Strings(names)
+		overrides = append(overrides, fmt.Sprintf("%s: %s", machinePool.Name, strings.Join(names, ", ")))
+	}
+
+	if !poolArgs && KubeletArgsOverridden.GetStatus(&status) == "" {
+		return status
+	}
+
+	KubeletArgsOverridden.SetStatusBool(&status, len(overrides) > 0)
+	KubeletArgsOverridden.Message(&status, strings.Join(overrides, "; "))
+	return status
+}
+
+// updatePausedStatus reports whether the reconciliation of the cluster is paused, the condition is only added once the
+// cluster is paused
+func updatePausedStatus(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) rancherv1.ClusterStatus {
+	if !cluster.Spec.Paused && Paused.GetStatus(&status) == "" {
+		return status
+	}
+
+	Paused.SetStatusBool(&status, cluster.Spec.Paused)
+	if cluster.Spec.Paused {
+		Paused.Message(&status, "the objects and plans of the cluster are not updated while paused")
+	} else {
+		Paused.Message(&status, "")
+	}
+	return status
+}
+
+func (h *handler) machinePoolStatus(cluster *rancherv1.Cluster, machinePool rancherv1.RKEMachinePool) (rancherv1.MachinePoolStatus, error) {
+	result := rancherv1.MachinePoolStatus{
+		Name: machinePool.Name,
+	}
+	if machinePool.Quantity != nil {
+		result.Replicas = *machinePool.Quantity
+	}
+
+	md, err := h.machineDeployment.Get(cluster.Namespace, name.SafeConcatName(cluster.Name, machinePool.Name))
+	if apierror.IsNotFound(err) {
+		// machine pools scaled to zero have no MachineDeployment
+		return result, nil
+	} else if err != nil {
+		return result, err
+	}
+
+	if md.Spec.Replicas != nil {
+		result.Replicas = *md.Spec.Replicas
+	} else {
+		result.Replicas = md.Status.Replicas
+	}
+	result.ReadyReplicas = md.Status.ReadyReplicas
+	result.AvailableReplicas = md.Status.AvailableReplicas
+	result.UpdatedReplicas = md.Status.UpdatedReplicas
+	return result, nil
+}
@@ -0,0 +1,372 @@
+package provisioningcluster
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+type fakeClusterCache struct {
+	clusters []*rancherv1.Cluster
+	indexers map[string]rocontrollers.ClusterIndexer
+}
+
+func (f *fakeClusterCache) Get(namespace, name string) (*rancherv1.Cluster, error) {
+	for _, cluster := range f.clusters {
+		if cluster.Namespace == namespace && cluster.Name == name {
+			return cluster, nil
+		}
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "clusters"}, name)
+}
+
+func (f *fakeClusterCache) List(namespace string, selector labels.Selector) ([]*rancherv1.Cluster, error) {
+	return f.clusters, nil
+}
+
+func (f *fakeClusterCache) AddIndexer(indexName string, indexer rocontrollers.ClusterIndexer) {
+	if f.indexers == nil {
+		f.indexers = map[string]rocontrollers.ClusterIndexer{}
+	}
+	f.indexers[indexName] = indexer
+}
+
+func (f *fakeClusterCache) GetByIndex(indexName, key string) ([]*rancherv1.Cluster, error) {
+	var result []*rancherv1.Cluster
+	for _, cluster := range f.clusters {
+		keys, err := f.indexers[indexName](cluster)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if k == key {
+				result = append(result, cluster)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+type fakeCAPIClusterCache map[string]*capi.Cluster
+
+func (f fakeCAPIClusterCache) Get(namespace, name string) (*capi.Cluster, error) {
+	if cluster, ok := f[namespace+"/"+name]; ok {
+		return cluster, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "clusters"}, name)
+}
+
+func (f fakeCAPIClusterCache) List(namespace string, selector labels.Selector) ([]*capi.Cluster, error) {
+	return nil, nil
+}
+
+func (f fakeCAPIClusterCache) AddIndexer(indexName string, indexer capicontrollers.ClusterIndexer) {
+}
+
+func (f fakeCAPIClusterCache) GetByIndex(indexName, key string) ([]*capi.Cluster, error) {
+	return nil, nil
+}
+
+type fakeMachineDeploymentCache map[string]*capi.MachineDeployment
+
+func (f fakeMachineDeploymentCache) Get(namespace, name string) (*capi.MachineDeployment, error) {
+	if md, ok := f[namespace+"/"+name]; ok {
+		return md, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "machinedeployments"}, name)
+}
+
+func (f fakeMachineDeploymentCache) List(namespace string, selector labels.Selector) ([]*capi.MachineDeployment, error) {
+	return nil, nil
+}
+
+func (f fakeMachineDeploymentCache) AddIndexer(indexName string, indexer capicontrollers.MachineDeploymentIndexer) {
+}
+
+func (f fakeMachineDeploymentCache) GetByIndex(indexName, key string) ([]*capi.MachineDeployment, error) {
+	return nil, nil
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func newMachineDeployment(name string, replicas, ready int32) *capi.MachineDeployment {
+	return &capi.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "fleet-default",
+			Name:      name,
+		},
+		Spec: capi.MachineDeploymentSpec{
+			Replicas: &replicas,
+		},
+		Status: capi.MachineDeploymentStatus{
+			Replicas:          replicas,
+			ReadyReplicas:     ready,
+			AvailableReplicas: ready,
+			UpdatedReplicas:   replicas,
+		},
+	}
+}
+
+func newCluster(machinePools ...rancherv1.RKEMachinePool) *rancherv1.Cluster {
+	return &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "fleet-default",
+			Name:      "test",
+		},
+		Spec: rancherv1.ClusterSpec{
+			RKEConfig: &rancherv1.RKEConfig{
+				MachinePools: machinePools,
+			},
+		},
+	}
+}
+
+func newMachinePool(name string, quantity *int32) rancherv1.RKEMachinePool {
+	return rancherv1.RKEMachinePool{
+		Name:       name,
+		Quantity:   quantity,
+		WorkerRole: true,
+		NodeConfig: &corev1.ObjectReference{Kind: "Amazonec2Config", Name: name},
+	}
+}
+
+func TestUpdateMachinePoolStatus(t *testing.T) {
+	tests := []struct {
+		name               string
+		machinePools       []rancherv1.RKEMachinePool
+		machineDeployments map[string]*capi.MachineDeployment
+		expected           []rancherv1.MachinePoolStatus
+		expectedReady      bool
+		expectedMessage    string
+	}{
+		{
+			name: "all machine pools ready",
+			machinePools: []rancherv1.RKEMachinePool{
+				newMachinePool("controlplane", int32Ptr(1)),
+				newMachinePool("workerpool", int32Ptr(3)),
+			},
+			machineDeployments: map[string]*capi.MachineDeployment{
+				"fleet-default/test-controlplane": newMachineDeployment("test-controlplane", 1, 1),
+				"fleet-default/test-workerpool":   newMachineDeployment("test-workerpool", 3, 3),
+			},
+			expected: []rancherv1.MachinePoolStatus{
+				{Name: "controlplane", Replicas: 1, ReadyReplicas: 1, AvailableReplicas: 1, UpdatedReplicas: 1},
+				{Name: "workerpool", Replicas: 3, ReadyReplicas: 3, AvailableReplicas: 3, UpdatedReplicas: 3},
+			},
+			expectedReady: true,
+		},
+		{
+			name: "machine pool scaling up",
+			machinePools: []rancherv1.RKEMachinePool{
+				newMachinePool("controlplane", int32Ptr(1)),
+				newMachinePool("workerpool", int32Ptr(3)),
+			},
+			machineDeployments: map[string]*capi.MachineDeployment{
+				"fleet-default/test-controlplane": newMachineDeployment("test-controlplane", 1, 1),
+				"fleet-default/test-workerpool":   newMachineDeployment("test-workerpool", 3, 2),
+			},
+			expected: []rancherv1.MachinePoolStatus{
+				{Name: "controlplane", Replicas: 1, ReadyReplicas: 1, AvailableReplicas: 1, UpdatedReplicas: 1},
+				{Name: "workerpool", Replicas: 3, ReadyReplicas: 2, AvailableReplicas: 2, UpdatedReplicas: 3},
+			},
+			expectedMessage: "workerpool: 2/3 ready",
+		},
+		{
+			name: "machine deployment not created yet",
+			machinePools: []rancherv1.RKEMachinePool{
+				newMachinePool("workerpool", int32Ptr(2)),
+			},
+			expected: []rancherv1.MachinePoolStatus{
+				{Name: "workerpool", Replicas: 2},
+			},
+			expectedMessage: "workerpool: 0/2 ready",
+		},
+		{
+			name: "machine pool scaled to zero",
+			machinePools: []rancherv1.RKEMachinePool{
+				newMachinePool("workerpool", int32Ptr(0)),
+			},
+			expected: []rancherv1.MachinePoolStatus{
+				{Name: "workerpool"},
+			},
+			expectedReady: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler{
+				machineDeployment: fakeMachineDeploymentCache(tt.machineDeployments),
+			}
+
+			status, err := h.updateMachinePoolStatus(newCluster(tt.machinePools...), rancherv1.ClusterStatus{})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, status.MachinePools)
+			assert.Equal(t, tt.expectedReady, MachinePoolsReady.IsTrue(&status))
+			assert.Equal(t, tt.expectedMessage, MachinePoolsReady.GetMessage(&status))
+		})
+	}
+}
+
+func TestUpdateMachinePoolStatusUnchanged(t *testing.T) {
+	h := handler{
+		machineDeployment: fakeMachineDeploymentCache{
+			"fleet-default/test-workerpool": newMachineDeployment("test-workerpool", 3, 2),
+		},
+	}
+	cluster := newCluster(newMachinePool("workerpool", int32Ptr(3)))
+
+	status, err := h.updateMachinePoolStatus(cluster, rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	first := status.DeepCopy()
+
+	status, err = h.updateMachinePoolStatus(cluster, status)
+	require.NoError(t, err)
+	assert.Equal(t, first, &status)
+}
+
+func TestUpdateMachinePoolStatusNoMachinePools(t *testing.T) {
+	h := handler{
+		machineDeployment: fakeMachineDeploymentCache{},
+	}
+
+	status, err := h.updateMachinePoolStatus(newCluster(), rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Empty(t, status.MachinePools)
+	assert.Empty(t, status.Conditions)
+}
+
+func TestOnRancherClusterChangePaused(t *testing.T) {
+	h := handler{
+		capiClusters: fakeCAPIClusterCache{},
+		machineDeployment: fakeMachineDeploymentCache{
+			"fleet-default/test-workerpool": newMachineDeployment("test-workerpool", 3, 2),
+		},
+	}
+	cluster := newCluster(newMachinePool("workerpool", int32Ptr(3)))
+	cluster.Spec.KubernetesVersion = "v1.21.4+rke2r2"
+	cluster.Status.ClusterName = "c-m-abcdefgh"
+
+	generated, status, err := h.OnRancherClusterChange(cluster, rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, generated)
+	assert.Len(t, status.MachinePools, 1)
+	assert.Empty(t, Paused.GetStatus(&status), "expected no condition unless paused")
+
+	cluster.Spec.Paused = true
+	objs, status, err := h.OnRancherClusterChange(cluster, rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Empty(t, objs)
+	assert.True(t, Paused.IsTrue(&status))
+	assert.Equal(t, []rancherv1.MachinePoolStatus{
+		{Name: "workerpool", Replicas: 3, ReadyReplicas: 2, AvailableReplicas: 2, UpdatedReplicas: 3},
+	}, status.MachinePools)
+	assert.Equal(t, "workerpool: 2/3 ready", MachinePoolsReady.GetMessage(&status))
+
+	// nothing is applied while paused
+	status, err = h.OnChange(cluster, rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Len(t, status.MachinePools, 1)
+
+	// all the objects are generated again once resumed
+	cluster.Spec.Paused = false
+	objs, status, err = h.OnRancherClusterChange(cluster, status)
+	require.NoError(t, err)
+	assert.Equal(t, generated, objs)
+	assert.True(t, Paused.IsFalse(&status))
+}
+
+func TestClustersForCloudCredential(t *testing.T) {
+	clusterCredential := newCluster()
+	clusterCredential.Name = "cluster-credential"
+	clusterCredential.Spec.CloudCredentialSecretName = "cc-abcde"
+
+	poolCredential := newCluster(newMachinePool("workerpool", int32Ptr(1)))
+	poolCredential.Name = "pool-credential"
+	poolCredential.Spec.RKEConfig.MachinePools[0].CloudCredentialSecretName = "cc-abcde"
+
+	otherCredential := newCluster(newMachinePool("workerpool", int32Ptr(1)))
+	otherCredential.Name = "other-credential"
+	otherCredential.Spec.CloudCredentialSecretName = "cc-fghij"
+
+	otherNamespace := newCluster()
+	otherNamespace.Namespace = "fleet-local"
+	otherNamespace.Name = "other-namespace"
+	otherNamespace.Spec.CloudCredentialSecretName = "cc-abcde"
+
+	cache := &fakeClusterCache{
+		clusters: []*rancherv1.Cluster{clusterCredential, poolCredential, otherCredential, otherNamespace},
+	}
+	cache.AddIndexer(byCloudCredential, byCloudCredentialIndex)
+	h := handler{
+		clusterCache: cache,
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "cc-abcde"}}
+	keys, err := h.clustersForCloudCredential(secret.Namespace, secret.Name, secret)
+	require.NoError(t, err)
+	assert.Equal(t, []relatedresource.Key{
+		{Namespace: "fleet-default", Name: "cluster-credential"},
+		{Namespace: "fleet-default", Name: "pool-credential"},
+	}, keys)
+
+	// a deleted secret still enqueues the clusters that use it
+	keys, err = h.clustersForCloudCredential("fleet-default", "cc-fghij", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []relatedresource.Key{
+		{Namespace: "fleet-default", Name: "other-credential"},
+	}, keys)
+
+	keys, err = h.clustersForCloudCredential("fleet-default", "unused", nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestByCloudCredentialIndex(t *testing.T) {
+	cluster := newCluster(newMachinePool("pool1", int32Ptr(1)), newMachinePool("pool2", int32Ptr(1)), newMachinePool("pool3", int32Ptr(1)))
+	cluster.Spec.CloudCredentialSecretName = "cc-abcde"
+	cluster.Spec.RKEConfig.MachinePools[0].CloudCredentialSecretName = "cc-abcde"
+	cluster.Spec.RKEConfig.MachinePools[1].CloudCredentialSecretName = "cc-fghij"
+
+	keys, err := byCloudCredentialIndex(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fleet-default/cc-abcde", "fleet-default/cc-fghij"}, keys)
+}
+
+func TestUpdateKubeletArgsStatus(t *testing.T) {
+	memoryPool := newMachinePool("memory", nil)
+	memoryPool.KubeletArgs = map[string]string{"eviction-hard": "memory.available<1Gi", "max-pods": "110"}
+	cluster := newCluster(memoryPool, newMachinePool("worker", nil))
+	cluster.Spec.RKEConfig.ControlPlaneConfig.Data = map[string]interface{}{
+		"kubelet-arg": []interface{}{"eviction-hard=memory.available<100Mi", "max-pods=110"},
+	}
+
+	status := updateKubeletArgsStatus(cluster, rancherv1.ClusterStatus{})
+	assert.True(t, KubeletArgsOverridden.IsTrue(&status))
+	assert.Equal(t, "memory: eviction-hard (memory.available<100Mi => memory.available<1Gi)", KubeletArgsOverridden.GetMessage(&status))
+
+	cluster.Spec.RKEConfig.ControlPlaneConfig.Data = nil
+	status = updateKubeletArgsStatus(cluster, status)
+	assert.True(t, KubeletArgsOverridden.IsFalse(&status))
+	assert.Empty(t, KubeletArgsOverridden.GetMessage(&status))
+}
+
+func TestUpdateKubeletArgsStatusNoKubeletArgs(t *testing.T) {
+	status := updateKubeletArgsStatus(newCluster(newMachinePool("worker", nil)), rancherv1.ClusterStatus{})
+	assert.Empty(t, status.Conditions)
+}
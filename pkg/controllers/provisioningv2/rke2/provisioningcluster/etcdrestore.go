@@ -0,0 +1,265 @@
+package provisioningcluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	minio "github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+const (
+	defaultS3Endpoint = "s3.amazonaws.com"
+	s3StatTimeout     = 30 * time.Second
+)
+
+// statS3Object fails if the object can't be retrieved from the bucket, it is replaced in tests
+var statS3Object = func(ctx context.Context, s3 *rkev1.ETCDSnapshotS3, cred planner.S3Credential, object string) error {
+	client, err := newS3Client(s3, cred)
+	if err != nil {
+		return err
+	}
+	_, err = client.StatObject(ctx, s3.Bucket, object, minio.StatObjectOptions{})
+	return err
+}
+
+// restoreRejectedError means the requested restore can't succeed until the spec of the cluster changes
+type restoreRejectedError struct {
+	message string
+}
+
+func (e *restoreRejectedError) Error() string {
+	return e.message
+}
+
+func rejectRestore(format string, args ...interface{}) error {
+	return &restoreRejectedError{message: fmt.Sprintf(format, args...)}
+}
+
+// preflightRestore validates the etcd snapshot restore requested on the cluster before it is set on the control
+// plane, as the control plane is shut down as soon as the restore starts. A rejected restore is reported by the
+// RestoreSucceeded condition and the returned cluster, which the objects are generated from, keeps the restore
+// currently set on the control plane.
+func (h *handler) preflightRestore(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) (*rancherv1.Cluster, rancherv1.ClusterStatus, error) {
+	restore := cluster.Spec.RKEConfig.ETCDSnapshotRestore
+	if restore == nil {
+		return cluster, status, nil
+	}
+
+	cp, err := h.rkeControlPlane.Get(cluster.Namespace, cluster.Name)
+	if apierror.IsNotFound(err) {
+		cp = nil
+	} else if err != nil {
+		return cluster, status, err
+	}
+
+	var current *rkev1.ETCDSnapshot
+	if cp != nil {
+		current = cp.Spec.ETCDSnapshotRestore
+		if equality.Semantic.DeepEqual(current, restore) {
+			// the restore was already validated
+			return cluster, status, nil
+		}
+	}
+
+	err = h.validateRestore(cluster, restore)
+	if _, ok := err.(*restoreRejectedError); ok {
+		RestoreSucceeded.False(&status)
+		RestoreSucceeded.Reason(&status, "Rejected")
+		RestoreSucceeded.Message(&status, err.Error())
+		cluster = cluster.DeepCopy()
+		cluster.Spec.RKEConfig.ETCDSnapshotRestore = current.DeepCopy()
+		return cluster, status, nil
+	} else if err != nil {
+		return cluster, status, err
+	}
+
+	RestoreSucceeded.Unknown(&status)
+	RestoreSucceeded.Reason(&status, "Restoring")
+	RestoreSucceeded.Message(&status, fmt.Sprintf("restoring etcd snapshot [%s]", restore.Name))
+	return cluster, status, nil
+}
+
+// validateRestore checks that the snapshot is one of the snapshots reported by the cluster and, if it is stored on
+// S3, that it can be retrieved with the S3 config of the cluster
+func (h *handler) validateRestore(cluster *rancherv1.Cluster, restore *rkev1.ETCDSnapshot) error {
+	if restore.Name == "" {
+		return rejectRestore("the name of the etcd snapshot to restore is not set")
+	}
+	if restore.S3 == nil && restore.NodeName == "" {
+		return rejectRestore("the node of the local etcd snapshot [%s] is not set", restore.Name)
+	}
+
+	if !hasSnapshot(cluster, restore) {
+		owner, err := h.snapshotOwner(cluster, restore)
+		if err != nil {
+			return err
+		}
+		if owner != "" {
+			return rejectRestore("etcd snapshot [%s] belongs to cluster [%s]", restore.Name, owner)
+		}
+		return rejectRestore("etcd snapshot [%s] was not found in the snapshots of the cluster", restore.Name)
+	}
+
+	if restore.S3 == nil {
+		return nil
+	}
+
+	credName := restore.S3.CloudCredentialName
+	if credName == "" && cluster.Spec.RKEConfig.ETCD != nil && cluster.Spec.RKEConfig.ETCD.S3 != nil {
+		credName = cluster.Spec.RKEConfig.ETCD.S3.CloudCredentialName
+	}
+	cred, err := planner.GetS3Credential(h.secretCache, cluster.Namespace, credName, restore.S3.Region)
+	if err != nil {
+		return rejectRestore("etcd snapshot [%s] can't be retrieved from S3: %v", restore.Name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3StatTimeout)
+	defer cancel()
+	err = statS3Object(ctx, restore.S3, cred, path.Join(restore.S3.Folder, restore.Name))
+	switch minio.ToErrorResponse(err).Code {
+	case "":
+		if err != nil {
+			return fmt.Errorf("failed to retrieve etcd snapshot [%s] from S3: %w", restore.Name, err)
+		}
+		return nil
+	case "NoSuchKey", "NoSuchBucket", "AccessDenied":
+		return rejectRestore("etcd snapshot [%s] can't be retrieved from bucket [%s]: %v", restore.Name, restore.S3.Bucket, err)
+	default:
+		return fmt.Errorf("failed to retrieve etcd snapshot [%s] from S3: %w", restore.Name, err)
+	}
+}
+
+// hasSnapshot returns whether the snapshot is stored where it is restored from: on S3 or on the node of the restore
+func hasSnapshot(cluster *rancherv1.Cluster, restore *rkev1.ETCDSnapshot) bool {
+	for _, snapshot := range cluster.Status.ETCDSnapshots {
+		if snapshot.Name != restore.Name || (snapshot.S3 == nil) != (restore.S3 == nil) {
+			continue
+		}
+		if restore.S3 != nil || snapshot.NodeName == restore.NodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotOwner returns the other cluster reporting the snapshot, if any
+func (h *handler) snapshotOwner(cluster *rancherv1.Cluster, restore *rkev1.ETCDSnapshot) (string, error) {
+	clusters, err := h.clusterCache.List("", labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	for _, other := range clusters {
+		if other.Namespace == cluster.Namespace && other.Name == cluster.Name {
+			continue
+		}
+		for _, snapshot := range other.Status.ETCDSnapshots {
+			if snapshot.Name == restore.Name {
+				return other.Namespace + "/" + other.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// updateRestoreStatus verifies the restore once the control plane finished it: the control plane must be provisioned
+// again and all the etcd members of the cluster must be running
+func (h *handler) updateRestoreStatus(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) (rancherv1.ClusterStatus, error) {
+	restore := cluster.Spec.RKEConfig.ETCDSnapshotRestore
+	if restore == nil || RestoreSucceeded.GetReason(&status) == "Rejected" {
+		return status, nil
+	}
+
+	cp, err := h.rkeControlPlane.Get(cluster.Namespace, cluster.Name)
+	if apierror.IsNotFound(err) {
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+	if cp.Status.ETCDSnapshotRestorePhase != rkev1.ETCDSnapshotPhaseFinished ||
+		!equality.Semantic.DeepEqual(cp.Status.ETCDSnapshotRestore, restore) {
+		return status, nil
+	}
+
+	machines, err := h.machines.List(cluster.Namespace, labels.SelectorFromSet(map[string]string{
+		capi.ClusterLabelName: cluster.Name,
+		planner.EtcdRoleLabel: "true",
+	}))
+	if err != nil {
+		return status, err
+	}
+
+	var members, waiting, failed []string
+	for _, machine := range machines {
+		switch {
+		case machine.Status.FailureReason != nil || machine.Status.Phase == string(capi.MachinePhaseFailed):
+			failed = append(failed, machine.Name)
+		case machine.Status.NodeRef == nil || machine.Status.Phase != string(capi.MachinePhaseRunning):
+			waiting = append(waiting, machine.Name)
+		default:
+			members = append(members, machine.Status.NodeRef.Name)
+		}
+	}
+	sort.Strings(members)
+	sort.Strings(waiting)
+	sort.Strings(failed)
+
+	switch {
+	case len(failed) > 0:
+		RestoreSucceeded.False(&status)
+		RestoreSucceeded.Reason(&status, "VerificationFailed")
+		RestoreSucceeded.Message(&status, fmt.Sprintf("etcd members failed after restoring snapshot [%s]: %s", restore.Name, strings.Join(failed, ", ")))
+	case len(members) == 0 || len(waiting) > 0 || !Provisioned.IsTrue(cp):
+		RestoreSucceeded.Unknown(&status)
+		RestoreSucceeded.Reason(&status, "Verifying")
+		RestoreSucceeded.Message(&status, fmt.Sprintf("waiting for the etcd members and the control plane after restoring snapshot [%s]", restore.Name))
+	default:
+		RestoreSucceeded.True(&status)
+		RestoreSucceeded.Reason(&status, "")
+		RestoreSucceeded.Message(&status, fmt.Sprintf("restored etcd snapshot [%s] on members: %s", restore.Name, strings.Join(members, ", ")))
+	}
+	return status, nil
+}
+
+func newS3Client(s3 *rkev1.ETCDSnapshotS3, cred planner.S3Credential) (*minio.Client, error) {
+	endpoint := s3.Endpoint
+	if endpoint == "" {
+		endpoint = defaultS3Endpoint
+	}
+
+	creds := credentials.NewIAM("")
+	if cred.AccessKey != "" && cred.SecretKey != "" {
+		creds = credentials.NewStatic(cred.AccessKey, cred.SecretKey, "", credentials.SignatureV4)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: s3.SkipSSLVerify}
+	if s3.EndpointCA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(s3.EndpointCA)) {
+			return nil, fmt.Errorf("the S3 endpoint CA is invalid")
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return minio.New(endpoint, &minio.Options{
+		Creds:     creds,
+		Region:    cred.Region,
+		Secure:    true,
+		Transport: transport,
+	})
+}
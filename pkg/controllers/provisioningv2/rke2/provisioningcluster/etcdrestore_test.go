@@ -0,0 +1,236 @@
+package provisioningcluster
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	minio "github.com/minio/minio-go/v7"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+type fakeControlPlaneCache struct {
+	rkecontroller.RKEControlPlaneCache
+	controlPlanes map[string]*rkev1.RKEControlPlane
+}
+
+func (f fakeControlPlaneCache) Get(namespace, name string) (*rkev1.RKEControlPlane, error) {
+	if cp, ok := f.controlPlanes[namespace+"/"+name]; ok {
+		return cp, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "rkecontrolplanes"}, name)
+}
+
+type fakeMachineCache struct {
+	capicontrollers.MachineCache
+	machines []*capi.Machine
+}
+
+func (f fakeMachineCache) List(namespace string, selector labels.Selector) ([]*capi.Machine, error) {
+	var result []*capi.Machine
+	for _, machine := range f.machines {
+		if machine.Namespace == namespace && selector.Matches(labels.Set(machine.Labels)) {
+			result = append(result, machine)
+		}
+	}
+	return result, nil
+}
+
+func newRestoreCluster(restore *rkev1.ETCDSnapshot) *rancherv1.Cluster {
+	cluster := newCluster()
+	cluster.Spec.RKEConfig.ETCDSnapshotRestore = restore
+	cluster.Status.ETCDSnapshots = []rkev1.ETCDSnapshot{
+		{Name: "etcd-snapshot-node1-1630000000", NodeName: "node1"},
+		{Name: "on-demand-node1-1630000100", NodeName: "node1", S3: &rkev1.ETCDSnapshotS3{Bucket: "snapshots"}},
+	}
+	return cluster
+}
+
+func newEtcdMachine(name, phase string, nodeRef bool) *capi.Machine {
+	machine := &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "fleet-default",
+			Name:      name,
+			Labels: map[string]string{
+				capi.ClusterLabelName: "test",
+				planner.EtcdRoleLabel: "true",
+			},
+		},
+		Status: capi.MachineStatus{Phase: phase},
+	}
+	if nodeRef {
+		machine.Status.NodeRef = &corev1.ObjectReference{Name: name + "-node"}
+	}
+	return machine
+}
+
+func stubStatS3Object(t *testing.T, err error) func() {
+	old := statS3Object
+	statS3Object = func(ctx context.Context, s3 *rkev1.ETCDSnapshotS3, cred planner.S3Credential, object string) error {
+		assert.Equal(t, "snapshots", s3.Bucket)
+		assert.Equal(t, "rancher/on-demand-node1-1630000100", object)
+		return err
+	}
+	return func() { statS3Object = old }
+}
+
+func TestPreflightRestoreRejected(t *testing.T) {
+	other := newCluster()
+	other.Name = "other"
+	other.Status.ETCDSnapshots = []rkev1.ETCDSnapshot{{Name: "etcd-snapshot-node9-1630000000", NodeName: "node9"}}
+
+	tests := []struct {
+		name    string
+		restore *rkev1.ETCDSnapshot
+		s3Err   error
+		message string
+	}{
+		{
+			name:    "no name",
+			restore: &rkev1.ETCDSnapshot{NodeName: "node1"},
+			message: "the name of the etcd snapshot to restore is not set",
+		},
+		{
+			name:    "no node",
+			restore: &rkev1.ETCDSnapshot{Name: "etcd-snapshot-node1-1630000000"},
+			message: "the node of the local etcd snapshot [etcd-snapshot-node1-1630000000] is not set",
+		},
+		{
+			name:    "typo",
+			restore: &rkev1.ETCDSnapshot{Name: "etcd-snapshot-node1-163000000", NodeName: "node1"},
+			message: "etcd snapshot [etcd-snapshot-node1-163000000] was not found in the snapshots of the cluster",
+		},
+		{
+			name:    "other node",
+			restore: &rkev1.ETCDSnapshot{Name: "etcd-snapshot-node1-1630000000", NodeName: "node2"},
+			message: "etcd snapshot [etcd-snapshot-node1-1630000000] was not found in the snapshots of the cluster",
+		},
+		{
+			name:    "other cluster",
+			restore: &rkev1.ETCDSnapshot{Name: "etcd-snapshot-node9-1630000000", NodeName: "node9"},
+			message: "etcd snapshot [etcd-snapshot-node9-1630000000] belongs to cluster [fleet-default/other]",
+		},
+		{
+			name:    "missing s3 object",
+			restore: &rkev1.ETCDSnapshot{Name: "on-demand-node1-1630000100", S3: &rkev1.ETCDSnapshotS3{Bucket: "snapshots", Folder: "rancher"}},
+			s3Err:   minio.ErrorResponse{Code: "NoSuchKey", Message: "The specified key does not exist."},
+			message: "etcd snapshot [on-demand-node1-1630000100] can't be retrieved from bucket [snapshots]: The specified key does not exist.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer stubStatS3Object(t, tt.s3Err)()
+			cluster := newRestoreCluster(tt.restore)
+			h := handler{
+				clusterCache:    &fakeClusterCache{clusters: []*rancherv1.Cluster{cluster, other}},
+				rkeControlPlane: fakeControlPlaneCache{},
+			}
+
+			generateFrom, status, err := h.preflightRestore(cluster, rancherv1.ClusterStatus{})
+			require.NoError(t, err)
+			assert.Nil(t, generateFrom.Spec.RKEConfig.ETCDSnapshotRestore, "expected the rejected restore not to be set on the control plane")
+			assert.Equal(t, tt.restore, cluster.Spec.RKEConfig.ETCDSnapshotRestore, "expected the spec of the cluster to be left as is")
+			assert.True(t, RestoreSucceeded.IsFalse(&status))
+			assert.Equal(t, "Rejected", RestoreSucceeded.GetReason(&status))
+			assert.Equal(t, tt.message, RestoreSucceeded.GetMessage(&status))
+
+			// the status isn't overwritten by the verification of the restore
+			status, err = h.updateRestoreStatus(cluster, status)
+			require.NoError(t, err)
+			assert.Equal(t, "Rejected", RestoreSucceeded.GetReason(&status))
+		})
+	}
+}
+
+func TestPreflightRestoreAccepted(t *testing.T) {
+	restore := &rkev1.ETCDSnapshot{Name: "on-demand-node1-1630000100", S3: &rkev1.ETCDSnapshotS3{Bucket: "snapshots", Folder: "rancher"}}
+	cluster := newRestoreCluster(restore)
+	previous := &rkev1.ETCDSnapshot{Name: "etcd-snapshot-node1-1630000000", NodeName: "node1"}
+	cp := &rkev1.RKEControlPlane{Spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotRestore: previous}}
+	h := handler{
+		clusterCache:    &fakeClusterCache{clusters: []*rancherv1.Cluster{cluster}},
+		rkeControlPlane: fakeControlPlaneCache{controlPlanes: map[string]*rkev1.RKEControlPlane{"fleet-default/test": cp}},
+	}
+
+	defer stubStatS3Object(t, stderrors.New("connection reset by peer"))()
+	_, _, err := h.preflightRestore(cluster, rancherv1.ClusterStatus{})
+	assert.Error(t, err, "expected a failure to reach S3 to be retried")
+
+	stubStatS3Object(t, nil)
+	generateFrom, status, err := h.preflightRestore(cluster, rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Equal(t, restore, generateFrom.Spec.RKEConfig.ETCDSnapshotRestore)
+	assert.Equal(t, "Restoring", RestoreSucceeded.GetReason(&status))
+
+	// the restore set on the control plane isn't validated again
+	cp.Spec.ETCDSnapshotRestore = restore
+	statS3Object = func(ctx context.Context, s3 *rkev1.ETCDSnapshotS3, cred planner.S3Credential, object string) error {
+		t.Fatal("expected the snapshot not to be retrieved again")
+		return nil
+	}
+	cluster.Status.ETCDSnapshots = nil
+	generateFrom, _, err = h.preflightRestore(cluster, status)
+	require.NoError(t, err)
+	assert.Equal(t, restore, generateFrom.Spec.RKEConfig.ETCDSnapshotRestore)
+}
+
+func TestUpdateRestoreStatus(t *testing.T) {
+	restore := &rkev1.ETCDSnapshot{Name: "etcd-snapshot-node1-1630000000", NodeName: "node1"}
+	cluster := newRestoreCluster(restore)
+	cp := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotRestore: restore},
+		Status: rkev1.RKEControlPlaneStatus{
+			ETCDSnapshotRestore:      restore,
+			ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseRestore,
+		},
+	}
+	machines := &fakeMachineCache{machines: []*capi.Machine{
+		newEtcdMachine("etcd-1", string(capi.MachinePhaseRunning), true),
+		newEtcdMachine("etcd-2", string(capi.MachinePhaseProvisioned), false),
+	}}
+	h := handler{
+		machines:        machines,
+		rkeControlPlane: fakeControlPlaneCache{controlPlanes: map[string]*rkev1.RKEControlPlane{"fleet-default/test": cp}},
+	}
+
+	status := rancherv1.ClusterStatus{}
+	RestoreSucceeded.Unknown(&status)
+	RestoreSucceeded.Reason(&status, "Restoring")
+
+	status, err := h.updateRestoreStatus(cluster, status)
+	require.NoError(t, err)
+	assert.Equal(t, "Restoring", RestoreSucceeded.GetReason(&status), "expected the restore not to be verified before it finished")
+
+	cp.Status.ETCDSnapshotRestorePhase = rkev1.ETCDSnapshotPhaseFinished
+	Provisioned.True(cp)
+	status, err = h.updateRestoreStatus(cluster, status)
+	require.NoError(t, err)
+	assert.True(t, RestoreSucceeded.IsUnknown(&status))
+	assert.Equal(t, "Verifying", RestoreSucceeded.GetReason(&status), "expected to wait for all the etcd members")
+
+	machines.machines[1] = newEtcdMachine("etcd-2", string(capi.MachinePhaseRunning), true)
+	status, err = h.updateRestoreStatus(cluster, status)
+	require.NoError(t, err)
+	assert.True(t, RestoreSucceeded.IsTrue(&status))
+	assert.Equal(t, "restored etcd snapshot [etcd-snapshot-node1-1630000000] on members: etcd-1-node, etcd-2-node", RestoreSucceeded.GetMessage(&status))
+
+	machines.machines[1] = newEtcdMachine("etcd-2", string(capi.MachinePhaseFailed), true)
+	status, err = h.updateRestoreStatus(cluster, status)
+	require.NoError(t, err)
+	assert.True(t, RestoreSucceeded.IsFalse(&status))
+	assert.Equal(t, "VerificationFailed", RestoreSucceeded.GetReason(&status))
+	assert.Equal(t, "etcd members failed after restoring snapshot [etcd-snapshot-node1-1630000000]: etcd-2", RestoreSucceeded.GetMessage(&status))
+}
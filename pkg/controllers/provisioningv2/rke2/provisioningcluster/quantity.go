@@ -0,0 +1,92 @@
+package provisioningcluster
+
+import (
+	"fmt"
+	"strings"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MachinePoolScaledReason is the reason of the event of a machine pool scaled by more than the
+// machine-pool-scale-warning-delta setting at once
+const MachinePoolScaledReason = "MachinePoolScaled"
+
+// validateMachinePoolQuantities rejects the machine pools scaled above the machine-pool-max-quantity setting, so that
+// an accidental scale isn't applied. A rejected pool is reported by the MachinePoolQuantitiesValid condition and keeps
+// its current number of machines in the returned cluster, which the objects are generated from, while the other pools
+// are reconciled. Scaling a pool to zero removes it and is always allowed. The pools scaled by more than the
+// machine-pool-scale-warning-delta setting at once are reported by a warning event on the cluster.
+func (h *handler) validateMachinePoolQuantities(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) (*rancherv1.Cluster, rancherv1.ClusterStatus, error) {
+	var (
+		maxQuantity  = int32(settings.MachinePoolMaxQuantity.GetInt())
+		warningDelta = int32(settings.MachinePoolScaleWarningDelta.GetInt())
+		result       = cluster
+		rejected     []string
+	)
+
+	for i, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		if machinePool.Quantity == nil {
+			continue
+		}
+
+		var current int32
+		md, err := h.machineDeployment.Get(cluster.Namespace, name.SafeConcatName(cluster.Name, machinePool.Name))
+		if err != nil && !apierror.IsNotFound(err) {
+			return cluster, status, err
+		} else if err == nil && md.Spec.Replicas != nil {
+			current = *md.Spec.Replicas
+		}
+
+		quantity := *machinePool.Quantity
+		switch {
+		case quantity < 0:
+			rejected = append(rejected, fmt.Sprintf("quantity %d of machinePool [%s] may not be negative", quantity, machinePool.Name))
+		case maxQuantity > 0 && quantity > maxQuantity:
+			rejected = append(rejected, fmt.Sprintf("quantity %d of machinePool [%s] exceeds the maximum of %d machines per pool set by the %s setting",
+				quantity, machinePool.Name, maxQuantity, settings.MachinePoolMaxQuantity.Name))
+		default:
+			h.warnLargeScale(cluster, machinePool.Name, current, quantity, warningDelta)
+			continue
+		}
+
+		if result == cluster {
+			result = cluster.DeepCopy()
+		}
+		result.Spec.RKEConfig.MachinePools[i].Quantity = &current
+	}
+
+	MachinePoolQuantitiesValid.SetStatusBool(&status, len(rejected) == 0)
+	MachinePoolQuantitiesValid.Message(&status, strings.Join(rejected, ", "))
+	return result, status, nil
+}
+
+// warnLargeScale records a warning event on the cluster when one of its machine pools is scaled by more than the
+// warning delta at once. The event is recorded once for every quantity the pool is scaled to.
+func (h *handler) warnLargeScale(cluster *rancherv1.Cluster, machinePoolName string, current, quantity, warningDelta int32) {
+	key := cluster.Namespace + "/" + cluster.Name + "/" + machinePoolName
+	delta := quantity - current
+	if delta < 0 {
+		delta = -delta
+	}
+
+	h.scaleWarningsLock.Lock()
+	defer h.scaleWarningsLock.Unlock()
+
+	if warningDelta <= 0 || delta <= warningDelta {
+		delete(h.scaleWarnings, key)
+		return
+	}
+	if warned, ok := h.scaleWarnings[key]; ok && warned == quantity {
+		return
+	}
+	if h.scaleWarnings == nil {
+		h.scaleWarnings = map[string]int32{}
+	}
+	h.scaleWarnings[key] = quantity
+	h.recorder.Eventf(cluster, corev1.EventTypeWarning, MachinePoolScaledReason,
+		"machinePool [%s] is scaled from %d to %d machines", machinePoolName, current, quantity)
+}
@@ -0,0 +1,185 @@
+package provisioningcluster
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+)
+
+func setMachinePoolLimits(t *testing.T, maxQuantity, warningDelta string) func() {
+	oldMax, oldDelta := settings.MachinePoolMaxQuantity.Get(), settings.MachinePoolScaleWarningDelta.Get()
+	require.NoError(t, settings.MachinePoolMaxQuantity.Set(maxQuantity))
+	require.NoError(t, settings.MachinePoolScaleWarningDelta.Set(warningDelta))
+	return func() {
+		settings.MachinePoolMaxQuantity.Set(oldMax)
+		settings.MachinePoolScaleWarningDelta.Set(oldDelta)
+	}
+}
+
+func TestValidateMachinePoolQuantities(t *testing.T) {
+	defer setMachinePoolLimits(t, "100", "10")()
+
+	tests := []struct {
+		name             string
+		quantity         *int32
+		current          int32
+		expectedQuantity *int32
+		expectedEvents   []string
+		expectedMessage  string
+	}{
+		{
+			name:             "within limit",
+			quantity:         int32Ptr(5),
+			current:          3,
+			expectedQuantity: int32Ptr(5),
+		},
+		{
+			name:             "at limit",
+			quantity:         int32Ptr(100),
+			current:          95,
+			expectedQuantity: int32Ptr(100),
+		},
+		{
+			name:             "over limit",
+			quantity:         int32Ptr(101),
+			current:          95,
+			expectedQuantity: int32Ptr(95),
+			expectedMessage:  "quantity 101 of machinePool [workerpool] exceeds the maximum of 100 machines per pool set by the machine-pool-max-quantity setting",
+		},
+		{
+			name:             "negative",
+			quantity:         int32Ptr(-1),
+			current:          3,
+			expectedQuantity: int32Ptr(3),
+			expectedMessage:  "quantity -1 of machinePool [workerpool] may not be negative",
+		},
+		{
+			name:             "large delta",
+			quantity:         int32Ptr(50),
+			current:          3,
+			expectedQuantity: int32Ptr(50),
+			expectedEvents:   []string{"Warning MachinePoolScaled machinePool [workerpool] is scaled from 3 to 50 machines"},
+		},
+		{
+			name:             "scale to zero",
+			quantity:         int32Ptr(0),
+			current:          3,
+			expectedQuantity: int32Ptr(0),
+		},
+		{
+			name:             "scale large pool to zero",
+			quantity:         int32Ptr(0),
+			current:          60,
+			expectedQuantity: int32Ptr(0),
+			expectedEvents:   []string{"Warning MachinePoolScaled machinePool [workerpool] is scaled from 60 to 0 machines"},
+		},
+		{
+			name:    "no quantity",
+			current: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			h := handler{
+				machineDeployment: fakeMachineDeploymentCache{
+					"fleet-default/test-workerpool": newMachineDeployment("test-workerpool", tt.current, tt.current),
+				},
+				recorder: recorder,
+			}
+			cluster := newCluster(newMachinePool("workerpool", tt.quantity))
+
+			result, status, err := h.validateMachinePoolQuantities(cluster, rancherv1.ClusterStatus{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedQuantity, result.Spec.RKEConfig.MachinePools[0].Quantity)
+			assert.Equal(t, tt.quantity, cluster.Spec.RKEConfig.MachinePools[0].Quantity, "expected the cluster not to be modified")
+			assert.Equal(t, tt.expectedMessage == "", MachinePoolQuantitiesValid.IsTrue(&status))
+			assert.Equal(t, tt.expectedMessage, MachinePoolQuantitiesValid.GetMessage(&status))
+			assert.Equal(t, tt.expectedEvents, drainEvents(recorder))
+		})
+	}
+}
+
+func TestValidateMachinePoolQuantitiesNewPool(t *testing.T) {
+	defer setMachinePoolLimits(t, "100", "10")()
+	recorder := record.NewFakeRecorder(10)
+	h := handler{machineDeployment: fakeMachineDeploymentCache{}, recorder: recorder}
+
+	_, _, err := h.validateMachinePoolQuantities(newCluster(newMachinePool("workerpool", int32Ptr(20))), rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Warning MachinePoolScaled machinePool [workerpool] is scaled from 0 to 20 machines"}, drainEvents(recorder),
+		"expected a pool without MachineDeployment to be scaled from zero")
+
+	result, _, err := h.validateMachinePoolQuantities(newCluster(newMachinePool("workerpool", int32Ptr(200))), rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Equal(t, int32Ptr(0), result.Spec.RKEConfig.MachinePools[0].Quantity, "expected a new pool over the limit not to be created")
+}
+
+func TestValidateMachinePoolQuantitiesWarnsOnce(t *testing.T) {
+	defer setMachinePoolLimits(t, "100", "10")()
+	recorder := record.NewFakeRecorder(10)
+	h := handler{
+		machineDeployment: fakeMachineDeploymentCache{
+			"fleet-default/test-workerpool": newMachineDeployment("test-workerpool", 3, 3),
+		},
+		recorder: recorder,
+	}
+
+	for i := 0; i < 3; i++ {
+		_, _, err := h.validateMachinePoolQuantities(newCluster(newMachinePool("workerpool", int32Ptr(50))), rancherv1.ClusterStatus{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, drainEvents(recorder), 1, "expected the scale to be reported once")
+
+	_, _, err := h.validateMachinePoolQuantities(newCluster(newMachinePool("workerpool", int32Ptr(60))), rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Len(t, drainEvents(recorder), 1, "expected a scale to another quantity to be reported")
+}
+
+func TestValidateMachinePoolQuantitiesUnlimited(t *testing.T) {
+	defer setMachinePoolLimits(t, "0", "0")()
+	recorder := record.NewFakeRecorder(10)
+	h := handler{machineDeployment: fakeMachineDeploymentCache{}, recorder: recorder}
+
+	result, status, err := h.validateMachinePoolQuantities(newCluster(newMachinePool("workerpool", int32Ptr(5000))), rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.Equal(t, int32Ptr(5000), result.Spec.RKEConfig.MachinePools[0].Quantity)
+	assert.True(t, MachinePoolQuantitiesValid.IsTrue(&status))
+	assert.Empty(t, drainEvents(recorder))
+}
+
+func TestOnRancherClusterChangeOverLimit(t *testing.T) {
+	defer setMachinePoolLimits(t, "100", "10")()
+	h := handler{
+		capiClusters: fakeCAPIClusterCache{},
+		machineDeployment: fakeMachineDeploymentCache{
+			"fleet-default/test-workerpool": newMachineDeployment("test-workerpool", 3, 3),
+		},
+		recorder: record.NewFakeRecorder(10),
+	}
+	cluster := newCluster(newMachinePool("workerpool", int32Ptr(1000)))
+	cluster.Spec.KubernetesVersion = "v1.21.4+rke2r2"
+	cluster.Status.ClusterName = "c-m-abcdefgh"
+
+	objs, status, err := h.OnRancherClusterChange(cluster, rancherv1.ClusterStatus{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, objs, "expected the cluster to be reconciled while a pool is over the limit")
+	assert.True(t, MachinePoolQuantitiesValid.IsFalse(&status), "expected the pool over the limit to be reported")
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
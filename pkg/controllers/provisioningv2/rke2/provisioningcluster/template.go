@@ -17,6 +17,7 @@
 	"github.com/rancher/wrangler/pkg/name"
 	corev1 "k8s.io/api/core/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -33,7 +34,8 @@ func getInfraRef(rkeCluster *rkev1.RKECluster) *corev1.ObjectReference {
 	return infraRef
 }
 
-func objects(cluster *rancherv1.Cluster, dynamic *dynamic.Controller, dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache) (result []runtime.Object, _ error) {
+func objects(cluster *rancherv1.Cluster, dynamic *dynamic.Controller, dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache,
+	userData *userDataResolver) (result []runtime.Object, _ error) {
 	infraRef := cluster.Spec.RKEConfig.InfrastructureRef
 	if infraRef == nil {
 		rkeCluster := rkeCluster(cluster)
@@ -47,15 +49,79 @@ func objects(cluster *rancherv1.Cluster, dynamic *dynamic.Controller, dynamicSch
 	capiCluster := capiCluster(cluster, rkeControlPlane, infraRef)
 	result = append(result, capiCluster)
 
-	machineDeployments, err := machineDeployments(cluster, capiCluster, dynamic, dynamicSchema, secrets)
+	machineDeployments, err := machineDeployments(cluster, capiCluster, dynamic, dynamicSchema, secrets, userData)
 	if err != nil {
 		return nil, err
 	}
 
 	result = append(result, machineDeployments...)
+	if err := addGeneratedObjectMetadata(cluster, result); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
+// reservedMetadataPrefixes are the prefixes of the labels and annotations Rancher, wrangler and cluster-api rely on,
+// they may not be set by the generated object labels and annotations of the cluster
+var reservedMetadataPrefixes = []string{
+	"cluster.x-k8s.io/",
+	"rke.cattle.io/",
+	"provisioning.cattle.io/",
+	"management.cattle.io/",
+	"objectset.rio.cattle.io/",
+}
+
+// addGeneratedObjectMetadata adds the generated object labels and annotations of the cluster to the objects, the
+// labels and annotations already set on an object are kept
+func addGeneratedObjectMetadata(cluster *rancherv1.Cluster, objs []runtime.Object) error {
+	labels, annotations := cluster.Spec.RKEConfig.GeneratedObjectLabels, cluster.Spec.RKEConfig.GeneratedObjectAnnotations
+	if len(labels) == 0 && len(annotations) == 0 {
+		return nil
+	}
+	if err := validateGeneratedObjectMetadata("generatedObjectLabels", labels); err != nil {
+		return err
+	}
+	if err := validateGeneratedObjectMetadata("generatedObjectAnnotations", annotations); err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		objMeta.SetLabels(mergeMetadata(objMeta.GetLabels(), labels))
+		objMeta.SetAnnotations(mergeMetadata(objMeta.GetAnnotations(), annotations))
+	}
+	return nil
+}
+
+func validateGeneratedObjectMetadata(field string, values map[string]string) error {
+	for key := range values {
+		for _, prefix := range reservedMetadataPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return fmt.Errorf("key [%s] of %s may not be set, the %s prefix is reserved", key, field, prefix)
+			}
+		}
+	}
+	return nil
+}
+
+// mergeMetadata returns the existing labels or annotations along with the generated ones they don't set already
+func mergeMetadata(existing, generated map[string]string) map[string]string {
+	if len(generated) == 0 {
+		return existing
+	}
+	result := make(map[string]string, len(existing)+len(generated))
+	for k, v := range generated {
+		result[k] = v
+	}
+	for k, v := range existing {
+		result[k] = v
+	}
+	return result
+}
+
 func pruneBySchema(kind string, data map[string]interface{}, dynamicSchema mgmtcontroller.DynamicSchemaCache) error {
 	ds, err := dynamicSchema.Get(strings.ToLower(kind))
 	if apierror.IsNotFound(err) {
@@ -74,7 +140,7 @@ func pruneBySchema(kind string, data map[string]interface{}, dynamicSchema mgmtc
 }
 
 func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machinePool rancherv1.RKEMachinePool,
-	dynamic *dynamic.Controller, dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache) (*unstructured.Unstructured, error) {
+	dynamic *dynamic.Controller, dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache, userData string) (*unstructured.Unstructured, error) {
 	apiVersion := machinePool.NodeConfig.APIVersion
 	kind := machinePool.NodeConfig.Kind
 	if apiVersion == "" {
@@ -115,6 +181,11 @@ func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machi
 		machinePoolData.SetNested(secretName, "common", "cloudCredentialSecretName")
 	}
 
+	if userData != "" {
+		// the referenced user data replaces the inline user data of the machine config
+		machinePoolData.Set(userDataFields[kind], userData)
+	}
+
 	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       strings.TrimSuffix(kind, "Config") + "MachineTemplate",
@@ -133,7 +204,7 @@ func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machi
 }
 
 func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, dynamic *dynamic.Controller,
-	dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache) (result []runtime.Object, _ error) {
+	dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache, userDataResolver *userDataResolver) (result []runtime.Object, _ error) {
 	bootstrapName := name.SafeConcatName(cluster.Name, "bootstrap", "template")
 
 	if dynamicSchema == nil {
@@ -174,15 +245,25 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 		if machinePoolNames[machinePool.Name] {
 			return nil, fmt.Errorf("duplicate machinePool name [%s] used", machinePool.Name)
 		}
+		if denied := planner.DeniedKubeletArgs(machinePool.KubeletArgs); len(denied) > 0 {
+			return nil, fmt.Errorf("kubelet args %v of machinePool [%s] may not be set", denied, machinePool.Name)
+		}
 		machinePoolNames[machinePool.Name] = true
 
 		var (
 			machinePoolName = name.SafeConcatName(cluster.Name, machinePool.Name)
 			infraRef        corev1.ObjectReference
+			userData        string
 		)
 
 		if machinePool.NodeConfig.APIVersion == "" || machinePool.NodeConfig.APIVersion == "rke-machine-config.cattle.io/v1" {
-			machineTemplate, err := toMachineTemplate(machinePoolName, cluster, machinePool, dynamic, dynamicSchema, secrets)
+			var err error
+			userData, err = userDataResolver.resolve(cluster, machinePool)
+			if err != nil {
+				return nil, err
+			}
+
+			machineTemplate, err := toMachineTemplate(machinePoolName, cluster, machinePool, dynamic, dynamicSchema, secrets, userData)
 			if err != nil {
 				return nil, err
 			}
@@ -255,6 +336,10 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 			machineDeployment.Spec.Template.Labels[planner.WorkerRoleLabel] = "true"
 		}
 
+		if userData != "" {
+			machineDeployment.Spec.Template.Annotations[UserDataHashAnnotation] = userDataHash(userData)
+		}
+
 		if len(machinePool.Labels) > 0 {
 			if err := assign(machineDeployment.Spec.Template.Annotations, planner.LabelsAnnotation, machinePool.Labels); err != nil {
 				return nil, err
@@ -288,9 +373,32 @@ func rkeCluster(cluster *rancherv1.Cluster) *rkev1.RKECluster {
 			Name:      cluster.Name,
 			Namespace: cluster.Namespace,
 		},
+		Spec: rkev1.RKEClusterSpec{
+			ControlPlaneEndpoint: cluster.Spec.RKEConfig.ControlPlaneEndpoint.DeepCopy(),
+		},
 	}
 }
 
+// machinePoolKubeletArgs returns the kubelet args of the machine pools keyed by the name of their MachineDeployment,
+// the planner merges them in the config of the machines of the pools.
+func machinePoolKubeletArgs(cluster *rancherv1.Cluster) map[string]map[string]string {
+	var result map[string]map[string]string
+	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		if len(machinePool.KubeletArgs) == 0 {
+			continue
+		}
+		if result == nil {
+			result = map[string]map[string]string{}
+		}
+		args := map[string]string{}
+		for k, v := range machinePool.KubeletArgs {
+			args[k] = v
+		}
+		result[name.SafeConcatName(cluster.Name, machinePool.Name)] = args
+	}
+	return result
+}
+
 func rkeControlPlane(cluster *rancherv1.Cluster) *rkev1.RKEControlPlane {
 	return &rkev1.RKEControlPlane{
 		ObjectMeta: metav1.ObjectMeta{
@@ -301,13 +409,14 @@ func rkeControlPlane(cluster *rancherv1.Cluster) *rkev1.RKEControlPlane {
 			},
 		},
 		Spec: rkev1.RKEControlPlaneSpec{
-			RKEClusterSpecCommon:  *cluster.Spec.RKEConfig.RKEClusterSpecCommon.DeepCopy(),
-			ETCDSnapshotRestore:   cluster.Spec.RKEConfig.ETCDSnapshotRestore.DeepCopy(),
-			ETCDSnapshotCreate:    cluster.Spec.RKEConfig.ETCDSnapshotCreate.DeepCopy(),
-			KubernetesVersion:     cluster.Spec.KubernetesVersion,
-			ManagementClusterName: cluster.Status.ClusterName,
-			AgentEnvVars:          cluster.Spec.AgentEnvVars,
-			ClusterName:           cluster.Name,
+			RKEClusterSpecCommon:   *cluster.Spec.RKEConfig.RKEClusterSpecCommon.DeepCopy(),
+			ETCDSnapshotRestore:    cluster.Spec.RKEConfig.ETCDSnapshotRestore.DeepCopy(),
+			ETCDSnapshotCreate:     cluster.Spec.RKEConfig.ETCDSnapshotCreate.DeepCopy(),
+			KubernetesVersion:      cluster.Spec.KubernetesVersion,
+			ManagementClusterName:  cluster.Status.ClusterName,
+			AgentEnvVars:           cluster.Spec.AgentEnvVars,
+			ClusterName:            cluster.Name,
+			MachinePoolKubeletArgs: machinePoolKubeletArgs(cluster),
 		},
 	}
 }
@@ -323,7 +432,7 @@ func capiCluster(cluster *rancherv1.Cluster, rkeControlPlane *rkev1.RKEControlPl
 
 	ownerGVK := rancherv1.SchemeGroupVersion.WithKind("Cluster")
 	ownerAPIVersion, _ := ownerGVK.ToAPIVersionAndKind()
-	return &capi.Cluster{
+	result := &capi.Cluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cluster.Name,
 			Namespace: cluster.Namespace,
@@ -348,4 +457,13 @@ func capiCluster(cluster *rancherv1.Cluster, rkeControlPlane *rkev1.RKEControlPl
 			},
 		},
 	}
+
+	if endpoint := cluster.Spec.RKEConfig.ControlPlaneEndpoint; endpoint != nil {
+		result.Spec.ControlPlaneEndpoint = capi.APIEndpoint{
+			Host: endpoint.Host,
+			Port: int32(endpoint.Port),
+		}
+	}
+
+	return result
 }
@@ -0,0 +1,101 @@
+package provisioningcluster
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	mgmtcontroller "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func TestCAPIClusterControlPlaneEndpoint(t *testing.T) {
+	cluster := newCluster()
+	cluster.Spec.RKEConfig.ControlPlaneEndpoint = &rkev1.Endpoint{
+		Host: "cluster.example.com",
+		Port: 6443,
+	}
+
+	rkeCluster := rkeCluster(cluster)
+	result := capiCluster(cluster, rkeControlPlane(cluster), getInfraRef(rkeCluster))
+	assert.Equal(t, capi.APIEndpoint{Host: "cluster.example.com", Port: 6443}, result.Spec.ControlPlaneEndpoint)
+	assert.Equal(t, &rkev1.Endpoint{Host: "cluster.example.com", Port: 6443}, rkeCluster.Spec.ControlPlaneEndpoint)
+}
+
+func TestCAPIClusterNoControlPlaneEndpoint(t *testing.T) {
+	cluster := newCluster()
+
+	rkeCluster := rkeCluster(cluster)
+	result := capiCluster(cluster, rkeControlPlane(cluster), getInfraRef(rkeCluster))
+	assert.Equal(t, capi.APIEndpoint{}, result.Spec.ControlPlaneEndpoint)
+	assert.Nil(t, rkeCluster.Spec.ControlPlaneEndpoint)
+}
+
+func TestRKEControlPlaneMachinePoolKubeletArgs(t *testing.T) {
+	memoryPool := newMachinePool("memory", nil)
+	memoryPool.KubeletArgs = map[string]string{"eviction-hard": "memory.available<1Gi"}
+	cluster := newCluster(memoryPool, newMachinePool("worker", nil))
+
+	controlPlane := rkeControlPlane(cluster)
+	assert.Equal(t, map[string]map[string]string{
+		"test-memory": {"eviction-hard": "memory.available<1Gi"},
+	}, controlPlane.Spec.MachinePoolKubeletArgs)
+
+	assert.Nil(t, rkeControlPlane(newCluster(newMachinePool("worker", nil))).Spec.MachinePoolKubeletArgs)
+}
+
+type fakeDynamicSchemaCache struct {
+	mgmtcontroller.DynamicSchemaCache
+}
+
+func TestObjectsGeneratedObjectMetadata(t *testing.T) {
+	machinePool := newMachinePool("worker", int32Ptr(3))
+	machinePool.NodeConfig.APIVersion = "infrastructure.example.com/v1"
+	machinePool.MachineDeploymentLabels = map[string]string{"cost-center": "pool-override"}
+	cluster := newCluster(machinePool)
+	cluster.Spec.RKEConfig.GeneratedObjectLabels = map[string]string{"cost-center": "cc-1234", "team": "platform"}
+	cluster.Spec.RKEConfig.GeneratedObjectAnnotations = map[string]string{"billing.example.com/owner": "platform@example.com"}
+
+	objs, err := objects(cluster, nil, fakeDynamicSchemaCache{}, nil, nil)
+	require.NoError(t, err)
+
+	var capiClusters, controlPlanes, machineDeployments int
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *capi.Cluster:
+			capiClusters++
+			assert.Equal(t, map[string]string{"cost-center": "cc-1234", "team": "platform"}, o.Labels)
+			assert.Equal(t, "platform@example.com", o.Annotations["billing.example.com/owner"])
+		case *rkev1.RKEControlPlane:
+			controlPlanes++
+			assert.Equal(t, "cc-1234", o.Labels["cost-center"])
+			assert.Contains(t, o.Labels, planner.InitNodeMachineIDLabel, "expected the labels of Rancher to be kept")
+			assert.Equal(t, "platform@example.com", o.Annotations["billing.example.com/owner"])
+		case *capi.MachineDeployment:
+			machineDeployments++
+			assert.Equal(t, map[string]string{"cost-center": "pool-override", "team": "platform"}, o.Labels,
+				"expected the labels of the machine pool to be kept")
+			assert.Equal(t, "platform@example.com", o.Annotations["billing.example.com/owner"])
+			assert.Equal(t, map[string]string{"cost-center": "pool-override"}, machinePool.MachineDeploymentLabels,
+				"expected the labels of the machine pool not to be modified")
+		}
+	}
+	assert.Equal(t, 1, capiClusters)
+	assert.Equal(t, 1, controlPlanes)
+	assert.Equal(t, 1, machineDeployments)
+}
+
+func TestObjectsGeneratedObjectMetadataReserved(t *testing.T) {
+	cluster := newCluster()
+	cluster.Spec.RKEConfig.GeneratedObjectLabels = map[string]string{"cluster.x-k8s.io/cluster-name": "other"}
+
+	_, err := objects(cluster, nil, fakeDynamicSchemaCache{}, nil, nil)
+	assert.EqualError(t, err, "key [cluster.x-k8s.io/cluster-name] of generatedObjectLabels may not be set, the cluster.x-k8s.io/ prefix is reserved")
+
+	cluster.Spec.RKEConfig.GeneratedObjectLabels = nil
+	cluster.Spec.RKEConfig.GeneratedObjectAnnotations = map[string]string{"rke.cattle.io/labels": "{}"}
+	_, err = objects(cluster, nil, fakeDynamicSchemaCache{}, nil, nil)
+	assert.EqualError(t, err, "key [rke.cattle.io/labels] of generatedObjectAnnotations may not be set, the rke.cattle.io/ prefix is reserved")
+}
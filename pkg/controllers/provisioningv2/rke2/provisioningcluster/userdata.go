@@ -0,0 +1,194 @@
+package provisioningcluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	mgmtcontroller "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	v1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	authzv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	authzv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+const (
+	// UserDataKey is the key of the cloud-init user data in the secret or config map referenced by a machine pool
+	UserDataKey = "userdata"
+	// UserDataHashAnnotation is set on the machines of a pool to the hash of its referenced user data, so that the
+	// machines are replaced when the user data changes
+	UserDataHashAnnotation = "rke.cattle.io/user-data-hash"
+
+	byUserData   = "by-user-data"
+	creatorIDAnn = "field.cattle.io/creatorId"
+)
+
+// userDataFields are the fields of the machine configs holding the cloud-init user data of the machines
+var userDataFields = map[string]string{
+	"HarvesterConfig":     "userData",
+	"VmwarevsphereConfig": "cloudConfig",
+}
+
+type userDataResolver struct {
+	ctx                  context.Context
+	secrets              v1.SecretCache
+	configMaps           v1.ConfigMapCache
+	userAttributes       mgmtcontroller.UserAttributeCache
+	subjectAccessReviews authzv1client.SubjectAccessReviewInterface
+}
+
+// resolve returns the user data referenced by the machine pool, or an empty string if it doesn't reference any. The
+// creator of the cluster has to be able to get the referenced object.
+func (u *userDataResolver) resolve(cluster *rancherv1.Cluster, machinePool rancherv1.RKEMachinePool) (string, error) {
+	var (
+		resource, name string
+		content        map[string]string
+	)
+	switch {
+	case machinePool.UserDataSecretName != "" && machinePool.UserDataConfigMapName != "":
+		return "", fmt.Errorf("machinePool [%s] may only reference the user data of a secret or of a config map", machinePool.Name)
+	case machinePool.UserDataSecretName != "":
+		resource, name = "secrets", machinePool.UserDataSecretName
+		secret, err := u.secrets.Get(cluster.Namespace, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to get user data secret [%s] of machinePool [%s]: %w", name, machinePool.Name, err)
+		}
+		content = map[string]string{}
+		for k, v := range secret.Data {
+			content[k] = string(v)
+		}
+	case machinePool.UserDataConfigMapName != "":
+		resource, name = "configmaps", machinePool.UserDataConfigMapName
+		configMap, err := u.configMaps.Get(cluster.Namespace, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to get user data config map [%s] of machinePool [%s]: %w", name, machinePool.Name, err)
+		}
+		content = configMap.Data
+	default:
+		return "", nil
+	}
+
+	userData, ok := content[UserDataKey]
+	if !ok {
+		return "", fmt.Errorf("%s [%s] of machinePool [%s] has no [%s] key", resource, name, machinePool.Name, UserDataKey)
+	}
+	if _, ok := userDataFields[machinePool.NodeConfig.Kind]; !ok {
+		return "", fmt.Errorf("machinePool [%s] of kind [%s] doesn't support user data", machinePool.Name, machinePool.NodeConfig.Kind)
+	}
+	if err := u.checkAccess(cluster, resource, name); err != nil {
+		return "", err
+	}
+	return userData, nil
+}
+
+// checkAccess verifies that the creator of the cluster can get the object holding the user data, so that the user
+// data of objects the creator can't read doesn't end up on its machines
+func (u *userDataResolver) checkAccess(cluster *rancherv1.Cluster, resource, name string) error {
+	creatorID := cluster.Annotations[creatorIDAnn]
+	if creatorID == "" {
+		// clusters created before the creator was recorded can't be checked
+		return nil
+	}
+
+	groups, err := u.creatorGroups(creatorID)
+	if err != nil {
+		return err
+	}
+	allowed, err := sar.UserCanDo(u.ctx, u.subjectAccessReviews, creatorID, groups, authzv1.ResourceAttributes{
+		Verb:      "get",
+		Resource:  resource,
+		Namespace: cluster.Namespace,
+		Name:      name,
+	})
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("creator [%s] of cluster [%s/%s] can't get the user data of %s [%s]", creatorID,
+			cluster.Namespace, cluster.Name, resource, name)
+	}
+	return nil
+}
+
+// creatorGroups returns the groups of the creator of a cluster, so that the access granted to them is taken into
+// account like it is when the creator makes a request
+func (u *userDataResolver) creatorGroups(creatorID string) ([]string, error) {
+	groups := []string{user.AllAuthenticated, "system:cattle:authenticated"}
+	attribs, err := u.userAttributes.Get(creatorID)
+	if apierrors.IsNotFound(err) {
+		return groups, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, principals := range attribs.GroupPrincipals {
+		for _, principal := range principals.Items {
+			groups = append(groups, strings.TrimPrefix(principal.Name, "local://"))
+		}
+	}
+	return groups, nil
+}
+
+// userDataHash returns a short hash of the user data, which is part of the machine template of a pool
+func userDataHash(userData string) string {
+	hash := sha256.Sum256([]byte(userData))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+func byUserDataIndex(obj *rancherv1.Cluster) ([]string, error) {
+	if obj.Spec.RKEConfig == nil {
+		return nil, nil
+	}
+
+	var result []string
+	for _, np := range obj.Spec.RKEConfig.MachinePools {
+		if np.UserDataSecretName != "" {
+			result = append(result, toUserDataKey("secrets", obj.Namespace, np.UserDataSecretName))
+		}
+		if np.UserDataConfigMapName != "" {
+			result = append(result, toUserDataKey("configmaps", obj.Namespace, np.UserDataConfigMapName))
+		}
+	}
+	return result, nil
+}
+
+func toUserDataKey(resource, namespace, name string) string {
+	return resource + "/" + namespace + "/" + name
+}
+
+// clustersForUserData enqueues the clusters whose machine pools reference the user data of a secret or config map when
+// it changes
+func (h *handler) clustersForUserData(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	var resources []string
+	switch obj.(type) {
+	case *corev1.Secret:
+		resources = []string{"secrets"}
+	case *corev1.ConfigMap:
+		resources = []string{"configmaps"}
+	default:
+		// the type of a deleted object is unknown
+		resources = []string{"secrets", "configmaps"}
+	}
+
+	var result []relatedresource.Key
+	for _, resource := range resources {
+		clusters, err := h.clusterCache.GetByIndex(byUserData, toUserDataKey(resource, namespace, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusters {
+			result = append(result, relatedresource.Key{
+				Namespace: cluster.Namespace,
+				Name:      cluster.Name,
+			})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,196 @@
+package provisioningcluster
+
+import (
+	"context"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	mgmtcontroller "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	v1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeSecretCache struct {
+	v1.SecretCache
+	secrets map[string]*corev1.Secret
+}
+
+func (f *fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	if secret, ok := f.secrets[namespace+"/"+name]; ok {
+		return secret, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+type fakeConfigMapCache struct {
+	v1.ConfigMapCache
+	configMaps map[string]*corev1.ConfigMap
+}
+
+func (f *fakeConfigMapCache) Get(namespace, name string) (*corev1.ConfigMap, error) {
+	if configMap, ok := f.configMaps[namespace+"/"+name]; ok {
+		return configMap, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+}
+
+type fakeUserAttributeCache struct {
+	mgmtcontroller.UserAttributeCache
+	userAttributes map[string]*v3.UserAttribute
+}
+
+func (f *fakeUserAttributeCache) Get(name string) (*v3.UserAttribute, error) {
+	if userAttribute, ok := f.userAttributes[name]; ok {
+		return userAttribute, nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+}
+
+func newUserDataResolver() *userDataResolver {
+	return &userDataResolver{
+		ctx: context.Background(),
+		secrets: &fakeSecretCache{secrets: map[string]*corev1.Secret{
+			"fleet-default/harvester-userdata": {Data: map[string][]byte{UserDataKey: []byte("#cloud-config\npassword: secret")}},
+			"fleet-default/other-key":          {Data: map[string][]byte{"cloud-config": []byte("#cloud-config")}},
+		}},
+		configMaps: &fakeConfigMapCache{configMaps: map[string]*corev1.ConfigMap{
+			"fleet-default/vsphere-userdata": {Data: map[string]string{UserDataKey: "#cloud-config\nruncmd: []"}},
+		}},
+		userAttributes: &fakeUserAttributeCache{userAttributes: map[string]*v3.UserAttribute{
+			"u-fghij": {GroupPrincipals: map[string]v3.Principals{
+				"okta": {Items: []v3.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "okta_group://provisioners"}}}},
+			}},
+		}},
+		subjectAccessReviews: &fake.SubjectAccessReviews{Allowed: map[string][]string{
+			"u-abcde":                   {"secrets/fleet-default/harvester-userdata"},
+			"okta_group://provisioners": {"configmaps/fleet-default/vsphere-userdata"},
+		}},
+	}
+}
+
+func newUserDataMachinePool(kind string) rancherv1.RKEMachinePool {
+	machinePool := newMachinePool("pool1", int32Ptr(1))
+	machinePool.NodeConfig.Kind = kind
+	return machinePool
+}
+
+func TestResolveUserData(t *testing.T) {
+	resolver := newUserDataResolver()
+	cluster := newCluster()
+
+	machinePool := newUserDataMachinePool("HarvesterConfig")
+	userData, err := resolver.resolve(cluster, machinePool)
+	require.NoError(t, err)
+	assert.Empty(t, userData, "expected no user data for a machine pool without reference")
+
+	machinePool.UserDataSecretName = "harvester-userdata"
+	userData, err = resolver.resolve(cluster, machinePool)
+	require.NoError(t, err)
+	assert.Equal(t, "#cloud-config\npassword: secret", userData)
+
+	machinePool = newUserDataMachinePool("VmwarevsphereConfig")
+	machinePool.UserDataConfigMapName = "vsphere-userdata"
+	userData, err = resolver.resolve(cluster, machinePool)
+	require.NoError(t, err)
+	assert.Equal(t, "#cloud-config\nruncmd: []", userData)
+}
+
+func TestResolveUserDataErrors(t *testing.T) {
+	resolver := newUserDataResolver()
+	cluster := newCluster()
+
+	machinePool := newUserDataMachinePool("HarvesterConfig")
+	machinePool.UserDataSecretName = "missing"
+	_, err := resolver.resolve(cluster, machinePool)
+	assert.True(t, errors.IsNotFound(err), "expected a missing secret to be reported, got %v", err)
+
+	machinePool = newUserDataMachinePool("VmwarevsphereConfig")
+	machinePool.UserDataConfigMapName = "missing"
+	_, err = resolver.resolve(cluster, machinePool)
+	assert.True(t, errors.IsNotFound(err), "expected a missing config map to be reported, got %v", err)
+
+	machinePool = newUserDataMachinePool("HarvesterConfig")
+	machinePool.UserDataSecretName = "other-key"
+	_, err = resolver.resolve(cluster, machinePool)
+	assert.EqualError(t, err, "secrets [other-key] of machinePool [pool1] has no [userdata] key")
+
+	machinePool = newUserDataMachinePool("HarvesterConfig")
+	machinePool.UserDataSecretName = "harvester-userdata"
+	machinePool.UserDataConfigMapName = "vsphere-userdata"
+	_, err = resolver.resolve(cluster, machinePool)
+	assert.Error(t, err, "expected a machine pool to reference a single object")
+
+	machinePool = newUserDataMachinePool("Amazonec2Config")
+	machinePool.UserDataSecretName = "harvester-userdata"
+	_, err = resolver.resolve(cluster, machinePool)
+	assert.EqualError(t, err, "machinePool [pool1] of kind [Amazonec2Config] doesn't support user data")
+}
+
+func TestResolveUserDataCreatorAccess(t *testing.T) {
+	resolver := newUserDataResolver()
+	cluster := newCluster()
+	cluster.Annotations = map[string]string{creatorIDAnn: "u-abcde"}
+
+	machinePool := newUserDataMachinePool("HarvesterConfig")
+	machinePool.UserDataSecretName = "harvester-userdata"
+	_, err := resolver.resolve(cluster, machinePool)
+	assert.NoError(t, err)
+
+	machinePool = newUserDataMachinePool("VmwarevsphereConfig")
+	machinePool.UserDataConfigMapName = "vsphere-userdata"
+	_, err = resolver.resolve(cluster, machinePool)
+	assert.EqualError(t, err, "creator [u-abcde] of cluster [fleet-default/test] can't get the user data of configmaps [vsphere-userdata]")
+
+	// the access granted to a group of the creator is taken into account
+	cluster.Annotations = map[string]string{creatorIDAnn: "u-fghij"}
+	_, err = resolver.resolve(cluster, machinePool)
+	assert.NoError(t, err)
+}
+
+func TestUserDataHash(t *testing.T) {
+	hash := userDataHash("#cloud-config\nruncmd: []")
+	assert.Len(t, hash, 16)
+	assert.Equal(t, hash, userDataHash("#cloud-config\nruncmd: []"))
+	assert.NotEqual(t, hash, userDataHash("#cloud-config\nruncmd: [reboot]"), "expected the hash to change with the user data")
+}
+
+func TestClustersForUserData(t *testing.T) {
+	secretPool := newUserDataMachinePool("HarvesterConfig")
+	secretPool.UserDataSecretName = "userdata"
+	secretCluster := newCluster(secretPool)
+	secretCluster.Name = "secret-userdata"
+
+	configMapPool := newUserDataMachinePool("VmwarevsphereConfig")
+	configMapPool.UserDataConfigMapName = "userdata"
+	configMapCluster := newCluster(configMapPool)
+	configMapCluster.Name = "configmap-userdata"
+
+	cache := &fakeClusterCache{
+		clusters: []*rancherv1.Cluster{secretCluster, configMapCluster, newCluster(newMachinePool("pool1", int32Ptr(1)))},
+	}
+	cache.AddIndexer(byUserData, byUserDataIndex)
+	h := handler{
+		clusterCache: cache,
+	}
+
+	keys, err := h.clustersForUserData("fleet-default", "userdata", &corev1.Secret{})
+	require.NoError(t, err)
+	assert.Equal(t, []relatedresource.Key{{Namespace: "fleet-default", Name: "secret-userdata"}}, keys)
+
+	keys, err = h.clustersForUserData("fleet-default", "userdata", &corev1.ConfigMap{})
+	require.NoError(t, err)
+	assert.Equal(t, []relatedresource.Key{{Namespace: "fleet-default", Name: "configmap-userdata"}}, keys)
+
+	// a deleted object enqueues the clusters referencing a secret or a config map with its name
+	keys, err = h.clustersForUserData("fleet-default", "userdata", nil)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
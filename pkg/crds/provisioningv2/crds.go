@@ -71,6 +71,10 @@ func rke2() []crd.CRD {
 				WithColumn("Ready", ".status.ready").
 				WithColumn("Kubeconfig", ".status.clientSecretName")
 		}),
+		newRancherCRD(&v1.ClusterProfile{}, func(c crd.CRD) crd.CRD {
+			return c.
+				WithColumn("Revision", ".status.revision")
+		}),
 		newRKECRD(&rkev1.RKECluster{}, func(c crd.CRD) crd.CRD {
 			c.Labels = map[string]string{
 				"cluster.x-k8s.io/v1alpha4": "v1",
@@ -13,6 +13,7 @@
 	"github.com/rancher/norman/types/slice"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/tunnelserver"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/rancher/pkg/types/config/dialer"
 	"github.com/rancher/rancher/pkg/wrangler"
@@ -36,6 +37,7 @@ func NewFactory(apiContext *config.ScaledContext, wrangler *wrangler.Context) (*
 		clusterLister: apiContext.Management.Clusters("").Controller().Lister(),
 		nodeLister:    apiContext.Management.Nodes("").Controller().Lister(),
 		TunnelServer:  wrangler.TunnelServer,
+		Sessions:      wrangler.TunnelSessions,
 	}, nil
 }
 
@@ -43,6 +45,7 @@ type Factory struct {
 	nodeLister    v3.NodeLister
 	clusterLister v3.ClusterLister
 	TunnelServer  *remotedialer.Server
+	Sessions      *tunnelserver.Sessions
 }
 
 func (f *Factory) ClusterDialer(clusterName string) (dialer.Dialer, error) {
@@ -193,7 +196,7 @@ func (f *Factory) clusterDialer(clusterName, address string) (dialer.Dialer, err
 
 	if f.TunnelServer.HasSession(cluster.Name) {
 		logrus.Tracef("dialerFactory: tunnel session found for cluster [%s]", cluster.Name)
-		cd := f.TunnelServer.Dialer(cluster.Name)
+		cd := f.tunnelDialer(cluster.Name)
 		return func(ctx context.Context, network, address string) (net.Conn, error) {
 			if cluster.Status.Driver == v32.ClusterDriverRKE {
 				address = f.translateClusterAddress(cluster, hostPort, address)
@@ -239,7 +242,7 @@ func (f *Factory) clusterDialer(clusterName, address string) (dialer.Dialer, err
 	for i := 0; i < 4; i++ {
 		if f.TunnelServer.HasSession(cluster.Name) {
 			logrus.Debugf("Cluster [%s] has reconnected, resuming", cluster.Name)
-			cd := f.TunnelServer.Dialer(cluster.Name)
+			cd := f.tunnelDialer(cluster.Name)
 			return func(ctx context.Context, network, address string) (net.Conn, error) {
 				if cluster.Status.Driver == v32.ClusterDriverRKE {
 					address = f.translateClusterAddress(cluster, hostPort, address)
@@ -286,7 +289,7 @@ func (f *Factory) DockerDialer(clusterName, machineName string) (dialer.Dialer,
 		if machine.Status.InternalNodeStatus.NodeInfo.OperatingSystem == "windows" {
 			network, address = "npipe", "//./pipe/docker_engine"
 		}
-		d := f.TunnelServer.Dialer(sessionKey)
+		d := f.tunnelDialer(sessionKey)
 		return func(ctx context.Context, _ string, _ string) (net.Conn, error) {
 			return d(ctx, network, address)
 		}, nil
@@ -313,8 +316,8 @@ func (f *Factory) nodeDialer(clusterName, machineName string) (dialer.Dialer, er
 
 	sessionKey := machineSessionKey(machine)
 	if f.TunnelServer.HasSession(sessionKey) {
-		d := f.TunnelServer.Dialer(sessionKey)
-		return dialer.Dialer(d), nil
+		d := f.tunnelDialer(sessionKey)
+		return d, nil
 	}
 
 	return nil, fmt.Errorf("can not build dialer to [%s:%s]", clusterName, machineName)
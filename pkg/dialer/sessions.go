@@ -0,0 +1,97 @@
+package dialer
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/auth/util"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/tunnelserver"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	typedauthzv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// TunnelStatus is the state of the tunnel of a cluster agent. Connected is true if the agent is connected to this
+// server or to one of its peers, Sessions only holds the sessions connected to this server.
+type TunnelStatus struct {
+	Cluster   string                     `json:"cluster"`
+	Connected bool                       `json:"connected"`
+	Sessions  []tunnelserver.SessionInfo `json:"sessions"`
+}
+
+// TunnelStatus returns the state of the tunnel of the agent of the cluster
+func (f *Factory) TunnelStatus(clusterName string) TunnelStatus {
+	status := TunnelStatus{
+		Cluster:   clusterName,
+		Connected: f.TunnelServer.HasSession(clusterName),
+		Sessions:  []tunnelserver.SessionInfo{},
+	}
+	if f.Sessions != nil {
+		status.Sessions = f.Sessions.Sessions(clusterName)
+	}
+	return status
+}
+
+// tunnelDialer returns a dialer through the tunnel session of the client key, which records the connections in the
+// tunnel sessions
+func (f *Factory) tunnelDialer(clientKey string) dialer.Dialer {
+	d := f.TunnelServer.Dialer(clientKey)
+	if f.Sessions == nil {
+		return dialer.Dialer(d)
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := d(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return f.Sessions.TrackConn(clientKey, conn), nil
+	}
+}
+
+type tunnelStatusFunc func(clusterName string) TunnelStatus
+
+type tunnelSessionsHandler struct {
+	clusterLister v3.ClusterLister
+	sarClient     typedauthzv1.SubjectAccessReviewInterface
+	tunnelStatus  tunnelStatusFunc
+}
+
+// NewTunnelSessionsHandler serves the state of the tunnel of a cluster agent to the admins, whether they are admins
+// through their own global role bindings or the ones of their groups
+func NewTunnelSessionsHandler(scaledContext *config.ScaledContext, factory *Factory) http.Handler {
+	return &tunnelSessionsHandler{
+		clusterLister: scaledContext.Management.Clusters("").Controller().Lister(),
+		sarClient:     scaledContext.K8sClient.AuthorizationV1().SubjectAccessReviews(),
+		tunnelStatus:  factory.TunnelStatus,
+	}
+}
+
+func (h *tunnelSessionsHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	isAdmin, err := sar.IsAdmin(req, h.sarClient)
+	if err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !isAdmin {
+		util.ReturnHTTPError(rw, req, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	clusterID := mux.Vars(req)["clusterID"]
+	if _, err := h.clusterLister.Get("", clusterID); kerror.IsNotFound(err) {
+		util.ReturnHTTPError(rw, req, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(h.tunnelStatus(clusterID))
+}
@@ -0,0 +1,85 @@
+package dialer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/tunnelserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newTunnelSessionsRouter() http.Handler {
+	connectedAt := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	h := &tunnelSessionsHandler{
+		clusterLister: &fakes.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Cluster, error) {
+				if name != "c-abcde" {
+					return nil, kerror.NewNotFound(schema.GroupResource{Group: "management.cattle.io", Resource: "clusters"}, name)
+				}
+				return &v3.Cluster{}, nil
+			},
+		},
+		sarClient: &fake.SubjectAccessReviews{Admins: map[string]bool{
+			"u-admin":               true,
+			"okta_group://platform": true,
+		}},
+		tunnelStatus: func(clusterName string) TunnelStatus {
+			return TunnelStatus{
+				Cluster:   clusterName,
+				Connected: true,
+				Sessions: []tunnelserver.SessionInfo{{
+					ClientKey:         clusterName,
+					PeerIP:            "10.0.0.1",
+					ConnectedAt:       connectedAt,
+					LastActivity:      connectedAt.Add(time.Minute),
+					ActiveConnections: 3,
+				}},
+			}
+		},
+	}
+
+	router := mux.NewRouter()
+	router.Path("/v3/tunnelsessions/{clusterID}").Handler(h)
+	return router
+}
+
+func TestTunnelSessionsHandler(t *testing.T) {
+	router := newTunnelSessionsRouter()
+	request := func(user, cluster string, groups ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v3/tunnelsessions/"+cluster, nil)
+		if user != "" {
+			req.Header.Set("Impersonate-User", user)
+		}
+		for _, group := range groups {
+			req.Header.Add("Impersonate-Group", group)
+		}
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	assert.Equal(t, http.StatusForbidden, request("", "c-abcde").Code)
+	assert.Equal(t, http.StatusForbidden, request("u-user", "c-abcde").Code)
+	assert.Equal(t, http.StatusNotFound, request("u-admin", "c-other").Code)
+	assert.Equal(t, http.StatusOK, request("u-member", "c-abcde", "okta_group://platform").Code,
+		"expected the members of an admin group to be admins")
+
+	rw := request("u-admin", "c-abcde")
+	require.Equal(t, http.StatusOK, rw.Code)
+	status := TunnelStatus{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+	assert.True(t, status.Connected)
+	require.Len(t, status.Sessions, 1)
+	assert.Equal(t, "10.0.0.1", status.Sessions[0].PeerIP)
+	assert.Equal(t, 3, status.Sessions[0].ActiveConnections)
+}
@@ -0,0 +1,24 @@
+package eventrecorder
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/pkg/schemes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// New returns a recorder of the events of the component, which are written to the cluster of the given client. The
+// broadcaster of the recorder is shut down once the context is done, so that the recorders of the controllers of a
+// removed cluster or of a lost leadership don't keep running.
+func New(ctx context.Context, k8sClient kubernetes.Interface, component string) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedv1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	go func() {
+		<-ctx.Done()
+		eventBroadcaster.Shutdown()
+	}()
+	return eventBroadcaster.NewRecorder(schemes.All, corev1.EventSource{Component: component})
+}
@@ -32,6 +32,10 @@ func (c *FakeProvisioningV1) Clusters(namespace string) v1.ClusterInterface {
 	return &FakeClusters{c, namespace}
 }
 
+func (c *FakeProvisioningV1) ClusterProfiles(namespace string) v1.ClusterProfileInterface {
+	return &FakeClusterProfiles{c, namespace}
+}
+
 func (c *FakeProvisioningV1) ManagedOSs(namespace string) v1.ManagedOSInterface {
 	return &FakeManagedOSs{c, namespace}
 }
@@ -20,4 +20,6 @@
 
 type ClusterExpansion interface{}
 
+type ClusterProfileExpansion interface{}
+
 type ManagedOSExpansion interface{}
@@ -27,6 +27,7 @@
 type ProvisioningV1Interface interface {
 	RESTClient() rest.Interface
 	ClustersGetter
+	ClusterProfilesGetter
 	ManagedOSsGetter
 }
 
@@ -39,6 +40,10 @@ func (c *ProvisioningV1Client) Clusters(namespace string) ClusterInterface {
 	return newClusters(c, namespace)
 }
 
+func (c *ProvisioningV1Client) ClusterProfiles(namespace string) ClusterProfileInterface {
+	return newClusterProfiles(c, namespace)
+}
+
 func (c *ProvisioningV1Client) ManagedOSs(namespace string) ManagedOSInterface {
 	return newManagedOSs(c, namespace)
 }
@@ -31,6 +31,7 @@ func init() {
 
 type Interface interface {
 	Cluster() ClusterController
+	ClusterProfile() ClusterProfileController
 	ManagedOS() ManagedOSController
 }
 
@@ -47,6 +48,9 @@ type version struct {
 func (c *version) Cluster() ClusterController {
 	return NewClusterController(schema.GroupVersionKind{Group: "provisioning.cattle.io", Version: "v1", Kind: "Cluster"}, "clusters", true, c.controllerFactory)
 }
+func (c *version) ClusterProfile() ClusterProfileController {
+	return NewClusterProfileController(schema.GroupVersionKind{Group: "provisioning.cattle.io", Version: "v1", Kind: "ClusterProfile"}, "clusterprofiles", true, c.controllerFactory)
+}
 func (c *version) ManagedOS() ManagedOSController {
 	return NewManagedOSController(schema.GroupVersionKind{Group: "provisioning.cattle.io", Version: "v1", Kind: "ManagedOS"}, "managedoss", true, c.controllerFactory)
 }
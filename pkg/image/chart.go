@@ -0,0 +1,54 @@
+package image
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/rancher/pkg/settings"
+)
+
+// ChartImages returns the images set in the values of a chart, keyed by the path of the image in the values. The
+// images are prefixed with the registry as the charts of Rancher do with global.cattle.systemDefaultRegistry.
+func ChartImages(values map[string]interface{}, registry string) map[string]string {
+	images := map[string]string{}
+	collectChartImages(values, nil, registry, images)
+	return images
+}
+
+func collectChartImages(values map[string]interface{}, valuesPath []string, registry string, images map[string]string) {
+	repo, repoOk := values["repository"].(string)
+	tag, tagOk := values["tag"]
+	if repoOk && tagOk && repo != "" {
+		image := fmt.Sprintf("%s:%v", repo, tag)
+		if registry != "" {
+			image = registry + "/" + image
+		}
+		images[strings.Join(valuesPath, ".")] = image
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if child, ok := values[key].(map[string]interface{}); ok {
+			collectChartImages(child, append(valuesPath[:len(valuesPath):len(valuesPath)], key), registry, images)
+		}
+	}
+}
+
+// SystemAgentUpgradeImage returns the image and the version of the plan upgrading the system agent of the clusters
+// provisioned by Rancher, the image is empty if the system-agent-upgrade-image setting isn't set
+func SystemAgentUpgradeImage() (string, string) {
+	if settings.SystemAgentUpgradeImage.Get() == "" {
+		return "", ""
+	}
+	image := strings.SplitN(settings.SystemAgentUpgradeImage.Get(), ":", 2)
+	version := "latest"
+	if len(image) == 2 {
+		version = image[1]
+	}
+	return settings.PrefixPrivateRegistry(image[0]), version
+}
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path"
@@ -17,6 +18,14 @@
 
 const BaseJailPath = "/opt/jail"
 
+// Limits are the resource limits of a jailed process, a zero limit leaves the resource unlimited
+type Limits struct {
+	// MemoryBytes limits the address space of the process
+	MemoryBytes uint64
+	// CPUSeconds limits the CPU time of the process
+	CPUSeconds uint64
+}
+
 var lock = sync.Mutex{}
 
 // CreateJail sets up the named directory for use with chroot
@@ -77,6 +86,26 @@ func CreateJail(name string) error {
 	return nil
 }
 
+// RemoveJail removes the named jail directory and everything in it, it is a no-op if the jail was never created
+func RemoveJail(name string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return removeJail(BaseJailPath, name)
+}
+
+// removeJail removes the named directory of the jail root, refusing any name that would resolve to another directory
+// than a direct child of the root
+func removeJail(root, name string) error {
+	jailPath := path.Join(root, name)
+	if name == "" || strings.Contains(name, "/") || path.Dir(jailPath) != path.Clean(root) || path.Base(jailPath) != name {
+		return fmt.Errorf("invalid jail name [%s]", name)
+	}
+
+	logrus.Debugf("RemoveJail: removing jailPath [%s]", jailPath)
+	return os.RemoveAll(jailPath)
+}
+
 func WhitelistEnvvars(envvars []string) []string {
 	wl := settings.WhitelistEnvironmentVars.Get()
 	envWhiteList := strings.Split(wl, ",")
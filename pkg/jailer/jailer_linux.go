@@ -1,15 +1,20 @@
 package jailer
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"os/user"
 	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/pkg/errors"
 )
 
+// limitShell applies the limits of a command before executing it
+const limitShell = "/bin/bash"
+
 func JailCommand(cmd *exec.Cmd, jailPath string) (*exec.Cmd, error) {
 	if os.Getenv("CATTLE_DEV_MODE") != "" {
 		return cmd, nil
@@ -54,3 +59,41 @@ func getUserCred() (*syscall.Credential, error) {
 
 	return &syscall.Credential{Uid: uid, Gid: gid}, nil
 }
+
+// SetProcessGroup runs the command in a process group of its own, so that KillProcessGroup also kills the processes
+// it starts. It must be called after JailCommand.
+func SetProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// KillProcessGroup kills the process group of a command started after SetProcessGroup
+func KillProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// LimitCommand runs the command through bash, which applies the limits to itself and then executes the command, so that
+// the command is limited from its start and the processes it starts inherit the limits. The jails provide /bin/bash.
+// It must be called before the command is started.
+func LimitCommand(cmd *exec.Cmd, limits Limits) {
+	var script []string
+	if limits.MemoryBytes > 0 {
+		// the address space limit of ulimit is set in kilobytes
+		script = append(script, fmt.Sprintf("ulimit -v %d", limits.MemoryBytes/1024))
+	}
+	if limits.CPUSeconds > 0 {
+		script = append(script, fmt.Sprintf("ulimit -t %d", limits.CPUSeconds))
+	}
+	if len(script) == 0 {
+		return
+	}
+	script = append(script, `exec "$0" "$@"`)
+
+	cmd.Args = append([]string{limitShell, "-c", strings.Join(script, " && "), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = limitShell
+}
@@ -0,0 +1,36 @@
+package jailer
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// processLimit returns the soft limit of /proc/self/limits starting with the name
+func processLimit(t *testing.T, limits, name string) string {
+	for _, line := range strings.Split(limits, "\n") {
+		if strings.HasPrefix(line, name) {
+			return strings.Fields(strings.TrimPrefix(line, name))[0]
+		}
+	}
+	require.Failf(t, "limit not found", "no %s limit in %s", name, limits)
+	return ""
+}
+
+func TestLimitCommand(t *testing.T) {
+	cmd := exec.Command("cat", "/proc/self/limits")
+	LimitCommand(cmd, Limits{MemoryBytes: 1 << 30, CPUSeconds: 60})
+	assert.Equal(t, "/bin/bash", cmd.Path)
+
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "1073741824", processLimit(t, string(out), "Max address space"), "expected the command to start limited")
+	assert.Equal(t, "60", processLimit(t, string(out), "Max cpu time"))
+
+	cmd = exec.Command("cat", "/proc/self/limits")
+	LimitCommand(cmd, Limits{})
+	assert.Equal(t, []string{"cat", "/proc/self/limits"}, cmd.Args, "expected no limits to leave the command alone")
+}
@@ -13,3 +13,21 @@ func JailCommand(cmd *exec.Cmd, jailPath string) (*exec.Cmd, error) {
 	logrus.Warnf("not jailing command %v, unsupported on %s", cmd.Args, runtime.GOOS)
 	return cmd, nil
 }
+
+// SetProcessGroup is a no-op, process groups are only set on linux
+func SetProcessGroup(cmd *exec.Cmd) {}
+
+// KillProcessGroup kills the process of the command, process groups are only set on linux
+func KillProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// LimitCommand is a no-op, the limits are only applied on linux
+func LimitCommand(cmd *exec.Cmd, limits Limits) {
+	if limits != (Limits{}) {
+		logrus.Warnf("not limiting command %v, unsupported on %s", cmd.Args, runtime.GOOS)
+	}
+}
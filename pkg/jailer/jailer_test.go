@@ -2,6 +2,7 @@
 
 import (
 	"os"
+	"path"
 	"testing"
 
 	"github.com/rancher/rancher/pkg/settings"
@@ -44,3 +45,24 @@ type testCase struct {
 	}
 
 }
+
+func TestRemoveJail(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	assert.NoError(t, os.MkdirAll(path.Join(root, "c-abcde", "etc"), 0700))
+	assert.NoError(t, os.WriteFile(path.Join(root, "c-abcde", "done"), nil, 0600))
+
+	assert.NoError(t, removeJail(root, "c-abcde"))
+	_, err := os.Stat(path.Join(root, "c-abcde"))
+	assert.True(t, os.IsNotExist(err), "expected the jail to be removed")
+
+	assert.NoError(t, removeJail(root, "c-abcde"), "expected the removal of a jail which doesn't exist to succeed")
+
+	for _, name := range []string{"", ".", "..", "../" + path.Base(outside), "c-abcde/..", "/etc", "a/b"} {
+		assert.Error(t, removeJail(root, name), "expected jail name [%s] to be refused", name)
+	}
+	_, err = os.Stat(root)
+	assert.NoError(t, err, "expected the jail root to be left as is")
+	_, err = os.Stat(outside)
+	assert.NoError(t, err, "expected the directories outside the jail root to be left as is")
+}
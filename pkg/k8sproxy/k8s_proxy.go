@@ -3,6 +3,7 @@
 import (
 	"net/http"
 
+	"github.com/rancher/rancher/pkg/clustermanager/execauth"
 	"github.com/rancher/rancher/pkg/clusterrouter"
 	"github.com/rancher/rancher/pkg/k8slookup"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -11,5 +12,5 @@
 
 func New(scaledContext *config.ScaledContext, dialer dialer.Factory) http.Handler {
 	return clusterrouter.New(&scaledContext.RESTConfig, k8slookup.New(scaledContext, true), dialer,
-		scaledContext.Management.Clusters("").Controller().Lister())
+		scaledContext.Management.Clusters("").Controller().Lister(), execauth.ResolverFor(scaledContext))
 }
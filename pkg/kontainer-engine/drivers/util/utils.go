@@ -27,13 +27,106 @@
 
 // GenerateServiceAccountToken generate a serviceAccountToken for clusterAdmin given a rest clientset
 func GenerateServiceAccountToken(clientset kubernetes.Interface) (string, error) {
+	serviceAccount, err := ensureServiceAccount(clientset)
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Millisecond * 250
+	for i := 0; i < 5; i++ {
+		time.Sleep(start)
+		if serviceAccount, err = clientset.CoreV1().ServiceAccounts(cattleNamespace).Get(context.TODO(), serviceAccount.Name, metav1.GetOptions{}); err != nil {
+			return "", fmt.Errorf("error getting service account: %w", err)
+		}
+
+		if len(serviceAccount.Secrets) > 0 {
+			secret := serviceAccount.Secrets[0]
+			secretObj, err := clientset.CoreV1().Secrets(cattleNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", fmt.Errorf("error getting secret: %w", err)
+			}
+			if token, ok := secretObj.Data["token"]; ok {
+				return string(token), nil
+			}
+		}
+		start = start * 2
+	}
+
+	return "", errs.New("failed to fetch serviceAccountToken")
+}
+
+// CreateServiceAccountToken creates an additional token for the cluster admin service account, the existing tokens
+// keep working. It returns the new token and the name of the secret holding it.
+func CreateServiceAccountToken(clientset kubernetes.Interface) (string, string, error) {
+	serviceAccount, err := ensureServiceAccount(clientset)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err := clientset.CoreV1().Secrets(cattleNamespace).Create(context.TODO(), &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: serviceAccount.Name + "-token-",
+			Namespace:    cattleNamespace,
+			Annotations: map[string]string{
+				v1.ServiceAccountNameKey: serviceAccount.Name,
+			},
+		},
+		Type: v1.SecretTypeServiceAccountToken,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("error creating service account token secret: %w", err)
+	}
+
+	start := time.Millisecond * 250
+	for i := 0; i < 5; i++ {
+		time.Sleep(start)
+		secretObj, err := clientset.CoreV1().Secrets(cattleNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", secret.Name, fmt.Errorf("error getting secret: %w", err)
+		}
+		if token, ok := secretObj.Data[v1.ServiceAccountTokenKey]; ok {
+			return string(token), secret.Name, nil
+		}
+		start = start * 2
+	}
+
+	return "", secret.Name, errs.New("failed to fetch serviceAccountToken")
+}
+
+// DeleteServiceAccountToken deletes the secret holding the given token of the cluster admin service account and
+// returns whether the token was revoked. Tokens of other service accounts, such as the one mounted in the cluster
+// agent, are left in place and reported as not revoked.
+func DeleteServiceAccountToken(clientset kubernetes.Interface, token string) (bool, error) {
+	secrets, err := clientset.CoreV1().Secrets(cattleNamespace).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "type=" + string(v1.SecretTypeServiceAccountToken),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	revoked := false
+	for _, secret := range secrets.Items {
+		if secret.Annotations[v1.ServiceAccountNameKey] != kontainerEngine || string(secret.Data[v1.ServiceAccountTokenKey]) != token {
+			continue
+		}
+		err := clientset.CoreV1().Secrets(cattleNamespace).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		revoked = true
+	}
+	return revoked, nil
+}
+
+// ensureServiceAccount creates the cluster admin service account and its role binding
+func ensureServiceAccount(clientset kubernetes.Interface) (*v1.ServiceAccount, error) {
 	_, err := clientset.CoreV1().Namespaces().Create(context.TODO(), &v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: cattleNamespace,
 		},
 	}, metav1.CreateOptions{})
 	if err != nil && !errors.IsAlreadyExists(err) {
-		return "", err
+		return nil, err
 	}
 
 	serviceAccount := &v1.ServiceAccount{
@@ -44,7 +137,7 @@ func GenerateServiceAccountToken(clientset kubernetes.Interface) (string, error)
 
 	_, err = clientset.CoreV1().ServiceAccounts(cattleNamespace).Create(context.TODO(), serviceAccount, metav1.CreateOptions{})
 	if err != nil && !errors.IsAlreadyExists(err) {
-		return "", fmt.Errorf("error creating service account: %v", err)
+		return nil, fmt.Errorf("error creating service account: %w", err)
 	}
 
 	adminRole := &v1beta1.ClusterRole{
@@ -67,7 +160,7 @@ func GenerateServiceAccountToken(clientset kubernetes.Interface) (string, error)
 	if err != nil {
 		clusterAdminRole, err = clientset.RbacV1beta1().ClusterRoles().Create(context.TODO(), adminRole, metav1.CreateOptions{})
 		if err != nil {
-			return "", fmt.Errorf("error creating admin role: %v", err)
+			return nil, fmt.Errorf("error creating admin role: %w", err)
 		}
 	}
 
@@ -90,30 +183,10 @@ func GenerateServiceAccountToken(clientset kubernetes.Interface) (string, error)
 		},
 	}
 	if _, err = clientset.RbacV1beta1().ClusterRoleBindings().Create(context.TODO(), clusterRoleBinding, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
-		return "", fmt.Errorf("error creating role bindings: %v", err)
+		return nil, fmt.Errorf("error creating role bindings: %w", err)
 	}
 
-	start := time.Millisecond * 250
-	for i := 0; i < 5; i++ {
-		time.Sleep(start)
-		if serviceAccount, err = clientset.CoreV1().ServiceAccounts(cattleNamespace).Get(context.TODO(), serviceAccount.Name, metav1.GetOptions{}); err != nil {
-			return "", fmt.Errorf("error getting service account: %v", err)
-		}
-
-		if len(serviceAccount.Secrets) > 0 {
-			secret := serviceAccount.Secrets[0]
-			secretObj, err := clientset.CoreV1().Secrets(cattleNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
-			if err != nil {
-				return "", fmt.Errorf("error getting secret: %v", err)
-			}
-			if token, ok := secretObj.Data["token"]; ok {
-				return string(token), nil
-			}
-		}
-		start = start * 2
-	}
-
-	return "", errs.New("failed to fetch serviceAccountToken")
+	return serviceAccount, nil
 }
 
 func DeleteLegacyServiceAccountAndRoleBinding(clientset kubernetes.Interface) error {
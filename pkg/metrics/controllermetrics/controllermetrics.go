@@ -0,0 +1,161 @@
+package controllermetrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rancher/lasso/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// The handlers registered through the wrangler and norman controllers all end up registered on a lasso shared
+// controller, wrapping the shared controller factory instruments every one of them. The depth of the work queues of the
+// controllers is reported by the workqueue metrics provider.
+
+var (
+	handlerExecutions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "controller",
+			Name:      "handler_executions_total",
+			Help:      "Number of times a controller handler was executed",
+		},
+		[]string{"handler"},
+	)
+
+	handlerErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "controller",
+			Name:      "handler_errors_total",
+			Help:      "Number of times a controller handler returned an error",
+		},
+		[]string{"handler"},
+	)
+
+	handlerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "controller",
+			Name:      "handler_duration_seconds",
+			Help:      "Time a controller handler took to handle an object",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 9),
+		},
+		[]string{"handler"},
+	)
+
+	queueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "controller",
+			Name:      "queue_depth",
+			Help:      "Number of keys waiting in the work queue of a controller",
+		},
+		[]string{"queue"},
+	)
+)
+
+func init() {
+	workqueue.SetProvider(queueMetricsProvider{})
+}
+
+// Register exposes the controller metrics through the prometheus metrics endpoint
+func Register() {
+	prometheus.MustRegister(handlerExecutions)
+	prometheus.MustRegister(handlerErrors)
+	prometheus.MustRegister(handlerDuration)
+	prometheus.MustRegister(queueDepth)
+}
+
+// Wrap returns a shared controller factory instrumenting the handlers registered on its controllers
+func Wrap(factory controller.SharedControllerFactory) controller.SharedControllerFactory {
+	return &sharedControllerFactory{SharedControllerFactory: factory}
+}
+
+type sharedControllerFactory struct {
+	controller.SharedControllerFactory
+}
+
+func (f *sharedControllerFactory) ForObject(obj runtime.Object) (controller.SharedController, error) {
+	sharedController, err := f.SharedControllerFactory.ForObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &sharedControllerWrapper{SharedController: sharedController}, nil
+}
+
+func (f *sharedControllerFactory) ForKind(gvk schema.GroupVersionKind) (controller.SharedController, error) {
+	sharedController, err := f.SharedControllerFactory.ForKind(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return &sharedControllerWrapper{SharedController: sharedController}, nil
+}
+
+func (f *sharedControllerFactory) ForResource(gvr schema.GroupVersionResource, namespaced bool) controller.SharedController {
+	return &sharedControllerWrapper{SharedController: f.SharedControllerFactory.ForResource(gvr, namespaced)}
+}
+
+func (f *sharedControllerFactory) ForResourceKind(gvr schema.GroupVersionResource, kind string, namespaced bool) controller.SharedController {
+	return &sharedControllerWrapper{SharedController: f.SharedControllerFactory.ForResourceKind(gvr, kind, namespaced)}
+}
+
+type sharedControllerWrapper struct {
+	controller.SharedController
+}
+
+func (s *sharedControllerWrapper) RegisterHandler(ctx context.Context, name string, handler controller.SharedControllerHandler) {
+	s.SharedController.RegisterHandler(ctx, name, instrument(name, handler))
+}
+
+// instrument records the executions, errors and duration of the handler, its result is returned as is
+func instrument(name string, handler controller.SharedControllerHandler) controller.SharedControllerHandler {
+	return controller.SharedControllerHandlerFunc(func(key string, obj runtime.Object) (runtime.Object, error) {
+		start := time.Now()
+		newObj, err := handler.OnChange(key, obj)
+		handlerDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		handlerExecutions.WithLabelValues(name).Inc()
+		if err != nil && !errors.Is(err, controller.ErrIgnore) {
+			handlerErrors.WithLabelValues(name).Inc()
+		}
+		return newObj, err
+	})
+}
+
+// queueMetricsProvider reports the depth of the named work queues, the other work queue metrics are discarded
+type queueMetricsProvider struct{}
+
+func (queueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return queueDepth.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Dec()            {}
+func (noopMetric) Set(float64)     {}
+func (noopMetric) Observe(float64) {}
@@ -0,0 +1,77 @@
+package controllermetrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/lasso/pkg/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// fakeSharedController keeps the handlers registered on it
+type fakeSharedController struct {
+	controller.SharedController
+	handlers map[string]controller.SharedControllerHandler
+}
+
+func (f *fakeSharedController) RegisterHandler(ctx context.Context, name string, handler controller.SharedControllerHandler) {
+	f.handlers[name] = handler
+}
+
+func TestInstrumentedHandler(t *testing.T) {
+	fake := &fakeSharedController{handlers: map[string]controller.SharedControllerHandler{}}
+	sharedController := &sharedControllerWrapper{SharedController: fake}
+
+	errFailed := errors.New("failed")
+	result := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "updated"}}
+	var calls []string
+	sharedController.RegisterHandler(context.Background(), "synthetic-handler", controller.SharedControllerHandlerFunc(
+		func(key string, obj runtime.Object) (runtime.Object, error) {
+			calls = append(calls, key)
+			switch key {
+			case "default/failed":
+				return nil, errFailed
+			case "default/ignored":
+				return nil, controller.ErrIgnore
+			}
+			return result, nil
+		}))
+	handler := fake.handlers["synthetic-handler"]
+	require.NotNil(t, handler, "expected the handler to be registered under its name")
+
+	obj, err := handler.OnChange("default/updated", &corev1.ConfigMap{})
+	assert.NoError(t, err)
+	assert.Equal(t, result, obj, "expected the object returned by the handler to be passed on")
+
+	_, err = handler.OnChange("default/failed", nil)
+	assert.Equal(t, errFailed, err, "expected the error of the handler to be passed on")
+
+	_, err = handler.OnChange("default/ignored", nil)
+	assert.True(t, errors.Is(err, controller.ErrIgnore))
+
+	assert.Equal(t, []string{"default/updated", "default/failed", "default/ignored"}, calls)
+	assert.Equal(t, float64(3), testutil.ToFloat64(handlerExecutions.WithLabelValues("synthetic-handler")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(handlerErrors.WithLabelValues("synthetic-handler")),
+		"expected ignored errors not to be counted")
+	assert.Equal(t, 1, testutil.CollectAndCount(handlerDuration), "expected the latency of the handler to be observed")
+}
+
+func TestQueueDepth(t *testing.T) {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "synthetic-queue")
+	defer queue.ShutDown()
+
+	queue.Add("default/one")
+	queue.Add("default/two")
+	assert.Equal(t, float64(2), testutil.ToFloat64(queueDepth.WithLabelValues("synthetic-queue")))
+
+	item, _ := queue.Get()
+	queue.Done(item)
+	assert.Equal(t, float64(1), testutil.ToFloat64(queueDepth.WithLabelValues("synthetic-queue")))
+}
@@ -5,6 +5,8 @@
 	"encoding/json"
 	"errors"
 	"net/http"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -12,6 +14,7 @@
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/rancher/pkg/auth/util"
 	"github.com/rancher/rancher/pkg/clustermanager"
+	"github.com/rancher/rancher/pkg/metrics/controllermetrics"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/wrangler/pkg/ticker"
@@ -35,6 +38,38 @@
 		},
 		[]string{"cluster", "owner"},
 	)
+
+	clusterConfigUpdates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "cluster_manager",
+			Name:      "cluster_config_updates_total",
+			Help:      "Number of times the operator config of a hosted cluster was updated from the cluster spec",
+		},
+		[]string{"cluster", "kind"},
+	)
+
+	goroutinesHighWaterMark = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "rancher",
+			Name:      "goroutines_high_water_mark",
+			Help:      "The highest number of goroutines observed since the Rancher server started",
+		},
+	)
+
+	heapHighWaterMark = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "rancher",
+			Name:      "heap_inuse_bytes_high_water_mark",
+			Help:      "The highest number of in-use heap bytes observed since the Rancher server started",
+		},
+	)
+
+	highWaterMarkInterval = time.Duration(10 * time.Second)
+	highWaterMarks        = struct {
+		sync.Mutex
+		goroutines int
+		heap       uint64
+	}{}
 )
 
 type metricsHandler struct {
@@ -97,9 +132,32 @@ func (h *metricsHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	recordHighWaterMarks()
 	h.next.ServeHTTP(rw, req)
 }
 
+// recordHighWaterMarks updates the goroutine and heap high water mark gauges from the current runtime stats
+func recordHighWaterMarks() {
+	if !prometheusMetrics {
+		return
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	goroutines := runtime.NumGoroutine()
+
+	highWaterMarks.Lock()
+	defer highWaterMarks.Unlock()
+	if goroutines > highWaterMarks.goroutines {
+		highWaterMarks.goroutines = goroutines
+		goroutinesHighWaterMark.Set(float64(goroutines))
+	}
+	if memStats.HeapInuse > highWaterMarks.heap {
+		highWaterMarks.heap = memStats.HeapInuse
+		heapHighWaterMark.Set(float64(memStats.HeapInuse))
+	}
+}
+
 // getClusterObjectCount uses the caches to get the number of items in a cluster and return a json blob with this
 // information. The count is based off the cluster itself and where the object lives, not the count as you would
 // see through the UI. For example projects only live in the management cluster so the count would only be displayed
@@ -222,6 +280,16 @@ func Register(ctx context.Context, scaledContext *config.ScaledContext) {
 	// Cluster Owner
 	prometheus.MustRegister(clusterOwner)
 
+	// Hosted cluster operator config updates
+	prometheus.MustRegister(clusterConfigUpdates)
+
+	// Runtime high water marks
+	prometheus.MustRegister(goroutinesHighWaterMark)
+	prometheus.MustRegister(heapHighWaterMark)
+
+	// Controller handlers and work queues
+	controllermetrics.Register()
+
 	gc := metricGarbageCollector{
 		clusterLister:  scaledContext.Management.Clusters("").Controller().Lister(),
 		nodeLister:     scaledContext.Management.Nodes("").Controller().Lister(),
@@ -233,6 +301,12 @@ func Register(ctx context.Context, scaledContext *config.ScaledContext) {
 			gc.metricGarbageCollection()
 		}
 	}(ctx)
+
+	go func(ctx context.Context) {
+		for range ticker.Context(ctx, highWaterMarkInterval) {
+			recordHighWaterMarks()
+		}
+	}(ctx)
 }
 
 func SetClusterOwner(id, clusterID string) {
@@ -254,3 +328,13 @@ func UnsetClusterOwner(id, clusterID string) {
 			}).Set(float64(0))
 	}
 }
+
+func IncClusterConfigUpdates(clusterID, kind string) {
+	if prometheusMetrics {
+		clusterConfigUpdates.With(
+			prometheus.Labels{
+				"cluster": clusterID,
+				"kind":    kind,
+			}).Inc()
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/auth/util"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	typedauthzv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+type pprofHandler struct {
+	sarClient typedauthzv1.SubjectAccessReviewInterface
+	next      http.Handler
+}
+
+// NewPprofHandler serves the /debug/pprof endpoints to the admins, whether they are admins through their own global
+// role bindings or the ones of their groups. The endpoints only exist while the debug-pprof-enabled setting is true.
+func NewPprofHandler(scaledContext *config.ScaledContext) http.Handler {
+	return newPprofHandler(scaledContext.K8sClient.AuthorizationV1().SubjectAccessReviews())
+}
+
+func newPprofHandler(sarClient typedauthzv1.SubjectAccessReviewInterface) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &pprofHandler{
+		sarClient: sarClient,
+		next:      mux,
+	}
+}
+
+func (h *pprofHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if settings.DebugPprofEnabled.Get() != "true" {
+		http.NotFound(rw, req)
+		return
+	}
+
+	isAdmin, err := sar.IsAdmin(req, h.sarClient)
+	if err != nil {
+		util.ReturnHTTPError(rw, req, 500, err.Error())
+		return
+	}
+	if !isAdmin {
+		util.ReturnHTTPError(rw, req, 403, "Forbidden")
+		return
+	}
+
+	h.next.ServeHTTP(rw, req)
+}
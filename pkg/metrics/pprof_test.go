@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPprofHandler() http.Handler {
+	return newPprofHandler(&fake.SubjectAccessReviews{Admins: map[string]bool{
+		"u-admin":               true,
+		"okta_group://platform": true,
+	}})
+}
+
+func servePprof(h http.Handler, user string, groups ...string) int {
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	if user != "" {
+		req.Header.Set("Impersonate-User", user)
+	}
+	for _, group := range groups {
+		req.Header.Add("Impersonate-Group", group)
+	}
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	return rw.Code
+}
+
+func TestPprofHandlerAuthorization(t *testing.T) {
+	original := settings.DebugPprofEnabled.Get()
+	defer settings.DebugPprofEnabled.Set(original)
+	require.NoError(t, settings.DebugPprofEnabled.Set("true"))
+
+	h := newTestPprofHandler()
+
+	tests := []struct {
+		user   string
+		groups []string
+		code   int
+	}{
+		{user: "u-admin", code: http.StatusOK},
+		{user: "u-member", groups: []string{"okta_group://platform"}, code: http.StatusOK},
+		{user: "u-member", groups: []string{"okta_group://devs"}, code: http.StatusForbidden},
+		{user: "u-user", code: http.StatusForbidden},
+		{user: "", groups: []string{"okta_group://platform"}, code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, servePprof(h, tt.user, tt.groups...), "unexpected status for user %q of groups %v", tt.user, tt.groups)
+	}
+}
+
+func TestPprofHandlerToggle(t *testing.T) {
+	original := settings.DebugPprofEnabled.Get()
+	defer settings.DebugPprofEnabled.Set(original)
+
+	h := newTestPprofHandler()
+
+	require.NoError(t, settings.DebugPprofEnabled.Set("false"))
+	assert.Equal(t, http.StatusNotFound, servePprof(h, "u-admin"))
+
+	require.NoError(t, settings.DebugPprofEnabled.Set("true"))
+	assert.Equal(t, http.StatusOK, servePprof(h, "u-admin"))
+
+	require.NoError(t, settings.DebugPprofEnabled.Set("false"))
+	assert.Equal(t, http.StatusNotFound, servePprof(h, "u-admin"))
+}
@@ -89,7 +89,10 @@ func buildScaledContext(ctx context.Context, wranglerContext *wrangler.Context,
 	systemTokens := systemtokens.NewSystemTokensFromScale(scaledContext)
 	scaledContext.SystemTokens = systemTokens
 
-	manager := clustermanager.NewManager(cfg.HTTPSListenPort, scaledContext, wranglerContext.RBAC, wranglerContext.ASL)
+	manager, err := clustermanager.NewManager(cfg.HTTPSListenPort, scaledContext, wranglerContext.RBAC, wranglerContext.ASL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	scaledContext.AccessControl = manager
 	scaledContext.ClientGetter = manager
@@ -184,7 +187,7 @@ func (m *mcm) Start(ctx context.Context) error {
 		}
 	}
 
-	m.wranglerContext.OnLeader(func(ctx context.Context) error {
+	m.wranglerContext.OnLeader(m.wranglerContext.Readiness.Track(func(ctx context.Context) error {
 		err := m.wranglerContext.StartWithTransaction(ctx, func(ctx context.Context) error {
 			var (
 				err error
@@ -220,7 +223,7 @@ func (m *mcm) Start(ctx context.Context) error {
 		go managementdata.CleanupDuplicateBindings(m.ScaledContext, m.wranglerContext)
 		logrus.Infof("Rancher startup complete")
 		return nil
-	})
+	}))
 
 	return nil
 }
@@ -7,6 +7,7 @@
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/rancher/pkg/agent/clean"
 	"github.com/rancher/rancher/pkg/api/norman"
 	"github.com/rancher/rancher/pkg/api/norman/customization/aks"
 	"github.com/rancher/rancher/pkg/api/norman/customization/clusterregistrationtokens"
@@ -22,6 +23,7 @@
 	"github.com/rancher/rancher/pkg/auth/webhook"
 	"github.com/rancher/rancher/pkg/channelserver"
 	"github.com/rancher/rancher/pkg/clustermanager"
+	"github.com/rancher/rancher/pkg/clusterrouter"
 	rancherdialer "github.com/rancher/rancher/pkg/dialer"
 	"github.com/rancher/rancher/pkg/httpproxy"
 	k8sProxyPkg "github.com/rancher/rancher/pkg/k8sproxy"
@@ -29,6 +31,7 @@
 	"github.com/rancher/rancher/pkg/multiclustermanager/whitelist"
 	"github.com/rancher/rancher/pkg/pipeline/hooks"
 	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/rancher/rancher/pkg/rbac/permissions"
 	"github.com/rancher/rancher/pkg/rkenodeconfigserver"
 	"github.com/rancher/rancher/pkg/telemetry"
 	"github.com/rancher/rancher/pkg/tunnelserver/mcmauthorizer"
@@ -39,9 +42,14 @@
 func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcmauthorizer.Authorizer, scaledContext *config.ScaledContext, clusterManager *clustermanager.Manager) (func(http.Handler) http.Handler, error) {
 	var (
 		k8sProxy             = k8sProxyPkg.New(scaledContext, scaledContext.Dialer)
-		connectHandler       = scaledContext.Dialer.(*rancherdialer.Factory).TunnelServer
+		dialerFactory        = scaledContext.Dialer.(*rancherdialer.Factory)
+		connectHandler       = dialerFactory.Sessions.Handler(dialerFactory.TunnelServer)
 		connectConfigHandler = rkenodeconfigserver.Handler(tunnelAuthorizer, scaledContext)
-		clusterImport        = clusterregistrationtokens.ClusterImport{Clusters: scaledContext.Management.Clusters("")}
+		clusterImport        = clusterregistrationtokens.ClusterImport{
+			Clusters:                       scaledContext.Management.Clusters(""),
+			ClusterRegistrationTokens:      scaledContext.Management.ClusterRegistrationTokens(""),
+			ClusterRegistrationTokenLister: scaledContext.Management.ClusterRegistrationTokens("").Controller().Lister(),
+		}
 	)
 
 	tokenAPI, err := tokens.NewAPIHandler(ctx, scaledContext, norman.ConfigureAPIUI)
@@ -64,7 +72,14 @@ func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcm
 		return nil, err
 	}
 
+	tokenReviewer := &webhook.TokenReviewer{
+		Authenticator: requests.NewAuthenticator(ctx, clusterrouter.GetClusterID, scaledContext),
+		ServiceAccounts: webhook.NewServiceAccountTokenAuthenticator(ctx,
+			scaledContext.Management.Clusters("").Controller().Lister(), clusterManager.RESTConfig),
+	}
+
 	metricsHandler := metrics.NewMetricsHandler(scaledContext, clusterManager, promhttp.Handler())
+	pprofHandler := metrics.NewPprofHandler(scaledContext)
 
 	// Unauthenticated routes
 	unauthed := mux.NewRouter()
@@ -97,9 +112,15 @@ func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcm
 	authed.Path("/meta/{resource:gke.+}").Handler(gke.NewGKEHandler(scaledContext))
 	authed.Path("/meta/oci/{resource}").Handler(oci.NewOCIHandler(scaledContext))
 	authed.Path("/meta/vsphere/{field}").Handler(vsphere.NewVsphereHandler(scaledContext))
-	authed.Path("/v3/tokenreview").Methods(http.MethodPost).Handler(&webhook.TokenReviewer{})
+	authed.Path("/v3/tokenreview").Methods(http.MethodPost).Handler(tokenReviewer)
+	authed.Path("/v3/tokenreview/{clusterID}").Methods(http.MethodPost).Handler(tokenReviewer)
+	authed.Path("/v3/effectivepermissions/{clusterID}").Methods(http.MethodGet).Handler(permissions.NewHandler(scaledContext))
+	authed.Path("/v3/tunnelsessions/{clusterID}").Methods(http.MethodGet).Handler(rancherdialer.NewTunnelSessionsHandler(scaledContext, dialerFactory))
+	authed.Path("/v3/bindingscleanup/{clusterID}").Methods(http.MethodPost).Handler(clean.NewBindingsHandler(scaledContext))
+	authed.Path("/v3/clusterversions").Methods(http.MethodGet).Handler(clustermanager.NewServerVersionsHandler(scaledContext, clusterManager))
 	authed.Path("/metrics").Handler(metricsHandler)
 	authed.Path("/metrics/{clusterID}").Handler(metricsHandler)
+	authed.PathPrefix("/debug/pprof").Handler(pprofHandler)
 	authed.PathPrefix("/k8s/clusters/").Handler(k8sProxy)
 	authed.PathPrefix("/meta/proxy").Handler(metaProxy)
 	authed.PathPrefix("/v1-telemetry").Handler(telemetry.NewProxy())
@@ -2,6 +2,7 @@
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/rancher/norman/types/convert"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
@@ -18,8 +19,21 @@
 	externalAddressAnnotation = "rke.cattle.io/external-ip"
 	LabelNodeName             = "management.cattle.io/nodename"
 	nodeStatusLabel           = "cattle.rancher.io/node-status"
+
+	// SkipCredentialAccessCheckAnnotation on a node pool or node template lets an admin provision its nodes although
+	// its creator can't access its cloud credential anymore, its value is the ID of the admin who set it and it is only
+	// honored while that user is an admin
+	SkipCredentialAccessCheckAnnotation = "node.cattle.io/skip-credential-access-check"
 )
 
+var engineInstallURLChecksum = regexp.MustCompile("^(sha256:)?[0-9a-fA-F]{64}$")
+
+// ValidEngineInstallURLChecksum returns true if the engine install URL checksum of a node template is a sha256
+// checksum, optionally prefixed by sha256:
+func ValidEngineInstallURLChecksum(checksum string) bool {
+	return engineInstallURLChecksum.MatchString(checksum)
+}
+
 func GetNodeName(machine *v3.Node) string {
 	if machine.Status.NodeName != "" {
 		return machine.Status.NodeName
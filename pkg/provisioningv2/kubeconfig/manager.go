@@ -200,6 +200,48 @@ func createSHA256Hash(secretKey string) (string, error) {
 	return fmt.Sprintf(hashFormat, Version, encSalt, encKey), nil
 }
 
+// DeleteUser deletes the user and the token created for the kubeconfig of the provisioning cluster, along with the
+// kubeconfig secret holding the token, so that a cluster created again with the same name gets a new token. The
+// objects already deleted, or not created for the cluster, are left as is.
+func (m *Manager) DeleteUser(cluster *v1.Cluster) error {
+	principalID := getPrincipalID(cluster.Namespace, cluster.Name)
+	userName := getUserNameForPrincipal(principalID)
+
+	if cluster.Spec.ClusterAPIConfig == nil {
+		// the kubeconfig secret of a cluster-api cluster isn't generated by Rancher
+		err := m.secrets.Delete(cluster.Namespace, getKubeConfigSecretName(cluster.Name), &metav1.DeleteOptions{})
+		if err != nil && !apierror.IsNotFound(err) {
+			return err
+		}
+	}
+
+	token, err := m.tokens.Get(userName, metav1.GetOptions{})
+	if err != nil && !apierror.IsNotFound(err) {
+		return err
+	} else if err == nil && token.UserID == userName && token.Labels[tokenKindLabel] == "provisioning" {
+		if err := m.tokens.Delete(userName, &metav1.DeleteOptions{}); err != nil && !apierror.IsNotFound(err) {
+			return err
+		}
+	}
+
+	user, err := m.users.Get(userName, metav1.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, userPrincipalID := range user.PrincipalIDs {
+		if userPrincipalID != principalID {
+			continue
+		}
+		if err := m.users.Delete(userName, &metav1.DeleteOptions{}); err != nil && !apierror.IsNotFound(err) {
+			return err
+		}
+		break
+	}
+	return nil
+}
+
 func (m *Manager) GetCRTBForAdmin(cluster *v1.Cluster, status v1.ClusterStatus) (*v3.ClusterRoleTemplateBinding, error) {
 	if status.ClusterName == "" {
 		return nil, fmt.Errorf("management cluster is not assigned to v1.Cluster")
@@ -0,0 +1,113 @@
+package kubeconfig
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeTokens struct {
+	mgmtcontrollers.TokenClient
+	tokens map[string]*v3.Token
+}
+
+func (f fakeTokens) Get(name string, opts metav1.GetOptions) (*v3.Token, error) {
+	if token, ok := f.tokens[name]; ok {
+		return token, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Resource: "tokens"}, name)
+}
+
+func (f fakeTokens) Delete(name string, opts *metav1.DeleteOptions) error {
+	if _, ok := f.tokens[name]; !ok {
+		return apierror.NewNotFound(schema.GroupResource{Resource: "tokens"}, name)
+	}
+	delete(f.tokens, name)
+	return nil
+}
+
+type fakeUsers struct {
+	mgmtcontrollers.UserClient
+	users map[string]*v3.User
+}
+
+func (f fakeUsers) Get(name string, opts metav1.GetOptions) (*v3.User, error) {
+	if user, ok := f.users[name]; ok {
+		return user, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Resource: "users"}, name)
+}
+
+func (f fakeUsers) Delete(name string, opts *metav1.DeleteOptions) error {
+	if _, ok := f.users[name]; !ok {
+		return apierror.NewNotFound(schema.GroupResource{Resource: "users"}, name)
+	}
+	delete(f.users, name)
+	return nil
+}
+
+type fakeSecrets struct {
+	corecontrollers.SecretClient
+	secrets map[string]*corev1.Secret
+}
+
+func (f fakeSecrets) Delete(namespace, name string, opts *metav1.DeleteOptions) error {
+	if _, ok := f.secrets[namespace+"/"+name]; !ok {
+		return apierror.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	delete(f.secrets, namespace+"/"+name)
+	return nil
+}
+
+func TestDeleteUser(t *testing.T) {
+	cluster := &v1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"}}
+	principalID := getPrincipalID(cluster.Namespace, cluster.Name)
+	userName := getUserNameForPrincipal(principalID)
+
+	tokens := fakeTokens{tokens: map[string]*v3.Token{
+		userName: {
+			ObjectMeta: metav1.ObjectMeta{Name: userName, Labels: map[string]string{tokenKindLabel: "provisioning"}},
+			UserID:     userName,
+		},
+	}}
+	users := fakeUsers{users: map[string]*v3.User{
+		userName: {ObjectMeta: metav1.ObjectMeta{Name: userName}, PrincipalIDs: []string{principalID}},
+	}}
+	secrets := fakeSecrets{secrets: map[string]*corev1.Secret{
+		"fleet-default/test-kubeconfig": {},
+	}}
+	m := &Manager{tokens: tokens, users: users, secrets: secrets}
+
+	require.NoError(t, m.DeleteUser(cluster))
+	assert.Empty(t, tokens.tokens)
+	assert.Empty(t, users.users)
+	assert.Empty(t, secrets.secrets)
+
+	assert.NoError(t, m.DeleteUser(cluster), "expected the deletion to succeed once everything is deleted")
+}
+
+func TestDeleteUserNotOwned(t *testing.T) {
+	cluster := &v1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"}}
+	userName := getUserNameForPrincipal(getPrincipalID(cluster.Namespace, cluster.Name))
+
+	tokens := fakeTokens{tokens: map[string]*v3.Token{
+		userName: {ObjectMeta: metav1.ObjectMeta{Name: userName}, UserID: "u-other"},
+	}}
+	users := fakeUsers{users: map[string]*v3.User{
+		userName: {ObjectMeta: metav1.ObjectMeta{Name: userName}, PrincipalIDs: []string{"local://u-other"}},
+	}}
+	m := &Manager{tokens: tokens, users: users, secrets: fakeSecrets{}}
+
+	require.NoError(t, m.DeleteUser(cluster))
+	assert.Len(t, tokens.tokens, 1, "expected a token not created for the cluster to be left as is")
+	assert.Len(t, users.users, 1, "expected a user not created for the cluster to be left as is")
+}
@@ -7,6 +7,7 @@
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
@@ -43,7 +44,7 @@ func (r *RKE2ConfigServer) findMachineByClusterToken(req *http.Request) (string,
 	}
 	data["id"] = machineID
 
-	if len(tokens) == 0 {
+	if len(tokens) == 0 || !tokens[0].AcceptsToken(token, time.Now()) {
 		return "", "", nil
 	}
 
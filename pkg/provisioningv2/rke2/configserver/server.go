@@ -60,7 +60,7 @@ func New(clients *wrangler.Context) *RKE2ConfigServer {
 
 	clients.Mgmt.ClusterRegistrationToken().Cache().AddIndexer(tokenIndex,
 		func(obj *v3.ClusterRegistrationToken) ([]string, error) {
-			return []string{obj.Status.Token}, nil
+			return obj.Tokens(), nil
 		})
 
 	return &RKE2ConfigServer{
@@ -1,18 +1,30 @@
 package installer
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/systemtemplate"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// maxInstallScriptSize is the maximum size of the install script downloaded from the system-agent-install-script setting
+const maxInstallScriptSize = 5 << 20
+
 var (
+	downloadClient = &http.Client{Timeout: 30 * time.Second}
+
 	defaultSystemAgentInstallScript = "https://raw.githubusercontent.com/rancher/system-agent/main/install.sh"
 	localAgentInstallScripts        = []string{
 		"/usr/share/rancher/ui/assets/system-agent-install.sh",
@@ -20,8 +32,104 @@
 	}
 )
 
+// Source overrides the install script set by the system-agent-install-script setting for the machines of a cluster
+type Source struct {
+	// URL the install script is downloaded from
+	URL string
+	// Content is the install script, it takes precedence over the URL
+	Content []byte
+	// SHA256 is the expected checksum of the install script, it isn't verified when empty
+	SHA256 string
+}
+
+// ResolveSource returns the source of the install script of a cluster, reading the ConfigMap it references from the
+// namespace of the cluster. It returns nil when the cluster uses the install script of the settings.
+func ResolveSource(configMaps corecontrollers.ConfigMapCache, namespace string, script *rkev1.AgentInstallScript) (*Source, error) {
+	if script == nil {
+		return nil, nil
+	}
+
+	source := &Source{
+		URL:    script.URL,
+		SHA256: script.SHA256,
+	}
+	ref := script.ConfigMapRef
+	if ref == nil {
+		return source, nil
+	}
+	optional := ref.Optional != nil && *ref.Optional
+
+	configMap, err := configMaps.Get(namespace, ref.Name)
+	if apierror.IsNotFound(err) && optional {
+		return source, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get configmap [%s/%s] of the install script: %w", namespace, ref.Name, err)
+	}
+
+	if data, ok := configMap.Data[ref.Key]; ok {
+		source.Content = []byte(data)
+	} else if data, ok := configMap.BinaryData[ref.Key]; ok {
+		source.Content = data
+	} else if !optional {
+		return nil, fmt.Errorf("key [%s] of configmap [%s/%s] of the install script not found", ref.Key, namespace, ref.Name)
+	}
+	return source, nil
+}
+
+// AgentEnvVarSecretLabel marks the secrets agent env vars may take their value from. The other secrets of the namespace
+// of a cluster can't be read through its agent env vars, so that the clusters of a namespace can't read each other's
+// secrets.
+const AgentEnvVarSecretLabel = "rke.cattle.io/agent-env-var"
+
+// ResolveEnvVars returns the agent env vars with the values of the secrets they reference through
+// valueFrom.secretKeyRef, so that the secrets are only written in the install scripts served to the nodes. The secrets
+// are looked up in the namespace of the cluster and have to be labeled with AgentEnvVarSecretLabel.
+func ResolveEnvVars(secrets corecontrollers.SecretCache, namespace string, envVars []corev1.EnvVar) ([]corev1.EnvVar, error) {
+	var result []corev1.EnvVar
+	for _, envVar := range envVars {
+		if envVar.ValueFrom == nil {
+			result = append(result, envVar)
+			continue
+		}
+
+		ref := envVar.ValueFrom.SecretKeyRef
+		if ref == nil {
+			return nil, fmt.Errorf("agent env var [%s] may only reference the value of a secret", envVar.Name)
+		}
+		optional := ref.Optional != nil && *ref.Optional
+
+		secret, err := secrets.Get(namespace, ref.Name)
+		if apierror.IsNotFound(err) && optional {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get secret [%s/%s] of agent env var [%s]: %w", namespace, ref.Name, envVar.Name, err)
+		}
+		if secret.Labels[AgentEnvVarSecretLabel] != "true" {
+			return nil, fmt.Errorf("secret [%s/%s] of agent env var [%s] is not labeled %s=true", namespace, ref.Name, envVar.Name, AgentEnvVarSecretLabel)
+		}
+
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			if optional {
+				continue
+			}
+			return nil, fmt.Errorf("key [%s] of secret [%s/%s] of agent env var [%s] not found", ref.Key, namespace, ref.Name, envVar.Name)
+		}
+		result = append(result, corev1.EnvVar{
+			Name:  envVar.Name,
+			Value: string(value),
+		})
+	}
+	return result, nil
+}
+
 func InstallScript(token string, envVars []corev1.EnvVar) ([]byte, error) {
-	data, err := installScript()
+	return InstallScriptFromSource(nil, token, envVars)
+}
+
+// InstallScriptFromSource is InstallScript with the install script of the source, unless it is nil
+func InstallScriptFromSource(source *Source, token string, envVars []corev1.EnvVar) ([]byte, error) {
+	data, err := installScriptFromSource(source)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +166,55 @@ func InstallScript(token string, envVars []corev1.EnvVar) ([]byte, error) {
 `, envVarBuf.String(), binaryURL, server, ca, token, data)), nil
 }
 
+func installScriptFromSource(source *Source) ([]byte, error) {
+	if source == nil {
+		return installScript()
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case source.Content != nil:
+		data = source.Content
+	case source.URL != "":
+		// Rancher never fetches the URLs set by clusters, the nodes download and verify the install script themselves
+		return nodeDownloadScript(source.URL, source.SHA256), nil
+	default:
+		data, err = installScript()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if source.SHA256 != "" {
+		hash := sha256.Sum256(data)
+		if checksum := hex.EncodeToString(hash[:]); !strings.EqualFold(checksum, source.SHA256) {
+			return nil, fmt.Errorf("checksum %s of the install script does not match the expected checksum %s", checksum, source.SHA256)
+		}
+	}
+	return data, nil
+}
+
+// nodeDownloadScript returns the script run by the nodes in place of an install script taken from a URL, it downloads
+// the install script, verifies its checksum unless it is empty, then runs it in the same shell so that it sees the
+// variables set before it
+func nodeDownloadScript(url, checksum string) []byte {
+	buf := &strings.Builder{}
+	buf.WriteString("CATTLE_INSTALL_SCRIPT=\"$(mktemp)\"\n")
+	buf.WriteString(fmt.Sprintf("curl -fsSL %s -o \"${CATTLE_INSTALL_SCRIPT}\" || exit 1\n", shellQuote(url)))
+	if checksum != "" {
+		buf.WriteString(fmt.Sprintf("echo %s | sha256sum -c - || exit 1\n", shellQuote(checksum+"  ")+"\"${CATTLE_INSTALL_SCRIPT}\""))
+	}
+	buf.WriteString(". \"${CATTLE_INSTALL_SCRIPT}\"\n")
+	return []byte(buf.String())
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func installScript() ([]byte, error) {
 	url := settings.SystemAgentInstallScript.Get()
 	if url == "" {
@@ -73,11 +230,25 @@ func installScript() ([]byte, error) {
 		url = defaultSystemAgentInstallScript
 	}
 
-	resp, httpErr := http.Get(url)
+	return download(url)
+}
+
+func download(url string) ([]byte, error) {
+	resp, httpErr := downloadClient.Get(url)
 	if httpErr != nil {
 		return nil, httpErr
 	}
 	defer resp.Body.Close()
 
-	return ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download the install script from %s: %s", url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxInstallScriptSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxInstallScriptSize {
+		return nil, fmt.Errorf("install script downloaded from %s exceeds %d bytes", url, maxInstallScriptSize)
+	}
+	return data, nil
 }
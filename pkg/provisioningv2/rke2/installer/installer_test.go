@@ -0,0 +1,164 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeConfigMaps struct {
+	corecontrollers.ConfigMapCache
+	configMaps map[string]*corev1.ConfigMap
+}
+
+func (f fakeConfigMaps) Get(namespace, name string) (*corev1.ConfigMap, error) {
+	if configMap, ok := f.configMaps[namespace+"/"+name]; ok {
+		return configMap, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+}
+
+func checksum(data string) string {
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+func configMapRef(name, key string, optional bool) *corev1.ConfigMapKeySelector {
+	return &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: name},
+		Key:                  key,
+		Optional:             &optional,
+	}
+}
+
+func TestInstallScriptFromSource(t *testing.T) {
+	fetched := false
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fetched = true
+		rw.Write([]byte("echo mirror"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		source      *Source
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:     "inline content",
+			source:   &Source{Content: []byte("echo inline"), URL: server.URL},
+			expected: []string{"echo inline"},
+		},
+		{
+			name:   "url",
+			source: &Source{URL: server.URL},
+			expected: []string{
+				"curl -fsSL '" + server.URL + "' -o \"${CATTLE_INSTALL_SCRIPT}\" || exit 1\n",
+				". \"${CATTLE_INSTALL_SCRIPT}\"\n",
+			},
+		},
+		{
+			name:   "pinned checksum",
+			source: &Source{URL: server.URL, SHA256: checksum("echo mirror")},
+			expected: []string{
+				"curl -fsSL '" + server.URL + "' -o \"${CATTLE_INSTALL_SCRIPT}\" || exit 1\n",
+				"echo '" + checksum("echo mirror") + "  '\"${CATTLE_INSTALL_SCRIPT}\" | sha256sum -c - || exit 1\n",
+			},
+		},
+		{
+			name:        "checksum mismatch",
+			source:      &Source{Content: []byte("echo tampered"), SHA256: checksum("echo inline")},
+			expectedErr: "checksum " + checksum("echo tampered") + " of the install script does not match the expected checksum " + checksum("echo inline"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := InstallScriptFromSource(tt.source, "token", nil)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, string(data), "CATTLE_TOKEN=\"token\"")
+			for _, expected := range tt.expected {
+				assert.Contains(t, string(data), expected)
+			}
+		})
+	}
+	assert.False(t, fetched, "expected the install scripts of the clusters to be downloaded by the nodes")
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/large":
+			rw.Write(make([]byte, maxInstallScriptSize+1))
+		case "/missing":
+			rw.WriteHeader(http.StatusNotFound)
+		default:
+			rw.Write([]byte("echo install"))
+		}
+	}))
+	defer server.Close()
+
+	data, err := download(server.URL + "/install.sh")
+	require.NoError(t, err)
+	assert.Equal(t, "echo install", string(data))
+
+	_, err = download(server.URL + "/large")
+	assert.EqualError(t, err, fmt.Sprintf("install script downloaded from %s/large exceeds %d bytes", server.URL, maxInstallScriptSize))
+
+	_, err = download(server.URL + "/missing")
+	assert.EqualError(t, err, fmt.Sprintf("failed to download the install script from %s/missing: 404 Not Found", server.URL))
+}
+
+func TestResolveSource(t *testing.T) {
+	configMaps := fakeConfigMaps{configMaps: map[string]*corev1.ConfigMap{
+		"fleet-default/install": {
+			Data:       map[string]string{"install.sh": "echo inline"},
+			BinaryData: map[string][]byte{"binary.sh": []byte("echo binary")},
+		},
+	}}
+
+	source, err := ResolveSource(configMaps, "fleet-default", nil)
+	require.NoError(t, err)
+	assert.Nil(t, source, "expected the clusters without override to use the settings")
+
+	source, err = ResolveSource(configMaps, "fleet-default", &rkev1.AgentInstallScript{URL: "https://mirror.example.com/install.sh", SHA256: "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, &Source{URL: "https://mirror.example.com/install.sh", SHA256: "abc"}, source)
+
+	source, err = ResolveSource(configMaps, "fleet-default", &rkev1.AgentInstallScript{ConfigMapRef: configMapRef("install", "install.sh", false)})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("echo inline"), source.Content)
+
+	source, err = ResolveSource(configMaps, "fleet-default", &rkev1.AgentInstallScript{ConfigMapRef: configMapRef("install", "binary.sh", false)})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("echo binary"), source.Content)
+
+	source, err = ResolveSource(configMaps, "fleet-default", &rkev1.AgentInstallScript{
+		URL:          "https://mirror.example.com/install.sh",
+		ConfigMapRef: configMapRef("missing", "install.sh", true),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Source{URL: "https://mirror.example.com/install.sh"}, source, "expected a missing optional configmap to fall back to the URL")
+
+	_, err = ResolveSource(configMaps, "fleet-default", &rkev1.AgentInstallScript{ConfigMapRef: configMapRef("install", "other.sh", false)})
+	assert.EqualError(t, err, "key [other.sh] of configmap [fleet-default/install] of the install script not found")
+
+	_, err = ResolveSource(configMaps, "fleet-default", &rkev1.AgentInstallScript{ConfigMapRef: configMapRef("missing", "install.sh", false)})
+	assert.True(t, apierror.IsNotFound(err), "expected a missing configmap to fail the resolution")
+}
@@ -31,5 +31,5 @@ func (p *Planner) loadClusterAgent(controlPlane *rkev1.RKEControlPlane) ([]byte,
 		return nil, err
 	}
 
-	return systemtemplate.ForCluster(mgmtCluster, tokens[0].Status.Token)
+	return systemtemplate.ForCluster(mgmtCluster, tokens[0].AgentToken())
 }
@@ -154,7 +154,7 @@ func (e *etcdRestore) etcdShutdown(controlPlane *rkev1.RKEControlPlane, clusterP
 
 	updated := false
 	for _, server := range servers {
-		if server.Plan == nil || !equality.Semantic.DeepEqual(server.Plan.Plan, stopPlan) {
+		if server.Plan == nil || !planEqual(server.Plan.Plan, stopPlan) {
 			if err := e.store.UpdatePlan(server.Machine, stopPlan); err != nil {
 				return err
 			}
@@ -0,0 +1,134 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"github.com/sirupsen/logrus"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+const kubeletArgKey = "kubelet-arg"
+
+var (
+	// deniedKubeletArgs are the kubelet args managed by RKE2 and K3s that a machine pool may not set
+	deniedKubeletArgs = map[string]bool{
+		"anonymous-auth":       true,
+		"authorization-mode":   true,
+		"bootstrap-kubeconfig": true,
+		"cert-dir":             true,
+		"client-ca-file":       true,
+		"kubeconfig":           true,
+		"tls-cert-file":        true,
+		"tls-private-key-file": true,
+	}
+	deniedKubeletArgPrefixes = []string{"authentication-", "authorization-"}
+)
+
+// DeniedKubeletArgs returns the sorted names of the args a machine pool may not set
+func DeniedKubeletArgs(args map[string]string) (result []string) {
+	for key := range args {
+		if isDeniedKubeletArg(key) {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return
+}
+
+func isDeniedKubeletArg(key string) bool {
+	key = kubeletArgName(key)
+	if deniedKubeletArgs[key] {
+		return true
+	}
+	for _, prefix := range deniedKubeletArgPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func kubeletArgName(key string) string {
+	return strings.TrimLeft(key, "-")
+}
+
+// KubeletArgConflicts returns the args of the machine pool that override a different value of the cluster-level
+// kubelet-arg config value
+func KubeletArgConflicts(clusterArgs interface{}, poolArgs map[string]string) (result []string) {
+	values := map[string]string{}
+	for _, arg := range convert.ToStringSlice(clusterArgs) {
+		key, value := splitKubeletArg(arg)
+		values[key] = value
+	}
+
+	for key, value := range poolArgs {
+		if clusterValue, ok := values[kubeletArgName(key)]; ok && clusterValue != value {
+			result = append(result, fmt.Sprintf("%s (%s => %s)", kubeletArgName(key), clusterValue, value))
+		}
+	}
+	sort.Strings(result)
+	return
+}
+
+func splitKubeletArg(arg string) (string, string) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) == 1 {
+		return kubeletArgName(parts[0]), ""
+	}
+	return kubeletArgName(parts[0]), parts[1]
+}
+
+// addMachinePoolKubeletArgs merges the kubelet args of the machine pool of the machine into the kubelet-arg config
+// value, the args of the pool override the cluster-level ones.
+func addMachinePoolKubeletArgs(config map[string]interface{}, controlPlane *rkev1.RKEControlPlane, machine *capi.Machine) {
+	poolArgs := controlPlane.Spec.MachinePoolKubeletArgs[machine.Labels[capi.MachineDeploymentLabelName]]
+	if len(poolArgs) == 0 {
+		return
+	}
+
+	if conflicts := KubeletArgConflicts(config[kubeletArgKey], poolArgs); len(conflicts) > 0 {
+		logrus.Infof("[planner] rkecluster %s/%s: machine pool kubelet args of machine %s override the cluster-level args: %s",
+			controlPlane.Namespace, controlPlane.Spec.ClusterName, machine.Name, strings.Join(conflicts, ", "))
+	}
+
+	var (
+		args   []string
+		seen   = map[string]bool{}
+		keys   []string
+		values = map[string]string{}
+	)
+	for key, value := range poolArgs {
+		if isDeniedKubeletArg(key) {
+			logrus.Errorf("[planner] rkecluster %s/%s: ignoring denied kubelet arg %s of machine %s",
+				controlPlane.Namespace, controlPlane.Spec.ClusterName, key, machine.Name)
+			continue
+		}
+		keys = append(keys, kubeletArgName(key))
+		values[kubeletArgName(key)] = value
+	}
+	sort.Strings(keys)
+
+	// keep the order of the cluster-level args so the plan doesn't change for unrelated edits
+	for _, arg := range convert.ToStringSlice(config[kubeletArgKey]) {
+		key, _ := splitKubeletArg(arg)
+		if value, ok := values[key]; ok {
+			if !seen[key] {
+				args = append(args, key+"="+value)
+				seen[key] = true
+			}
+			continue
+		}
+		args = append(args, arg)
+	}
+	for _, key := range keys {
+		if !seen[key] {
+			args = append(args, key+"="+values[key])
+		}
+	}
+
+	config[kubeletArgKey] = args
+}
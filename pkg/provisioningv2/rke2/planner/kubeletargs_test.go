@@ -0,0 +1,90 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func newKubeletArgsMachine(machineDeployment string) *capi.Machine {
+	return &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   machineDeployment + "-abcde",
+			Labels: map[string]string{capi.MachineDeploymentLabelName: machineDeployment},
+		},
+	}
+}
+
+func newKubeletArgsControlPlane(poolArgs map[string]string) *rkev1.RKEControlPlane {
+	return &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			ClusterName: "test",
+			MachinePoolKubeletArgs: map[string]map[string]string{
+				"test-memory": poolArgs,
+			},
+		},
+	}
+}
+
+func TestAddMachinePoolKubeletArgsPrecedence(t *testing.T) {
+	config := map[string]interface{}{
+		kubeletArgKey: []interface{}{"max-pods=200", "eviction-hard=memory.available<100Mi"},
+	}
+	controlPlane := newKubeletArgsControlPlane(map[string]string{
+		"--eviction-hard": "memory.available<1Gi",
+		"system-reserved": "memory=2Gi",
+	})
+
+	addMachinePoolKubeletArgs(config, controlPlane, newKubeletArgsMachine("test-memory"))
+	assert.Equal(t, []string{
+		"max-pods=200",
+		"eviction-hard=memory.available<1Gi",
+		"system-reserved=memory=2Gi",
+	}, config[kubeletArgKey], "expected the pool args to override the cluster-level args")
+}
+
+func TestAddMachinePoolKubeletArgsOtherPool(t *testing.T) {
+	config := map[string]interface{}{
+		kubeletArgKey: []interface{}{"max-pods=200"},
+	}
+	controlPlane := newKubeletArgsControlPlane(map[string]string{"max-pods": "50"})
+
+	addMachinePoolKubeletArgs(config, controlPlane, newKubeletArgsMachine("test-worker"))
+	assert.Equal(t, []interface{}{"max-pods=200"}, config[kubeletArgKey])
+}
+
+func TestAddMachinePoolKubeletArgsDenied(t *testing.T) {
+	config := map[string]interface{}{
+		kubeletArgKey: []interface{}{"anonymous-auth=false"},
+	}
+	controlPlane := newKubeletArgsControlPlane(map[string]string{
+		"anonymous-auth":                               "true",
+		"authentication-token-webhook":                 "false",
+		"--authorization-webhook-cache-authorized-ttl": "0s",
+		"max-pods": "50",
+	})
+
+	addMachinePoolKubeletArgs(config, controlPlane, newKubeletArgsMachine("test-memory"))
+	assert.Equal(t, []string{"anonymous-auth=false", "max-pods=50"}, config[kubeletArgKey])
+}
+
+func TestDeniedKubeletArgs(t *testing.T) {
+	assert.Equal(t, []string{"--kubeconfig", "authentication-token-webhook", "tls-cert-file"}, DeniedKubeletArgs(map[string]string{
+		"authentication-token-webhook": "false",
+		"tls-cert-file":                "/tmp/cert.pem",
+		"--kubeconfig":                 "/tmp/kubeconfig",
+		"max-pods":                     "50",
+		"eviction-hard":                "memory.available<1Gi",
+	}))
+	assert.Empty(t, DeniedKubeletArgs(map[string]string{"max-pods": "50"}))
+}
+
+func TestKubeletArgConflicts(t *testing.T) {
+	assert.Equal(t, []string{"max-pods (200 => 50)"}, KubeletArgConflicts(
+		[]interface{}{"max-pods=200", "eviction-hard=memory.available<100Mi"},
+		map[string]string{"max-pods": "50", "--eviction-hard": "memory.available<100Mi", "system-reserved": "memory=2Gi"}))
+	assert.Empty(t, KubeletArgConflicts(nil, map[string]string{"max-pods": "50"}))
+}
@@ -32,7 +32,6 @@
 	"github.com/rancher/wrangler/pkg/summary"
 	"github.com/rancher/wrangler/pkg/yaml"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/equality"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -165,7 +164,9 @@ func (p *Planner) getCAPICluster(controlPlane *rkev1.RKEControlPlane) (*capi.Clu
 	return p.capiClusters.Get(controlPlane.Namespace, ref.Name)
 }
 
-func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
+// Process reconciles the plans of the machines of the control plane, the progress of every machine is recorded in
+// upgradeStatus.
+func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane, upgradeStatus *rkev1.UpgradeStatus) error {
 	p.locker.Lock(string(controlPlane.UID))
 	defer p.locker.Unlock(string(controlPlane.UID))
 
@@ -184,6 +185,8 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return err
 	}
 
+	resetUpgradeStatus(upgradeStatus, controlPlane, plan)
+
 	var (
 		firstIgnoreError error
 		joinServer       string
@@ -201,7 +204,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return err
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "bootstrap", true, isInitNode, none,
+	err = p.reconcile(controlPlane, secret, plan, upgradeStatus, "bootstrap", true, isInitNode, none,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, "",
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -218,7 +221,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return ErrWaiting("waiting for join url to be available on bootstrap node")
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "etcd", true, isEtcd, isInitNode,
+	err = p.reconcile(controlPlane, secret, plan, upgradeStatus, "etcd", true, isEtcd, isInitNode,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, joinServer,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -226,7 +229,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return err
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "control plane", true, isControlPlane, isInitNode,
+	err = p.reconcile(controlPlane, secret, plan, upgradeStatus, "control plane", true, isControlPlane, isInitNode,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, joinServer,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -239,7 +242,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return ErrWaiting("waiting for control plane to be available")
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "worker", false, isOnlyWorker, isInitNode,
+	err = p.reconcile(controlPlane, secret, plan, upgradeStatus, "worker", false, isOnlyWorker, isInitNode,
 		controlPlane.Spec.UpgradeStrategy.WorkerConcurrency, joinServer,
 		controlPlane.Spec.UpgradeStrategy.WorkerDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -412,6 +415,7 @@ func detailMessage(machines []string, messages map[string]string) string {
 }
 
 func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Secret, clusterPlan *plan.Plan,
+	upgradeStatus *rkev1.UpgradeStatus,
 	tierName string,
 	required bool,
 	include, exclude roleFilter, maxUnavailable string, joinServer string, drainOptions rkev1.DrainOptions) error {
@@ -438,8 +442,10 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 		}
 
 		summary := summary.Summarize(entry.Machine)
+		lastError := ""
 		if summary.Error {
 			errMachines = append(errMachines, entry.Machine.Name)
+			lastError = strings.Join(summary.Message, ", ")
 		}
 		if summary.Transitioning {
 			nonReady = append(nonReady, entry.Machine.Name)
@@ -451,13 +457,15 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 			return err
 		}
 
+		phase := rkev1.UpgradePhaseUpgrading
 		if entry.Plan == nil {
 			outOfSync = append(outOfSync, entry.Machine.Name)
 			if err := p.store.UpdatePlan(entry.Machine, plan); err != nil {
 				return err
 			}
-		} else if !equality.Semantic.DeepEqual(entry.Plan.Plan, plan) {
+		} else if !planEqual(entry.Plan.Plan, plan) {
 			outOfSync = append(outOfSync, entry.Machine.Name)
+			phase = rkev1.UpgradePhasePending
 			// Conditions
 			// 1. If plan is not in sync then there is no harm in updating it to something else because
 			//    the node will have already been considered unavailable.
@@ -473,8 +481,10 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 					if err := p.store.UpdatePlan(entry.Machine, plan); err != nil {
 						return err
 					}
+					phase = rkev1.UpgradePhaseUpgrading
 				} else {
 					draining = append(draining, entry.Machine.Name)
+					phase = rkev1.UpgradePhaseCordoned
 				}
 			}
 		} else if !entry.Plan.InSync {
@@ -484,8 +494,11 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 				return err
 			} else if !ok {
 				uncordoned = append(uncordoned, entry.Machine.Name)
+			} else {
+				phase = rkev1.UpgradePhaseDone
 			}
 		}
+		setUpgradePhase(upgradeStatus, entry.Machine.Name, controlPlane.Spec.KubernetesVersion, phase, lastError)
 	}
 
 	if required && len(entries) == 0 {
@@ -825,6 +838,7 @@ func (p *Planner) addConfigFile(nodePlan plan.NodePlan, controlPlane *rkev1.RKEC
 	if err := addUserConfig(config, controlPlane, machine); err != nil {
 		return nodePlan, err
 	}
+	addMachinePoolKubeletArgs(config, controlPlane, machine)
 
 	files, err := p.addRegistryConfig(config, controlPlane)
 	if err != nil {
@@ -0,0 +1,242 @@
+package planner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	// AgentCapabilitiesKey is the key of the plan secret in which the agent reports the plan features it supports, as
+	// a comma separated list
+	AgentCapabilitiesKey = "agent-capabilities"
+	// CapabilityCompressedFiles is reported by agents that decode the content of files with the gzip+base64 encoding
+	CapabilityCompressedFiles = "compressed-files"
+	// CapabilityFileHashes is reported by agents that keep files in place when they are only referenced by hash
+	CapabilityFileHashes = "file-hashes"
+
+	FileEncodingGzipBase64 = "gzip+base64"
+
+	// MaxPlanSecretSize is the maximum size of the data of a plan secret, below the 1MiB limit of etcd objects to leave
+	// room for the metadata of the secret
+	MaxPlanSecretSize = 1000 * 1024
+
+	largestEntriesCount = 3
+)
+
+// ErrPlanTooLarge is returned when the plan of a machine doesn't fit in its plan secret
+type ErrPlanTooLarge struct {
+	Machine string
+	Size    int
+	Largest []string
+}
+
+func (e *ErrPlanTooLarge) Error() string {
+	return fmt.Sprintf("plan secret of machine %s would be %d bytes, over the limit of %d bytes, largest entries: %s",
+		e.Machine, e.Size, MaxPlanSecretSize, strings.Join(e.Largest, ", "))
+}
+
+func agentCapabilities(secret *corev1.Secret) map[string]bool {
+	result := map[string]bool{}
+	for _, capability := range strings.Split(string(secret.Data[AgentCapabilitiesKey]), ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			result[capability] = true
+		}
+	}
+	return result
+}
+
+// fileContent returns the decoded content of a file
+func fileContent(file plan.File) ([]byte, error) {
+	content, err := base64.StdEncoding.DecodeString(file.Content)
+	if err != nil {
+		return nil, err
+	}
+	if file.Encoding != FileEncodingGzipBase64 {
+		return content, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
+
+// fileHash returns the hash of the content of a file, or the hash referencing the content if it is omitted
+func fileHash(file plan.File) (string, error) {
+	if file.Content == "" && file.Hash != "" {
+		return file.Hash, nil
+	}
+	content, err := fileContent(file)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func compress(content []byte) (string, error) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write(content); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// encodePlan serializes the plan for the plan secret. Depending on the capabilities of the agent, the content of the
+// files that are unchanged since the applied plan is replaced by its hash and the content of the other files is
+// compressed.
+func encodePlan(nodePlan plan.NodePlan, secret *corev1.Secret) ([]byte, error) {
+	capabilities := agentCapabilities(secret)
+
+	appliedHashes := map[string]string{}
+	if capabilities[CapabilityFileHashes] && len(secret.Data["appliedPlan"]) > 0 {
+		appliedPlan := plan.NodePlan{}
+		if err := json.Unmarshal(secret.Data["appliedPlan"], &appliedPlan); err != nil {
+			return nil, err
+		}
+		for _, file := range appliedPlan.Files {
+			hash, err := fileHash(file)
+			if err != nil {
+				return nil, err
+			}
+			appliedHashes[file.Path] = hash
+		}
+	}
+
+	files := make([]plan.File, 0, len(nodePlan.Files))
+	for _, file := range nodePlan.Files {
+		if capabilities[CapabilityFileHashes] {
+			hash, err := fileHash(file)
+			if err != nil {
+				return nil, err
+			}
+			if appliedHashes[file.Path] == hash {
+				files = append(files, plan.File{
+					Path:    file.Path,
+					Dynamic: file.Dynamic,
+					Hash:    hash,
+				})
+				continue
+			}
+		}
+		if capabilities[CapabilityCompressedFiles] && file.Encoding == "" && file.Content != "" {
+			content, err := fileContent(file)
+			if err != nil {
+				return nil, err
+			}
+			if file.Content, err = compress(content); err != nil {
+				return nil, err
+			}
+			file.Encoding = FileEncodingGzipBase64
+		}
+		files = append(files, file)
+	}
+
+	nodePlan.Files = files
+	return json.Marshal(nodePlan)
+}
+
+// decodePlan decompresses the content of the files of a plan read from a plan secret, files referenced by hash are left
+// as is
+func decodePlan(nodePlan *plan.NodePlan) error {
+	for i, file := range nodePlan.Files {
+		if file.Encoding != FileEncodingGzipBase64 {
+			continue
+		}
+		content, err := fileContent(file)
+		if err != nil {
+			return err
+		}
+		nodePlan.Files[i].Content = base64.StdEncoding.EncodeToString(content)
+		nodePlan.Files[i].Encoding = ""
+	}
+	return nil
+}
+
+// planEqual compares plans with the files compared by hash, as the content of files unchanged since the applied plan is
+// omitted from the plan secret
+func planEqual(existing, desired plan.NodePlan) bool {
+	return equality.Semantic.DeepEqual(hashFiles(existing), hashFiles(desired))
+}
+
+func hashFiles(nodePlan plan.NodePlan) plan.NodePlan {
+	files := make([]plan.File, len(nodePlan.Files))
+	for i, file := range nodePlan.Files {
+		hash, err := fileHash(file)
+		if err != nil {
+			// compare the invalid content as is
+			files[i] = file
+			continue
+		}
+		files[i] = plan.File{
+			Path:    file.Path,
+			Dynamic: file.Dynamic,
+			Hash:    hash,
+		}
+	}
+	nodePlan.Files = files
+	return nodePlan
+}
+
+// checkPlanSecretSize returns an ErrPlanTooLarge naming the largest files of the plan and entries of the secret if the
+// data of the secret exceeds MaxPlanSecretSize
+func checkPlanSecretSize(machineName string, secret *corev1.Secret) error {
+	type entry struct {
+		name string
+		size int
+	}
+
+	var (
+		size    int
+		entries []entry
+	)
+	for key, value := range secret.Data {
+		size += len(key) + len(value)
+		if key != "plan" {
+			entries = append(entries, entry{name: "secret key " + key, size: len(value)})
+		}
+	}
+	if size <= MaxPlanSecretSize {
+		return nil
+	}
+
+	nodePlan := plan.NodePlan{}
+	if err := json.Unmarshal(secret.Data["plan"], &nodePlan); err != nil {
+		return err
+	}
+	for _, file := range nodePlan.Files {
+		entries = append(entries, entry{name: "file " + file.Path, size: len(file.Content)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].size == entries[j].size {
+			return entries[i].name < entries[j].name
+		}
+		return entries[i].size > entries[j].size
+	})
+
+	err := &ErrPlanTooLarge{
+		Machine: machineName,
+		Size:    size,
+	}
+	for i := 0; i < len(entries) && i < largestEntriesCount; i++ {
+		err.Largest = append(err.Largest, fmt.Sprintf("%s (%d bytes)", entries[i].name, entries[i].size))
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newFile(path string, content []byte) plan.File {
+	return plan.File{
+		Path:    path,
+		Content: base64.StdEncoding.EncodeToString(content),
+	}
+}
+
+// compressibleContent returns content that is about as compressible as manifests and configs
+func compressibleContent(size int) []byte {
+	return bytes.Repeat([]byte("apiVersion: v1\nkind: ConfigMap\n"), size/31+1)[:size]
+}
+
+// randomContent returns content that can't be compressed
+func randomContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func newPlanSecret(capabilities string, appliedPlan *plan.NodePlan) *corev1.Secret {
+	secret := &corev1.Secret{
+		Data: map[string][]byte{},
+	}
+	if capabilities != "" {
+		secret.Data[AgentCapabilitiesKey] = []byte(capabilities)
+	}
+	if appliedPlan != nil {
+		data, _ := json.Marshal(appliedPlan)
+		secret.Data["appliedPlan"] = data
+	}
+	return secret
+}
+
+func decodeSecretPlan(t *testing.T, data []byte) plan.NodePlan {
+	nodePlan := plan.NodePlan{}
+	require.NoError(t, json.Unmarshal(data, &nodePlan))
+	return nodePlan
+}
+
+func TestEncodePlanCompression(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Files: []plan.File{
+			newFile("/var/lib/rancher/rke2/server/manifests/large.yaml", compressibleContent(2*MaxPlanSecretSize)),
+			newFile("/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", []byte("token: secret\n")),
+		},
+	}
+
+	uncompressed, err := encodePlan(nodePlan, newPlanSecret("", nil))
+	require.NoError(t, err)
+	assert.Equal(t, nodePlan, decodeSecretPlan(t, uncompressed))
+
+	compressed, err := encodePlan(nodePlan, newPlanSecret(CapabilityCompressedFiles, nil))
+	require.NoError(t, err)
+	assert.Less(t, len(compressed), MaxPlanSecretSize)
+	assert.Less(t, len(compressed), len(uncompressed))
+
+	compressedPlan := decodeSecretPlan(t, compressed)
+	for _, file := range compressedPlan.Files {
+		assert.Equal(t, FileEncodingGzipBase64, file.Encoding)
+	}
+
+	require.NoError(t, decodePlan(&compressedPlan))
+	assert.Equal(t, nodePlan, compressedPlan)
+}
+
+func TestEncodePlanFileHashes(t *testing.T) {
+	unchanged := newFile("/var/lib/rancher/rke2/server/manifests/large.yaml", compressibleContent(4096))
+	changed := newFile("/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", []byte("token: secret\n"))
+	appliedPlan := &plan.NodePlan{
+		Files: []plan.File{
+			unchanged,
+			newFile(changed.Path, []byte("token: old\n")),
+		},
+	}
+	nodePlan := plan.NodePlan{
+		Files: []plan.File{unchanged, changed},
+	}
+
+	data, err := encodePlan(nodePlan, newPlanSecret(CapabilityFileHashes, appliedPlan))
+	require.NoError(t, err)
+	encodedPlan := decodeSecretPlan(t, data)
+
+	require.Len(t, encodedPlan.Files, 2)
+	expectedHash, err := fileHash(unchanged)
+	require.NoError(t, err)
+	assert.Equal(t, plan.File{Path: unchanged.Path, Hash: expectedHash}, encodedPlan.Files[0])
+	assert.Equal(t, changed, encodedPlan.Files[1])
+
+	assert.True(t, planEqual(encodedPlan, nodePlan), "expected the plan referencing unchanged files to equal the full plan")
+	nodePlan.Files[0] = newFile(unchanged.Path, []byte("changed"))
+	assert.False(t, planEqual(encodedPlan, nodePlan))
+
+	// without the capability the content is always sent
+	data, err = encodePlan(plan.NodePlan{Files: []plan.File{unchanged}}, newPlanSecret("", appliedPlan))
+	require.NoError(t, err)
+	assert.Equal(t, []plan.File{unchanged}, decodeSecretPlan(t, data).Files)
+}
+
+func TestCheckPlanSecretSize(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Files: []plan.File{
+			newFile("/var/lib/rancher/rke2/server/manifests/small.yaml", randomContent(1024)),
+			newFile("/var/lib/rancher/rke2/server/manifests/large.yaml", randomContent(MaxPlanSecretSize)),
+			newFile("/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", []byte("token: secret\n")),
+		},
+	}
+
+	// random content is larger once compressed, compression doesn't help
+	secret := newPlanSecret(CapabilityCompressedFiles+","+CapabilityFileHashes, nil)
+	data, err := encodePlan(nodePlan, secret)
+	require.NoError(t, err)
+	secret.Data["plan"] = data
+
+	err = checkPlanSecretSize("machine-1", secret)
+	var errPlanTooLarge *ErrPlanTooLarge
+	require.True(t, errors.As(err, &errPlanTooLarge), "expected ErrPlanTooLarge, got %v", err)
+	assert.Equal(t, "machine-1", errPlanTooLarge.Machine)
+	assert.Greater(t, errPlanTooLarge.Size, MaxPlanSecretSize)
+	require.Len(t, errPlanTooLarge.Largest, largestEntriesCount)
+	assert.Contains(t, errPlanTooLarge.Largest[0], "file /var/lib/rancher/rke2/server/manifests/large.yaml")
+	assert.Contains(t, errPlanTooLarge.Largest[1], "file /var/lib/rancher/rke2/server/manifests/small.yaml")
+	assert.Contains(t, err.Error(), "machine-1")
+
+	// the same plan fits without the large file
+	nodePlan.Files = nodePlan.Files[:1]
+	secret.Data["plan"], err = encodePlan(nodePlan, secret)
+	require.NoError(t, err)
+	assert.NoError(t, checkPlanSecretSize("machine-1", secret))
+}
+
+func TestSecretToNodeCompressedPlan(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Files: []plan.File{
+			newFile("/var/lib/rancher/rke2/server/manifests/large.yaml", compressibleContent(4096)),
+		},
+	}
+	secret := newPlanSecret(CapabilityCompressedFiles, nil)
+	data, err := encodePlan(nodePlan, secret)
+	require.NoError(t, err)
+	secret.Data["plan"] = data
+	secret.Data["appliedPlan"] = data
+
+	node, err := SecretToNode(secret)
+	require.NoError(t, err)
+	require.NotNil(t, node)
+	assert.Equal(t, nodePlan, node.Plan)
+	require.NotNil(t, node.AppliedPlan)
+	assert.Equal(t, nodePlan, *node.AppliedPlan)
+}
@@ -13,7 +13,6 @@
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/equality"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -163,6 +162,9 @@ func SecretToNode(secret *corev1.Secret) (*plan.Node, error) {
 		if err := json.Unmarshal(planData, &result.Plan); err != nil {
 			return nil, err
 		}
+		if err := decodePlan(&result.Plan); err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, nil
 	}
@@ -172,6 +174,9 @@ func SecretToNode(secret *corev1.Secret) (*plan.Node, error) {
 		if err := json.Unmarshal(appliedPlanData, newPlan); err != nil {
 			return nil, err
 		}
+		if err := decodePlan(newPlan); err != nil {
+			return nil, err
+		}
 		result.AppliedPlan = newPlan
 	}
 
@@ -220,12 +225,12 @@ func (p *PlanStore) UpdatePlan(machine *capi.Machine, plan plan.NodePlan) error
 		return fmt.Errorf("machine %s/%s is not using RKEBootstrap", machine.Namespace, machine.Name)
 	}
 
-	data, err := json.Marshal(plan)
+	secret, err := p.secrets.Get(machine.Namespace, PlanSecretFromBootstrapName(machine.Spec.Bootstrap.ConfigRef.Name), metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 
-	secret, err := p.secrets.Get(machine.Namespace, PlanSecretFromBootstrapName(machine.Spec.Bootstrap.ConfigRef.Name), metav1.GetOptions{})
+	data, err := encodePlan(plan, secret)
 	if err != nil {
 		return err
 	}
@@ -235,12 +240,15 @@ func (p *PlanStore) UpdatePlan(machine *capi.Machine, plan plan.NodePlan) error
 	}
 
 	secret.Data["plan"] = data
+	if err := checkPlanSecretSize(machine.Name, secret); err != nil {
+		return err
+	}
 	_, err = p.secrets.Update(secret)
 	return err
 }
 
 func assignAndCheckPlan(store *PlanStore, msg string, server planEntry, newPlan plan.NodePlan) error {
-	if server.Plan == nil || !equality.Semantic.DeepEqual(server.Plan.Plan, newPlan) {
+	if server.Plan == nil || !planEqual(server.Plan.Plan, newPlan) {
 		if err := store.UpdatePlan(server.Machine, newPlan); err != nil {
 			return err
 		}
@@ -0,0 +1,66 @@
+package planner
+
+import (
+	"sort"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+)
+
+// resetUpgradeStatus prepares the upgrade status for a run of the planner: machines that are gone are removed, new
+// machines and machines that still have to move to a new desired version are pending until they are reconciled.
+func resetUpgradeStatus(status *rkev1.UpgradeStatus, controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) {
+	if status == nil {
+		return
+	}
+
+	existing := map[string]rkev1.MachineUpgradeStatus{}
+	for _, machine := range status.Machines {
+		existing[machine.MachineName] = machine
+	}
+
+	var machines []rkev1.MachineUpgradeStatus
+	for name := range clusterPlan.Machines {
+		machine, ok := existing[name]
+		if !ok || machine.DesiredVersion != controlPlane.Spec.KubernetesVersion {
+			machine = rkev1.MachineUpgradeStatus{
+				MachineName:    name,
+				CurrentVersion: machine.CurrentVersion,
+				DesiredVersion: controlPlane.Spec.KubernetesVersion,
+				Phase:          rkev1.UpgradePhasePending,
+			}
+		}
+		machines = append(machines, machine)
+	}
+
+	sort.Slice(machines, func(i, j int) bool {
+		return machines[i].MachineName < machines[j].MachineName
+	})
+	status.Machines = machines
+}
+
+// setUpgradePhase records the phase of a machine. The entry of the machine only changes when its phase or its error
+// does, so the status is not updated for every progress message of the machine. A machine that is done runs the
+// desired version.
+func setUpgradePhase(status *rkev1.UpgradeStatus, machineName, desiredVersion string, phase rkev1.UpgradePhase, lastError string) {
+	if status == nil {
+		return
+	}
+
+	for i, machine := range status.Machines {
+		if machine.MachineName != machineName {
+			continue
+		}
+		if machine.Phase == phase && machine.LastError == lastError {
+			return
+		}
+		machine.DesiredVersion = desiredVersion
+		machine.Phase = phase
+		machine.LastError = lastError
+		if phase == rkev1.UpgradePhaseDone {
+			machine.CurrentVersion = desiredVersion
+		}
+		status.Machines[i] = machine
+		return
+	}
+}
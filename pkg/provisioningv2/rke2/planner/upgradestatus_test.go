@@ -0,0 +1,109 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func newUpgradePlan(machineNames ...string) *plan.Plan {
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Nodes:    map[string]*plan.Node{},
+	}
+	for _, name := range machineNames {
+		clusterPlan.Machines[name] = &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		clusterPlan.Nodes[name] = &plan.Node{InSync: true}
+	}
+	return clusterPlan
+}
+
+func newUpgradeControlPlane(version string) *rkev1.RKEControlPlane {
+	return &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			KubernetesVersion: version,
+		},
+	}
+}
+
+func TestResetUpgradeStatus(t *testing.T) {
+	status := &rkev1.UpgradeStatus{
+		Machines: []rkev1.MachineUpgradeStatus{
+			{MachineName: "removed", CurrentVersion: "v1.20.8+rke2r1", DesiredVersion: "v1.20.8+rke2r1", Phase: rkev1.UpgradePhaseDone},
+			{MachineName: "worker", CurrentVersion: "v1.20.8+rke2r1", DesiredVersion: "v1.20.8+rke2r1", Phase: rkev1.UpgradePhaseDone},
+		},
+	}
+
+	resetUpgradeStatus(status, newUpgradeControlPlane("v1.20.8+rke2r1"), newUpgradePlan("worker", "etcd"))
+	assert.Equal(t, []rkev1.MachineUpgradeStatus{
+		{MachineName: "etcd", DesiredVersion: "v1.20.8+rke2r1", Phase: rkev1.UpgradePhasePending},
+		{MachineName: "worker", CurrentVersion: "v1.20.8+rke2r1", DesiredVersion: "v1.20.8+rke2r1", Phase: rkev1.UpgradePhaseDone},
+	}, status.Machines)
+
+	// a new version makes every machine pending again, until it is reconciled
+	resetUpgradeStatus(status, newUpgradeControlPlane("v1.21.3+rke2r1"), newUpgradePlan("worker", "etcd"))
+	assert.Equal(t, []rkev1.MachineUpgradeStatus{
+		{MachineName: "etcd", DesiredVersion: "v1.21.3+rke2r1", Phase: rkev1.UpgradePhasePending},
+		{MachineName: "worker", CurrentVersion: "v1.20.8+rke2r1", DesiredVersion: "v1.21.3+rke2r1", Phase: rkev1.UpgradePhasePending},
+	}, status.Machines)
+
+	// reset doesn't panic without a status
+	resetUpgradeStatus(nil, newUpgradeControlPlane("v1.21.3+rke2r1"), newUpgradePlan("worker"))
+}
+
+func TestSetUpgradePhase(t *testing.T) {
+	version := "v1.21.3+rke2r1"
+	status := &rkev1.UpgradeStatus{}
+	resetUpgradeStatus(status, newUpgradeControlPlane(version), newUpgradePlan("worker"))
+	status.Machines[0].CurrentVersion = "v1.20.8+rke2r1"
+
+	phases := []rkev1.UpgradePhase{
+		rkev1.UpgradePhasePending,
+		rkev1.UpgradePhaseCordoned,
+		rkev1.UpgradePhaseUpgrading,
+	}
+	for _, phase := range phases {
+		setUpgradePhase(status, "worker", version, phase, "")
+		require.Len(t, status.Machines, 1)
+		assert.Equal(t, phase, status.Machines[0].Phase)
+		assert.Equal(t, "v1.20.8+rke2r1", status.Machines[0].CurrentVersion, "expected the version not to change before the machine is done")
+	}
+
+	setUpgradePhase(status, "worker", version, rkev1.UpgradePhaseUpgrading, "machine is failing")
+	assert.Equal(t, "machine is failing", status.Machines[0].LastError)
+
+	setUpgradePhase(status, "worker", version, rkev1.UpgradePhaseDone, "")
+	assert.Equal(t, rkev1.MachineUpgradeStatus{
+		MachineName:    "worker",
+		CurrentVersion: version,
+		DesiredVersion: version,
+		Phase:          rkev1.UpgradePhaseDone,
+	}, status.Machines[0])
+
+	// machines that aren't part of the status aren't added
+	setUpgradePhase(status, "other", version, rkev1.UpgradePhaseDone, "")
+	assert.Len(t, status.Machines, 1)
+}
+
+func TestUpgradeStatusUnchanged(t *testing.T) {
+	version := "v1.21.3+rke2r1"
+	status := &rkev1.UpgradeStatus{}
+	clusterPlan := newUpgradePlan("cp-1", "cp-2", "worker")
+	resetUpgradeStatus(status, newUpgradeControlPlane(version), clusterPlan)
+	for name := range clusterPlan.Machines {
+		setUpgradePhase(status, name, version, rkev1.UpgradePhaseDone, "")
+	}
+	expected := status.DeepCopy()
+
+	// another run of the planner over machines in the same phase doesn't change the status
+	resetUpgradeStatus(status, newUpgradeControlPlane(version), clusterPlan)
+	for name := range clusterPlan.Machines {
+		setUpgradePhase(status, name, version, rkev1.UpgradePhaseDone, "")
+	}
+	assert.Equal(t, expected, status)
+}
@@ -2,13 +2,63 @@
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/provisioningv2/rke2/installer"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-func InstallHandler() http.Handler {
+// InstallHandler serves the install script of the settings, or the one of the cluster set by the clusterNamespace and
+// clusterName query parameters along with its agent env vars, which may take their value from secrets. The install
+// script of a cluster is only served to the callers presenting one of its registration tokens as a bearer token, and
+// Rancher never fetches the URLs set by clusters: the nodes download and verify those install scripts themselves.
+func InstallHandler(clusters rocontrollers.ClusterCache, crts mgmtcontrollers.ClusterRegistrationTokenCache,
+	configMaps corecontrollers.ConfigMapCache, secrets corecontrollers.SecretCache) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-		content, err := installer.InstallScript("", nil)
+		var (
+			source  *installer.Source
+			envVars []corev1.EnvVar
+		)
+		if namespace, name := req.URL.Query().Get("clusterNamespace"), req.URL.Query().Get("clusterName"); namespace != "" || name != "" {
+			cluster, err := clusters.Get(namespace, name)
+			if apierror.IsNotFound(err) {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			ok, err := acceptsToken(crts, cluster.Status.ClusterName, req)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			} else if !ok {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if cluster.Spec.RKEConfig != nil {
+				source, err = installer.ResolveSource(configMaps, cluster.Namespace, cluster.Spec.RKEConfig.AgentInstallScript)
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			envVars, err = installer.ResolveEnvVars(secrets, cluster.Namespace, cluster.Spec.AgentEnvVars)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		content, err := installer.InstallScriptFromSource(source, "", envVars)
 		if err != nil {
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
@@ -17,3 +67,24 @@ func InstallHandler() http.Handler {
 		rw.Write(content)
 	})
 }
+
+// acceptsToken returns true if the bearer token of the request is accepted by one of the registration tokens of the
+// management cluster
+func acceptsToken(crts mgmtcontrollers.ClusterRegistrationTokenCache, clusterName string, req *http.Request) (bool, error) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if clusterName == "" || token == "" {
+		return false, nil
+	}
+
+	tokens, err := crts.List(clusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	for _, crt := range tokens {
+		if crt.AcceptsToken(token, now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/installer"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeClusters struct {
+	rocontrollers.ClusterCache
+	clusters map[string]*provv1.Cluster
+}
+
+func (f fakeClusters) Get(namespace, name string) (*provv1.Cluster, error) {
+	if cluster, ok := f.clusters[namespace+"/"+name]; ok {
+		return cluster, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Resource: "clusters"}, name)
+}
+
+type fakeCRTs struct {
+	mgmtcontrollers.ClusterRegistrationTokenCache
+	crts []*v3.ClusterRegistrationToken
+}
+
+func (f fakeCRTs) List(namespace string, selector labels.Selector) (result []*v3.ClusterRegistrationToken, _ error) {
+	for _, crt := range f.crts {
+		if crt.Namespace == namespace {
+			result = append(result, crt)
+		}
+	}
+	return result, nil
+}
+
+type fakeConfigMaps struct {
+	corecontrollers.ConfigMapCache
+	configMaps map[string]*corev1.ConfigMap
+}
+
+func (f fakeConfigMaps) Get(namespace, name string) (*corev1.ConfigMap, error) {
+	if configMap, ok := f.configMaps[namespace+"/"+name]; ok {
+		return configMap, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+}
+
+type fakeSecrets struct {
+	corecontrollers.SecretCache
+	secrets map[string]*corev1.Secret
+}
+
+func (f fakeSecrets) Get(namespace, name string) (*corev1.Secret, error) {
+	if secret, ok := f.secrets[namespace+"/"+name]; ok {
+		return secret, nil
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+func TestInstallHandlerClusterScript(t *testing.T) {
+	handler := InstallHandler(
+		fakeClusters{clusters: map[string]*provv1.Cluster{
+			"fleet-default/test": {
+				ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+				Spec: provv1.ClusterSpec{
+					AgentEnvVars: []corev1.EnvVar{{Name: "PROXY_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "proxy"},
+						Key:                  "password",
+					}}}},
+					RKEConfig: &provv1.RKEConfig{RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+						AgentInstallScript: &rkev1.AgentInstallScript{ConfigMapRef: &corev1.ConfigMapKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "install"},
+							Key:                  "install.sh",
+						}},
+					}},
+				},
+				Status: provv1.ClusterStatus{ClusterName: "c-m-abcdefgh"},
+			},
+		}},
+		fakeCRTs{crts: []*v3.ClusterRegistrationToken{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "c-m-abcdefgh", Name: "default-token"}, Status: v3.ClusterRegistrationTokenStatus{Token: "token"}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "c-m-other", Name: "default-token"}, Status: v3.ClusterRegistrationTokenStatus{Token: "other"}},
+		}},
+		fakeConfigMaps{configMaps: map[string]*corev1.ConfigMap{
+			"fleet-default/install": {Data: map[string]string{"install.sh": "echo configmap"}},
+		}},
+		fakeSecrets{secrets: map[string]*corev1.Secret{
+			"fleet-default/proxy": {
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{installer.AgentEnvVarSecretLabel: "true"}},
+				Data:       map[string][]byte{"password": []byte("s3cr3t")},
+			},
+		}},
+	)
+
+	tests := []struct {
+		name           string
+		query          string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "registration token",
+			query:          "?clusterNamespace=fleet-default&clusterName=test",
+			token:          "token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no token",
+			query:          "?clusterNamespace=fleet-default&clusterName=test",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token of another cluster",
+			query:          "?clusterNamespace=fleet-default&clusterName=test",
+			token:          "other",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing cluster",
+			query:          "?clusterNamespace=fleet-default&clusterName=missing",
+			token:          "token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/system-agent-install.sh"+tt.query, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.expectedStatus, rw.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, rw.Body.String(), "echo configmap")
+				assert.Contains(t, rw.Body.String(), "PROXY_PASSWORD=\"s3cr3t\"")
+			} else {
+				assert.NotContains(t, rw.Body.String(), "echo configmap")
+				assert.NotContains(t, rw.Body.String(), "s3cr3t")
+			}
+		})
+	}
+}
@@ -31,7 +31,6 @@
 	"github.com/rancher/rancher/pkg/ui"
 	"github.com/rancher/rancher/pkg/websocket"
 	"github.com/rancher/rancher/pkg/wrangler"
-	aggregation2 "github.com/rancher/steve/pkg/aggregation"
 	steveauth "github.com/rancher/steve/pkg/auth"
 	steveserver "github.com/rancher/steve/pkg/server"
 	"github.com/rancher/wrangler/pkg/k8scheck"
@@ -49,22 +48,25 @@
 const encryptionConfigUpdate = "provisioner.cattle.io/encrypt-migrated"
 
 type Options struct {
-	ACMEDomains       cli.StringSlice
-	AddLocal          string
-	Embedded          bool
-	BindHost          string
-	HTTPListenPort    int
-	HTTPSListenPort   int
-	K8sMode           string
-	Debug             bool
-	Trace             bool
-	NoCACerts         bool
-	AuditLogPath      string
-	AuditLogMaxage    int
-	AuditLogMaxsize   int
-	AuditLogMaxbackup int
-	AuditLevel        int
-	Features          string
+	ACMEDomains            cli.StringSlice
+	AddLocal               string
+	Embedded               bool
+	BindHost               string
+	HTTPListenPort         int
+	HTTPSListenPort        int
+	K8sMode                string
+	Debug                  bool
+	Trace                  bool
+	NoCACerts              bool
+	AuditLogPath           string
+	AuditLogMaxage         int
+	AuditLogMaxsize        int
+	AuditLogMaxbackup      int
+	AuditLogRotate         string
+	AuditLogRedact         cli.StringSlice
+	AuditLevel             int
+	AuditClusterProxyLevel int
+	Features               string
 }
 
 type Rancher struct {
@@ -171,8 +173,11 @@ func New(ctx context.Context, clientConfg clientcmd.ClientConfig, opts *Options)
 		return nil, err
 	}
 
-	auditLogWriter := audit.NewLogWriter(opts.AuditLogPath, opts.AuditLevel, opts.AuditLogMaxage, opts.AuditLogMaxbackup, opts.AuditLogMaxsize)
-	auditFilter, err := audit.NewAuditLogMiddleware(auditLogWriter)
+	auditLogWriter, err := audit.NewLogWriter(opts.AuditLogPath, opts.AuditLevel, opts.AuditClusterProxyLevel, opts.AuditLogMaxage, opts.AuditLogMaxbackup, opts.AuditLogMaxsize, opts.AuditLogRotate)
+	if err != nil {
+		return nil, err
+	}
+	auditFilter, err := audit.NewAuditLogMiddleware(auditLogWriter, opts.AuditLogRedact)
 	if err != nil {
 		return nil, err
 	}
@@ -221,20 +226,20 @@ func (r *Rancher) Start(ctx context.Context) error {
 		}
 	}
 
-	r.Wrangler.OnLeader(func(ctx context.Context) error {
+	r.Wrangler.OnLeader(r.Wrangler.Readiness.Track(func(ctx context.Context) error {
 		if err := dashboarddata.Add(ctx, r.Wrangler, localClusterEnabled(r.opts), r.opts.AddLocal == "false", r.opts.Embedded); err != nil {
 			return err
 		}
 		return r.Wrangler.StartWithTransaction(ctx, func(ctx context.Context) error {
 			return dashboard.Register(ctx, r.Wrangler)
 		})
-	})
+	}))
 
 	if err := r.authServer.Start(ctx, false); err != nil {
 		return err
 	}
 
-	r.Wrangler.OnLeader(r.authServer.OnLeader)
+	r.Wrangler.OnLeader(r.Wrangler.Readiness.Track(r.authServer.OnLeader))
 	r.auditLog.Start(ctx)
 
 	return r.Wrangler.Start(ctx)
@@ -246,6 +251,7 @@ func (r *Rancher) ListenAndServe(ctx context.Context) error {
 	}
 
 	r.Wrangler.MultiClusterManager.Wait(ctx)
+	r.Wrangler.Readiness.Started()
 
 	r.startAggregation(ctx)
 	go r.Steve.StartAggregation(ctx)
@@ -260,11 +266,12 @@ func (r *Rancher) ListenAndServe(ctx context.Context) error {
 	}
 
 	<-ctx.Done()
+	r.auditLog.Wait()
 	return ctx.Err()
 }
 
 func (r *Rancher) startAggregation(ctx context.Context) {
-	aggregation2.Watch(ctx, r.Wrangler.Core.Secret(), namespace.System, "steve-aggregation", r.Handler)
+	aggregation.Watch(ctx, r.Wrangler.Core.Secret(), namespace.System, "steve-aggregation", r.Handler)
 }
 
 func newMCM(wrangler *wrangler.Context, opts *Options) wrangler.MultiClusterManager {
@@ -3,17 +3,26 @@
 import (
 	"context"
 
+	"github.com/rancher/norman/authorization"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	v1 "github.com/rancher/wrangler/pkg/generated/controllers/rbac/v1"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/transport"
 )
 
-func NewAccessControl(ctx context.Context, clusterName string, rbacClient v1.Interface) types.AccessControl {
+// GlobalRoleLookup returns the names of the global roles bound to a user
+type GlobalRoleLookup func(userName string) ([]string, error)
+
+func NewAccessControl(ctx context.Context, clusterName string, rbacClient v1.Interface, globalRoles GlobalRoleLookup) types.AccessControl {
 	asl := accesscontrol.NewAccessStore(ctx, true, rbacClient)
-	return NewAccessControlWithASL(clusterName, rbacClient, asl)
+	return NewAccessControlWithASL(clusterName, rbacClient, asl, globalRoles)
 }
 
-func NewAccessControlWithASL(clusterName string, rbacClient v1.Interface, asl accesscontrol.AccessSetLookup) types.AccessControl {
+// NewAccessControlWithASL returns an access control that evaluates the RBAC of the user of the request. If globalRoles is
+// set, a user bound to the admin global role, or to the restricted-admin global role in a cluster other than the local
+// one, is granted access to the cluster without looking up its RBAC.
+func NewAccessControlWithASL(clusterName string, rbacClient v1.Interface, asl accesscontrol.AccessSetLookup, globalRoles GlobalRoleLookup) types.AccessControl {
 	return newContextBased(func(ctx *types.APIContext) (types.AccessControl, bool) {
 		cache, ok := ctx.Request.Context().Value(contextKey{}).(*accessControlCache)
 		if !ok {
@@ -32,8 +41,38 @@ func NewAccessControlWithASL(clusterName string, rbacClient v1.Interface, asl ac
 
 		cache.Lock()
 		defer cache.Unlock()
-		ac = newUserLookupAccess(ctx, asl)
+		if hasClusterAdminGlobalRole(ctx, clusterName, globalRoles) {
+			ac = &authorization.AllAccess{}
+		} else {
+			ac = newUserLookupAccess(ctx, asl)
+		}
 		cache.cache[clusterName] = ac
 		return ac, true
 	})
 }
+
+// hasClusterAdminGlobalRole returns true if the user of the request has a global role granting it full access to the
+// downstream cluster. The restricted-admin role doesn't grant access to the local cluster.
+func hasClusterAdminGlobalRole(ctx *types.APIContext, clusterName string, globalRoles GlobalRoleLookup) bool {
+	if globalRoles == nil || clusterName == "" {
+		return false
+	}
+
+	userName := ctx.Request.Header.Get(transport.ImpersonateUserHeader)
+	if userName == "" {
+		return false
+	}
+
+	roles, err := globalRoles(userName)
+	if err != nil {
+		logrus.Errorf("[rbac] failed to look up the global roles of user %s: %v", userName, err)
+		return false
+	}
+
+	for _, role := range roles {
+		if role == GlobalAdmin || (role == GlobalRestrictedAdmin && clusterName != "local") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/transport"
+)
+
+type fakeAccessSetLookup struct {
+	lookups int
+}
+
+func (f *fakeAccessSetLookup) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	f.lookups++
+	return &accesscontrol.AccessSet{}
+}
+
+func (f *fakeAccessSetLookup) PurgeUserData(id string) {}
+
+func newAPIContext(userName string) *types.APIContext {
+	req, _ := http.NewRequest(http.MethodGet, "/v3/clusters", nil)
+	req.Header.Set(transport.ImpersonateUserHeader, userName)
+	ctx := context.WithValue(req.Context(), contextKey{}, &accessControlCache{cache: map[string]types.AccessControl{}})
+	return &types.APIContext{Request: req.WithContext(ctx)}
+}
+
+func TestAccessControlGlobalRoleFastPath(t *testing.T) {
+	globalRoles := func(userName string) ([]string, error) {
+		switch userName {
+		case "u-admin":
+			return []string{"user", GlobalAdmin}, nil
+		case "u-restricted":
+			return []string{"user", GlobalRestrictedAdmin}, nil
+		}
+		return []string{"user"}, nil
+	}
+
+	tests := []struct {
+		name        string
+		clusterName string
+		userName    string
+		allowed     bool
+	}{
+		{name: "admin downstream", clusterName: "c-abcde", userName: "u-admin", allowed: true},
+		{name: "admin local", clusterName: "local", userName: "u-admin", allowed: true},
+		{name: "restricted-admin downstream", clusterName: "c-abcde", userName: "u-restricted", allowed: true},
+		{name: "restricted-admin local", clusterName: "local", userName: "u-restricted"},
+		{name: "restricted-admin management", clusterName: "", userName: "u-restricted"},
+		{name: "standard user downstream", clusterName: "c-abcde", userName: "u-user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asl := &fakeAccessSetLookup{}
+			ac := NewAccessControlWithASL(tt.clusterName, nil, asl, globalRoles)
+			apiContext := newAPIContext(tt.userName)
+
+			err := ac.CanDo("", "pods", "get", apiContext, nil, &types.Schema{ID: "pod"})
+			if tt.allowed {
+				assert.NoError(t, err)
+				assert.Equal(t, 0, asl.lookups, "expected no RBAC lookup for the fast path")
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, 1, asl.lookups)
+			}
+		})
+	}
+}
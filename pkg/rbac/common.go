@@ -26,8 +26,17 @@
 	RestrictedAdminProjectRoleBinding = "restricted-admin-rb-project"
 	RestrictedAdminCRForClusters      = "restricted-admin-cr-clusters"
 	RestrictedAdminCRBForClusters     = "restricted-admin-crb-clusters"
+	// RestrictedAdminExcludeAnnotation on a cluster set to "true" excludes the cluster from the downstream clusters the
+	// restricted-admin global role grants access to
+	RestrictedAdminExcludeAnnotation = "management.cattle.io/restricted-admin-exclude"
 )
 
+// RestrictedAdminExcluded returns true if the restricted-admin global role doesn't grant access to the cluster, either
+// because it is the local cluster or because it is annotated with RestrictedAdminExcludeAnnotation
+func RestrictedAdminExcluded(cluster *v3.Cluster) bool {
+	return cluster.Name == "local" || cluster.Annotations[RestrictedAdminExcludeAnnotation] == "true"
+}
+
 // BuildSubjectFromRTB This function will generate
 // PRTB and CRTB to the subject with user, group
 // or service account
@@ -8,6 +8,7 @@
 	"github.com/rancher/norman/types"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func Test_BuildSubjectFromRTB(t *testing.T) {
@@ -119,3 +120,40 @@ type testCase struct {
 		}
 	}
 }
+
+func Test_RestrictedAdminExcluded(t *testing.T) {
+	testCases := []struct {
+		cluster  *v3.Cluster
+		excluded bool
+	}{
+		{
+			cluster:  &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "local"}},
+			excluded: true,
+		},
+		{
+			cluster:  &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}},
+			excluded: false,
+		},
+		{
+			cluster: &v3.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Name:        "c-abcde",
+				Annotations: map[string]string{RestrictedAdminExcludeAnnotation: "true"},
+			}},
+			excluded: true,
+		},
+		{
+			cluster: &v3.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Name:        "c-abcde",
+				Annotations: map[string]string{RestrictedAdminExcludeAnnotation: "false"},
+			}},
+			excluded: false,
+		},
+	}
+
+	for _, tcase := range testCases {
+		if excluded := RestrictedAdminExcluded(tcase.cluster); excluded != tcase.excluded {
+			t.Errorf("cluster %s with annotations %v: expected excluded %v, got %v", tcase.cluster.Name,
+				tcase.cluster.Annotations, tcase.excluded, excluded)
+		}
+	}
+}
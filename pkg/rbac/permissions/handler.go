@@ -0,0 +1,92 @@
+package permissions
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/requests/sar"
+	"github.com/rancher/rancher/pkg/auth/util"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	authzv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	typedauthzv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+type handler struct {
+	resolver      *Resolver
+	clusterLister v3.ClusterLister
+	sarClient     typedauthzv1.SubjectAccessReviewInterface
+}
+
+// NewHandler serves the effective permissions of a user on the cluster of the clusterID path variable. The user is
+// taken from the user query parameter and defaults to the requesting user, only admins can query other users, whether
+// they are admins through their own global role bindings or the ones of their groups. The clusters users can't get are
+// reported as not found, like the clusters which don't exist, so that the endpoint doesn't tell which clusters exist.
+func NewHandler(scaledContext *config.ScaledContext) http.Handler {
+	return &handler{
+		resolver:      NewResolver(scaledContext.Management),
+		clusterLister: scaledContext.Management.Clusters("").Controller().Lister(),
+		sarClient:     scaledContext.K8sClient.AuthorizationV1().SubjectAccessReviews(),
+	}
+}
+
+func (h *handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	requester := req.Header.Get("Impersonate-User")
+	if requester == "" {
+		util.ReturnHTTPError(rw, req, 401, "Unauthorized")
+		return
+	}
+
+	userName := req.URL.Query().Get("user")
+	if userName == "" {
+		userName = requester
+	}
+	isAdmin, err := sar.IsAdmin(req, h.sarClient)
+	if err != nil {
+		util.ReturnHTTPError(rw, req, 500, err.Error())
+		return
+	}
+	if userName != requester && !isAdmin {
+		util.ReturnHTTPError(rw, req, 403, "Forbidden")
+		return
+	}
+
+	clusterID := mux.Vars(req)["clusterID"]
+	canGet := isAdmin
+	if !canGet {
+		canGet, err = sar.UserCanDo(req.Context(), h.sarClient, requester, req.Header["Impersonate-Group"], authzv1.ResourceAttributes{
+			Verb:     "get",
+			Group:    v3.ClusterGroupVersionKind.Group,
+			Resource: v3.ClusterResource.Name,
+			Name:     clusterID,
+		})
+		if err != nil {
+			util.ReturnHTTPError(rw, req, 500, err.Error())
+			return
+		}
+	}
+	if !canGet {
+		util.ReturnHTTPError(rw, req, 404, "cluster not found")
+		return
+	}
+	if _, err := h.clusterLister.Get("", clusterID); apierrors.IsNotFound(err) {
+		util.ReturnHTTPError(rw, req, 404, "cluster not found")
+		return
+	} else if err != nil {
+		util.ReturnHTTPError(rw, req, 500, err.Error())
+		return
+	}
+
+	permissions, err := h.resolver.Resolve(userName, clusterID)
+	if err != nil {
+		util.ReturnHTTPError(rw, req, 500, err.Error())
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(permissions); err != nil {
+		util.ReturnHTTPError(rw, req, 500, err.Error())
+	}
+}
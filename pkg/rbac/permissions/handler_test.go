@@ -0,0 +1,60 @@
+package permissions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/requests/sar/fake"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestHandlerHidesClusters(t *testing.T) {
+	h := &handler{
+		resolver: newTestResolver(nil, []*v3.ClusterRoleTemplateBinding{crtb("member", "u-1", "view", false)}, nil),
+		clusterLister: &fakes.ClusterListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Cluster, error) {
+				if name == "c-1" || name == "c-2" {
+					return &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "clusters"}, name)
+			},
+		},
+		sarClient: &fake.SubjectAccessReviews{
+			Admins:  map[string]bool{"u-admin": true},
+			Allowed: map[string][]string{"u-1": {"clusters//c-1"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		requester string
+		user      string
+		clusterID string
+		code      int
+	}{
+		{name: "own permissions", requester: "u-1", clusterID: "c-1", code: http.StatusOK},
+		{name: "cluster the user can't get", requester: "u-1", clusterID: "c-2", code: http.StatusNotFound},
+		{name: "missing cluster", requester: "u-1", clusterID: "c-missing", code: http.StatusNotFound},
+		{name: "other user", requester: "u-1", user: "u-2", clusterID: "c-1", code: http.StatusForbidden},
+		{name: "other user on a missing cluster", requester: "u-1", user: "u-2", clusterID: "c-missing", code: http.StatusForbidden},
+		{name: "admin", requester: "u-admin", user: "u-1", clusterID: "c-2", code: http.StatusOK},
+		{name: "admin on a missing cluster", requester: "u-admin", user: "u-1", clusterID: "c-missing", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v3/effectivepermissions/"+tt.clusterID+"?user="+tt.user, nil)
+			req.Header.Set("Impersonate-User", tt.requester)
+			req = mux.SetURLVars(req, map[string]string{"clusterID": tt.clusterID})
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package permissions
+
+import (
+	"sort"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	ScopeGlobal  = "global"
+	ScopeCluster = "cluster"
+	ScopeProject = "project"
+
+	KindGlobalRoleBinding          = "GlobalRoleBinding"
+	KindClusterRoleTemplateBinding = "ClusterRoleTemplateBinding"
+	KindProjectRoleTemplateBinding = "ProjectRoleTemplateBinding"
+)
+
+// Rule is a policy rule granted to a user together with where it came from
+type Rule struct {
+	rbacv1.PolicyRule `json:",inline"`
+
+	// Scope is global, cluster or project
+	Scope string `json:"scope"`
+	// Project is the project the rule applies to for project scoped rules
+	Project string `json:"project,omitempty"`
+	// BindingKind and Binding identify the binding granting the rule, in the namespace:name format for role template bindings
+	BindingKind string `json:"bindingKind"`
+	Binding     string `json:"binding"`
+	// Subject is the user or group principal the binding is for
+	Subject string `json:"subject"`
+	// Role is the global role or role template declaring the rule
+	Role string `json:"role"`
+	// InheritedFrom is the chain of role templates from the bound role template down to the one declaring the rule,
+	// it is empty when the bound role template declares the rule itself
+	InheritedFrom []string `json:"inheritedFrom,omitempty"`
+	// Locked is true when the role template declaring the rule is locked, existing bindings keep granting its rules
+	Locked bool `json:"locked,omitempty"`
+}
+
+// EffectivePermissions are the flattened rules granted to a user on a cluster
+type EffectivePermissions struct {
+	User    string `json:"user"`
+	Cluster string `json:"cluster"`
+	Rules   []Rule `json:"rules"`
+	// Unresolved lists role templates whose rules could not be resolved: external role templates whose rules live in
+	// the downstream cluster, missing role templates and role templates inheriting themselves
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// Resolver resolves the effective permissions of users from the global role bindings, cluster and project role
+// template bindings and role template inheritance.
+type Resolver struct {
+	grbLister           v3.GlobalRoleBindingLister
+	grLister            v3.GlobalRoleLister
+	crtbLister          v3.ClusterRoleTemplateBindingLister
+	prtbLister          v3.ProjectRoleTemplateBindingLister
+	rtLister            v3.RoleTemplateLister
+	userAttributeLister v3.UserAttributeLister
+}
+
+func NewResolver(management v3.Interface) *Resolver {
+	return &Resolver{
+		grbLister:           management.GlobalRoleBindings("").Controller().Lister(),
+		grLister:            management.GlobalRoles("").Controller().Lister(),
+		crtbLister:          management.ClusterRoleTemplateBindings("").Controller().Lister(),
+		prtbLister:          management.ProjectRoleTemplateBindings("").Controller().Lister(),
+		rtLister:            management.RoleTemplates("").Controller().Lister(),
+		userAttributeLister: management.UserAttributes("").Controller().Lister(),
+	}
+}
+
+// Resolve returns the rules granted to the user on the cluster by bindings to the user or to one of its groups
+func (r *Resolver) Resolve(userName, clusterName string) (*EffectivePermissions, error) {
+	subjects, err := r.subjects(userName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EffectivePermissions{
+		User:    userName,
+		Cluster: clusterName,
+		Rules:   []Rule{},
+	}
+	unresolved := map[string]bool{}
+
+	grbs, err := r.grbLister.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, grb := range grbs {
+		subject := grb.UserName
+		if subject == "" {
+			subject = grb.GroupPrincipalName
+		}
+		if !subjects[subject] {
+			continue
+		}
+		gr, err := r.grLister.Get("", grb.GlobalRoleName)
+		if apierrors.IsNotFound(err) {
+			unresolved[grb.GlobalRoleName] = true
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, rule := range gr.Rules {
+			result.Rules = append(result.Rules, Rule{
+				PolicyRule:  rule,
+				Scope:       ScopeGlobal,
+				BindingKind: KindGlobalRoleBinding,
+				Binding:     grb.Name,
+				Subject:     subject,
+				Role:        gr.Name,
+			})
+		}
+	}
+
+	crtbs, err := r.crtbLister.List(clusterName, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, crtb := range crtbs {
+		subject := crtb.UserName
+		if subject == "" {
+			subject = crtb.GroupPrincipalName
+		}
+		if crtb.ClusterName != clusterName || !subjects[subject] {
+			continue
+		}
+		binding := Rule{
+			Scope:       ScopeCluster,
+			BindingKind: KindClusterRoleTemplateBinding,
+			Binding:     crtb.Namespace + ":" + crtb.Name,
+			Subject:     subject,
+		}
+		rules, err := r.roleTemplateRules(crtb.RoleTemplateName, binding, unresolved)
+		if err != nil {
+			return nil, err
+		}
+		result.Rules = append(result.Rules, rules...)
+	}
+
+	prtbs, err := r.prtbLister.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, prtb := range prtbs {
+		subject := prtb.UserName
+		if subject == "" {
+			subject = prtb.GroupPrincipalName
+		}
+		if !strings.HasPrefix(prtb.ProjectName, clusterName+":") || !subjects[subject] {
+			continue
+		}
+		binding := Rule{
+			Scope:       ScopeProject,
+			Project:     prtb.ProjectName,
+			BindingKind: KindProjectRoleTemplateBinding,
+			Binding:     prtb.Namespace + ":" + prtb.Name,
+			Subject:     subject,
+		}
+		rules, err := r.roleTemplateRules(prtb.RoleTemplateName, binding, unresolved)
+		if err != nil {
+			return nil, err
+		}
+		result.Rules = append(result.Rules, rules...)
+	}
+
+	for name := range unresolved {
+		result.Unresolved = append(result.Unresolved, name)
+	}
+	sort.Strings(result.Unresolved)
+	return result, nil
+}
+
+// subjects returns the user and the group principals of the user
+func (r *Resolver) subjects(userName string) (map[string]bool, error) {
+	subjects := map[string]bool{
+		userName: true,
+	}
+	attribs, err := r.userAttributeLister.Get("", userName)
+	if apierrors.IsNotFound(err) {
+		return subjects, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, principals := range attribs.GroupPrincipals {
+		for _, principal := range principals.Items {
+			subjects[principal.Name] = true
+		}
+	}
+	return subjects, nil
+}
+
+// roleTemplateRules returns the rules of the role template and the role templates it inherits, each role template is
+// visited once per binding so inheritance cycles and shared parents don't repeat rules.
+func (r *Resolver) roleTemplateRules(name string, binding Rule, unresolved map[string]bool) ([]Rule, error) {
+	var rules []Rule
+	visited := map[string]bool{}
+
+	var walk func(name string, chain []string) error
+	walk = func(name string, chain []string) error {
+		if visited[name] {
+			for _, parent := range chain {
+				if parent == name {
+					logrus.Debugf("[effective-permissions] role template [%s] inherits itself through %v", name, chain)
+					unresolved[name] = true
+				}
+			}
+			return nil
+		}
+		visited[name] = true
+
+		rt, err := r.rtLister.Get("", name)
+		if apierrors.IsNotFound(err) {
+			unresolved[name] = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if rt.External {
+			unresolved[name] = true
+		}
+
+		var inheritedFrom []string
+		if len(chain) > 0 {
+			inheritedFrom = append(append([]string{}, chain...), name)
+		}
+		for _, policyRule := range rt.Rules {
+			rule := binding
+			rule.PolicyRule = policyRule
+			rule.Role = name
+			rule.InheritedFrom = inheritedFrom
+			rule.Locked = rt.Locked
+			rules = append(rules, rule)
+		}
+
+		for _, parent := range rt.RoleTemplateNames {
+			if err := walk(parent, append(append([]string{}, chain...), name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return rules, walk(name, nil)
+}
@@ -0,0 +1,218 @@
+package permissions
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func rule(resource string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{resource}, Verbs: []string{"get"}}
+}
+
+func roleTemplate(name string, rules []rbacv1.PolicyRule, inherits ...string) *v3.RoleTemplate {
+	return &v3.RoleTemplate{
+		ObjectMeta:        metav1.ObjectMeta{Name: name},
+		Rules:             rules,
+		RoleTemplateNames: inherits,
+	}
+}
+
+var (
+	roleTemplates = map[string]*v3.RoleTemplate{
+		"view":          roleTemplate("view", []rbacv1.PolicyRule{rule("pods")}),
+		"edit":          roleTemplate("edit", []rbacv1.PolicyRule{rule("deployments")}, "view"),
+		"project-owner": roleTemplate("project-owner", []rbacv1.PolicyRule{rule("projectroletemplatebindings")}, "edit", "view"),
+		"locked": func() *v3.RoleTemplate {
+			rt := roleTemplate("locked", []rbacv1.PolicyRule{rule("nodes")})
+			rt.Locked = true
+			return rt
+		}(),
+		"external": func() *v3.RoleTemplate {
+			rt := roleTemplate("external", nil)
+			rt.External = true
+			return rt
+		}(),
+		"cycle-a": roleTemplate("cycle-a", []rbacv1.PolicyRule{rule("secrets")}, "cycle-b"),
+		"cycle-b": roleTemplate("cycle-b", []rbacv1.PolicyRule{rule("configmaps")}, "cycle-a"),
+	}
+	globalRoles = map[string]*v3.GlobalRole{
+		"user": {ObjectMeta: metav1.ObjectMeta{Name: "user"}, Rules: []rbacv1.PolicyRule{rule("settings")}},
+	}
+)
+
+func newTestResolver(grbs []*v3.GlobalRoleBinding, crtbs []*v3.ClusterRoleTemplateBinding, prtbs []*v3.ProjectRoleTemplateBinding) *Resolver {
+	return &Resolver{
+		grbLister: &fakes.GlobalRoleBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.GlobalRoleBinding, error) {
+				return grbs, nil
+			},
+		},
+		grLister: &fakes.GlobalRoleListerMock{
+			GetFunc: func(namespace string, name string) (*v3.GlobalRole, error) {
+				if gr, ok := globalRoles[name]; ok {
+					return gr, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "globalroles"}, name)
+			},
+		},
+		crtbLister: &fakes.ClusterRoleTemplateBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.ClusterRoleTemplateBinding, error) {
+				var result []*v3.ClusterRoleTemplateBinding
+				for _, crtb := range crtbs {
+					if crtb.Namespace == namespace {
+						result = append(result, crtb)
+					}
+				}
+				return result, nil
+			},
+		},
+		prtbLister: &fakes.ProjectRoleTemplateBindingListerMock{
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.ProjectRoleTemplateBinding, error) {
+				return prtbs, nil
+			},
+		},
+		rtLister: &fakes.RoleTemplateListerMock{
+			GetFunc: func(namespace string, name string) (*v3.RoleTemplate, error) {
+				if rt, ok := roleTemplates[name]; ok {
+					return rt, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "roletemplates"}, name)
+			},
+		},
+		userAttributeLister: &fakes.UserAttributeListerMock{
+			GetFunc: func(namespace string, name string) (*v3.UserAttribute, error) {
+				if name != "u-1" {
+					return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+				}
+				return &v3.UserAttribute{
+					GroupPrincipals: map[string]v32.Principals{
+						"github": {Items: []v32.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "github_team://1"}}}},
+					},
+				}, nil
+			},
+		},
+	}
+}
+
+func crtb(name, subject, roleTemplateName string, group bool) *v3.ClusterRoleTemplateBinding {
+	binding := &v3.ClusterRoleTemplateBinding{
+		ObjectMeta:       metav1.ObjectMeta{Namespace: "c-1", Name: name},
+		ClusterName:      "c-1",
+		RoleTemplateName: roleTemplateName,
+	}
+	if group {
+		binding.GroupPrincipalName = subject
+	} else {
+		binding.UserName = subject
+	}
+	return binding
+}
+
+func prtb(name, project, roleTemplateName string) *v3.ProjectRoleTemplateBinding {
+	_, namespace := ref.Parse(project)
+	return &v3.ProjectRoleTemplateBinding{
+		ObjectMeta:       metav1.ObjectMeta{Namespace: namespace, Name: name},
+		ProjectName:      project,
+		UserName:         "u-1",
+		RoleTemplateName: roleTemplateName,
+	}
+}
+
+type expectedRule struct {
+	resource      string
+	binding       string
+	role          string
+	inheritedFrom []string
+	locked        bool
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name           string
+		grbs           []*v3.GlobalRoleBinding
+		crtbs          []*v3.ClusterRoleTemplateBinding
+		prtbs          []*v3.ProjectRoleTemplateBinding
+		wantRules      []expectedRule
+		wantUnresolved []string
+	}{
+		{
+			name: "global role binding",
+			grbs: []*v3.GlobalRoleBinding{
+				{ObjectMeta: metav1.ObjectMeta{Name: "grb-1"}, UserName: "u-1", GlobalRoleName: "user"},
+				{ObjectMeta: metav1.ObjectMeta{Name: "grb-2"}, UserName: "u-2", GlobalRoleName: "user"},
+			},
+			wantRules: []expectedRule{
+				{resource: "settings", binding: "grb-1", role: "user"},
+			},
+		},
+		{
+			name:  "inherited role templates",
+			prtbs: []*v3.ProjectRoleTemplateBinding{prtb("prtb-1", "c-1:p-1", "project-owner")},
+			wantRules: []expectedRule{
+				{resource: "projectroletemplatebindings", binding: "p-1:prtb-1", role: "project-owner"},
+				{resource: "deployments", binding: "p-1:prtb-1", role: "edit", inheritedFrom: []string{"project-owner", "edit"}},
+				{resource: "pods", binding: "p-1:prtb-1", role: "view", inheritedFrom: []string{"project-owner", "edit", "view"}},
+			},
+		},
+		{
+			name:  "bindings of other clusters are ignored",
+			prtbs: []*v3.ProjectRoleTemplateBinding{prtb("prtb-1", "c-2:p-2", "view")},
+		},
+		{
+			name:  "locked role template bound to a group of the user",
+			crtbs: []*v3.ClusterRoleTemplateBinding{crtb("crtb-1", "github_team://1", "locked", true)},
+			wantRules: []expectedRule{
+				{resource: "nodes", binding: "c-1:crtb-1", role: "locked", locked: true},
+			},
+		},
+		{
+			name:  "inheritance cycle",
+			crtbs: []*v3.ClusterRoleTemplateBinding{crtb("crtb-1", "u-1", "cycle-a", false)},
+			wantRules: []expectedRule{
+				{resource: "secrets", binding: "c-1:crtb-1", role: "cycle-a"},
+				{resource: "configmaps", binding: "c-1:crtb-1", role: "cycle-b", inheritedFrom: []string{"cycle-a", "cycle-b"}},
+			},
+			wantUnresolved: []string{"cycle-a"},
+		},
+		{
+			name: "external and missing role templates",
+			crtbs: []*v3.ClusterRoleTemplateBinding{
+				crtb("crtb-1", "u-1", "external", false),
+				crtb("crtb-2", "u-1", "missing", false),
+			},
+			wantUnresolved: []string{"external", "missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := newTestResolver(tt.grbs, tt.crtbs, tt.prtbs)
+			permissions, err := resolver.Resolve("u-1", "c-1")
+			require.NoError(t, err)
+
+			var rules []expectedRule
+			for _, r := range permissions.Rules {
+				rules = append(rules, expectedRule{
+					resource:      r.Resources[0],
+					binding:       r.Binding,
+					role:          r.Role,
+					inheritedFrom: r.InheritedFrom,
+					locked:        r.Locked,
+				})
+			}
+			assert.Equal(t, tt.wantRules, rules)
+			assert.Equal(t, tt.wantUnresolved, permissions.Unresolved)
+		})
+	}
+}
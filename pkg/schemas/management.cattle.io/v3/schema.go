@@ -239,12 +239,18 @@ func clusterTypes(schemas *types.Schemas) *types.Schemas {
 			m.Drop{Field: "systemImages"},
 		).
 		MustImport(&Version, v3.Cluster{}).
-		MustImport(&Version, v3.ClusterRegistrationToken{}).
+		MustImportAndCustomize(&Version, v3.ClusterRegistrationToken{}, func(schema *types.Schema) {
+			schema.ResourceActions[v3.ClusterRegistrationTokenActionRegenerateManifest] = types.Action{}
+		}).
 		MustImport(&Version, v3.GenerateKubeConfigOutput{}).
 		MustImport(&Version, v3.ImportClusterYamlInput{}).
 		MustImport(&Version, v3.RotateCertificateInput{}).
 		MustImport(&Version, v3.RotateCertificateOutput{}).
 		MustImport(&Version, v3.RotateEncryptionKeyOutput{}).
+		MustImport(&Version, v3.RotateServiceAccountTokenOutput{}).
+		MustImport(&Version, v3.ResolvedImagesOutput{}).
+		MustImport(&Version, v3.NodeCommandInput{}).
+		MustImport(&Version, v3.NodeCommandOutput{}).
 		MustImport(&Version, v3.ImportYamlOutput{}).
 		MustImport(&Version, v3.ExportOutput{}).
 		MustImport(&Version, v3.MonitoringInput{}).
@@ -303,6 +309,16 @@ func clusterTypes(schemas *types.Schemas) *types.Schemas {
 			schema.ResourceActions[v3.ClusterActionRotateEncryptionKey] = types.Action{
 				Output: "rotateEncryptionKeyOutput",
 			}
+			schema.ResourceActions[v3.ClusterActionRotateServiceAccountToken] = types.Action{
+				Output: "rotateServiceAccountTokenOutput",
+			}
+			schema.ResourceActions[v3.ClusterActionNodeCommand] = types.Action{
+				Input:  "nodeCommandInput",
+				Output: "nodeCommandOutput",
+			}
+			schema.ResourceActions[v3.ClusterActionResolvedImages] = types.Action{
+				Output: "resolvedImagesOutput",
+			}
 			schema.ResourceActions[v3.ClusterActionRunSecurityScan] = types.Action{
 				Input: "cisScanConfig",
 			}
@@ -310,6 +326,10 @@ func clusterTypes(schemas *types.Schemas) *types.Schemas {
 				Input:  "saveAsTemplateInput",
 				Output: "saveAsTemplateOutput",
 			}
+			schema.ResourceActions[v3.ClusterActionSetMembers] = types.Action{
+				Input:  "setMembersInput",
+				Output: "setMembersOutput",
+			}
 		})
 }
 
@@ -329,6 +349,8 @@ func authzTypes(schemas *types.Schemas) *types.Schemas {
 			&mapper.NamespaceIDMapper{},
 		).
 		MustImport(&Version, v3.SetPodSecurityPolicyTemplateInput{}).
+		MustImport(&Version, v3.SetMembersInput{}).
+		MustImport(&Version, v3.SetMembersOutput{}).
 		MustImport(&Version, v3.ImportYamlOutput{}).
 		MustImport(&Version, v3.MonitoringInput{}).
 		MustImport(&Version, v3.MonitoringOutput{}).
@@ -349,6 +371,10 @@ func authzTypes(schemas *types.Schemas) *types.Schemas {
 				"editMonitoring": {
 					Input: "monitoringInput",
 				},
+				"setMembers": {
+					Input:  "setMembersInput",
+					Output: "setMembersOutput",
+				},
 			}
 		}).
 		MustImport(&Version, v3.GlobalRole{}).
@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+const (
+	// UpdatedByAnnotation is set by the API to the user that last updated the setting, bound to the value they set it
+	// to so that the changes made afterwards by anyone else aren't attributed to them
+	UpdatedByAnnotation = "management.cattle.io/setting-updated-by"
+
+	redactedValue = "[redacted]"
+)
+
+// secretPattern matches the names of the settings whose values are credentials or point to them
+var secretPattern = regexp.MustCompile(`(^|-)(secret|password|credential|private-key|token)$`)
+
+// IsSecret returns true if the value of the setting must not show up in logs or events
+func IsSecret(name string) bool {
+	return secretPattern.MatchString(name)
+}
+
+// Redact returns the value of the setting as it can be recorded in the audit trail
+func Redact(name, value string) string {
+	if IsSecret(name) && value != "" {
+		return redactedValue
+	}
+	return value
+}
+
+// UpdatedBy returns the value of the UpdatedByAnnotation recording that the user set the setting to the value
+func UpdatedBy(user, value string) string {
+	return user + ":" + valueHash(value)
+}
+
+// UpdatedByUser returns the user recorded by the UpdatedByAnnotation, or an empty string if the setting was changed
+// since the user set it to its current value.
+func UpdatedByUser(annotation, value string) string {
+	i := strings.LastIndex(annotation, ":")
+	if i < 0 || annotation[i+1:] != valueHash(value) {
+		return ""
+	}
+	return annotation[:i]
+}
+
+func valueHash(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
@@ -19,6 +19,7 @@
 	provider       Provider
 	InjectDefaults string
 
+	AgentHeartbeatStaleThreshold      = NewSetting("agent-heartbeat-stale-threshold", "300") // seconds since the last check-in of a cluster agent before its cluster is marked AgentHeartbeatStale
 	AgentImage                        = NewSetting("agent-image", "rancher/rancher-agent:master-head")
 	AuthImage                         = NewSetting("auth-image", v32.ToolsSystemImages.AuthSystemImages.KubeAPIAuth)
 	AuthTokenMaxTTLMinutes            = NewSetting("auth-token-max-ttl-minutes", "0") // never expire
@@ -29,7 +30,19 @@
 	CLIURLDarwin                      = NewSetting("cli-url-darwin", "https://releases.rancher.com/cli/v1.0.0-alpha8/rancher-darwin-amd64-v1.0.0-alpha8.tar.gz")
 	CLIURLLinux                       = NewSetting("cli-url-linux", "https://releases.rancher.com/cli/v1.0.0-alpha8/rancher-linux-amd64-v1.0.0-alpha8.tar.gz")
 	CLIURLWindows                     = NewSetting("cli-url-windows", "https://releases.rancher.com/cli/v1.0.0-alpha8/rancher-windows-386-v1.0.0-alpha8.zip")
+	ClusterCACertAutoUpdate           = NewSetting("cluster-ca-cert-auto-update", "false") // update the CA cert of a cluster with the one reported by its agent when the cluster CA is rotated
+	ClusterClientBurst                = NewSetting("cluster-client-burst", "200")          // burst of the requests to the API server of downstream clusters
+	ClusterClientQPS                  = NewSetting("cluster-client-qps", "100")            // queries per second to the API server of downstream clusters
 	ClusterControllerStartCount       = NewSetting("cluster-controller-start-count", "50")
+	ClusterExecAuthCommands           = NewSetting("cluster-exec-auth-commands", "")                    // JSON list of the exec credential plugins clusters may authenticate with, see execauth.AllowedCommand
+	ClusterConditionWebhooks          = NewSetting("cluster-condition-webhooks", "")                    // JSON list of the webhooks notified of the provisioning failures of clusters, see clusternotifier.Destination
+	ClusterConditionWebhookReadyDelay = NewSetting("cluster-condition-webhook-ready-delay", "10")       // minutes a cluster stays not ready before the webhooks are notified
+	ClusterPublicAPIDialTimeout       = NewSetting("cluster-public-api-dial-timeout", "30")             // seconds to dial the public API endpoint of hosted clusters before tunneling to it
+	CustomNodeRegistrationTimeout     = NewSetting("custom-node-registration-timeout", "0")             // seconds a custom node may wait to register, 0 disables the timeout
+	ClusterRegistrationTokenTTL       = NewSetting("cluster-registration-token-ttl", "0")               // seconds before cluster registration tokens are rotated, 0 disables the rotation
+	ClusterRegistrationTokenGrace     = NewSetting("cluster-registration-token-rotation-grace", "3600") // seconds the old and new tokens are both accepted while rotating
+	ImportManifestActiveGate          = NewSetting("import-manifest-active-gate", "false")              // stop serving the import manifest of clusters once they are active
+	DebugPprofEnabled                 = NewSetting("debug-pprof-enabled", "false")                      // serve /debug/pprof to admins
 	EngineInstallURL                  = NewSetting("engine-install-url", "https://releases.rancher.com/install-docker/20.10.sh")
 	EngineISOURL                      = NewSetting("engine-iso-url", "https://releases.rancher.com/os/latest/rancheros-vmware.iso")
 	EngineNewestVersion               = NewSetting("engine-newest-version", "v17.12.0")
@@ -52,23 +65,34 @@
 	KubernetesVersionsCurrent         = NewSetting("k8s-versions-current", "")
 	KubernetesVersionsDeprecated      = NewSetting("k8s-versions-deprecated", "")
 	KDMBranch                         = NewSetting("kdm-branch", "dev-v2.6")
+	MachineDriverCPULimit             = NewSetting("machine-driver-cpu-limit", "0")          // CPU seconds a node driver process may use while provisioning a node, 0 is unlimited
+	MachineDriverMemoryLimit          = NewSetting("machine-driver-memory-limit", "0")       // megabytes of address space of a node driver process while provisioning a node, 0 is unlimited
+	MachineDriverTimeout              = NewSetting("machine-driver-timeout", "1800")         // seconds a node driver process may run to provision a node before it is killed, 0 disables the timeout
+	MachinePoolMaxQuantity            = NewSetting("machine-pool-max-quantity", "0")         // machines a machine pool may be scaled to, 0 is unlimited
+	MachinePoolScaleWarningDelta      = NewSetting("machine-pool-scale-warning-delta", "50") // machines a machine pool may be scaled by at once before a warning event is recorded, 0 disables the warning
 	MachineVersion                    = NewSetting("machine-version", "dev")
 	Namespace                         = NewSetting("namespace", os.Getenv("CATTLE_NAMESPACE"))
+	NodeConfigSaveInterval            = NewSetting("node-config-save-interval", "5")           // seconds between node config saves while provisioning
+	NodeProvisionConcurrency          = NewSetting("node-provision-concurrency", "0")          // nodes provisioned at once in a cluster, 0 is unlimited
+	NodeRemoveAppliedSpecTimeout      = NewSetting("node-remove-applied-spec-timeout", "1800") // seconds a removed etcd node waits to leave the applied spec of its cluster before it is deleted anyway, 0 waits forever
 	PeerServices                      = NewSetting("peer-service", os.Getenv("CATTLE_PEER_SERVICE"))
 	RDNSServerBaseURL                 = NewSetting("rdns-base-url", "https://api.lb.rancher.cloud/v1")
 	RkeVersion                        = NewSetting("rke-version", "")
 	RkeMetadataConfig                 = NewSetting("rke-metadata-config", getMetadataConfig())
+	RKETLSDialTimeoutSeconds          = NewSetting("rke-tls-dial-timeout-seconds", "15")
 	ServerImage                       = NewSetting("server-image", "rancher/rancher")
 	ServerURL                         = NewSetting("server-url", "")
 	ServerVersion                     = NewSetting("server-version", "dev")
 	SystemAgentVersion                = NewSetting("system-agent-version", "")
 	SystemAgentInstallScript          = NewSetting("system-agent-install-script", "")
+	SystemAgentInstallScriptURL       = NewSetting("system-agent-install-script-url", "") // overrides the URL of the install script served by Rancher in the RKE2 node commands
 	SystemAgentInstallerImage         = NewSetting("system-agent-installer-image", "docker.io/rancher/system-agent-installer-")
 	SystemAgentUpgradeImage           = NewSetting("system-agent-upgrade-image", "")
 	SystemDefaultRegistry             = NewSetting("system-default-registry", "")
 	SystemNamespaces                  = NewSetting("system-namespaces", "kube-system,kube-public,cattle-system,cattle-alerting,cattle-logging,cattle-pipeline,cattle-prometheus,ingress-nginx,cattle-global-data,cattle-istio,kube-node-lease,cert-manager,cattle-global-nt,security-scan,cattle-fleet-system,calico-system,tigera-operator")
 	TelemetryOpt                      = NewSetting("telemetry-opt", "")
 	TokenHashing                      = NewSetting("token-hashing", "true")
+	TokenReviewAudiences              = NewSetting("token-review-audiences", "") // comma separated, Rancher tokens are valid for any audience if empty
 	TLSMinVersion                     = NewSetting("tls-min-version", "1.2")
 	TLSCiphers                        = NewSetting("tls-ciphers", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305")
 	UIBanners                         = NewSetting("ui-banners", "{}")
@@ -91,6 +115,9 @@
 	AuthUserInfoResyncCron            = NewSetting("auth-user-info-resync-cron", "0 0 * * *")
 	AuthUserSessionTTLMinutes         = NewSetting("auth-user-session-ttl-minutes", "960")   // 16 hours
 	AuthUserInfoMaxAgeSeconds         = NewSetting("auth-user-info-max-age-seconds", "3600") // 1 hour
+	UserRetentionDisableAfter         = NewSetting("user-retention-disable-after", "")       // duration since the last login after which a user is disabled, e.g. 2160h, empty to never disable users
+	UserRetentionDeleteAfter          = NewSetting("user-retention-delete-after", "")        // duration a user disabled by the retention stays disabled before it is deleted, empty to never delete users
+	UserRetentionDryRun               = NewSetting("user-retention-dry-run", "false")        // only report the users the retention would disable or delete
 	APIUIVersion                      = NewSetting("api-ui-version", "1.1.6")                // Please update the CATTLE_API_UI_VERSION in package/Dockerfile when updating the version here.
 	RotateCertsIfExpiringInDays       = NewSetting("rotate-certs-if-expiring-in-days", "7")  // 7 days
 	ClusterTemplateEnforcement        = NewSetting("cluster-template-enforcement", "false")
@@ -99,6 +126,9 @@
 	ChartDefaultBranch                = NewSetting("chart-default-branch", "dev-v2.6")
 	PartnerChartDefaultBranch         = NewSetting("partner-chart-default-branch", "main")
 	RKE2ChartDefaultBranch            = NewSetting("rke2-chart-default-branch", "main")
+	SystemChartsOCIRepository         = NewSetting("system-charts-oci-repository", "")              // oci:// reference the system charts are pulled from instead of the rancher-charts repo, as <reference>/<chart name>
+	SystemChartsOCIReferences         = NewSetting("system-charts-oci-references", "")              // JSON object of chart name to the oci:// reference of the chart, takes precedence over system-charts-oci-repository
+	SystemChartsOCISecret             = NewSetting("system-charts-oci-secret", "")                  // namespace:name of the basic-auth or dockerconfigjson secret holding the credentials of the system charts OCI registry
 	FleetDefaultWorkspaceName         = NewSetting("fleet-default-workspace-name", "fleet-default") // fleetWorkspaceName to assign to clusters with none
 	ShellImage                        = NewSetting("shell-image", "rancher/shell:v0.1.8")
 	IgnoreNodeName                    = NewSetting("ignore-node-name", "") // nodes to ignore when syncing v1.node to v3.node
@@ -107,12 +137,19 @@
 	AKSUpstreamRefresh                = NewSetting("aks-refresh", "300")
 	EKSUpstreamRefreshCron            = NewSetting("eks-refresh-cron", "*/5 * * * *") // EKSUpstreamRefreshCron is deprecated and will be replaced by EKSUpstreamRefresh
 	EKSUpstreamRefresh                = NewSetting("eks-refresh", "300")
+	EKSDefaultCredentialChain         = NewSetting("eks-default-credential-chain", "false") // lets the EKS clusters created by admins without a cloud credential use the default AWS credential chain of Rancher
 	GKEUpstreamRefresh                = NewSetting("gke-refresh", "300")
 	HideLocalCluster                  = NewSetting("hide-local-cluster", "false")
 	MachineProvisionImage             = NewSetting("machine-provision-image", "rancher/machine:v0.15.0-rancher60")
+	MachineProvisionJobTolerations    = NewSetting("machine-provision-job-tolerations", "") // JSON list of tolerations added to the machine provision jobs, in addition to the tolerations of the control plane and etcd taints
 
 	FleetMinVersion          = NewSetting("fleet-min-version", "")
 	RancherWebhookMinVersion = NewSetting("rancher-webhook-min-version", "")
+
+	// AKSOperatorVersion and EKSOperatorVersion pin the operator charts to an exact version, the latest
+	// available version is installed when empty
+	AKSOperatorVersion = NewSetting("aks-operator-version", "")
+	EKSOperatorVersion = NewSetting("eks-operator-version", "")
 )
 
 func FullShellImage() string {
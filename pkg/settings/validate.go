@@ -0,0 +1,100 @@
+package settings
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+)
+
+// Validator returns an error if the value can't be set on its setting
+type Validator func(value string) error
+
+var (
+	validators = map[string]Validator{}
+	lookupHost = net.LookupHost
+)
+
+func init() {
+	RegisterValidator(ServerURL.Name, validateServerURL)
+	RegisterValidator(SystemDefaultRegistry.Name, validateRegistry)
+	for _, setting := range []Setting{AgentImage, AuthImage, MachineProvisionImage, ShellImage, SystemAgentUpgradeImage} {
+		RegisterValidator(setting.Name, validateImage)
+	}
+	for _, setting := range []Setting{AuthTokenMaxTTLMinutes, AuthUserSessionTTLMinutes} {
+		RegisterValidator(setting.Name, validateTTLMinutes)
+	}
+}
+
+// RegisterValidator registers a validator run on the values the setting is updated to through the API. It replaces
+// the validator registered earlier for the setting, if any.
+func RegisterValidator(name string, validator Validator) {
+	validators[name] = validator
+}
+
+// Validate runs the validator registered for the setting on its new value. An empty value resets the setting to its
+// default and is always valid.
+func Validate(name, value string) error {
+	validator, ok := validators[name]
+	if !ok || value == "" {
+		return nil
+	}
+	if err := validator(value); err != nil {
+		return fmt.Errorf("invalid value for %s: %v", name, err)
+	}
+	return nil
+}
+
+func validateServerURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%s must be an https URL", value)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%s has no host", value)
+	}
+	if net.ParseIP(u.Hostname()) != nil {
+		return nil
+	}
+	if _, err := lookupHost(u.Hostname()); err != nil {
+		return fmt.Errorf("host of %s can't be resolved: %v", value, err)
+	}
+	return nil
+}
+
+func validateRegistry(value string) error {
+	if strings.Contains(value, "://") {
+		return fmt.Errorf("%s must be a registry host without a scheme", value)
+	}
+	// the images are pulled from the registry prefixed to their name, so the registry has to be the domain of the result
+	named, err := reference.ParseNormalizedNamed(value + "/rancher/rancher-agent")
+	if err != nil {
+		return err
+	}
+	if domain := reference.Domain(named); domain != strings.Split(value, "/")[0] {
+		return fmt.Errorf("%s is not a registry host", value)
+	}
+	return nil
+}
+
+func validateImage(value string) error {
+	_, err := reference.ParseNormalizedNamed(value)
+	return err
+}
+
+func validateTTLMinutes(value string) error {
+	ttl, err := time.ParseDuration(value + "m")
+	if err != nil {
+		return fmt.Errorf("%s is not a number of minutes", value)
+	}
+	if ttl < 0 {
+		return fmt.Errorf("%s can't be negative", value)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateServerURL(t *testing.T) {
+	defer func(original func(string) ([]string, error)) { lookupHost = original }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		if host == "rancher.example.com" {
+			return []string{"10.0.0.1"}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+
+	assert.NoError(t, Validate(ServerURL.Name, "https://rancher.example.com"))
+	assert.NoError(t, Validate(ServerURL.Name, "https://10.0.0.1:8443"))
+	assert.NoError(t, Validate(ServerURL.Name, ""), "expected an empty value resetting the setting to be valid")
+	assert.Error(t, Validate(ServerURL.Name, "http://rancher.example.com"), "expected the server url to require https")
+	assert.Error(t, Validate(ServerURL.Name, "rancher.example.com"))
+	assert.Error(t, Validate(ServerURL.Name, "https://"))
+	assert.Error(t, Validate(ServerURL.Name, "https://unknown.example.com"), "expected the host of the server url to resolve")
+}
+
+func TestValidateSystemDefaultRegistry(t *testing.T) {
+	for _, registry := range []string{"registry.example.com", "registry.example.com:5000", "localhost:5000", "registry.example.com/mirror"} {
+		assert.NoError(t, Validate(SystemDefaultRegistry.Name, registry), registry)
+	}
+	for _, registry := range []string{"https://registry.example.com", "registry", "Registry.example.com/UPPER", "registry.example.com:port"} {
+		assert.Error(t, Validate(SystemDefaultRegistry.Name, registry), registry)
+	}
+}
+
+func TestValidateImages(t *testing.T) {
+	for _, setting := range []Setting{AgentImage, AuthImage, MachineProvisionImage, ShellImage, SystemAgentUpgradeImage} {
+		assert.NoError(t, Validate(setting.Name, "rancher/rancher-agent:v2.6.0"), setting.Name)
+		assert.NoError(t, Validate(setting.Name, "registry.example.com:5000/rancher/rancher-agent@sha256:"+
+			"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"), setting.Name)
+		assert.Error(t, Validate(setting.Name, "rancher/rancher-agent:v2.6.0:latest"), setting.Name)
+		assert.Error(t, Validate(setting.Name, "Rancher/Agent"), setting.Name)
+	}
+}
+
+func TestValidateTTLMinutes(t *testing.T) {
+	for _, setting := range []Setting{AuthTokenMaxTTLMinutes, AuthUserSessionTTLMinutes} {
+		assert.NoError(t, Validate(setting.Name, "0"), setting.Name)
+		assert.NoError(t, Validate(setting.Name, "960"), setting.Name)
+		assert.Error(t, Validate(setting.Name, "-1"), setting.Name)
+		assert.Error(t, Validate(setting.Name, "16h"), setting.Name)
+	}
+}
+
+func TestRegisterValidator(t *testing.T) {
+	defer delete(validators, "test-setting")
+	assert.NoError(t, Validate("test-setting", "anything"), "expected a setting without validator to accept any value")
+
+	RegisterValidator("test-setting", func(value string) error {
+		if value != "valid" {
+			return fmt.Errorf("not valid")
+		}
+		return nil
+	})
+	assert.NoError(t, Validate("test-setting", "valid"))
+	assert.EqualError(t, Validate("test-setting", "other"), "invalid value for test-setting: not valid")
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, redactedValue, Redact(SystemChartsOCISecret.Name, "my-secret"))
+	assert.Equal(t, redactedValue, Redact("github-client-password", "hunter2"))
+	assert.Equal(t, "", Redact("github-client-password", ""))
+	assert.Equal(t, "60", Redact(AuthTokenMaxTTLMinutes.Name, "60"), "expected settings about tokens not to be redacted")
+	assert.Equal(t, "https://rancher.example.com", Redact(ServerURL.Name, "https://rancher.example.com"))
+}
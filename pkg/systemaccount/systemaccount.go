@@ -2,6 +2,7 @@
 
 import (
 	"fmt"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
@@ -11,6 +12,7 @@
 	"github.com/rancher/rancher/pkg/types/config/systemtokens"
 	"github.com/rancher/rancher/pkg/user"
 	"github.com/rancher/wrangler/pkg/randomtoken"
+	"github.com/sirupsen/logrus"
 	errors2 "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -90,12 +92,13 @@ func (s *Manager) GetSystemUser(clusterName string) (*v3.User, error) {
 	return s.userManager.EnsureUser(fmt.Sprintf("system://%s", clusterName), ClusterSystemAccountPrefix+clusterName)
 }
 
+// GetOrCreateSystemClusterToken returns the token agents deployed for the cluster should be configured with. The system
+// registration token of the cluster is created if it doesn't exist and its token is regenerated if it isn't usable, so
+// that agents are never deployed with a dead token.
 func (s *Manager) GetOrCreateSystemClusterToken(clusterName string) (string, error) {
-	token := ""
-
 	crt, err := s.crts.GetNamespaced(clusterName, "system", v1.GetOptions{})
 	if errors2.IsNotFound(err) {
-		token, err = randomtoken.Generate()
+		token, err := randomtoken.Generate()
 		if err != nil {
 			return "", err
 		}
@@ -108,22 +111,60 @@ func (s *Manager) GetOrCreateSystemClusterToken(clusterName string) (string, err
 				ClusterName: clusterName,
 			},
 			Status: v32.ClusterRegistrationTokenStatus{
-				Token: token,
+				Token:         token,
+				TokenIssuedAt: time.Now().UTC().Format(time.RFC3339),
 			},
 		}
 
 		if _, err := s.crts.Create(crt); err != nil {
 			return "", err
 		}
+		return token, nil
 	} else if err != nil {
 		return "", err
-	} else {
-		token = crt.Status.Token
 	}
 
+	if crt.DeletionTimestamp != nil {
+		return "", fmt.Errorf("system cluster registration token of cluster [%s] is being deleted", clusterName)
+	}
+	if token := crt.AgentToken(); token != "" && crt.Spec.ClusterName == clusterName {
+		return token, nil
+	}
+
+	return s.regenerateSystemClusterToken(crt, clusterName)
+}
+
+// regenerateSystemClusterToken replaces the token of a system registration token which isn't usable, any rotation in
+// progress is dropped since the token it rotates is unusable as well
+func (s *Manager) regenerateSystemClusterToken(crt *v3.ClusterRegistrationToken, clusterName string) (string, error) {
+	token, err := randomtoken.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	crt = crt.DeepCopy()
+	crt.Spec.ClusterName = clusterName
+	crt.Status.Token = token
+	crt.Status.TokenIssuedAt = time.Now().UTC().Format(time.RFC3339)
+	crt.Status.NextToken = ""
+	crt.Status.NextTokenIssuedAt = ""
+
+	if _, err := s.crts.Update(crt); err != nil {
+		return "", err
+	}
+	logrus.Infof("[systemaccount] regenerated the unusable system cluster registration token of cluster [%s]", clusterName)
 	return token, nil
 }
 
+// RemoveSystemClusterToken deletes the registration token created by GetOrCreateSystemClusterToken for the cluster,
+// it is a no-op if the token is already deleted
+func (s *Manager) RemoveSystemClusterToken(clusterName string) error {
+	if err := s.crts.DeleteNamespaced(clusterName, "system", &v1.DeleteOptions{}); err != nil && !errors2.IsNotFound(err) && !errors2.IsGone(err) {
+		return err
+	}
+	return nil
+}
+
 func (s *Manager) GetOrCreateProjectSystemAccount(projectID string) error {
 	_, projectName := ref.Parse(projectID)
 
@@ -0,0 +1,177 @@
+package systemaccount
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/rancher/rancher/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeUserManager struct {
+	user.Manager
+	users map[string]*v3.User
+}
+
+func (f fakeUserManager) GetUserByPrincipalID(principalName string) (*v3.User, error) {
+	return f.users[principalName], nil
+}
+
+func crtsMock(crts map[string]*v3.ClusterRegistrationToken) *fakes.ClusterRegistrationTokenInterfaceMock {
+	return &fakes.ClusterRegistrationTokenInterfaceMock{
+		GetNamespacedFunc: func(namespace, name string, opts metav1.GetOptions) (*v3.ClusterRegistrationToken, error) {
+			if crt, ok := crts[namespace+"/"+name]; ok {
+				return crt, nil
+			}
+			return nil, apierror.NewNotFound(schema.GroupResource{Resource: "clusterregistrationtokens"}, name)
+		},
+		CreateFunc: func(crt *v3.ClusterRegistrationToken) (*v3.ClusterRegistrationToken, error) {
+			crts[crt.Namespace+"/"+crt.Name] = crt
+			return crt, nil
+		},
+		UpdateFunc: func(crt *v3.ClusterRegistrationToken) (*v3.ClusterRegistrationToken, error) {
+			crts[crt.Namespace+"/"+crt.Name] = crt
+			return crt, nil
+		},
+	}
+}
+
+func TestGetOrCreateSystemClusterToken(t *testing.T) {
+	crts := map[string]*v3.ClusterRegistrationToken{
+		"c-abcde/system": {
+			ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "system"},
+			Spec:       v32.ClusterRegistrationTokenSpec{ClusterName: "c-abcde"},
+			Status:     v32.ClusterRegistrationTokenStatus{Token: "valid"},
+		},
+	}
+	mock := crtsMock(crts)
+	m := &Manager{crts: mock}
+
+	token, err := m.GetOrCreateSystemClusterToken("c-abcde")
+	require.NoError(t, err)
+	assert.Equal(t, "valid", token)
+	assert.Empty(t, mock.CreateCalls())
+	assert.Empty(t, mock.UpdateCalls(), "expected a usable token to be left as is")
+
+	crts["c-abcde/system"].Status.NextToken = "next"
+	token, err = m.GetOrCreateSystemClusterToken("c-abcde")
+	require.NoError(t, err)
+	assert.Equal(t, "next", token, "expected agents to be deployed with the next token while the token is rotated")
+}
+
+func TestGetOrCreateSystemClusterTokenMissing(t *testing.T) {
+	crts := map[string]*v3.ClusterRegistrationToken{}
+	mock := crtsMock(crts)
+	m := &Manager{crts: mock}
+
+	token, err := m.GetOrCreateSystemClusterToken("c-abcde")
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	require.Len(t, mock.CreateCalls(), 1)
+	assert.Equal(t, token, crts["c-abcde/system"].Status.Token)
+	assert.Equal(t, "c-abcde", crts["c-abcde/system"].Spec.ClusterName)
+
+	again, err := m.GetOrCreateSystemClusterToken("c-abcde")
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+	assert.Len(t, mock.CreateCalls(), 1, "expected the created token to be reused")
+}
+
+func TestGetOrCreateSystemClusterTokenInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		crt  *v3.ClusterRegistrationToken
+	}{
+		{
+			name: "blank token",
+			crt: &v3.ClusterRegistrationToken{
+				Spec: v32.ClusterRegistrationTokenSpec{ClusterName: "c-abcde"},
+			},
+		},
+		{
+			name: "other cluster",
+			crt: &v3.ClusterRegistrationToken{
+				Spec:   v32.ClusterRegistrationTokenSpec{ClusterName: "c-other"},
+				Status: v32.ClusterRegistrationTokenStatus{Token: "other"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.crt.ObjectMeta = metav1.ObjectMeta{Namespace: "c-abcde", Name: "system"}
+			crts := map[string]*v3.ClusterRegistrationToken{"c-abcde/system": tt.crt}
+			mock := crtsMock(crts)
+			m := &Manager{crts: mock}
+
+			token, err := m.GetOrCreateSystemClusterToken("c-abcde")
+			require.NoError(t, err)
+			assert.NotEmpty(t, token)
+			assert.NotEqual(t, tt.crt.Status.Token, token)
+			require.Len(t, mock.UpdateCalls(), 1)
+			assert.Equal(t, token, crts["c-abcde/system"].Status.Token)
+			assert.Equal(t, "c-abcde", crts["c-abcde/system"].Spec.ClusterName)
+			assert.Empty(t, mock.CreateCalls())
+		})
+	}
+}
+
+func TestGetOrCreateSystemClusterTokenDeleting(t *testing.T) {
+	now := metav1.Now()
+	crts := map[string]*v3.ClusterRegistrationToken{
+		"c-abcde/system": {
+			ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: "system", DeletionTimestamp: &now},
+			Spec:       v32.ClusterRegistrationTokenSpec{ClusterName: "c-abcde"},
+			Status:     v32.ClusterRegistrationTokenStatus{Token: "deleted"},
+		},
+	}
+	m := &Manager{crts: crtsMock(crts)}
+
+	_, err := m.GetOrCreateSystemClusterToken("c-abcde")
+	assert.EqualError(t, err, "system cluster registration token of cluster [c-abcde] is being deleted")
+}
+
+func TestRemoveSystemClusterToken(t *testing.T) {
+	crts := map[string]bool{"c-abcde/system": true}
+	m := &Manager{
+		crts: &fakes.ClusterRegistrationTokenInterfaceMock{
+			DeleteNamespacedFunc: func(namespace, name string, options *metav1.DeleteOptions) error {
+				if !crts[namespace+"/"+name] {
+					return apierror.NewNotFound(schema.GroupResource{Resource: "clusterregistrationtokens"}, name)
+				}
+				delete(crts, namespace+"/"+name)
+				return nil
+			},
+		},
+	}
+
+	assert.NoError(t, m.RemoveSystemClusterToken("c-abcde"))
+	assert.Empty(t, crts)
+	assert.NoError(t, m.RemoveSystemClusterToken("c-abcde"), "expected the removal of a deleted token to succeed")
+}
+
+func TestRemoveSystemAccount(t *testing.T) {
+	systemUser := &v3.User{ObjectMeta: metav1.ObjectMeta{Name: "u-abcde"}}
+	users := &fakes.UserInterfaceMock{
+		DeleteFunc: func(name string, options *metav1.DeleteOptions) error {
+			return apierror.NewNotFound(schema.GroupResource{Resource: "users"}, name)
+		},
+	}
+	m := &Manager{
+		userManager: fakeUserManager{users: map[string]*v3.User{"system://c-abcde": systemUser}},
+		users:       users,
+	}
+
+	assert.NoError(t, m.RemoveSystemAccount("c-abcde"), "expected a user deleted in the meantime to be ignored")
+	assert.Len(t, users.DeleteCalls(), 1)
+
+	m.userManager = fakeUserManager{}
+	assert.NoError(t, m.RemoveSystemAccount("c-abcde"))
+	assert.Len(t, users.DeleteCalls(), 1, "expected no deletion once the user is removed")
+}
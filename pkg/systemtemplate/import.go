@@ -43,8 +43,16 @@ type context struct {
 	IsRKE                 bool
 	PrivateRegistryConfig string
 	Tolerations           string
+	Preflight             bool
+	PreflightConfigMap    string
+	PreflightMaxClockSkew int
+
+	PreflightCheckedAtAnnotation string
 }
 
+// preflightMaxClockSkew is the clock skew in seconds tolerated by the pre-flight check
+const preflightMaxClockSkew = 60
+
 var (
 	staticFeatures = features.MCM.Name() + "=false," +
 		features.MCMAgent.Name() + "=true," +
@@ -77,7 +85,10 @@ func toFeatureString(features map[string]bool) string {
 	return buf.String()
 }
 
-func SystemTemplate(resp io.Writer, agentImage, authImage, namespace, token, url string, isWindowsCluster bool,
+// SystemTemplate renders the manifest deploying the agents of an imported or provisioned cluster. When preflight is
+// true, a Job checking DNS, connectivity, CA and clock skew against the server URL is added to the manifest, its
+// results are written to the pod log and to the PreflightConfigMapName ConfigMap.
+func SystemTemplate(resp io.Writer, agentImage, authImage, namespace, token, url string, isWindowsCluster, preflight bool,
 	cluster *v3.Cluster, features map[string]bool, taints []corev1.Taint) error {
 	var tolerations, agentEnvVars string
 	d := md5.Sum([]byte(url + token + namespace))
@@ -96,8 +107,8 @@ func SystemTemplate(resp io.Writer, agentImage, authImage, namespace, token, url
 		tolerations = templates.ToYAML(taints)
 	}
 
-	if cluster != nil && len(cluster.Spec.AgentEnvVars) > 0 {
-		agentEnvVars = templates.ToYAML(cluster.Spec.AgentEnvVars)
+	if envVars := LiteralEnvVars(cluster); len(envVars) > 0 {
+		agentEnvVars = templates.ToYAML(envVars)
 	}
 
 	context := &context{
@@ -115,6 +126,11 @@ func SystemTemplate(resp io.Writer, agentImage, authImage, namespace, token, url
 		IsRKE:                 cluster != nil && cluster.Status.Driver == apimgmtv3.ClusterDriverRKE,
 		PrivateRegistryConfig: privateRegistryConfig,
 		Tolerations:           tolerations,
+		Preflight:             preflight,
+		PreflightConfigMap:    PreflightConfigMapName,
+		PreflightMaxClockSkew: preflightMaxClockSkew,
+
+		PreflightCheckedAtAnnotation: PreflightCheckedAtAnnotation,
 	}
 
 	return t.Execute(resp, context)
@@ -136,7 +152,7 @@ func ForCluster(cluster *v3.Cluster, token string) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	err := SystemTemplate(buf, GetDesiredAgentImage(cluster),
 		GetDesiredAuthImage(cluster),
-		cluster.Name, token, settings.ServerURL.Get(), cluster.Spec.WindowsPreferedCluster,
+		cluster.Name, token, settings.ServerURL.Get(), cluster.Spec.WindowsPreferedCluster, false,
 		cluster, GetDesiredFeatures(cluster), nil)
 	return buf.Bytes(), err
 }
@@ -189,3 +205,18 @@ func GetDesiredAuthImage(cluster *v3.Cluster) string {
 	logrus.Tracef("clusterDeploy: deployAgent: desiredAuth is [%s] for cluster [%s]", desiredAuth, cluster.Name)
 	return desiredAuth
 }
+
+// LiteralEnvVars returns the agent env vars of the cluster which don't take their value from a secret, the agents
+// deployed in the cluster can't read the secrets of the management cluster they reference
+func LiteralEnvVars(cluster *v3.Cluster) []corev1.EnvVar {
+	if cluster == nil {
+		return nil
+	}
+	var result []corev1.EnvVar
+	for _, envVar := range cluster.Spec.AgentEnvVars {
+		if envVar.ValueFrom == nil {
+			result = append(result, envVar)
+		}
+	}
+	return result
+}
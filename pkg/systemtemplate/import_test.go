@@ -0,0 +1,65 @@
+package systemtemplate
+
+import (
+	"bytes"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSystemTemplatePreflight(t *testing.T) {
+	tests := []struct {
+		name      string
+		preflight bool
+	}{
+		{name: "with pre-flight", preflight: true},
+		{name: "without pre-flight", preflight: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := SystemTemplate(buf, "rancher/rancher-agent:v2.6.0", "", "", "token", "https://rancher.example.com",
+				false, tt.preflight, nil, nil, nil)
+			require.NoError(t, err)
+
+			manifest := buf.String()
+			assert.Contains(t, manifest, "name: cattle-cluster-agent")
+			if tt.preflight {
+				assert.Contains(t, manifest, "kind: Job")
+				assert.Contains(t, manifest, "name: cattle-preflight-")
+				assert.Contains(t, manifest, "create configmap "+PreflightConfigMapName)
+				assert.Contains(t, manifest, "annotate --local -f - "+PreflightCheckedAtAnnotation+"=")
+				for _, check := range preflightChecks {
+					assert.Contains(t, manifest, "record "+check+" ")
+				}
+			} else {
+				assert.NotContains(t, manifest, "kind: Job")
+				assert.NotContains(t, manifest, "cattle-preflight")
+			}
+		})
+	}
+}
+
+func TestSystemTemplateAgentEnvVarsFromSecrets(t *testing.T) {
+	cluster := &v3.Cluster{Spec: v3.ClusterSpec{ClusterSpecBase: v3.ClusterSpecBase{AgentEnvVars: []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"},
+		{Name: "PROXY_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "proxy"},
+			Key:                  "password",
+		}}},
+	}}}}
+
+	buf := &bytes.Buffer{}
+	err := SystemTemplate(buf, "rancher/rancher-agent:v2.6.0", "", "", "token", "https://rancher.example.com",
+		false, false, cluster, nil, nil)
+	require.NoError(t, err)
+
+	manifest := buf.String()
+	assert.Contains(t, manifest, "name: HTTP_PROXY")
+	assert.NotContains(t, manifest, "PROXY_PASSWORD",
+		"expected the env vars taking their value from a secret of the management cluster to be left out of the agent")
+}
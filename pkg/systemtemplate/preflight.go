@@ -0,0 +1,88 @@
+package systemtemplate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// PreflightConfigMapName is the ConfigMap in the cattle-system namespace the pre-flight Job writes its results to
+	PreflightConfigMapName = "cattle-preflight"
+	// PreflightCheckedAtAnnotation on the pre-flight ConfigMap is the time the checks were run, in RFC 3339
+	PreflightCheckedAtAnnotation = "cattle.io/preflight-checked-at"
+
+	PreflightCheckDNS          = "dns"
+	PreflightCheckConnectivity = "connectivity"
+	PreflightCheckCAChecksum   = "ca-checksum"
+	PreflightCheckClockSkew    = "clock-skew"
+
+	preflightOK   = "ok"
+	preflightFail = "fail"
+)
+
+// preflightChecks is the order the checks are run in, a failing check usually explains the failures after it.
+var preflightChecks = []string{
+	PreflightCheckDNS,
+	PreflightCheckConnectivity,
+	PreflightCheckCAChecksum,
+	PreflightCheckClockSkew,
+}
+
+// PreflightResult is the outcome of a single pre-flight check. Each check is stored in the pre-flight ConfigMap with
+// the check name as key and either "ok" or "fail: <message>" as value.
+type PreflightResult struct {
+	Check   string
+	Passed  bool
+	Message string
+}
+
+// ParsePreflightResults parses the data of the pre-flight ConfigMap, results are returned in the order the checks are run.
+func ParsePreflightResults(data map[string]string) ([]PreflightResult, error) {
+	var checks []string
+	for check := range data {
+		checks = append(checks, check)
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		return preflightCheckIndex(checks[i]) < preflightCheckIndex(checks[j]) ||
+			(preflightCheckIndex(checks[i]) == preflightCheckIndex(checks[j]) && checks[i] < checks[j])
+	})
+
+	var results []PreflightResult
+	for _, check := range checks {
+		value := strings.TrimSpace(data[check])
+		status, message := value, ""
+		if i := strings.Index(value, ":"); i >= 0 {
+			status, message = strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:])
+		}
+
+		switch status {
+		case preflightOK:
+			results = append(results, PreflightResult{Check: check, Passed: true, Message: message})
+		case preflightFail:
+			results = append(results, PreflightResult{Check: check, Message: message})
+		default:
+			return nil, fmt.Errorf("invalid result [%s] for pre-flight check [%s]", value, check)
+		}
+	}
+	return results, nil
+}
+
+// PreflightFailureReason returns a message describing the first failed check, or an empty string if all checks passed.
+func PreflightFailureReason(results []PreflightResult) string {
+	for _, result := range results {
+		if !result.Passed {
+			return fmt.Sprintf("pre-flight check [%s] failed: %s", result.Check, result.Message)
+		}
+	}
+	return ""
+}
+
+func preflightCheckIndex(check string) int {
+	for i, c := range preflightChecks {
+		if c == check {
+			return i
+		}
+	}
+	return len(preflightChecks)
+}
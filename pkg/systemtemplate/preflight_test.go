@@ -0,0 +1,81 @@
+package systemtemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePreflightResults(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       map[string]string
+		expected   []PreflightResult
+		wantReason string
+		wantErr    bool
+	}{
+		{
+			name: "all passed",
+			data: map[string]string{
+				PreflightCheckClockSkew:    "ok",
+				PreflightCheckDNS:          "ok",
+				PreflightCheckConnectivity: "ok",
+				PreflightCheckCAChecksum:   "ok",
+			},
+			expected: []PreflightResult{
+				{Check: PreflightCheckDNS, Passed: true},
+				{Check: PreflightCheckConnectivity, Passed: true},
+				{Check: PreflightCheckCAChecksum, Passed: true},
+				{Check: PreflightCheckClockSkew, Passed: true},
+			},
+		},
+		{
+			name: "first failure is reported",
+			data: map[string]string{
+				PreflightCheckDNS:          "ok",
+				PreflightCheckClockSkew:    "fail: clock differs 120s from https://rancher.example.com, check NTP on the cluster nodes",
+				PreflightCheckConnectivity: "fail: unable to open a TLS connection to https://rancher.example.com",
+			},
+			expected: []PreflightResult{
+				{Check: PreflightCheckDNS, Passed: true},
+				{Check: PreflightCheckConnectivity, Message: "unable to open a TLS connection to https://rancher.example.com"},
+				{Check: PreflightCheckClockSkew, Message: "clock differs 120s from https://rancher.example.com, check NTP on the cluster nodes"},
+			},
+			wantReason: "pre-flight check [connectivity] failed: unable to open a TLS connection to https://rancher.example.com",
+		},
+		{
+			name: "unknown checks are sorted last",
+			data: map[string]string{
+				"proxy":           "fail",
+				PreflightCheckDNS: "ok",
+			},
+			expected: []PreflightResult{
+				{Check: PreflightCheckDNS, Passed: true},
+				{Check: "proxy"},
+			},
+			wantReason: "pre-flight check [proxy] failed: ",
+		},
+		{
+			name:    "invalid status",
+			data:    map[string]string{PreflightCheckDNS: "maybe"},
+			wantErr: true,
+		},
+		{
+			name: "no results",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ParsePreflightResults(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, results)
+			assert.Equal(t, tt.wantReason, PreflightFailureReason(results))
+		})
+	}
+}
@@ -225,6 +225,117 @@
         secret:
           secretName: cattle-credentials-{{.TokenKey}}
           defaultMode: 320
+{{- if .Preflight }}
+
+---
+
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: cattle-preflight-{{.TokenKey}}
+  namespace: cattle-system
+spec:
+  backoffLimit: 0
+  template:
+    metadata:
+      labels:
+        app: cattle-preflight
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+              - matchExpressions:
+                - key: beta.kubernetes.io/os
+                  operator: NotIn
+                  values:
+                    - windows
+      restartPolicy: Never
+      serviceAccountName: cattle
+      tolerations:
+      {{- if .Tolerations }}
+{{ .Tolerations | indent 6 }}
+      {{- else }}
+      - effect: NoSchedule
+        key: node-role.kubernetes.io/controlplane
+        value: "true"
+      - effect: NoSchedule
+        key: "node-role.kubernetes.io/control-plane"
+        operator: "Exists"
+      - effect: NoSchedule
+        key: "node-role.kubernetes.io/master"
+        operator: "Exists"
+      {{- end }}
+      containers:
+        - name: preflight
+          image: {{.AgentImage}}
+          imagePullPolicy: IfNotPresent
+          env:
+          - name: CATTLE_SERVER
+            value: "{{.URLPlain}}"
+          - name: CATTLE_CA_CHECKSUM
+            value: "{{.CAChecksum}}"
+          - name: CATTLE_MAX_CLOCK_SKEW
+            value: "{{.PreflightMaxClockSkew}}"
+          command:
+          - /bin/bash
+          - -c
+          - |
+            results=()
+            record() {
+              echo "preflight $1: $2"
+              results+=("--from-literal=$1=$2")
+            }
+            host=$(echo "${CATTLE_SERVER}" | sed -e 's!^[a-z]*://!!' -e 's![:/].*$!!')
+
+            if getent hosts "${host}" > /dev/null; then
+              record dns ok
+            else
+              record dns "fail: unable to resolve ${host}, check the DNS configuration of the cluster"
+            fi
+
+            if curl --insecure -s -o /dev/null --connect-timeout 10 "${CATTLE_SERVER}/ping"; then
+              record connectivity ok
+            else
+              record connectivity "fail: unable to open a TLS connection to ${CATTLE_SERVER}, check firewalls and proxies between the cluster and Rancher"
+            fi
+
+            if [ -n "${CATTLE_CA_CHECKSUM}" ]; then
+              temp=$(mktemp)
+              curl --insecure -s -fL --connect-timeout 10 "${CATTLE_SERVER}/v3/settings/cacerts" | jq -r '.value | select(length > 0)' > "${temp}"
+              checksum=$(sha256sum "${temp}" | awk '{print $1}')
+              rm -f "${temp}"
+              if [ "${checksum}" = "${CATTLE_CA_CHECKSUM}" ]; then
+                record ca-checksum ok
+              else
+                record ca-checksum "fail: checksum ${checksum} of ${CATTLE_SERVER}/v3/settings/cacerts does not match ${CATTLE_CA_CHECKSUM}"
+              fi
+            elif curl -s -o /dev/null --connect-timeout 10 "${CATTLE_SERVER}/ping"; then
+              record ca-checksum ok
+            else
+              record ca-checksum "fail: certificate of ${CATTLE_SERVER} is not trusted and no CA checksum is configured"
+            fi
+
+            server_date=$(curl --insecure -s -I --connect-timeout 10 "${CATTLE_SERVER}/ping" | grep -i '^date:' | cut -d' ' -f2- | tr -d '\r')
+            if [ -z "${server_date}" ]; then
+              record clock-skew "fail: unable to read the time of ${CATTLE_SERVER}"
+            else
+              skew=$(( $(date +%s) - $(date -d "${server_date}" +%s) ))
+              skew=${skew#-}
+              if [ "${skew}" -le "${CATTLE_MAX_CLOCK_SKEW}" ]; then
+                record clock-skew ok
+              else
+                record clock-skew "fail: clock differs ${skew}s from ${CATTLE_SERVER}, check NTP on the cluster nodes"
+              fi
+            fi
+
+            kubectl -n cattle-system create configmap {{.PreflightConfigMap}} "${results[@]}" --dry-run=client -o yaml | \
+              kubectl annotate --local -f - {{.PreflightCheckedAtAnnotation}}="$(date -u +%Y-%m-%dT%H:%M:%SZ)" -o yaml | kubectl apply -f -
+      {{- if .PrivateRegistryConfig}}
+      imagePullSecrets:
+      - name: cattle-private-registry
+      {{- end }}
+{{- end }}
 {{ if .IsRKE }}
 
 ---
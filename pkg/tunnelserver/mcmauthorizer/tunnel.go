@@ -10,12 +10,14 @@
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/kontainerdriver"
 
 	"github.com/rancher/norman/types/convert"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	util "github.com/rancher/rancher/pkg/cluster"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/taints"
 	"github.com/rancher/rancher/pkg/types/config"
@@ -32,6 +34,14 @@
 
 	Token  = "X-API-Tunnel-Token"
 	Params = "X-API-Tunnel-Params"
+
+	// ReregisteredLabel marks the nodes adopted by another host than the one they were registered by, which ran the
+	// registration command with the same hostname and address
+	ReregisteredLabel = "node.cattle.io/reregistered"
+
+	// preflightFailureReason is the reason of the Waiting condition of the clusters whose agent reported a failed
+	// pre-flight check
+	preflightFailureReason = "PreflightFailure"
 )
 
 var (
@@ -46,9 +56,12 @@
 )
 
 type cluster struct {
-	Address string `json:"address"`
-	Token   string `json:"token"`
-	CACert  string `json:"caCert"`
+	Address            string `json:"address"`
+	Token              string `json:"token"`
+	CACert             string `json:"caCert"`
+	PreflightFailure   string `json:"preflightFailure,omitempty"`
+	PreflightCheckedAt string `json:"preflightCheckedAt,omitempty"`
+	AgentVersion       string `json:"agentVersion,omitempty"`
 }
 
 type input struct {
@@ -66,6 +79,7 @@ func NewAuthorizer(context *config.ScaledContext) *Authorizer {
 		machines:              context.Management.Nodes(""),
 		clusters:              context.Management.Clusters(""),
 		KontainerDriverLister: context.Management.KontainerDrivers("").Controller().Lister(),
+		heartbeats:            util.NewAgentHeartbeats(),
 	}
 	context.Management.ClusterRegistrationTokens("").Controller().Informer().AddIndexers(map[string]cache.IndexFunc{
 		crtKeyIndex: auth.crtIndex,
@@ -84,6 +98,7 @@ type Authorizer struct {
 	machines              v3.NodeInterface
 	clusters              v3.ClusterInterface
 	KontainerDriverLister v3.KontainerDriverLister
+	heartbeats            *util.AgentHeartbeats
 }
 
 type Client struct {
@@ -146,6 +161,9 @@ func (t *Authorizer) Authorize(req *http.Request) (*Client, bool, error) {
 
 	if input.Cluster != nil {
 		cluster, ok, err := t.authorizeCluster(cluster, input.Cluster, req)
+		if ok && err == nil {
+			cluster = t.recordAgentHeartbeat(cluster, input.Cluster.AgentVersion)
+		}
 		return &Client{
 			Cluster: cluster,
 			Token:   token,
@@ -189,6 +207,13 @@ func (t *Authorizer) getMachine(cluster *v3.Cluster, inNode *client.Node) (*v3.N
 
 func (t *Authorizer) authorizeNode(register bool, cluster *v3.Cluster, inNode *client.Node, req *http.Request) (*v3.Node, bool, error) {
 	machine, err := t.getMachine(cluster, inNode)
+	if err == nil && isOtherHost(machine, inNode) {
+		// the hostname is reused by a new host, which gets its own node
+		logrus.Tracef("authorizeNode: node [%s] in cluster [%s] belongs to another host than [%s]", machine.Name, cluster.Name, customAddress(inNode))
+		machine, err = t.getMachineByAddress(cluster, inNode)
+	}
+
+	created := false
 	if apierrors.IsNotFound(err) {
 		if !register {
 			return nil, false, err
@@ -197,12 +222,13 @@ func (t *Authorizer) authorizeNode(register bool, cluster *v3.Cluster, inNode *c
 		if err != nil {
 			return nil, false, err
 		}
+		created = true
 	} else if err != nil && machine == nil {
 		return nil, false, err
 	}
 
 	if register {
-		machine, err = t.updateNode(machine, inNode, cluster)
+		machine, err = t.updateNode(machine, inNode, cluster, !created && adoptedByOtherHost(machine, inNode))
 		if err != nil {
 			return nil, false, err
 		}
@@ -224,6 +250,10 @@ func (t *Authorizer) createNode(inNode *client.Node, cluster *v3.Cluster, req *h
 	}
 
 	name := machineName(inNode)
+	if _, err := t.machineLister.Get(cluster.Name, name); err == nil {
+		// the name of the hostname is taken by the node of another host
+		name = hostMachineName(inNode, customConfig.Address)
+	}
 
 	machine := &v3.Node{
 		ObjectMeta: v1.ObjectMeta{
@@ -262,24 +292,60 @@ func (t *Authorizer) updateDockerInfo(machine *v3.Node, inNode *client.Node) (*v
 	return machine, nil
 }
 
-func (t *Authorizer) updateNode(machine *v3.Node, inNode *client.Node, cluster *v3.Cluster) (*v3.Node, error) {
+func (t *Authorizer) updateNode(machine *v3.Node, inNode *client.Node, cluster *v3.Cluster, adopted bool) (*v3.Node, error) {
 	newMachine := machine.DeepCopy()
-	newMachine.Spec.Etcd = inNode.Etcd
-	newMachine.Spec.ControlPlane = inNode.ControlPlane
-	newMachine.Spec.Worker = inNode.Worker
+	if t.rolesChangeAllowed(machine, inNode, cluster) {
+		newMachine.Spec.Etcd = inNode.Etcd
+		newMachine.Spec.ControlPlane = inNode.ControlPlane
+		newMachine.Spec.Worker = inNode.Worker
+	} else {
+		logrus.Warnf("updateNode: keeping the roles of node [%s] in cluster [%s], it is the last node with the etcd or controlplane role", machine.Name, cluster.Name)
+	}
+	if adopted {
+		logrus.Debugf("updateNode: host [%s] with address [%s] adopted node [%s] in cluster [%s]", inNode.RequestedHostname, customAddress(inNode), machine.Name, cluster.Name)
+		if newMachine.Labels == nil {
+			newMachine.Labels = map[string]string{}
+		}
+		newMachine.Labels[ReregisteredLabel] = "true"
+	}
 	if !reflect.DeepEqual(machine, newMachine) {
 		return t.machines.Update(newMachine)
 	}
 	return machine, nil
 }
 
+// rolesChangeAllowed returns false if the registration drops the etcd or controlplane role of the last node of the
+// cluster with that role
+func (t *Authorizer) rolesChangeAllowed(machine *v3.Node, inNode *client.Node, cluster *v3.Cluster) bool {
+	dropsEtcd := machine.Spec.Etcd && !inNode.Etcd
+	dropsControlPlane := machine.Spec.ControlPlane && !inNode.ControlPlane
+	if !dropsEtcd && !dropsControlPlane {
+		return true
+	}
+
+	machines, err := t.machineLister.List(cluster.Name, labels.Everything())
+	if err != nil {
+		return false
+	}
+	var otherEtcd, otherControlPlane bool
+	for _, other := range machines {
+		if other.Name == machine.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		otherEtcd = otherEtcd || other.Spec.Etcd
+		otherControlPlane = otherControlPlane || other.Spec.ControlPlane
+	}
+	return (!dropsEtcd || otherEtcd) && (!dropsControlPlane || otherControlPlane)
+}
+
 func (t *Authorizer) authorizeCluster(cluster *v3.Cluster, inCluster *cluster, req *http.Request) (*v3.Cluster, bool, error) {
 	var (
 		err error
 	)
 
 	if !importDrivers[cluster.Status.Driver] && cluster.Status.Driver != "" {
-		return cluster, true, nil
+		cluster, err = t.recordAgentCACert(cluster, inCluster.CACert)
+		return cluster, true, err
 	}
 
 	changed := false
@@ -300,6 +366,11 @@ func (t *Authorizer) authorizeCluster(cluster *v3.Cluster, inCluster *cluster, r
 	token := inCluster.Token
 	caCert := inCluster.CACert
 
+	// keep the token rotated by Rancher over the one of the agent
+	if cluster.Annotations[v32.ClusterRotatedServiceAccountTokenAnnotation] == "true" && cluster.Status.ServiceAccountToken != "" {
+		token = cluster.Status.ServiceAccountToken
+	}
+
 	if importDrivers[cluster.Status.Driver] {
 		if cluster.Status.APIEndpoint != apiEndpoint ||
 			cluster.Status.ServiceAccountToken != token ||
@@ -311,11 +382,94 @@ func (t *Authorizer) authorizeCluster(cluster *v3.Cluster, inCluster *cluster, r
 		}
 	}
 
+	if recordPreflightFailure(cluster, inCluster) {
+		changed = true
+	}
+
 	if changed {
-		_, err = t.clusters.Update(cluster)
+		updated, err := t.clusters.Update(cluster)
+		if err != nil {
+			return cluster, true, err
+		}
+		cluster = updated
+	}
+
+	return cluster, true, nil
+}
+
+// recordPreflightFailure surfaces the failed pre-flight check of the import manifest reported by the agent in the Waiting
+// condition until the cluster API is available, and drops it once the agent stops reporting it. Waiting messages set for
+// other reasons are left alone. It returns true if the cluster was changed.
+func recordPreflightFailure(cluster *v3.Cluster, inCluster *cluster) bool {
+	reported := v32.ClusterConditionWaiting.GetReason(cluster) == preflightFailureReason
+	if inCluster.PreflightFailure == "" || v32.ClusterConditionWaiting.IsTrue(cluster) {
+		if !reported {
+			return false
+		}
+		v32.ClusterConditionWaiting.Reason(cluster, "")
+		v32.ClusterConditionWaiting.Message(cluster, "")
+		return true
 	}
 
-	return cluster, true, err
+	message := inCluster.PreflightFailure
+	if inCluster.PreflightCheckedAt != "" {
+		message = fmt.Sprintf("%s (checked at %s)", message, inCluster.PreflightCheckedAt)
+	}
+	if (!reported && v32.ClusterConditionWaiting.GetMessage(cluster) != "") || v32.ClusterConditionWaiting.GetMessage(cluster) == message {
+		return false
+	}
+	logrus.Infof("Cluster [%s] agent reported: %s", cluster.Name, message)
+	v32.ClusterConditionWaiting.Reason(cluster, preflightFailureReason)
+	v32.ClusterConditionWaiting.Message(cluster, message)
+	return true
+}
+
+// recordAgentHeartbeat records the check-in of the cluster agent in the status of the cluster, at most once per
+// util.AgentHeartbeatInterval. A failure to record it doesn't fail the check-in.
+func (t *Authorizer) recordAgentHeartbeat(cluster *v3.Cluster, agentVersion string) *v3.Cluster {
+	lastSeen, due := t.heartbeats.Due(cluster, agentVersion)
+	if !due {
+		return cluster
+	}
+
+	updated := cluster.DeepCopy()
+	updated.Status.AgentLastSeen = lastSeen
+	if agentVersion != "" {
+		updated.Status.AgentVersion = agentVersion
+	}
+	updated, err := t.clusters.Update(updated)
+	if err != nil {
+		logrus.Debugf("Failed to record the agent heartbeat of cluster [%s]: %v", cluster.Name, err)
+		return cluster
+	}
+	return updated
+}
+
+// recordAgentCACert annotates a cluster that isn't imported with the CA cert reported by its agent when it differs from
+// the CA cert in its status. The CA cert in the status is only replaced by the health syncer, once the cluster presents
+// a certificate that no longer matches it.
+func (t *Authorizer) recordAgentCACert(cluster *v3.Cluster, caCert string) (*v3.Cluster, error) {
+	if caCert == "" || cluster.Status.CACert == "" {
+		return cluster, nil
+	}
+
+	_, recorded := cluster.Annotations[v32.ClusterAgentCACertAnnotation]
+	if caCert == cluster.Status.CACert && !recorded ||
+		caCert == cluster.Annotations[v32.ClusterAgentCACertAnnotation] {
+		return cluster, nil
+	}
+
+	cluster = cluster.DeepCopy()
+	if caCert == cluster.Status.CACert {
+		delete(cluster.Annotations, v32.ClusterAgentCACertAnnotation)
+	} else {
+		if cluster.Annotations == nil {
+			cluster.Annotations = map[string]string{}
+		}
+		logrus.Infof("Cluster [%s] agent reported a CA cert different from the one of the cluster", cluster.Name)
+		cluster.Annotations[v32.ClusterAgentCACertAnnotation] = caCert
+	}
+	return t.clusters.Update(cluster)
 }
 
 func (t *Authorizer) readInput(cluster *v3.Cluster, req *http.Request) (*input, error) {
@@ -354,6 +508,54 @@ func (t *Authorizer) readInput(cluster *v3.Cluster, req *http.Request) (*input,
 	return &input, nil
 }
 
+// getMachineByAddress returns the node of the cluster registered by the host with the hostname and address
+func (t *Authorizer) getMachineByAddress(cluster *v3.Cluster, inNode *client.Node) (*v3.Node, error) {
+	machines, err := t.machineLister.List(cluster.Name, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	address := customAddress(inNode)
+	for _, machine := range machines {
+		if machine.Spec.RequestedHostname == inNode.RequestedHostname && machine.Spec.CustomConfig != nil &&
+			machine.Spec.CustomConfig.Address == address {
+			return machine, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(v3.NodeGroupVersionResource.GroupResource(), hostMachineName(inNode, address))
+}
+
+// isOtherHost returns true if the node was registered by a host with another address
+func isOtherHost(machine *v3.Node, inNode *client.Node) bool {
+	address := customAddress(inNode)
+	return address != "" && machine.Spec.CustomConfig != nil && machine.Spec.CustomConfig.Address != "" &&
+		machine.Spec.CustomConfig.Address != address
+}
+
+// adoptedByOtherHost returns true if the node is claimed by another host than the one it was registered by. The host
+// has the hostname and the address of the node, it is another host if the node has no address recorded or another
+// internal address, like when a host is rebuilt. The reruns of the registration command on the host of the node don't
+// adopt it.
+func adoptedByOtherHost(machine *v3.Node, inNode *client.Node) bool {
+	if inNode.CustomConfig == nil {
+		return false
+	}
+	recorded := machine.Spec.CustomConfig
+	return recorded == nil || recorded.Address == "" || recorded.InternalAddress != inNode.CustomConfig.InternalAddress
+}
+
+func customAddress(inNode *client.Node) string {
+	if inNode.CustomConfig == nil {
+		return ""
+	}
+	return inNode.CustomConfig.Address
+}
+
+// hostMachineName is the name of the node of a host whose hostname is already used by the node of another host
+func hostMachineName(machine *client.Node, address string) string {
+	digest := md5.Sum([]byte(machine.RequestedHostname + "/" + address))
+	return fmt.Sprintf("m-%s", hex.EncodeToString(digest[:])[:12])
+}
+
 func machineName(machine *client.Node) string {
 	digest := md5.Sum([]byte(machine.RequestedHostname))
 	machineNameMD5 := fmt.Sprintf("m-%s", hex.EncodeToString(digest[:])[:12])
@@ -369,6 +571,9 @@ func (t *Authorizer) getClusterByToken(token string) (*v3.Cluster, error) {
 
 	for _, obj := range keys {
 		crt := obj.(*v3.ClusterRegistrationToken)
+		if !crt.AcceptsToken(token, time.Now()) {
+			continue
+		}
 		return t.clusterLister.Get("", crt.Spec.ClusterName)
 	}
 
@@ -377,10 +582,7 @@ func (t *Authorizer) getClusterByToken(token string) (*v3.Cluster, error) {
 
 func (t *Authorizer) crtIndex(obj interface{}) ([]string, error) {
 	crt := obj.(*v3.ClusterRegistrationToken)
-	if crt.Status.Token == "" {
-		return nil, nil
-	}
-	return []string{crt.Status.Token}, nil
+	return crt.Tokens(), nil
 }
 
 func (t *Authorizer) nodeIndex(obj interface{}) ([]string, error) {
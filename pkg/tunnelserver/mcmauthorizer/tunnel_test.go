@@ -0,0 +1,206 @@
+package mcmauthorizer
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+type nodeClients struct {
+	nodes   map[string]*v3.Node
+	created []*v3.Node
+	updated []*v3.Node
+}
+
+func newTestAuthorizer(nodes ...*v3.Node) (*Authorizer, *nodeClients) {
+	clients := &nodeClients{nodes: map[string]*v3.Node{}}
+	for _, node := range nodes {
+		clients.nodes[node.Name] = node
+	}
+
+	t := &Authorizer{
+		machineLister: &fakes.NodeListerMock{
+			GetFunc: func(namespace string, name string) (*v3.Node, error) {
+				if node, ok := clients.nodes[name]; ok {
+					return node, nil
+				}
+				return nil, apierrors.NewNotFound(v3.NodeGroupVersionResource.GroupResource(), name)
+			},
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v3.Node, error) {
+				var result []*v3.Node
+				for _, node := range clients.nodes {
+					result = append(result, node)
+				}
+				return result, nil
+			},
+		},
+		machines: &fakes.NodeInterfaceMock{
+			CreateFunc: func(in1 *v3.Node) (*v3.Node, error) {
+				clients.created = append(clients.created, in1)
+				clients.nodes[in1.Name] = in1
+				return in1, nil
+			},
+			UpdateFunc: func(in1 *v3.Node) (*v3.Node, error) {
+				clients.updated = append(clients.updated, in1)
+				clients.nodes[in1.Name] = in1
+				return in1, nil
+			},
+		},
+	}
+	t.nodeIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{nodeKeyIndex: t.nodeIndex})
+	return t, clients
+}
+
+func newCustomNode(name, address string, etcd, controlPlane, worker bool) *v3.Node {
+	return &v3.Node{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c-abcde", Name: name},
+		Spec: v32.NodeSpec{
+			Etcd:              etcd,
+			ControlPlane:      controlPlane,
+			Worker:            worker,
+			RequestedHostname: "node1",
+			CustomConfig:      &v32.CustomConfig{Address: address},
+			Imported:          true,
+		},
+	}
+}
+
+func newInNode(address string, etcd, controlPlane, worker bool) *client.Node {
+	return &client.Node{
+		Etcd:              etcd,
+		ControlPlane:      controlPlane,
+		Worker:            worker,
+		RequestedHostname: "node1",
+		CustomConfig:      &client.CustomConfig{Address: address},
+	}
+}
+
+var testCluster = &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+
+func TestRegistrationRerun(t *testing.T) {
+	name := machineName(newInNode("10.0.0.1", false, false, true))
+	auth, clients := newTestAuthorizer(newCustomNode(name, "10.0.0.1", false, false, true))
+
+	node, ok, err := auth.authorizeNode(true, testCluster, newInNode("10.0.0.1", false, false, true), nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, clients.created, "expected the node of the host to be adopted")
+	assert.Equal(t, name, node.Name)
+	assert.Empty(t, node.Labels[ReregisteredLabel], "expected a rerun on the host of the node not to be marked as adopted")
+	assert.True(t, node.Spec.Worker)
+	assert.False(t, node.Spec.Etcd)
+}
+
+func TestRegistrationRerunWithOtherRoles(t *testing.T) {
+	name := machineName(newInNode("10.0.0.1", false, false, true))
+	auth, clients := newTestAuthorizer(
+		newCustomNode(name, "10.0.0.1", true, true, false),
+		newCustomNode("m-other", "10.0.0.9", true, true, false),
+	)
+
+	node, _, err := auth.authorizeNode(true, testCluster, newInNode("10.0.0.1", false, false, true), nil)
+	require.NoError(t, err)
+	assert.Empty(t, clients.created)
+	assert.Equal(t, name, node.Name)
+	assert.Equal(t, []bool{false, false, true}, []bool{node.Spec.Etcd, node.Spec.ControlPlane, node.Spec.Worker},
+		"expected the roles of the command to replace the roles of the node")
+	assert.Empty(t, node.Labels[ReregisteredLabel])
+
+	// the node is now the only one with the etcd and controlplane roles, which it can't drop
+	delete(clients.nodes, "m-other")
+	clients.nodes[name] = newCustomNode(name, "10.0.0.1", true, true, false)
+	node, _, err = auth.authorizeNode(true, testCluster, newInNode("10.0.0.1", false, false, true), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false}, []bool{node.Spec.Etcd, node.Spec.ControlPlane, node.Spec.Worker},
+		"expected the last etcd and controlplane node to keep its roles")
+}
+
+func TestRegistrationNewHostWithReusedHostname(t *testing.T) {
+	name := machineName(newInNode("10.0.0.1", false, false, true))
+	auth, clients := newTestAuthorizer(newCustomNode(name, "10.0.0.1", false, false, true))
+
+	node, ok, err := auth.authorizeNode(true, testCluster, newInNode("10.0.0.2", false, false, true), nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.Len(t, clients.created, 1, "expected a new host reusing a hostname to get its own node")
+	assert.NotEqual(t, name, node.Name)
+	assert.Equal(t, "10.0.0.2", node.Spec.CustomConfig.Address)
+	assert.Empty(t, node.Labels[ReregisteredLabel])
+	assert.Equal(t, "10.0.0.1", clients.nodes[name].Spec.CustomConfig.Address, "expected the node of the other host to be left alone")
+
+	// the connections of the new host are authorized as its own node
+	node, ok, err = auth.authorizeNode(false, testCluster, newInNode("10.0.0.2", false, false, true), nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, clients.created[0].Name, node.Name)
+
+	// and so are the reruns of its registration
+	node, _, err = auth.authorizeNode(true, testCluster, newInNode("10.0.0.2", false, false, true), nil)
+	require.NoError(t, err)
+	assert.Len(t, clients.created, 1)
+	assert.Equal(t, clients.created[0].Name, node.Name)
+	assert.Empty(t, node.Labels[ReregisteredLabel])
+}
+
+func TestRegistrationAdoptedByOtherHost(t *testing.T) {
+	name := machineName(newInNode("10.0.0.1", false, false, true))
+	existing := newCustomNode(name, "10.0.0.1", false, false, true)
+	existing.Spec.CustomConfig.InternalAddress = "192.168.0.1"
+	auth, clients := newTestAuthorizer(existing)
+
+	inNode := newInNode("10.0.0.1", false, false, true)
+	inNode.CustomConfig.InternalAddress = "192.168.0.2"
+	node, ok, err := auth.authorizeNode(true, testCluster, inNode, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, clients.created, "expected the node to be adopted")
+	assert.Equal(t, name, node.Name)
+	assert.Equal(t, "true", node.Labels[ReregisteredLabel], "expected a node adopted by another host to be marked")
+
+	// a node registered before its address was recorded can't be told apart from another host
+	legacy := newCustomNode(name, "", false, false, true)
+	auth, _ = newTestAuthorizer(legacy)
+	node, _, err = auth.authorizeNode(true, testCluster, newInNode("10.0.0.1", false, false, true), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "true", node.Labels[ReregisteredLabel])
+}
+
+func TestRecordPreflightFailure(t *testing.T) {
+	failure := &cluster{PreflightFailure: "pre-flight check [dns] failed: unable to resolve rancher.example.com", PreflightCheckedAt: "2021-06-01T10:00:00Z"}
+	message := "pre-flight check [dns] failed: unable to resolve rancher.example.com (checked at 2021-06-01T10:00:00Z)"
+
+	c := &v3.Cluster{}
+	v32.ClusterConditionWaiting.Unknown(c)
+	assert.True(t, recordPreflightFailure(c, failure))
+	assert.Equal(t, message, v32.ClusterConditionWaiting.GetMessage(c))
+	assert.False(t, recordPreflightFailure(c, failure), "expected the same failure not to change the cluster")
+
+	// the failure is dropped once the agent stops reporting it
+	assert.True(t, recordPreflightFailure(c, &cluster{}))
+	assert.Empty(t, v32.ClusterConditionWaiting.GetMessage(c))
+	assert.Empty(t, v32.ClusterConditionWaiting.GetReason(c))
+	assert.False(t, recordPreflightFailure(c, &cluster{}))
+
+	// and once the cluster API is available
+	assert.True(t, recordPreflightFailure(c, failure))
+	v32.ClusterConditionWaiting.True(c)
+	assert.True(t, recordPreflightFailure(c, failure))
+	assert.Empty(t, v32.ClusterConditionWaiting.GetMessage(c))
+
+	// unrelated Waiting messages are left alone
+	c = &v3.Cluster{}
+	v32.ClusterConditionWaiting.Unknown(c)
+	v32.ClusterConditionWaiting.Message(c, "waiting for cluster agent to be deployed")
+	assert.False(t, recordPreflightFailure(c, failure))
+	assert.False(t, recordPreflightFailure(c, &cluster{}))
+	assert.Equal(t, "waiting for cluster agent to be deployed", v32.ClusterConditionWaiting.GetMessage(c))
+}
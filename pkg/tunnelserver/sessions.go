@@ -0,0 +1,201 @@
+package tunnelserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rancher/remotedialer"
+)
+
+// activityInterval limits how often the activity of proxied connections is recorded
+const activityInterval = time.Second
+
+type sessionContextKey struct{}
+
+// SessionInfo describes a tunnel session of an agent connected to this server
+type SessionInfo struct {
+	ClientKey         string    `json:"clientKey"`
+	PeerIP            string    `json:"peerIP"`
+	ForwardedFor      string    `json:"forwardedFor,omitempty"`
+	ConnectedAt       time.Time `json:"connectedAt"`
+	LastActivity      time.Time `json:"lastActivity"`
+	ActiveConnections int       `json:"activeConnections"`
+}
+
+type session struct {
+	info SessionInfo
+}
+
+// Sessions records the tunnel sessions served by a remotedialer server, so they can be inspected when debugging the
+// connectivity of agents. Only the sessions connected to this server are known, sessions of peers are not.
+type Sessions struct {
+	lock     sync.Mutex
+	sessions map[string][]*session
+	now      func() time.Time
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{
+		sessions: map[string][]*session{},
+		now:      time.Now,
+	}
+}
+
+// Handler wraps the handler of the tunnel server. Serving a tunnel request blocks until the session is closed, so the
+// session is recorded once authorized and removed when the request returns.
+func (s *Sessions) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		sess := &session{
+			info: SessionInfo{
+				PeerIP:       peerIP(req.RemoteAddr),
+				ForwardedFor: req.Header.Get("X-Forwarded-For"),
+			},
+		}
+		defer s.remove(sess)
+		next.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), sessionContextKey{}, sess)))
+	})
+}
+
+// Authorizer wraps the authorizer of the tunnel server to record the client key of the sessions it authorizes
+func (s *Sessions) Authorizer(auth remotedialer.Authorizer) remotedialer.Authorizer {
+	return func(req *http.Request) (string, bool, error) {
+		clientKey, authed, err := auth(req)
+		if err != nil || !authed {
+			return clientKey, authed, err
+		}
+		if sess, ok := req.Context().Value(sessionContextKey{}).(*session); ok {
+			s.add(clientKey, sess)
+		}
+		return clientKey, authed, err
+	}
+}
+
+// Sessions returns the sessions of the client key, the most recent first
+func (s *Sessions) Sessions(clientKey string) []SessionInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := make([]SessionInfo, 0, len(s.sessions[clientKey]))
+	for _, sess := range s.sessions[clientKey] {
+		result = append(result, sess.info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ConnectedAt.After(result[j].ConnectedAt)
+	})
+	return result
+}
+
+// TrackConn counts the connection proxied through the sessions of the client key as active until it is closed, and
+// records the activity of the sessions when it is used
+func (s *Sessions) TrackConn(clientKey string, conn net.Conn) net.Conn {
+	s.update(clientKey, 1)
+	return &trackedConn{
+		Conn: conn,
+		done: func() {
+			s.update(clientKey, -1)
+		},
+		active: func() {
+			s.update(clientKey, 0)
+		},
+	}
+}
+
+func (s *Sessions) add(clientKey string, sess *session) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	sess.info.ClientKey = clientKey
+	sess.info.ConnectedAt = now
+	sess.info.LastActivity = now
+	s.sessions[clientKey] = append(s.sessions[clientKey], sess)
+}
+
+func (s *Sessions) remove(sess *session) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	clientKey := sess.info.ClientKey
+	sessions := s.sessions[clientKey]
+	for i, existing := range sessions {
+		if existing == sess {
+			sessions = append(sessions[:i:i], sessions[i+1:]...)
+			break
+		}
+	}
+	if len(sessions) == 0 {
+		delete(s.sessions, clientKey)
+	} else {
+		s.sessions[clientKey] = sessions
+	}
+}
+
+// update records activity on the sessions of the client key and adjusts their count of active connections. The
+// remotedialer server doesn't tell which of the sessions of a client a connection uses, so all of them are updated.
+func (s *Sessions) update(clientKey string, delta int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	for _, sess := range s.sessions[clientKey] {
+		sess.info.LastActivity = now
+		sess.info.ActiveConnections += delta
+		if sess.info.ActiveConnections < 0 {
+			sess.info.ActiveConnections = 0
+		}
+	}
+}
+
+func peerIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return strings.Trim(remoteAddr, "[]")
+	}
+	return host
+}
+
+type trackedConn struct {
+	// lastActive is the unix time in nanoseconds the activity of the connection was last recorded, first in the struct
+	// for the alignment of atomic operations
+	lastActive int64
+	net.Conn
+	once   sync.Once
+	done   func()
+	active func()
+}
+
+func (c *trackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.recordActivity()
+	}
+	return n, err
+}
+
+func (c *trackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.recordActivity()
+	}
+	return n, err
+}
+
+func (c *trackedConn) recordActivity() {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&c.lastActive)
+	if now-last < int64(activityInterval) || !atomic.CompareAndSwapInt64(&c.lastActive, last, now) {
+		return
+	}
+	c.active()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.done)
+	return c.Conn.Close()
+}
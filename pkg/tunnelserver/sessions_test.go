@@ -0,0 +1,126 @@
+package tunnelserver
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTunnel serves tunnel requests like the remotedialer server: the request is authorized, then blocks until the
+// session is closed
+type testTunnel struct {
+	authorize func(req *http.Request) (string, bool, error)
+	connected chan string
+	close     chan struct{}
+}
+
+func newTestTunnel(sessions *Sessions) *testTunnel {
+	t := &testTunnel{
+		connected: make(chan string),
+		close:     make(chan struct{}),
+	}
+	t.authorize = sessions.Authorizer(func(req *http.Request) (string, bool, error) {
+		clientKey := req.Header.Get("X-Client-Key")
+		return clientKey, clientKey != "", nil
+	})
+	return t
+}
+
+func (t *testTunnel) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	clientKey, authed, _ := t.authorize(req)
+	if !authed {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	t.connected <- clientKey
+	<-t.close
+}
+
+func connect(handler http.Handler, clientKey, remoteAddr string) chan struct{} {
+	done := make(chan struct{})
+	req := httptest.NewRequest(http.MethodGet, "/v3/connect", nil)
+	req.RemoteAddr = remoteAddr
+	if clientKey != "" {
+		req.Header.Set("X-Client-Key", clientKey)
+	}
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	return done
+}
+
+func TestSessions(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	sessions := NewSessions()
+	sessions.now = func() time.Time {
+		return now
+	}
+	tunnel := newTestTunnel(sessions)
+	handler := sessions.Handler(tunnel)
+
+	<-connect(handler, "", "10.0.0.1:40000")
+	assert.Empty(t, sessions.sessions, "expected unauthorized requests not to be recorded")
+
+	first := connect(handler, "c-abcde", "10.0.0.1:40000")
+	assert.Equal(t, "c-abcde", <-tunnel.connected)
+	now = now.Add(time.Minute)
+	second := connect(handler, "c-abcde", "[fd00::1]:40001")
+	<-tunnel.connected
+
+	infos := sessions.Sessions("c-abcde")
+	require.Len(t, infos, 2)
+	assert.Equal(t, "fd00::1", infos[0].PeerIP)
+	assert.Equal(t, now, infos[0].ConnectedAt)
+	assert.Equal(t, "10.0.0.1", infos[1].PeerIP)
+	assert.Equal(t, now.Add(-time.Minute), infos[1].ConnectedAt)
+	assert.Empty(t, sessions.Sessions("c-other"))
+
+	tunnel.close <- struct{}{}
+	select {
+	case <-first:
+	case <-second:
+	}
+	assert.Len(t, sessions.Sessions("c-abcde"), 1)
+
+	close(tunnel.close)
+	<-first
+	<-second
+	assert.Empty(t, sessions.Sessions("c-abcde"))
+	assert.Empty(t, sessions.sessions)
+}
+
+func TestTrackConn(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	sessions := NewSessions()
+	sessions.now = func() time.Time {
+		return now
+	}
+	sessions.add("c-abcde", &session{})
+
+	client, server := net.Pipe()
+	defer server.Close()
+	otherClient, otherServer := net.Pipe()
+	defer otherServer.Close()
+	conn := sessions.TrackConn("c-abcde", client)
+	other := sessions.TrackConn("c-abcde", otherClient)
+	assert.Equal(t, 2, sessions.Sessions("c-abcde")[0].ActiveConnections)
+
+	now = now.Add(time.Minute)
+	go server.Read(make([]byte, 4))
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, now, sessions.Sessions("c-abcde")[0].LastActivity)
+
+	require.NoError(t, conn.Close())
+	conn.Close()
+	assert.Equal(t, 1, sessions.Sessions("c-abcde")[0].ActiveConnections, "expected a connection to be counted once when closed twice")
+
+	require.NoError(t, other.Close())
+	assert.Equal(t, 0, sessions.Sessions("c-abcde")[0].ActiveConnections)
+}
@@ -26,6 +26,7 @@
 	projectv3 "github.com/rancher/rancher/pkg/generated/norman/project.cattle.io/v3"
 	rbacv1 "github.com/rancher/rancher/pkg/generated/norman/rbac.authorization.k8s.io/v1"
 	storagev1 "github.com/rancher/rancher/pkg/generated/norman/storage.k8s.io/v1"
+	"github.com/rancher/rancher/pkg/metrics/controllermetrics"
 	"github.com/rancher/rancher/pkg/peermanager"
 	clusterSchema "github.com/rancher/rancher/pkg/schemas/cluster.cattle.io/v3"
 	managementSchema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
@@ -114,7 +115,7 @@ func NewScaledContext(config rest.Config, opts *ScaleContextOptions) (*ScaledCon
 		if err != nil {
 			return nil, err
 		}
-		context.ControllerFactory = controllerFactory
+		context.ControllerFactory = controllermetrics.Wrap(controllerFactory)
 	} else {
 		context.ControllerFactory = opts.ControllerFactory
 	}
@@ -356,6 +357,10 @@ func NewUserContext(scaledContext *ScaledContext, config rest.Config, clusterNam
 		ClusterName: clusterName,
 		runContext:  scaledContext.RunContext,
 	}
+	if config.RateLimiter != nil {
+		// keep the client rate limit of the downstream cluster
+		context.RESTConfig.RateLimiter = config.RateLimiter
+	}
 
 	context.Management, err = scaledContext.NewManagementContext()
 	if err != nil {
@@ -366,7 +371,7 @@ func NewUserContext(scaledContext *ScaledContext, config rest.Config, clusterNam
 	if err != nil {
 		return nil, err
 	}
-	context.ControllerFactory = controllerFactory
+	context.ControllerFactory = controllermetrics.Wrap(controllerFactory)
 
 	context.K8sClient, err = kubernetes.NewForConfig(&config)
 	if err != nil {
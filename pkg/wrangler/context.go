@@ -13,6 +13,7 @@
 	"github.com/rancher/lasso/pkg/controller"
 	"github.com/rancher/lasso/pkg/dynamic"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/api/steve/health"
 	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	clusterv3api "github.com/rancher/rancher/pkg/apis/cluster.cattle.io/v3"
 	managementv3api "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
@@ -36,6 +37,7 @@
 	provisioningv1 "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io"
 	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/metrics/controllermetrics"
 	"github.com/rancher/rancher/pkg/peermanager"
 	"github.com/rancher/rancher/pkg/tunnelserver"
 	"github.com/rancher/remotedialer"
@@ -114,6 +116,8 @@ type Context struct {
 	MultiClusterManager MultiClusterManager
 	TunnelServer        *remotedialer.Server
 	TunnelAuthorizer    *tunnelserver.Authorizers
+	TunnelSessions      *tunnelserver.Sessions
+	Readiness           *health.Readiness
 	PeerManager         peermanager.PeerManager
 	Provisioning        provisioningv1.Interface
 
@@ -187,6 +191,8 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 		return nil, err
 	}
 
+	controllerFactory = controllermetrics.Wrap(controllerFactory)
+
 	opts := &generic.FactoryOptions{
 		SharedControllerFactory: controllerFactory,
 	}
@@ -279,13 +285,14 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 		RESTMapper:      restMapper,
 	}
 
-	systemCharts, err := system.NewManager(ctx, restClientGetter, content, helmop, steveControllers.Core.Pod())
+	systemCharts, err := system.NewManager(ctx, restClientGetter, content, helmop, steveControllers.Core.Pod(), steveControllers.Core.Secret())
 	if err != nil {
 		return nil, err
 	}
 
 	tunnelAuth := &tunnelserver.Authorizers{}
-	tunnelServer := remotedialer.New(tunnelAuth.Authorize, tunnelserver.ErrorWriter)
+	tunnelSessions := tunnelserver.NewSessions()
+	tunnelServer := remotedialer.New(tunnelSessions.Authorizer(tunnelAuth.Authorize), tunnelserver.ErrorWriter)
 	peerManager, err := tunnelserver.NewPeerManager(ctx, steveControllers.Core.Endpoints(), tunnelServer)
 	if err != nil {
 		return nil, err
@@ -327,6 +334,8 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 		SystemChartsManager:     systemCharts,
 		TunnelAuthorizer:        tunnelAuth,
 		TunnelServer:            tunnelServer,
+		TunnelSessions:          tunnelSessions,
+		Readiness:               health.NewReadiness(),
 	}, nil
 }
 